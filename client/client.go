@@ -0,0 +1,119 @@
+// Package client drives a PanGo rig over its HTTP API: start, preview and
+// cancel captures, read the status and edit the configuration.
+//
+// The methods and type aliases in client_gen.go are generated from the API
+// description in internal/web (also served at GET /openapi.json and saved in
+// api/openapi.json); run go generate after changing it.
+package client
+
+//go:generate go run ../internal/apigen -client client_gen.go -spec ../api/openapi.json
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum size of an error message read from a response.
+const maxErrorBytes = 4 << 10
+
+// Client calls the API of the PanGo server at BaseURL
+// (e.g. "http://pango.local:8080").
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client // nil = http.DefaultClient
+
+	// Credentials, matching web.auth in the server config. Token takes
+	// precedence over Username and Password.
+	Token    string
+	Username string
+	Password string
+
+	// ControlToken is sent in the X-Control-Token header: set it to the
+	// token returned by ClaimControl to act on a rig whose control is claimed.
+	ControlToken string
+}
+
+// New returns a client for the server at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Error is returned when the server answers with an unexpected status.
+type Error struct {
+	StatusCode int
+	Message    string        // plain-text error from the server
+	RetryAfter time.Duration // when rate-limited (429): wait this long before retrying; 0 if not given
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("pango: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// do sends a request with body encoded as JSON (when not nil) and decodes
+// the response into out (when not nil). Any status other than want is
+// returned as an *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body any, want int, out any) error {
+	u := c.BaseURL + apiPrefix + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case c.Username != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	if c.ControlToken != "" {
+		req.Header.Set("X-Control-Token", c.ControlToken)
+	}
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != want {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBytes))
+		e := &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(msg))}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			e.RetryAfter = time.Duration(secs) * time.Second
+		}
+		return e
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
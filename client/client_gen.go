@@ -0,0 +1,216 @@
+// Code generated by apigen from internal/web.Operations. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// apiPrefix is where the API routes are served (web.APIPrefix).
+const apiPrefix = "/api/v1"
+
+// API types, shared with the server.
+type (
+	BuildInfo       = web.BuildInfo
+	Cell            = capture.Cell
+	ConfigUpdate    = web.ConfigUpdate
+	ControlClaim    = web.ControlClaim
+	ControlStatus   = web.ControlStatus
+	DebugState      = web.DebugState
+	ETA             = capture.ETA
+	EffectiveConfig = web.EffectiveConfig
+	FormConfig      = web.FormConfig
+	HeadState       = web.HeadState
+	LogLevels       = web.LogLevels
+	Overrides       = web.Overrides
+	Plan            = web.Plan
+	PlanGrid        = web.PlanGrid
+	ProfileUpload   = web.ProfileUpload
+	SavedDefaults   = web.SavedDefaults
+	State           = capture.State
+	Status          = capture.Status
+	StatusEvent     = web.StatusEvent
+	SyncCell        = web.SyncCell
+)
+
+// RunCapture calls POST /run: start a capture now.
+func (c *Client) RunCapture(ctx context.Context, body Overrides) (map[string]string, error) {
+	var out map[string]string
+	err := c.do(ctx, "POST", "/run", nil, body, 202, &out)
+	return out, err
+}
+
+// CancelCapture calls POST /cancel: cancel the running capture.
+func (c *Client) CancelCapture(ctx context.Context) (map[string]string, error) {
+	var out map[string]string
+	err := c.do(ctx, "POST", "/cancel", nil, nil, 200, &out)
+	return out, err
+}
+
+// PauseCapture calls POST /pause: pause the running capture.
+func (c *Client) PauseCapture(ctx context.Context) (Status, error) {
+	var out Status
+	err := c.do(ctx, "POST", "/pause", nil, nil, 200, &out)
+	return out, err
+}
+
+// ResumeCapture calls POST /resume: resume a paused capture.
+func (c *Client) ResumeCapture(ctx context.Context) (Status, error) {
+	var out Status
+	err := c.do(ctx, "POST", "/resume", nil, nil, 200, &out)
+	return out, err
+}
+
+// GetControl calls GET /control: get who holds control of the rig.
+func (c *Client) GetControl(ctx context.Context) (ControlStatus, error) {
+	var out ControlStatus
+	err := c.do(ctx, "GET", "/control", nil, nil, 200, &out)
+	return out, err
+}
+
+// ClaimControl calls POST /control/claim: claim control of the rig.
+func (c *Client) ClaimControl(ctx context.Context, body ControlClaim) (ControlStatus, error) {
+	var out ControlStatus
+	err := c.do(ctx, "POST", "/control/claim", nil, body, 200, &out)
+	return out, err
+}
+
+// ReleaseControl calls POST /control/release: release control of the rig.
+func (c *Client) ReleaseControl(ctx context.Context) (ControlStatus, error) {
+	var out ControlStatus
+	err := c.do(ctx, "POST", "/control/release", nil, nil, 200, &out)
+	return out, err
+}
+
+// PlanCapture calls POST /plan: preview a capture.
+func (c *Client) PlanCapture(ctx context.Context, body Overrides) (Plan, error) {
+	var out Plan
+	err := c.do(ctx, "POST", "/plan", nil, body, 200, &out)
+	return out, err
+}
+
+// GetFormConfig calls GET /config: get the capture form defaults.
+func (c *Client) GetFormConfig(ctx context.Context) (FormConfig, error) {
+	var out FormConfig
+	err := c.do(ctx, "GET", "/config", nil, nil, 200, &out)
+	return out, err
+}
+
+// GetConfig calls GET /config/full: get the full configuration.
+func (c *Client) GetConfig(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	err := c.do(ctx, "GET", "/config/full", nil, nil, 200, &out)
+	return out, err
+}
+
+// GetEffectiveConfig calls GET /config/effective: get the configuration in use.
+func (c *Client) GetEffectiveConfig(ctx context.Context) (EffectiveConfig, error) {
+	var out EffectiveConfig
+	err := c.do(ctx, "GET", "/config/effective", nil, nil, 200, &out)
+	return out, err
+}
+
+// GetConfigSchema calls GET /config/schema: get the JSON Schema of the config file.
+func (c *Client) GetConfigSchema(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	err := c.do(ctx, "GET", "/config/schema", nil, nil, 200, &out)
+	return out, err
+}
+
+// UpdateConfig calls PUT /config: replace the configuration.
+func (c *Client) UpdateConfig(ctx context.Context, body json.RawMessage, save bool) (ConfigUpdate, error) {
+	query := url.Values{}
+	query.Set("save", fmt.Sprint(save))
+	var out ConfigUpdate
+	err := c.do(ctx, "PUT", "/config", query, body, 200, &out)
+	return out, err
+}
+
+// UploadProfile calls POST /config/upload: add a configuration profile.
+func (c *Client) UploadProfile(ctx context.Context, body json.RawMessage, name string, replace bool, activate bool) (ProfileUpload, error) {
+	query := url.Values{}
+	query.Set("name", fmt.Sprint(name))
+	query.Set("replace", fmt.Sprint(replace))
+	query.Set("activate", fmt.Sprint(activate))
+	var out ProfileUpload
+	err := c.do(ctx, "POST", "/config/upload", query, body, 201, &out)
+	return out, err
+}
+
+// SaveDefaults calls POST /config/defaults: save capture parameters as profile defaults.
+func (c *Client) SaveDefaults(ctx context.Context, body Overrides, profile string) (SavedDefaults, error) {
+	query := url.Values{}
+	query.Set("profile", fmt.Sprint(profile))
+	var out SavedDefaults
+	err := c.do(ctx, "POST", "/config/defaults", query, body, 200, &out)
+	return out, err
+}
+
+// GetStatus calls GET /status: get the capture status.
+func (c *Client) GetStatus(ctx context.Context) (Status, error) {
+	var out Status
+	err := c.do(ctx, "GET", "/status", nil, nil, 200, &out)
+	return out, err
+}
+
+// GetETA calls GET /eta: estimate when the running capture completes.
+func (c *Client) GetETA(ctx context.Context) (ETA, error) {
+	var out ETA
+	err := c.do(ctx, "GET", "/eta", nil, nil, 200, &out)
+	return out, err
+}
+
+// GetVersion calls GET /version: get the PanGo build.
+func (c *Client) GetVersion(ctx context.Context) (BuildInfo, error) {
+	var out BuildInfo
+	err := c.do(ctx, "GET", "/version", nil, nil, 200, &out)
+	return out, err
+}
+
+// GetLogLevels calls GET /log-level: get the debug output levels.
+func (c *Client) GetLogLevels(ctx context.Context) (LogLevels, error) {
+	var out LogLevels
+	err := c.do(ctx, "GET", "/log-level", nil, nil, 200, &out)
+	return out, err
+}
+
+// SetLogLevels calls PUT /log-level: set the debug output level of modules.
+func (c *Client) SetLogLevels(ctx context.Context, body map[string]string) (LogLevels, error) {
+	var out LogLevels
+	err := c.do(ctx, "PUT", "/log-level", nil, body, 200, &out)
+	return out, err
+}
+
+// GetDebugState calls GET /debug/state: dump the state of the server.
+func (c *Client) GetDebugState(ctx context.Context) (DebugState, error) {
+	var out DebugState
+	err := c.do(ctx, "GET", "/debug/state", nil, nil, 200, &out)
+	return out, err
+}
+
+// SyncRun calls POST /sync/run: start a capture as a follower rig.
+func (c *Client) SyncRun(ctx context.Context, body Overrides) (map[string]string, error) {
+	var out map[string]string
+	err := c.do(ctx, "POST", "/sync/run", nil, body, 202, &out)
+	return out, err
+}
+
+// SyncReady calls GET /sync/ready: wait until a follower rig is in place for its next shot.
+func (c *Client) SyncReady(ctx context.Context) (SyncCell, error) {
+	var out SyncCell
+	err := c.do(ctx, "GET", "/sync/ready", nil, nil, 200, &out)
+	return out, err
+}
+
+// SyncShoot calls POST /sync/shoot: release the shot of a follower rig.
+func (c *Client) SyncShoot(ctx context.Context, body SyncCell) (SyncCell, error) {
+	var out SyncCell
+	err := c.do(ctx, "POST", "/sync/shoot", nil, body, 200, &out)
+	return out, err
+}
@@ -0,0 +1,107 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+const testToken = "0123456789abcdef"
+
+// newTestServer runs the real web handlers with fake dependencies.
+func newTestServer(t *testing.T) (*web.Server, *Client) {
+	t.Helper()
+	srv := web.NewServer(":0", web.NewStatusBroadcaster(), func(ctx context.Context, o web.Overrides) error { return nil }, web.FormConfig{FocalLengthMm: 35})
+	srv.SetAuth(web.AuthConfig{Token: testToken})
+	mux := srv.Mux()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+		if w.Header().Get("Deprecation") != "" {
+			t.Errorf("%s %s: the client calls a deprecated path", r.Method, r.URL.Path)
+		}
+	}))
+	t.Cleanup(ts.Close)
+	c := New(ts.URL + "/")
+	c.Token = testToken
+	return srv, c
+}
+
+func TestClient_Calls(t *testing.T) {
+	srv, c := newTestServer(t)
+	srv.Handlers().Lifecycle = capture.NewLifecycle()
+	srv.Handlers().Plan = func(o Overrides) (Plan, error) {
+		return Plan{Mode: "grid", TotalShots: 12, Columns: 4, Rows: 3}, nil
+	}
+	var saved bool
+	srv.Handlers().UpdateConfig = func(data []byte, persist bool) (web.ConfigUpdate, error) {
+		saved = persist
+		return web.ConfigUpdate{Saved: persist}, nil
+	}
+	ctx := context.Background()
+
+	form, err := c.GetFormConfig(ctx)
+	if err != nil || form.FocalLengthMm != 35 {
+		t.Errorf("GetFormConfig = %+v, %v", form, err)
+	}
+	plan, err := c.PlanCapture(ctx, Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35})
+	if err != nil || plan.TotalShots != 12 {
+		t.Errorf("PlanCapture = %+v, %v", plan, err)
+	}
+	st, err := c.GetStatus(ctx)
+	if err != nil || st.State != capture.StateIdle {
+		t.Errorf("GetStatus = %+v, %v", st, err)
+	}
+	update, err := c.UpdateConfig(ctx, json.RawMessage(`{"lens":{"focal_length_mm":50}}`), true)
+	if err != nil || !update.Saved || !saved {
+		t.Errorf("UpdateConfig = %+v, %v (persist %t)", update, err, saved)
+	}
+	res, err := c.RunCapture(ctx, Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35})
+	if err != nil || res["status"] != "started" {
+		t.Errorf("RunCapture = %v, %v", res, err)
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	_, c := newTestServer(t)
+	ctx := context.Background()
+
+	// No full config function: the server answers 503 with a message.
+	_, err := c.GetConfig(ctx)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable || apiErr.Message != "configuration editing not available" {
+		t.Errorf("GetConfig error = %v", err)
+	}
+
+	_, err = c.RunCapture(ctx, Overrides{})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("RunCapture(invalid) error = %v, want 400", err)
+	}
+
+	valid := Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35}
+	if _, err := c.RunCapture(ctx, valid); err != nil {
+		t.Fatalf("RunCapture: %v", err)
+	}
+	// Once the first capture is done (409 until then), the spacing applies.
+	for i := 0; i < 100; i++ {
+		_, err = c.RunCapture(ctx, valid)
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.RetryAfter != 5*time.Second {
+		t.Errorf("RunCapture(again) error = %v, want 429 with RetryAfter 5s", err)
+	}
+
+	c.Token = "wrong-token-wrong-token"
+	if _, err := c.GetStatus(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GetStatus(bad token) error = %v, want 401", err)
+	}
+}
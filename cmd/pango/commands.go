@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/logic/motion"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// defaultWebPort is the port of the web interface when neither -port (or
+// -web) nor web.port set one.
+const defaultWebPort = 8080
+
+const pangoUsage = `Usage: pango <command> [flags]
+
+Commands:
+  serve      run the web interface and API
+  run        run one capture with the configuration, then exit
+  plan       print the grid, shot count and duration of a capture
+  jog        move the head by an angle or a number of steps
+  home       drive the head back to its home position
+  calibrate  measure the steps per revolution of an axis
+  validate   check config files
+  config     print the configuration a capture would run with
+  schema     print the JSON Schema of config files
+  remote     drive a running PanGo server over its HTTP API
+  gpio       replay or compare GPIO recordings (-record-gpio)
+  completion print the shell completion script (bash, zsh or fish)
+  version    print the version and build information
+
+Only serve, run, jog, home and calibrate drive the GPIO pins; the other
+commands can be used on any computer. "pango <command> -h" lists the flags
+of a command.
+
+"pango -dry-run <command>" rehearses a command: mock GPIO and camera
+whatever the config says, the pin changes and shots logged, and neither the
+head position nor the config file saved.
+
+"pango -log-file <path> <command>" also writes the log of serve, run, jog,
+home or calibrate to a file, rotated by size: the command line version of
+log.file in the config file.
+
+"pango -record-gpio <path> <command>" records every GPIO operation of the
+command (pin, level, time) to a file, to replay or compare with "pango gpio".
+
+Without a command, pango reads the flags of serve and run as before
+subcommands existed: "pango -web 8080" serves, "pango" runs one capture
+(or serves when web.port is set).
+`
+
+// command is a subcommand of pango. run returns the exit status: 0 on
+// success, 1 when the command failed, 2 on a usage error.
+type command struct {
+	name string
+	run  func(ctx context.Context, args []string, stdout, stderr io.Writer) int
+}
+
+var commands = []command{
+	{"serve", runServe},
+	{"run", runRun},
+	{"plan", runPlan},
+	{"jog", runJog},
+	{"home", runHome},
+	{"calibrate", runCalibrate},
+	{"validate", func(_ context.Context, args []string, stdout, stderr io.Writer) int {
+		return runValidate(args, stdout, stderr)
+	}},
+	{"config", func(_ context.Context, args []string, stdout, stderr io.Writer) int {
+		return runConfig(args, stdout, stderr)
+	}},
+	{"schema", func(_ context.Context, args []string, stdout, stderr io.Writer) int {
+		return runSchema(args, stdout, stderr)
+	}},
+	{"remote", runRemote},
+	{"gpio", runGPIO},
+	{"completion", runCompletion},
+	{"version", runVersion},
+}
+
+// dryRun is set by -dry-run: the hardware is mocked, its operations logged,
+// and nothing is saved for the next runs.
+var dryRun bool
+
+// runPango runs the command named by args[0] and returns its exit status.
+// Leading -dry-run, -log-file and -record-gpio apply to the command that
+// follows.
+func runPango(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	for len(args) > 0 {
+		if args[0] == "-dry-run" || args[0] == "--dry-run" {
+			dryRun = true
+			defer func() { dryRun = false }()
+			args = args[1:]
+			continue
+		}
+		name, path, n, ok := pathFlag(args)
+		if !ok {
+			break
+		}
+		if path == "" {
+			fmt.Fprintf(stderr, "pango: -%s needs a file path\n\n%s", name, pangoUsage)
+			return 2
+		}
+		switch name {
+		case "log-file":
+			logFile = path
+			defer func() { logFile = "" }()
+		case "record-gpio":
+			recordGPIO = path
+			defer func() { recordGPIO = "" }()
+		}
+		args = args[n:]
+	}
+	if len(args) > 0 {
+		switch args[0] {
+		case "help", "-h", "-help", "--help":
+			fmt.Fprint(stdout, pangoUsage)
+			return 0
+		case "__complete": // called by the completion scripts
+			return runComplete(ctx, args[1:], stdout, stderr)
+		}
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runLegacy(ctx, args, stderr)
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(ctx, args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "pango: unknown command %q\n\n%s", args[0], pangoUsage)
+	return 2
+}
+
+// pathFlags are the leading flags of pango taking a file path.
+var pathFlags = []string{"log-file", "record-gpio"}
+
+// pathFlag parses a leading -log-file or -record-gpio path (or
+// -name=path) in args, returning the flag name, the path and the number of
+// args it took.
+func pathFlag(args []string) (name, path string, n int, ok bool) {
+	name, value, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+	if !strings.HasPrefix(args[0], "-") || !slices.Contains(pathFlags, name) {
+		return "", "", 0, false
+	}
+	if hasValue {
+		return name, value, 1, true
+	}
+	if len(args) < 2 {
+		return name, "", 1, true
+	}
+	return name, args[1], 2, true
+}
+
+// configFlags are the flags selecting the configuration of a command.
+type configFlags struct {
+	path, profile               string
+	horizontal, vertical, focal float64
+	overlap                     float64
+	shotDelay, moveSpeed        int
+}
+
+// addConfigFlags registers -config and -profile on fs, and the capture
+// overrides with overrides set.
+func addConfigFlags(fs *flag.FlagSet, overrides bool) *configFlags {
+	f := &configFlags{}
+	fs.StringVar(&f.path, "config", filepath.Join("configs", "default.yaml"), "path to config file")
+	fs.StringVar(&f.profile, "profile", "", "run with this profile: an entry of the config file's profiles: section, a config file next to it, or file/entry")
+	if overrides {
+		fs.Float64Var(&f.horizontal, "horizontal_angle_deg", 0, "override horizontal angle in degrees (1-360)")
+		fs.Float64Var(&f.vertical, "vertical_angle_deg", 0, "override vertical angle in degrees (1-180)")
+		fs.Float64Var(&f.focal, "focal_length_mm", 0, "override focal length in mm")
+		fs.Float64Var(&f.overlap, "overlap_percent", 0, "override overlap between photos in percent (below 100)")
+		fs.IntVar(&f.shotDelay, "shot_delay_ms", 0, "override hold after each shot before moving, in ms (camera.post_shot_delay_ms)")
+		fs.IntVar(&f.moveSpeed, "move_speed_ms", 0, "override delay between motor steps in ms (1-1000)")
+	}
+	return f
+}
+
+// load validates the overrides, then loads the configuration like
+// loadRunConfig.
+func (f *configFlags) load() (*config.Config, error) {
+	// Only non-zero values are applied; zero means "use config default"
+	if err := validateCLIOverrides(f.horizontal, f.vertical, f.focal); err != nil {
+		return nil, fmt.Errorf("invalid override: %w", err)
+	}
+	if err := validateCLIPacing(f.overlap, f.shotDelay, f.moveSpeed); err != nil {
+		return nil, fmt.Errorf("invalid override: %w", err)
+	}
+	return loadRunConfig(f.path, f.profile, web.Overrides{
+		HorizontalAngleDeg: f.horizontal,
+		VerticalAngleDeg:   f.vertical,
+		FocalLengthMm:      f.focal,
+		OverlapPercent:     f.overlap,
+		ShotDelayMs:        f.shotDelay,
+		MoveSpeedMs:        f.moveSpeed,
+	})
+}
+
+// newFlagSet returns the flag set of command name, printing usage then the
+// flags on -h.
+func newFlagSet(name, usage string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(strings.TrimSpace("pango "+name), flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// completingFlags, when set, receives the flag set of the command being
+// completed, which then stops instead of running: see commandFlags.
+var completingFlags func(fs *flag.FlagSet)
+
+// errCompleting stops a command at its flags while completing.
+var errCompleting = errors.New("completing")
+
+// parseArgs parses args into fs. While completing, the flag set of the
+// command (the one parsing no arguments) goes to completingFlags instead.
+func parseArgs(fs *flag.FlagSet, args []string) error {
+	if completingFlags != nil && len(args) == 0 {
+		completingFlags(fs)
+		return errCompleting
+	}
+	return fs.Parse(args)
+}
+
+// parseFlags parses args into fs, refusing arguments after the flags.
+func parseFlags(fs *flag.FlagSet, args []string) bool {
+	if err := parseArgs(fs, args); err != nil {
+		return false
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "%s: unexpected argument %q\n", fs.Name(), fs.Arg(0))
+		fs.Usage()
+		return false
+	}
+	return true
+}
+
+// withRig loads the configuration of f, initializes the hardware and runs
+// fn, then releases the GPIO pins. It returns the exit status, errors
+// being reported as from prog (e.g. "pango run").
+func withRig(f *configFlags, prog string, stderr io.Writer, fn func(cfg *config.Config, r *rig) error) int {
+	cfg, err := f.load()
+	if err == nil {
+		var r *rig
+		if r, err = openRig(cfg, f.path); err == nil {
+			err = fn(cfg, r)
+			r.close()
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", prog, err)
+		return 1
+	}
+	return 0
+}
+
+const serveUsage = `Usage: pango serve [flags]
+
+Run the web interface and API until interrupted. Captures, jogs and
+configuration changes are driven from the page or the API.
+
+Flags:
+`
+
+// runServe implements "pango serve".
+func runServe(ctx context.Context, args []string, _, stderr io.Writer) int {
+	fs := newFlagSet("serve", serveUsage, stderr)
+	f := addConfigFlags(fs, true)
+	var opts serveOptions
+	fs.IntVar(&opts.port, "port", 0, fmt.Sprintf("port of the web interface (default web.port, else %d)", defaultWebPort))
+	fs.BoolVar(&opts.watchConfig, "watch-config", false, "reload the config file when it changes (also reloaded on SIGHUP)")
+	fs.StringVar(&opts.staticDir, "static-dir", "", "serve the web interface files from this directory, falling back to the built-in ones (overrides web.static_dir)")
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	if opts.port < 0 || opts.port > 65535 {
+		fmt.Fprintf(stderr, "pango serve: -port must be 1-65535, got %d\n", opts.port)
+		return 2
+	}
+	return withRig(f, fs.Name(), stderr, func(cfg *config.Config, r *rig) error {
+		return serve(ctx, cfg, f.path, r, opts)
+	})
+}
+
+const runUsage = `Usage: pango run [flags]
+
+Run one capture with the configuration, then exit. On SIGINT or SIGTERM
+the capture stops after the current shot.
+
+Flags:
+`
+
+// runRun implements "pango run".
+func runRun(ctx context.Context, args []string, _, stderr io.Writer) int {
+	fs := newFlagSet("run", runUsage, stderr)
+	f := addConfigFlags(fs, true)
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	return withRig(f, fs.Name(), stderr, func(cfg *config.Config, r *rig) error {
+		return runOnce(ctx, cfg, r)
+	})
+}
+
+const homeUsage = `Usage: pango home [flags]
+
+Drive the head back to its home position: where it was when -set was last
+given, or when pango first ran. pango keeps the head position between
+runs (after captures, jogs and the web server) in its cache directory.
+
+Flags:
+`
+
+// runHome implements "pango home".
+func runHome(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("home", homeUsage, stderr)
+	f := addConfigFlags(fs, false)
+	set := fs.Bool("set", false, "make the current head position home, without moving")
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	if *set {
+		if err := saveHeadPosition(capture.Position{}); err != nil {
+			fmt.Fprintf(stderr, "pango home: %v\n", err)
+			return 1
+		}
+		fmt.Fprintln(stdout, "Home set to the current head position.")
+		return 0
+	}
+	return withRig(f, fs.Name(), stderr, func(cfg *config.Config, r *rig) error {
+		err := r.goHome(ctx, cfg.MoveSpeed())
+		r.park()
+		return err
+	})
+}
+
+// goHome drives the head back to its home position.
+func (r *rig) goHome(ctx context.Context, period time.Duration) error {
+	if !r.busy.TryLock() {
+		return web.ErrHeadBusy
+	}
+	defer r.busy.Unlock()
+
+	pos := r.home()
+	if pos == (capture.Position{}) {
+		log.Printf("head already home")
+		return nil
+	}
+	log.Printf("head at pan %d, tilt %d steps from home, driving back", pos.PanSteps, pos.TiltSteps)
+	ctrl := motion.NewController(r.pan, r.tilt)
+	if err := ctrl.EnableMotors(); err != nil {
+		return err
+	}
+	if err := ctrl.MoveTiltContext(ctx, -pos.TiltSteps, period); err != nil {
+		return err
+	}
+	return ctrl.MovePanContext(ctx, -pos.PanSteps, period)
+}
+
+const calibrateUsage = `Usage: pango calibrate -axis pan|tilt [-deg n] [-measured n [-save]] [flags]
+
+Check the steps per revolution of an axis, gearing included. First mark the
+position of the head and run without -measured: the axis turns by -deg as
+configured. Measure the angle it really turned, then run again with
+-measured to get the corrected steps_per_rev (-save writes it to the config
+file). The second step does not touch the GPIO pins.
+
+Flags:
+`
+
+// runCalibrate implements "pango calibrate".
+func runCalibrate(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("calibrate", calibrateUsage, stderr)
+	cfgPath := fs.String("config", filepath.Join("configs", "default.yaml"), "path to config file")
+	axis := fs.String("axis", "pan", "axis to calibrate: pan or tilt")
+	deg := fs.Float64("deg", 360, "angle to turn, in degrees")
+	measured := fs.Float64("measured", 0, "angle the axis really turned with the same -deg, in degrees")
+	save := fs.Bool("save", false, "with -measured, write the corrected steps_per_rev to the config file")
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	req := web.JogRequest{Axis: *axis, Degrees: *deg}
+	if err := web.ValidateJog(req); err != nil {
+		fmt.Fprintf(stderr, "pango calibrate: %v\n", err)
+		return 2
+	}
+	if math.IsNaN(*measured) || math.IsInf(*measured, 0) || *measured < 0 || (*measured == 0 && *save) {
+		fmt.Fprintf(stderr, "pango calibrate: -measured must be a positive angle\n")
+		return 2
+	}
+
+	f := &configFlags{path: *cfgPath}
+	if *measured == 0 {
+		return withRig(f, fs.Name(), stderr, func(_ *config.Config, r *rig) error {
+			err := r.jogger()(ctx, req)
+			r.park()
+			if err == nil {
+				fmt.Fprintf(stdout, "Turned %s by %g°. Measure the angle it really turned, then run:\n  pango calibrate -config %s -axis %s -deg %g -measured <angle>\n", *axis, *deg, *cfgPath, *axis, *deg)
+			}
+			return err
+		})
+	}
+
+	cfg, err := f.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "pango calibrate: %v\n", err)
+		return 1
+	}
+	section, stepper := "pan_stepper", cfg.PanStepper
+	if *axis == "tilt" {
+		section, stepper = "tilt_stepper", cfg.TiltStepper
+	}
+	stepsPerRev := calibratedStepsPerRev(stepper, *deg, *measured)
+	fmt.Fprintf(stdout, "%s.steps_per_rev: %d (configured %d)\n", section, stepsPerRev, stepper.StepsPerRev)
+	if !*save || stepsPerRev == stepper.StepsPerRev {
+		return 0
+	}
+	if dryRun {
+		fmt.Fprintf(stdout, "Dry run: not saved to %s.\n", *cfgPath)
+		return 0
+	}
+	if err := config.SetValues(*cfgPath, "", map[string]map[string]any{section: {"steps_per_rev": stepsPerRev}}); err != nil {
+		fmt.Fprintf(stderr, "pango calibrate: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Saved to %s.\n", *cfgPath)
+	return 0
+}
+
+// calibratedStepsPerRev returns the steps_per_rev that turns an axis
+// configured as s by deg, when it turned by measured: the microsteps made
+// for deg, as jog counts them, scaled to a full turn.
+func calibratedStepsPerRev(s config.StepperConfig, deg, measured float64) int {
+	microsteps := math.Trunc(deg * float64(s.StepsPerRev*s.Microstepping) / 360)
+	return int(math.Round(math.Abs(microsteps) * 360 / measured / float64(s.Microstepping)))
+}
+
+// runLegacy runs pango with the flags it read before subcommands existed:
+// it serves with -web or web.port, or runs one capture.
+func runLegacy(ctx context.Context, args []string, stderr io.Writer) int {
+	fs := newFlagSet("", pangoUsage+"\nFlags without a command:\n", stderr)
+	webPort := &webPortFlag{defaultPort: defaultWebPort}
+	fs.Var(webPort, "web", fmt.Sprintf("start web server on port; -web= for default %d, -web 8980 for custom port (overrides web.port)", defaultWebPort))
+	f := addConfigFlags(fs, true)
+	var opts serveOptions
+	fs.BoolVar(&opts.watchConfig, "watch-config", false, "in web mode, reload the config file when it changes (also reloaded on SIGHUP)")
+	fs.StringVar(&opts.staticDir, "static-dir", "", "serve the web interface files from this directory, falling back to the built-in ones (overrides web.static_dir)")
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	opts.port = webPort.port()
+	return withRig(f, fs.Name(), stderr, func(cfg *config.Config, r *rig) error {
+		if opts.port > 0 || cfg.Web.Port > 0 {
+			return serve(ctx, cfg, f.path, r, opts)
+		}
+		return runOnce(ctx, cfg, r)
+	})
+}
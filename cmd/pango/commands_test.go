@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// withHeadPath keeps the head position of the test in a temporary file.
+func withHeadPath(t *testing.T) {
+	t.Helper()
+	old := headPath
+	headPath = filepath.Join(t.TempDir(), "head.json")
+	t.Cleanup(func() { headPath = old })
+}
+
+// ---------- runPango ----------
+
+func TestRunPango_Usage(t *testing.T) {
+	for _, args := range [][]string{{"help"}, {"-h"}} {
+		var out, errOut bytes.Buffer
+		if code := runPango(context.Background(), args, &out, &errOut); code != 0 {
+			t.Errorf("%v: exit status = %d", args, code)
+		}
+		if !strings.Contains(out.String(), "Usage: pango <command>") {
+			t.Errorf("%v: output = %q", args, out.String())
+		}
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runPango(context.Background(), []string{"shoot"}, &out, &errOut); code != 2 {
+		t.Errorf("unknown command: exit status = %d, want 2", code)
+	}
+	if !strings.Contains(errOut.String(), `unknown command "shoot"`) {
+		t.Errorf("stderr = %q", errOut.String())
+	}
+}
+
+func TestRunPango_UsageErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+	}{
+		{"unknown_flag", []string{"run", "-web", "8080"}},
+		{"extra_argument", []string{"plan", "now"}},
+		{"bad_port", []string{"serve", "-port", "70000"}},
+		{"bad_override", []string{"plan", "-horizontal_angle_deg", "400"}},
+		{"jog_bad_axis", []string{"jog", "-axis", "roll", "-deg", "5"}},
+		{"calibrate_save_unmeasured", []string{"calibrate", "-save"}},
+		{"legacy_unknown_flag", []string{"-port", "8080"}},
+		{"remote_status_argument", []string{"remote", "status", "now"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			code := runPango(context.Background(), tc.args, &out, &errOut)
+			if tc.name == "bad_override" {
+				if code != 1 || !strings.Contains(errOut.String(), "invalid override") {
+					t.Errorf("exit status = %d, stderr = %q", code, errOut.String())
+				}
+				return
+			}
+			if code != 2 {
+				t.Errorf("exit status = %d, want 2; stderr = %q", code, errOut.String())
+			}
+		})
+	}
+}
+
+// ---------- pango -dry-run ----------
+
+func TestRunPango_DryRun(t *testing.T) {
+	withHeadPath(t)
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", func(cfg *config.Config) {
+		cfg.Defaults.MockGPIO = false
+	})
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	ctx := context.Background()
+
+	var out, errOut bytes.Buffer
+	if code := runPango(ctx, []string{"-dry-run", "jog", "-config", path, "-axis", "pan", "-steps", "12"}, &out, &errOut); code != 0 {
+		t.Fatalf("jog: exit status = %d: %s", code, errOut.String())
+	}
+	for _, want := range []string{"dry run: GPIO 17 set up as output", "dry run: GPIO 17: 12 pulses", "dry run: head position (pan 12, tilt 0 steps from home) not saved"} {
+		if !strings.Contains(logged.String(), want) {
+			t.Errorf("log does not contain %q:\n%s", want, logged.String())
+		}
+	}
+	if _, err := os.Stat(headPath); !os.IsNotExist(err) {
+		t.Errorf("head position saved in a dry run: %v", err)
+	}
+	if dryRun {
+		t.Error("dryRun left set")
+	}
+
+	out.Reset()
+	if code := runPango(ctx, []string{"--dry-run", "calibrate", "-config", path, "-measured", "300", "-save"}, &out, &errOut); code != 0 {
+		t.Fatalf("calibrate: exit status = %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "Dry run: not saved") {
+		t.Errorf("calibrate output = %q", out.String())
+	}
+	if cfg, err := config.Load(path); err != nil || cfg.PanStepper.StepsPerRev != 200 {
+		t.Errorf("config changed by a dry run: %v", err)
+	}
+}
+
+// ---------- pango jog / home ----------
+
+func TestRunJogAndHome(t *testing.T) {
+	withHeadPath(t)
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", nil)
+	ctx := context.Background()
+
+	var out, errOut bytes.Buffer
+	if code := runPango(ctx, []string{"jog", "-config", path, "-axis", "pan", "-deg", "10"}, &out, &errOut); code != 0 {
+		t.Fatalf("jog pan: exit status = %d: %s", code, errOut.String())
+	}
+	if code := runPango(ctx, []string{"jog", "-config", path, "-axis", "tilt", "-steps", "-40"}, &out, &errOut); code != 0 {
+		t.Fatalf("jog tilt: exit status = %d: %s", code, errOut.String())
+	}
+	// 10° of a 200 × 16 microsteps turn
+	if got, want := loadHeadPosition(), (capture.Position{PanSteps: 88, TiltSteps: -40}); got != want {
+		t.Errorf("head position after jogs = %+v, want %+v", got, want)
+	}
+
+	if code := runPango(ctx, []string{"home", "-config", path}, &out, &errOut); code != 0 {
+		t.Fatalf("home: exit status = %d: %s", code, errOut.String())
+	}
+	if got := loadHeadPosition(); got != (capture.Position{}) {
+		t.Errorf("head position after home = %+v, want home", got)
+	}
+}
+
+func TestRunHome_Set(t *testing.T) {
+	withHeadPath(t)
+	if err := saveHeadPosition(capture.Position{PanSteps: 120}); err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+	if code := runPango(context.Background(), []string{"home", "-set"}, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d: %s", code, errOut.String())
+	}
+	if got := loadHeadPosition(); got != (capture.Position{}) {
+		t.Errorf("head position = %+v, want home", got)
+	}
+}
+
+func TestRig_ReconfigureKeepsHeadPosition(t *testing.T) {
+	withHeadPath(t)
+	r := &rig{gpio: &pinRecorder{}, start: capture.Position{PanSteps: 10}}
+	if err := r.setup(newTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.pan.MoveSteps(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.reconfigure(newTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	r.park()
+	if got := loadHeadPosition(); got.PanSteps != 15 {
+		t.Errorf("saved pan position = %d, want 15", got.PanSteps)
+	}
+}
+
+// ---------- pango calibrate ----------
+
+func TestCalibratedStepsPerRev(t *testing.T) {
+	s := config.StepperConfig{StepsPerRev: 200, Microstepping: 16}
+	cases := []struct {
+		name          string
+		deg, measured float64
+		want          int
+	}{
+		{"exact", 360, 360, 200},
+		{"short", 360, 300, 240},
+		{"long", 360, 400, 180},
+		{"half_turn", 180, 150, 240},
+		{"negative", -360, 300, 240},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := calibratedStepsPerRev(s, tc.deg, tc.measured); got != tc.want {
+				t.Errorf("calibratedStepsPerRev = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunCalibrate(t *testing.T) {
+	withHeadPath(t)
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", nil)
+	ctx := context.Background()
+
+	var out, errOut bytes.Buffer
+	if code := runPango(ctx, []string{"calibrate", "-config", path, "-axis", "tilt", "-deg", "90"}, &out, &errOut); code != 0 {
+		t.Fatalf("turn: exit status = %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "-measured <angle>") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	out.Reset()
+	if code := runPango(ctx, []string{"calibrate", "-config", path, "-axis", "tilt", "-deg", "90", "-measured", "75", "-save"}, &out, &errOut); code != 0 {
+		t.Fatalf("measured: exit status = %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "tilt_stepper.steps_per_rev: 240 (configured 200)") {
+		t.Errorf("output = %q", out.String())
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TiltStepper.StepsPerRev != 240 || cfg.PanStepper.StepsPerRev != 200 {
+		t.Errorf("steps_per_rev saved: pan %d, tilt %d", cfg.PanStepper.StepsPerRev, cfg.TiltStepper.StepsPerRev)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+)
+
+const completionUsage = `Usage: pango completion bash|zsh|fish
+
+Print the shell completion script of pango: commands, flags, -axis and
+-format values and the profiles of the -config file complete with Tab.
+Load it from the shell's startup file, e.g.:
+
+  bash  echo 'source <(pango completion bash)' >> ~/.bashrc
+  zsh   echo 'source <(pango completion zsh)' >> ~/.zshrc
+  fish  pango completion fish > ~/.config/fish/completions/pango.fish
+
+The scripts ask pango for the candidates, so they follow upgrades.
+`
+
+// completionScripts are the completion scripts by shell. They pass the
+// words of the command line, up to the one completed, to pango __complete
+// and fall back to file names when it prints nothing.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for pango
+_pango() {
+	local IFS=$'\n'
+	COMPREPLY=($("$1" __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _pango pango
+`,
+	"zsh": `#compdef pango
+# zsh completion for pango
+_pango() {
+	local -a candidates
+	candidates=(${(f)"$(${words[1]} __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+	if (( ${#candidates} )); then
+		compadd -a candidates
+	else
+		_files
+	fi
+}
+if [[ ${funcstack[1]} == _pango ]]; then
+	_pango "$@"
+else
+	compdef _pango pango
+fi
+`,
+	"fish": `# fish completion for pango
+function __pango_complete
+	set -l words (commandline -opc)
+	set -l candidates ($words[1] __complete $words[2..-1] (commandline -ct) 2>/dev/null)
+	if set -q candidates[1]
+		printf '%s\n' $candidates
+	else
+		__fish_complete_path (commandline -ct)
+	end
+end
+complete -c pango -f -a '(__pango_complete)'
+`,
+}
+
+// completionSubcommands are the words completed after the commands taking
+// a subcommand.
+var completionSubcommands = map[string][]string{
+	"config":     {"show"},
+	"remote":     {"run", "cancel", "status", "plan", "save"},
+	"completion": {"bash", "zsh", "fish"},
+	"gpio":       {"replay", "diff"},
+}
+
+// runCompletion implements "pango completion".
+func runCompletion(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pango completion", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, completionUsage)
+	}
+	if err := parseArgs(fs, args); err != nil {
+		return 2
+	}
+	script, ok := completionScripts[fs.Arg(0)]
+	if fs.NArg() != 1 || !ok {
+		fs.Usage()
+		return 2
+	}
+	fmt.Fprint(stdout, script)
+	return 0
+}
+
+// runComplete implements the hidden "pango __complete" the completion
+// scripts call: it prints the candidates for the last of args, one per
+// line.
+func runComplete(_ context.Context, args []string, stdout, _ io.Writer) int {
+	for _, c := range complete(args) {
+		fmt.Fprintln(stdout, c)
+	}
+	return 0
+}
+
+// complete returns the candidates for the last of words, the words of a
+// pango command line after "pango". No candidates means a file name.
+func complete(words []string) []string {
+	for len(words) > 1 {
+		if words[0] == "-dry-run" || words[0] == "--dry-run" {
+			words = words[1:]
+			continue
+		}
+		_, _, n, ok := pathFlag(words)
+		if !ok || n == len(words) {
+			break
+		}
+		words = words[n:]
+	}
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur, prev := words[len(words)-1], words[:len(words)-1]
+	if len(prev) == 0 && strings.HasPrefix(cur, "-") {
+		return withPrefix([]string{"-dry-run", "-log-file", "-record-gpio"}, cur)
+	}
+	if len(prev) == 1 && slices.Contains(pathFlags, strings.TrimLeft(prev[0], "-")) {
+		return nil // a file name
+	}
+	if len(prev) == 0 {
+		names := []string{"help"}
+		for _, c := range commands {
+			names = append(names, c.name)
+		}
+		return withPrefix(names, cur)
+	}
+
+	path := prev[:1]
+	subcommands := completionSubcommands[prev[0]]
+	for _, w := range prev[1:] {
+		if slices.Contains(subcommands, w) {
+			path = append(path, w)
+			break
+		}
+	}
+	fs := commandFlags(path)
+	if fs == nil {
+		// commands stopping before their flags, like config without show
+		fs = flag.NewFlagSet("", flag.ContinueOnError)
+	}
+
+	if strings.HasPrefix(cur, "-") {
+		var names []string
+		fs.VisitAll(func(f *flag.Flag) {
+			names = append(names, "-"+f.Name)
+		})
+		return withPrefix(names, cur)
+	}
+	if last := prev[len(prev)-1]; strings.HasPrefix(last, "-") && !strings.Contains(last, "=") {
+		name := strings.TrimLeft(last, "-")
+		if f := fs.Lookup(name); f != nil && !isBoolFlag(f) {
+			return withPrefix(flagValues(name, prev), cur)
+		}
+	}
+	if len(path) == 1 {
+		return withPrefix(subcommands, cur)
+	}
+	return nil
+}
+
+// commandFlags returns the flag set of the command named by path (e.g.
+// ["remote", "plan"]), or nil when it has none. The command stops at its
+// flags instead of running.
+func commandFlags(path []string) *flag.FlagSet {
+	var fs *flag.FlagSet
+	completingFlags = func(f *flag.FlagSet) { fs = f }
+	defer func() { completingFlags = nil }()
+	runPango(context.Background(), path, io.Discard, io.Discard)
+	return fs
+}
+
+// flagValues returns the values completing flag name, words being the
+// command line before the value.
+func flagValues(name string, words []string) []string {
+	switch name {
+	case "axis":
+		return []string{"pan", "tilt"}
+	case "format":
+		return []string{"yaml", "json"}
+	case "profile":
+		return profileCompletions(completionConfigPath(words))
+	}
+	return nil
+}
+
+// profileCompletions returns the profiles -profile accepts with the config
+// file path: the entries of its profiles: section, then the profiles of
+// its directory.
+func profileCompletions(path string) []string {
+	var names []string
+	if cfg, err := config.Load(path); err == nil {
+		names = cfg.ProfileNames()
+	}
+	if files, err := profileNames(filepath.Dir(path)); err == nil {
+		names = append(names, files...)
+	}
+	return names
+}
+
+// completionConfigPath returns the -config file given in words, or the
+// default one.
+func completionConfigPath(words []string) string {
+	for i, w := range words {
+		if !strings.HasPrefix(w, "-") {
+			continue
+		}
+		w = strings.TrimLeft(w, "-")
+		if path, ok := strings.CutPrefix(w, "config="); ok {
+			return path
+		}
+		if w == "config" && i+1 < len(words) {
+			return words[i+1]
+		}
+	}
+	return filepath.Join("configs", "default.yaml")
+}
+
+// isBoolFlag reports whether f takes no value, like -json.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// withPrefix returns the candidates starting with prefix.
+func withPrefix(candidates []string, prefix string) []string {
+	var out []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+// ---------- pango __complete ----------
+
+func TestComplete(t *testing.T) {
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", nil)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withProfiles := string(data) + "profiles:\n  macro:\n    lens:\n      focal_length_mm: 100\n"
+	if err := os.WriteFile(path, []byte(withProfiles), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writeValidateConfig(t, dir, "tele.yaml", nil)
+
+	cases := []struct {
+		name  string
+		words []string
+		want  string
+	}{
+		{"commands", []string{""}, "help serve run plan jog home calibrate validate config schema remote gpio completion version"},
+		{"command_prefix", []string{"s"}, "serve schema"},
+		{"dry_run", []string{"-d"}, "-dry-run"},
+		{"leading_flags", []string{"-"}, "-dry-run -log-file -record-gpio"},
+		{"record_gpio_value", []string{"-record-gpio", ""}, ""},
+		{"log_file_value", []string{"-log-file", ""}, ""},
+		{"after_log_file", []string{"-log-file", "pango.log", "-dry-run", "ca"}, "calibrate"},
+		{"after_dry_run", []string{"-dry-run", "ho"}, "home"},
+		{"flags_after_dry_run", []string{"-dry-run", "home", "-s"}, "-set"},
+		{"flags", []string{"run", "-"}, "-config -focal_length_mm -horizontal_angle_deg -move_speed_ms -overlap_percent -profile -shot_delay_ms -vertical_angle_deg"},
+		{"flag_prefix", []string{"jog", "-s"}, "-speed_ms -steps"},
+		{"axis", []string{"calibrate", "-axis", ""}, "pan tilt"},
+		{"format", []string{"config", "show", "-format", "j"}, "json"},
+		{"profiles", []string{"plan", "-config", path, "-profile", ""}, "macro rig rig/macro tele"},
+		{"profiles_config_equals", []string{"run", "--config=" + path, "-profile", "t"}, "tele"},
+		{"no_value_after_bool", []string{"plan", "-json", ""}, ""},
+		{"file_value", []string{"serve", "-config", ""}, ""},
+		{"subcommands", []string{"remote", ""}, "run cancel status plan save"},
+		{"subcommand_after_flags", []string{"remote", "-json", "st"}, "status"},
+		{"config_subcommand", []string{"config", ""}, "show"},
+		{"subcommand_flags", []string{"remote", "plan", "-f"}, "-focal_length_mm"},
+		{"global_flags", []string{"remote", "-c"}, "-control-token"},
+		{"shells", []string{"completion", ""}, "bash zsh fish"},
+		{"gpio_subcommands", []string{"gpio", ""}, "replay diff"},
+		{"gpio_diff_flags", []string{"gpio", "diff", "-"}, "-tolerance"},
+		{"files", []string{"validate", ""}, ""},
+		{"unknown_command", []string{"shoot", "-"}, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := strings.Join(complete(tc.words), " "); got != tc.want {
+				t.Errorf("complete(%q) = %q, want %q", tc.words, got, tc.want)
+			}
+			if completingFlags != nil {
+				t.Error("completingFlags left set")
+			}
+		})
+	}
+}
+
+func TestComplete_DoesNotRunCommands(t *testing.T) {
+	withHeadPath(t)
+	for _, words := range [][]string{{"run", ""}, {"home", ""}, {"remote", "status", ""}, {"remote", "cancel", ""}} {
+		complete(words)
+	}
+	if _, err := os.Stat(headPath); !os.IsNotExist(err) {
+		t.Errorf("head position written while completing: %v", err)
+	}
+}
+
+// ---------- pango completion ----------
+
+func TestRunCompletion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var out, errOut bytes.Buffer
+		if code := runPango(context.Background(), []string{"completion", shell}, &out, &errOut); code != 0 {
+			t.Fatalf("%s: exit status = %d: %s", shell, code, errOut.String())
+		}
+		if !strings.Contains(out.String(), "__complete") {
+			t.Errorf("%s script = %q", shell, out.String())
+		}
+	}
+
+	for _, args := range [][]string{{"completion"}, {"completion", "tcsh"}, {"completion", "bash", "zsh"}} {
+		var out, errOut bytes.Buffer
+		if code := runPango(context.Background(), args, &out, &errOut); code != 2 {
+			t.Errorf("%v: exit status = %d, want 2", args, code)
+		}
+	}
+
+	var out, errOut bytes.Buffer
+	runPango(context.Background(), []string{"__complete", "jog", "-axis", "t"}, &out, &errOut)
+	if out.String() != "tilt\n" {
+		t.Errorf("__complete output = %q", out.String())
+	}
+}
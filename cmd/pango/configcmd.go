@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/web"
+	"gopkg.in/yaml.v3"
+)
+
+const configUsage = `Usage: pango config show [flags]
+
+Print the configuration a capture would run with: the config file merged
+with its use: libraries and include: files, defaults filled in, PANGO_*
+variables, the profile and command line overrides applied. YAML output
+starts with comments naming the files merged, in order, and the variables
+and overrides applied. Credentials are redacted; secret references are
+shown as written. Nothing is moved or triggered.
+
+Flags:
+  -config path               config file (default configs/default.yaml)
+  -profile name              apply this profile, as when running pango: an
+                             entry of the profiles: section, a config file
+                             next to -config, or file/entry
+  -format yaml|json          output format (default yaml); JSON is the
+                             document of GET /config/effective
+  -horizontal_angle_deg n, -vertical_angle_deg n, -focal_length_mm n,
+  -overlap_percent n, -shot_delay_ms n, -move_speed_ms n
+                             overrides, as when running pango
+`
+
+// runConfig implements "pango config" and returns the exit status.
+func runConfig(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "show" {
+		fmt.Fprint(stderr, configUsage)
+		return 2
+	}
+	fs := flag.NewFlagSet("pango config show", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, configUsage)
+	}
+	cfgPath := fs.String("config", filepath.Join("configs", "default.yaml"), "")
+	profile := fs.String("profile", "", "")
+	format := fs.String("format", "yaml", "")
+	horizontal := fs.Float64("horizontal_angle_deg", 0, "")
+	vertical := fs.Float64("vertical_angle_deg", 0, "")
+	focal := fs.Float64("focal_length_mm", 0, "")
+	overlap := fs.Float64("overlap_percent", 0, "")
+	shotDelay := fs.Int("shot_delay_ms", 0, "")
+	moveSpeed := fs.Int("move_speed_ms", 0, "")
+	if err := parseArgs(fs, args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 || (*format != "yaml" && *format != "json") {
+		fs.Usage()
+		return 2
+	}
+	if err := validateCLIOverrides(*horizontal, *vertical, *focal); err != nil {
+		fmt.Fprintf(stderr, "pango config show: invalid override: %v\n", err)
+		return 2
+	}
+	if err := validateCLIPacing(*overlap, *shotDelay, *moveSpeed); err != nil {
+		fmt.Fprintf(stderr, "pango config show: invalid override: %v\n", err)
+		return 2
+	}
+
+	cfg, err := loadRunConfig(*cfgPath, *profile, web.Overrides{
+		HorizontalAngleDeg: *horizontal, VerticalAngleDeg: *vertical, FocalLengthMm: *focal,
+		OverlapPercent: *overlap, ShotDelayMs: *shotDelay, MoveSpeedMs: *moveSpeed,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "pango config show: %v\n", err)
+		return 1
+	}
+	effective, err := effectiveConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "pango config show: %v\n", err)
+		return 1
+	}
+
+	if *format == "json" {
+		data, err := json.MarshalIndent(effective, "", "  ")
+		if err != nil {
+			fmt.Fprintf(stderr, "pango config show: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "%s\n", data)
+		return 0
+	}
+	data, err := yaml.Marshal(effective.Config)
+	if err != nil {
+		fmt.Fprintf(stderr, "pango config show: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "# files merged: %s\n", strings.Join(effective.Sources, ", "))
+	if effective.Profile != "" {
+		fmt.Fprintf(stdout, "# profile: %s\n", effective.Profile)
+	}
+	if len(effective.Env) > 0 {
+		fmt.Fprintf(stdout, "# environment: %s\n", strings.Join(effective.Env, ", "))
+	}
+	var flags []string
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "config" && f.Name != "profile" && f.Name != "format" {
+			flags = append(flags, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	if len(flags) > 0 {
+		fmt.Fprintf(stdout, "# command line: %s\n", strings.Join(flags, " "))
+	}
+	for _, w := range effective.Warnings {
+		fmt.Fprintf(stdout, "# warning: %s\n", w)
+	}
+	stdout.Write(data)
+	return 0
+}
+
+// effectiveConfig describes cfg for GET /config/effective and pango config
+// show.
+func effectiveConfig(cfg *config.Config) (web.EffectiveConfig, error) {
+	doc, err := cfg.Effective()
+	if err != nil {
+		return web.EffectiveConfig{}, err
+	}
+	return web.EffectiveConfig{
+		Sources:  cfg.Sources,
+		Profile:  cfg.Profile,
+		Env:      cfg.EnvVars(),
+		Warnings: cfg.Warnings,
+		Config:   doc,
+	}, nil
+}
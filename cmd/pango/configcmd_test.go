@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// ---------- pango config show ----------
+
+func TestRunConfigShow(t *testing.T) {
+	t.Setenv("PANGO_DEFAULTS_OVERLAP_PERCENT", "40")
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", func(c *config.Config) {
+		c.Web.Auth.Token = "0123456789abcdef"
+		c.Profiles = map[string]map[string]any{"tele": {"lens": map[string]any{"focal_length_mm": 200}}}
+	})
+
+	var out, errOut bytes.Buffer
+	if code := runConfig([]string{"show", "-config", path, "-profile", "tele", "-horizontal_angle_deg", "270"}, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d: %s", code, errOut.String())
+	}
+	report := out.String()
+	for _, want := range []string{
+		"# files merged: " + path + "\n",
+		"# profile: tele\n",
+		"# environment: PANGO_DEFAULTS_OVERLAP_PERCENT\n",
+		"# command line: -horizontal_angle_deg=270\n",
+		"overlap_percent: 40\n",
+		"horizontal_angle_deg: 270\n",
+		"focal_length_mm: 200\n",
+		"inter_move_delay_ms: 500\n", // default filled in
+		"token: " + config.Redacted + "\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("output lacks %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "0123456789abcdef") {
+		t.Errorf("output shows the token:\n%s", report)
+	}
+}
+
+func TestRunConfigShow_JSON(t *testing.T) {
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", nil)
+	var out, errOut bytes.Buffer
+	if code := runConfig([]string{"show", "-format", "json", "-config", path}, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d: %s", code, errOut.String())
+	}
+	var effective web.EffectiveConfig
+	if err := json.Unmarshal(out.Bytes(), &effective); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+	}
+	if len(effective.Sources) != 1 || effective.Sources[0] != path || effective.Config["lens"] == nil {
+		t.Errorf("effective = %+v", effective)
+	}
+}
+
+func TestTracingHeadersRedacted(t *testing.T) {
+	const key = "otlp-api-key-0123456789"
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", func(c *config.Config) {
+		c.Tracing.Endpoint = "https://otlp.example/v1/traces"
+		c.Tracing.Headers = map[string]string{"x-api-key": key}
+	})
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	if code := runConfig([]string{"show", "-config", path}, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d: %s", code, errOut.String())
+	}
+	effective, err := effectiveConfig(cfg) // GET /config/effective
+	if err != nil {
+		t.Fatal(err)
+	}
+	live := newLiveConfig(cfg, path, func(*config.Config) error { return nil })
+	full, err := live.document() // GET /config/full
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, v := range map[string]any{"pango config show": out.String(), "effective": effective, "full": full} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), key) || !strings.Contains(string(data), "x-api-key") {
+			t.Errorf("%s: %s, want the header redacted", name, data)
+		}
+	}
+
+	// The document sent back keeps the key.
+	data, err := json.Marshal(full)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := live.update(data, false); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if got := live.get().Tracing.Headers["x-api-key"]; got != key {
+		t.Errorf("header after update = %q, want the current key", got)
+	}
+}
+
+func TestRunConfigShow_Errors(t *testing.T) {
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", nil)
+	cases := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"no_command", nil, 2},
+		{"unknown_command", []string{"edit"}, 2},
+		{"format", []string{"show", "-config", path, "-format", "toml"}, 2},
+		{"argument", []string{"show", "-config", path, "extra"}, 2},
+		{"override", []string{"show", "-config", path, "-vertical_angle_deg", "200"}, 2},
+		{"missing_file", []string{"show", "-config", filepath.Join(dir, "missing.yaml")}, 1},
+		{"unknown_profile", []string{"show", "-config", path, "-profile", "macro"}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if code := runConfig(tc.args, &out, &errOut); code != tc.code || out.Len() != 0 {
+				t.Errorf("exit status = %d (want %d), output %q", code, tc.code, out.String())
+			}
+		})
+	}
+}
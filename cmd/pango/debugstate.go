@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// headLock is the lock of the head, which also tells whether it is held,
+// for state dumps.
+type headLock struct {
+	mu   sync.Mutex
+	held atomic.Bool
+}
+
+func (l *headLock) Lock() {
+	l.mu.Lock()
+	l.held.Store(true)
+}
+
+func (l *headLock) TryLock() bool {
+	if !l.mu.TryLock() {
+		return false
+	}
+	l.held.Store(true)
+	return true
+}
+
+func (l *headLock) Unlock() {
+	l.held.Store(false)
+	l.mu.Unlock()
+}
+
+// Held reports whether the head is in use.
+func (l *headLock) Held() bool {
+	return l.held.Load()
+}
+
+// headState returns the state of the head for web.DebugState, without
+// taking it.
+func (r *rig) headState() web.HeadState {
+	state := web.HeadState{
+		Busy:           r.busy.Held(),
+		PanMovedSteps:  r.panMoved.Load(),
+		TiltMovedSteps: r.tiltMoved.Load(),
+	}
+	if pos, ok := r.position(); ok {
+		state.PanSteps, state.TiltSteps = pos.PanSteps, pos.TiltSteps
+		state.PanDeg, state.TiltDeg = pos.PanDeg, pos.TiltDeg
+	}
+	return state
+}
+
+// dumpStateOnSignal logs the state returned by state on each SIGUSR1
+// (kill -USR1), until ctx is done: to see where a long run that seems hung
+// stands, with only a shell on the rig.
+func dumpStateOnSignal(ctx context.Context, state func() web.DebugState) {
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	go func() {
+		defer signal.Stop(usr1)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-usr1:
+				logState(state())
+			}
+		}
+	}()
+}
+
+// logState logs s as indented JSON.
+func logState(s web.DebugState) {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		log.Printf("state dump failed: %v", err)
+		return
+	}
+	log.Printf("state dump (SIGUSR1):\n%s", data)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+	"github.com/cjeanneret/PanGo/internal/hw/stepper"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// ---------- State dump (SIGUSR1) ----------
+
+// lockedLog is a log output read while it is written.
+type lockedLog struct {
+	mu sync.Mutex
+	sb strings.Builder
+}
+
+func (l *lockedLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sb.Write(p)
+}
+
+func (l *lockedLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.sb.String()
+}
+
+func TestRig_HeadState(t *testing.T) {
+	stepperCfg := stepper.Config{StepPin: 1, DirPin: 2, StepDelay: time.Microsecond}
+	r := &rig{hw: newTestConfig()}
+	if got := r.headState(); got != (web.HeadState{}) {
+		t.Errorf("head state without motors = %+v", got)
+	}
+	r.pan = stepper.NewStepper(&gpio.MockDriver{}, stepperCfg)
+	r.tilt = stepper.NewStepper(&gpio.MockDriver{}, stepperCfg)
+	if err := r.pan.MoveSteps(120); err != nil {
+		t.Fatal(err)
+	}
+	r.panMoved.Add(120)
+	r.busy.Lock()
+	got := r.headState()
+	r.busy.Unlock()
+	if !got.Busy || got.PanSteps != 120 || got.PanDeg <= 0 || got.PanMovedSteps != 120 || got.TiltSteps != 0 {
+		t.Errorf("head state = %+v, want busy at pan 120 steps", got)
+	}
+	if r.headState().Busy {
+		t.Error("head still busy after Unlock")
+	}
+	if !r.busy.TryLock() || r.busy.TryLock() || !r.busy.Held() {
+		t.Error("TryLock does not lock the head once")
+	}
+	r.busy.Unlock()
+}
+
+func TestDumpStateOnSignal(t *testing.T) {
+	var logged lockedLog
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dumpStateOnSignal(ctx, func() web.DebugState {
+		return web.DebugState{Goroutines: 7, Head: &web.HeadState{PanSteps: 1200}}
+	})
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGUSR1); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(logged.String(), `"pan_steps": 1200`) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, want := range []string{"state dump (SIGUSR1):", `"goroutines": 7`, `"pan_steps": 1200`} {
+		if !strings.Contains(logged.String(), want) {
+			t.Errorf("log does not contain %q:\n%s", want, logged.String())
+		}
+	}
+}
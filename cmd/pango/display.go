@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hw/ssd1306"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// statusDisplay shows the status of the rig on an OLED display until
+// closed.
+type statusDisplay struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Close stops updating the display and turns it off.
+func (d *statusDisplay) Close() {
+	d.cancel()
+	<-d.done
+}
+
+// openDisplay starts showing the status of r, the web address of h and the
+// menu of panel (nil when there is none) on the display of cfg
+// (display.bus). It returns nil when none is set or the GPIO is mocked;
+// stop it with Close.
+func openDisplay(cfg *config.Config, r *rig, h *web.Handlers, panel *web.Panel) (*statusDisplay, error) {
+	c := cfg.Display
+	if c.Bus == "" {
+		return nil, nil
+	}
+	if cfg.Defaults.MockGPIO {
+		log.Printf("mock GPIO: display on %s ignored", c.Bus)
+		return nil, nil
+	}
+	bus, err := ssd1306.OpenI2C(c.Bus, c.Address)
+	if err != nil {
+		return nil, fmt.Errorf("display: %w", err)
+	}
+	oled, err := ssd1306.New(bus, c.Height, c.Rotate)
+	if err != nil {
+		bus.Close()
+		return nil, fmt.Errorf("display: %s at %#x: %w", c.Bus, c.Address, err)
+	}
+
+	status := func() ([]string, int) {
+		st := capture.Status{State: capture.StateIdle}
+		if r.lifecycle != nil {
+			st = r.lifecycle.Snapshot()
+		}
+		pos, ok := r.position()
+		addrs, _ := net.InterfaceAddrs()
+		var menu *web.PanelMenu
+		if panel != nil {
+			m := panel.Menu()
+			menu = &m
+		}
+		return statusLines(st, pos, ok, h.PublicURL, localIPv4(addrs), menu, oled.Rows())
+	}
+	var changed <-chan struct{}
+	if panel != nil {
+		changed = panel.Changed()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &statusDisplay{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(d.done)
+		defer bus.Close()
+		showStatus(ctx, oled, time.Duration(c.RefreshMs)*time.Millisecond, status, changed)
+	}()
+	log.Printf("display: showing the status on %s at %#x", c.Bus, c.Address)
+	return d, nil
+}
+
+// showStatus draws the lines of status on oled every period, and at once
+// when changed is signalled, until ctx is cancelled; it then turns oled off.
+// Write errors are logged, the first of consecutive ones only, and drawing
+// goes on: a loose connector may come back.
+func showStatus(ctx context.Context, oled *ssd1306.Display, period time.Duration, status func() ([]string, int), changed <-chan struct{}) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	failing := false
+	for {
+		err := oled.Lines(status())
+		if err != nil && !failing {
+			log.Printf("display: %v", err)
+		}
+		failing = err != nil
+		select {
+		case <-ctx.Done():
+			oled.Clear()
+			if err := errors.Join(oled.Show(), oled.Off()); err != nil {
+				log.Printf("display: %v", err)
+			}
+			return
+		case <-ticker.C:
+		case <-changed:
+		}
+	}
+}
+
+// statusLines returns the text of a display of rows lines: the capture
+// state and progress, the head position, the web address (with the IP
+// address when the URL has a name) and, with a menu, as many of its items
+// around the selected one as fit, then its message. The second value is
+// the index of the selected item among the lines, -1 without a menu.
+func statusLines(st capture.Status, pos capture.HeadPosition, havePos bool, url, ip string, menu *web.PanelMenu, rows int) ([]string, int) {
+	if st.State == "" {
+		st.State = capture.StateIdle
+	}
+	state := strings.ToUpper(string(st.State[:1])) + string(st.State[1:])
+	progress := ""
+	switch {
+	case st.State == capture.StateError:
+		progress = st.LastError
+	case st.State == capture.StateIdle:
+		progress = "Ready"
+	case st.ShotsTotal > 0:
+		progress = fmt.Sprintf("Shot %d/%d", st.ShotsDone, st.ShotsTotal)
+	}
+	position := "Position unknown"
+	if havePos {
+		position = fmt.Sprintf("Pan %.1f Tilt %.1f", pos.PanDeg, pos.TiltDeg)
+	}
+	address := "No network"
+	if url != "" {
+		_, rest, ok := strings.Cut(url, "://")
+		if !ok {
+			rest = url
+		}
+		address = strings.TrimSuffix(rest, "/")
+	}
+
+	var lines []string
+	if rows >= 8 {
+		lines = []string{state, progress, position, address}
+		if ip != "" && !strings.Contains(url, ip) {
+			lines = append(lines, "IP "+ip)
+		}
+	} else {
+		if progress != "" && st.State != capture.StateIdle {
+			state += " " + strings.TrimPrefix(progress, "Shot ")
+		}
+		lines = []string{state, position, address}
+	}
+
+	free := rows - len(lines)
+	if menu == nil || len(menu.Items) == 0 || free <= 0 {
+		return lines, -1
+	}
+	showMessage := menu.Message != "" && free >= 2
+	if showMessage {
+		free--
+	}
+	start := max(0, menu.Selected-free+1)
+	end := min(len(menu.Items), start+free)
+	selected := len(lines) + menu.Selected - start
+	lines = append(lines, menu.Items[start:end]...)
+	if showMessage {
+		for len(lines) < rows-1 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, menu.Message)
+	}
+	return lines, selected
+}
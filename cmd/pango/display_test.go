@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hw/ssd1306"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+func TestStatusLines(t *testing.T) {
+	shooting := capture.Status{State: capture.StateShooting, ShotsDone: 23, ShotsTotal: 84}
+	pos := capture.HeadPosition{PanDeg: 120.25, TiltDeg: -12.5}
+	menu := &web.PanelMenu{Items: []string{"Cancel capture", "Pause"}, Selected: 1, Message: "Capture started"}
+	long := &web.PanelMenu{Items: []string{"Start capture", "Preset: a", "Preset: b", "Preset: c *", "Preset: d"}, Selected: 4}
+
+	cases := []struct {
+		name     string
+		st       capture.Status
+		pos      capture.HeadPosition
+		havePos  bool
+		url, ip  string
+		menu     *web.PanelMenu
+		rows     int
+		want     []string
+		selected int
+	}{
+		{"shooting", shooting, pos, true, "http://192.168.1.20:8080/", "192.168.1.20", nil, 8,
+			[]string{"Shooting", "Shot 23/84", "Pan 120.2 Tilt -12.5", "192.168.1.20:8080"}, -1},
+		{"mdns", capture.Status{State: capture.StateIdle}, pos, false, "http://pango.local:8080/", "192.168.1.20", menu, 8,
+			[]string{"Idle", "Ready", "Position unknown", "pango.local:8080", "IP 192.168.1.20", "Cancel capture", "Pause", "Capture started"}, 6},
+		{"scrolled", capture.Status{State: capture.StateError, LastError: "homing failed"}, capture.HeadPosition{}, true, "", "", long, 8,
+			[]string{"Error", "homing failed", "Pan 0.0 Tilt 0.0", "No network", "Preset: a", "Preset: b", "Preset: c *", "Preset: d"}, 7},
+		{"small", shooting, pos, true, "https://pango.local/", "", menu, 4,
+			[]string{"Shooting 23/84", "Pan 120.2 Tilt -12.5", "pango.local", "Pause"}, 3},
+		{"small_idle", capture.Status{}, pos, false, "", "", nil, 4,
+			[]string{"Idle", "Position unknown", "No network"}, -1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			lines, selected := statusLines(tc.st, tc.pos, tc.havePos, tc.url, tc.ip, tc.menu, tc.rows)
+			if !reflect.DeepEqual(lines, tc.want) || selected != tc.selected {
+				t.Errorf("lines = %q (selected %d), want %q (%d)", lines, selected, tc.want, tc.selected)
+			}
+		})
+	}
+}
+
+// i2cRecorder records the transactions written to a display.
+type i2cRecorder struct {
+	mu     sync.Mutex
+	writes [][]byte
+}
+
+func (b *i2cRecorder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writes = append(b.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (b *i2cRecorder) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.writes)
+}
+
+func TestShowStatus(t *testing.T) {
+	b := &i2cRecorder{}
+	oled, err := ssd1306.New(b, 32, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	initWrites := b.count()
+
+	var mu sync.Mutex
+	text := "Idle"
+	status := func() ([]string, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return []string{text}, -1
+	}
+	changed := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		showStatus(ctx, oled, time.Hour, status, changed)
+		close(done)
+	}()
+
+	waitWrites := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for b.count() < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("%d writes, want %d", b.count(), n)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// A picture is the addressing command and a write per page.
+	waitWrites(initWrites + 5)
+	mu.Lock()
+	text = "Shooting"
+	mu.Unlock()
+	changed <- struct{}{}
+	waitWrites(initWrites + 10)
+
+	cancel()
+	<-done
+	if last := b.writes[len(b.writes)-1]; !reflect.DeepEqual(last, []byte{0x00, 0xae}) {
+		t.Errorf("last write = % x, want display off", last)
+	}
+}
+
+func TestOpenDisplay(t *testing.T) {
+	cfg := &config.Config{}
+	if d, err := openDisplay(cfg, &rig{}, nil, nil); d != nil || err != nil {
+		t.Fatalf("without bus: %v, %v", d, err)
+	}
+	cfg.Display = config.DisplayConfig{Bus: "/dev/i2c-1", Address: 0x3c, Height: 64, RefreshMs: 500}
+	cfg.Defaults.MockGPIO = true
+	if d, err := openDisplay(cfg, &rig{}, nil, nil); d != nil || err != nil {
+		t.Fatalf("with mock GPIO: %v, %v", d, err)
+	}
+	cfg.Defaults.MockGPIO = false
+	cfg.Display.Bus = t.TempDir() + "/i2c-9"
+	if _, err := openDisplay(cfg, &rig{}, nil, nil); err == nil {
+		t.Error("missing bus: want an error")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hw/gamepad"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// gamepadMover is the head of a rig jogged by a gamepad.
+type gamepadMover struct {
+	r *rig
+}
+
+// Jog moves one axis of the head like a web jog: refused while a capture
+// (or another jog) is using the head.
+func (m gamepadMover) Jog(ctx context.Context, axis string, steps, speedMs int) error {
+	if m.r.pan == nil || m.r.hw == nil {
+		return errors.New("hardware not set up")
+	}
+	return m.r.jogger()(ctx, web.JogRequest{Axis: axis, Steps: steps, SpeedMs: speedMs})
+}
+
+// Shoot takes a test shot with the camera, unless a capture (or a jog) is
+// using the head.
+func (m gamepadMover) Shoot(ctx context.Context) error {
+	r := m.r
+	if !r.busy.TryLock() {
+		return web.ErrHeadBusy
+	}
+	defer r.busy.Unlock()
+	if r.cam == nil {
+		return errors.New("camera not set up")
+	}
+	return r.cam.Shoot()
+}
+
+// openGamepad starts jogging the head of r with the gamepad of cfg
+// (gamepad.device). It returns nil when none is set; stop it with Close.
+func openGamepad(cfg *config.Config, r *rig) (*gamepad.Controller, error) {
+	g := cfg.Gamepad
+	if g.Device == "" {
+		return nil, nil
+	}
+	o := gamepad.Options{
+		Device:      g.Device,
+		PanAxis:     g.PanAxis,
+		TiltAxis:    g.TiltAxis,
+		InvertPan:   g.InvertPan,
+		InvertTilt:  g.InvertTilt,
+		DeadZone:    g.DeadZone,
+		Curve:       g.Curve,
+		SlowSpeedMs: g.SlowSpeedMs,
+		FastSpeedMs: g.FastSpeedMs,
+		ShootButton: g.ShootButton,
+	}
+	if o.FastSpeedMs == 0 {
+		o.FastSpeedMs = min(cfg.Defaults.MoveSpeedMs, o.SlowSpeedMs)
+	}
+	if o.ShootButton == "none" {
+		o.ShootButton = ""
+	}
+	c, err := gamepad.Open(o, gamepadMover{r})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("gamepad: jogging the head with %s (%s/%s)", g.Device, g.PanAxis, g.TiltAxis)
+	return c, nil
+}
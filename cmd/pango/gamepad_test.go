@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hw/camera"
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+	"github.com/cjeanneret/PanGo/internal/hw/stepper"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+func TestGamepadMover(t *testing.T) {
+	d := &gpio.MockDriver{}
+	var shots []string
+	r := &rig{
+		hw:   newTestConfig(),
+		pan:  stepper.NewStepper(d, stepper.Config{StepPin: 1, DirPin: 2, EnablePin: 3, StepDelay: time.Microsecond}),
+		tilt: stepper.NewStepper(d, stepper.Config{StepPin: 4, DirPin: 5, EnablePin: 6, StepDelay: time.Microsecond}),
+		cam:  camera.NewDryRun(func(format string, args ...any) { shots = append(shots, fmt.Sprintf(format, args...)) }),
+	}
+	m := gamepadMover{r}
+	ctx := context.Background()
+
+	if err := m.Jog(ctx, "pan", 32, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Jog(ctx, "tilt", -16, 0); err != nil {
+		t.Fatal(err)
+	}
+	if pos, _ := r.position(); pos.PanSteps != 32 || pos.TiltSteps != -16 {
+		t.Errorf("position = %+v, want 32, -16 steps", pos)
+	}
+	if err := m.Shoot(ctx); err != nil || len(shots) != 1 {
+		t.Errorf("Shoot = %v, %d shots", err, len(shots))
+	}
+
+	r.busy.Lock()
+	jogErr, shootErr := m.Jog(ctx, "pan", 32, 0), m.Shoot(ctx)
+	r.busy.Unlock()
+	if !errors.Is(jogErr, web.ErrHeadBusy) || !errors.Is(shootErr, web.ErrHeadBusy) {
+		t.Errorf("while busy: Jog = %v, Shoot = %v, want ErrHeadBusy", jogErr, shootErr)
+	}
+}
+
+func TestOpenGamepad(t *testing.T) {
+	cfg := &config.Config{Gamepad: config.GamepadConfig{PanAxis: "x", TiltAxis: "y", DeadZone: 0.15, Curve: 2, SlowSpeedMs: 20, ShootButton: "none"}}
+	cfg.Defaults.MoveSpeedMs = 2
+	if c, err := openGamepad(cfg, &rig{}); c != nil || err != nil {
+		t.Fatalf("without device: %v, %v", c, err)
+	}
+
+	// The gamepad may be plugged in later: a missing device is retried.
+	cfg.Gamepad.Device = filepath.Join(t.TempDir(), "event0")
+	c, err := openGamepad(cfg, &rig{})
+	if err != nil || c == nil {
+		t.Fatalf("missing device: %v, %v", c, err)
+	}
+	c.Close()
+
+	cfg.Gamepad.TiltAxis = "x"
+	if _, err := openGamepad(cfg, &rig{}); err == nil {
+		t.Error("same axes: no error")
+	}
+}
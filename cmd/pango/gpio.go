@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+)
+
+// recordGPIO is set by -record-gpio: the file recording the GPIO
+// operations of the command.
+var recordGPIO string
+
+// maxDifferences is the number of differences pango gpio diff prints.
+const maxDifferences = 20
+
+const gpioUsage = `Usage: pango gpio <command> [flags] <recording>...
+
+Replay or compare recordings of GPIO operations, made with
+"pango -record-gpio <path> <command>", e.g. of the same capture before and
+after a change, to catch regressions in motion timing or trigger sequences
+without hardware:
+
+  pango -dry-run -record-gpio before.gpio run
+  pango -dry-run -record-gpio after.gpio run
+  pango gpio diff before.gpio after.gpio
+
+Commands:
+  diff    compare the pin setups and writes of two recordings, and their
+          timing; exits with status 1 when they differ
+  replay  do the operations of a recording on the GPIO driver of the
+          config (real pins unless mock_gpio or -dry-run), with its timing
+
+Flags:
+  -tolerance d   diff: allowed timing difference between operations
+                 (default 1ms)
+  -speed x       replay: speed factor of the recorded timing; 0 replays
+                 at once (default 1)
+  -config path, -profile name
+                 replay: config of the GPIO driver
+`
+
+// openGPIO returns the GPIO driver of cfg: a mock logging the operations
+// with -dry-run, recorded to the file of -record-gpio.
+func openGPIO(cfg *config.Config) (gpio.Driver, error) {
+	var d gpio.Driver
+	if dryRun {
+		log.Print("dry run: mock GPIO and camera, hardware operations are logged")
+		d = gpio.NewDryRunDriver(log.Printf)
+	} else {
+		var err error
+		if d, err = gpio.NewDriver(cfg.Defaults.MockGPIO); err != nil {
+			return nil, fmt.Errorf("init GPIO failed: %w", err)
+		}
+	}
+	if recordGPIO == "" {
+		return d, nil
+	}
+	f, err := os.Create(recordGPIO)
+	if err != nil {
+		d.Close()
+		return nil, fmt.Errorf("GPIO recording: %w", err)
+	}
+	log.Printf("recording GPIO operations to %s", recordGPIO)
+	return gpio.NewRecorder(d, f), nil
+}
+
+// runGPIO implements "pango gpio" and returns the exit status.
+func runGPIO(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || !slices.Contains(completionSubcommands["gpio"], args[0]) {
+		fmt.Fprint(stderr, gpioUsage)
+		return 2
+	}
+	fs := flag.NewFlagSet("pango gpio "+args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, gpioUsage)
+	}
+	if args[0] == "diff" {
+		tolerance := fs.Duration("tolerance", time.Millisecond, "allowed timing difference between operations")
+		if err := parseArgs(fs, args[1:]); err != nil {
+			return 2
+		}
+		if fs.NArg() != 2 || *tolerance < 0 {
+			fs.Usage()
+			return 2
+		}
+		return gpioDiff(fs.Arg(0), fs.Arg(1), *tolerance, stdout, stderr)
+	}
+
+	f := addConfigFlags(fs, false)
+	speed := fs.Float64("speed", 1, "speed factor of the recorded timing; 0 replays at once")
+	if err := parseArgs(fs, args[1:]); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || *speed < 0 {
+		fs.Usage()
+		return 2
+	}
+	ops, err := readRecording(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", fs.Name(), err)
+		return 1
+	}
+	cfg, err := f.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", fs.Name(), err)
+		return 1
+	}
+	d, err := openGPIO(cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", fs.Name(), err)
+		return 1
+	}
+	err = gpio.Replay(ctx, d, ops, *speed)
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", fs.Name(), err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "replayed %d operations\n", len(ops))
+	return 0
+}
+
+// gpioDiff prints the differences between the recordings at oldPath and
+// newPath, and the pulses per pin when they differ. It returns 1 when the
+// recordings differ.
+func gpioDiff(oldPath, newPath string, tolerance time.Duration, stdout, stderr io.Writer) int {
+	oldOps, err := readRecording(oldPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "pango gpio diff: %v\n", err)
+		return 1
+	}
+	newOps, err := readRecording(newPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "pango gpio diff: %v\n", err)
+		return 1
+	}
+	diffs := gpio.Diff(oldOps, newOps, tolerance)
+	if len(diffs) == 0 {
+		fmt.Fprintln(stdout, "same operations and timing")
+		return 0
+	}
+	for i, d := range diffs {
+		if i == maxDifferences {
+			fmt.Fprintf(stdout, "... and %d more\n", len(diffs)-maxDifferences)
+			break
+		}
+		fmt.Fprintln(stdout, d)
+	}
+	oldPulses, newPulses := gpio.Pulses(oldOps), gpio.Pulses(newOps)
+	var pins []int
+	for pin := range oldPulses {
+		pins = append(pins, pin)
+	}
+	for pin := range newPulses {
+		if _, ok := oldPulses[pin]; !ok {
+			pins = append(pins, pin)
+		}
+	}
+	slices.Sort(pins)
+	for _, pin := range pins {
+		if oldPulses[pin] != newPulses[pin] {
+			fmt.Fprintf(stdout, "GPIO %d: %d pulses, was %d\n", pin, newPulses[pin], oldPulses[pin])
+		}
+	}
+	return 1
+}
+
+// readRecording reads the GPIO recording at path.
+func readRecording(path string) ([]gpio.Op, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ops, err := gpio.ReadRecording(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return ops, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ---------- -record-gpio / pango gpio ----------
+
+func TestRunPango_RecordGPIO(t *testing.T) {
+	withHeadPath(t)
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", nil)
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	ctx := context.Background()
+
+	recordings := t.TempDir()
+	jog := func(name, steps string) string {
+		t.Helper()
+		rec := filepath.Join(recordings, name)
+		var out, errOut bytes.Buffer
+		if code := runPango(ctx, []string{"-record-gpio", rec, "-dry-run", "jog", "-config", path, "-axis", "pan", "-steps", steps}, &out, &errOut); code != 0 {
+			t.Fatalf("jog: exit status = %d: %s", code, errOut.String())
+		}
+		return rec
+	}
+	before, same, longer := jog("before.gpio", "12"), jog("same.gpio", "12"), jog("longer.gpio", "14")
+	if recordGPIO != "" {
+		t.Error("recordGPIO left set")
+	}
+	if data, err := os.ReadFile(before); err != nil || !strings.Contains(string(data), " write 17 high\n") {
+		t.Fatalf("recording = %q, %v, want the pulses of GPIO 17", data, err)
+	}
+
+	cases := []struct {
+		name     string
+		args     []string
+		wantCode int
+		want     string
+	}{
+		{"same", []string{"gpio", "diff", "-tolerance", "1s", before, same}, 0, "same operations and timing"},
+		{"more_steps", []string{"gpio", "diff", "-tolerance", "1s", before, longer}, 1, "GPIO 17: 14 pulses, was 12"},
+		{"not_a_recording", []string{"gpio", "diff", before, path}, 1, ""},
+		{"one_recording", []string{"gpio", "diff", before}, 2, ""},
+		{"no_command", []string{"gpio"}, 2, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			if code := runPango(ctx, tc.args, &out, &errOut); code != tc.wantCode {
+				t.Fatalf("exit status = %d, want %d: %s%s", code, tc.wantCode, out.String(), errOut.String())
+			}
+			if !strings.Contains(out.String(), tc.want) {
+				t.Errorf("output = %q, want %q", out.String(), tc.want)
+			}
+		})
+	}
+
+	logged.Reset()
+	var out, errOut bytes.Buffer
+	if code := runPango(ctx, []string{"-dry-run", "gpio", "replay", "-config", path, "-speed", "0", longer}, &out, &errOut); code != 0 {
+		t.Fatalf("replay: exit status = %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(logged.String(), "dry run: GPIO 17: 14 pulses") {
+		t.Errorf("replay log does not contain the 14 pulses:\n%s", logged.String())
+	}
+}
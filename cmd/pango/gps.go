@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/debug"
+	"github.com/cjeanneret/PanGo/internal/gps"
+	"github.com/cjeanneret/PanGo/internal/session"
+)
+
+// openGPS starts reading the GPS receiver of cfg (gps.gpsd or gps.device).
+// It returns nil when none is set. Stop it with closeGPS.
+func openGPS(cfg *config.Config) (*gps.Receiver, error) {
+	if cfg.GPS.GPSD == "" && cfg.GPS.Device == "" {
+		return nil, nil
+	}
+	g, err := gps.Open(gps.Options{GPSD: cfg.GPS.GPSD, Device: cfg.GPS.Device, MaxAge: cfg.GPSMaxAge()})
+	if err != nil {
+		return nil, err
+	}
+	debug.Info("GPS", "gpsd", cfg.GPS.GPSD, "device", cfg.GPS.Device)
+	return g, nil
+}
+
+// closeGPS stops reading the receiver g.
+func closeGPS(g *gps.Receiver) {
+	if g != nil {
+		g.Close()
+	}
+}
+
+// location returns the position of the head from the GPS receiver of r,
+// nil without a recent fix.
+func (r *rig) location() *session.Location {
+	f, ok := r.gps.Fix()
+	if !ok {
+		return nil
+	}
+	l := &session.Location{LatitudeDeg: f.Latitude, LongitudeDeg: f.Longitude, Time: f.Time}
+	if f.Has3D {
+		l.AltitudeM = &f.Altitude
+	}
+	return l
+}
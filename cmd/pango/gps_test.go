@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenGPS(t *testing.T) {
+	cfg := newTestConfig()
+	if g, err := openGPS(cfg); g != nil || err != nil {
+		t.Fatalf("openGPS without gps = %v, %v, want nil, nil", g, err)
+	}
+
+	// A GPS module on a serial port: position and altitude.
+	cfg.GPS.Device = filepath.Join(t.TempDir(), "ttyS0")
+	cfg.GPS.MaxAgeMs = 10000
+	nmea := "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47\r\n" +
+		"$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A\r\n"
+	if err := os.WriteFile(cfg.GPS.Device, []byte(nmea), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := openGPS(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeGPS(g)
+	r := &rig{gps: g}
+	deadline := time.Now().Add(5 * time.Second)
+	for r.location() == nil && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	l := r.location()
+	if l == nil {
+		t.Fatal("no location")
+	}
+	if l.LatitudeDeg < 48.11 || l.LatitudeDeg > 48.12 || l.AltitudeM == nil || *l.AltitudeM != 545.4 ||
+		!l.Time.Equal(time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC)) {
+		t.Errorf("location = %+v", l)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// headPath is the file keeping the head position between runs of pango, in
+// steps from home (the position pango home returns to); "" = not kept.
+var headPath = defaultHeadPath()
+
+// defaultHeadPath returns the head position file: in the state directory
+// of the systemd service (StateDirectory=), else in the user cache.
+func defaultHeadPath() string {
+	if dirs := os.Getenv("STATE_DIRECTORY"); dirs != "" {
+		dir, _, _ := strings.Cut(dirs, ":")
+		return filepath.Join(dir, "head.json")
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "pango", "head.json")
+}
+
+// loadHeadPosition returns the head position saved by the last run, or
+// home when there is none.
+func loadHeadPosition() capture.Position {
+	var pos capture.Position
+	if headPath == "" {
+		return pos
+	}
+	data, err := os.ReadFile(headPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pos
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &pos)
+	}
+	if err != nil {
+		log.Printf("head position: %v; assuming the head is home", err)
+		return capture.Position{}
+	}
+	return pos
+}
+
+// saveHeadPosition keeps pos for the next run of pango.
+func saveHeadPosition(pos capture.Position) error {
+	if headPath == "" {
+		return nil
+	}
+	if dryRun {
+		log.Printf("dry run: head position (pan %d, tilt %d steps from home) not saved", pos.PanSteps, pos.TiltSteps)
+		return nil
+	}
+	data, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(headPath), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(headPath, data, 0o644)
+}
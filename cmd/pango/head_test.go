@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// ---------- head position ----------
+
+func TestHeadPosition_RoundTrip(t *testing.T) {
+	withHeadPath(t)
+	if got := loadHeadPosition(); got != (capture.Position{}) {
+		t.Errorf("without a file: %+v, want home", got)
+	}
+	want := capture.Position{PanSteps: -320, TiltSteps: 48}
+	if err := saveHeadPosition(want); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadHeadPosition(); got != want {
+		t.Errorf("loaded %+v, want %+v", got, want)
+	}
+
+	if err := os.WriteFile(headPath, []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadHeadPosition(); got != (capture.Position{}) {
+		t.Errorf("corrupt file: %+v, want home", got)
+	}
+}
+
+func TestDefaultHeadPath_StateDirectory(t *testing.T) {
+	t.Setenv("STATE_DIRECTORY", "/var/lib/pango:/var/lib/other")
+	if got, want := defaultHeadPath(), filepath.Join("/var/lib/pango", "head.json"); got != want {
+		t.Errorf("defaultHeadPath = %q, want %q", got, want)
+	}
+}
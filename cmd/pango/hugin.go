@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hugin"
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+)
+
+// huginLens returns the lens of the Hugin projects of captures made with
+// cfg: the fields of view of the calculator and the configured resolution.
+// ok is false without a sensor.
+func huginLens(cfg *config.Config) (lens hugin.Lens, ok bool) {
+	fov, err := geometry.NewFOVCalculator(cfg)
+	if err != nil {
+		return hugin.Lens{}, false
+	}
+	lens = hugin.Lens{HFOVDeg: fov.HorizontalFOV(), VFOVDeg: fov.VerticalFOV()}
+	if cfg.Resolution != nil {
+		lens.WidthPx, lens.HeightPx = cfg.Resolution.WidthPx, cfg.Resolution.HeightPx
+	}
+	return lens, true
+}
+
+// writePlanHugin writes to path the Hugin project of the capture cfg
+// plans: one image per planned shot, in shooting order, named by names.
+func writePlanHugin(path string, cfg *config.Config, names hugin.Namer) error {
+	if cfg.Defaults.Mode == config.ModeTimelapse {
+		return errors.New("Hugin project: timelapses are not panoramas")
+	}
+	lens, ok := huginLens(cfg)
+	if !ok {
+		return errors.New("Hugin project: sensor configuration is required for the field of view")
+	}
+	panoramas, err := planPanoramas(cfg)
+	if err != nil {
+		return err
+	}
+	if len(panoramas) > 1 {
+		return fmt.Errorf("Hugin project: the run makes %d panoramas, not one", len(panoramas))
+	}
+
+	steps := geometry.NewStepsCalculator(cfg)
+	project := hugin.Project{Lens: lens}
+	for i, cell := range panoramas[0].Plan.Cells() {
+		project.Images = append(project.Images, hugin.Image{
+			Name:     names.Name(i + 1),
+			YawDeg:   steps.PanAngleFromSteps(cell.PanSteps),
+			PitchDeg: steps.TiltAngleFromSteps(cell.TiltSteps),
+		})
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = project.Write(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+
+	"github.com/cjeanneret/PanGo/internal/indi"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+	"github.com/cjeanneret/PanGo/internal/logic/motion"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// indiMount is the head of a rig pointed by INDI clients.
+type indiMount struct {
+	r *rig
+}
+
+// Position returns the head angles from where it started.
+func (m indiMount) Position() (panDeg, tiltDeg float64, ok bool) {
+	pos, ok := m.r.position()
+	return pos.PanDeg, pos.TiltDeg, ok
+}
+
+// Goto drives the head to the angles at the configured speed, tilt first.
+// Like a jog, it is refused while a capture (or a jog) is using the head,
+// and the motors stay enabled afterwards to hold the pointing.
+func (m indiMount) Goto(ctx context.Context, panDeg, tiltDeg float64) error {
+	r := m.r
+	if !r.busy.TryLock() {
+		return web.ErrHeadBusy
+	}
+	defer r.busy.Unlock()
+	if r.pan == nil || r.hw == nil {
+		return errors.New("hardware not set up")
+	}
+
+	ctrl := motion.NewController(r.pan, r.tilt)
+	if err := ctrl.EnableMotors(); err != nil {
+		return err
+	}
+	stepsCalc := geometry.NewStepsCalculator(r.hw)
+	pan, tilt := ctrl.Position()
+	err := ctrl.MoveTiltContext(ctx, stepsCalc.TiltStepsFromAngle(tiltDeg)-tilt, 0)
+	if err == nil {
+		err = ctrl.MovePanContext(ctx, stepsCalc.PanStepsFromAngle(panDeg)-pan, 0)
+	}
+	if r.events != nil {
+		pan, tilt := ctrl.Position()
+		r.events(capture.EventMove, capture.Position{PanSteps: pan, TiltSteps: tilt})
+	}
+	return err
+}
+
+// indiSite returns the site of the head from the GPS receiver of r, if any.
+func (r *rig) indiSite() (indi.Site, bool) {
+	if r.gps == nil {
+		return indi.Site{}, false
+	}
+	l := r.location()
+	if l == nil {
+		return indi.Site{}, false
+	}
+	site := indi.Site{LatitudeDeg: l.LatitudeDeg, LongitudeDeg: l.LongitudeDeg}
+	if l.AltitudeM != nil {
+		site.ElevationM = *l.AltitudeM
+	}
+	return site, true
+}
+
+// serveINDI serves the head of r as an INDI telescope on address:port
+// until ctx is cancelled. INDI has no authentication: slews and syncs are
+// checked by h as local commands are (control networks, control claims).
+func serveINDI(ctx context.Context, r *rig, h *web.Handlers, address string, port int) error {
+	ln, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("indi: %w", err)
+	}
+	s := indi.NewServer(indiMount{r}, indi.Options{Version: buildInfo().Version, Site: r.indiSite, Allow: h.CheckControl})
+	go func() {
+		if err := s.Serve(ctx, ln); err != nil {
+			log.Printf("indi: %v", err)
+		}
+	}()
+	log.Printf("indi: serving the head as a telescope on %s (no authentication)", ln.Addr())
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+	"github.com/cjeanneret/PanGo/internal/hw/stepper"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+func TestINDIMount(t *testing.T) {
+	d := &gpio.MockDriver{}
+	r := &rig{
+		hw:   newTestConfig(),
+		pan:  stepper.NewStepper(d, stepper.Config{StepPin: 1, DirPin: 2, EnablePin: 3, StepDelay: time.Microsecond}),
+		tilt: stepper.NewStepper(d, stepper.Config{StepPin: 4, DirPin: 5, EnablePin: 6, StepDelay: time.Microsecond}),
+	}
+	m := indiMount{r}
+	if pan, tilt, ok := m.Position(); !ok || pan != 0 || tilt != 0 {
+		t.Fatalf("Position = %g, %g, %v, want 0, 0, true", pan, tilt, ok)
+	}
+	if err := m.Goto(context.Background(), 90, -10); err != nil {
+		t.Fatal(err)
+	}
+	// 3200 steps per turn: 1/8.9 degree per step.
+	if pan, tilt, _ := m.Position(); math.Abs(pan-90) > 0.12 || math.Abs(tilt+10) > 0.12 {
+		t.Errorf("Position = %g, %g, want 90, -10", pan, tilt)
+	}
+
+	r.busy.Lock()
+	err := m.Goto(context.Background(), 0, 0)
+	r.busy.Unlock()
+	if !errors.Is(err, web.ErrHeadBusy) {
+		t.Errorf("Goto while busy = %v, want ErrHeadBusy", err)
+	}
+
+	// The site comes from the GPS, when there is one.
+	if _, ok := r.indiSite(); ok {
+		t.Error("indiSite without a GPS: got a site")
+	}
+}
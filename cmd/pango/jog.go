@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+const jogUsage = `Usage: pango jog [-axis pan|tilt (-deg n | -steps n)] [flags]
+
+Move the head from where it is, to frame a capture. Signed values: positive
+is clockwise (pan) or up (tilt). Without -deg or -steps, pango jog is
+interactive, e.g. over SSH when no browser is at hand:
+
+  ←/→  ↑/↓   pan, tilt by the step size (also a/d, w/s)
+  + -        larger, smaller step size
+  c          mark the current position as the center of the next capture
+             (home)
+  g          go back to the center
+  q          quit
+
+The motors are disabled on exit and the head position is kept for pango
+home.
+
+Flags:
+`
+
+// jogTerminal is the terminal of the interactive jog.
+var jogTerminal = os.Stdin
+
+// runJog implements "pango jog".
+func runJog(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("jog", jogUsage, stderr)
+	f := addConfigFlags(fs, false)
+	var req web.JogRequest
+	fs.StringVar(&req.Axis, "axis", "pan", "axis to move: pan or tilt")
+	fs.Float64Var(&req.Degrees, "deg", 0, "angle to move, in degrees")
+	fs.IntVar(&req.Steps, "steps", 0, "motor steps to move, instead of -deg")
+	fs.IntVar(&req.SpeedMs, "speed_ms", 0, "delay between steps in ms (default defaults.move_speed_ms)")
+	if !parseFlags(fs, args) {
+		return 2
+	}
+
+	if req.Degrees == 0 && req.Steps == 0 {
+		if _, err := stty(jogTerminal, "-g"); err != nil {
+			fmt.Fprintf(stderr, "pango jog: give -deg or -steps, or run it in a terminal for the interactive mode\n")
+			return 2
+		}
+		return withRig(f, fs.Name(), stderr, func(_ *config.Config, r *rig) error {
+			restore, err := rawTerminal(jogTerminal)
+			if err != nil {
+				return err
+			}
+			ui := &jogUI{r: r, in: bufio.NewReader(jogTerminal), out: stdout, speedMs: req.SpeedMs, step: jogDefaultStep}
+			err = ui.run(ctx)
+			restore()
+			r.park()
+			return err
+		})
+	}
+
+	if err := web.ValidateJog(req); err != nil {
+		fmt.Fprintf(stderr, "pango jog: %v\n", err)
+		return 2
+	}
+	return withRig(f, fs.Name(), stderr, func(_ *config.Config, r *rig) error {
+		err := r.jogger()(ctx, req)
+		r.park()
+		return err
+	})
+}
+
+// rawTerminal puts the terminal f in raw mode, keys being read as typed
+// and not echoed, and returns the function restoring it.
+func rawTerminal(f *os.File) (func(), error) {
+	saved, err := stty(f, "-g")
+	if err != nil {
+		return nil, fmt.Errorf("not a terminal: %w", err)
+	}
+	if _, err := stty(f, "raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(f, saved) }, nil
+}
+
+// stty runs stty on the terminal f and returns its output.
+func stty(f *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = f
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// jogStepsDeg are the step sizes of the interactive jog, in degrees.
+var jogStepsDeg = []float64{0.1, 0.5, 1, 5, 15, 45}
+
+// jogDefaultStep is the step size the interactive jog starts with: 5°.
+const jogDefaultStep = 3
+
+// jogKey is a key of the interactive jog.
+type jogKey int
+
+const (
+	keyOther jogKey = iota
+	keyLeft
+	keyRight
+	keyUp
+	keyDown
+	keyLarger
+	keySmaller
+	keyCenter
+	keyGoCenter
+	keyQuit
+)
+
+// jogUI is the interactive jog: it reads keys from in and redraws the
+// head position on out, a terminal in raw mode.
+type jogUI struct {
+	r       *rig
+	in      *bufio.Reader
+	out     io.Writer
+	speedMs int    // delay between steps; 0 = configured speed
+	step    int    // index in jogStepsDeg
+	status  string // result of the last action
+}
+
+// run handles keys until q, Ctrl-C, the end of in or ctx is done.
+func (u *jogUI) run(ctx context.Context) error {
+	fmt.Fprint(u.out, "PanGo jog: ←/→ pan, ↑/↓ tilt, +/- step size, c mark center, g go to center, q quit\r\n")
+	for ctx.Err() == nil {
+		u.draw()
+		key, err := u.readKey()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if key == keyQuit {
+			break
+		}
+		if err := u.handle(ctx, key); err != nil {
+			u.status = err.Error()
+		}
+	}
+	u.draw()
+	fmt.Fprint(u.out, "\r\n")
+	return nil
+}
+
+// handle runs the action of key.
+func (u *jogUI) handle(ctx context.Context, key jogKey) error {
+	u.status = ""
+	step := jogStepsDeg[u.step]
+	switch key {
+	case keyLeft:
+		return u.jog(ctx, "pan", -step)
+	case keyRight:
+		return u.jog(ctx, "pan", step)
+	case keyUp:
+		return u.jog(ctx, "tilt", step)
+	case keyDown:
+		return u.jog(ctx, "tilt", -step)
+	case keyLarger:
+		u.step = min(u.step+1, len(jogStepsDeg)-1)
+	case keySmaller:
+		u.step = max(u.step-1, 0)
+	case keyCenter:
+		if err := u.r.markHome(); err != nil {
+			return err
+		}
+		u.status = "center marked"
+	case keyGoCenter:
+		if err := u.r.goHome(ctx, u.r.hw.MoveSpeed()); err != nil {
+			return err
+		}
+		u.status = "at center"
+	}
+	return nil
+}
+
+func (u *jogUI) jog(ctx context.Context, axis string, deg float64) error {
+	return u.r.jogger()(ctx, web.JogRequest{Axis: axis, Degrees: deg, SpeedMs: u.speedMs})
+}
+
+// readKey reads one key: arrows come as ESC [ A..D.
+func (u *jogUI) readKey() (jogKey, error) {
+	b, err := u.in.ReadByte()
+	if err != nil {
+		return keyOther, err
+	}
+	switch b {
+	case 'a', 'A':
+		return keyLeft, nil
+	case 'd', 'D':
+		return keyRight, nil
+	case 'w', 'W':
+		return keyUp, nil
+	case 's', 'S':
+		return keyDown, nil
+	case '+', '=':
+		return keyLarger, nil
+	case '-', '_':
+		return keySmaller, nil
+	case 'c', 'C':
+		return keyCenter, nil
+	case 'g', 'G':
+		return keyGoCenter, nil
+	case 'q', 'Q', 0x03, 0x04: // Ctrl-C, Ctrl-D
+		return keyQuit, nil
+	case 0x1b:
+		if next, err := u.in.ReadByte(); err != nil || (next != '[' && next != 'O') {
+			return keyOther, err
+		}
+		b, err := u.in.ReadByte()
+		if err != nil {
+			return keyOther, err
+		}
+		switch b {
+		case 'A':
+			return keyUp, nil
+		case 'B':
+			return keyDown, nil
+		case 'C':
+			return keyRight, nil
+		case 'D':
+			return keyLeft, nil
+		}
+	}
+	return keyOther, nil
+}
+
+// draw rewrites the status line: the head position from the center, the
+// step size and the result of the last action.
+func (u *jogUI) draw() {
+	u.r.busy.Lock()
+	pos := u.r.home()
+	u.r.busy.Unlock()
+	steps := geometry.NewStepsCalculator(u.r.hw)
+	line := fmt.Sprintf("pan %+7.1f° (%+d steps)  tilt %+6.1f° (%+d steps)  step %g°",
+		steps.PanAngleFromSteps(pos.PanSteps), pos.PanSteps, steps.TiltAngleFromSteps(pos.TiltSteps), pos.TiltSteps, jogStepsDeg[u.step])
+	if u.status != "" {
+		line += "  " + u.status
+	}
+	fmt.Fprintf(u.out, "\r\033[K%s", line)
+}
+
+// markHome makes the current head position home: the center captures start
+// from and pango home returns to.
+func (r *rig) markHome() error {
+	if !r.busy.TryLock() {
+		return web.ErrHeadBusy
+	}
+	defer r.busy.Unlock()
+	r.start = capture.Position{PanSteps: -r.pan.Position(), TiltSteps: -r.tilt.Position()}
+	return saveHeadPosition(capture.Position{})
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+	"github.com/cjeanneret/PanGo/internal/hw/stepper"
+)
+
+// newJogUI returns an interactive jog reading keys, over a mock rig.
+func newJogUI(keys string) (*jogUI, *bytes.Buffer) {
+	cfg := newTestConfig()
+	stepperCfg := stepper.Config{StepPin: 17, DirPin: 27, StepsPerRev: 200, Microstepping: 16}
+	r := &rig{
+		hw:   cfg,
+		pan:  stepper.NewStepper(&gpio.MockDriver{}, stepperCfg),
+		tilt: stepper.NewStepper(&gpio.MockDriver{}, stepperCfg),
+	}
+	var out bytes.Buffer
+	return &jogUI{r: r, in: bufio.NewReader(strings.NewReader(keys)), out: &out, speedMs: 1, step: jogDefaultStep}, &out
+}
+
+// ---------- interactive jog ----------
+
+func TestJogUI_Moves(t *testing.T) {
+	withHeadPath(t)
+	// right twice by 5°, larger step, up by 15°, smaller twice, left by 1°
+	ui, out := newJogUI("\x1b[C\x1b[C+\x1b[A--\x1b[Dq")
+	if err := ui.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	// 200 × 16 microsteps a turn: 5° = 44 steps, 15° = 133, 1° = 8
+	if got := ui.r.pan.Position(); got != 2*44-8 {
+		t.Errorf("pan position = %d, want %d", got, 2*44-8)
+	}
+	if got := ui.r.tilt.Position(); got != 133 {
+		t.Errorf("tilt position = %d, want 133", got)
+	}
+	lines := strings.Split(out.String(), "\r\033[K")
+	if last := lines[len(lines)-1]; !strings.Contains(last, "pan    +9.0° (+80 steps)  tilt  +15.0° (+133 steps)  step 1°") {
+		t.Errorf("last status line = %q", last)
+	}
+}
+
+func TestJogUI_Center(t *testing.T) {
+	withHeadPath(t)
+	// right, mark center, right twice, back to center
+	ui, out := newJogUI("dcddg")
+	if err := ui.run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := ui.r.pan.Position(); got != 44 {
+		t.Errorf("pan position = %d, want the marked center (44)", got)
+	}
+	if got := ui.r.home(); got.PanSteps != 0 {
+		t.Errorf("position from center = %+v, want 0", got)
+	}
+	if got := loadHeadPosition(); got.PanSteps != 0 {
+		t.Errorf("saved position = %+v, want the center", got)
+	}
+	for _, want := range []string{"center marked", "at center"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+}
+
+func TestJogUI_ReadKey(t *testing.T) {
+	cases := []struct {
+		in   string
+		want jogKey
+	}{
+		{"\x1b[A", keyUp},
+		{"\x1b[B", keyDown},
+		{"\x1b[C", keyRight},
+		{"\x1b[D", keyLeft},
+		{"\x1bOA", keyUp}, // application cursor mode
+		{"w", keyUp},
+		{"s", keyDown},
+		{"a", keyLeft},
+		{"d", keyRight},
+		{"+", keyLarger},
+		{"-", keySmaller},
+		{"c", keyCenter},
+		{"g", keyGoCenter},
+		{"q", keyQuit},
+		{"\x03", keyQuit},
+		{"x", keyOther},
+		{"\x1b[Z", keyOther},
+	}
+	for _, tc := range cases {
+		ui, _ := newJogUI(tc.in)
+		if got, err := ui.readKey(); err != nil || got != tc.want {
+			t.Errorf("readKey(%q) = %v, %v; want %v", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestRunJog_NotATerminal(t *testing.T) {
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+	old := jogTerminal
+	jogTerminal = null
+	defer func() { jogTerminal = old }()
+
+	var out, errOut bytes.Buffer
+	if code := runPango(context.Background(), []string{"jog"}, &out, &errOut); code != 2 {
+		t.Errorf("exit status = %d, want 2", code)
+	}
+	if !strings.Contains(errOut.String(), "interactive mode") {
+		t.Errorf("stderr = %q", errOut.String())
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+
+	"github.com/cjeanneret/PanGo/internal/debug"
+	"github.com/cjeanneret/PanGo/internal/systemd"
+)
+
+// journal is the systemd journal when pango runs as a service whose output
+// goes to it (see useJournal); nil otherwise.
+var journal *systemd.Journal
+
+// useJournal sends the log and the debug output natively to the journal
+// when stderr is connected to it, so that journalctl shows their priority
+// and the debug record attributes as fields. It returns a function closing
+// the journal; outside systemd, it does nothing.
+func useJournal() (closeJournal func()) {
+	if !systemd.JournalStream() {
+		return func() {}
+	}
+	j, err := systemd.OpenJournal("pango")
+	if err != nil {
+		log.Printf("%v; logging to stderr", err)
+		return func() {}
+	}
+	journal = j
+	flags := log.Flags()
+	log.SetFlags(0) // the journal has the time
+	log.SetOutput(j)
+	return func() {
+		log.SetFlags(flags)
+		log.SetOutput(os.Stderr)
+		journal = nil
+		j.Close()
+	}
+}
+
+// setDebugOutput sends the debug output to the terminal, or the journal,
+// and to ws.
+func setDebugOutput(ws ...io.Writer) {
+	if journal != nil {
+		debug.SetOutput(io.MultiWriter(ws...), journal.Handler())
+		return
+	}
+	debug.SetOutput(io.MultiWriter(append([]io.Writer{os.Stdout}, ws...)...))
+}
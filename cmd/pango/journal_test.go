@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/debug"
+)
+
+// ---------- journald ----------
+
+func TestUseJournal_NotUnderSystemd(t *testing.T) {
+	t.Setenv("JOURNAL_STREAM", "")
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	closeJournal := useJournal()
+	defer closeJournal()
+	if journal != nil || log.Writer() != &logged {
+		t.Fatal("journal used outside systemd")
+	}
+
+	debug.Init(debug.LevelInfo)
+	t.Cleanup(func() { debug.Init(debug.LevelOff) })
+	var extra bytes.Buffer
+	setDebugOutput(&extra)
+	debug.Info("configuration", "profile", "field")
+	if !strings.Contains(extra.String(), `msg=configuration module=pango profile=field`) {
+		t.Errorf("debug output = %q", extra.String())
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/logfile"
+)
+
+// logFile is set by -log-file: the log file of the command, instead of
+// log.file.
+var logFile string
+
+// logTerminal is where the log went before openLogFile, restored by
+// closeLogFile.
+var logTerminal io.Writer = os.Stderr
+
+// openLogFile opens the log file of cfg (or -log-file) and copies the log
+// to it, besides the terminal. It returns nil when no log
+// file is set. Close it with closeLogFile.
+func openLogFile(cfg *config.Config) (*logfile.Writer, error) {
+	path := logFile
+	if path == "" {
+		path = cfg.Log.File
+	}
+	if path == "" {
+		return nil, nil
+	}
+	w, err := logfile.Open(path, logOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	logTerminal = log.Writer()
+	log.SetOutput(io.MultiWriter(logTerminal, w))
+	return w, nil
+}
+
+// closeLogFile sends the log back to the terminal only and closes w.
+func closeLogFile(w *logfile.Writer) {
+	if w == nil {
+		return
+	}
+	log.SetOutput(logTerminal)
+	setDebugOutput()
+	w.Close()
+}
+
+// openEventLog opens the event log of cfg (log.events_file), rotated as
+// the log file. It returns nil when none is set.
+func openEventLog(cfg *config.Config) (*logfile.Writer, error) {
+	if cfg.Log.EventsFile == "" {
+		return nil, nil
+	}
+	w, err := logfile.Open(cfg.Log.EventsFile, logOptions(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("event log: %w", err)
+	}
+	return w, nil
+}
+
+// logOptions returns the rotation limits of the log files of cfg.
+func logOptions(cfg *config.Config) logfile.Options {
+	return logfile.Options{
+		MaxSize:    cfg.LogMaxSize(),
+		MaxAge:     cfg.LogMaxAge(),
+		MaxBackups: cfg.Log.MaxBackups,
+	}
+}
+
+// logOutput returns the log file of r, for the debug output; io.Discard
+// when there is none.
+func (r *rig) logOutput() io.Writer {
+	if r.logFile == nil {
+		return io.Discard
+	}
+	return r.logFile
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// ---------- -log-file / log.file ----------
+
+func TestRunPango_LogFile(t *testing.T) {
+	withHeadPath(t)
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", nil)
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	ctx := context.Background()
+
+	logPath := filepath.Join(t.TempDir(), "logs", "pango.log")
+	var out, errOut bytes.Buffer
+	if code := runPango(ctx, []string{"-log-file", logPath, "-dry-run", "jog", "-config", path, "-axis", "pan", "-steps", "12"}, &out, &errOut); code != 0 {
+		t.Fatalf("jog: exit status = %d: %s", code, errOut.String())
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"PanGo ", "dry run: GPIO 17: 12 pulses"} {
+		if !strings.Contains(string(data), want) || !strings.Contains(logged.String(), want) {
+			t.Errorf("%q not logged to both the file and the terminal:\n%s", want, data)
+		}
+	}
+	if logFile != "" || log.Writer() != &logged {
+		t.Error("log file left in use")
+	}
+
+	// log.file in the config, for commands not given -log-file
+	configured := filepath.Join(t.TempDir(), "rig.log")
+	path = writeValidateConfig(t, dir, "logged.yaml", func(cfg *config.Config) {
+		cfg.Log.File = configured
+	})
+	if code := runPango(ctx, []string{"home", "-config", path}, &out, &errOut); code != 0 {
+		t.Fatalf("home: exit status = %d: %s", code, errOut.String())
+	}
+	if data, err := os.ReadFile(configured); err != nil || !strings.Contains(string(data), "PanGo ") {
+		t.Errorf("log.file = %q, %v", data, err)
+	}
+
+	for _, args := range [][]string{{"-log-file"}, {"-log-file="}} {
+		if code := runPango(ctx, args, &out, &errOut); code != 2 {
+			t.Errorf("%v: exit status = %d, want 2", args, code)
+		}
+	}
+}
+
+// ---------- log.events_file ----------
+
+func TestOpenEventLog(t *testing.T) {
+	cfg := newTestConfig()
+	if w, err := openEventLog(cfg); w != nil || err != nil {
+		t.Fatalf("no events_file: %v, %v, want nil", w, err)
+	}
+
+	cfg.Log.EventsFile = filepath.Join(t.TempDir(), "logs", "events.jsonl")
+	cfg.Log.MaxSizeMB, cfg.Log.MaxBackups = 1, 3
+	w, err := openEventLog(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	b := web.NewStatusBroadcaster()
+	b.SetRecorder(w)
+	b.Emit(web.EventProgress, map[string]int{"shots_done": 1})
+	b.SetRecorder(nil) // flush
+	data, err := os.ReadFile(cfg.Log.EventsFile)
+	if err != nil || !strings.HasPrefix(string(data), `{"id":1,`) || !strings.Contains(string(data), `"type":"progress"`) {
+		t.Errorf("event log = %q, %v", data, err)
+	}
+
+	cfg.Log.EventsFile = filepath.Join(cfg.Log.EventsFile, "not-a-dir", "events.jsonl")
+	if _, err := openEventLog(cfg); err == nil || !strings.HasPrefix(err.Error(), "event log: ") {
+		t.Errorf("err = %v, want an event log error", err)
+	}
+}
@@ -2,202 +2,1635 @@ package main
 
 import (
 	"context"
-	"flag"
+	"errors"
 	"fmt"
-	"io"
 	"log"
 	"math"
+	"net"
+	"net/netip"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
+	"github.com/cjeanneret/PanGo/client"
 	"github.com/cjeanneret/PanGo/internal/config"
 	"github.com/cjeanneret/PanGo/internal/debug"
+	"github.com/cjeanneret/PanGo/internal/gps"
+	"github.com/cjeanneret/PanGo/internal/hw/board"
 	"github.com/cjeanneret/PanGo/internal/hw/camera"
 	"github.com/cjeanneret/PanGo/internal/hw/gpio"
 	"github.com/cjeanneret/PanGo/internal/hw/stepper"
+	"github.com/cjeanneret/PanGo/internal/hw/trigger"
+	"github.com/cjeanneret/PanGo/internal/logfile"
 	"github.com/cjeanneret/PanGo/internal/logic/capture"
 	"github.com/cjeanneret/PanGo/internal/logic/geometry"
 	"github.com/cjeanneret/PanGo/internal/logic/motion"
+	"github.com/cjeanneret/PanGo/internal/mdns"
+	"github.com/cjeanneret/PanGo/internal/metrics"
+	"github.com/cjeanneret/PanGo/internal/mqtt"
+	"github.com/cjeanneret/PanGo/internal/multirig"
+	"github.com/cjeanneret/PanGo/internal/qr"
+	"github.com/cjeanneret/PanGo/internal/session"
+	"github.com/cjeanneret/PanGo/internal/systemd"
+	"github.com/cjeanneret/PanGo/internal/tracing"
 	"github.com/cjeanneret/PanGo/internal/web"
+	"github.com/cjeanneret/PanGo/internal/webhook"
 )
 
 func main() {
-	// CLI flags
-	webPort := &webPortFlag{defaultPort: 8080}
-	flag.Var(webPort, "web", "start web server on port; -web= for default 8080, -web 8980 for custom port")
-	cfgPath := flag.String("config", filepath.Join("configs", "default.yaml"), "path to config file")
-	horizontalAngleDeg := flag.Float64("horizontal_angle_deg", 0, "override horizontal angle in degrees (1-360)")
-	verticalAngleDeg := flag.Float64("vertical_angle_deg", 0, "override vertical angle in degrees (1-180)")
-	focalLengthMm := flag.Float64("focal_length_mm", 0, "override focal length in mm")
-	flag.Parse()
-
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer cancel()
+	closeJournal := useJournal()
+	code := runPango(ctx, os.Args[1:], os.Stdout, os.Stderr)
+	cancel()
+	closeJournal()
+	os.Exit(code)
+}
+
+// openRig initializes the GPIO driver and the hardware of cfg, as every
+// command that moves the head does. Close the returned rig when done.
+func openRig(cfg *config.Config, cfgPath string) (*rig, error) {
+	logw, err := openLogFile(cfg)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("PanGo %s", buildInfo())
+	debug.Init(cfg.Defaults.DebugLevel)
+	setDebugOutput()
+	debug.ResetModuleLevels()
+	if err := debug.SetModuleLevels(cfg.Log.Levels); err != nil {
+		closeLogFile(logw)
+		return nil, err
+	}
+	if logw != nil {
+		setDebugOutput(logw)
+		debug.Info("log file", "path", logw.Path())
+	}
+	debug.Section("initialization")
+	debug.Info("configuration", "path", cfgPath, "profile", cfg.Profile, "debug_level", cfg.Defaults.DebugLevel, "log_levels", cfg.Log.Levels)
+	tracer, err := openTracing(cfg)
+	if err != nil {
+		closeLogFile(logw)
+		return nil, err
+	}
+
+	// Initialize GPIO driver
+	debug.Step(1, "initializing GPIO driver")
+	debug.Info("GPIO driver", "mock", cfg.Defaults.MockGPIO, "dry_run", dryRun)
+	gpioDriver, err := openGPIO(cfg)
+	if err != nil {
+		closeTracing(tracer)
+		closeLogFile(logw)
+		return nil, err
+	}
+	var pi *board.Board
+	if !cfg.Defaults.MockGPIO && !dryRun {
+		if pi, err = board.Detect("/"); err != nil {
+			log.Printf("GPIO pins not checked against the board: %v", err)
+		} else {
+			debug.Info("board", "model", pi.Model, "header_pins", pi.HeaderPins())
+		}
+	}
 
-	// Load configuration
-	cfg, err := config.Load(*cfgPath)
+	sessions, err := session.NewStore(cfg.Defaults.SessionsDir)
 	if err != nil {
-		log.Fatalf("load config failed: %v", err)
+		gpioDriver.Close()
+		closeTracing(tracer)
+		closeLogFile(logw)
+		return nil, fmt.Errorf("init session store failed: %w", err)
+	}
+	receiver, err := openGPS(cfg)
+	if err != nil {
+		gpioDriver.Close()
+		closeTracing(tracer)
+		closeLogFile(logw)
+		return nil, err
+	}
+	watch := newWatchedGPIO(gpioDriver)
+	r := &rig{
+		gpio:      watch,
+		watch:     watch,
+		board:     pi,
+		sessions:  sessions,
+		thumbs:    session.NewThumbnails(cfg.Defaults.SessionsDir),
+		webhooks:  webhook.NewSender(),
+		lifecycle: capture.NewLifecycle(),
+		// Operator announcements (e.g. "cap the lens") go to the log, or to SSE clients in web mode.
+		notify: func(_, level, msg string) {
+			log.Printf("[%s] %s", level, msg)
+		},
+		start:   loadHeadPosition(),
+		logFile: logw,
+		tracer:  tracer,
+		gps:     receiver,
 	}
+	if err := r.setup(cfg); err != nil {
+		r.close()
+		return nil, fmt.Errorf("init hardware failed: %w", err)
+	}
+	return r, nil
+}
 
-	// Validate CLI overrides (only non-zero values are applied; zero means "use config default")
-	if err := validateCLIOverrides(*horizontalAngleDeg, *verticalAngleDeg, *focalLengthMm); err != nil {
-		log.Fatalf("invalid CLI override: %v", err)
+// close releases the GPIO driver, the GPS receiver, the trace exporter and
+// the log file of r.
+func (r *rig) close() {
+	if err := r.gpio.Close(); err != nil {
+		log.Printf("closing GPIO driver failed: %v", err)
 	}
+	closeGPS(r.gps)
+	closeTracing(r.tracer)
+	closeLogFile(r.logFile)
+}
 
-	// Apply CLI overrides to config
-	applyOverrides(cfg, web.Overrides{
-		HorizontalAngleDeg: *horizontalAngleDeg,
-		VerticalAngleDeg:   *verticalAngleDeg,
-		FocalLengthMm:      *focalLengthMm,
+// serveOptions are the settings of pango serve given on the command line.
+type serveOptions struct {
+	port        int    // 0 = web.port
+	watchConfig bool   // reload the config file when it changes
+	staticDir   string // overrides web.static_dir
+}
+
+// serve runs the web interface and API over r until ctx is done. The
+// configuration, loaded from cfgPath, can then be edited from the web API.
+func serve(ctx context.Context, cfg *config.Config, cfgPath string, r *rig, opts serveOptions) error {
+	// Build runCapture closure over hardware and the live config (editable from the web API)
+	live := newLiveConfig(cfg, cfgPath, r.reconfigure)
+	runCapture := func(ctx context.Context, overrides web.Overrides) error {
+		return executeCapture(ctx, live.get(), r, overrides)
+	}
+
+	port := opts.port
+	if port == 0 {
+		port = cfg.Web.Port
+	}
+	if port == 0 {
+		port = defaultWebPort
+	}
+	webAddr := fmt.Sprintf(":%d", port)
+	if cfg.Web.UnixSocketOnly {
+		webAddr = ""
+	}
+	broadcaster := web.NewStatusBroadcaster()
+	events, err := openEventLog(cfg)
+	if err != nil {
+		return err
+	}
+	if events != nil {
+		defer func() {
+			broadcaster.SetRecorder(nil) // write the events still queued
+			events.Close()
+		}()
+		broadcaster.SetRecorder(events)
+		log.Printf("web: writing the status events to %s", events.Path())
+	}
+	setDebugOutput(web.BroadcastWriter(broadcaster), r.logOutput())
+	reg := metrics.NewRegistry()
+	observe := newRigMetrics(reg, r, broadcaster.Clients).observe
+	r.notify = broadcaster.BroadcastRequest
+	r.events = func(kind string, data any) {
+		observe(kind, data)
+		broadcaster.Emit(kind, data)
+	}
+	r.lifecycle.SetEvents(r.events)
+	go r.trackPosition(ctx, positionInterval)
+
+	srv := web.NewServer(webAddr, broadcaster, runCapture, formDefaults(cfg))
+	srv.SetAuth(web.AuthConfig{
+		Token:    cfg.Web.Auth.Token,
+		Username: cfg.Web.Auth.Username,
+		Password: cfg.Web.Auth.Password,
 	})
+	srv.SetBasePath(cfg.Web.BasePath)
+	srv.SetUnixSocket(cfg.Web.UnixSocket)
+	srv.SetPprof(cfg.Web.Pprof)
+	srv.SetTimeouts(webTimeouts(cfg.Web.Timeouts))
+	if opts.staticDir != "" {
+		cfg.Web.StaticDir = opts.staticDir
+	}
+	if err := srv.SetStaticDir(cfg.Web.StaticDir); err != nil {
+		return fmt.Errorf("web: %w", err)
+	}
+	if cfg.Web.StaticDir != "" {
+		log.Printf("web: serving the interface files from %s when present", cfg.Web.StaticDir)
+	}
+	if len(cfg.Web.ControlNetworks) > 0 {
+		addrs, _ := net.InterfaceAddrs()
+		nets := controlNetworks(cfg.Web.ControlNetworks, addrs)
+		srv.Handlers().ControlNetworks = nets
+		log.Printf("web: control of the rig restricted to %v", nets)
+	}
+	if cfg.Web.GRPCPort > 0 {
+		srv.SetGRPC(fmt.Sprintf(":%d", cfg.Web.GRPCPort))
+	}
+	srv.SetONVIF(cfg.Web.ONVIF)
+	srv.SetCORS(web.CORSConfig{AllowedOrigins: cfg.Web.CORS.AllowedOrigins})
+	if err := srv.SetLimits(web.Limits{
+		CaptureSpacing:    cfg.CaptureSpacing(),
+		RequestsPerMinute: cfg.Web.Limits.RequestsPerMinute,
+	}); err != nil {
+		return fmt.Errorf("web limits: %w", err)
+	}
+	srv.SetTLS(web.TLSConfig{
+		CertFile:   cfg.Web.TLS.CertFile,
+		KeyFile:    cfg.Web.TLS.KeyFile,
+		SelfSigned: cfg.Web.TLS.SelfSigned,
+	})
+	if !cfg.Web.Auth.Enabled() {
+		log.Printf("web: no authentication configured (web.auth); anyone on the network can control the rig")
+	}
+	srv.Handlers().Lifecycle = r.lifecycle
+	srv.Handlers().Jog = r.jogger()
+	srv.Handlers().LiveView = r.liveView()
+	srv.Handlers().Sessions = r.sessions
+	srv.Handlers().Thumbnails = r.thumbs
+	srv.Handlers().Metrics = reg
+	srv.Handlers().Health = r.health(live)
+	srv.Handlers().Head = r.headState
+	srv.Handlers().SafeStop = r.safeStop
+	srv.Handlers().Page = r.pageInfo(live)
+	srv.Handlers().Build = buildInfo()
+	srv.Handlers().Plan = func(overrides web.Overrides) (web.Plan, error) {
+		return planCapture(live.get(), overrides)
+	}
+	srv.Handlers().FullConfig = live.document
+	srv.Handlers().EffectiveConfig = func() (web.EffectiveConfig, error) {
+		return effectiveConfig(live.get())
+	}
+	srv.Handlers().ConfigSchema = config.Schema()
+	srv.Handlers().UpdateConfig = live.update
+	srv.Handlers().Profiles = live.profiles
+	srv.Handlers().ActivateProfile = live.activate
+	srv.Handlers().UploadProfile = live.upload
+	srv.Handlers().SaveDefaults = live.saveDefaults
+	reloadOnSignal(ctx, live, srv.Handlers().SetFormDefaults)
+	dumpStateOnSignal(ctx, srv.Handlers().DebugState)
+	if opts.watchConfig {
+		go watchConfig(ctx, live, configPollInterval, srv.Handlers().SetFormDefaults)
+		log.Printf("web: reloading %s when it changes", cfgPath)
+	}
+	if cfg.Web.MDNS.Enabled && webAddr == "" {
+		log.Printf("web: mdns disabled, no TCP port with unix_socket_only")
+	} else if cfg.Web.MDNS.Enabled {
+		svc := mdnsService(cfg, port)
+		go func() {
+			if err := mdns.Advertise(ctx, svc); err != nil {
+				log.Printf("mdns: %v", err)
+			}
+		}()
+		log.Printf("web: advertising %s.local (%s) on the local network", svc.Host, svc.Type)
+	}
+	if webAddr != "" {
+		addrs, _ := net.InterfaceAddrs()
+		if url := interfaceURL(cfg, port, addrs); url != "" {
+			srv.Handlers().PublicURL = url
+			printInterfaceURL(os.Stdout, url)
+		}
+	}
+	if m := cfg.Web.MQTT; m.Broker != "" {
+		opts := mqtt.Options{Broker: m.Broker, ClientID: m.ClientID, Username: m.Username, Password: m.Password}
+		go func() {
+			if err := srv.Handlers().RunMQTT(ctx, opts, m.TopicPrefix); err != nil {
+				log.Printf("mqtt: %v", err)
+			}
+		}()
+		log.Printf("web: bridging to MQTT broker %s under %s/", m.Broker, m.TopicPrefix)
+	}
+	if cfg.Web.INDIPort > 0 {
+		if err := serveINDI(ctx, r, srv.Handlers(), cfg.Web.INDIAddress, cfg.Web.INDIPort); err != nil {
+			return err
+		}
+	}
+	pad, err := openGamepad(cfg, r)
+	if err != nil {
+		return err
+	}
+	if pad != nil {
+		defer pad.Close()
+	}
+	panel := startPanel(ctx, cfg, r, srv.Handlers())
+	display, err := openDisplay(cfg, r, srv.Handlers(), panel)
+	if err != nil {
+		return err
+	}
+	if display != nil {
+		defer display.Close()
+	}
+	if err := runUnderSystemd(ctx, srv, r); err != nil {
+		return err
+	}
+	srv.RegisterOnShutdown(r.park)
+	srv.RegisterOnShutdown(r.flushWebhooks)
+	if err := srv.Run(ctx); err != nil {
+		return fmt.Errorf("web server: %w", err)
+	}
+	return nil
+}
 
-	// Initialize debug system
-	debug.Init(cfg.Defaults.DebugLevel)
-	debug.Section("Initialization")
-	debug.Value("Config path", *cfgPath)
-	debug.Value("Debug level", cfg.Defaults.DebugLevel)
+// runOnce runs one capture with cfg, then parks the head. On SIGINT/SIGTERM
+// (ctx done) the capture stops after the current shot. SIGUSR1 logs the state
+// of the capture meanwhile.
+func runOnce(ctx context.Context, cfg *config.Config, r *rig) error {
+	dumpCtx, stopDump := context.WithCancel(ctx)
+	dumpStateOnSignal(dumpCtx, func() web.DebugState {
+		return web.CollectDebugState(r.lifecycle, nil, r.headState)
+	})
+	err := executeCapture(ctx, cfg, r, web.Overrides{})
+	stopDump()
+	r.park()
+	r.flushWebhooks()
+	switch {
+	case errors.Is(err, context.Canceled) && ctx.Err() != nil:
+		log.Printf("capture interrupted")
+	case err != nil:
+		return fmt.Errorf("capture failed: %w", err)
+	}
+	return nil
+}
 
-	// Initialize GPIO driver
-	debug.Value("Mock GPIO", cfg.Defaults.MockGPIO)
-	debug.Step(1, "Initializing GPIO driver")
-	gpioDriver, err := gpio.NewDriver(cfg.Defaults.MockGPIO)
+// runUnderSystemd integrates the web server with systemd when run as a
+// service: sockets passed by a .socket unit replace the -port port,
+// readiness and shutdown are notified (Type=notify), and the watchdog
+// (WatchdogSec=) is pinged while the rig passes its liveness check, so a
+// wedged capture loop or web server gets the service restarted. Outside
+// systemd it does nothing.
+func runUnderSystemd(ctx context.Context, srv *web.Server, r *rig) error {
+	activated, err := systemd.Listeners()
 	if err != nil {
-		log.Fatalf("init GPIO failed: %v", err)
+		return err
 	}
-	defer func() {
-		if err := gpioDriver.Close(); err != nil {
-			log.Printf("closing GPIO driver failed: %v", err)
+	if len(activated) > 0 {
+		srv.SetListeners(activated)
+		log.Printf("web: serving %d socket-activated listener(s) instead of the -port port", len(activated))
+	}
+	srv.RegisterOnReady(func() {
+		if _, err := systemd.Notify(systemd.Ready); err != nil {
+			log.Printf("%v", err)
 		}
+	})
+	go func() {
+		<-ctx.Done()
+		systemd.Notify(systemd.Stopping)
 	}()
+	go func() {
+		err := systemd.RunWatchdog(ctx, func() error {
+			err := r.alive(ctx, srv)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("watchdog: %v; not notifying systemd", err)
+			}
+			return err
+		})
+		if err != nil {
+			log.Printf("%v", err)
+		}
+	}()
+	return nil
+}
+
+// interfaceURL returns the URL a phone on the local network opens the web
+// interface at: on the mDNS name when advertised, else on the first
+// non-loopback IPv4 address of addrs. "" when there is none.
+func interfaceURL(cfg *config.Config, port int, addrs []net.Addr) string {
+	scheme := "http"
+	if cfg.Web.TLS.Enabled() {
+		scheme = "https"
+	}
+	host := localIPv4(addrs)
+	if cfg.Web.MDNS.Enabled {
+		host = mdnsService(cfg, port).Host + ".local"
+	}
+	if host == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s://%s%s/", scheme, net.JoinHostPort(host, strconv.Itoa(port)), cfg.Web.BasePath)
+}
+
+// localIPv4 returns the first non-loopback IPv4 address of addrs, "" when
+// there is none.
+func localIPv4(addrs []net.Addr) string {
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if ok && ipnet.IP.To4() != nil && !ipnet.IP.IsLoopback() && !ipnet.IP.IsLinkLocalUnicast() {
+			return ipnet.IP.String()
+		}
+	}
+	return ""
+}
+
+// printInterfaceURL logs url and, on a terminal, prints it as a QR code to
+// open the interface from a phone.
+func printInterfaceURL(out *os.File, url string) {
+	log.Printf("web: open %s", url)
+	if fi, err := out.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return
+	}
+	if code, err := qr.Encode(url); err == nil {
+		fmt.Fprint(out, code.Terminal())
+	}
+}
+
+// controlNetworks resolves web.control_networks (validated by config.Load);
+// config.LocalNetworks stands for the subnets of addrs, loopback included.
+func controlNetworks(networks []string, addrs []net.Addr) []netip.Prefix {
+	var out []netip.Prefix
+	for _, n := range networks {
+		if n != config.LocalNetworks {
+			p, _ := config.ParseNetwork(n)
+			out = append(out, p)
+			continue
+		}
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			addr, ok := netip.AddrFromSlice(ipnet.IP)
+			if !ok {
+				continue
+			}
+			ones, bits := ipnet.Mask.Size()
+			if bits == 32 {
+				addr = addr.Unmap() // IPv4 stored in 16 bytes
+			}
+			if p, err := addr.Prefix(ones); err == nil {
+				out = append(out, p)
+			}
+		}
+	}
+	return out
+}
+
+// webTimeouts converts the web timeouts from the config; zero values keep
+// the server defaults.
+func webTimeouts(c config.TimeoutsConfig) web.Timeouts {
+	ms := func(v int) time.Duration { return time.Duration(v) * time.Millisecond }
+	return web.Timeouts{
+		ReadHeader: ms(c.ReadHeaderMs),
+		Read:       ms(c.ReadMs),
+		Write:      ms(c.WriteMs),
+		Idle:       ms(c.IdleMs),
+		Shutdown:   ms(c.ShutdownMs),
+	}
+}
+
+// formDefaults returns the capture form defaults for the web UI.
+func formDefaults(cfg *config.Config) web.FormConfig {
+	return web.FormConfig{
+		HorizontalAngleDeg: cfg.Defaults.HorizontalAngleDeg,
+		VerticalAngleDeg:   cfg.Defaults.VerticalAngleDeg,
+		FocalLengthMm:      cfg.Lens.FocalLengthMm,
+
+		OverlapPercent:       cfg.Defaults.OverlapPercent,
+		InterMoveDelayMs:     cfg.Defaults.InterMoveDelayMs,
+		StabilizationDelayMs: cfg.Defaults.StabilizationDelayMs,
+		ShotDelayMs:          cfg.Camera.PostShotDelayMs,
+		MoveSpeedMs:          cfg.Defaults.MoveSpeedMs,
+	}
+}
+
+// captureDurationBuckets are the GET /metrics histogram bounds for run
+// durations (seconds): from a quick panorama to a night-long timelapse.
+var captureDurationBuckets = []float64{30, 60, 120, 300, 600, 1200, 1800, 3600, 7200, 14400, 28800}
+
+// rigMetrics counts rig activity for GET /metrics from capture events.
+type rigMetrics struct {
+	shots    *metrics.Counter
+	started  *metrics.Counter
+	failed   *metrics.Counter
+	duration *metrics.Histogram
+
+	mu      sync.Mutex
+	running bool // a run started and has not ended yet
+}
+
+// newRigMetrics registers the rig metrics in reg. clients reports the
+// number of status stream subscribers.
+func newRigMetrics(reg *metrics.Registry, r *rig, clients func() int) *rigMetrics {
+	m := &rigMetrics{
+		shots:    reg.Counter("pango_shots_total", "Shutter releases."),
+		started:  reg.Counter("pango_captures_started_total", "Captures started."),
+		failed:   reg.Counter("pango_captures_failed_total", "Captures that ended in error."),
+		duration: reg.Histogram("pango_capture_duration_seconds", "Duration of finished captures (completed, cancelled or failed).", captureDurationBuckets),
+	}
+	const stepsHelp = "Motor steps made, in either direction (captures and jogs)."
+	reg.CounterFunc("pango_steps_moved_total", stepsHelp, func() float64 { return float64(r.panMoved.Load()) }, "axis", "pan")
+	reg.CounterFunc("pango_steps_moved_total", stepsHelp, func() float64 { return float64(r.tiltMoved.Load()) }, "axis", "tilt")
+	reg.GaugeFunc("pango_status_clients", "Clients following the status stream (SSE and WebSocket).", func() float64 { return float64(clients()) })
+	return m
+}
+
+// observe is a capture.EventFunc updating the metrics.
+func (m *rigMetrics) observe(kind string, data any) {
+	switch kind {
+	case capture.EventShot:
+		m.shots.Inc()
+	case capture.EventState:
+		st, ok := data.(capture.Status)
+		if !ok {
+			return
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		switch st.State {
+		case capture.StatePlanning:
+			m.started.Inc()
+			m.running = true
+		case capture.StateIdle, capture.StateError:
+			if !m.running {
+				return
+			}
+			m.running = false
+			if st.State == capture.StateError {
+				m.failed.Inc()
+			}
+			if st.StartedAt != nil && st.EndedAt != nil {
+				m.duration.Observe(st.EndedAt.Sub(*st.StartedAt).Seconds())
+			}
+		}
+	}
+}
+
+// mdnsService describes the web interface for mDNS advertisement.
+func mdnsService(cfg *config.Config, port int) mdns.Service {
+	scheme := "http"
+	if cfg.Web.TLS.Enabled() {
+		scheme = "https"
+	}
+	return mdns.Service{
+		Instance: cfg.Web.MDNS.Name,
+		Type:     "_pango._tcp",
+		Host:     cfg.Web.MDNS.Name,
+		Port:     port,
+		TXT:      []string{"path=" + cfg.Web.BasePath + "/", "scheme=" + scheme},
+	}
+}
+
+// liveConfig is the configuration captures start from. The web API can
+// replace it (PUT /config, profile activation); a capture already running
+// keeps its own copy.
+type liveConfig struct {
+	mu     sync.RWMutex
+	cfg    *config.Config
+	boot   *config.Config // configuration the program started with
+	hw     *config.Config // configuration the hardware was initialized with
+	path   string         // file of the active profile
+	reinit func(cfg *config.Config) error
+}
+
+// newLiveConfig returns a liveConfig for cfg, loaded from path. reinit
+// rebuilds the hardware when a new configuration changes it.
+func newLiveConfig(cfg *config.Config, path string, reinit func(cfg *config.Config) error) *liveConfig {
+	return &liveConfig{cfg: cfg, boot: cfg, hw: cfg, path: path, reinit: reinit}
+}
+
+func (l *liveConfig) get() *config.Config {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.cfg
+}
+
+// document returns the configuration as a JSON-encodable map keyed like the
+// YAML file, without the web section and with the other credentials
+// redacted (see config.Redact).
+func (l *liveConfig) document() (any, error) {
+	data, err := yaml.Marshal(l.get())
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	delete(doc, "web")
+	config.Redact(doc)
+	return doc, nil
+}
+
+// update validates data and makes it the configuration of the next captures,
+// writing it to the active profile's file when persist is set.
+func (l *liveConfig) update(data []byte, persist bool) (web.ConfigUpdate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	next, err := config.ParseUpdate(data, l.cfg)
+	if err != nil {
+		return web.ConfigUpdate{}, fmt.Errorf("%w: %v", web.ErrInvalidConfig, err)
+	}
+	if persist && l.cfg.Profile != "" {
+		// Saving would write the profile's values over those of the file.
+		return web.ConfigUpdate{}, fmt.Errorf("%w: profile %q of the profiles: section is active, edit %s to change it", web.ErrInvalidConfig, l.cfg.Profile, l.path)
+	}
+	if persist && len(l.cfg.Sources) > 1 {
+		// Saving would copy the included files' values into this one.
+		return web.ConfigUpdate{}, fmt.Errorf("%w: %s includes other files, edit them to change it", web.ErrInvalidConfig, l.path)
+	}
+	update, err := l.apply(next)
+	if err != nil {
+		return web.ConfigUpdate{}, err
+	}
+	if persist {
+		if err := config.Save(l.path, next); err != nil {
+			return web.ConfigUpdate{}, fmt.Errorf("configuration applied but not saved: %w", err)
+		}
+		update.Saved = true
+	}
+	log.Printf("configuration updated from the web API (saved: %t, restart required: %t)", update.Saved, update.RestartRequired)
+	return update, nil
+}
+
+// profiles lists the config files next to the active one, each followed by
+// the entries of its profiles: section ("file/entry"). Files that do not
+// load are listed alone.
+func (l *liveConfig) profiles() (web.Profiles, error) {
+	l.mu.RLock()
+	path := l.path
+	active := l.activeProfile()
+	l.mu.RUnlock()
+	available, err := profileNames(filepath.Dir(path))
+	if err != nil {
+		return web.Profiles{}, err
+	}
+	return web.Profiles{Active: active, Available: available}, nil
+}
+
+// profileNames returns the profiles of the config files in dir: the name of
+// each file, followed by file/entry for the entries of its profiles:
+// section.
+func profileNames(dir string) ([]string, error) {
+	names, err := config.Profiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	available := make([]string, 0, len(names))
+	for _, name := range names {
+		available = append(available, name)
+		file, err := config.ProfilePath(dir, name)
+		if err != nil {
+			continue
+		}
+		cfg, err := config.Load(file)
+		if err != nil {
+			continue
+		}
+		for _, entry := range cfg.ProfileNames() {
+			available = append(available, name+config.ProfileSeparator+entry)
+		}
+	}
+	return available, nil
+}
+
+// profile returns the name of the active profile.
+func (l *liveConfig) profile() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.activeProfile()
+}
+
+// activeProfile returns the name of the active profile: the file's, with the
+// applied entry of its profiles: section. l.mu must be held.
+func (l *liveConfig) activeProfile() string {
+	name := config.ProfileName(l.path)
+	if l.cfg.Profile != "" {
+		name += config.ProfileSeparator + l.cfg.Profile
+	}
+	return name
+}
+
+// check reports whether the active profile's file still loads.
+func (l *liveConfig) check() error {
+	l.mu.RLock()
+	path := l.path
+	l.mu.RUnlock()
+	_, err := config.Load(path)
+	return err
+}
+
+// activate loads the named profile from the active profile's directory and
+// applies it: a file, or an entry of a file's profiles: section
+// ("file/entry"). CLI overrides given at startup are not re-applied.
+func (l *liveConfig) activate(name string) (web.ConfigUpdate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	path, entry, err := profileFile(filepath.Dir(l.path), name)
+	if err != nil {
+		return web.ConfigUpdate{}, err
+	}
+	next, err := loadProfile(path, entry)
+	if errors.Is(err, config.ErrUnknownProfile) {
+		return web.ConfigUpdate{}, fmt.Errorf("%w: %q", web.ErrProfileNotFound, name)
+	}
+	if err != nil {
+		return web.ConfigUpdate{}, fmt.Errorf("%w: %v", web.ErrInvalidConfig, err)
+	}
+	update, err := l.apply(next)
+	if err != nil {
+		return web.ConfigUpdate{}, err
+	}
+	l.path = path
+	log.Printf("profile %q activated (hardware re-initialized: %t, restart required: %t)", name, update.Reinitialized, update.RestartRequired)
+	return update, nil
+}
+
+// loadProfile loads the config file path with entry profile of its
+// profiles: section applied; "" applies none. Keys migrated from an older
+// layout are logged.
+func loadProfile(path, profile string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range cfg.Warnings {
+		log.Printf("config %s: %s", path, w)
+	}
+	if profile == "" {
+		return cfg, nil
+	}
+	return cfg.WithProfile(profile)
+}
+
+// profileFile returns the config file and the entry of its profiles:
+// section of a profile named as in GET /profiles, a file of dir or
+// "file/entry". A missing file is reported as web.ErrProfileNotFound.
+func profileFile(dir, name string) (string, string, error) {
+	file, entry, _ := strings.Cut(name, config.ProfileSeparator)
+	path, err := config.ProfilePath(dir, file)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %q", web.ErrProfileNotFound, name)
+	}
+	return path, entry, nil
+}
+
+// loadRunConfig loads the configuration of a CLI run: the config file path,
+// the profile selected with -profile, then overrides, applied as to the
+// runs started from the web page. The profile is a name of GET /profiles,
+// resolved next to path like POST /profiles/{name}/activate; a bare name
+// that is an entry of the profiles: section of path selects that entry.
+func loadRunConfig(path, profile string, overrides web.Overrides) (*config.Config, error) {
+	entry := ""
+	if profile != "" {
+		cfg, err := config.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(profile, config.ProfileSeparator) || !slices.Contains(cfg.ProfileNames(), profile) {
+			if path, entry, err = profileFile(filepath.Dir(path), profile); err != nil {
+				return nil, err
+			}
+		} else {
+			entry = profile
+		}
+	}
+	cfg, err := loadProfile(path, entry)
+	if err != nil {
+		return nil, err
+	}
+	return applyOverridesToCopy(cfg, overrides), nil
+}
+
+// upload stores data as profile name next to the active profile, then
+// activates it if asked.
+func (l *liveConfig) upload(name string, data []byte, replace, activate bool) (web.ProfileUpload, error) {
+	l.mu.RLock()
+	dir := filepath.Dir(l.path)
+	l.mu.RUnlock()
+	if _, err := config.SaveProfile(dir, name, data, replace); err != nil {
+		switch {
+		case errors.Is(err, config.ErrInvalidProfile):
+			return web.ProfileUpload{}, fmt.Errorf("%w: %v", web.ErrInvalidConfig, err)
+		case errors.Is(err, config.ErrProfileExists):
+			return web.ProfileUpload{}, fmt.Errorf("%w: %q (set replace to overwrite it)", web.ErrProfileExists, name)
+		}
+		return web.ProfileUpload{}, err
+	}
+	log.Printf("profile %q uploaded from the web API", name)
+	result := web.ProfileUpload{Profile: name}
+	if !activate {
+		return result, nil
+	}
+	update, err := l.activate(name)
+	if err != nil {
+		return web.ProfileUpload{}, fmt.Errorf("profile %q saved but not activated: %w", name, err)
+	}
+	result.Activated = true
+	result.ConfigUpdate = update
+	return result, nil
+}
+
+// saveDefaults writes the capture parameters of overrides, over the live
+// configuration, into profile name next to the active one ("" = the active
+// profile). A file profile that does not exist yet is written from the live
+// configuration. Saving into the active profile reloads it.
+func (l *liveConfig) saveDefaults(name string, overrides web.Overrides) (web.SavedDefaults, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if name == "" {
+		name = l.activeProfile()
+	}
+	file, entry, _ := strings.Cut(name, config.ProfileSeparator)
+	dir := filepath.Dir(l.path)
+	cfg := applyOverridesToCopy(l.cfg, overrides)
+	result := web.SavedDefaults{Profile: name}
+
+	path, err := config.ProfilePath(dir, file)
+	switch {
+	case err == nil:
+		if err := config.SetValues(path, entry, captureDefaults(cfg)); err != nil {
+			return web.SavedDefaults{}, fmt.Errorf("%w: %v", web.ErrInvalidConfig, err)
+		}
+	case entry != "":
+		return web.SavedDefaults{}, fmt.Errorf("%w: %q", web.ErrProfileNotFound, name)
+	default:
+		if path, err = config.CreateProfile(dir, file, cfg); err != nil {
+			if errors.Is(err, config.ErrInvalidProfile) {
+				return web.SavedDefaults{}, fmt.Errorf("%w: %v", web.ErrInvalidConfig, err)
+			}
+			return web.SavedDefaults{}, err
+		}
+		result.Created = true
+	}
+	log.Printf("capture defaults saved to profile %q (created: %t)", name, result.Created)
+
+	if path != l.path || entry != l.cfg.Profile {
+		return result, nil
+	}
+	next, err := loadProfile(path, entry)
+	if err != nil {
+		return web.SavedDefaults{}, fmt.Errorf("%w: %v", web.ErrInvalidConfig, err)
+	}
+	update, err := l.apply(next)
+	if err != nil {
+		return web.SavedDefaults{}, fmt.Errorf("defaults saved but not applied: %w", err)
+	}
+	update.Saved = true
+	result.Active, result.ConfigUpdate = true, update
+	return result, nil
+}
+
+// captureDefaults returns the capture parameters of cfg that the form sets,
+// by config section and key.
+func captureDefaults(cfg *config.Config) map[string]map[string]any {
+	return map[string]map[string]any{
+		"defaults": {
+			"horizontal_angle_deg":   cfg.Defaults.HorizontalAngleDeg,
+			"vertical_angle_deg":     cfg.Defaults.VerticalAngleDeg,
+			"overlap_percent":        cfg.Defaults.OverlapPercent,
+			"inter_move_delay_ms":    cfg.Defaults.InterMoveDelayMs,
+			"stabilization_delay_ms": cfg.Defaults.StabilizationDelayMs,
+			"move_speed_ms":          cfg.Defaults.MoveSpeedMs,
+		},
+		"camera": {"post_shot_delay_ms": cfg.Camera.PostShotDelayMs},
+		"lens":   {"focal_length_mm": cfg.Lens.FocalLengthMm},
+	}
+}
+
+// apply makes next the live configuration, re-initializing the hardware when
+// its settings changed. Web settings are kept: they cannot be changed at
+// runtime. l.mu must be held.
+func (l *liveConfig) apply(next *config.Config) (web.ConfigUpdate, error) {
+	next.KeepWeb(l.cfg)
+	update := web.ConfigUpdate{
+		RestartRequired: config.RestartRequired(l.boot, next),
+		Form:            formDefaults(next),
+	}
+	if l.reinit != nil && config.HardwareChanged(l.hw, next) {
+		if err := l.reinit(next); err != nil {
+			return web.ConfigUpdate{}, err
+		}
+		l.hw = next
+		update.Reinitialized = true
+	}
+	l.cfg = next
+	return update, nil
+}
 
-	// Initialize stepper motors
-	debug.Step(2, "Initializing stepper motors")
+// rig bundles the hardware and shared state a capture runs against.
+type rig struct {
+	busy      headLock // held while the head is in use (capture or jog)
+	gpio      gpio.Driver
+	watch     *watchedGPIO   // gpio, for the service watchdog; nil in tests
+	board     *board.Board   // detected at startup; nil with mock GPIO or off a Raspberry Pi
+	hw        *config.Config // configuration the hardware below was built from
+	pan, tilt *stepper.Stepper
+	camMu     sync.RWMutex // guards cam for readers not holding busy (live view)
+	cam       camera.Camera
+	trigger   capture.Trigger // optional external trigger input
+	lifecycle *capture.Lifecycle
+	sessions  *session.Store
+	thumbs    *session.Thumbnails
+	webhooks  *webhook.Sender
+	notify    func(requestID, level, msg string) // operator messages; requestID is empty outside web requests
+	events    capture.EventFunc                  // structured events (web mode); optional
+	logFile   *logfile.Writer                    // copy of the log (log.file, -log-file); nil = none
+	tracer    *tracing.Exporter                  // capture traces (tracing.endpoint); nil = none
+	gps       *gps.Receiver                      // position of the head (gps.gpsd, gps.device); nil = none
+
+	panMoved, tiltMoved atomic.Uint64 // steps made per axis, across re-initializations
+
+	// start is the head position, in steps from home, where the motors
+	// were built: positions of pan and tilt are relative to it.
+	start capture.Position
+}
+
+// sequence creates the motion controller and capture sequence for one run.
+// Its messages carry the ID of the request that started the run, from ctx.
+func (r *rig) sequence(ctx context.Context, cfg *config.Config, rec *session.Recorder) *capture.Sequence {
+	seq := capture.NewSequence(motion.NewController(r.pan, r.tilt), r.cam)
+	id := web.RequestID(ctx)
+	seq.SetNotifier(func(level, msg string) { r.notify(id, level, msg) })
+	seq.SetLifecycle(r.lifecycle)
+	seq.SetRecorder(rec)
+	seq.SetEvents(r.events)
+	if cfg.Camera.Download && r.thumbs != nil {
+		if _, ok := r.cam.(camera.DownloadCamera); !ok {
+			log.Printf("camera.download: the %s camera cannot download pictures, no thumbnails", cfg.Camera.Type)
+		}
+		seq.SetImages(func(index int, image []byte) {
+			if err := r.thumbs.Add(rec.ID(), index, image); err != nil {
+				log.Printf("thumbnail of shot %d: %v", index, err)
+			}
+		})
+	}
+	return seq
+}
+
+// setup builds the motors, camera and optional trigger input from cfg,
+// after checking its pins against the board.
+func (r *rig) setup(cfg *config.Config) error {
+	if r.board != nil && !cfg.Defaults.MockGPIO {
+		if problems := r.board.Check(cfg.Pins()); len(problems) > 0 {
+			return fmt.Errorf("GPIO pins on the %s: %s", r.board.Model, strings.Join(problems, "; "))
+		}
+	}
+	debug.Step(2, "initializing stepper motors")
 	stepDelay := cfg.MoveSpeed() / 2
-	panMotor := stepper.NewStepper(gpioDriver, stepper.Config{
+	pan := stepper.NewStepper(r.gpio, stepper.Config{
 		StepPin:       cfg.PanStepper.StepPin,
 		DirPin:        cfg.PanStepper.DirPin,
 		EnablePin:     cfg.PanStepper.EnablePin,
 		StepsPerRev:   cfg.PanStepper.StepsPerRev,
 		Microstepping: cfg.PanStepper.Microstepping,
 		StepDelay:     stepDelay,
+		Moved:         &r.panMoved,
 	})
-	debug.PrintStruct("Pan stepper config", cfg.PanStepper)
-	tiltMotor := stepper.NewStepper(gpioDriver, stepper.Config{
+	debug.Verbose("stepper", "axis", "pan", "config", cfg.PanStepper)
+	tilt := stepper.NewStepper(r.gpio, stepper.Config{
 		StepPin:       cfg.TiltStepper.StepPin,
 		DirPin:        cfg.TiltStepper.DirPin,
 		EnablePin:     cfg.TiltStepper.EnablePin,
 		StepsPerRev:   cfg.TiltStepper.StepsPerRev,
 		Microstepping: cfg.TiltStepper.Microstepping,
 		StepDelay:     stepDelay,
+		Moved:         &r.tiltMoved,
 	})
-	debug.PrintStruct("Tilt stepper config", cfg.TiltStepper)
+	debug.Verbose("stepper", "axis", "tilt", "config", cfg.TiltStepper)
 
-	// Initialize camera
-	debug.Step(3, "Initializing camera")
-	cam, err := newCameraFromConfig(gpioDriver, cfg)
-	if err != nil {
-		log.Fatalf("init camera failed: %v", err)
+	debug.Step(3, "initializing camera")
+	var cam camera.Camera = camera.NewDryRun(log.Printf)
+	if !dryRun {
+		var err error
+		if cam, err = newCameraFromConfig(r.gpio, cfg); err != nil {
+			return fmt.Errorf("init camera: %w", err)
+		}
 	}
-	debug.Value("Camera type", cfg.Camera.Type)
-	debug.Value("Focus pin", cfg.Camera.FocusPin)
-	debug.Value("Shutter pin", cfg.Camera.ShutterPin)
+	debug.Info("camera", "type", cfg.Camera.Type, "focus_pin", cfg.Camera.FocusPin, "shutter_pin", cfg.Camera.ShutterPin)
 
-	// Build runCapture closure over hardware and base config
-	runCapture := func(ctx context.Context, overrides web.Overrides) error {
-		return executeCapture(ctx, cfg, panMotor, tiltMotor, cam, overrides)
+	// Optional external trigger input (flash-ready signal, hand switch)
+	var trig capture.Trigger
+	if cfg.Trigger.Pin != 0 {
+		if cfg.Defaults.MockGPIO || dryRun {
+			// The mock driver never reports a pulse: waiting would block forever.
+			log.Printf("mock GPIO: external trigger on pin %d ignored", cfg.Trigger.Pin)
+		} else {
+			trig = trigger.NewInput(r.gpio, cfg.Trigger.Pin, cfg.Trigger.ActiveLow, cfg.TriggerDebounce(), cfg.TriggerTimeout())
+			debug.Info("trigger", "pin", cfg.Trigger.Pin, "active_low", cfg.Trigger.ActiveLow)
+		}
 	}
 
-	if port := webPort.port(); port > 0 {
-		webAddr := fmt.Sprintf(":%d", port)
-		broadcaster := web.NewStatusBroadcaster()
-		debug.SetOutput(io.MultiWriter(os.Stdout, web.BroadcastWriter(broadcaster)))
+	r.camMu.Lock()
+	r.pan, r.tilt, r.cam, r.trigger, r.hw = pan, tilt, cam, trig, cfg
+	r.camMu.Unlock()
+	return nil
+}
+
+// health returns the checks of GET /healthz and /readyz: the GPIO driver
+// answers, the camera is initialized (and reachable, for cameras that can
+// tell) and the active config file still loads, so that a restart would work.
+func (r *rig) health(live *liveConfig) web.HealthFunc {
+	return func(ctx context.Context) []web.HealthCheck {
+		r.camMu.RLock()
+		cam, hw := r.cam, r.hw
+		r.camMu.RUnlock()
 
-		formDefaults := web.FormConfig{
-			HorizontalAngleDeg: cfg.Defaults.HorizontalAngleDeg,
-			VerticalAngleDeg:   cfg.Defaults.VerticalAngleDeg,
-			FocalLengthMm:      cfg.Lens.FocalLengthMm,
+		gpioErr := errors.New("not initialized")
+		if r.gpio != nil && hw != nil {
+			_, gpioErr = r.gpio.ReadPin(hw.PanStepper.StepPin)
 		}
-		srv := web.NewServer(webAddr, broadcaster, runCapture, formDefaults)
-		if err := srv.Run(ctx); err != nil {
-			log.Fatalf("web server: %v", err)
+		camErr := errors.New("not initialized")
+		if cam != nil {
+			camErr = nil
+			if c, ok := cam.(camera.Checker); ok {
+				camErr = c.Check(ctx)
+			}
 		}
+		return []web.HealthCheck{
+			healthCheck("gpio", gpioErr),
+			healthCheck("camera", camErr),
+			healthCheck("config", live.check()),
+		}
+	}
+}
+
+func healthCheck(name string, err error) web.HealthCheck {
+	if err != nil {
+		return web.HealthCheck{Name: name, Status: web.HealthFail, Error: err.Error()}
+	}
+	return web.HealthCheck{Name: name, Status: web.HealthOK}
+}
+
+// park disables the motors at exit, so they do not stay energized, and logs
+// the final head position. The head is left alone if still in use.
+func (r *rig) park() {
+	if !r.busy.TryLock() {
+		log.Printf("shutdown: head still in use, motors left as they are")
 		return
 	}
+	defer r.busy.Unlock()
+
+	if r.pan == nil {
+		return
+	}
+	ctrl := motion.NewController(r.pan, r.tilt)
+	if err := ctrl.DisableMotors(); err != nil {
+		log.Printf("shutdown: disable motors: %v", err)
+	}
+	pan, tilt := ctrl.Position()
+	log.Printf("shutdown: motors disabled, head at pan %d, tilt %d steps", pan, tilt)
+	if err := saveHeadPosition(r.home()); err != nil {
+		log.Printf("shutdown: save head position: %v", err)
+	}
+}
+
+// home returns the head position in steps from home. r.busy must be held.
+func (r *rig) home() capture.Position {
+	return capture.Position{PanSteps: r.start.PanSteps + r.pan.Position(), TiltSteps: r.start.TiltSteps + r.tilt.Position()}
+}
+
+// reconfigure rebuilds the hardware from cfg (profile switch, config edit).
+// It is refused while the head is in use. The previous motors are disabled
+// first, as their pins may no longer be driven; positions restart at zero,
+// from the head position kept in r.start.
+func (r *rig) reconfigure(cfg *config.Config) error {
+	if !r.busy.TryLock() {
+		return web.ErrHeadBusy
+	}
+	defer r.busy.Unlock()
+
+	start := r.start
+	if r.pan != nil {
+		if err := motion.NewController(r.pan, r.tilt).DisableMotors(); err != nil {
+			log.Printf("disable motors before re-initialization: %v", err)
+		}
+		start = r.home()
+	}
+	if err := r.setup(cfg); err != nil {
+		return err
+	}
+	r.start = start
+	return nil
+}
+
+// pageInfo returns the description of the rig rendered into the web
+// interface: build, active profile and what the hardware can do.
+func (r *rig) pageInfo(live *liveConfig) web.PageInfoFunc {
+	return func() web.PageInfo {
+		r.camMu.RLock()
+		cam, trig, hw := r.cam, r.trigger, r.hw
+		r.camMu.RUnlock()
+
+		info := web.PageInfo{Version: buildVersion(), Profile: live.profile()}
+		if hw == nil {
+			return info
+		}
+		_, liveView := cam.(camera.LiveViewCamera)
+		_, download := cam.(camera.DownloadCamera)
+		info.CameraType = hw.Camera.Type
+		info.Capabilities = web.Capabilities{
+			LiveView: liveView,
+			Download: download && hw.Camera.Download,
+			Trigger:  trig != nil,
+			MockGPIO: hw.Defaults.MockGPIO,
+		}
+		return info
+	}
+}
+
+// liveView returns the live view source for the web API. Frames are only
+// grabbed while no capture is running, so they never delay a shot.
+func (r *rig) liveView() web.LiveViewFunc {
+	return func(ctx context.Context) ([]byte, error) {
+		r.camMu.RLock()
+		lv, ok := r.cam.(camera.LiveViewCamera)
+		r.camMu.RUnlock()
+		if !ok {
+			return nil, web.ErrLiveViewUnsupported
+		}
+		if st := r.lifecycle.State(); st != capture.StateIdle && st != capture.StateError {
+			return nil, web.ErrHeadBusy
+		}
+		return lv.LiveViewFrame(ctx)
+	}
+}
+
+// jogger returns the manual movement function for the web API. A jog is
+// refused while a capture (or another jog) is using the head; motors stay
+// enabled afterwards to hold the framing.
+func (r *rig) jogger() web.JogFunc {
+	return func(ctx context.Context, req web.JogRequest) error {
+		if !r.busy.TryLock() {
+			return web.ErrHeadBusy
+		}
+		defer r.busy.Unlock()
+
+		ctrl := motion.NewController(r.pan, r.tilt)
+		if err := ctrl.EnableMotors(); err != nil {
+			return err
+		}
+		stepsCalc := geometry.NewStepsCalculator(r.hw)
+		steps := req.Steps
+		if steps == 0 {
+			if req.Axis == "tilt" {
+				steps = stepsCalc.TiltStepsFromAngle(req.Degrees)
+			} else {
+				steps = stepsCalc.PanStepsFromAngle(req.Degrees)
+			}
+		}
+		period := time.Duration(req.SpeedMs) * time.Millisecond
+		debug.Live("jog", "axis", req.Axis, "steps", steps)
 
-	{
-		// Run capture once with current config (already has CLI overrides applied)
-		if err := runCapture(ctx, web.Overrides{}); err != nil {
-			log.Fatalf("capture failed: %v", err)
+		var err error
+		if req.Axis == "tilt" {
+			err = ctrl.MoveTiltContext(ctx, steps, period)
+		} else {
+			err = ctrl.MovePanContext(ctx, steps, period)
 		}
+		if r.events != nil {
+			pan, tilt := ctrl.Position()
+			r.events(capture.EventMove, capture.Position{PanSteps: pan, TiltSteps: tilt})
+		}
+		return err
 	}
 }
 
+// How often the head position is sampled for EventPosition while it moves.
+const positionInterval = 200 * time.Millisecond
+
+// trackPosition emits EventPosition every interval while the head moves,
+// and once more when it stops, until ctx is done. It samples the motors
+// without taking the head, so captures and jogs are not slowed down.
+func (r *rig) trackPosition(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last capture.HeadPosition
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if pos, ok := r.samplePosition(&last); ok {
+			r.events(capture.EventPosition, pos)
+		}
+	}
+}
+
+// samplePosition reads the head position and reports whether it differs
+// from *last (then updated): the head moved since, or just stopped.
+func (r *rig) samplePosition(last *capture.HeadPosition) (capture.HeadPosition, bool) {
+	pos, ok := r.position()
+	if !ok {
+		return capture.HeadPosition{}, false
+	}
+	pos.Moving = pos.PanSteps != last.PanSteps || pos.TiltSteps != last.TiltSteps
+	if pos == *last {
+		return pos, false
+	}
+	*last = pos
+	return pos, true
+}
+
+// position reads the positions tracked by the motors, without taking the
+// head; false before the hardware is set up.
+func (r *rig) position() (capture.HeadPosition, bool) {
+	r.camMu.RLock()
+	pan, tilt, hw := r.pan, r.tilt, r.hw
+	r.camMu.RUnlock()
+	if pan == nil || hw == nil {
+		return capture.HeadPosition{}, false
+	}
+	stepsCalc := geometry.NewStepsCalculator(hw)
+	pos := capture.HeadPosition{PanSteps: pan.Position(), TiltSteps: tilt.Position()}
+	pos.PanDeg = stepsCalc.PanAngleFromSteps(pos.PanSteps)
+	pos.TiltDeg = stepsCalc.TiltAngleFromSteps(pos.TiltSteps)
+	return pos, true
+}
+
 // executeCapture runs the grid shot sequence with the given config and overrides.
-// It applies overrides to a copy of the config, then runs the capture.
-func executeCapture(
-	ctx context.Context,
-	baseCfg *config.Config,
-	panMotor *stepper.Stepper,
-	tiltMotor *stepper.Stepper,
-	cam camera.Camera,
-	overrides web.Overrides,
-) error {
+// It applies overrides to a copy of the config, then runs the capture,
+// driving the lifecycle from planning back to idle (or error).
+func executeCapture(ctx context.Context, baseCfg *config.Config, r *rig, overrides web.Overrides) error {
+	if !r.busy.TryLock() {
+		return web.ErrHeadBusy
+	}
+	defer r.busy.Unlock()
+	if err := r.lifecycle.Transition(capture.StatePlanning); err != nil {
+		return err
+	}
 	cfg := applyOverridesToCopy(baseCfg, overrides)
+	stepsCalc := geometry.NewStepsCalculator(cfg)
+	lens, _ := huginLens(cfg)
+	rec := session.NewRecorder(cfg.Defaults.Mode, session.Params{
+		HorizontalAngleDeg: cfg.Defaults.HorizontalAngleDeg,
+		VerticalAngleDeg:   cfg.Defaults.VerticalAngleDeg,
+		FocalLengthMm:      cfg.Lens.FocalLengthMm,
+		OverlapPercent:     cfg.Defaults.OverlapPercent,
+		PanStepsPerDeg:     stepsCalc.PanStepsPerDegree(),
+		TiltStepsPerDeg:    stepsCalc.TiltStepsPerDegree(),
+		HorizontalFOVDeg:   lens.HFOVDeg,
+		VerticalFOVDeg:     lens.VFOVDeg,
+		ImageWidthPx:       lens.WidthPx,
+		ImageHeightPx:      lens.HeightPx,
+	})
+	rec.SetBuild(buildInfo().String())
+	if r.gps != nil {
+		rec.SetLocator(r.location)
+	}
+
+	hooks := webhooks(cfg)
+	r.webhooks.Send(hooks, webhook.Payload{Event: webhook.EventStarted, Time: time.Now(), Session: rec.Snapshot().Summary()})
+
+	ctx, span := startCaptureTrace(ctx, cfg)
+	err := r.protect(ctx, func() error { return runSession(ctx, cfg, r, rec) })
+	if r.pan != nil {
+		rec.SetEndPosition(motion.NewController(r.pan, r.tilt).Position())
+	}
+	outcome := session.OutcomeDone
+	switch {
+	case errors.Is(err, context.Canceled):
+		outcome = session.OutcomeCancelled
+		_ = r.lifecycle.Transition(capture.StateIdle)
+	case err != nil:
+		outcome = session.OutcomeFailed
+		r.lifecycle.Fail(err)
+	default:
+		_ = r.lifecycle.Transition(capture.StateIdle)
+	}
+
+	record := rec.Finish(outcome, err)
+	span.SetAttributes("session", record.ID, "shots", len(record.Shots), "outcome", outcome)
+	span.End(err)
+	r.webhooks.Send(hooks, webhook.Payload{Event: outcome, Time: record.EndedAt, Session: record.Summary()})
+	logTimings(record)
+	if saveErr := r.sessions.Save(record); saveErr != nil {
+		log.Printf("saving session %s failed: %v", record.ID, saveErr)
+	}
+	return err
+}
+
+// How long the program waits for webhook deliveries in progress before
+// exiting (retries included).
+const webhookDrainTimeout = 15 * time.Second
+
+// webhooks returns the hooks configured in cfg.
+func webhooks(cfg *config.Config) []webhook.Hook {
+	hooks := make([]webhook.Hook, len(cfg.Web.Webhooks))
+	for i, h := range cfg.Web.Webhooks {
+		hooks[i] = webhook.Hook{URL: h.URL, Events: h.Events, Secret: h.Secret}
+	}
+	return hooks
+}
+
+// flushWebhooks waits for the webhook deliveries in progress, so that the
+// end of the last capture is reported before the program exits.
+func (r *rig) flushWebhooks() {
+	if !r.webhooks.Wait(webhookDrainTimeout) {
+		log.Printf("webhook: deliveries still pending after %v, giving up", webhookDrainTimeout)
+	}
+}
+
+// logTimings prints the per-shot timing summary of a run, to help tune delays.
+func logTimings(rec session.Record) {
+	if len(rec.Shots) == 0 {
+		return
+	}
+	debug.Info("session", "id", rec.ID, "shots", len(rec.Shots), "duration", rec.Duration().Round(time.Second))
+	for _, t := range []struct {
+		name  string
+		stats session.Stats
+	}{
+		{"move", rec.Timings.Move},
+		{"settle", rec.Timings.Settle},
+		{"shutter", rec.Timings.Shutter},
+	} {
+		debug.Info("timings", "stage", t.name,
+			"min", time.Duration(t.stats.Min).Round(time.Millisecond),
+			"mean", time.Duration(t.stats.Mean).Round(time.Millisecond),
+			"p95", time.Duration(t.stats.P95).Round(time.Millisecond),
+			"max", time.Duration(t.stats.Max).Round(time.Millisecond))
+	}
+}
+
+// runSession runs the capture mode selected in cfg.
+func runSession(ctx context.Context, cfg *config.Config, r *rig, rec *session.Recorder) error {
+	gate := web.SyncGateFrom(ctx)
+	if gate != nil {
+		defer gate.Close()
+	}
+	if cfg.Defaults.Mode == config.ModeTimelapse {
+		if gate != nil {
+			return fmt.Errorf("multi-rig: a follower must be in grid mode")
+		}
+		rec.SetPlan(0, 0, cfg.Timelapse.Frames)
+		r.lifecycle.SetEstimate(capture.EstimateTimelapseDuration(timelapseParams(cfg)))
+		return executeTimelapse(ctx, cfg, r, rec)
+	}
 
-	debug.Step(4, "Calculating grid plan")
-	fovCalc, err := geometry.NewFOVCalculator(cfg)
+	debug.Step(4, "calculating grid plan")
+	panoramas, err := buildPanoramas(cfg)
 	if err != nil {
-		return fmt.Errorf("create FOV calculator: %w", err)
+		return err
+	}
+
+	total := 0
+	for _, p := range panoramas {
+		total += p.Plan.PanColumns * p.Plan.TiltRows
+	}
+	r.lifecycle.SetShotsTotal(total)
+	r.lifecycle.SetEstimate(capture.EstimatePanoramasDuration(panoramas, gridParams(cfg, panoramas[0].Plan)))
+	if len(panoramas) == 1 {
+		rec.SetPlan(panoramas[0].Plan.PanColumns, panoramas[0].Plan.TiltRows, total)
+	} else {
+		rec.SetPlan(0, 0, total)
+	}
+
+	debug.Step(5, "creating motion and capture controllers")
+	captureSeq := r.sequence(ctx, cfg, rec)
+
+	params := gridParams(cfg, panoramas[0].Plan)
+	params.Trigger = r.trigger
+	if gate != nil {
+		params.BeforeShot = gate.Wait
+	} else if leader := multiRigLeader(cfg); leader != nil {
+		debug.Info("starting the capture on the followers", "followers", len(cfg.Web.MultiRig.Followers))
+		o := web.Overrides{HorizontalAngleDeg: cfg.Defaults.HorizontalAngleDeg, VerticalAngleDeg: cfg.Defaults.VerticalAngleDeg}
+		if err := leader.Start(ctx, o, total); err != nil {
+			return fmt.Errorf("multi-rig: %w", err)
+		}
+		params.BeforeShot = leader.BeforeShot
+		defer func() {
+			if err != nil {
+				leader.Cancel(context.Background())
+			}
+		}()
+	}
+
+	debug.Section("grid shot sequence")
+	if len(cfg.Panoramas) > 0 {
+		err = captureSeq.RunPanoramas(ctx, panoramas, params)
+	} else {
+		err = captureSeq.RunGridShot(ctx, params)
 	}
-	stepsCalc := geometry.NewStepsCalculator(cfg)
-	gridPlan, err := geometry.CalculateGridPlan(cfg, fovCalc, stepsCalc)
 	if err != nil {
-		return fmt.Errorf("calculate grid plan: %w", err)
-	}
-
-	totalPhotos := gridPlan.PanColumns * gridPlan.TiltRows
-	debug.Summary("Grid Plan Summary")
-	debug.Grid(gridPlan.PanColumns, gridPlan.TiltRows, totalPhotos)
-	debug.Info("Step sizes: pan=%d steps, tilt=%d steps", gridPlan.PanStepSize, gridPlan.TiltStepSize)
-
-	debug.Section("Grid Plan Details")
-	debug.Value("Pan columns", gridPlan.PanColumns)
-	debug.Value("Tilt rows", gridPlan.TiltRows)
-	debug.Value("Total photos", totalPhotos)
-	debug.Value("Pan step size", gridPlan.PanStepSize)
-	debug.Value("Tilt step size", gridPlan.TiltStepSize)
-	debug.Value("Start pan steps", gridPlan.StartPanSteps)
-	debug.Value("Start tilt steps", gridPlan.StartTiltSteps)
-	debug.Value("Horizontal FOV", fovCalc.HorizontalFOV())
-	debug.Value("Vertical FOV", fovCalc.VerticalFOV())
-	debug.Value("Horizontal rotation angle", fovCalc.HorizontalRotationAngle())
-	debug.Value("Vertical rotation angle", fovCalc.VerticalRotationAngle())
-
-	debug.Step(5, "Creating motion and capture controllers")
-	motionCtrl := motion.NewController(panMotor, tiltMotor)
-	captureSeq := capture.NewSequence(motionCtrl, cam)
-
-	debug.Section("Starting Grid Shot Sequence")
-	err = captureSeq.RunGridShot(ctx, capture.GridShotParams{
-		GridPlan:      gridPlan,
-		Delay:         500 * time.Millisecond,
+		return err
+	}
+	if err := captureSeq.RunDarkFrames(ctx, darkFrameParams(cfg, 0)); err != nil {
+		return fmt.Errorf("dark frames: %w", err)
+	}
+
+	debug.Section("sequence complete")
+	return nil
+}
+
+// multiRigLeader returns the leader of the followers configured in
+// web.multi_rig, or nil when there are none. Followers get the angles of the
+// capture; each uses its own lens.
+func multiRigLeader(cfg *config.Config) *multirig.Leader {
+	if len(cfg.Web.MultiRig.Followers) == 0 {
+		return nil
+	}
+	followers := make([]multirig.Follower, 0, len(cfg.Web.MultiRig.Followers))
+	for _, f := range cfg.Web.MultiRig.Followers {
+		c := client.New(f.URL)
+		c.Token, c.Username, c.Password = f.Token, f.Username, f.Password
+		followers = append(followers, multirig.Follower{Name: f.URL, Client: c})
+	}
+	return multirig.NewLeader(followers, cfg.FollowerReadyTimeout())
+}
+
+// gridParams builds the grid traversal parameters from config.
+func gridParams(cfg *config.Config, plan *geometry.GridPlan) capture.GridShotParams {
+	return capture.GridShotParams{
+		GridPlan:      plan,
+		Delay:         cfg.InterMoveDelay(),
 		MoveSpeed:     cfg.MoveSpeed(),
-		ShotDelay:     300 * time.Millisecond,
+		ShotDelay:     cfg.StabilizationDelay(),
 		PostShotDelay: cfg.PostShotDelay(),
-	})
+
+		ExposureTime:   cfg.ExposureTime(),
+		ExposureMargin: cfg.ExposureMargin(),
+
+		ReturnHomeOnAbort: cfg.Defaults.ReturnHomeOnAbort,
+		RetryFailedShots:  cfg.Defaults.RetryFailedShots,
+	}
+}
+
+// planCapture previews the capture selected by cfg with overrides applied:
+// grid sizes, shot count and estimated duration. Nothing moves.
+func planCapture(baseCfg *config.Config, overrides web.Overrides) (web.Plan, error) {
+	cfg := applyOverridesToCopy(baseCfg, overrides)
+	if cfg.Defaults.Mode == config.ModeTimelapse {
+		return web.Plan{
+			Mode:         config.ModeTimelapse,
+			TotalShots:   cfg.Timelapse.Frames,
+			EstimatedSec: capture.EstimateTimelapseDuration(timelapseParams(cfg)).Round(time.Second).Seconds(),
+		}, nil
+	}
+
+	panoramas, err := planPanoramas(cfg)
 	if err != nil {
+		return web.Plan{}, err
+	}
+	first := panoramas[0].Plan
+	plan := web.Plan{
+		Mode:         config.ModeGrid,
+		Columns:      first.PanColumns,
+		Rows:         first.TiltRows,
+		PanStepSize:  first.PanStepSize,
+		TiltStepSize: first.TiltStepSize,
+		EstimatedSec: capture.EstimatePanoramasDuration(panoramas, gridParams(cfg, first)).Round(time.Second).Seconds(),
+	}
+	for _, p := range panoramas {
+		plan.TotalShots += p.Plan.PanColumns * p.Plan.TiltRows
+	}
+	if len(panoramas) > 1 {
+		for _, p := range panoramas {
+			plan.Panoramas = append(plan.Panoramas, web.PlanGrid{
+				Name:         p.Name,
+				Columns:      p.Plan.PanColumns,
+				Rows:         p.Plan.TiltRows,
+				PanStepSize:  p.Plan.PanStepSize,
+				TiltStepSize: p.Plan.TiltStepSize,
+			})
+		}
+	}
+	return plan, nil
+}
+
+// buildPanoramas computes the grid plan of each panorama in cfg.Panoramas,
+// or a single centered grid from the defaults when none are configured.
+func buildPanoramas(cfg *config.Config) ([]capture.Panorama, error) {
+	return computePanoramas(cfg, true)
+}
+
+// planPanoramas is buildPanoramas without logging, for previews.
+func planPanoramas(cfg *config.Config) ([]capture.Panorama, error) {
+	return computePanoramas(cfg, false)
+}
+
+func computePanoramas(cfg *config.Config, logPlans bool) ([]capture.Panorama, error) {
+	specs := cfg.Panoramas
+	if len(specs) == 0 {
+		specs = []config.PanoramaConfig{{}}
+	}
+
+	panoramas := make([]capture.Panorama, 0, len(specs))
+	for i, spec := range specs {
+		pcfg := *cfg
+		if spec.HorizontalAngleDeg > 0 {
+			pcfg.Defaults.HorizontalAngleDeg = spec.HorizontalAngleDeg
+		}
+		if spec.VerticalAngleDeg > 0 {
+			pcfg.Defaults.VerticalAngleDeg = spec.VerticalAngleDeg
+		}
+
+		fovCalc, err := geometry.NewFOVCalculator(&pcfg)
+		if err != nil {
+			return nil, fmt.Errorf("create FOV calculator: %w", err)
+		}
+		stepsCalc := geometry.NewStepsCalculator(&pcfg)
+		gridPlan, err := geometry.CalculateGridPlanAt(&pcfg, fovCalc, stepsCalc, spec.PanCenterDeg, spec.TiltCenterDeg)
+		if err != nil {
+			if len(cfg.Panoramas) > 0 {
+				return nil, fmt.Errorf("calculate grid plan for panorama %d: %w", i+1, err)
+			}
+			return nil, fmt.Errorf("calculate grid plan: %w", err)
+		}
+
+		if logPlans {
+			var args []any
+			if len(cfg.Panoramas) > 0 {
+				args = []any{"panorama", i + 1, "panoramas", len(specs), "name", spec.Name}
+			}
+			logGridPlan(gridPlan, fovCalc, args...)
+		}
+		panoramas = append(panoramas, capture.Panorama{Name: spec.Name, Plan: gridPlan})
+	}
+	return panoramas, nil
+}
+
+// logGridPlan logs the summary and details of a grid plan, with the
+// key-value pairs of args (the panorama of multi-panorama runs).
+func logGridPlan(gridPlan *geometry.GridPlan, fovCalc *geometry.FOVCalculator, args ...any) {
+	debug.Info("grid plan", append(args,
+		"columns", gridPlan.PanColumns,
+		"rows", gridPlan.TiltRows,
+		"photos", gridPlan.PanColumns*gridPlan.TiltRows,
+		"pan_step_size", gridPlan.PanStepSize,
+		"tilt_step_size", gridPlan.TiltStepSize)...)
+	debug.Verbose("grid plan details", append(args,
+		"start_pan_steps", gridPlan.StartPanSteps,
+		"start_tilt_steps", gridPlan.StartTiltSteps,
+		"horizontal_fov_deg", fovCalc.HorizontalFOV(),
+		"vertical_fov_deg", fovCalc.VerticalFOV(),
+		"pan_rotation_deg", fovCalc.HorizontalRotationAngle(),
+		"tilt_rotation_deg", fovCalc.VerticalRotationAngle())...)
+}
+
+// executeTimelapse runs a fixed-position timelapse with the given config.
+func executeTimelapse(ctx context.Context, cfg *config.Config, r *rig, rec *session.Recorder) error {
+	debug.Step(4, "creating motion and capture controllers")
+	captureSeq := r.sequence(ctx, cfg, rec)
+
+	params := timelapseParams(cfg)
+	if st := cfg.Timelapse.SunTracking; st != nil {
+		debug.Info("sun tracking", "latitude_deg", st.LatitudeDeg, "longitude_deg", st.LongitudeDeg, "reference_azimuth_deg", st.ReferenceAzimuthDeg)
+	}
+
+	debug.Section("timelapse")
+	if err := captureSeq.RunTimelapse(ctx, params); err != nil {
 		return err
 	}
+	var bulbExposure time.Duration
+	if params.Ramp != nil {
+		bulbExposure = params.Ramp.End // match the last light frame
+	}
+	if err := captureSeq.RunDarkFrames(ctx, darkFrameParams(cfg, bulbExposure)); err != nil {
+		return fmt.Errorf("dark frames: %w", err)
+	}
 
-	debug.Section("Sequence Complete")
+	debug.Section("timelapse complete")
 	return nil
 }
 
+// timelapseParams builds the timelapse parameters from config.
+func timelapseParams(cfg *config.Config) capture.TimelapseParams {
+	params := capture.TimelapseParams{
+		Frames:         cfg.Timelapse.Frames,
+		Interval:       cfg.TimelapseInterval(),
+		Jitter:         cfg.TimelapseJitter(),
+		ShotDelay:      cfg.StabilizationDelay(),
+		PostShotDelay:  cfg.PostShotDelay(),
+		ExposureTime:   cfg.ExposureTime(),
+		ExposureMargin: cfg.ExposureMargin(),
+	}
+	if r := cfg.Timelapse.BulbRamp; r != nil {
+		params.Ramp = &capture.ExposureRamp{
+			Start: time.Duration(r.StartExposureMs) * time.Millisecond,
+			End:   time.Duration(r.EndExposureMs) * time.Millisecond,
+		}
+	}
+	if st := cfg.Timelapse.SunTracking; st != nil {
+		stepsCalc := geometry.NewStepsCalculator(cfg)
+		lastPan := 0.0 // the head holds its last aim while the sun is down
+		params.Aim = func(t time.Time) (int, int, bool) {
+			panDeg, tiltDeg, visible := geometry.SunPointing(*st, t, lastPan)
+			if visible {
+				lastPan = panDeg
+			}
+			return stepsCalc.PanStepsFromAngle(panDeg), stepsCalc.TiltStepsFromAngle(tiltDeg), visible
+		}
+	}
+	return params
+}
+
+// darkFrameParams builds the dark-frame series from config.
+// bulbExposure > 0 shoots the dark frames in bulb mode with that exposure.
+func darkFrameParams(cfg *config.Config, bulbExposure time.Duration) capture.DarkFrameParams {
+	return capture.DarkFrameParams{
+		Count:          cfg.DarkFrames.Count,
+		CapDelay:       cfg.DarkFrameCapDelay(),
+		ShotDelay:      cfg.StabilizationDelay(),
+		PostShotDelay:  cfg.PostShotDelay(),
+		ExposureTime:   cfg.ExposureTime(),
+		ExposureMargin: cfg.ExposureMargin(),
+		BulbExposure:   bulbExposure,
+	}
+}
+
 // validateCLIOverrides checks that non-zero CLI overrides are within valid ranges.
 // Zero values are ignored (they mean "use config default").
 func validateCLIOverrides(horizontal, vertical, focal float64) error {
@@ -219,17 +1652,24 @@ func validateCLIOverrides(horizontal, vertical, focal float64) error {
 	return nil
 }
 
-// applyOverrides mutates cfg with overrides. Only non-zero override values are applied.
-func applyOverrides(cfg *config.Config, overrides web.Overrides) {
-	if overrides.HorizontalAngleDeg > 0 {
-		cfg.Defaults.HorizontalAngleDeg = overrides.HorizontalAngleDeg
+// validateCLIPacing checks the overlap and pacing CLI overrides like
+// validateCLIOverrides: zero values are ignored.
+func validateCLIPacing(overlap float64, shotDelay, moveSpeed int) error {
+	if math.IsNaN(overlap) || math.IsInf(overlap, 0) || overlap < 0 || overlap >= 100 {
+		return fmt.Errorf("overlap_percent must be at least 0 and below 100, got %g", overlap)
 	}
-	if overrides.VerticalAngleDeg > 0 {
-		cfg.Defaults.VerticalAngleDeg = overrides.VerticalAngleDeg
+	if shotDelay < 0 || shotDelay > config.MaxCameraDelayMs {
+		return fmt.Errorf("shot_delay_ms must be between 0 and %d, got %d", config.MaxCameraDelayMs, shotDelay)
 	}
-	if overrides.FocalLengthMm > 0 {
-		cfg.Lens.FocalLengthMm = overrides.FocalLengthMm
+	if moveSpeed < 0 || moveSpeed > 1000 {
+		return fmt.Errorf("move_speed_ms must be between 0 and 1000, got %d", moveSpeed)
 	}
+	return nil
+}
+
+// applyOverrides mutates cfg with overrides, like applyOverridesToCopy.
+func applyOverrides(cfg *config.Config, overrides web.Overrides) {
+	*cfg = *applyOverridesToCopy(cfg, overrides)
 }
 
 // applyOverridesToCopy returns a new config with overrides applied.
@@ -245,6 +1685,21 @@ func applyOverridesToCopy(baseCfg *config.Config, overrides web.Overrides) *conf
 	if overrides.FocalLengthMm > 0 {
 		cfg.Lens.FocalLengthMm = overrides.FocalLengthMm
 	}
+	if overrides.InterMoveDelayMs > 0 {
+		cfg.Defaults.InterMoveDelayMs = overrides.InterMoveDelayMs
+	}
+	if overrides.StabilizationDelayMs > 0 {
+		cfg.Defaults.StabilizationDelayMs = overrides.StabilizationDelayMs
+	}
+	if overrides.OverlapPercent > 0 {
+		cfg.Defaults.OverlapPercent = overrides.OverlapPercent
+	}
+	if overrides.MoveSpeedMs > 0 {
+		cfg.Defaults.MoveSpeedMs = overrides.MoveSpeedMs
+	}
+	if overrides.ShotDelayMs > 0 {
+		cfg.Camera.PostShotDelayMs = overrides.ShotDelayMs
+	}
 	return &cfg
 }
 
@@ -1,10 +1,30 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 
 	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hw/board"
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+	"github.com/cjeanneret/PanGo/internal/hw/stepper"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+	"github.com/cjeanneret/PanGo/internal/metrics"
 	"github.com/cjeanneret/PanGo/internal/web"
 )
 
@@ -114,6 +134,35 @@ func TestValidateCLIOverrides_Infinity(t *testing.T) {
 	}
 }
 
+// ---------- validateCLIPacing ----------
+
+func TestValidateCLIPacing(t *testing.T) {
+	cases := []struct {
+		name                 string
+		overlap              float64
+		shotDelay, moveSpeed int
+		wantErr              bool
+	}{
+		{"all_zero", 0, 0, 0, false},
+		{"max", 99.9, 60000, 1000, false},
+		{"overlap_100", 100, 0, 0, true},
+		{"overlap_negative", -1, 0, 0, true},
+		{"overlap_NaN", math.NaN(), 0, 0, true},
+		{"shot_delay_negative", 0, -1, 0, true},
+		{"shot_delay_too_large", 0, 60001, 0, true},
+		{"move_speed_negative", 0, 0, -1, true},
+		{"move_speed_too_large", 0, 0, 1001, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateCLIPacing(tc.overlap, tc.shotDelay, tc.moveSpeed)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("validateCLIPacing(%g, %d, %d) = %v, want error %v", tc.overlap, tc.shotDelay, tc.moveSpeed, err, tc.wantErr)
+			}
+		})
+	}
+}
+
 // ---------- webPortFlag ----------
 
 func TestWebPortFlag_EmptyString(t *testing.T) {
@@ -252,6 +301,63 @@ func TestApplyOverrides_Partial(t *testing.T) {
 	}
 }
 
+func TestApplyOverrides_Delays(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Defaults.InterMoveDelayMs = 500
+	cfg.Defaults.StabilizationDelayMs = 300
+
+	applyOverrides(cfg, web.Overrides{StabilizationDelayMs: 1200})
+
+	if cfg.Defaults.InterMoveDelayMs != 500 {
+		t.Errorf("InterMoveDelayMs should be unchanged: %d", cfg.Defaults.InterMoveDelayMs)
+	}
+	if cfg.Defaults.StabilizationDelayMs != 1200 {
+		t.Errorf("StabilizationDelayMs = %d, want 1200", cfg.Defaults.StabilizationDelayMs)
+	}
+
+	copy := applyOverridesToCopy(cfg, web.Overrides{InterMoveDelayMs: 900})
+	if copy.Defaults.InterMoveDelayMs != 900 || cfg.Defaults.InterMoveDelayMs != 500 {
+		t.Errorf("copy InterMoveDelayMs = %d, original = %d, want 900 and 500", copy.Defaults.InterMoveDelayMs, cfg.Defaults.InterMoveDelayMs)
+	}
+}
+
+func TestCaptureParams_Delays(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Defaults.InterMoveDelayMs = 750
+	cfg.Defaults.StabilizationDelayMs = 1500
+
+	grid := gridParams(cfg, nil)
+	if grid.Delay != 750*time.Millisecond || grid.ShotDelay != 1500*time.Millisecond {
+		t.Errorf("grid Delay = %v, ShotDelay = %v, want 750ms and 1.5s", grid.Delay, grid.ShotDelay)
+	}
+	if got := timelapseParams(cfg).ShotDelay; got != 1500*time.Millisecond {
+		t.Errorf("timelapse ShotDelay = %v, want 1.5s", got)
+	}
+	if got := darkFrameParams(cfg, 0).ShotDelay; got != 1500*time.Millisecond {
+		t.Errorf("dark frame ShotDelay = %v, want 1.5s", got)
+	}
+}
+
+func TestApplyOverrides_Pacing(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Defaults.OverlapPercent = 30
+	cfg.Camera.PostShotDelayMs = 300
+
+	copy := applyOverridesToCopy(cfg, web.Overrides{OverlapPercent: 45, ShotDelayMs: 800, MoveSpeedMs: 5})
+	if copy.Defaults.OverlapPercent != 45 || cfg.Defaults.OverlapPercent != 30 {
+		t.Errorf("copy OverlapPercent = %g, original = %g, want 45 and 30", copy.Defaults.OverlapPercent, cfg.Defaults.OverlapPercent)
+	}
+	grid := gridParams(copy, nil)
+	if grid.MoveSpeed != 5*time.Millisecond || grid.PostShotDelay != 800*time.Millisecond {
+		t.Errorf("grid MoveSpeed = %v, PostShotDelay = %v, want 5ms and 800ms", grid.MoveSpeed, grid.PostShotDelay)
+	}
+
+	same := applyOverridesToCopy(cfg, web.Overrides{})
+	if same.Defaults.MoveSpeedMs != cfg.Defaults.MoveSpeedMs || same.Camera.PostShotDelayMs != 300 {
+		t.Errorf("zero overrides changed MoveSpeedMs = %d, PostShotDelayMs = %d", same.Defaults.MoveSpeedMs, same.Camera.PostShotDelayMs)
+	}
+}
+
 // ---------- applyOverridesToCopy ----------
 
 func TestApplyOverridesToCopy_OriginalUnmutated(t *testing.T) {
@@ -339,3 +445,978 @@ func TestOverrides_CLIAndWebProduceSameResult(t *testing.T) {
 			cfgCLI.Lens.FocalLengthMm, cfgWebCopy.Lens.FocalLengthMm)
 	}
 }
+
+// ---------- planCapture ----------
+
+func TestPlanCapture_Grid(t *testing.T) {
+	cfg := newTestConfig()
+	plan, err := planCapture(cfg, web.Overrides{})
+	if err != nil {
+		t.Fatalf("planCapture: %v", err)
+	}
+	panoramas, _ := buildPanoramas(cfg)
+	want := panoramas[0].Plan
+	if plan.Mode != config.ModeGrid || plan.Columns != want.PanColumns || plan.Rows != want.TiltRows {
+		t.Errorf("plan = %+v, want %dx%d grid", plan, want.PanColumns, want.TiltRows)
+	}
+	if plan.TotalShots != want.PanColumns*want.TiltRows {
+		t.Errorf("total_shots = %d, want %d", plan.TotalShots, want.PanColumns*want.TiltRows)
+	}
+	if plan.EstimatedSec <= 0 {
+		t.Errorf("estimated_seconds = %v, want > 0", plan.EstimatedSec)
+	}
+	if plan.Panoramas != nil {
+		t.Error("single grid plan should not list panoramas")
+	}
+}
+
+func TestPlanCapture_OverridesApplied(t *testing.T) {
+	cfg := newTestConfig()
+	wide, _ := planCapture(cfg, web.Overrides{})
+	narrow, err := planCapture(cfg, web.Overrides{HorizontalAngleDeg: 60})
+	if err != nil {
+		t.Fatalf("planCapture: %v", err)
+	}
+	if narrow.Columns >= wide.Columns || narrow.EstimatedSec >= wide.EstimatedSec {
+		t.Errorf("60° plan (%d cols, %vs) should be smaller than 180° (%d cols, %vs)",
+			narrow.Columns, narrow.EstimatedSec, wide.Columns, wide.EstimatedSec)
+	}
+	if cfg.Defaults.HorizontalAngleDeg != 180 {
+		t.Error("planCapture must not mutate the base config")
+	}
+}
+
+func TestPlanCapture_Panoramas(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Panoramas = []config.PanoramaConfig{{Name: "a", HorizontalAngleDeg: 60}, {Name: "b"}}
+	plan, err := planCapture(cfg, web.Overrides{})
+	if err != nil {
+		t.Fatalf("planCapture: %v", err)
+	}
+	if len(plan.Panoramas) != 2 || plan.Panoramas[0].Name != "a" {
+		t.Fatalf("panoramas = %+v", plan.Panoramas)
+	}
+	sum := 0
+	for _, p := range plan.Panoramas {
+		sum += p.Columns * p.Rows
+	}
+	if plan.TotalShots != sum {
+		t.Errorf("total_shots = %d, want %d", plan.TotalShots, sum)
+	}
+}
+
+func TestPlanCapture_Timelapse(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Defaults.Mode = config.ModeTimelapse
+	cfg.Timelapse = config.TimelapseConfig{Frames: 360, IntervalMs: 10000}
+	plan, err := planCapture(cfg, web.Overrides{})
+	if err != nil {
+		t.Fatalf("planCapture: %v", err)
+	}
+	if plan.Mode != config.ModeTimelapse || plan.TotalShots != 360 {
+		t.Errorf("plan = %+v", plan)
+	}
+	// 359 intervals of 10s, plus the last frame
+	if plan.EstimatedSec < 3590 || plan.EstimatedSec > 3600 {
+		t.Errorf("estimated_seconds = %v, want ~3590", plan.EstimatedSec)
+	}
+}
+
+// ---------- buildPanoramas ----------
+
+func TestBuildPanoramas_SingleGridByDefault(t *testing.T) {
+	cfg := newTestConfig()
+	panoramas, err := buildPanoramas(cfg)
+	if err != nil {
+		t.Fatalf("buildPanoramas: %v", err)
+	}
+	if len(panoramas) != 1 {
+		t.Fatalf("panoramas = %d, want 1", len(panoramas))
+	}
+	if panoramas[0].Plan.StartPanAngle != -90 {
+		t.Errorf("StartPanAngle = %v, want -90 (centered 180° grid)", panoramas[0].Plan.StartPanAngle)
+	}
+}
+
+func TestBuildPanoramas_PerPanoramaAngles(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Panoramas = []config.PanoramaConfig{
+		{Name: "a", HorizontalAngleDeg: 60, PanCenterDeg: 90},
+		{Name: "b"},
+	}
+	panoramas, err := buildPanoramas(cfg)
+	if err != nil {
+		t.Fatalf("buildPanoramas: %v", err)
+	}
+	if len(panoramas) != 2 {
+		t.Fatalf("panoramas = %d, want 2", len(panoramas))
+	}
+	if got := panoramas[0].Plan.StartPanAngle; got != 60 {
+		t.Errorf("panorama a StartPanAngle = %v, want 60", got)
+	}
+	if got := panoramas[1].Plan.StartPanAngle; got != -90 {
+		t.Errorf("panorama b StartPanAngle = %v, want -90 (defaults)", got)
+	}
+	if panoramas[0].Plan.PanColumns >= panoramas[1].Plan.PanColumns {
+		t.Errorf("60° panorama should need fewer columns than 180° (%d vs %d)",
+			panoramas[0].Plan.PanColumns, panoramas[1].Plan.PanColumns)
+	}
+	if cfg.Defaults.HorizontalAngleDeg != 180 {
+		t.Error("buildPanoramas must not mutate the base config")
+	}
+}
+
+// ---------- multi-rig ----------
+
+func TestMultiRigLeader(t *testing.T) {
+	cfg := newTestConfig()
+	if multiRigLeader(cfg) != nil {
+		t.Fatal("leader without followers")
+	}
+
+	var auth string
+	var planned web.Overrides
+	follower := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&planned)
+		json.NewEncoder(w).Encode(web.Plan{Mode: "grid", TotalShots: 7})
+	}))
+	defer follower.Close()
+	cfg.Web.MultiRig.Followers = []config.FollowerConfig{{URL: follower.URL, Token: "0123456789abcdef"}}
+	leader := multiRigLeader(cfg)
+	if leader == nil {
+		t.Fatal("no leader with a follower")
+	}
+
+	o := web.Overrides{HorizontalAngleDeg: 90, VerticalAngleDeg: 20}
+	err := leader.Start(context.Background(), o, 12)
+	if err == nil || !strings.Contains(err.Error(), "follower "+follower.URL+" plans 7 grid shots") {
+		t.Errorf("Start = %v, want the shot count mismatch", err)
+	}
+	if auth != "Bearer 0123456789abcdef" {
+		t.Errorf("Authorization = %q, want the follower token", auth)
+	}
+	if planned != o {
+		t.Errorf("planned %+v, want %+v", planned, o)
+	}
+}
+
+func TestRunSession_FollowerTimelapse(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Defaults.Mode = config.ModeTimelapse
+	gate := web.NewSyncGate()
+	ctx := web.WithSyncGate(context.Background(), gate)
+	if err := runSession(ctx, cfg, nil, nil); err == nil {
+		t.Fatal("a timelapse ran as a multi-rig follower")
+	}
+	if _, err := gate.Ready(context.Background()); !errors.Is(err, web.ErrSyncEnded) {
+		t.Errorf("Ready = %v, want the gate closed", err)
+	}
+}
+
+// ---------- jogger ----------
+
+func TestJogger_DegreesAndSteps(t *testing.T) {
+	cfg := newTestConfig()
+	stepperCfg := stepper.Config{StepPin: 1, DirPin: 2, StepDelay: time.Microsecond}
+	r := &rig{
+		hw:   cfg,
+		pan:  stepper.NewStepper(&gpio.MockDriver{}, stepperCfg),
+		tilt: stepper.NewStepper(&gpio.MockDriver{}, stepperCfg),
+	}
+	var moves []capture.Position
+	r.events = func(kind string, data any) {
+		if kind == capture.EventMove {
+			moves = append(moves, data.(capture.Position))
+		}
+	}
+	jog := r.jogger()
+
+	if err := jog(context.Background(), web.JogRequest{Axis: "pan", Degrees: 10}); err != nil {
+		t.Fatalf("jog pan: %v", err)
+	}
+	if err := jog(context.Background(), web.JogRequest{Axis: "tilt", Steps: -25, SpeedMs: 1}); err != nil {
+		t.Fatalf("jog tilt: %v", err)
+	}
+
+	wantPan := geometry.NewStepsCalculator(cfg).PanStepsFromAngle(10)
+	if got := r.pan.Position(); got != wantPan {
+		t.Errorf("pan position = %d, want %d", got, wantPan)
+	}
+	if got := r.tilt.Position(); got != -25 {
+		t.Errorf("tilt position = %d, want -25", got)
+	}
+	if len(moves) != 2 || moves[1] != (capture.Position{PanSteps: wantPan, TiltSteps: -25}) {
+		t.Errorf("move events = %+v", moves)
+	}
+}
+
+func TestJogger_RefusedWhileBusy(t *testing.T) {
+	stepperCfg := stepper.Config{StepPin: 1, DirPin: 2, StepDelay: time.Microsecond}
+	r := &rig{
+		hw:   newTestConfig(),
+		pan:  stepper.NewStepper(&gpio.MockDriver{}, stepperCfg),
+		tilt: stepper.NewStepper(&gpio.MockDriver{}, stepperCfg),
+	}
+	r.busy.Lock()
+	defer r.busy.Unlock()
+
+	err := r.jogger()(context.Background(), web.JogRequest{Axis: "pan", Degrees: 1})
+	if !errors.Is(err, web.ErrHeadBusy) {
+		t.Errorf("err = %v, want ErrHeadBusy", err)
+	}
+	if got := r.pan.Position(); got != 0 {
+		t.Errorf("pan position = %d, want 0", got)
+	}
+}
+
+// ---------- position tracking ----------
+
+func TestRig_SamplePosition(t *testing.T) {
+	cfg := newTestConfig()
+	stepperCfg := stepper.Config{StepPin: 1, DirPin: 2, StepDelay: time.Microsecond}
+	r := &rig{hw: cfg}
+	var last capture.HeadPosition
+	if _, ok := r.samplePosition(&last); ok {
+		t.Error("sampled a position without motors")
+	}
+	r.pan = stepper.NewStepper(&gpio.MockDriver{}, stepperCfg)
+	r.tilt = stepper.NewStepper(&gpio.MockDriver{}, stepperCfg)
+	if _, ok := r.samplePosition(&last); ok {
+		t.Error("sampled a position at rest")
+	}
+
+	stepsCalc := geometry.NewStepsCalculator(cfg)
+	steps := stepsCalc.PanStepsFromAngle(90)
+	if err := r.pan.MoveSteps(steps); err != nil {
+		t.Fatal(err)
+	}
+	pos, ok := r.samplePosition(&last)
+	if !ok || !pos.Moving || pos.PanSteps != steps || math.Abs(pos.PanDeg-stepsCalc.PanAngleFromSteps(steps)) > 1e-9 {
+		t.Errorf("after moving: %+v, %v; want %d steps, moving", pos, ok, steps)
+	}
+	pos, ok = r.samplePosition(&last)
+	if !ok || pos.Moving || pos.PanSteps != steps {
+		t.Errorf("after stopping: %+v, %v; want a last sample, not moving", pos, ok)
+	}
+	if _, ok := r.samplePosition(&last); ok {
+		t.Error("sampled again while stopped")
+	}
+}
+
+// ---------- health ----------
+
+// checkingCamera is a camera that can report whether it is reachable.
+type checkingCamera struct{ err error }
+
+func (c *checkingCamera) Shoot() error                    { return nil }
+func (c *checkingCamera) Check(ctx context.Context) error { return c.err }
+
+func TestRig_Health(t *testing.T) {
+	var reinits []*config.Config
+	live, _ := newTestLiveConfig(t, &reinits)
+	r := &rig{gpio: &gpio.MockDriver{}}
+	health := r.health(live)
+
+	status := func() map[string]string {
+		got := map[string]string{}
+		for _, c := range health(context.Background()) {
+			got[c.Name] = c.Status + " " + c.Error
+		}
+		return got
+	}
+
+	if got := status(); got["gpio"] != "fail not initialized" || got["camera"] != "fail not initialized" || got["config"] != "ok " {
+		t.Errorf("before setup: %v", got)
+	}
+	if err := r.setup(live.get()); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(); got["gpio"] != "ok " || got["camera"] != "ok " {
+		t.Errorf("after setup: %v", got)
+	}
+
+	r.cam = &checkingCamera{err: errors.New("camera not found on USB")}
+	if got := status(); got["camera"] != "fail camera not found on USB" {
+		t.Errorf("unreachable camera: %v", got)
+	}
+
+	if err := os.WriteFile(live.path, []byte("camera: ["), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(); !strings.HasPrefix(got["config"], "fail ") {
+		t.Errorf("broken config file: %v", got)
+	}
+}
+
+// ---------- pageInfo ----------
+
+func TestRig_PageInfo(t *testing.T) {
+	var reinits []*config.Config
+	live, _ := newTestLiveConfig(t, &reinits)
+	r := &rig{gpio: &gpio.MockDriver{}}
+	page := r.pageInfo(live)
+
+	if got := page(); got.Profile != "pango" || got.CameraType != "" || got.Version == "" {
+		t.Errorf("before setup: %+v", got)
+	}
+	if err := r.setup(live.get()); err != nil {
+		t.Fatal(err)
+	}
+	want := web.PageInfo{
+		Version:      buildVersion(),
+		Profile:      "pango",
+		CameraType:   "nikon_d90_gpio",
+		Capabilities: web.Capabilities{MockGPIO: true},
+	}
+	if got := page(); got != want {
+		t.Errorf("after setup: %+v, want %+v", got, want)
+	}
+
+	r.cam = liveViewCamera{}
+	if got := page(); !got.Capabilities.LiveView || got.Capabilities.Download {
+		t.Errorf("live view camera: %+v", got.Capabilities)
+	}
+}
+
+// ---------- rigMetrics ----------
+
+func TestRigMetrics(t *testing.T) {
+	r := &rig{gpio: &gpio.MockDriver{}}
+	if err := r.setup(newTestConfig()); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	reg := metrics.NewRegistry()
+	m := newRigMetrics(reg, r, func() int { return 2 })
+	lc := capture.NewLifecycle()
+	lc.SetEvents(m.observe)
+
+	r.pan.MoveSteps(30)
+	r.pan.MoveSteps(-10)
+	// Re-initialized motors keep counting.
+	if err := r.reconfigure(newTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	r.tilt.MoveSteps(5)
+
+	for _, st := range []capture.State{capture.StatePlanning, capture.StateShooting, capture.StateError, capture.StateIdle, capture.StatePlanning, capture.StateShooting, capture.StateIdle} {
+		if err := lc.Transition(st); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.observe(capture.EventShot, nil)
+	m.observe(capture.EventShot, nil)
+
+	var b strings.Builder
+	if err := reg.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"pango_shots_total 2\n",
+		"pango_captures_started_total 2\n",
+		"pango_captures_failed_total 1\n",
+		"pango_capture_duration_seconds_count 2\n", // error -> idle is not a second end
+		`pango_steps_moved_total{axis="pan"} 40` + "\n",
+		`pango_steps_moved_total{axis="tilt"} 5` + "\n",
+		"pango_status_clients 2\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+// ---------- mdnsService ----------
+
+func TestMDNSService(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Web.MDNS.Name = "rig-2"
+	svc := mdnsService(cfg, 8443)
+	if err := svc.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if svc.Host != "rig-2" || svc.Type != "_pango._tcp" || svc.Port != 8443 {
+		t.Errorf("service = %+v", svc)
+	}
+	if svc.TXT[len(svc.TXT)-1] != "scheme=http" {
+		t.Errorf("TXT = %v, want scheme=http", svc.TXT)
+	}
+	cfg.Web.TLS.SelfSigned = true
+	if txt := mdnsService(cfg, 8443).TXT; txt[len(txt)-1] != "scheme=https" {
+		t.Errorf("TXT = %v, want scheme=https with TLS", txt)
+	}
+	cfg.Web.BasePath = "/pango"
+	if txt := mdnsService(cfg, 8443).TXT; txt[0] != "path=/pango/" {
+		t.Errorf("TXT = %v, want path=/pango/", txt)
+	}
+}
+
+// ---------- controlNetworks ----------
+
+func TestControlNetworks(t *testing.T) {
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(8, 32)},
+		&net.IPNet{IP: net.IPv4(192, 168, 1, 20), Mask: net.CIDRMask(24, 32)},
+		&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+	}
+	var got []string
+	for _, p := range controlNetworks([]string{"10.0.0.5", "local"}, addrs) {
+		got = append(got, p.String())
+	}
+	want := []string{"10.0.0.5/32", "127.0.0.0/8", "192.168.1.0/24", "fe80::/64"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("controlNetworks = %v, want %v", got, want)
+	}
+}
+
+// ---------- interfaceURL ----------
+
+func TestInterfaceURL(t *testing.T) {
+	cfg := newTestConfig()
+	addrs := []net.Addr{
+		&net.IPNet{IP: net.IPv4(127, 0, 0, 1), Mask: net.CIDRMask(8, 32)},
+		&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+		&net.IPNet{IP: net.IPv4(169, 254, 3, 4), Mask: net.CIDRMask(16, 32)},
+		&net.IPNet{IP: net.IPv4(192, 168, 1, 20), Mask: net.CIDRMask(24, 32)},
+	}
+	if got := interfaceURL(cfg, 8080, addrs); got != "http://192.168.1.20:8080/" {
+		t.Errorf("interfaceURL = %q, want the LAN address", got)
+	}
+	if got := interfaceURL(cfg, 8080, addrs[:3]); got != "" {
+		t.Errorf("interfaceURL = %q without a LAN address, want empty", got)
+	}
+
+	cfg.Web.MDNS.Enabled = true
+	cfg.Web.MDNS.Name = "rig-2"
+	cfg.Web.TLS.SelfSigned = true
+	cfg.Web.BasePath = "/pango"
+	if got := interfaceURL(cfg, 8443, addrs); got != "https://rig-2.local:8443/pango/" {
+		t.Errorf("interfaceURL = %q, want the mDNS name", got)
+	}
+}
+
+// ---------- liveConfig ----------
+
+// newTestLiveConfig writes newTestConfig to configs/pango.yaml and returns a
+// liveConfig for it, plus the YAML document. Hardware re-initializations are
+// recorded in reinits.
+func newTestLiveConfig(t *testing.T, reinits *[]*config.Config) (*liveConfig, []byte) {
+	t.Helper()
+	cfg := newTestConfig()
+	cfg.Web.Auth.Token = "0123456789abcdef"
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "configs")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "pango.yaml")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newLiveConfig(loaded, path, func(cfg *config.Config) error {
+		*reinits = append(*reinits, cfg)
+		return nil
+	}), data
+}
+
+func TestLiveConfig_DocumentHidesWebSettings(t *testing.T) {
+	live, _ := newTestLiveConfig(t, new([]*config.Config))
+	doc, err := live.document()
+	if err != nil {
+		t.Fatalf("document: %v", err)
+	}
+	m := doc.(map[string]any)
+	if _, ok := m["web"]; ok {
+		t.Error("document must not include the web section")
+	}
+	lens, _ := m["lens"].(map[string]any)
+	if fmt.Sprint(lens["focal_length_mm"]) != "35" {
+		t.Errorf("lens = %v", m["lens"])
+	}
+}
+
+func TestLiveConfig_Update(t *testing.T) {
+	var reinits []*config.Config
+	live, data := newTestLiveConfig(t, &reinits)
+	edited := strings.Replace(string(data), "focal_length_mm: 35", "focal_length_mm: 85", 1)
+	edited = strings.Replace(edited, "0123456789abcdef", "", 1) // cannot drop auth over the API
+
+	update, err := live.update([]byte(edited), true)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if !update.Saved || update.RestartRequired || update.Reinitialized || update.Form.FocalLengthMm != 85 {
+		t.Errorf("update = %+v", update)
+	}
+	if got := live.get(); got.Lens.FocalLengthMm != 85 || got.Web.Auth.Token != "0123456789abcdef" {
+		t.Errorf("live config lens %v, token %q", got.Lens.FocalLengthMm, got.Web.Auth.Token)
+	}
+	saved, err := config.Load(live.path)
+	if err != nil {
+		t.Fatalf("load saved: %v", err)
+	}
+	if saved.Lens.FocalLengthMm != 85 || saved.Web.Auth.Token != "0123456789abcdef" {
+		t.Errorf("saved lens %v, token %q", saved.Lens.FocalLengthMm, saved.Web.Auth.Token)
+	}
+
+	edited = strings.Replace(edited, "step_pin: 17", "step_pin: 12", 1)
+	update, err = live.update([]byte(edited), false)
+	if err != nil || !update.Reinitialized || update.RestartRequired || update.Saved {
+		t.Errorf("pin change: update = %+v, err = %v", update, err)
+	}
+	if len(reinits) != 1 || reinits[0].PanStepper.StepPin != 12 {
+		t.Errorf("hardware re-initializations = %d, want one with the new pin", len(reinits))
+	}
+}
+
+func TestLiveConfig_UpdateInvalid(t *testing.T) {
+	live, data := newTestLiveConfig(t, new([]*config.Config))
+	before := live.get()
+	edited := strings.Replace(string(data), "overlap_percent: 30", "overlap_percent: 130", 1)
+
+	_, err := live.update([]byte(edited), true)
+	if !errors.Is(err, web.ErrInvalidConfig) {
+		t.Fatalf("err = %v, want ErrInvalidConfig", err)
+	}
+	if live.get() != before {
+		t.Error("an invalid document must not replace the config")
+	}
+	if _, err := os.Stat(live.path + ".bak"); !os.IsNotExist(err) {
+		t.Error("an invalid document must not be saved")
+	}
+}
+
+func TestLiveConfig_UpdateRefusedWhileBusy(t *testing.T) {
+	live, data := newTestLiveConfig(t, new([]*config.Config))
+	live.reinit = func(*config.Config) error { return web.ErrHeadBusy }
+	before := live.get()
+
+	edited := strings.Replace(string(data), "step_pin: 17", "step_pin: 12", 1)
+	if _, err := live.update([]byte(edited), true); !errors.Is(err, web.ErrHeadBusy) {
+		t.Fatalf("err = %v, want ErrHeadBusy", err)
+	}
+	if live.get() != before {
+		t.Error("config must not change when the hardware cannot be re-initialized")
+	}
+}
+
+func TestLiveConfig_Profiles(t *testing.T) {
+	var reinits []*config.Config
+	live, data := newTestLiveConfig(t, &reinits)
+	dir := filepath.Dir(live.path)
+
+	tele := strings.Replace(string(data), "focal_length_mm: 35", "focal_length_mm: 200", 1)
+	tele = strings.Replace(tele, "microstepping: 16", "microstepping: 32", 1)
+	if err := os.WriteFile(filepath.Join(dir, "tele-200mm.yaml"), []byte(tele), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := live.profiles()
+	if err != nil {
+		t.Fatalf("profiles: %v", err)
+	}
+	if profiles.Active != "pango" || strings.Join(profiles.Available, ",") != "pango,tele-200mm" {
+		t.Errorf("profiles = %+v", profiles)
+	}
+
+	update, err := live.activate("tele-200mm")
+	if err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+	if !update.Reinitialized || update.Form.FocalLengthMm != 200 {
+		t.Errorf("update = %+v", update)
+	}
+	if live.get().Lens.FocalLengthMm != 200 || len(reinits) != 1 {
+		t.Errorf("focal length %v, %d re-initializations", live.get().Lens.FocalLengthMm, len(reinits))
+	}
+	if profiles, _ := live.profiles(); profiles.Active != "tele-200mm" {
+		t.Errorf("active profile = %q, want tele-200mm", profiles.Active)
+	}
+	if live.get().Web.Auth.Token != "0123456789abcdef" {
+		t.Error("web settings must be kept across profiles")
+	}
+
+	if _, err := live.activate("../pango"); !errors.Is(err, web.ErrProfileNotFound) {
+		t.Errorf("err = %v, want ErrProfileNotFound", err)
+	}
+}
+
+func TestLiveConfig_InFileProfiles(t *testing.T) {
+	var reinits []*config.Config
+	live, data := newTestLiveConfig(t, &reinits)
+	withProfiles := string(data) + "profiles:\n  macro:\n    lens:\n      focal_length_mm: 100\n"
+	if err := os.WriteFile(live.path, []byte(withProfiles), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(filepath.Dir(live.path), "tele-200mm.yaml"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := live.profiles()
+	if err != nil {
+		t.Fatalf("profiles: %v", err)
+	}
+	if strings.Join(profiles.Available, ",") != "pango,pango/macro,tele-200mm" {
+		t.Errorf("available profiles = %v", profiles.Available)
+	}
+
+	update, err := live.activate("pango/macro")
+	if err != nil {
+		t.Fatalf("activate: %v", err)
+	}
+	if update.Form.FocalLengthMm != 100 || live.get().Lens.FocalLengthMm != 100 {
+		t.Errorf("focal length = %v, want the profile's", live.get().Lens.FocalLengthMm)
+	}
+	if live.profile() != "pango/macro" {
+		t.Errorf("active profile = %q", live.profile())
+	}
+	if _, err := live.reload(); err != nil || live.get().Lens.FocalLengthMm != 100 {
+		t.Errorf("reload dropped the profile: %v, focal length %v", err, live.get().Lens.FocalLengthMm)
+	}
+	if _, err := live.update([]byte(withProfiles), true); !errors.Is(err, web.ErrInvalidConfig) {
+		t.Errorf("saving with a profile of the file active: err = %v, want ErrInvalidConfig", err)
+	}
+
+	if _, err := live.activate("pango"); err != nil || live.get().Lens.FocalLengthMm != 35 {
+		t.Errorf("back to the file: %v, focal length %v", err, live.get().Lens.FocalLengthMm)
+	}
+	for _, name := range []string{"pango/missing", "missing/macro"} {
+		if _, err := live.activate(name); !errors.Is(err, web.ErrProfileNotFound) {
+			t.Errorf("activate(%q) err = %v, want ErrProfileNotFound", name, err)
+		}
+	}
+}
+
+func TestLoadRunConfig(t *testing.T) {
+	var reinits []*config.Config
+	live, data := newTestLiveConfig(t, &reinits)
+	dir := filepath.Dir(live.path)
+	withProfiles := string(data) + "profiles:\n  macro:\n    lens:\n      focal_length_mm: 100\n"
+	if err := os.WriteFile(live.path, []byte(withProfiles), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tele := strings.Replace(string(data), "focal_length_mm: 35", "focal_length_mm: 200", 1)
+	tele += "profiles:\n  wide:\n    defaults:\n      horizontal_angle_deg: 90\n"
+	if err := os.WriteFile(filepath.Join(dir, "tele-200mm.yaml"), []byte(tele), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	overrides := web.Overrides{HorizontalAngleDeg: 270, StabilizationDelayMs: 1200}
+	cases := []struct {
+		profile string
+		active  string // same profile, as named by GET /profiles
+		focal   float64
+		entry   string
+	}{
+		{"", "", 35, ""},
+		{"macro", "pango/macro", 100, "macro"},
+		{"pango/macro", "pango/macro", 100, "macro"},
+		{"tele-200mm", "tele-200mm", 200, ""},
+		{"tele-200mm/wide", "tele-200mm/wide", 200, "wide"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.profile, func(t *testing.T) {
+			cfg, err := loadRunConfig(live.path, tc.profile, overrides)
+			if err != nil {
+				t.Fatalf("loadRunConfig: %v", err)
+			}
+			if cfg.Lens.FocalLengthMm != tc.focal || cfg.Profile != tc.entry {
+				t.Errorf("focal length %v, entry %q; want %v, %q", cfg.Lens.FocalLengthMm, cfg.Profile, tc.focal, tc.entry)
+			}
+			if cfg.Defaults.HorizontalAngleDeg != 270 || cfg.Defaults.StabilizationDelayMs != 1200 {
+				t.Errorf("overrides not applied over the profile: %+v", cfg.Defaults)
+			}
+			if tc.active == "" {
+				return
+			}
+			// The web path: activate the profile, then run with the overrides.
+			if _, err := live.activate(tc.active); err != nil {
+				t.Fatalf("activate: %v", err)
+			}
+			fromWeb := applyOverridesToCopy(live.get(), overrides)
+			if fromWeb.Lens != cfg.Lens || fromWeb.Defaults != cfg.Defaults {
+				t.Errorf("CLI config differs from the web's:\nCLI %+v\nweb %+v", cfg.Defaults, fromWeb.Defaults)
+			}
+		})
+	}
+
+	for _, name := range []string{"missing", "missing/macro", "pango/missing", "../pango"} {
+		if _, err := loadRunConfig(live.path, name, web.Overrides{}); err == nil {
+			t.Errorf("loadRunConfig(%q): want an error", name)
+		}
+	}
+}
+
+func TestLiveConfig_UpdateKeepsSecretRefs(t *testing.T) {
+	t.Setenv("TEST_PANGO_TOKEN", "0123456789abcdef")
+	live, data := newTestLiveConfig(t, new([]*config.Config))
+	withRef := strings.Replace(string(data), "token: 0123456789abcdef", "token: ${env:TEST_PANGO_TOKEN}", 1)
+	if err := os.WriteFile(live.path, []byte(withRef), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load(live.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	live = newLiveConfig(cfg, live.path, nil)
+
+	doc := strings.Replace(withRef, "focal_length_mm: 35", "focal_length_mm: 50", 1)
+	doc = strings.Replace(doc, "token: ${env:TEST_PANGO_TOKEN}", `token: ""`, 1)
+	if _, err := live.update([]byte(doc), true); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	saved := string(mustReadFile(t, live.path))
+	if strings.Contains(saved, "0123456789abcdef") || !strings.Contains(saved, "${env:TEST_PANGO_TOKEN}") {
+		t.Errorf("saved file does not keep the token reference:\n%s", saved)
+	}
+	if live.get().Web.Auth.Token != "0123456789abcdef" || live.get().Lens.FocalLengthMm != 50 {
+		t.Errorf("live token %q, focal length %v", live.get().Web.Auth.Token, live.get().Lens.FocalLengthMm)
+	}
+}
+
+func TestLiveConfig_SaveDefaults(t *testing.T) {
+	var reinits []*config.Config
+	live, _ := newTestLiveConfig(t, &reinits)
+	dir := filepath.Dir(live.path)
+	form := web.Overrides{HorizontalAngleDeg: 270, VerticalAngleDeg: 45, FocalLengthMm: 50, StabilizationDelayMs: 900}
+
+	saved, err := live.saveDefaults("", form)
+	if err != nil {
+		t.Fatalf("saveDefaults: %v", err)
+	}
+	if saved.Profile != "pango" || saved.Created || !saved.Active || saved.Form.HorizontalAngleDeg != 270 {
+		t.Errorf("saved = %+v", saved)
+	}
+	cfg, err := config.Load(live.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Defaults.HorizontalAngleDeg != 270 || cfg.Lens.FocalLengthMm != 50 || cfg.Defaults.StabilizationDelayMs != 900 || cfg.Defaults.InterMoveDelayMs != 500 {
+		t.Errorf("file defaults = %+v, lens %v", cfg.Defaults, cfg.Lens.FocalLengthMm)
+	}
+	if cfg.Web.Auth.Token != "0123456789abcdef" {
+		t.Error("other settings of the file were lost")
+	}
+	if live.get().Defaults.VerticalAngleDeg != 45 || len(reinits) != 0 {
+		t.Errorf("live vertical angle = %v, reinits = %d", live.get().Defaults.VerticalAngleDeg, len(reinits))
+	}
+
+	saved, err = live.saveDefaults("tele-200mm", web.Overrides{HorizontalAngleDeg: 90, VerticalAngleDeg: 20, FocalLengthMm: 200})
+	if err != nil {
+		t.Fatalf("saveDefaults tele-200mm: %v", err)
+	}
+	if !saved.Created || saved.Active {
+		t.Errorf("saved = %+v, want a new inactive profile", saved)
+	}
+	tele, err := config.Load(filepath.Join(dir, "tele-200mm.yaml"))
+	if err != nil || tele.Lens.FocalLengthMm != 200 || tele.Defaults.StabilizationDelayMs != 900 {
+		t.Errorf("tele-200mm = %+v, %v", tele, err)
+	}
+	if live.get().Lens.FocalLengthMm != 50 {
+		t.Errorf("saving another profile changed the live config: %v", live.get().Lens.FocalLengthMm)
+	}
+
+	if _, err := live.saveDefaults("tele-200mm/macro", web.Overrides{FocalLengthMm: 105}); err != nil {
+		t.Fatalf("saveDefaults tele-200mm/macro: %v", err)
+	}
+	if tele, err = loadProfile(filepath.Join(dir, "tele-200mm.yaml"), "macro"); err != nil || tele.Lens.FocalLengthMm != 105 {
+		t.Errorf("tele-200mm/macro = %v, %v", tele, err)
+	}
+	if _, err := live.saveDefaults("missing/macro", form); !errors.Is(err, web.ErrProfileNotFound) {
+		t.Errorf("entry of a missing file: err = %v, want ErrProfileNotFound", err)
+	}
+	if _, err := live.saveDefaults(".hidden", form); !errors.Is(err, web.ErrInvalidConfig) {
+		t.Errorf("invalid name: err = %v, want ErrInvalidConfig", err)
+	}
+}
+
+func TestLiveConfig_Upload(t *testing.T) {
+	var reinits []*config.Config
+	live, data := newTestLiveConfig(t, &reinits)
+	tele := strings.Replace(string(data), "focal_length_mm: 35", "focal_length_mm: 200", 1)
+
+	result, err := live.upload("tele-200mm", []byte(tele), false, false)
+	if err != nil || result.Profile != "tele-200mm" || result.Activated {
+		t.Fatalf("upload = %+v, %v", result, err)
+	}
+	if live.get().Lens.FocalLengthMm != 35 {
+		t.Error("upload without activate must not change the live configuration")
+	}
+
+	if _, err := live.upload("tele-200mm", []byte(tele), false, true); !errors.Is(err, web.ErrProfileExists) {
+		t.Errorf("err = %v, want ErrProfileExists", err)
+	}
+	result, err = live.upload("tele-200mm", []byte(tele), true, true)
+	if err != nil || !result.Activated || result.Form.FocalLengthMm != 200 {
+		t.Fatalf("upload and activate = %+v, %v", result, err)
+	}
+	if profiles, _ := live.profiles(); profiles.Active != "tele-200mm" || live.get().Lens.FocalLengthMm != 200 {
+		t.Errorf("active profile = %q, focal length %v", profiles.Active, live.get().Lens.FocalLengthMm)
+	}
+
+	for name, doc := range map[string]string{"bad-name/x": tele, "broken": "lens: ["} {
+		if _, err := live.upload(name, []byte(doc), true, false); !errors.Is(err, web.ErrInvalidConfig) {
+			t.Errorf("upload(%q): err = %v, want ErrInvalidConfig", name, err)
+		}
+	}
+}
+
+func TestRig_Reconfigure(t *testing.T) {
+	r := &rig{gpio: &gpio.MockDriver{}}
+	cfg := newTestConfig()
+	if err := r.setup(cfg); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := r.pan.MoveSteps(40); err != nil {
+		t.Fatal(err)
+	}
+
+	next := newTestConfig()
+	next.PanStepper.Microstepping = 32
+	if err := r.reconfigure(next); err != nil {
+		t.Fatalf("reconfigure: %v", err)
+	}
+	if r.hw != next || r.pan.Position() != 0 {
+		t.Errorf("hw = %p (want %p), pan position = %d (want 0)", r.hw, next, r.pan.Position())
+	}
+
+	bad := newTestConfig()
+	bad.Camera.Type = "unknown"
+	if err := r.reconfigure(bad); err == nil {
+		t.Error("expected error for an unsupported camera")
+	}
+	if r.hw != next {
+		t.Error("hardware must not change when the new camera cannot be built")
+	}
+
+	r.busy.Lock()
+	defer r.busy.Unlock()
+	if err := r.reconfigure(cfg); !errors.Is(err, web.ErrHeadBusy) {
+		t.Errorf("err = %v, want ErrHeadBusy", err)
+	}
+	if r.hw != next {
+		t.Error("hardware must not change while busy")
+	}
+}
+
+func TestRig_SetupChecksBoardPins(t *testing.T) {
+	pi := &board.Board{
+		Model:   "Raspberry Pi 4 Model B Rev 1.4",
+		Header:  []int{2, 3, 5, 6, 17, 22, 23, 24, 25, 27},
+		Enabled: []board.Function{board.Functions[0]}, // I2C
+	}
+	r := &rig{gpio: &gpio.MockDriver{}, board: pi}
+	cfg := newTestConfig()
+	cfg.Defaults.MockGPIO = false
+	cfg.Camera.FocusPin, cfg.Camera.ShutterPin = 24, 25
+	if err := r.setup(cfg); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	bad := *cfg
+	bad.Camera.FocusPin = 2
+	err := r.reconfigure(&bad)
+	if err == nil || !strings.Contains(err.Error(), "GPIO 2/3 are I2C (camera.focus_pin = 2); disable i2c") {
+		t.Errorf("reconfigure = %v, want the I2C pins refused", err)
+	}
+	if r.hw != cfg {
+		t.Error("hardware must not change when pins are not usable")
+	}
+
+	// Mock GPIO drives no pin
+	bad.Defaults.MockGPIO = true
+	if err := r.reconfigure(&bad); err != nil {
+		t.Errorf("reconfigure with mock GPIO: %v", err)
+	}
+}
+
+// pinRecorder is a mock GPIO driver remembering the last level written to each pin.
+type pinRecorder struct {
+	gpio.MockDriver
+	mu     sync.Mutex
+	levels map[int]gpio.Level
+}
+
+func (p *pinRecorder) WritePin(pin int, level gpio.Level) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.levels == nil {
+		p.levels = map[int]gpio.Level{}
+	}
+	p.levels[pin] = level
+	return nil
+}
+
+func (p *pinRecorder) level(pin int) gpio.Level {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.levels[pin]
+}
+
+func TestRig_Park(t *testing.T) {
+	withHeadPath(t)
+	pins := &pinRecorder{}
+	r := &rig{gpio: pins}
+	cfg := newTestConfig()
+	if err := r.setup(cfg); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	enablePins := []int{cfg.PanStepper.EnablePin, cfg.TiltStepper.EnablePin}
+
+	r.busy.Lock()
+	r.park()
+	for _, pin := range enablePins {
+		if pins.level(pin) != gpio.Low {
+			t.Errorf("busy: enable pin %d changed, want motors left enabled", pin)
+		}
+	}
+	r.busy.Unlock()
+
+	r.park()
+	for _, pin := range enablePins {
+		if pins.level(pin) != gpio.High {
+			t.Errorf("enable pin %d = %v, want HIGH (motors disabled)", pin, pins.level(pin))
+		}
+	}
+}
+
+// liveViewCamera is a camera with a live view, for rig.liveView.
+type liveViewCamera struct{}
+
+func (liveViewCamera) Shoot() error { return nil }
+
+func (liveViewCamera) LiveViewFrame(context.Context) ([]byte, error) {
+	return []byte("jpeg"), nil
+}
+
+func TestRig_LiveView(t *testing.T) {
+	r := &rig{gpio: &gpio.MockDriver{}, lifecycle: capture.NewLifecycle()}
+	if err := r.setup(newTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	lv := r.liveView()
+
+	if _, err := lv(context.Background()); !errors.Is(err, web.ErrLiveViewUnsupported) {
+		t.Errorf("GPIO camera: err = %v, want ErrLiveViewUnsupported", err)
+	}
+
+	r.cam = liveViewCamera{}
+	if frame, err := lv(context.Background()); err != nil || string(frame) != "jpeg" {
+		t.Errorf("frame = %q, err = %v", frame, err)
+	}
+
+	if err := r.lifecycle.Transition(capture.StatePlanning); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lv(context.Background()); !errors.Is(err, web.ErrHeadBusy) {
+		t.Errorf("during a capture: err = %v, want ErrHeadBusy", err)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hw/encoder"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// panelDebounce is how long the panel button must hold its level to count.
+const panelDebounce = 20 * time.Millisecond
+
+// startPanel reads the front panel of cfg (panel.pin_a) with the GPIO of r
+// until ctx is done, driving the menu of h. It returns the menu, or nil
+// when no panel is set or the GPIO is mocked.
+func startPanel(ctx context.Context, cfg *config.Config, r *rig, h *web.Handlers) *web.Panel {
+	p := cfg.Panel
+	if p.PinA == 0 {
+		return nil
+	}
+	if cfg.Defaults.MockGPIO {
+		// The mock driver never reports a level change: the knob would not turn.
+		log.Printf("mock GPIO: front panel on pins %d/%d ignored", p.PinA, p.PinB)
+		return nil
+	}
+	enc := encoder.New(r.gpio, encoder.Options{
+		PinA:            p.PinA,
+		PinB:            p.PinB,
+		ButtonPin:       p.ButtonPin,
+		ButtonActiveLow: p.ButtonActiveLow,
+		StepsPerDetent:  p.StepsPerDetent,
+		Debounce:        panelDebounce,
+		LongPress:       time.Duration(p.LongPressMs) * time.Millisecond,
+	})
+	panel := web.NewPanel(h)
+	go func() {
+		err := enc.Run(ctx, func(e encoder.Event) { panelEvent(ctx, panel, e) })
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("panel: %v", err)
+		}
+	}()
+	log.Printf("panel: rotary encoder on pins %d/%d, button on pin %d", p.PinA, p.PinB, p.ButtonPin)
+	return panel
+}
+
+// panelEvent acts on an event of the front panel encoder, logging the
+// outcome of presses.
+func panelEvent(ctx context.Context, panel *web.Panel, e encoder.Event) {
+	switch e {
+	case encoder.Clockwise:
+		panel.Turn(1)
+		return
+	case encoder.Counterclockwise:
+		panel.Turn(-1)
+		return
+	case encoder.Press:
+		panel.Press(ctx)
+	case encoder.LongPress:
+		panel.LongPress(ctx)
+	}
+	log.Printf("panel: %s", panel.Menu().Message)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hw/encoder"
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+func TestStartPanel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h := web.NewServer(":1", web.NewStatusBroadcaster(), nil, web.FormConfig{}).Handlers() // not serving
+	r := &rig{gpio: &gpio.MockDriver{}}
+
+	cfg := &config.Config{}
+	if p := startPanel(ctx, cfg, r, h); p != nil {
+		t.Error("panel started without pins")
+	}
+	cfg.Panel = config.PanelConfig{PinA: 16, PinB: 20, ButtonPin: 21, StepsPerDetent: 4, LongPressMs: 1000}
+	cfg.Defaults.MockGPIO = true
+	if p := startPanel(ctx, cfg, r, h); p != nil {
+		t.Error("panel started with mock GPIO")
+	}
+	cfg.Defaults.MockGPIO = false
+	if p := startPanel(ctx, cfg, r, h); p == nil {
+		t.Error("panel not started")
+	}
+}
+
+func TestPanelEvent(t *testing.T) {
+	h := web.NewServer(":1", web.NewStatusBroadcaster(), nil, web.FormConfig{}).Handlers()
+	h.Profiles = func() (web.Profiles, error) {
+		return web.Profiles{Active: "wide", Available: []string{"wide"}}, nil
+	}
+	panel := web.NewPanel(h)
+	ctx := context.Background()
+
+	panelEvent(ctx, panel, encoder.Clockwise)
+	if m := panel.Menu(); m.Selected != 1 {
+		t.Errorf("after clockwise: selected %d, want 1", m.Selected)
+	}
+	panelEvent(ctx, panel, encoder.Counterclockwise)
+	if m := panel.Menu(); m.Selected != 0 {
+		t.Errorf("after counterclockwise: selected %d, want 0", m.Selected)
+	}
+	panelEvent(ctx, panel, encoder.LongPress)
+	if m := panel.Menu(); m.Message != "no capture in progress" {
+		t.Errorf("after long press: message %q, want no capture", m.Message)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hugin"
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+const planUsage = `Usage: pango plan [flags]
+
+Print the capture pango run would make with the configuration: field of
+view of the lens, grid, motor steps between shots, shot count and estimated
+duration. The GPIO pins are not touched, so plans can be made on a laptop
+before going on site.
+
+With -pto, also write a Hugin project of the grid: one image per planned
+shot, in shooting order, at its yaw and pitch and with the field of view of
+the lens, so that stitching starts from the known geometry. Name the images
+as the camera will with -images and -first, e.g. -images DSC_%04d.NEF
+-first 1234.
+
+Flags:
+`
+
+// runPlan implements "pango plan".
+func runPlan(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("plan", planUsage, stderr)
+	f := addConfigFlags(fs, true)
+	asJSON := fs.Bool("json", false, "print the plan as JSON, the document of POST /plan")
+	pto := fs.String("pto", "", "also write a Hugin project of the planned shots to this file")
+	images := fs.String("images", hugin.DefaultNames, "file names of the images in the Hugin project, numbered with its integer verb")
+	first := fs.Int("first", 1, "number of the first image in the Hugin project")
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	names, err := hugin.NewNamer(*images, *first)
+	if err != nil {
+		fmt.Fprintf(stderr, "pango plan: %v\n", err)
+		return 2
+	}
+	cfg, err := f.load()
+	if err == nil {
+		err = printPlan(stdout, cfg, *asJSON)
+	}
+	if err == nil && *pto != "" {
+		err = writePlanHugin(*pto, cfg, names)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "pango plan: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// printPlan writes the capture plan of cfg to w, as text or as JSON.
+func printPlan(w io.Writer, cfg *config.Config, asJSON bool) error {
+	plan, err := planCapture(cfg, web.Overrides{})
+	if err != nil {
+		return err
+	}
+	if asJSON {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\n", data)
+		return nil
+	}
+
+	if plan.Mode == config.ModeGrid {
+		fov, err := geometry.NewFOVCalculator(cfg)
+		if err != nil {
+			return err
+		}
+		steps := geometry.NewStepsCalculator(cfg)
+		fmt.Fprintf(w, "Lens:     %g mm", cfg.Lens.FocalLengthMm)
+		if cfg.Lens.Name != "" {
+			fmt.Fprintf(w, " (%s)", cfg.Lens.Name)
+		}
+		fmt.Fprintf(w, ", sensor %g × %g mm\n", cfg.Sensor.WidthMm, cfg.Sensor.HeightMm)
+		fmt.Fprintf(w, "FOV:      %.1f° × %.1f° per shot, %g%% overlap\n", fov.HorizontalFOV(), fov.VerticalFOV(), cfg.Defaults.OverlapPercent)
+		grids := plan.Panoramas
+		if len(grids) == 0 {
+			fmt.Fprintf(w, "Area:     %g° × %g°\n", cfg.Defaults.HorizontalAngleDeg, cfg.Defaults.VerticalAngleDeg)
+			grids = []web.PlanGrid{{Columns: plan.Columns, Rows: plan.Rows, PanStepSize: plan.PanStepSize, TiltStepSize: plan.TiltStepSize}}
+		}
+		for _, g := range grids {
+			if len(plan.Panoramas) > 0 {
+				fmt.Fprintf(w, "Panorama: %s\n", g.Name)
+			}
+			fmt.Fprintf(w, "Grid:     %d columns × %d rows\n", g.Columns, g.Rows)
+			fmt.Fprintf(w, "Steps:    pan %d steps (%.1f°), tilt %d steps (%.1f°) between shots\n",
+				g.PanStepSize, steps.PanAngleFromSteps(g.PanStepSize), g.TiltStepSize, steps.TiltAngleFromSteps(g.TiltStepSize))
+		}
+	} else {
+		fmt.Fprintf(w, "Mode:     %s\n", plan.Mode)
+	}
+	duration := time.Duration(plan.EstimatedSec * float64(time.Second)).Round(time.Second)
+	fmt.Fprintf(w, "Shots:    %d\n", plan.TotalShots)
+	fmt.Fprintf(w, "Duration: about %v\n", duration)
+	return nil
+}
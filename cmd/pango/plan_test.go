@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hugin"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// ---------- pango plan ----------
+
+func TestRunPlan(t *testing.T) {
+	dir := validateDir(t)
+	// Real GPIO: plan must not open it.
+	path := writeValidateConfig(t, dir, "rig.yaml", func(c *config.Config) {
+		c.Defaults.MockGPIO = false
+		c.Lens.Name = "Nikkor 35mm"
+	})
+
+	var out, errOut bytes.Buffer
+	if code := runPango(context.Background(), []string{"plan", "-config", path}, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d: %s", code, errOut.String())
+	}
+	want := `Lens:     35 mm (Nikkor 35mm), sensor 23.6 × 15.8 mm
+FOV:      37.3° × 25.4° per shot, 30% overlap
+Area:     180° × 30°
+Grid:     7 columns × 2 rows
+Steps:    pan 231 steps (26.0°), tilt 158 steps (17.8°) between shots
+Shots:    14
+Duration: about 22s
+`
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestRunPlan_Overrides(t *testing.T) {
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", nil)
+
+	var out, errOut bytes.Buffer
+	args := []string{"plan", "-config", path, "-json", "-horizontal_angle_deg", "360", "-focal_length_mm", "50"}
+	if code := runPango(context.Background(), args, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d: %s", code, errOut.String())
+	}
+	var got web.Plan
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("decode %q: %v", out.String(), err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := planCapture(cfg, web.Overrides{HorizontalAngleDeg: 360, FocalLengthMm: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Columns != want.Columns || got.Rows != want.Rows || got.TotalShots != want.TotalShots || got.EstimatedSec != want.EstimatedSec {
+		t.Errorf("plan = %+v, want POST /plan's %+v", got, want)
+	}
+}
+
+func TestRunPlan_PacingOverrides(t *testing.T) {
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", nil)
+
+	var out, errOut bytes.Buffer
+	args := []string{"plan", "-config", path, "-overlap_percent", "50", "-shot_delay_ms", "1000", "-move_speed_ms", "4"}
+	if code := runPango(context.Background(), args, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "50% overlap") {
+		t.Errorf("output lacks the overlap override:\n%s", out.String())
+	}
+
+	for _, bad := range [][]string{{"-overlap_percent", "100"}, {"-shot_delay_ms", "-1"}, {"-move_speed_ms", "1001"}} {
+		errOut.Reset()
+		if code := runPango(context.Background(), append([]string{"plan", "-config", path}, bad...), &out, &errOut); code == 0 {
+			t.Errorf("%v: exit status 0", bad)
+		}
+		if !strings.Contains(errOut.String(), "invalid override") {
+			t.Errorf("%v: stderr = %q", bad, errOut.String())
+		}
+	}
+}
+
+func TestRunPlan_Hugin(t *testing.T) {
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "rig.yaml", nil)
+	pto := filepath.Join(t.TempDir(), "pano.pto")
+
+	var out, errOut bytes.Buffer
+	args := []string{"plan", "-config", path, "-pto", pto, "-images", "DSC_%04d.NEF", "-first", "10"}
+	if code := runPango(context.Background(), args, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d: %s", code, errOut.String())
+	}
+	data, err := os.ReadFile(pto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var images []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "i ") {
+			images = append(images, line)
+		}
+	}
+	// 7 columns × 2 rows from the top left, the second column bottom up.
+	if len(images) != 14 {
+		t.Fatalf("%d images, want 14:\n%s", len(images), data)
+	}
+	for i, want := range map[int]string{
+		0:  ` f0 v37.262333 a0 b0 c0 d0 e0 g0 t0 Ra0 Rb0 Rc0 Rd0 Re0 Eev0 Er1 Eb1 r0 p14.9625 y-90 `,
+		1:  ` r0 p-2.8125 y-90 `,
+		2:  ` r0 p-2.8125 y-64.0125 `,
+		3:  ` r0 p14.9625 y-64.0125 `,
+		13: `n"DSC_0023.NEF"`,
+	} {
+		if !strings.Contains(images[i], want) {
+			t.Errorf("image %d does not contain %q: %s", i, want, images[i])
+		}
+	}
+
+	out.Reset()
+	errOut.Reset()
+	if code := runPango(context.Background(), []string{"plan", "-config", path, "-pto", pto, "-images", "DSC.NEF"}, &out, &errOut); code != 2 {
+		t.Errorf("-images without a number: exit status = %d, want 2", code)
+	}
+}
+
+func TestWritePlanHugin_Unsupported(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(c *config.Config)
+	}{
+		{"timelapse", func(c *config.Config) { c.Defaults.Mode = config.ModeTimelapse }},
+		{"no_sensor", func(c *config.Config) { c.Sensor = nil }},
+		{"panoramas", func(c *config.Config) {
+			c.Panoramas = []config.PanoramaConfig{{Name: "sky"}, {Name: "ground"}}
+		}},
+	}
+	names, _ := hugin.NewNamer(hugin.DefaultNames, 1)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := newTestConfig()
+			tc.edit(cfg)
+			if err := writePlanHugin(filepath.Join(t.TempDir(), "pano.pto"), cfg, names); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestPrintPlan_Panoramas(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Panoramas = []config.PanoramaConfig{
+		{Name: "sky", VerticalAngleDeg: 20, TiltCenterDeg: 40},
+		{Name: "ground", HorizontalAngleDeg: 90},
+	}
+	var out bytes.Buffer
+	if err := printPlan(&out, cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Panorama: sky\nGrid:     7 columns × 2 rows\n",
+		"Panorama: ground\nGrid:     4 columns × 2 rows\n",
+		"Shots:    22\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "Area:") {
+		t.Errorf("panoramas have their own areas:\n%s", out.String())
+	}
+}
+
+func TestPrintPlan_Timelapse(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Defaults.Mode = config.ModeTimelapse
+	cfg.Timelapse.Frames = 120
+	cfg.Timelapse.IntervalMs = 5000
+	var out bytes.Buffer
+	if err := printPlan(&out, cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Mode:     timelapse\n", "Shots:    120\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"log"
+	rdebug "runtime/debug"
+
+	"github.com/cjeanneret/PanGo/internal/hw/camera"
+	"github.com/cjeanneret/PanGo/internal/hw/stepper"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// safeStop brings the hardware to a safe state after a panic, without
+// waiting for the head: both motors disabled and the camera trigger lines
+// released, so that a shot cut short does not leave the shutter held.
+func (r *rig) safeStop() {
+	r.camMu.RLock()
+	pan, tilt, cam := r.pan, r.tilt, r.cam
+	r.camMu.RUnlock()
+	for _, m := range []*stepper.Stepper{pan, tilt} {
+		if m == nil {
+			continue
+		}
+		if err := m.Disable(); err != nil {
+			log.Printf("safe stop: disabling motor failed: %v", err)
+		}
+	}
+	if rel, ok := cam.(camera.Releaser); ok {
+		if err := rel.Release(); err != nil {
+			log.Printf("safe stop: releasing camera failed: %v", err)
+		}
+	}
+	log.Print("safe stop: motors disabled, camera released")
+}
+
+// protect runs fn, the capture of executeCapture, and turns a panic into a
+// *web.PanicError once the hardware is stopped and the operator told, so
+// that the session is saved as failed like any other error.
+func (r *rig) protect(ctx context.Context, fn func() error) (err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		log.Printf("panic in capture: %v\n%s", v, rdebug.Stack())
+		r.safeStop()
+		r.notify(web.RequestID(ctx), "error", "Internal error in capture: motors disabled, camera released")
+		err = &web.PanicError{Value: v}
+	}()
+	return fn()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/camera"
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+	"github.com/cjeanneret/PanGo/internal/hw/stepper"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// ---------- protect / safeStop ----------
+
+func TestRigProtect(t *testing.T) {
+	log.SetOutput(io.Discard)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	path := filepath.Join(t.TempDir(), "panic.gpio")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := gpio.NewRecorder(&gpio.MockDriver{}, f)
+	var notified []string
+	r := &rig{
+		hw:   newTestConfig(),
+		pan:  stepper.NewStepper(d, stepper.Config{StepPin: 1, DirPin: 2, EnablePin: 3, StepDelay: time.Microsecond}),
+		tilt: stepper.NewStepper(d, stepper.Config{StepPin: 4, DirPin: 5, EnablePin: 6, StepDelay: time.Microsecond}),
+		cam:  camera.NewNikonD90GPIO(d, 24, 25, time.Microsecond, time.Microsecond),
+		notify: func(_, level, msg string) {
+			notified = append(notified, level+": "+msg)
+		},
+	}
+
+	want := errors.New("failed")
+	if err := r.protect(context.Background(), func() error { return want }); err != want {
+		t.Errorf("protect = %v, want %v", err, want)
+	}
+	if len(notified) != 0 {
+		t.Errorf("notified %q for an error", notified)
+	}
+
+	err = r.protect(context.Background(), func() error {
+		_ = r.pan.Enable()
+		_ = r.tilt.Enable()
+		_ = d.WritePin(25, gpio.Low) // shutter held when the shot panics
+		panic("nil camera")
+	})
+	var perr *web.PanicError
+	if !errors.As(err, &perr) || perr.Value != "nil camera" {
+		t.Fatalf("protect = %v, want a *web.PanicError", err)
+	}
+	if len(notified) != 1 {
+		t.Errorf("notified %q, want one error", notified)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := readRecording(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	levels := map[int]gpio.Level{}
+	for _, op := range ops {
+		if op.Kind == gpio.OpWrite {
+			levels[op.Pin] = op.Level
+		}
+	}
+	for _, pin := range []int{3, 6, 24, 25} {
+		if levels[pin] != gpio.High {
+			t.Errorf("GPIO %d left %v, want HIGH (motor disabled, trigger released)", pin, levels[pin])
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/systemd"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// configPollInterval is how often -watch-config checks the active profile's
+// file for changes.
+const configPollInterval = 2 * time.Second
+
+// reload reads the active profile's file again and applies it like a
+// profile activation, with the same entry of its profiles: section: angles,
+// overlap and delays apply to the next capture, and changed hardware
+// settings are refused with web.ErrHeadBusy while the head is in use. CLI
+// overrides given at startup are not re-applied.
+func (l *liveConfig) reload() (web.ConfigUpdate, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	next, err := loadProfile(l.path, l.cfg.Profile)
+	if err != nil {
+		return web.ConfigUpdate{}, fmt.Errorf("%w: %v", web.ErrInvalidConfig, err)
+	}
+	return l.apply(next)
+}
+
+// reloaded logs the outcome of a reload and passes the new capture form
+// defaults to applied. why names the cause in the log.
+func reloaded(why string, update web.ConfigUpdate, err error, applied func(web.FormConfig)) {
+	if err != nil {
+		log.Printf("configuration not reloaded (%s): %v", why, err)
+		return
+	}
+	if applied != nil {
+		applied(update.Form)
+	}
+	log.Printf("configuration reloaded (%s; hardware re-initialized: %t, restart required: %t)", why, update.Reinitialized, update.RestartRequired)
+}
+
+// reloadOnSignal reloads live on each SIGHUP (systemctl reload) until ctx is
+// done, telling systemd while the reload runs. SIGHUP no longer ends the
+// program once it returns.
+func reloadOnSignal(ctx context.Context, live *liveConfig, applied func(web.FormConfig)) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				systemd.Notify(systemd.Reloading)
+				update, err := live.reload()
+				reloaded("SIGHUP", update, err, applied)
+				systemd.Notify(systemd.Ready)
+			}
+		}
+	}()
+}
+
+// fileStamp identifies a version of a file for change polling.
+type fileStamp struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) (fileStamp, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{path: path, modTime: fi.ModTime(), size: fi.Size()}, nil
+}
+
+// statFiles stamps the files of a configuration, the active profile's file
+// first.
+func statFiles(paths []string) ([]fileStamp, error) {
+	stamps := make([]fileStamp, 0, len(paths))
+	for _, path := range paths {
+		stamp, err := statFile(path)
+		if err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, stamp)
+	}
+	return stamps, nil
+}
+
+// watchConfig reloads live whenever the active profile's file, or a file it
+// includes, changes, checking every interval until ctx is done. A reload
+// refused because a capture runs is retried once the head is free; an
+// invalid file is reported once, and the running configuration kept.
+func watchConfig(ctx context.Context, live *liveConfig, interval time.Duration, applied func(web.FormConfig)) {
+	last, _ := statFiles(live.activeFiles())
+	waiting := false // a reload waits for the head
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		files := live.activeFiles()
+		cur, err := statFiles(files)
+		if err != nil || slices.Equal(cur, last) {
+			continue
+		}
+		if len(last) == 0 || files[0] != last[0].path {
+			// Another profile was activated: it is loaded already.
+			last = cur
+			continue
+		}
+		update, err := live.reload()
+		if errors.Is(err, web.ErrHeadBusy) {
+			if !waiting {
+				log.Printf("%s changed: reloading it once the head is free", files[0])
+			}
+			waiting = true
+			continue
+		}
+		reloaded(files[0]+" changed", update, err, applied)
+		// The files included may have changed with the reload.
+		last, _ = statFiles(live.activeFiles())
+		waiting = false
+	}
+}
+
+// activeFiles returns the file of the active profile, then the files it
+// includes.
+func (l *liveConfig) activeFiles() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	files := []string{l.path}
+	for _, source := range l.cfg.Sources {
+		if source != l.path {
+			files = append(files, source)
+		}
+	}
+	return files
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// editConfig replaces old with new in the live config's file.
+func editConfig(t *testing.T, live *liveConfig, old, new string) {
+	t.Helper()
+	data, err := os.ReadFile(live.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), old) {
+		t.Fatalf("%q not in the config file", old)
+	}
+	if err := os.WriteFile(live.path, []byte(strings.Replace(string(data), old, new, 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// ---------- liveConfig.reload ----------
+
+func TestLiveConfig_Reload(t *testing.T) {
+	var reinits []*config.Config
+	live, _ := newTestLiveConfig(t, &reinits)
+
+	editConfig(t, live, "horizontal_angle_deg: 180", "horizontal_angle_deg: 90")
+	update, err := live.reload()
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if live.get().Defaults.HorizontalAngleDeg != 90 || update.Form.HorizontalAngleDeg != 90 {
+		t.Errorf("angle %v, form %v: want 90", live.get().Defaults.HorizontalAngleDeg, update.Form.HorizontalAngleDeg)
+	}
+	if update.Reinitialized || len(reinits) != 0 {
+		t.Error("an angle change re-initialized the hardware")
+	}
+
+	editConfig(t, live, "microstepping: 16", "microstepping: 3")
+	if _, err := live.reload(); !errors.Is(err, web.ErrInvalidConfig) {
+		t.Errorf("invalid file: err = %v, want ErrInvalidConfig", err)
+	}
+	if live.get().Defaults.HorizontalAngleDeg != 90 {
+		t.Error("an invalid file replaced the configuration")
+	}
+}
+
+func TestLiveConfig_ReloadRefusedWhileBusy(t *testing.T) {
+	live, _ := newTestLiveConfig(t, new([]*config.Config))
+	live.reinit = func(*config.Config) error { return web.ErrHeadBusy }
+	before := live.get()
+
+	editConfig(t, live, "step_pin: 17", "step_pin: 12")
+	if _, err := live.reload(); !errors.Is(err, web.ErrHeadBusy) {
+		t.Fatalf("err = %v, want ErrHeadBusy", err)
+	}
+	if live.get() != before {
+		t.Error("config must not change when the hardware cannot be re-initialized")
+	}
+}
+
+// ---------- watchConfig ----------
+
+func TestWatchConfig(t *testing.T) {
+	live, _ := newTestLiveConfig(t, new([]*config.Config))
+	busy := true
+	live.reinit = func(*config.Config) error {
+		if busy {
+			return web.ErrHeadBusy
+		}
+		return nil
+	}
+	forms := make(chan web.FormConfig, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchConfig(ctx, live, 5*time.Millisecond, func(f web.FormConfig) { forms <- f })
+
+	next := func() web.FormConfig {
+		t.Helper()
+		select {
+		case f := <-forms:
+			return f
+		case <-time.After(5 * time.Second):
+			t.Fatal("configuration not reloaded")
+			return web.FormConfig{}
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond) // let the watcher stamp the file
+	editConfig(t, live, "horizontal_angle_deg: 180", "horizontal_angle_deg: 90")
+	if f := next(); f.HorizontalAngleDeg != 90 {
+		t.Errorf("form angle %v, want 90", f.HorizontalAngleDeg)
+	}
+
+	// A hardware change waits for the head
+	editConfig(t, live, "step_pin: 17", "step_pin: 12")
+	time.Sleep(50 * time.Millisecond)
+	if live.get().PanStepper.StepPin != 17 {
+		t.Fatal("hardware changed while the head is busy")
+	}
+	live.mu.Lock()
+	busy = false
+	live.mu.Unlock()
+	next()
+	if live.get().PanStepper.StepPin != 12 {
+		t.Error("hardware change not applied once the head is free")
+	}
+}
+
+func TestWatchConfig_Include(t *testing.T) {
+	live, _ := newTestLiveConfig(t, new([]*config.Config))
+	part := filepath.Join(filepath.Dir(live.path), "parts", "angles.yaml")
+	if err := os.Mkdir(filepath.Dir(part), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(part, []byte("defaults:\n  vertical_angle_deg: 45\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	editConfig(t, live, "    vertical_angle_deg: 30\n", "")
+	editConfig(t, live, "pan_stepper:", "include: [parts/angles.yaml]\npan_stepper:")
+	if _, err := live.reload(); err != nil {
+		t.Fatal(err)
+	}
+	if live.get().Defaults.VerticalAngleDeg != 45 {
+		t.Fatalf("vertical angle = %v, want the included 45", live.get().Defaults.VerticalAngleDeg)
+	}
+	if _, err := live.update(mustReadFile(t, live.path), true); !errors.Is(err, web.ErrInvalidConfig) {
+		t.Errorf("saving a file with includes: err = %v, want ErrInvalidConfig", err)
+	}
+
+	forms := make(chan web.FormConfig, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchConfig(ctx, live, 5*time.Millisecond, func(f web.FormConfig) { forms <- f })
+	time.Sleep(20 * time.Millisecond) // let the watcher stamp the files
+	if err := os.WriteFile(part, []byte("defaults:\n  vertical_angle_deg: 60\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case f := <-forms:
+		if f.VerticalAngleDeg != 60 {
+			t.Errorf("form angle %v, want 60", f.VerticalAngleDeg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("change of an included file not reloaded")
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// ---------- reloadOnSignal ----------
+
+func TestReloadOnSignal(t *testing.T) {
+	live, _ := newTestLiveConfig(t, new([]*config.Config))
+	forms := make(chan web.FormConfig, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloadOnSignal(ctx, live, func(f web.FormConfig) { forms <- f })
+
+	editConfig(t, live, "vertical_angle_deg: 30", "vertical_angle_deg: 45")
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case f := <-forms:
+		if f.VerticalAngleDeg != 45 {
+			t.Errorf("form angle %v, want 45", f.VerticalAngleDeg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGHUP did not reload the configuration")
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cjeanneret/PanGo/client"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// Environment variables read by pango remote, so that credentials stay out
+// of the shell history and the process list.
+const (
+	envRemoteHost         = "PANGO_HOST"
+	envRemoteToken        = "PANGO_TOKEN"
+	envRemoteUser         = "PANGO_USER"
+	envRemotePassword     = "PANGO_PASSWORD"
+	envRemoteControlToken = "PANGO_CONTROL_TOKEN"
+)
+
+// How often run -wait polls the status of the capture.
+const remotePollInterval = time.Second
+
+const remoteUsage = `Usage: pango remote [flags] <command> [command flags]
+
+Drive a running PanGo server (pango serve) over its HTTP API.
+
+Commands:
+  run     start a capture; -wait follows it to the end
+  cancel  stop the running capture after the current shot
+  status  show the capture state, progress and estimated end
+  plan    preview the grid and duration of a capture
+  save    save capture parameters as the defaults of a profile
+          (-profile, default the active one)
+
+run, plan and save take -horizontal_angle_deg, -vertical_angle_deg,
+-focal_length_mm, -overlap_percent, -shot_delay_ms and -move_speed_ms;
+omitted values use the server's defaults.
+
+Flags:
+`
+
+// remote runs the commands of pango remote against a server.
+type remote struct {
+	c    *client.Client
+	out  io.Writer
+	json bool
+}
+
+// runRemote implements "pango remote" and returns the exit status: 0 on
+// success, 1 when the server refused or failed the command, 2 on a usage
+// error.
+func runRemote(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pango remote", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	host := fs.String("host", envOr(envRemoteHost, "localhost:8080"), "server: host:port or URL, e.g. pi.local:8080 or https://proxy/pango ($"+envRemoteHost+")")
+	token := fs.String("token", os.Getenv(envRemoteToken), "API token, web.auth.token ($"+envRemoteToken+")")
+	user := fs.String("user", os.Getenv(envRemoteUser), "basic auth user ($"+envRemoteUser+"); the password is read from $"+envRemotePassword)
+	controlToken := fs.String("control-token", os.Getenv(envRemoteControlToken), "token of a control claim, when control of the rig is claimed ($"+envRemoteControlToken+")")
+	asJSON := fs.Bool("json", false, "print the server's JSON responses")
+	fs.Usage = func() {
+		fmt.Fprint(stderr, remoteUsage)
+		fs.PrintDefaults()
+	}
+	if err := parseArgs(fs, args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	c := client.New(remoteURL(*host))
+	c.Token, c.Username, c.Password = *token, *user, os.Getenv(envRemotePassword)
+	c.ControlToken = *controlToken
+	r := &remote{c: c, out: stdout, json: *asJSON}
+
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	var err error
+	switch cmd {
+	case "run":
+		err = r.run(ctx, cmdArgs, stderr)
+	case "cancel":
+		if err = noFlags(cmd, cmdArgs, stderr); err == nil {
+			err = r.cancel(ctx)
+		}
+	case "status":
+		if err = noFlags(cmd, cmdArgs, stderr); err == nil {
+			err = r.status(ctx)
+		}
+	case "plan":
+		err = r.plan(ctx, cmdArgs, stderr)
+	case "save":
+		err = r.save(ctx, cmdArgs, stderr)
+	default:
+		fmt.Fprintf(stderr, "pango remote: unknown command %q\n", cmd)
+		fs.Usage()
+		return 2
+	}
+	var usage usageError
+	switch {
+	case errors.As(err, &usage):
+		return 2
+	case err != nil:
+		fmt.Fprintf(stderr, "pango remote %s: %v\n", cmd, err)
+		return 1
+	}
+	return 0
+}
+
+// usageError reports invalid command flags, already printed.
+type usageError struct{ error }
+
+// envOr returns the value of the environment variable key, or def.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// remoteURL returns the base URL of host, http:// unless it has a scheme.
+func remoteURL(host string) string {
+	if strings.Contains(host, "://") {
+		return host
+	}
+	return "http://" + host
+}
+
+// noFlags parses the arguments of command cmd, which takes none.
+func noFlags(cmd string, args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("pango remote "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	if err := parseArgs(fs, args); err != nil {
+		return usageError{err}
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return usageError{errors.New("unexpected arguments")}
+	}
+	return nil
+}
+
+// overrides parses the capture flags of run and plan. Values not given are
+// taken from the server's form defaults.
+func (r *remote) overrides(ctx context.Context, fs *flag.FlagSet, args []string) (client.Overrides, error) {
+	horizontal := fs.Float64("horizontal_angle_deg", 0, "horizontal angle in degrees (1-360); 0 = server default")
+	vertical := fs.Float64("vertical_angle_deg", 0, "vertical angle in degrees (1-180); 0 = server default")
+	focal := fs.Float64("focal_length_mm", 0, "focal length in mm; 0 = server default")
+	overlap := fs.Float64("overlap_percent", 0, "overlap between photos in percent (below 100); 0 = server default")
+	shotDelay := fs.Int("shot_delay_ms", 0, "hold after each shot before moving, in ms; 0 = server default")
+	moveSpeed := fs.Int("move_speed_ms", 0, "delay between motor steps in ms (1-1000); 0 = server default")
+	if err := parseArgs(fs, args); err != nil {
+		return client.Overrides{}, usageError{err}
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %s\n", strings.Join(fs.Args(), " "))
+		return client.Overrides{}, usageError{errors.New("unexpected arguments")}
+	}
+	if err := validateCLIOverrides(*horizontal, *vertical, *focal); err != nil {
+		return client.Overrides{}, err
+	}
+	if err := validateCLIPacing(*overlap, *shotDelay, *moveSpeed); err != nil {
+		return client.Overrides{}, err
+	}
+
+	o := client.Overrides{
+		HorizontalAngleDeg: *horizontal, VerticalAngleDeg: *vertical, FocalLengthMm: *focal,
+		OverlapPercent: *overlap, ShotDelayMs: *shotDelay, MoveSpeedMs: *moveSpeed,
+	}
+	if o.HorizontalAngleDeg == 0 || o.VerticalAngleDeg == 0 || o.FocalLengthMm == 0 {
+		form, err := r.c.GetFormConfig(ctx)
+		if err != nil {
+			return client.Overrides{}, fmt.Errorf("read the server defaults: %w", err)
+		}
+		if o.HorizontalAngleDeg == 0 {
+			o.HorizontalAngleDeg = form.HorizontalAngleDeg
+		}
+		if o.VerticalAngleDeg == 0 {
+			o.VerticalAngleDeg = form.VerticalAngleDeg
+		}
+		if o.FocalLengthMm == 0 {
+			o.FocalLengthMm = form.FocalLengthMm
+		}
+	}
+	return o, nil
+}
+
+// print writes v as JSON with -json, or the text of text otherwise.
+func (r *remote) print(v any, text func(w io.Writer)) {
+	if r.json {
+		enc := json.NewEncoder(r.out)
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+		return
+	}
+	text(r.out)
+}
+
+func (r *remote) run(ctx context.Context, args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("pango remote run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	wait := fs.Bool("wait", false, "follow the capture until it ends; fail if it fails")
+	o, err := r.overrides(ctx, fs, args)
+	if err != nil {
+		return err
+	}
+
+	var before *time.Time
+	if *wait {
+		st, err := r.c.GetStatus(ctx)
+		if err != nil {
+			return err
+		}
+		before = st.StartedAt
+	}
+	resp, err := r.c.RunCapture(ctx, o)
+	if err != nil {
+		return err
+	}
+	r.print(resp, func(w io.Writer) {
+		fmt.Fprintf(w, "Capture started: %g° × %g° at %g mm\n", o.HorizontalAngleDeg, o.VerticalAngleDeg, o.FocalLengthMm)
+	})
+	if !*wait {
+		return nil
+	}
+	return r.follow(ctx, before)
+}
+
+// follow polls the status until the capture started after before ends.
+func (r *remote) follow(ctx context.Context, before *time.Time) error {
+	ticker := time.NewTicker(remotePollInterval)
+	defer ticker.Stop()
+	lastDone := -1
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		st, err := r.c.GetStatus(ctx)
+		if err != nil {
+			return err
+		}
+		if st.StartedAt == nil || (before != nil && st.StartedAt.Equal(*before)) {
+			continue // not started yet
+		}
+		if !r.json && st.ShotsDone != lastDone && st.ShotsTotal > 0 {
+			fmt.Fprintf(r.out, "%s: %d/%d shots\n", st.State, st.ShotsDone, st.ShotsTotal)
+			lastDone = st.ShotsDone
+		}
+		switch st.State {
+		case capture.StateIdle:
+			r.print(st, func(w io.Writer) { fmt.Fprintf(w, "Capture ended: %d/%d shots\n", st.ShotsDone, st.ShotsTotal) })
+			return nil
+		case capture.StateError:
+			r.print(st, func(io.Writer) {})
+			return fmt.Errorf("capture failed: %s", st.LastError)
+		}
+	}
+}
+
+func (r *remote) cancel(ctx context.Context) error {
+	resp, err := r.c.CancelCapture(ctx)
+	if err != nil {
+		return err
+	}
+	r.print(resp, func(w io.Writer) { fmt.Fprintln(w, "Capture cancellation requested") })
+	return nil
+}
+
+func (r *remote) status(ctx context.Context) error {
+	st, err := r.c.GetStatus(ctx)
+	if err != nil {
+		return err
+	}
+	eta, etaErr := r.c.GetETA(ctx)
+	if r.json {
+		r.print(struct {
+			client.Status
+			ETA *client.ETA `json:"eta,omitempty"`
+		}{st, optional(eta, etaErr)}, nil)
+		return nil
+	}
+
+	fmt.Fprintf(r.out, "State:    %s since %s\n", st.State, st.StateSince.Local().Format(time.TimeOnly))
+	if st.ShotsTotal > 0 {
+		fmt.Fprintf(r.out, "Shots:    %d/%d", st.ShotsDone, st.ShotsTotal)
+		if st.Cell.Column > 0 {
+			fmt.Fprintf(r.out, " (column %d/%d, row %d/%d)", st.Cell.Column, st.Columns, st.Cell.Row, st.Rows)
+		}
+		fmt.Fprintln(r.out)
+	}
+	if etaErr == nil && eta.CompletesAt != nil {
+		remaining := time.Duration(eta.RemainingSec * float64(time.Second)).Round(time.Second)
+		fmt.Fprintf(r.out, "Ends:     in %v, at %s\n", remaining, eta.CompletesAt.Local().Format(time.TimeOnly))
+	}
+	if st.LastError != "" {
+		fmt.Fprintf(r.out, "Error:    %s\n", st.LastError)
+	}
+	return nil
+}
+
+// optional returns &v, or nil when err is set.
+func optional[T any](v T, err error) *T {
+	if err != nil {
+		return nil
+	}
+	return &v
+}
+
+func (r *remote) plan(ctx context.Context, args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("pango remote plan", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	o, err := r.overrides(ctx, fs, args)
+	if err != nil {
+		return err
+	}
+	plan, err := r.c.PlanCapture(ctx, o)
+	if err != nil {
+		return err
+	}
+	r.print(plan, func(w io.Writer) {
+		duration := time.Duration(plan.EstimatedSec * float64(time.Second)).Round(time.Second)
+		if plan.Mode == "grid" && len(plan.Panoramas) == 0 {
+			fmt.Fprintf(w, "Grid:     %d columns × %d rows\n", plan.Columns, plan.Rows)
+		} else {
+			fmt.Fprintf(w, "Mode:     %s\n", plan.Mode)
+		}
+		for _, g := range plan.Panoramas {
+			fmt.Fprintf(w, "Panorama: %s, %d columns × %d rows\n", g.Name, g.Columns, g.Rows)
+		}
+		fmt.Fprintf(w, "Shots:    %d\n", plan.TotalShots)
+		fmt.Fprintf(w, "Duration: about %v\n", duration)
+	})
+	return nil
+}
+
+func (r *remote) save(ctx context.Context, args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("pango remote save", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	profile := fs.String("profile", "", "profile to write: file name, or file/entry of its profiles: section; empty = the active profile")
+	o, err := r.overrides(ctx, fs, args)
+	if err != nil {
+		return err
+	}
+	saved, err := r.c.SaveDefaults(ctx, o, *profile)
+	if err != nil {
+		return err
+	}
+	r.print(saved, func(w io.Writer) {
+		how := "updated"
+		if saved.Created {
+			how = "created"
+		}
+		fmt.Fprintf(w, "Profile %s %s: %g° × %g° at %g mm\n", saved.Profile, how, o.HorizontalAngleDeg, o.VerticalAngleDeg, o.FocalLengthMm)
+		if saved.Active {
+			fmt.Fprintln(w, "Active profile reloaded with the new defaults.")
+		}
+	})
+	return nil
+}
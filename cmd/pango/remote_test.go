@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+const remoteTestToken = "0123456789abcdef"
+
+// newRemoteTestServer runs the web handlers with runCapture and returns
+// the server and its host:port.
+func newRemoteTestServer(t *testing.T, runCapture web.RunCaptureFunc) (*web.Server, string) {
+	t.Helper()
+	srv := web.NewServer(":0", web.NewStatusBroadcaster(), runCapture, web.FormConfig{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35})
+	srv.SetAccessLog(nil)
+	srv.SetAuth(web.AuthConfig{Token: remoteTestToken})
+	srv.Handlers().Lifecycle = capture.NewLifecycle()
+	ts := httptest.NewServer(srv.Mux())
+	t.Cleanup(ts.Close)
+	return srv, strings.TrimPrefix(ts.URL, "http://")
+}
+
+// remoteCmd runs pango remote against host with args.
+func remoteCmd(t *testing.T, host string, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	args = append([]string{"-host", host, "-token", remoteTestToken}, args...)
+	code = runRemote(context.Background(), args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+// ---------- pango remote ----------
+
+func TestRemote_Plan(t *testing.T) {
+	srv, host := newRemoteTestServer(t, noopRemoteCapture)
+	var got web.Overrides
+	srv.Handlers().Plan = func(o web.Overrides) (web.Plan, error) {
+		got = o
+		return web.Plan{Mode: "grid", Columns: 8, Rows: 3, TotalShots: 24, EstimatedSec: 250}, nil
+	}
+
+	code, out, errOut := remoteCmd(t, host, "plan", "-focal_length_mm", "50")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if got != (web.Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 50}) {
+		t.Errorf("planned %+v, want server defaults with 50 mm", got)
+	}
+	for _, want := range []string{"8 columns × 3 rows", "Shots:    24", "about 4m10s"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+
+	if code, _, errOut := remoteCmd(t, host, "plan", "-overlap_percent", "40", "-shot_delay_ms", "500", "-move_speed_ms", "3"); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if want := (web.Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35, OverlapPercent: 40, ShotDelayMs: 500, MoveSpeedMs: 3}); got != want {
+		t.Errorf("planned %+v, want %+v", got, want)
+	}
+
+	code, out, _ = remoteCmd(t, host, "-json", "plan")
+	var plan web.Plan
+	if code != 0 || json.Unmarshal([]byte(out), &plan) != nil || plan.TotalShots != 24 {
+		t.Errorf("-json plan: exit %d, output %s", code, out)
+	}
+}
+
+func TestRemote_Save(t *testing.T) {
+	srv, host := newRemoteTestServer(t, noopRemoteCapture)
+	var gotName string
+	var got web.Overrides
+	srv.Handlers().SaveDefaults = func(name string, o web.Overrides) (web.SavedDefaults, error) {
+		gotName, got = name, o
+		return web.SavedDefaults{Profile: "tele", Created: true}, nil
+	}
+
+	code, out, errOut := remoteCmd(t, host, "save", "-profile", "tele", "-focal_length_mm", "200")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	if gotName != "tele" || got != (web.Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 200}) {
+		t.Errorf("saved %+v to %q, want server defaults with 200 mm to tele", got, gotName)
+	}
+	if !strings.Contains(out, "Profile tele created: 180° × 30° at 200 mm") {
+		t.Errorf("output:\n%s", out)
+	}
+}
+
+func TestRemote_RunWait(t *testing.T) {
+	var lc *capture.Lifecycle
+	srv, host := newRemoteTestServer(t, func(ctx context.Context, o web.Overrides) error {
+		lc.Transition(capture.StatePlanning)
+		lc.SetShotsTotal(2)
+		lc.Transition(capture.StateShooting)
+		lc.ShotDone()
+		time.Sleep(50 * time.Millisecond)
+		lc.ShotDone()
+		lc.Transition(capture.StateIdle)
+		return nil
+	})
+	lc = srv.Handlers().Lifecycle
+
+	code, out, errOut := remoteCmd(t, host, "run", "-wait", "-horizontal_angle_deg", "90")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut)
+	}
+	for _, want := range []string{"Capture started: 90° × 30° at 35 mm", "Capture ended: 2/2 shots"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestRemote_RunWaitFailure(t *testing.T) {
+	var lc *capture.Lifecycle
+	srv, host := newRemoteTestServer(t, func(ctx context.Context, o web.Overrides) error {
+		lc.Transition(capture.StatePlanning)
+		lc.Fail(errors.New("camera not responding"))
+		return nil
+	})
+	lc = srv.Handlers().Lifecycle
+
+	code, _, errOut := remoteCmd(t, host, "run", "-wait")
+	if code != 1 || !strings.Contains(errOut, "capture failed: camera not responding") {
+		t.Errorf("exit %d, stderr %q; want 1 and the capture error", code, errOut)
+	}
+}
+
+func TestRemote_StatusAndCancel(t *testing.T) {
+	_, host := newRemoteTestServer(t, noopRemoteCapture)
+
+	code, out, errOut := remoteCmd(t, host, "status")
+	if code != 0 || !strings.Contains(out, "State:    idle since ") {
+		t.Errorf("status: exit %d, output %q, stderr %q", code, out, errOut)
+	}
+
+	code, _, errOut = remoteCmd(t, host, "cancel")
+	if code != 1 || !strings.Contains(errOut, "no capture in progress") {
+		t.Errorf("cancel while idle: exit %d, stderr %q; want 1", code, errOut)
+	}
+}
+
+func TestRemote_Errors(t *testing.T) {
+	_, host := newRemoteTestServer(t, noopRemoteCapture)
+	cases := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"no_command", nil, 2},
+		{"unknown_command", []string{"shoot"}, 2},
+		{"unknown_flag", []string{"run", "-angle", "5"}, 2},
+		{"extra_argument", []string{"plan", "now"}, 2},
+		{"invalid_override", []string{"run", "-focal_length_mm", "-5"}, 1},
+		{"invalid_overlap", []string{"plan", "-overlap_percent", "100"}, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if code, _, _ := remoteCmd(t, host, tc.args...); code != tc.code {
+				t.Errorf("exit %d, want %d", code, tc.code)
+			}
+		})
+	}
+
+	// Wrong credentials
+	var out, errOut bytes.Buffer
+	if code := runRemote(context.Background(), []string{"-host", host, "status"}, &out, &errOut); code != 1 || !strings.Contains(errOut.String(), "401") {
+		t.Errorf("without token: exit %d, stderr %q", code, errOut.String())
+	}
+}
+
+func TestRemoteURL(t *testing.T) {
+	cases := map[string]string{
+		"pi.local:8080":             "http://pi.local:8080",
+		"https://proxy.lan/pango":   "https://proxy.lan/pango",
+		"http://192.168.1.20:8980/": "http://192.168.1.20:8980/",
+	}
+	for host, want := range cases {
+		if got := remoteURL(host); got != want {
+			t.Errorf("remoteURL(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func noopRemoteCapture(context.Context, web.Overrides) error { return nil }
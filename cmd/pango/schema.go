@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+)
+
+const schemaUsage = `Usage: pango schema
+
+Print the JSON Schema of the config file, for editors to complete and check
+config files, e.g.:
+
+  pango schema > configs/pango.schema.json
+
+then start a YAML file with
+  # yaml-language-server: $schema=pango.schema.json
+`
+
+// runSchema implements "pango schema" and returns the exit status.
+func runSchema(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pango schema", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, schemaUsage)
+	}
+	if err := parseArgs(fs, args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	data, err := json.MarshalIndent(config.Schema(), "", "  ")
+	if err != nil {
+		fmt.Fprintf(stderr, "pango schema: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "%s\n", data)
+	return 0
+}
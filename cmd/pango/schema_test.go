@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+)
+
+// ---------- pango schema ----------
+
+func TestRunSchema(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := runSchema(nil, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d: %s", code, errOut.String())
+	}
+	var s map[string]any
+	if err := json.Unmarshal(out.Bytes(), &s); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	if s["$schema"] != config.SchemaDialect {
+		t.Errorf("$schema = %v", s["$schema"])
+	}
+
+	out.Reset()
+	if code := runSchema([]string{"extra"}, &out, &errOut); code != 2 || out.Len() != 0 {
+		t.Errorf("with an argument: exit status = %d, output %q", code, out.String())
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/debug"
+	"github.com/cjeanneret/PanGo/internal/tracing"
+)
+
+// How long the program waits for the export of the last spans before
+// exiting.
+const tracingDrainTimeout = 5 * time.Second
+
+// openTracing starts recording the capture traces and sending them to the
+// collector of cfg (tracing.endpoint). It returns nil when none is set.
+// Stop it with closeTracing.
+func openTracing(cfg *config.Config) (*tracing.Exporter, error) {
+	if cfg.Tracing.Endpoint == "" {
+		return nil, nil
+	}
+	e, err := tracing.NewExporter(cfg.Tracing.Endpoint, tracing.Options{
+		ServiceName:    cfg.Tracing.ServiceName,
+		ServiceVersion: buildInfo().Version,
+		Headers:        cfg.Tracing.Headers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tracing.SetExporter(e)
+	debug.Info("tracing", "endpoint", cfg.Tracing.Endpoint)
+	return e, nil
+}
+
+// closeTracing stops recording traces and exports the last spans of e.
+func closeTracing(e *tracing.Exporter) {
+	if e == nil {
+		return
+	}
+	tracing.SetExporter(nil)
+	if !e.Shutdown(tracingDrainTimeout) {
+		log.Printf("tracing: export still pending after %v, giving up", tracingDrainTimeout)
+	}
+}
+
+// startCaptureTrace starts the root span of a capture of cfg, and logs its
+// trace ID to find the trace in the tracing backend.
+func startCaptureTrace(ctx context.Context, cfg *config.Config) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, "capture", "mode", cfg.Defaults.Mode, "profile", cfg.Profile)
+	if id := span.TraceID(); id != "" {
+		debug.Info("trace", "trace_id", id)
+	}
+	return ctx, span
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/tracing"
+)
+
+// ---------- tracing.endpoint ----------
+
+func TestOpenTracing(t *testing.T) {
+	cfg := newTestConfig()
+	if e, err := openTracing(cfg); e != nil || err != nil {
+		t.Fatalf("no endpoint: %v, %v, want nil", e, err)
+	}
+
+	var mu sync.Mutex
+	var exports []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		exports = append(exports, r.Header.Get("Authorization")+" "+string(body))
+	}))
+	defer srv.Close()
+	cfg.Tracing.Endpoint = srv.URL
+	cfg.Tracing.ServiceName = "pango-roof"
+	cfg.Tracing.Headers = map[string]string{"Authorization": "Bearer secret"}
+	e, err := openTracing(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, span := startCaptureTrace(context.Background(), cfg)
+	if span == nil || tracing.FromContext(ctx) != span || len(span.TraceID()) != 32 {
+		t.Fatalf("capture span = %v, want a trace", span)
+	}
+	span.End(nil)
+	closeTracing(e)
+
+	if _, span := tracing.Start(context.Background(), "capture"); span != nil {
+		t.Error("spans still recorded after closeTracing")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(exports) != 1 {
+		t.Fatalf("%d export(s), want 1", len(exports))
+	}
+	for _, want := range []string{"Bearer secret ", `"pango-roof"`, `"name":"capture"`, span.TraceID()} {
+		if !strings.Contains(exports[0], want) {
+			t.Errorf("export %s does not contain %s", exports[0], want)
+		}
+	}
+
+	cfg.Tracing.Endpoint = "collector.local:4318"
+	if _, err := openTracing(cfg); err == nil {
+		t.Error("endpoint without scheme: expected error, got nil")
+	}
+}
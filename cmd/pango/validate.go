@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+	"github.com/cjeanneret/PanGo/internal/hw/board"
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+const validateUsage = `Usage: pango validate [-strict] [config files]
+
+Load and check config files without touching the hardware: values, keys
+renamed since the version: of the file, GPIO pin conflicts, camera type,
+credentials written in the file, sensor size, the entries of profiles: and
+the capture it plans. On a Raspberry Pi, the pins of files not using mock
+GPIO are also checked against its header and enabled interfaces (I2C,
+SPI, serial port, 1-Wire). Files merged with use: and include: are listed
+in merge order. Prints a report per file; the default file is
+configs/default.yaml.
+
+Keys that are not config keys (e.g. a typo) are warnings, or errors with
+-strict or in files setting strict: true.
+
+Exit status: 0 when all files are valid (warnings allowed), 1 when one is
+not, 2 on a usage error.
+`
+
+// boardRoot is the root of the device tree and devices read to identify
+// the Raspberry Pi, for tests.
+var boardRoot = "/"
+
+// Statuses of the lines of a validation report.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "FAIL"
+)
+
+// validation writes the report of one config file.
+type validation struct {
+	out              io.Writer
+	errors, warnings int
+}
+
+func (v *validation) report(status, topic, format string, args ...any) {
+	switch status {
+	case checkFail:
+		v.errors++
+	case checkWarn:
+		v.warnings++
+	}
+	fmt.Fprintf(v.out, "  %-4s  %s: %s\n", status, topic, fmt.Sprintf(format, args...))
+}
+
+// runValidate implements "pango validate" and returns the exit status.
+func runValidate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("pango validate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, validateUsage)
+	}
+	strict := fs.Bool("strict", false, "")
+	if err := parseArgs(fs, args); err != nil {
+		return 2
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{filepath.Join("configs", "default.yaml")}
+	}
+	code := 0
+	for _, path := range paths {
+		if !validateFile(path, *strict, stdout) {
+			code = 1
+		}
+	}
+	return code
+}
+
+// validateFile writes the report of path to out and reports whether the
+// file is valid. With strict, unknown keys are errors (see config.Strict).
+func validateFile(path string, strict bool, out io.Writer) bool {
+	fmt.Fprintln(out, path)
+	v := &validation{out: out}
+	load := config.Load
+	if strict {
+		load = config.LoadStrict
+	}
+	cfg, err := load(path)
+	if err != nil {
+		v.report(checkFail, "config", "%v", err)
+	} else {
+		v.report(checkOK, "config", "syntax and values")
+		if _, err := config.LoadStrict(path); !cfg.Strict && errors.Is(err, config.ErrUnknownKey) {
+			v.report(checkWarn, "keys", "%v; ignored (set strict: true to refuse them)", err)
+		}
+		if len(cfg.Sources) > 1 {
+			v.report(checkOK, "files", "merged in order: %s", strings.Join(cfg.Sources, ", "))
+		}
+		checkConfig(v, cfg)
+	}
+
+	switch {
+	case v.errors > 0:
+		fmt.Fprintf(out, "%s: invalid, %s\n", path, plural(v.errors, "error"))
+	case v.warnings > 0:
+		fmt.Fprintf(out, "%s: valid, %s\n", path, plural(v.warnings, "warning"))
+	default:
+		fmt.Fprintf(out, "%s: valid\n", path)
+	}
+	return v.errors == 0
+}
+
+// checkConfig runs the checks Load leaves to the start of a capture.
+func checkConfig(v *validation, cfg *config.Config) {
+	if conflicts := config.PinConflicts(cfg); len(conflicts) > 0 {
+		for _, c := range conflicts {
+			v.report(checkFail, "gpio", "pin used twice: %s", c)
+		}
+	} else {
+		v.report(checkOK, "gpio", "%d pins, no conflicts", len(cfg.Pins()))
+	}
+	// On a Raspberry Pi, files driving its GPIO are checked against it.
+	if pi, err := board.Detect(boardRoot); err == nil && !cfg.Defaults.MockGPIO {
+		problems := pi.Check(cfg.Pins())
+		for _, p := range problems {
+			v.report(checkFail, "board", "%s", p)
+		}
+		if len(problems) == 0 {
+			v.report(checkOK, "board", "%s, pins on the header and free", pi.Model)
+		}
+	}
+
+	if _, err := newCameraFromConfig(&gpio.MockDriver{}, cfg); err != nil {
+		v.report(checkFail, "camera", "%v", err)
+	} else {
+		v.report(checkOK, "camera", "%s", cfg.Camera.Type)
+	}
+
+	for _, w := range cfg.Warnings {
+		v.report(checkWarn, "version", "%s", w)
+	}
+
+	if names := cfg.ProfileNames(); len(names) > 0 {
+		v.report(checkOK, "profiles", "%s", strings.Join(names, ", "))
+	}
+
+	if refs := cfg.SecretRefs(); len(refs) > 0 {
+		v.report(checkOK, "secrets", "from references: %s", strings.Join(refs, ", "))
+	}
+	if inline := cfg.InlineSecrets(); len(inline) > 0 {
+		v.report(checkWarn, "secrets", "written in the file: %s; use ${env:NAME} or ${file:/path} references", strings.Join(inline, ", "))
+	}
+
+	grid := cfg.Defaults.Mode != config.ModeTimelapse
+	fov, err := geometry.NewFOVCalculator(cfg)
+	switch {
+	case err == nil:
+		v.report(checkOK, "sensor", "%.1f x %.1f mm, field of view %.1f° x %.1f° at %g mm",
+			cfg.Sensor.WidthMm, cfg.Sensor.HeightMm, fov.HorizontalFOV(), fov.VerticalFOV(), cfg.Lens.FocalLengthMm)
+	case grid:
+		v.report(checkFail, "sensor", "sensor.width_mm and sensor.height_mm are required to plan the grid")
+		return
+	default:
+		v.report(checkWarn, "sensor", "not set: grid captures cannot be planned with this file")
+	}
+
+	plan, err := planCapture(cfg, web.Overrides{})
+	if err != nil {
+		v.report(checkFail, "plan", "%v", err)
+		return
+	}
+	duration := time.Duration(plan.EstimatedSec * float64(time.Second)).Round(time.Second)
+	if plan.Mode == config.ModeTimelapse {
+		v.report(checkOK, "plan", "timelapse of %d frames, about %s", plan.TotalShots, duration)
+		return
+	}
+	if len(plan.Panoramas) > 1 {
+		v.report(checkOK, "plan", "%d panoramas, %d shots, about %s", len(plan.Panoramas), plan.TotalShots, duration)
+		return
+	}
+	v.report(checkOK, "plan", "%d x %d grid, %d shots, about %s", plan.Columns, plan.Rows, plan.TotalShots, duration)
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
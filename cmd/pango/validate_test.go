@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+)
+
+// writeValidateConfig writes newTestConfig, changed by edit, to
+// configs/name in a temporary directory and returns its path.
+func writeValidateConfig(t *testing.T, dir, name string, edit func(*config.Config)) string {
+	t.Helper()
+	cfg := newTestConfig()
+	cfg.Camera.FocusPin, cfg.Camera.ShutterPin = 24, 25
+	if edit != nil {
+		edit(cfg)
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func validateDir(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "configs")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// ---------- pango validate ----------
+
+func TestRunValidate(t *testing.T) {
+	t.Setenv("TEST_PANGO_VALIDATE_TOKEN", "0123456789abcdef")
+	dir := validateDir(t)
+	cases := []struct {
+		name     string
+		edit     func(*config.Config)
+		code     int
+		want     []string
+		dontWant string
+	}{
+		{"valid", nil, 0, []string{
+			"ok    gpio: 8 pins, no conflicts",
+			"ok    camera: nikon_d90_gpio",
+			"ok    sensor: 23.6 x 15.8 mm",
+			"ok    plan: ",
+			": valid\n",
+		}, "FAIL"},
+		{"pin_conflict", func(c *config.Config) { c.Camera.FocusPin = 17 }, 1, []string{
+			"FAIL  gpio: pin used twice: GPIO 17: camera.focus_pin, pan_stepper.step_pin",
+			": invalid, 1 error\n",
+		}, ""},
+		{"camera_type", func(c *config.Config) { c.Camera.Type = "canon_eos" }, 1, []string{
+			"FAIL  camera: unsupported camera type: canon_eos",
+		}, ""},
+		{"no_sensor", func(c *config.Config) { c.Sensor = nil }, 1, []string{
+			"FAIL  sensor: sensor.width_mm and sensor.height_mm are required",
+		}, "plan:"},
+		{"timelapse_no_sensor", func(c *config.Config) {
+			c.Sensor = nil
+			c.Defaults.Mode = config.ModeTimelapse
+			c.Timelapse = config.TimelapseConfig{Frames: 10, IntervalMs: 5000}
+		}, 0, []string{
+			"warn  sensor: not set",
+			"ok    plan: timelapse of 10 frames",
+			": valid, 1 warning\n",
+		}, ""},
+		{"profiles", func(c *config.Config) {
+			c.Profiles = map[string]map[string]any{"tele": {"lens": map[string]any{"focal_length_mm": 200}}, "macro": nil}
+		}, 0, []string{"ok    profiles: macro, tele"}, ""},
+		{"inline_secret", func(c *config.Config) { c.Web.Auth.Token = "0123456789abcdef" }, 0, []string{
+			"warn  secrets: written in the file: web.auth.token; use ${env:NAME}",
+			": valid, 1 warning\n",
+		}, ""},
+		{"secret_ref", func(c *config.Config) { c.Web.Auth.Token = "${env:TEST_PANGO_VALIDATE_TOKEN}" }, 0, []string{
+			"ok    secrets: from references: web.auth.token",
+		}, "warn"},
+		{"invalid_value", func(c *config.Config) { c.PanStepper.Microstepping = 3 }, 1, []string{
+			"FAIL  config: pan_stepper microstepping must be one of",
+		}, "gpio:"},
+		{"newer_version", func(c *config.Config) { c.Version = config.CurrentVersion() + 1 }, 1, []string{
+			"FAIL  config: version 2 is newer than this PanGo reads (up to 1): upgrade PanGo",
+		}, "gpio:"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeValidateConfig(t, dir, tc.name+".yaml", tc.edit)
+			var out, errOut bytes.Buffer
+			if code := runValidate([]string{path}, &out, &errOut); code != tc.code {
+				t.Errorf("exit status = %d, want %d\n%s", code, tc.code, out.String())
+			}
+			report := out.String()
+			if !strings.HasPrefix(report, path+"\n") {
+				t.Errorf("report does not start with the file name:\n%s", report)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(report, want) {
+					t.Errorf("report lacks %q:\n%s", want, report)
+				}
+			}
+			if tc.dontWant != "" && strings.Contains(report, tc.dontWant) {
+				t.Errorf("report has %q:\n%s", tc.dontWant, report)
+			}
+		})
+	}
+}
+
+func TestRunValidate_Board(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"proc/device-tree/model": "Raspberry Pi 4 Model B Rev 1.4\x00",
+		"dev/i2c-1":              "",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := boardRoot
+	boardRoot = root
+	t.Cleanup(func() { boardRoot = old })
+
+	dir := validateDir(t)
+	cases := []struct {
+		name     string
+		edit     func(*config.Config)
+		code     int
+		want     string
+		dontWant string
+	}{
+		{"free_pins", func(c *config.Config) { c.Defaults.MockGPIO = false }, 0,
+			"ok    board: Raspberry Pi 4 Model B Rev 1.4, pins on the header and free", "FAIL"},
+		{"i2c_pins", func(c *config.Config) { c.Defaults.MockGPIO, c.Camera.FocusPin = false, 2 }, 1,
+			"FAIL  board: GPIO 2/3 are I2C (camera.focus_pin = 2); disable i2c", "ok    board"},
+		{"mock_gpio", func(c *config.Config) { c.Defaults.MockGPIO, c.Camera.FocusPin = true, 2 }, 0,
+			"ok    gpio:", "board:"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeValidateConfig(t, dir, tc.name+".yaml", tc.edit)
+			var out, errOut bytes.Buffer
+			if code := runValidate([]string{path}, &out, &errOut); code != tc.code {
+				t.Errorf("exit status = %d, want %d\n%s", code, tc.code, out.String())
+			}
+			if !strings.Contains(out.String(), tc.want) || strings.Contains(out.String(), tc.dontWant) {
+				t.Errorf("report lacks %q or has %q:\n%s", tc.want, tc.dontWant, out.String())
+			}
+		})
+	}
+}
+
+func TestRunValidate_UnknownKeys(t *testing.T) {
+	dir := validateDir(t)
+	path := writeValidateConfig(t, dir, "typo.yaml", nil)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bytes.Replace(data, []byte("overlap_percent:"), []byte("overlap_pecent:"), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{[]string{path}, 0, `warn  keys: unknown config key: "overlap_pecent" in defaults (did you mean "overlap_percent"?); ignored`},
+		{[]string{"-strict", path}, 1, `FAIL  config: unknown config key: "overlap_pecent" in defaults`},
+	}
+	for _, tc := range cases {
+		var out, errOut bytes.Buffer
+		if code := runValidate(tc.args, &out, &errOut); code != tc.code {
+			t.Errorf("%v: exit status = %d, want %d", tc.args, code, tc.code)
+		}
+		if !strings.Contains(out.String(), tc.want) {
+			t.Errorf("%v: report lacks %q:\n%s", tc.args, tc.want, out.String())
+		}
+	}
+}
+
+func TestRunValidate_SeveralFiles(t *testing.T) {
+	dir := validateDir(t)
+	good := writeValidateConfig(t, dir, "good.yaml", nil)
+	bad := writeValidateConfig(t, dir, "bad.yaml", func(c *config.Config) { c.Trigger = config.TriggerConfig{Pin: 25, DebounceMs: 20} })
+	var out, errOut bytes.Buffer
+	if code := runValidate([]string{good, bad}, &out, &errOut); code != 1 {
+		t.Errorf("exit status = %d, want 1 when one file is invalid", code)
+	}
+	for _, want := range []string{good + ": valid\n", bad + ": invalid, 1 error\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("report lacks %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunValidate_Include(t *testing.T) {
+	dir := validateDir(t)
+	base := writeValidateConfig(t, dir, "base.yaml", nil)
+	path := filepath.Join(dir, "site.yaml")
+	if err := os.WriteFile(path, []byte("include: [base.yaml]\nlens:\n  focal_length_mm: 50\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var out, errOut bytes.Buffer
+	if code := runValidate([]string{path}, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d\n%s", code, out.String())
+	}
+	if want := "ok    files: merged in order: " + base + ", " + path; !strings.Contains(out.String(), want) {
+		t.Errorf("report lacks %q:\n%s", want, out.String())
+	}
+}
+
+func TestRunValidate_Usage(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := runValidate([]string{"-bogus"}, &out, &errOut); code != 2 {
+		t.Errorf("exit status = %d, want 2", code)
+	}
+	if !strings.Contains(errOut.String(), "Usage: pango validate") {
+		t.Errorf("usage not printed: %q", errOut.String())
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	rdebug "runtime/debug"
+
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// Build information set at link time, e.g.
+//
+//	go build -ldflags "-X main.version=v1.4.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)" ./cmd/pango
+//
+// Empty values are taken from what go build stamps in the binary.
+var version, commit, buildDate string
+
+// buildInfo returns the version, commit and build date of this build: the
+// link-time values, else the module version when installed with go
+// install and the VCS revision and commit date stamped by go build.
+func buildInfo() web.BuildInfo {
+	b := web.BuildInfo{Version: version, Commit: commit, Date: buildDate, GoVersion: runtime.Version()}
+	if info, ok := rdebug.ReadBuildInfo(); ok {
+		if v := info.Main.Version; b.Version == "" && v != "" && v != "(devel)" {
+			b.Version = v
+		}
+		stamped := b.Commit == ""
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && stamped:
+				b.Commit = s.Value
+			case s.Key == "vcs.modified" && stamped:
+				b.Modified = s.Value == "true"
+			case s.Key == "vcs.time" && b.Date == "":
+				b.Date = s.Value
+			}
+		}
+	}
+	if b.Version == "" {
+		b.Version = "dev"
+	}
+	return b
+}
+
+// buildVersion returns the version of this build for display: the release
+// version, else the VCS revision, else "dev".
+func buildVersion() string {
+	b := buildInfo()
+	if b.Version != "dev" || b.Commit == "" {
+		return b.Version
+	}
+	revision := b.Commit
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if b.Modified {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+const versionUsage = `Usage: pango version [-json]
+
+Print the version, commit, build date and Go version of this build. The
+web server also serves them at GET /version, and session records keep the
+build that ran each capture.
+
+Flags:
+`
+
+// runVersion implements "pango version".
+func runVersion(_ context.Context, args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("version", versionUsage, stderr)
+	asJSON := fs.Bool("json", false, "print the document GET /version returns")
+	if !parseFlags(fs, args) {
+		return 2
+	}
+	b := buildInfo()
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(b)
+		return 0
+	}
+	fmt.Fprintf(stdout, "pango %s\n", b)
+	return 0
+}
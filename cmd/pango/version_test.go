@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+func TestBuildVersion(t *testing.T) {
+	// Test binaries carry neither a module version nor VCS stamps
+	if got := buildVersion(); got != "dev" {
+		t.Errorf("buildVersion() = %q, want %q", got, "dev")
+	}
+}
+
+// withBuild sets the link-time build information for the test.
+func withBuild(t *testing.T, v, c, date string) {
+	t.Helper()
+	oldVersion, oldCommit, oldDate := version, commit, buildDate
+	version, commit, buildDate = v, c, date
+	t.Cleanup(func() { version, commit, buildDate = oldVersion, oldCommit, oldDate })
+}
+
+func TestBuildInfo_LinkTime(t *testing.T) {
+	withBuild(t, "v1.4.0", "0123456789abcdef", "2026-10-01T12:00:00Z")
+	b := buildInfo()
+	if b.Version != "v1.4.0" || b.Commit != "0123456789abcdef" || b.Date != "2026-10-01T12:00:00Z" || b.GoVersion == "" {
+		t.Errorf("buildInfo() = %+v", b)
+	}
+	if got := buildVersion(); got != "v1.4.0" {
+		t.Errorf("buildVersion() = %q, want v1.4.0", got)
+	}
+
+	withBuild(t, "", "0123456789abcdef", "")
+	if got := buildVersion(); got != "0123456789ab" {
+		t.Errorf("buildVersion() without version = %q, want the commit", got)
+	}
+}
+
+// ---------- pango version ----------
+
+func TestRunVersion(t *testing.T) {
+	withBuild(t, "v1.4.0", "0123456789abcdef", "2026-10-01T12:00:00Z")
+	var out, errOut bytes.Buffer
+	if code := runPango(context.Background(), []string{"version"}, &out, &errOut); code != 0 {
+		t.Fatalf("exit status = %d: %s", code, errOut.String())
+	}
+	if !strings.HasPrefix(out.String(), "pango v1.4.0 (commit 0123456789ab, built 2026-10-01T12:00:00Z, go") {
+		t.Errorf("output = %q", out.String())
+	}
+
+	out.Reset()
+	if code := runPango(context.Background(), []string{"version", "-json"}, &out, &errOut); code != 0 {
+		t.Fatalf("-json: exit status = %d: %s", code, errOut.String())
+	}
+	var b web.BuildInfo
+	if err := json.Unmarshal(out.Bytes(), &b); err != nil {
+		t.Fatal(err)
+	}
+	if b != buildInfo() {
+		t.Errorf("-json = %+v, want %+v", b, buildInfo())
+	}
+}
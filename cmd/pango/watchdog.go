@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// gpioCallTimeout is how long a GPIO call may last before the service
+// watchdog takes the capture loop for wedged: calls take microseconds.
+const gpioCallTimeout = 10 * time.Second
+
+// watchdogProbeTimeout bounds the web server probe of the service watchdog.
+const watchdogProbeTimeout = 5 * time.Second
+
+// alive is the liveness check of the service watchdog: the capture state
+// can be read (no deadlock), no GPIO call of the capture loop hangs, and
+// the web server still accepts and answers connections.
+func (r *rig) alive(ctx context.Context, srv *web.Server) error {
+	r.lifecycle.Snapshot() // blocks if the capture state is deadlocked
+	if err := r.watch.blocked(time.Now(), gpioCallTimeout); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, watchdogProbeTimeout)
+	defer cancel()
+	return srv.Alive(ctx)
+}
+
+// watchedGPIO is a GPIO driver keeping track of the calls in flight, so
+// that the service watchdog can tell a capture blocked in the hardware.
+type watchedGPIO struct {
+	gpio.Driver
+
+	mu       sync.Mutex
+	next     uint64
+	inFlight map[uint64]gpioCall
+}
+
+// gpioCall is a call to the GPIO driver in flight.
+type gpioCall struct {
+	op    string
+	pin   int
+	start time.Time
+}
+
+func newWatchedGPIO(d gpio.Driver) *watchedGPIO {
+	return &watchedGPIO{Driver: d, inFlight: make(map[uint64]gpioCall)}
+}
+
+// begin records a call and returns the function recording its end.
+func (w *watchedGPIO) begin(op string, pin int) func() {
+	w.mu.Lock()
+	id := w.next
+	w.next++
+	w.inFlight[id] = gpioCall{op: op, pin: pin, start: time.Now()}
+	w.mu.Unlock()
+	return func() {
+		w.mu.Lock()
+		delete(w.inFlight, id)
+		w.mu.Unlock()
+	}
+}
+
+func (w *watchedGPIO) SetupPin(pin int, mode gpio.PinMode) error {
+	defer w.begin("SetupPin", pin)()
+	return w.Driver.SetupPin(pin, mode)
+}
+
+func (w *watchedGPIO) WritePin(pin int, level gpio.Level) error {
+	defer w.begin("WritePin", pin)()
+	return w.Driver.WritePin(pin, level)
+}
+
+func (w *watchedGPIO) ReadPin(pin int) (gpio.Level, error) {
+	defer w.begin("ReadPin", pin)()
+	return w.Driver.ReadPin(pin)
+}
+
+// blocked returns an error naming the oldest call in flight for longer
+// than timeout at now, nil when there is none (or w is nil).
+func (w *watchedGPIO) blocked(now time.Time, timeout time.Duration) error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var oldest *gpioCall
+	for _, c := range w.inFlight {
+		if now.Sub(c.start) > timeout && (oldest == nil || c.start.Before(oldest.start)) {
+			oldest = &c
+		}
+	}
+	if oldest == nil {
+		return nil
+	}
+	return fmt.Errorf("GPIO %s on pin %d blocked for %v", oldest.op, oldest.pin, now.Sub(oldest.start).Round(time.Second))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// stuckGPIO blocks WritePin until release is closed.
+type stuckGPIO struct {
+	gpio.MockDriver
+	writing, release chan struct{}
+}
+
+func (s *stuckGPIO) WritePin(pin int, level gpio.Level) error {
+	close(s.writing)
+	<-s.release
+	return nil
+}
+
+// ---------- watchedGPIO ----------
+
+func TestWatchedGPIO_Blocked(t *testing.T) {
+	stuck := &stuckGPIO{writing: make(chan struct{}), release: make(chan struct{})}
+	w := newWatchedGPIO(stuck)
+	if _, err := w.ReadPin(4); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		w.WritePin(17, gpio.High)
+		close(done)
+	}()
+	<-stuck.writing
+
+	now := time.Now()
+	if err := w.blocked(now, gpioCallTimeout); err != nil {
+		t.Errorf("blocked right after the call = %v, want nil", err)
+	}
+	err := w.blocked(now.Add(gpioCallTimeout+time.Second), gpioCallTimeout)
+	if err == nil || !strings.Contains(err.Error(), "GPIO WritePin on pin 17 blocked") {
+		t.Errorf("blocked = %v", err)
+	}
+
+	close(stuck.release)
+	<-done
+	if err := w.blocked(now.Add(time.Hour), gpioCallTimeout); err != nil {
+		t.Errorf("blocked after the call returned = %v, want nil", err)
+	}
+
+	var none *watchedGPIO
+	if err := none.blocked(now, gpioCallTimeout); err != nil {
+		t.Errorf("nil watcher: %v", err)
+	}
+}
+
+// ---------- rig.alive ----------
+
+func TestRig_Alive(t *testing.T) {
+	stuck := &stuckGPIO{writing: make(chan struct{}), release: make(chan struct{})}
+	watch := newWatchedGPIO(stuck)
+	r := &rig{gpio: watch, watch: watch, lifecycle: capture.NewLifecycle()}
+	srv := web.NewServer(":1", web.NewStatusBroadcaster(), nil, web.FormConfig{}) // not serving
+
+	if err := r.alive(context.Background(), srv); err != nil {
+		t.Errorf("alive = %v", err)
+	}
+	go watch.WritePin(17, gpio.High)
+	<-stuck.writing
+	watch.mu.Lock()
+	for id, c := range watch.inFlight {
+		c.start = c.start.Add(-time.Minute)
+		watch.inFlight[id] = c
+	}
+	watch.mu.Unlock()
+	if err := r.alive(context.Background(), srv); err == nil {
+		t.Error("alive with a GPIO call blocked for a minute: want an error")
+	}
+	close(stuck.release)
+}
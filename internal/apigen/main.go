@@ -0,0 +1,243 @@
+// Command apigen writes the OpenAPI document and the Go client methods from
+// the operations described in package web. It is run by go generate in
+// package client.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+func main() {
+	clientPath := flag.String("client", "client_gen.go", "generated client file")
+	specPath := flag.String("spec", "openapi.json", "generated OpenAPI document")
+	flag.Parse()
+
+	spec, err := generateSpec()
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := generateClient(web.Operations())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*specPath, spec, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*clientPath, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generateSpec returns the OpenAPI document as indented JSON.
+func generateSpec() ([]byte, error) {
+	data, err := json.MarshalIndent(web.OpenAPI(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// method is the template data of one client method.
+type method struct {
+	web.Operation
+	Doc      string
+	Params   string // extra parameters after ctx
+	Body     string // request body argument, "nil" when none
+	Out      string // Go type of the response, "" when none
+	Query    []web.QueryParam
+	HasQuery bool
+}
+
+// alias is a generated type alias for an API type of an internal package.
+type alias struct {
+	Name, Target string
+}
+
+var clientTemplate = template.Must(template.New("client").Parse(`// Code generated by apigen from internal/web.Operations. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+{{- range .StdImports}}
+	"{{.}}"
+{{- end}}
+{{if .ModImports}}
+{{- range .ModImports}}
+	"{{.}}"
+{{- end}}
+{{- end}}
+)
+
+// apiPrefix is where the API routes are served (web.APIPrefix).
+const apiPrefix = "{{.Prefix}}"
+
+// API types, shared with the server.
+type (
+{{- range .Aliases}}
+	{{.Name}} = {{.Target}}
+{{- end}}
+)
+{{range .Methods}}
+// {{.ID}} calls {{.Method}} {{.Path}}: {{.Doc}}
+func (c *Client) {{.ID}}(ctx context.Context{{.Params}}) {{if .Out}}({{.Out}}, error){{else}}error{{end}} {
+{{- if .HasQuery}}
+	query := url.Values{}
+{{- range .Query}}
+	query.Set("{{.Name}}", fmt.Sprint({{.Name}}))
+{{- end}}
+{{- end}}
+{{- if .Out}}
+	var out {{.Out}}
+	err := c.do(ctx, "{{.Method}}", "{{.Path}}", {{if .HasQuery}}query{{else}}nil{{end}}, {{.Body}}, {{.Status}}, &out)
+	return out, err
+{{- else}}
+	return c.do(ctx, "{{.Method}}", "{{.Path}}", {{if .HasQuery}}query{{else}}nil{{end}}, {{.Body}}, {{.Status}}, nil)
+{{- end}}
+}
+{{end}}`))
+
+// generateClient returns the gofmt-ed source of the client methods.
+func generateClient(ops []web.Operation) ([]byte, error) {
+	g := &typeNamer{aliases: map[string]alias{}, imports: map[string]bool{}}
+	var methods []method
+	for _, op := range ops {
+		m := method{Operation: op, Doc: sentence(op.Summary), Body: "nil", Query: op.Query, HasQuery: len(op.Query) > 0}
+		if op.Request != nil {
+			m.Params += ", body " + g.name(reflect.TypeOf(op.Request))
+			m.Body = "body"
+		}
+		for _, q := range op.Query {
+			typ, ok := queryTypes[q.Type]
+			if !ok {
+				return nil, fmt.Errorf("%s: unsupported query parameter type %q", op.ID, q.Type)
+			}
+			m.Params += ", " + q.Name + " " + typ
+		}
+		if op.Response != nil {
+			m.Out = g.name(reflect.TypeOf(op.Response))
+		}
+		if m.HasQuery {
+			g.imports["fmt"] = true
+			g.imports["net/url"] = true
+		}
+		methods = append(methods, m)
+	}
+
+	aliases := make([]alias, 0, len(g.aliases))
+	for _, a := range g.aliases {
+		aliases = append(aliases, a)
+	}
+	sort.Slice(aliases, func(i, j int) bool { return aliases[i].Name < aliases[j].Name })
+	var std, mod []string
+	for imp := range g.imports {
+		if strings.Contains(strings.Split(imp, "/")[0], ".") {
+			mod = append(mod, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(mod)
+
+	var buf bytes.Buffer
+	err := clientTemplate.Execute(&buf, map[string]any{"Prefix": web.APIPrefix, "StdImports": std, "ModImports": mod, "Aliases": aliases, "Methods": methods})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+var queryTypes = map[string]string{"boolean": "bool", "integer": "int", "string": "string"}
+
+// typeNamer spells Go types for the client, aliasing types of the module's
+// internal packages (which other modules cannot import) and recording the
+// imports they need.
+type typeNamer struct {
+	aliases map[string]alias
+	imports map[string]bool
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// name returns the Go spelling of t in the client.
+func (g *typeNamer) name(t reflect.Type) string {
+	switch {
+	case t == rawMessageType:
+		// Spelled by name: it may be an alias of another type.
+		g.imports["encoding/json"] = true
+		return "json.RawMessage"
+	case t.Name() != "" && t.PkgPath() != "":
+		if g.alias(t) {
+			return t.Name()
+		}
+		g.imports[t.PkgPath()] = true
+		return t.String()
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + g.name(t.Elem())
+	case reflect.Slice:
+		return "[]" + g.name(t.Elem())
+	case reflect.Map:
+		return "map[" + g.name(t.Key()) + "]" + g.name(t.Elem())
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "any"
+		}
+	}
+	if t.Name() != "" {
+		return t.Name() // predeclared type
+	}
+	panic(fmt.Sprintf("apigen: unsupported type %s", t))
+}
+
+// alias declares an alias for t when it belongs to an internal package,
+// along with the internal types of its fields, so that callers can name
+// them. It reports whether t is aliased.
+func (g *typeNamer) alias(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Name() == "" || !strings.Contains(t.PkgPath(), "/internal/") {
+		return false
+	}
+	if a, ok := g.aliases[t.Name()]; ok {
+		if a.Target != t.String() {
+			panic(fmt.Sprintf("apigen: %s and %s would both be aliased as %s", a.Target, t, t.Name()))
+		}
+		return true
+	}
+	g.imports[t.PkgPath()] = true
+	g.aliases[t.Name()] = alias{Name: t.Name(), Target: t.String()}
+	if t.Kind() == reflect.Struct {
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() {
+				g.alias(f.Type)
+			}
+		}
+	}
+	return true
+}
+
+// sentence turns a summary into the end of a doc comment sentence.
+func sentence(s string) string {
+	r := []rune(s)
+	if len(r) > 0 {
+		r[0] = unicode.ToLower(r[0])
+	}
+	return strings.TrimSuffix(string(r), ".") + "."
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// TestGeneratedFilesUpToDate fails when the API description changed without
+// running go generate in package client.
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	spec, err := generateSpec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := generateClient(web.Operations())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string][]byte{"../../api/openapi.json": spec, "../../client/client_gen.go": src} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date: run go generate ./client", path)
+		}
+	}
+}
+
+func TestGenerateClient_Methods(t *testing.T) {
+	src, err := generateClient([]web.Operation{
+		{ID: "Ping", Method: "POST", Path: "/ping", Summary: "Check the server", Status: 204},
+		{ID: "Plan", Method: "POST", Path: "/plan", Summary: "Preview.", Request: web.Overrides{}, Response: []web.Plan{}, Status: 200,
+			Query: []web.QueryParam{{Name: "dry", Type: "boolean"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// Ping calls POST /ping: check the server.\nfunc (c *Client) Ping(ctx context.Context) error {",
+		"func (c *Client) Plan(ctx context.Context, body Overrides, dry bool) ([]Plan, error) {",
+		`query.Set("dry", fmt.Sprint(dry))`,
+		"Overrides = web.Overrides",
+		"PlanGrid  = web.PlanGrid", // field type of Plan
+	} {
+		if !bytes.Contains(src, []byte(want)) {
+			t.Errorf("generated source lacks %q:\n%s", want, src)
+		}
+	}
+}
+
+func TestGenerateClient_UnsupportedQueryType(t *testing.T) {
+	_, err := generateClient([]web.Operation{{ID: "X", Method: "GET", Path: "/x", Status: 200, Query: []web.QueryParam{{Name: "n", Type: "array"}}}})
+	if err == nil {
+		t.Error("expected error")
+	}
+}
@@ -1,11 +1,22 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/cjeanneret/PanGo/internal/debug"
+	"github.com/cjeanneret/PanGo/internal/hw/gamepad"
+	"github.com/cjeanneret/PanGo/internal/hw/ssd1306"
 	"gopkg.in/yaml.v3"
 )
 
@@ -21,12 +32,15 @@ type StepperConfig struct {
 // CameraConfig describes how to communicate with the camera.
 // Type selects a concrete implementation (e.g., "nikon_d90_gpio").
 type CameraConfig struct {
-	Type            string `yaml:"type"`               // e.g., "nikon_d90_gpio"
-	FocusPin        int    `yaml:"focus_pin"`          // GPIO pin for FOCUS line
-	ShutterPin      int    `yaml:"shutter_pin"`        // GPIO pin for SHUTTER line
-	FocusDelayMs    int    `yaml:"focus_delay_ms"`     // autofocus delay (ms)
-	ShutterDelayMs  int    `yaml:"shutter_delay_ms"`   // shutter hold time (ms)
-	PostShotDelayMs int    `yaml:"post_shot_delay_ms"` // delay after shot before movement (ms)
+	Type             string `yaml:"type"`               // e.g., "nikon_d90_gpio"
+	FocusPin         int    `yaml:"focus_pin"`          // GPIO pin for FOCUS line
+	ShutterPin       int    `yaml:"shutter_pin"`        // GPIO pin for SHUTTER line
+	FocusDelayMs     int    `yaml:"focus_delay_ms"`     // autofocus delay (ms)
+	ShutterDelayMs   int    `yaml:"shutter_delay_ms"`   // shutter hold time (ms)
+	PostShotDelayMs  int    `yaml:"post_shot_delay_ms"` // delay after shot before movement (ms)
+	ExposureTimeMs   int    `yaml:"exposure_time_ms"`   // exposure set on the camera (ms). 0 = short exposure, post_shot_delay_ms only.
+	ExposureMarginMs int    `yaml:"exposure_margin_ms"` // extra hold after exposure_time_ms before moving (ms)
+	Download         bool   `yaml:"download"`           // download each picture (tethered USB/network cameras) for the web thumbnails
 	// Note: GND is physically connected to Raspberry Pi ground
 }
 
@@ -56,6 +70,299 @@ type DefaultsConfig struct {
 	VerticalAngleDeg   float64 `yaml:"vertical_angle_deg"`   // total vertical shooting angle (default: 30°)
 	DebugLevel         int     `yaml:"debug_level"`          // debug level 0-4 (0=off, 1=info, 2=live, 3=verbose, 4=trace)
 	MockGPIO           bool    `yaml:"mock_gpio"`            // use mock GPIO (true=dev/test, false=real Raspberry Pi)
+	ReturnHomeOnAbort  bool    `yaml:"return_home_on_abort"` // drive back to the start position when a capture is cancelled or fails
+	RetryFailedShots   bool    `yaml:"retry_failed_shots"`   // on a shoot error, go on and reshoot the failed cells at the end of the grid
+	Mode               string  `yaml:"mode"`                 // capture mode: "grid" (default) or "timelapse"
+	SessionsDir        string  `yaml:"sessions_dir"`         // where session records (shots, timings) are saved as JSON; empty = memory only
+
+	InterMoveDelayMs     int `yaml:"inter_move_delay_ms"`    // delay between movements (ms)
+	StabilizationDelayMs int `yaml:"stabilization_delay_ms"` // stabilization wait before each shot (ms)
+}
+
+// DarkFramesConfig configures dark frames shot at the end of a session
+// (astro/long exposures), for noise subtraction when stacking.
+type DarkFramesConfig struct {
+	Count      int    `yaml:"count"`        // number of dark frames (0 = disabled)
+	Mode       string `yaml:"mode"`         // "prompt" (announce, wait cap_delay_ms) or "shutter" (shoot immediately)
+	CapDelayMs int    `yaml:"cap_delay_ms"` // time given to cap the lens in prompt mode (ms)
+}
+
+// Dark frame modes selectable with dark_frames.mode.
+const (
+	DarkFramesPrompt  = "prompt"
+	DarkFramesShutter = "shutter"
+)
+
+// PanoramaConfig is one grid of a multi-panorama run (panoramas: list).
+// Zero angles fall back to the defaults section (after overrides).
+type PanoramaConfig struct {
+	Name               string  `yaml:"name"`                 // informational, shown in progress messages
+	HorizontalAngleDeg float64 `yaml:"horizontal_angle_deg"` // total horizontal angle (0 = defaults)
+	VerticalAngleDeg   float64 `yaml:"vertical_angle_deg"`   // total vertical angle (0 = defaults)
+	PanCenterDeg       float64 `yaml:"pan_center_deg"`       // grid center, degrees right of the start position
+	TiltCenterDeg      float64 `yaml:"tilt_center_deg"`      // grid center, degrees above the start position
+}
+
+// LogConfig configures a log file kept besides the terminal and the web
+// status stream, and an event log of the status stream, both rotated by
+// size with the same limits, and the debug output level of each module.
+type LogConfig struct {
+	File       string            `yaml:"file"`         // also write the log to this file; "" = none
+	MaxSizeMB  int               `yaml:"max_size_mb"`  // rotate the file past this size (default 10)
+	MaxBackups int               `yaml:"max_backups"`  // rotated files kept (default 5)
+	MaxAgeDays int               `yaml:"max_age_days"` // remove rotated files older than this; 0 = keep them
+	EventsFile string            `yaml:"events_file"`  // pango serve: write the status events to this file, as JSON lines; "" = none
+	Levels     map[string]string `yaml:"levels"`       // debug output level per module, e.g. gpio: trace (see debug.Modules); others follow debug_level
+}
+
+// TracingConfig configures the export of capture traces (spans of the
+// sequence, columns, cells, moves and shots) to an OpenTelemetry collector,
+// with OTLP over HTTP.
+type TracingConfig struct {
+	Endpoint    string            `yaml:"endpoint"`     // collector URL, e.g. http://collector.local:4318; "" = no tracing
+	Headers     map[string]string `yaml:"headers"`      // sent with each export, e.g. an API key of a hosted backend; values are redacted when shown
+	ServiceName string            `yaml:"service_name"` // service.name of the traces (default pango), e.g. one per head
+}
+
+// TriggerConfig configures an optional external trigger input (flash-ready
+// signal, hand switch): the grid waits for a pulse before each move.
+type TriggerConfig struct {
+	Pin        int  `yaml:"pin"`         // GPIO input pin (BCM). 0 = disabled.
+	ActiveLow  bool `yaml:"active_low"`  // pulse pulls the line LOW (switch to GND with pull-up)
+	DebounceMs int  `yaml:"debounce_ms"` // active level must hold this long to count (ms)
+	TimeoutMs  int  `yaml:"timeout_ms"`  // abort the capture if no pulse within this time (ms). 0 = wait forever.
+}
+
+// GPSConfig configures a GPS receiver, read through gpsd or directly from
+// a serial port speaking NMEA: where the head stands is recorded with each
+// run and each shot, to place the panoramas on a map.
+type GPSConfig struct {
+	GPSD     string `yaml:"gpsd"`       // gpsd address, e.g. localhost:2947; "" = none
+	Device   string `yaml:"device"`     // NMEA serial device read without gpsd, e.g. /dev/serial0 (speed set with stty); "" = none
+	MaxAgeMs int    `yaml:"max_age_ms"` // positions older than this are not recorded (default 10000)
+}
+
+// GamepadConfig configures a gamepad or joystick plugged into the Pi, read
+// through evdev while pango serve runs: a stick jogs the head, faster the
+// further it is pushed, and a button takes a test shot, without a phone or
+// laptop.
+type GamepadConfig struct {
+	Device      string  `yaml:"device"`        // evdev device, e.g. /dev/input/by-id/usb-...-event-joystick; "" = none
+	PanAxis     string  `yaml:"pan_axis"`      // axis jogging pan: x, y, z, rx, ry, rz, hat0x, hat0y (default x, left stick)
+	TiltAxis    string  `yaml:"tilt_axis"`     // axis jogging tilt (default y)
+	InvertPan   bool    `yaml:"invert_pan"`    // stick right turns counterclockwise
+	InvertTilt  bool    `yaml:"invert_tilt"`   // stick up tilts down
+	DeadZone    float64 `yaml:"dead_zone"`     // fraction of the travel around the center ignored (default 0.15)
+	Curve       float64 `yaml:"curve"`         // speed curve exponent: 1 linear, 2 (default) finer near the center
+	SlowSpeedMs int     `yaml:"slow_speed_ms"` // delay between steps just past the dead zone (default 20)
+	FastSpeedMs int     `yaml:"fast_speed_ms"` // delay between steps at full deflection; 0 = defaults.move_speed_ms
+	ShootButton string  `yaml:"shoot_button"`  // button taking a test shot, e.g. south (A, cross), start; "none" = none (default south)
+}
+
+// PanelConfig configures a front panel: a rotary encoder with a push
+// button (e.g. a KY-040 module) wired to GPIO inputs, read while pango
+// serve runs. Turning selects an item of the menu (start a capture, select
+// a preset), pressing acts on it, a long press cancels the running capture:
+// the rig runs without a phone or laptop.
+type PanelConfig struct {
+	PinA            int  `yaml:"pin_a"`             // encoder output A (CLK), BCM; 0 = no panel. Swap with pin_b to reverse the direction.
+	PinB            int  `yaml:"pin_b"`             // encoder output B (DT), BCM
+	ButtonPin       int  `yaml:"button_pin"`        // push button (SW), BCM; 0 = none
+	ButtonActiveLow bool `yaml:"button_active_low"` // pressing pulls the line LOW (KY-040)
+	StepsPerDetent  int  `yaml:"steps_per_detent"`  // quadrature transitions per detent: 4 (default), 2 or 1
+	LongPressMs     int  `yaml:"long_press_ms"`     // holding the button this long cancels the capture (default 1000)
+}
+
+// DisplayConfig configures a small OLED display with an SSD1306 controller
+// on the I2C bus, updated while pango serve runs: the capture state and
+// progress, the head position, the web address and the front panel menu,
+// so a headless rig shows its status without a network client.
+type DisplayConfig struct {
+	Bus       string `yaml:"bus"`        // I2C bus device, e.g. /dev/i2c-1; "" = no display
+	Address   int    `yaml:"address"`    // I2C address of the display (default 0x3c; 0x3d on some modules)
+	Height    int    `yaml:"height"`     // 64 (default) or 32 pixels
+	Rotate    bool   `yaml:"rotate"`     // turn the picture upside down, for displays mounted that way
+	RefreshMs int    `yaml:"refresh_ms"` // delay between updates (default 500)
+}
+
+// WebConfig configures the web interface (pango serve).
+type WebConfig struct {
+	Port     int    `yaml:"port"`      // port of pango serve when -port is not given; pango without a command serves when set, else runs one capture
+	BasePath string `yaml:"base_path"` // path prefix behind a reverse proxy, e.g. "/pango"; empty = root
+	GRPCPort int    `yaml:"grpc_port"` // also serve the gRPC API (api/pango.proto) on this port; 0 = disabled
+	INDIPort int    `yaml:"indi_port"` // also serve the head as an INDI telescope on this port (INDI's is 7624); 0 = disabled
+	// INDIAddress is the address the INDI server listens on (default
+	// 127.0.0.1, this machine only). INDI has no authentication: "0.0.0.0"
+	// opens it to the network, within ControlNetworks.
+	INDIAddress string `yaml:"indi_address"`
+	// ONVIF also serves the ONVIF device, media and PTZ services under
+	// /onvif/, for video management software to pan and tilt the head.
+	ONVIF bool `yaml:"onvif"`
+	// UnixSocket also serves the interface on a Unix domain socket at this
+	// path, for a reverse proxy on the same machine; UnixSocketOnly then
+	// disables the TCP port.
+	UnixSocket     string `yaml:"unix_socket"`
+	UnixSocketOnly bool   `yaml:"unix_socket_only"`
+	// Pprof serves the Go runtime profiles under /debug/pprof/, to profile
+	// the rig in place. Requires auth.
+	Pprof bool `yaml:"pprof"`
+	// ControlNetworks restricts the requests acting on the rig (capture,
+	// jog, configuration) to these CIDR ranges or addresses; "local" stands
+	// for the subnets of the Pi's interfaces. Empty = any client.
+	ControlNetworks []string `yaml:"control_networks"`
+	// StaticDir serves the interface files from this directory when it has
+	// them, falling back to those built into PanGo, to edit the frontend
+	// on the rig without rebuilding. Empty = built-in files only.
+	StaticDir string `yaml:"static_dir"`
+
+	Auth     AuthConfig      `yaml:"auth"`
+	TLS      TLSConfig       `yaml:"tls"`
+	MDNS     MDNSConfig      `yaml:"mdns"`
+	CORS     CORSConfig      `yaml:"cors"`
+	Limits   LimitsConfig    `yaml:"limits"`
+	Timeouts TimeoutsConfig  `yaml:"timeouts"`
+	Webhooks []WebhookConfig `yaml:"webhooks,omitempty"`
+	MQTT     MQTTConfig      `yaml:"mqtt"`
+	MultiRig MultiRigConfig  `yaml:"multi_rig"`
+}
+
+// MultiRigConfig makes this rig the leader of a multi-rig capture (stereo
+// panoramas, several viewpoints): each grid capture, in CLI and web mode
+// alike, also runs on the followers, PanGo instances in web mode, and every
+// shot is taken on all rigs together. Followers must plan the same grid.
+type MultiRigConfig struct {
+	Followers      []FollowerConfig `yaml:"followers,omitempty"`
+	ReadyTimeoutMs int              `yaml:"ready_timeout_ms"` // max wait for a follower to reach each cell (ms). 0 = default (120000).
+}
+
+// FollowerConfig is a follower rig and its credentials (its web.auth).
+type FollowerConfig struct {
+	URL      string `yaml:"url"`      // http:// or https:// address of its web interface
+	Token    string `yaml:"token"`    // optional; takes precedence over username and password
+	Username string `yaml:"username"` // optional
+	Password string `yaml:"password"` // optional
+}
+
+// MQTTConfig bridges the web mode to an MQTT broker: status, progress and
+// position are published under topic_prefix, and commands are read from
+// topic_prefix/cmd/<command>. Disabled when broker is empty.
+type MQTTConfig struct {
+	Broker      string `yaml:"broker"`       // "tcp://host[:1883]" or "tls://host[:8883]"
+	Username    string `yaml:"username"`     // optional
+	Password    string `yaml:"password"`     // optional
+	ClientID    string `yaml:"client_id"`    // default "pango"; unique per broker
+	TopicPrefix string `yaml:"topic_prefix"` // default "pango"
+}
+
+// MQTTSchemes are the accepted broker URL schemes.
+var MQTTSchemes = []string{"tcp", "mqtt", "tls", "ssl", "mqtts"}
+
+// WebhookConfig posts a JSON payload to URL when a capture starts and ends,
+// in CLI and web mode alike. It lives in the web section so that neither
+// the URL nor the secret is exposed or changed through the web API.
+type WebhookConfig struct {
+	URL    string   `yaml:"url"`    // http:// or https:// endpoint
+	Events []string `yaml:"events"` // any of WebhookEvents; empty = all
+	Secret string   `yaml:"secret"` // when set, requests carry an HMAC-SHA256 signature of the body (X-PanGo-Signature)
+}
+
+// WebhookEvents are the capture events webhooks can select.
+var WebhookEvents = []string{"started", "done", "failed", "cancelled"}
+
+// LimitsConfig spaces capture starts and rate-limits API requests per client
+// IP. requests_per_minute is keyed by route as "METHOD /path" (e.g.
+// "POST /run", "GET /jobs/{id}"); unlisted routes are not limited.
+type LimitsConfig struct {
+	CaptureSpacingMs  int            `yaml:"capture_spacing_ms"`  // minimum delay between two capture starts (ms). 0 = default (5000).
+	RequestsPerMinute map[string]int `yaml:"requests_per_minute"` // per route and client IP; bursts up to the same count
+}
+
+// TimeoutsConfig bounds how long the web server waits on clients (ms). 0
+// keeps the default given for each field.
+type TimeoutsConfig struct {
+	ReadHeaderMs int `yaml:"read_header_ms"` // request headers (default 5000)
+	ReadMs       int `yaml:"read_ms"`        // whole request, body included (default 5000)
+	WriteMs      int `yaml:"write_ms"`       // response, except streams and jogs (default: none)
+	IdleMs       int `yaml:"idle_ms"`        // keep-alive connection between requests (default 120000)
+	ShutdownMs   int `yaml:"shutdown_ms"`    // in-flight requests on shutdown (default 5000)
+}
+
+// CORSConfig lets pages from other origins (a separately hosted frontend, a
+// local dev server) call the API. Origins are "scheme://host[:port]"; "*"
+// allows any origin, without credentials. Empty disables CORS.
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins"`
+}
+
+// MDNSConfig advertises the web interface on the local network as a
+// _pango._tcp service, reachable at <name>.local.
+type MDNSConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Name    string `yaml:"name"` // host and service instance name (default "pango")
+}
+
+// TLSConfig serves the web interface over HTTPS. With self_signed, a
+// certificate is generated at startup and saved to cert_file/key_file when
+// set and missing (so it is only accepted once by browsers); otherwise
+// cert_file and key_file must point to an existing certificate and key.
+type TLSConfig struct {
+	CertFile   string `yaml:"cert_file"`   // PEM certificate (chain)
+	KeyFile    string `yaml:"key_file"`    // PEM private key
+	SelfSigned bool   `yaml:"self_signed"` // generate a self-signed certificate if the files are missing
+}
+
+// Enabled reports whether the web interface is served over TLS.
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.SelfSigned
+}
+
+// AuthConfig protects the web interface. With a token, clients send
+// "Authorization: Bearer <token>" (or open the page once with ?token=...).
+// With a username and password, browsers prompt for them (HTTP basic auth).
+// Both may be set; either is accepted. All empty = no authentication.
+type AuthConfig struct {
+	Token    string `yaml:"token"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// Enabled reports whether any authentication method is configured.
+func (a AuthConfig) Enabled() bool {
+	return a.Token != "" || a.Username != ""
+}
+
+// Capture modes selectable with defaults.mode.
+const (
+	ModeGrid      = "grid"
+	ModeTimelapse = "timelapse"
+)
+
+// TimelapseConfig describes a fixed-position timelapse (defaults.mode: timelapse).
+type TimelapseConfig struct {
+	Frames     int             `yaml:"frames"`              // number of frames to shoot
+	IntervalMs int             `yaml:"interval_ms"`         // time between the start of two frames (ms)
+	JitterMs   int             `yaml:"jitter_ms"`           // random ± offset on each frame start, 0 = none (ms)
+	BulbRamp   *BulbRampConfig `yaml:"bulb_ramp,omitempty"` // optional exposure ramp (camera in Bulb mode)
+
+	SunTracking *SunTrackingConfig `yaml:"sun_tracking,omitempty"` // optional: follow the sun between frames
+}
+
+// SunTrackingConfig aims the rig at the sun (plus offsets) before each
+// timelapse frame, for eclipse and sun-path timelapses. The rig must start
+// level (tilt 0 = horizon) with pan 0 facing reference_azimuth_deg.
+type SunTrackingConfig struct {
+	LatitudeDeg         float64 `yaml:"latitude_deg"`          // observer latitude, north positive
+	LongitudeDeg        float64 `yaml:"longitude_deg"`         // observer longitude, east positive
+	ReferenceAzimuthDeg float64 `yaml:"reference_azimuth_deg"` // compass azimuth faced at start (0 = north, 90 = east)
+	AzimuthOffsetDeg    float64 `yaml:"azimuth_offset_deg"`    // aim this far right of the sun (negative = left)
+	ElevationOffsetDeg  float64 `yaml:"elevation_offset_deg"`  // aim this far above the sun (negative = below)
+}
+
+// BulbRampConfig ramps the bulb exposure from start to end over the session,
+// evenly in EV (stops), to follow sunset/sunrise light changes.
+type BulbRampConfig struct {
+	StartExposureMs int `yaml:"start_exposure_ms"` // exposure of the first frame (ms)
+	EndExposureMs   int `yaml:"end_exposure_ms"`   // exposure of the last frame (ms)
 }
 
 // MaxConfigFileBytes is the maximum allowed size for a config file (256 KB).
@@ -64,6 +371,13 @@ const MaxConfigFileBytes = 256 << 10
 
 // Config aggregates all application configuration.
 type Config struct {
+	// Version is the layout of the file; older files are migrated when read
+	// (see CurrentVersion). Parse sets it to the current version.
+	Version int `yaml:"version,omitempty" env:"-"`
+	// Strict refuses keys that are not config keys (e.g. a typo) instead of
+	// ignoring them, in this file, its included files and profiles.
+	Strict bool `yaml:"strict,omitempty" env:"-"`
+
 	PanStepper  StepperConfig     `yaml:"pan_stepper"`
 	TiltStepper StepperConfig     `yaml:"tilt_stepper"`
 	Camera      CameraConfig      `yaml:"camera"`
@@ -71,6 +385,27 @@ type Config struct {
 	Sensor      *SensorConfig     `yaml:"sensor,omitempty"`     // optional
 	Resolution  *ResolutionConfig `yaml:"resolution,omitempty"` // optional
 	Defaults    DefaultsConfig    `yaml:"defaults"`
+	Timelapse   TimelapseConfig   `yaml:"timelapse"`
+	DarkFrames  DarkFramesConfig  `yaml:"dark_frames"`
+	Panoramas   []PanoramaConfig  `yaml:"panoramas,omitempty"` // optional: several grids per run
+	Trigger     TriggerConfig     `yaml:"trigger"`
+	GPS         GPSConfig         `yaml:"gps"`
+	Gamepad     GamepadConfig     `yaml:"gamepad"`
+	Panel       PanelConfig       `yaml:"panel"`
+	Display     DisplayConfig     `yaml:"display"`
+	Web         WebConfig         `yaml:"web"`
+	Log         LogConfig         `yaml:"log"`
+	Tracing     TracingConfig     `yaml:"tracing"`
+
+	// Profiles are named variants of the file (e.g. one per lens): each sets
+	// keys of ProfileSections, the others are inherited (see WithProfile).
+	Profiles map[string]map[string]any `yaml:"profiles,omitempty"`
+	Profile  string                    `yaml:"-"` // entry of Profiles applied by WithProfile; "" = none
+	Sources  []string                  `yaml:"-"` // files read by Load, included ones first
+	Warnings []string                  `yaml:"-"` // keys migrated from an older Version, to log
+
+	env     []string          // PANGO_* variables applied (see EnvVars)
+	secrets map[string]string // secret references by key path, e.g. "web.auth.token": "${env:PANGO_TOKEN}" (see SecretRefs)
 }
 
 const (
@@ -78,9 +413,32 @@ const (
 	MaxGPIOPin           = 27
 	MaxMotorStepsPerRev  = 1000
 	MaxCameraDelayMs     = 60000
+	MaxExposureTimeMs    = 30 * 60 * 1000 // 30 min bulb exposures
+	MaxTimelapseFrames   = 100000
+	MaxTimelapseInterval = 24 * 60 * 60 * 1000 // one frame per day
+	MaxDarkFrames        = 1000
+	MaxDarkFrameCapDelay = 10 * 60 * 1000
+	MaxPanoramas         = 32
+	MaxTriggerDebounceMs = 1000
+	MaxTriggerTimeoutMs  = 24 * 60 * 60 * 1000
+	MaxGPSAgeMs          = 60 * 60 * 1000
+	MaxGamepadDeadZone   = 0.9
+	MaxGamepadCurve      = 5.0
+	MinPanelLongPressMs  = 200
+	MaxPanelLongPressMs  = 10000
+	MinDisplayRefreshMs  = 100
+	MaxDisplayRefreshMs  = 10000
+	MaxLogSizeMB         = 1024
+	MaxLogBackups        = 1000
+	MaxLogAgeDays        = 3650
 	MaxFocalLengthMm     = 2000.0
 	MinFocalLengthMm     = 1.0
 	MaxSensorDimensionMm = 100.0
+	MinAuthTokenLength   = 16
+	MaxWebhooks          = 8
+	MaxFollowers         = 8
+	MaxReadyTimeoutMs    = 60 * 60 * 1000
+	MaxMQTTClientID      = 23 // MQTT 3.1.1 guarantees brokers accept up to 23 characters
 )
 
 var validMicrostepping = map[int]bool{
@@ -134,6 +492,12 @@ func validateCameraConfig(cfg CameraConfig) error {
 	if cfg.PostShotDelayMs < 0 || cfg.PostShotDelayMs > MaxCameraDelayMs {
 		return fmt.Errorf("camera post_shot_delay_ms must be between 0 and %d ms, got %d", MaxCameraDelayMs, cfg.PostShotDelayMs)
 	}
+	if cfg.ExposureTimeMs < 0 || cfg.ExposureTimeMs > MaxExposureTimeMs {
+		return fmt.Errorf("camera exposure_time_ms must be between 0 and %d ms, got %d", MaxExposureTimeMs, cfg.ExposureTimeMs)
+	}
+	if cfg.ExposureMarginMs < 0 || cfg.ExposureMarginMs > MaxCameraDelayMs {
+		return fmt.Errorf("camera exposure_margin_ms must be between 0 and %d ms, got %d", MaxCameraDelayMs, cfg.ExposureMarginMs)
+	}
 	return nil
 }
 
@@ -154,12 +518,454 @@ func validateSensorConfig(cfg *SensorConfig) error {
 	return nil
 }
 
-// ValidateConfigPath ensures the path is within a configs/ directory and has .yaml extension.
-// Prevents path traversal (e.g. ../../etc/passwd) when loading configuration.
+func validateTimelapseConfig(cfg TimelapseConfig) error {
+	if cfg.Frames <= 0 || cfg.Frames > MaxTimelapseFrames {
+		return fmt.Errorf("timelapse frames must be between 1 and %d, got %d", MaxTimelapseFrames, cfg.Frames)
+	}
+	if cfg.IntervalMs <= 0 || cfg.IntervalMs > MaxTimelapseInterval {
+		return fmt.Errorf("timelapse interval_ms must be between 1 and %d ms, got %d", MaxTimelapseInterval, cfg.IntervalMs)
+	}
+	// Two consecutive frames may move toward each other by 2*jitter.
+	if cfg.JitterMs < 0 || 2*cfg.JitterMs >= cfg.IntervalMs {
+		return fmt.Errorf("timelapse jitter_ms must be between 0 and half of interval_ms (%d ms), got %d", cfg.IntervalMs, cfg.JitterMs)
+	}
+	if r := cfg.BulbRamp; r != nil {
+		if r.StartExposureMs <= 0 || r.StartExposureMs > MaxExposureTimeMs {
+			return fmt.Errorf("timelapse bulb_ramp start_exposure_ms must be between 1 and %d ms, got %d", MaxExposureTimeMs, r.StartExposureMs)
+		}
+		if r.EndExposureMs <= 0 || r.EndExposureMs > MaxExposureTimeMs {
+			return fmt.Errorf("timelapse bulb_ramp end_exposure_ms must be between 1 and %d ms, got %d", MaxExposureTimeMs, r.EndExposureMs)
+		}
+		if longest := max(r.StartExposureMs, r.EndExposureMs); longest >= cfg.IntervalMs-2*cfg.JitterMs {
+			return fmt.Errorf("timelapse bulb_ramp exposure (%d ms) must be shorter than interval_ms minus twice jitter_ms (%d ms)", longest, cfg.IntervalMs-2*cfg.JitterMs)
+		}
+	}
+	if st := cfg.SunTracking; st != nil {
+		if st.LatitudeDeg < -90 || st.LatitudeDeg > 90 {
+			return fmt.Errorf("timelapse sun_tracking latitude_deg must be between -90 and 90, got %.4f", st.LatitudeDeg)
+		}
+		if st.LongitudeDeg < -180 || st.LongitudeDeg > 180 {
+			return fmt.Errorf("timelapse sun_tracking longitude_deg must be between -180 and 180, got %.4f", st.LongitudeDeg)
+		}
+		if st.ReferenceAzimuthDeg < 0 || st.ReferenceAzimuthDeg >= 360 {
+			return fmt.Errorf("timelapse sun_tracking reference_azimuth_deg must be between 0 and 360, got %.2f", st.ReferenceAzimuthDeg)
+		}
+		if st.AzimuthOffsetDeg < -180 || st.AzimuthOffsetDeg > 180 {
+			return fmt.Errorf("timelapse sun_tracking azimuth_offset_deg must be between -180 and 180, got %.2f", st.AzimuthOffsetDeg)
+		}
+		if st.ElevationOffsetDeg < -90 || st.ElevationOffsetDeg > 90 {
+			return fmt.Errorf("timelapse sun_tracking elevation_offset_deg must be between -90 and 90, got %.2f", st.ElevationOffsetDeg)
+		}
+	}
+	return nil
+}
+
+func validateDarkFramesConfig(cfg DarkFramesConfig) error {
+	if cfg.Count < 0 || cfg.Count > MaxDarkFrames {
+		return fmt.Errorf("dark_frames count must be between 0 and %d, got %d", MaxDarkFrames, cfg.Count)
+	}
+	if cfg.Mode != DarkFramesPrompt && cfg.Mode != DarkFramesShutter {
+		return fmt.Errorf("dark_frames mode must be %q or %q, got %q", DarkFramesPrompt, DarkFramesShutter, cfg.Mode)
+	}
+	if cfg.CapDelayMs < 0 || cfg.CapDelayMs > MaxDarkFrameCapDelay {
+		return fmt.Errorf("dark_frames cap_delay_ms must be between 0 and %d ms, got %d", MaxDarkFrameCapDelay, cfg.CapDelayMs)
+	}
+	return nil
+}
+
+func validatePanoramaConfig(cfg PanoramaConfig, name string) error {
+	if cfg.HorizontalAngleDeg < 0 || cfg.HorizontalAngleDeg > 360 {
+		return fmt.Errorf("%s horizontal_angle_deg must be between 0 and 360, got %.2f", name, cfg.HorizontalAngleDeg)
+	}
+	if cfg.VerticalAngleDeg < 0 || cfg.VerticalAngleDeg > 180 {
+		return fmt.Errorf("%s vertical_angle_deg must be between 0 and 180, got %.2f", name, cfg.VerticalAngleDeg)
+	}
+	if cfg.PanCenterDeg < -180 || cfg.PanCenterDeg > 180 {
+		return fmt.Errorf("%s pan_center_deg must be between -180 and 180, got %.2f", name, cfg.PanCenterDeg)
+	}
+	if cfg.TiltCenterDeg < -90 || cfg.TiltCenterDeg > 90 {
+		return fmt.Errorf("%s tilt_center_deg must be between -90 and 90, got %.2f", name, cfg.TiltCenterDeg)
+	}
+	return nil
+}
+
+func validateTriggerConfig(cfg TriggerConfig) error {
+	if cfg.Pin == 0 {
+		return nil
+	}
+	if err := validateGPIOPin(cfg.Pin, "trigger pin"); err != nil {
+		return err
+	}
+	if cfg.DebounceMs < 0 || cfg.DebounceMs > MaxTriggerDebounceMs {
+		return fmt.Errorf("trigger debounce_ms must be between 0 and %d ms, got %d", MaxTriggerDebounceMs, cfg.DebounceMs)
+	}
+	if cfg.TimeoutMs < 0 || cfg.TimeoutMs > MaxTriggerTimeoutMs {
+		return fmt.Errorf("trigger timeout_ms must be between 0 and %d ms, got %d", MaxTriggerTimeoutMs, cfg.TimeoutMs)
+	}
+	return nil
+}
+
+func validateGPSConfig(cfg GPSConfig) error {
+	if cfg.GPSD != "" && cfg.Device != "" {
+		return fmt.Errorf("gps gpsd and device are exclusive: gpsd reads the device itself")
+	}
+	if cfg.GPSD != "" {
+		if host, port, err := net.SplitHostPort(cfg.GPSD); err != nil || host == "" || port == "" {
+			return fmt.Errorf("gps gpsd %q must be host:port, e.g. localhost:2947", cfg.GPSD)
+		}
+	}
+	if cfg.MaxAgeMs < 1 || cfg.MaxAgeMs > MaxGPSAgeMs {
+		return fmt.Errorf("gps max_age_ms must be between 1 and %d ms, got %d", MaxGPSAgeMs, cfg.MaxAgeMs)
+	}
+	return nil
+}
+
+// setGamepadDefaults fills the unset keys of cfg: the left stick, and a
+// speed curve that allows both framing and fine adjustment.
+func setGamepadDefaults(cfg *GamepadConfig) {
+	if cfg.PanAxis == "" {
+		cfg.PanAxis = "x"
+	}
+	if cfg.TiltAxis == "" {
+		cfg.TiltAxis = "y"
+	}
+	if cfg.DeadZone == 0 {
+		cfg.DeadZone = 0.15
+	}
+	if cfg.Curve == 0 {
+		cfg.Curve = 2
+	}
+	if cfg.SlowSpeedMs == 0 {
+		cfg.SlowSpeedMs = 20
+	}
+	if cfg.ShootButton == "" {
+		cfg.ShootButton = "south"
+	}
+}
+
+func validateGamepadConfig(cfg GamepadConfig) error {
+	if _, err := gamepad.AxisCode(cfg.PanAxis); err != nil {
+		return fmt.Errorf("gamepad pan_axis: %w", err)
+	}
+	if _, err := gamepad.AxisCode(cfg.TiltAxis); err != nil {
+		return fmt.Errorf("gamepad tilt_axis: %w", err)
+	}
+	if cfg.PanAxis == cfg.TiltAxis {
+		return fmt.Errorf("gamepad pan_axis and tilt_axis must differ, both are %q", cfg.PanAxis)
+	}
+	if cfg.ShootButton != "none" {
+		if _, err := gamepad.ButtonCode(cfg.ShootButton); err != nil {
+			return fmt.Errorf("gamepad shoot_button: %w", err)
+		}
+	}
+	if cfg.DeadZone < 0 || cfg.DeadZone > MaxGamepadDeadZone {
+		return fmt.Errorf("gamepad dead_zone must be between 0 and %g, got %g", MaxGamepadDeadZone, cfg.DeadZone)
+	}
+	if cfg.Curve < 1 || cfg.Curve > MaxGamepadCurve {
+		return fmt.Errorf("gamepad curve must be between 1 and %g, got %g", MaxGamepadCurve, cfg.Curve)
+	}
+	const maxSpeedMs = 1000 // as defaults.move_speed_ms
+	if cfg.SlowSpeedMs < 1 || cfg.SlowSpeedMs > maxSpeedMs {
+		return fmt.Errorf("gamepad slow_speed_ms must be between 1 and %d, got %d", maxSpeedMs, cfg.SlowSpeedMs)
+	}
+	if cfg.FastSpeedMs < 0 || cfg.FastSpeedMs > cfg.SlowSpeedMs {
+		return fmt.Errorf("gamepad fast_speed_ms must be between 0 and slow_speed_ms (%d), got %d", cfg.SlowSpeedMs, cfg.FastSpeedMs)
+	}
+	return nil
+}
+
+func validatePanelConfig(cfg PanelConfig) error {
+	if cfg.PinA == 0 && cfg.PinB == 0 && cfg.ButtonPin == 0 {
+		return nil
+	}
+	if cfg.PinA == 0 || cfg.PinB == 0 {
+		return fmt.Errorf("panel pin_a and pin_b must both be set, got %d and %d", cfg.PinA, cfg.PinB)
+	}
+	if cfg.PinA == cfg.PinB {
+		return fmt.Errorf("panel pin_a and pin_b must differ, both are %d", cfg.PinA)
+	}
+	for _, p := range []struct {
+		pin  int
+		name string
+	}{{cfg.PinA, "panel pin_a"}, {cfg.PinB, "panel pin_b"}, {cfg.ButtonPin, "panel button_pin"}} {
+		if err := validateGPIOPin(p.pin, p.name); err != nil {
+			return err
+		}
+	}
+	if cfg.ButtonPin == cfg.PinA || cfg.ButtonPin == cfg.PinB {
+		return fmt.Errorf("panel button_pin must differ from pin_a and pin_b, got %d", cfg.ButtonPin)
+	}
+	switch cfg.StepsPerDetent {
+	case 1, 2, 4:
+	default:
+		return fmt.Errorf("panel steps_per_detent must be 1, 2 or 4, got %d", cfg.StepsPerDetent)
+	}
+	if cfg.LongPressMs < MinPanelLongPressMs || cfg.LongPressMs > MaxPanelLongPressMs {
+		return fmt.Errorf("panel long_press_ms must be between %d and %d ms, got %d", MinPanelLongPressMs, MaxPanelLongPressMs, cfg.LongPressMs)
+	}
+	return nil
+}
+
+func validateDisplayConfig(cfg DisplayConfig) error {
+	if cfg.Bus == "" {
+		return nil
+	}
+	if cfg.Address < 0x03 || cfg.Address > 0x77 {
+		return fmt.Errorf("display address must be a 7-bit I2C address between 0x03 and 0x77, got %#x", cfg.Address)
+	}
+	if cfg.Height != 64 && cfg.Height != 32 {
+		return fmt.Errorf("display height must be 64 or 32, got %d", cfg.Height)
+	}
+	if cfg.RefreshMs < MinDisplayRefreshMs || cfg.RefreshMs > MaxDisplayRefreshMs {
+		return fmt.Errorf("display refresh_ms must be between %d and %d ms, got %d", MinDisplayRefreshMs, MaxDisplayRefreshMs, cfg.RefreshMs)
+	}
+	return nil
+}
+
+func validateLogConfig(cfg LogConfig) error {
+	if cfg.MaxSizeMB < 1 || cfg.MaxSizeMB > MaxLogSizeMB {
+		return fmt.Errorf("log max_size_mb must be between 1 and %d, got %d", MaxLogSizeMB, cfg.MaxSizeMB)
+	}
+	if cfg.MaxBackups < 1 || cfg.MaxBackups > MaxLogBackups {
+		return fmt.Errorf("log max_backups must be between 1 and %d, got %d", MaxLogBackups, cfg.MaxBackups)
+	}
+	if cfg.MaxAgeDays < 0 || cfg.MaxAgeDays > MaxLogAgeDays {
+		return fmt.Errorf("log max_age_days must be between 0 and %d, got %d", MaxLogAgeDays, cfg.MaxAgeDays)
+	}
+	if err := debug.CheckModuleLevels(cfg.Levels); err != nil {
+		return fmt.Errorf("log levels: %w", err)
+	}
+	return nil
+}
+
+func validateTracingConfig(cfg TracingConfig) error {
+	if cfg.Endpoint == "" {
+		return nil
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("tracing endpoint %q must be an http or https URL", cfg.Endpoint)
+	}
+	for name := range cfg.Headers {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("tracing header name %q is invalid", name)
+		}
+	}
+	return nil
+}
+
+func validateAuthConfig(cfg AuthConfig) error {
+	if cfg.Token != "" && len(cfg.Token) < MinAuthTokenLength {
+		return fmt.Errorf("web auth token must be at least %d characters", MinAuthTokenLength)
+	}
+	if (cfg.Username == "") != (cfg.Password == "") {
+		return fmt.Errorf("web auth username and password must be set together")
+	}
+	if strings.Contains(cfg.Username, ":") {
+		return fmt.Errorf("web auth username must not contain ':'")
+	}
+	return nil
+}
+
+func validateTLSConfig(cfg TLSConfig) error {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return fmt.Errorf("web tls cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+func validateMDNSConfig(cfg MDNSConfig) error {
+	if len(cfg.Name) > 63 {
+		return fmt.Errorf("web mdns name must be at most 63 characters, got %d", len(cfg.Name))
+	}
+	for i, c := range cfg.Name {
+		letterOrDigit := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+		if !letterOrDigit && (c != '-' || i == 0 || i == len(cfg.Name)-1) {
+			return fmt.Errorf("web mdns name %q must contain only letters, digits and inner hyphens", cfg.Name)
+		}
+	}
+	return nil
+}
+
+func validateCORSConfig(cfg CORSConfig) error {
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return fmt.Errorf("web cors origin %q must be \"*\" or scheme://host[:port]", origin)
+		}
+	}
+	return nil
+}
+
+func validateLimitsConfig(cfg LimitsConfig) error {
+	if cfg.CaptureSpacingMs < 0 || cfg.CaptureSpacingMs > 3600000 {
+		return fmt.Errorf("web limits capture_spacing_ms must be between 0 and 3600000, got %d", cfg.CaptureSpacingMs)
+	}
+	for route, n := range cfg.RequestsPerMinute {
+		method, path, ok := strings.Cut(route, " ")
+		if !ok || method == "" || !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("web limits route %q must be \"METHOD /path\"", route)
+		}
+		if n < 0 {
+			return fmt.Errorf("web limits requests_per_minute for %q must not be negative, got %d", route, n)
+		}
+	}
+	return nil
+}
+
+// LocalNetworks is the control_networks entry for the subnets of the Pi's
+// own interfaces, resolved at startup.
+const LocalNetworks = "local"
+
+// ParseNetwork parses a control_networks entry other than LocalNetworks: a
+// CIDR range ("192.168.1.0/24") or a single address ("192.168.1.10").
+func ParseNetwork(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		p, err := netip.ParsePrefix(s)
+		return p.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func validateControlNetworks(networks []string) error {
+	for _, n := range networks {
+		if n == LocalNetworks {
+			continue
+		}
+		if _, err := ParseNetwork(n); err != nil {
+			return fmt.Errorf("web control_networks %q must be a CIDR range, an address or %q", n, LocalNetworks)
+		}
+	}
+	return nil
+}
+
+// MaxTimeoutMs bounds each web timeout (1 hour).
+const MaxTimeoutMs = 3600000
+
+func validateTimeoutsConfig(cfg TimeoutsConfig) error {
+	for _, f := range []struct {
+		name string
+		ms   int
+	}{
+		{"read_header_ms", cfg.ReadHeaderMs},
+		{"read_ms", cfg.ReadMs},
+		{"write_ms", cfg.WriteMs},
+		{"idle_ms", cfg.IdleMs},
+		{"shutdown_ms", cfg.ShutdownMs},
+	} {
+		if f.ms < 0 || f.ms > MaxTimeoutMs {
+			return fmt.Errorf("web timeouts %s must be between 0 and %d, got %d", f.name, MaxTimeoutMs, f.ms)
+		}
+	}
+	return nil
+}
+
+func validateWebhooks(hooks []WebhookConfig) error {
+	if len(hooks) > MaxWebhooks {
+		return fmt.Errorf("at most %d web webhooks, got %d", MaxWebhooks, len(hooks))
+	}
+	for i, h := range hooks {
+		u, err := url.Parse(h.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("web webhooks[%d] url must be an http:// or https:// URL", i)
+		}
+		for _, event := range h.Events {
+			if !slices.Contains(WebhookEvents, event) {
+				return fmt.Errorf("web webhooks[%d] event %q must be one of %s", i, event, strings.Join(WebhookEvents, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+func validateMultiRigConfig(cfg MultiRigConfig) error {
+	if len(cfg.Followers) > MaxFollowers {
+		return fmt.Errorf("at most %d web multi_rig followers, got %d", MaxFollowers, len(cfg.Followers))
+	}
+	for i, f := range cfg.Followers {
+		u, err := url.Parse(f.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("web multi_rig followers[%d] url must be an http:// or https:// URL", i)
+		}
+	}
+	if cfg.ReadyTimeoutMs < 0 || cfg.ReadyTimeoutMs > MaxReadyTimeoutMs {
+		return fmt.Errorf("web multi_rig ready_timeout_ms must be between 0 and %d, got %d", MaxReadyTimeoutMs, cfg.ReadyTimeoutMs)
+	}
+	return nil
+}
+
+// MaxUnixSocketPath is the longest Unix socket path Linux accepts
+// (sizeof(sun_path) - 1).
+const MaxUnixSocketPath = 107
+
+func validateUnixSocket(cfg WebConfig) error {
+	if cfg.UnixSocket == "" {
+		if cfg.UnixSocketOnly {
+			return fmt.Errorf("web unix_socket_only requires unix_socket")
+		}
+		return nil
+	}
+	if len(cfg.UnixSocket) > MaxUnixSocketPath {
+		return fmt.Errorf("web unix_socket %q is longer than %d bytes", cfg.UnixSocket, MaxUnixSocketPath)
+	}
+	return nil
+}
+
+// validateBasePath checks a web base_path, already without its trailing
+// slash.
+func validateBasePath(base string) error {
+	if base == "" {
+		return nil
+	}
+	if !strings.HasPrefix(base, "/") || strings.Contains(base, "//") {
+		return fmt.Errorf("web base_path %q must start with / and not contain //", base)
+	}
+	for _, c := range base {
+		safe := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("/-_.~", c)
+		if !safe {
+			return fmt.Errorf("web base_path %q must contain only letters, digits and / - _ . ~", base)
+		}
+	}
+	return nil
+}
+
+func validateMQTTConfig(cfg MQTTConfig) error {
+	if cfg.Broker == "" {
+		return nil
+	}
+	u, err := url.Parse(cfg.Broker)
+	if err != nil || !slices.Contains(MQTTSchemes, u.Scheme) || u.Hostname() == "" || (u.Path != "" && u.Path != "/") {
+		return fmt.Errorf("web mqtt broker %q must be scheme://host[:port] with scheme %s", cfg.Broker, strings.Join(MQTTSchemes, ", "))
+	}
+	if len(cfg.ClientID) > MaxMQTTClientID {
+		return fmt.Errorf("web mqtt client_id must be at most %d characters, got %d", MaxMQTTClientID, len(cfg.ClientID))
+	}
+	if strings.ContainsAny(cfg.TopicPrefix, "+#") || strings.HasPrefix(cfg.TopicPrefix, "/") || strings.HasSuffix(cfg.TopicPrefix, "/") {
+		return fmt.Errorf("web mqtt topic_prefix %q must not contain + or # nor start or end with /", cfg.TopicPrefix)
+	}
+	return nil
+}
+
+// Extensions are the accepted config file extensions; a profile with
+// several files is read from the first. JSON documents are valid YAML; TOML
+// documents are converted (see toYAML).
+var Extensions = []string{".yaml", ".json", ".toml"}
+
+// ValidateConfigPath ensures the path is within a configs/ directory and has
+// one of Extensions. Prevents path traversal (e.g. ../../etc/passwd) when
+// loading configuration.
 func ValidateConfigPath(path string) error {
 	cleaned := filepath.Clean(path)
-	if filepath.Ext(cleaned) != ".yaml" {
-		return fmt.Errorf("config file must have .yaml extension, got %s", filepath.Ext(cleaned))
+	if !slices.Contains(Extensions, filepath.Ext(cleaned)) {
+		return fmt.Errorf("config file must have a %s extension, got %q", strings.Join(Extensions, " or "), filepath.Ext(cleaned))
 	}
 	absPath, err := filepath.Abs(cleaned)
 	if err != nil {
@@ -173,26 +979,100 @@ func ValidateConfigPath(path string) error {
 	return nil
 }
 
-// Load reads a YAML file and returns the configuration.
+// Load reads a YAML, JSON or TOML file, merged over the files it includes
+// (see readConfig), and returns the configuration.
 func Load(path string) (*Config, error) {
 	if err := ValidateConfigPath(path); err != nil {
 		return nil, err
 	}
-	info, err := os.Stat(path)
+	return load(path, false)
+}
+
+// LoadStrict is Load with Strict set, whatever the file says: keys that
+// are not config keys are refused with ErrUnknownKey.
+func LoadStrict(path string) (*Config, error) {
+	if err := ValidateConfigPath(path); err != nil {
+		return nil, err
+	}
+	return load(path, true)
+}
+
+func load(path string, strict bool) (*Config, error) {
+	data, sources, warnings, err := readConfig(path)
 	if err != nil {
-		return nil, fmt.Errorf("read config file: %w", err)
+		return nil, err
 	}
-	if info.Size() > MaxConfigFileBytes {
-		return nil, fmt.Errorf("config file too large: %d bytes (max %d)", info.Size(), MaxConfigFileBytes)
+	cfg, err := parse(data, strict, readSecret)
+	if err != nil {
+		return nil, err
 	}
-	data, err := os.ReadFile(path)
+	cfg.Sources = sources
+	cfg.Warnings = append(warnings, cfg.Warnings...)
+	return cfg, nil
+}
+
+// Parse decodes a YAML (or JSON) configuration document, applies the
+// PANGO_* environment variables (see EnvPrefix) and defaults, and validates
+// it with the same rules as Load. The document comes from a client (the web
+// API): secret references are refused with ErrSecretRef, anywhere in it.
+func Parse(data []byte) (*Config, error) {
+	return ParseUpdate(data, nil)
+}
+
+// ParseUpdate is Parse for a document replacing current, e.g. edited from
+// GET /config: its secret references are accepted where current has the
+// same ones, and keep their values; others are refused with ErrSecretRef.
+// Values left Redacted keep those of current.
+func ParseUpdate(data []byte, current *Config) (*Config, error) {
+	data, err := restoreRedacted(data, current)
 	if err != nil {
-		return nil, fmt.Errorf("read config file: %w", err)
+		return nil, err
 	}
+	if err := checkSecretRefs(data, current); err != nil {
+		return nil, err
+	}
+	return parse(data, false, func(path, kind, name string) (string, error) {
+		if value, ok := current.stringAt(path); ok {
+			return value, nil
+		}
+		return "", ErrSecretRef
+	})
+}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("unmarshal yaml: %w", err)
+// parse decodes data, with Strict set when strict is, reading its secret
+// references with secrets. Errors name the keys of resolved references
+// rather than showing their values.
+func parse(data []byte, strict bool, secrets secretReader) (*Config, error) {
+	cfg := &Config{}
+	if err := cfg.parse(data, strict, secrets); err != nil {
+		return nil, cfg.hideSecrets(err)
+	}
+	return cfg, nil
+}
+
+// parse decodes data into cfg: see parse.
+func (cfg *Config) parse(data []byte, strict bool, secrets secretReader) error {
+	if len(data) > MaxConfigFileBytes {
+		return fmt.Errorf("config too large: %d bytes (max %d)", len(data), MaxConfigFileBytes)
+	}
+
+	data, warnings, err := migrate(data)
+	if err != nil {
+		return err
+	}
+	if data, err = normalizeUnits(data); err != nil {
+		return err
+	}
+	if err := decode(data, cfg, strict); err != nil {
+		return err
+	}
+	cfg.Version, cfg.Warnings = CurrentVersion(), warnings
+	cfg.Strict = cfg.Strict || strict
+	if err := resolveSecrets(cfg, secrets); err != nil {
+		return err
+	}
+	if err := envOverrides(cfg); err != nil {
+		return err
 	}
 
 	// Apply defaults for stepper configs if not provided
@@ -213,29 +1093,29 @@ func Load(path string) (*Config, error) {
 
 	// Validate stepper configurations
 	if err := validateStepperConfig(cfg.PanStepper, "pan_stepper"); err != nil {
-		return nil, err
+		return err
 	}
 	if err := validateStepperConfig(cfg.TiltStepper, "tilt_stepper"); err != nil {
-		return nil, err
+		return err
 	}
 
 	// Validate camera configuration
 	if cfg.Camera.Type == "" {
-		return nil, fmt.Errorf("camera.type is required")
+		return fmt.Errorf("camera.type is required")
 	}
 	if err := validateCameraConfig(cfg.Camera); err != nil {
-		return nil, err
+		return err
 	}
 
 	// Validate lens configuration
 	if err := validateLensConfig(cfg.Lens); err != nil {
-		return nil, err
+		return err
 	}
 
 	// Validate sensor configuration if provided
 	if cfg.Sensor != nil {
 		if err := validateSensorConfig(cfg.Sensor); err != nil {
-			return nil, err
+			return err
 		}
 	}
 	const MaxMoveSpeedMs = 1000
@@ -246,7 +1126,7 @@ func Load(path string) (*Config, error) {
 		cfg.Defaults.MoveSpeedMs = MaxMoveSpeedMs // cap at max
 	}
 	if cfg.Defaults.OverlapPercent < 0 || cfg.Defaults.OverlapPercent > 100 {
-		return nil, fmt.Errorf("overlap_percent must be between 0 and 100, got %.2f", cfg.Defaults.OverlapPercent)
+		return fmt.Errorf("overlap_percent must be between 0 and 100, got %.2f", cfg.Defaults.OverlapPercent)
 	}
 	if cfg.Defaults.OverlapPercent == 0 {
 		cfg.Defaults.OverlapPercent = 30 // reasonable default (30%)
@@ -258,10 +1138,23 @@ func Load(path string) (*Config, error) {
 		cfg.Defaults.VerticalAngleDeg = 30 // default (30°)
 	}
 	if cfg.Defaults.HorizontalAngleDeg > 360 {
-		return nil, fmt.Errorf("horizontal_angle_deg must be <= 360, got %.2f", cfg.Defaults.HorizontalAngleDeg)
+		return fmt.Errorf("horizontal_angle_deg must be <= 360, got %.2f", cfg.Defaults.HorizontalAngleDeg)
 	}
 	if cfg.Defaults.VerticalAngleDeg > 180 {
-		return nil, fmt.Errorf("vertical_angle_deg must be <= 180, got %.2f", cfg.Defaults.VerticalAngleDeg)
+		return fmt.Errorf("vertical_angle_deg must be <= 180, got %.2f", cfg.Defaults.VerticalAngleDeg)
+	}
+
+	if cfg.Defaults.InterMoveDelayMs < 0 || cfg.Defaults.InterMoveDelayMs > MaxCameraDelayMs {
+		return fmt.Errorf("inter_move_delay_ms must be between 0 and %d ms, got %d", MaxCameraDelayMs, cfg.Defaults.InterMoveDelayMs)
+	}
+	if cfg.Defaults.StabilizationDelayMs < 0 || cfg.Defaults.StabilizationDelayMs > MaxCameraDelayMs {
+		return fmt.Errorf("stabilization_delay_ms must be between 0 and %d ms, got %d", MaxCameraDelayMs, cfg.Defaults.StabilizationDelayMs)
+	}
+	if cfg.Defaults.InterMoveDelayMs == 0 {
+		cfg.Defaults.InterMoveDelayMs = 500 // 500ms between movements
+	}
+	if cfg.Defaults.StabilizationDelayMs == 0 {
+		cfg.Defaults.StabilizationDelayMs = 300 // 300ms for the rig to settle before shooting
 	}
 
 	// Default values for camera delays (if not set)
@@ -274,13 +1167,378 @@ func Load(path string) (*Config, error) {
 	if cfg.Camera.PostShotDelayMs == 0 {
 		cfg.Camera.PostShotDelayMs = 300 // 300ms after shot before movement
 	}
+	if cfg.Camera.ExposureMarginMs == 0 {
+		cfg.Camera.ExposureMarginMs = 250 // 250ms safety margin after long exposures
+	}
+
+	switch cfg.Defaults.Mode {
+	case "":
+		cfg.Defaults.Mode = ModeGrid
+	case ModeGrid:
+	case ModeTimelapse:
+		if cfg.Timelapse.IntervalMs == 0 {
+			cfg.Timelapse.IntervalMs = 10000 // one frame every 10s
+		}
+		if err := validateTimelapseConfig(cfg.Timelapse); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("mode must be %q or %q, got %q", ModeGrid, ModeTimelapse, cfg.Defaults.Mode)
+	}
+
+	if len(cfg.Panoramas) > MaxPanoramas {
+		return fmt.Errorf("at most %d panoramas per run, got %d", MaxPanoramas, len(cfg.Panoramas))
+	}
+	for i, p := range cfg.Panoramas {
+		if err := validatePanoramaConfig(p, fmt.Sprintf("panoramas[%d]", i)); err != nil {
+			return err
+		}
+	}
+
+	if cfg.DarkFrames.Mode == "" {
+		cfg.DarkFrames.Mode = DarkFramesPrompt
+	}
+	if cfg.DarkFrames.Mode == DarkFramesPrompt && cfg.DarkFrames.CapDelayMs == 0 {
+		cfg.DarkFrames.CapDelayMs = 30000 // 30s to cap the lens
+	}
+	if err := validateDarkFramesConfig(cfg.DarkFrames); err != nil {
+		return err
+	}
+
+	if cfg.Trigger.Pin != 0 && cfg.Trigger.DebounceMs == 0 {
+		cfg.Trigger.DebounceMs = 20 // ignore contact bounce
+	}
+	if err := validateTriggerConfig(cfg.Trigger); err != nil {
+		return err
+	}
+
+	if cfg.GPS.MaxAgeMs == 0 {
+		cfg.GPS.MaxAgeMs = 10000
+	}
+	if err := validateGPSConfig(cfg.GPS); err != nil {
+		return err
+	}
+
+	setGamepadDefaults(&cfg.Gamepad)
+	if err := validateGamepadConfig(cfg.Gamepad); err != nil {
+		return err
+	}
+
+	if cfg.Panel.StepsPerDetent == 0 {
+		cfg.Panel.StepsPerDetent = 4
+	}
+	if cfg.Panel.LongPressMs == 0 {
+		cfg.Panel.LongPressMs = 1000
+	}
+	if err := validatePanelConfig(cfg.Panel); err != nil {
+		return err
+	}
+
+	if cfg.Display.Address == 0 {
+		cfg.Display.Address = ssd1306.DefaultAddress
+	}
+	if cfg.Display.Height == 0 {
+		cfg.Display.Height = 64
+	}
+	if cfg.Display.RefreshMs == 0 {
+		cfg.Display.RefreshMs = 500
+	}
+	if err := validateDisplayConfig(cfg.Display); err != nil {
+		return err
+	}
+
+	if cfg.Log.MaxSizeMB == 0 {
+		cfg.Log.MaxSizeMB = 10
+	}
+	if cfg.Log.MaxBackups == 0 {
+		cfg.Log.MaxBackups = 5
+	}
+	if err := validateLogConfig(cfg.Log); err != nil {
+		return err
+	}
+	if err := validateTracingConfig(cfg.Tracing); err != nil {
+		return err
+	}
+
+	if err := validateAuthConfig(cfg.Web.Auth); err != nil {
+		return err
+	}
+	if err := validateTLSConfig(cfg.Web.TLS); err != nil {
+		return err
+	}
+	if cfg.Web.MDNS.Name == "" {
+		cfg.Web.MDNS.Name = "pango"
+	}
+	if err := validateMDNSConfig(cfg.Web.MDNS); err != nil {
+		return err
+	}
+	if err := validateCORSConfig(cfg.Web.CORS); err != nil {
+		return err
+	}
+	if cfg.Web.Limits.CaptureSpacingMs == 0 {
+		cfg.Web.Limits.CaptureSpacingMs = 5000
+	}
+	if err := validateLimitsConfig(cfg.Web.Limits); err != nil {
+		return err
+	}
+	if err := validateTimeoutsConfig(cfg.Web.Timeouts); err != nil {
+		return err
+	}
+	if err := validateControlNetworks(cfg.Web.ControlNetworks); err != nil {
+		return err
+	}
+	if err := validateWebhooks(cfg.Web.Webhooks); err != nil {
+		return err
+	}
+	if err := validateMultiRigConfig(cfg.Web.MultiRig); err != nil {
+		return err
+	}
+	if len(cfg.Web.MultiRig.Followers) > 0 && (cfg.Defaults.Mode != ModeGrid || len(cfg.Panoramas) > 0) {
+		return fmt.Errorf("web multi_rig followers require single-grid captures: mode grid, no panoramas")
+	}
+	if cfg.Web.Port < 0 || cfg.Web.Port > 65535 {
+		return fmt.Errorf("web port must be 0 (CLI mode) or 1-65535, got %d", cfg.Web.Port)
+	}
+	if cfg.Web.GRPCPort < 0 || cfg.Web.GRPCPort > 65535 {
+		return fmt.Errorf("web grpc_port must be 0 (disabled) or 1-65535, got %d", cfg.Web.GRPCPort)
+	}
+	if cfg.Web.INDIPort < 0 || cfg.Web.INDIPort > 65535 {
+		return fmt.Errorf("web indi_port must be 0 (disabled) or 1-65535, got %d", cfg.Web.INDIPort)
+	}
+	if cfg.Web.INDIAddress == "" {
+		cfg.Web.INDIAddress = "127.0.0.1"
+	}
+	if net.ParseIP(cfg.Web.INDIAddress) == nil {
+		return fmt.Errorf("web indi_address must be an IP address, got %q", cfg.Web.INDIAddress)
+	}
+	if err := validateUnixSocket(cfg.Web); err != nil {
+		return err
+	}
+	if cfg.Web.Pprof && !cfg.Web.Auth.Enabled() {
+		return fmt.Errorf("web pprof requires web auth: profiles expose the command line and memory")
+	}
+	cfg.Web.BasePath = strings.TrimRight(cfg.Web.BasePath, "/")
+	if err := validateBasePath(cfg.Web.BasePath); err != nil {
+		return err
+	}
+	if cfg.Web.MQTT.ClientID == "" {
+		cfg.Web.MQTT.ClientID = "pango"
+	}
+	if cfg.Web.MQTT.TopicPrefix == "" {
+		cfg.Web.MQTT.TopicPrefix = "pango"
+	}
+	if err := validateMQTTConfig(cfg.Web.MQTT); err != nil {
+		return err
+	}
 
 	// Validate debug level is in valid range
 	if cfg.Defaults.DebugLevel < 0 || cfg.Defaults.DebugLevel > 4 {
-		return nil, fmt.Errorf("debug_level must be between 0 and 4, got %d", cfg.Defaults.DebugLevel)
+		return fmt.Errorf("debug_level must be between 0 and 4, got %d", cfg.Defaults.DebugLevel)
 	}
 
-	return &cfg, nil
+	if err := validateProfiles(cfg); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Save writes cfg to path in the format of its extension, replacing the
+// file atomically and keeping the previous version as path+".bak". Comments
+// in the file are not preserved.
+func Save(path string, cfg *Config) error {
+	if err := ValidateConfigPath(path); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("marshal yaml: %w", err)
+	}
+	if data, err = fromYAML(filepath.Ext(path), data); err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// writeFile replaces path with data atomically, keeping the previous version
+// as path+".bak".
+func writeFile(path string, data []byte) error {
+	mode := os.FileMode(0o600) // the file may hold web credentials
+	if prev, err := os.ReadFile(path); err == nil {
+		if info, err := os.Stat(path); err == nil {
+			mode = info.Mode().Perm()
+		}
+		if err := os.WriteFile(path+".bak", prev, mode); err != nil {
+			return fmt.Errorf("back up config file: %w", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*.yaml")
+	if err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	return nil
+}
+
+// HardwareChanged reports whether switching from cur to next changes the
+// motors, camera driver or trigger input, which must then be re-initialized.
+func HardwareChanged(cur, next *Config) bool {
+	camera := func(c CameraConfig) CameraConfig {
+		// Per-shot delays are read for each capture; the rest configures the driver.
+		return CameraConfig{
+			Type:           c.Type,
+			FocusPin:       c.FocusPin,
+			ShutterPin:     c.ShutterPin,
+			FocusDelayMs:   c.FocusDelayMs,
+			ShutterDelayMs: c.ShutterDelayMs,
+		}
+	}
+	return cur.PanStepper != next.PanStepper ||
+		cur.TiltStepper != next.TiltStepper ||
+		camera(cur.Camera) != camera(next.Camera) ||
+		cur.Trigger != next.Trigger ||
+		cur.Defaults.MoveSpeedMs != next.Defaults.MoveSpeedMs
+}
+
+// RestartRequired reports whether switching from cur to next changes
+// settings only read at startup: GPIO driver, web server, logging, tracing,
+// GPS, gamepad, front panel, display and session storage. Other settings
+// apply to the next capture (after re-initializing the hardware when
+// HardwareChanged).
+func RestartRequired(cur, next *Config) bool {
+	return !reflect.DeepEqual(cur.Web, next.Web) ||
+		!reflect.DeepEqual(cur.Log, next.Log) ||
+		!reflect.DeepEqual(cur.Tracing, next.Tracing) ||
+		cur.GPS != next.GPS ||
+		cur.Gamepad != next.Gamepad ||
+		cur.Panel != next.Panel ||
+		cur.Display != next.Display ||
+		cur.Defaults.MockGPIO != next.Defaults.MockGPIO ||
+		cur.Defaults.DebugLevel != next.Defaults.DebugLevel ||
+		cur.Defaults.SessionsDir != next.Defaults.SessionsDir
+}
+
+// ErrProfileNotFound is returned by ProfilePath for unknown profiles.
+var ErrProfileNotFound = errors.New("profile not found")
+
+var (
+	// ErrInvalidProfile wraps SaveProfile errors for invalid names or documents.
+	ErrInvalidProfile = errors.New("invalid profile")
+	// ErrProfileExists is returned by SaveProfile when the profile exists.
+	ErrProfileExists = errors.New("profile already exists")
+)
+
+// SaveProfile validates data, a YAML, JSON or TOML document, with the rules
+// of Parse (no secret references: it comes from a client) and stores it as
+// profile name in dir, as is (comments included):
+// in name.json for JSON, name.toml for TOML, else name.yaml. An existing
+// profile is only replaced with replace, keeping its previous version as
+// .bak, and keeps its format. It returns the path of the profile.
+func SaveProfile(dir, name string, data []byte, replace bool) (string, error) {
+	if !validProfileName(name) {
+		return "", fmt.Errorf("%w: name %q", ErrInvalidProfile, name)
+	}
+	format, doc, err := detectFormat(data)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	path, err := ProfilePath(dir, name)
+	switch {
+	case err == nil && !replace:
+		return "", fmt.Errorf("%w: %q", ErrProfileExists, name)
+	case err != nil:
+		path = filepath.Join(dir, name+format)
+	}
+	if err := ValidateConfigPath(path); err != nil {
+		return "", err
+	}
+	if ext := filepath.Ext(path); ext != format {
+		if data, err = fromYAML(ext, doc); err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+		}
+	}
+	if err := writeFile(path, data); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// CreateProfile saves cfg, with its secret references, as the new profile
+// name in dir (name.yaml), and returns its path. It returns
+// ErrProfileExists when the profile exists.
+func CreateProfile(dir, name string, cfg *Config) (string, error) {
+	if !validProfileName(name) {
+		return "", fmt.Errorf("%w: name %q", ErrInvalidProfile, name)
+	}
+	if _, err := ProfilePath(dir, name); err == nil {
+		return "", fmt.Errorf("%w: %q", ErrProfileExists, name)
+	}
+	path := filepath.Join(dir, name+".yaml")
+	if err := Save(path, cfg); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Profiles lists the configuration profiles in dir: the names of its
+// config files without extension, sorted (e.g. "default", "wide-18mm").
+func Profiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("list profiles: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		name := strings.TrimSuffix(e.Name(), ext)
+		if slices.Contains(Extensions, ext) && e.Type().IsRegular() && validProfileName(name) && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ProfilePath returns the config file of profile name in dir, trying
+// Extensions in order.
+func ProfilePath(dir, name string) (string, error) {
+	if !validProfileName(name) {
+		return "", fmt.Errorf("%w: %q", ErrProfileNotFound, name)
+	}
+	for _, ext := range Extensions {
+		path := filepath.Join(dir, name+ext)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrProfileNotFound, name)
+}
+
+// ProfileName returns the profile name of a config file path.
+func ProfileName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// validProfileName rejects names that could leave the profile directory, and
+// hidden files (such as Save's temporary files).
+func validProfileName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\`)
 }
 
 // MoveSpeed returns the duration between two motor steps.
@@ -335,3 +1593,80 @@ func (c *Config) ShutterDelay() time.Duration {
 func (c *Config) PostShotDelay() time.Duration {
 	return time.Duration(c.Camera.PostShotDelayMs) * time.Millisecond
 }
+
+// InterMoveDelay returns the delay between movements.
+func (c *Config) InterMoveDelay() time.Duration {
+	return time.Duration(c.Defaults.InterMoveDelayMs) * time.Millisecond
+}
+
+// StabilizationDelay returns the stabilization wait before each shot.
+func (c *Config) StabilizationDelay() time.Duration {
+	return time.Duration(c.Defaults.StabilizationDelayMs) * time.Millisecond
+}
+
+// ExposureTime returns the exposure duration configured on the camera.
+// Zero means a short exposure that fits within the post-shot delay.
+func (c *Config) ExposureTime() time.Duration {
+	return time.Duration(c.Camera.ExposureTimeMs) * time.Millisecond
+}
+
+// ExposureMargin returns the extra hold after an exposure before movement.
+func (c *Config) ExposureMargin() time.Duration {
+	return time.Duration(c.Camera.ExposureMarginMs) * time.Millisecond
+}
+
+// TimelapseInterval returns the time between the start of two timelapse frames.
+func (c *Config) TimelapseInterval() time.Duration {
+	return time.Duration(c.Timelapse.IntervalMs) * time.Millisecond
+}
+
+// TimelapseJitter returns the maximum random offset applied to each frame start.
+func (c *Config) TimelapseJitter() time.Duration {
+	return time.Duration(c.Timelapse.JitterMs) * time.Millisecond
+}
+
+// DarkFrameCapDelay returns the time given to cap the lens before dark frames.
+// Zero in shutter mode (no prompt).
+func (c *Config) DarkFrameCapDelay() time.Duration {
+	if c.DarkFrames.Mode == DarkFramesShutter {
+		return 0
+	}
+	return time.Duration(c.DarkFrames.CapDelayMs) * time.Millisecond
+}
+
+// TriggerDebounce returns how long the trigger input must stay active to count as a pulse.
+func (c *Config) TriggerDebounce() time.Duration {
+	return time.Duration(c.Trigger.DebounceMs) * time.Millisecond
+}
+
+// GPSMaxAge returns the age past which a GPS position is not recorded.
+func (c *Config) GPSMaxAge() time.Duration {
+	return time.Duration(c.GPS.MaxAgeMs) * time.Millisecond
+}
+
+// CaptureSpacing returns the minimum delay between two capture starts in web mode.
+func (c *Config) CaptureSpacing() time.Duration {
+	return time.Duration(c.Web.Limits.CaptureSpacingMs) * time.Millisecond
+}
+
+// TriggerTimeout returns the maximum wait for a trigger pulse (0 = no limit).
+func (c *Config) TriggerTimeout() time.Duration {
+	return time.Duration(c.Trigger.TimeoutMs) * time.Millisecond
+}
+
+// LogMaxSize returns the size past which the log file is rotated, in bytes.
+func (c *Config) LogMaxSize() int64 {
+	return int64(c.Log.MaxSizeMB) << 20
+}
+
+// LogMaxAge returns the age past which rotated log files are removed (0 =
+// kept).
+func (c *Config) LogMaxAge() time.Duration {
+	return time.Duration(c.Log.MaxAgeDays) * 24 * time.Hour
+}
+
+// FollowerReadyTimeout returns the maximum wait for a multi-rig follower to
+// reach each cell (0 = the multirig default).
+func (c *Config) FollowerReadyTimeout() time.Duration {
+	return time.Duration(c.Web.MultiRig.ReadyTimeoutMs) * time.Millisecond
+}
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -42,7 +45,7 @@ func TestValidateConfigPath_PathTraversal(t *testing.T) {
 
 func TestValidateConfigPath_WrongExtension(t *testing.T) {
 	cases := []string{
-		"configs/default.json",
+		"configs/default.ini",
 		"configs/default.yml",
 		"configs/default.txt",
 		"configs/default",
@@ -332,6 +335,272 @@ lens:
 	if cfg.Camera.PostShotDelayMs != 300 {
 		t.Errorf("post_shot_delay_ms default = %d, want 300", cfg.Camera.PostShotDelayMs)
 	}
+	if cfg.Camera.ExposureTimeMs != 0 {
+		t.Errorf("exposure_time_ms default = %d, want 0", cfg.Camera.ExposureTimeMs)
+	}
+	if cfg.Camera.ExposureMarginMs != 250 {
+		t.Errorf("exposure_margin_ms default = %d, want 250", cfg.Camera.ExposureMarginMs)
+	}
+	if cfg.Defaults.InterMoveDelayMs != 500 {
+		t.Errorf("inter_move_delay_ms default = %d, want 500", cfg.Defaults.InterMoveDelayMs)
+	}
+	if cfg.Defaults.StabilizationDelayMs != 300 {
+		t.Errorf("stabilization_delay_ms default = %d, want 300", cfg.Defaults.StabilizationDelayMs)
+	}
+}
+
+func TestLoad_ExposureTimeOutOfRange(t *testing.T) {
+	cases := []struct {
+		name  string
+		field string
+		value int
+	}{
+		{"exposure_negative", "exposure_time_ms", -1},
+		{"exposure_too_long", "exposure_time_ms", MaxExposureTimeMs + 1},
+		{"margin_negative", "exposure_margin_ms", -1},
+		{"margin_too_long", "exposure_margin_ms", MaxCameraDelayMs + 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			yaml := fmt.Sprintf(`
+camera:
+  type: "nikon_d90_gpio"
+  %s: %d
+lens:
+  focal_length_mm: 35.0
+`, tc.field, tc.value)
+			path := writeConfig(t, yaml)
+			if _, err := Load(path); err == nil {
+				t.Errorf("expected error for %s=%d, got nil", tc.field, tc.value)
+			}
+		})
+	}
+}
+
+func TestLoad_CaptureDelays(t *testing.T) {
+	path := writeConfig(t, validYAML+"  inter_move_delay_ms: 1200\n  stabilization_delay_ms: 800\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Defaults.InterMoveDelayMs != 1200 || cfg.Defaults.StabilizationDelayMs != 800 {
+		t.Errorf("delays = %d/%d, want 1200/800", cfg.Defaults.InterMoveDelayMs, cfg.Defaults.StabilizationDelayMs)
+	}
+}
+
+func TestLoad_CaptureDelaysOutOfRange(t *testing.T) {
+	cases := []struct {
+		name  string
+		field string
+		value int
+	}{
+		{"inter_move_negative", "inter_move_delay_ms", -1},
+		{"inter_move_too_long", "inter_move_delay_ms", MaxCameraDelayMs + 1},
+		{"stabilization_negative", "stabilization_delay_ms", -1},
+		{"stabilization_too_long", "stabilization_delay_ms", MaxCameraDelayMs + 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, validYAML+fmt.Sprintf("  %s: %d\n", tc.field, tc.value))
+			if _, err := Load(path); err == nil {
+				t.Errorf("expected error for %s=%d, got nil", tc.field, tc.value)
+			}
+		})
+	}
+}
+
+func TestLoad_ReturnHomeOnAbort(t *testing.T) {
+	yaml := `
+camera:
+  type: "nikon_d90_gpio"
+lens:
+  focal_length_mm: 35.0
+defaults:
+  return_home_on_abort: true
+`
+	path := writeConfig(t, yaml)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Defaults.ReturnHomeOnAbort {
+		t.Error("return_home_on_abort = false, want true")
+	}
+}
+
+func TestLoad_RetryFailedShots(t *testing.T) {
+	path := writeConfig(t, validYAML+"  retry_failed_shots: true\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Defaults.RetryFailedShots {
+		t.Error("retry_failed_shots = false, want true")
+	}
+}
+
+func TestLoad_ModeDefaultsToGrid(t *testing.T) {
+	path := writeConfig(t, validYAML)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Defaults.Mode != ModeGrid {
+		t.Errorf("mode = %q, want %q", cfg.Defaults.Mode, ModeGrid)
+	}
+}
+
+func TestLoad_InvalidMode(t *testing.T) {
+	path := writeConfig(t, validYAML+"  mode: spiral\n")
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for unknown mode, got nil")
+	}
+}
+
+func TestLoad_Timelapse(t *testing.T) {
+	yaml := validYAML + `  mode: timelapse
+timelapse:
+  frames: 120
+  bulb_ramp:
+    start_exposure_ms: 100
+    end_exposure_ms: 4000
+`
+	path := writeConfig(t, yaml)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Timelapse.Frames != 120 {
+		t.Errorf("timelapse.frames = %d, want 120", cfg.Timelapse.Frames)
+	}
+	if cfg.Timelapse.IntervalMs != 10000 {
+		t.Errorf("timelapse.interval_ms default = %d, want 10000", cfg.Timelapse.IntervalMs)
+	}
+	if cfg.TimelapseInterval() != 10*time.Second {
+		t.Errorf("TimelapseInterval() = %v, want 10s", cfg.TimelapseInterval())
+	}
+	if cfg.Timelapse.BulbRamp == nil || cfg.Timelapse.BulbRamp.EndExposureMs != 4000 {
+		t.Errorf("timelapse.bulb_ramp = %+v, want end_exposure_ms 4000", cfg.Timelapse.BulbRamp)
+	}
+}
+
+func TestLoad_TimelapseJitter(t *testing.T) {
+	path := writeConfig(t, validYAML+"  mode: timelapse\ntimelapse:\n  frames: 10\n  interval_ms: 1000\n  jitter_ms: 150\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.TimelapseJitter() != 150*time.Millisecond {
+		t.Errorf("TimelapseJitter() = %v, want 150ms", cfg.TimelapseJitter())
+	}
+}
+
+func TestLoad_TimelapseInvalid(t *testing.T) {
+	cases := []struct {
+		name      string
+		timelapse string
+	}{
+		{"missing_frames", "timelapse:\n  interval_ms: 1000\n"},
+		{"negative_interval", "timelapse:\n  frames: 10\n  interval_ms: -1\n"},
+		{"ramp_longer_than_interval", "timelapse:\n  frames: 10\n  interval_ms: 1000\n  bulb_ramp:\n    start_exposure_ms: 100\n    end_exposure_ms: 1000\n"},
+		{"ramp_zero_start", "timelapse:\n  frames: 10\n  interval_ms: 1000\n  bulb_ramp:\n    end_exposure_ms: 500\n"},
+		{"negative_jitter", "timelapse:\n  frames: 10\n  interval_ms: 1000\n  jitter_ms: -1\n"},
+		{"jitter_half_interval", "timelapse:\n  frames: 10\n  interval_ms: 1000\n  jitter_ms: 500\n"},
+		{"ramp_overlaps_with_jitter", "timelapse:\n  frames: 10\n  interval_ms: 1000\n  jitter_ms: 200\n  bulb_ramp:\n    start_exposure_ms: 100\n    end_exposure_ms: 700\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, validYAML+"  mode: timelapse\n"+tc.timelapse)
+			if _, err := Load(path); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoad_DarkFrames(t *testing.T) {
+	path := writeConfig(t, validYAML+"dark_frames:\n  count: 10\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.DarkFrames.Mode != DarkFramesPrompt {
+		t.Errorf("dark_frames.mode default = %q, want %q", cfg.DarkFrames.Mode, DarkFramesPrompt)
+	}
+	if cfg.DarkFrameCapDelay() != 30*time.Second {
+		t.Errorf("DarkFrameCapDelay() = %v, want 30s", cfg.DarkFrameCapDelay())
+	}
+
+	path = writeConfig(t, validYAML+"dark_frames:\n  count: 10\n  mode: shutter\n  cap_delay_ms: 5000\n")
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.DarkFrameCapDelay() != 0 {
+		t.Errorf("DarkFrameCapDelay() in shutter mode = %v, want 0", cfg.DarkFrameCapDelay())
+	}
+}
+
+func TestLoad_DarkFramesInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+	}{
+		{"negative_count", "dark_frames:\n  count: -1\n"},
+		{"too_many", "dark_frames:\n  count: 1001\n"},
+		{"bad_mode", "dark_frames:\n  count: 1\n  mode: flash\n"},
+		{"negative_delay", "dark_frames:\n  count: 1\n  cap_delay_ms: -5\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, validYAML+tc.yaml)
+			if _, err := Load(path); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoad_Panoramas(t *testing.T) {
+	yaml := validYAML + `panoramas:
+  - name: "north"
+    horizontal_angle_deg: 90
+    pan_center_deg: -45
+  - name: "south"
+    tilt_center_deg: 10
+`
+	path := writeConfig(t, yaml)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Panoramas) != 2 {
+		t.Fatalf("panoramas = %d, want 2", len(cfg.Panoramas))
+	}
+	if cfg.Panoramas[0].PanCenterDeg != -45 || cfg.Panoramas[1].TiltCenterDeg != 10 {
+		t.Errorf("panoramas = %+v", cfg.Panoramas)
+	}
+}
+
+func TestLoad_PanoramasInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+	}{
+		{"horizontal_too_large", "panoramas:\n  - horizontal_angle_deg: 361\n"},
+		{"vertical_negative", "panoramas:\n  - vertical_angle_deg: -1\n"},
+		{"pan_center_out_of_range", "panoramas:\n  - pan_center_deg: 181\n"},
+		{"tilt_center_out_of_range", "panoramas:\n  - tilt_center_deg: -91\n"},
+		{"too_many", "panoramas:\n" + strings.Repeat("  - name: x\n", MaxPanoramas+1)},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, validYAML+tc.yaml)
+			if _, err := Load(path); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
 }
 
 func TestLoad_FileTooLarge(t *testing.T) {
@@ -469,6 +738,26 @@ func TestConfig_PostShotDelay(t *testing.T) {
 	}
 }
 
+func TestConfig_CaptureDelays(t *testing.T) {
+	cfg := &Config{Defaults: DefaultsConfig{InterMoveDelayMs: 500, StabilizationDelayMs: 300}}
+	if got := cfg.InterMoveDelay(); got != 500*time.Millisecond {
+		t.Errorf("InterMoveDelay() = %v, want 500ms", got)
+	}
+	if got := cfg.StabilizationDelay(); got != 300*time.Millisecond {
+		t.Errorf("StabilizationDelay() = %v, want 300ms", got)
+	}
+}
+
+func TestConfig_ExposureDurations(t *testing.T) {
+	cfg := &Config{Camera: CameraConfig{ExposureTimeMs: 30000, ExposureMarginMs: 250}}
+	if got := cfg.ExposureTime(); got != 30*time.Second {
+		t.Errorf("ExposureTime() = %v, want 30s", got)
+	}
+	if got := cfg.ExposureMargin(); got != 250*time.Millisecond {
+		t.Errorf("ExposureMargin() = %v, want 250ms", got)
+	}
+}
+
 func TestConfig_OverlapPercent(t *testing.T) {
 	cfg := &Config{Defaults: DefaultsConfig{OverlapPercent: 42.5}}
 	if got := cfg.OverlapPercent(); got != 42.5 {
@@ -493,3 +782,911 @@ func TestConfig_AngleAccessors(t *testing.T) {
 func formatFloat(f float64) string {
 	return fmt.Sprintf("%g", f)
 }
+
+func TestLoad_Trigger(t *testing.T) {
+	path := writeConfig(t, validYAML+"trigger:\n  pin: 12\n  active_low: true\n  timeout_ms: 60000\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Trigger.ActiveLow {
+		t.Error("trigger.active_low should be true")
+	}
+	if cfg.TriggerDebounce() != 20*time.Millisecond {
+		t.Errorf("TriggerDebounce() = %v, want 20ms default", cfg.TriggerDebounce())
+	}
+	if cfg.TriggerTimeout() != time.Minute {
+		t.Errorf("TriggerTimeout() = %v, want 1m", cfg.TriggerTimeout())
+	}
+
+	// Disabled by default
+	cfg, err = Load(writeConfig(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Trigger.Pin != 0 || cfg.TriggerDebounce() != 0 {
+		t.Errorf("trigger should be disabled by default, got %+v", cfg.Trigger)
+	}
+}
+
+func TestLoad_TriggerInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+	}{
+		{"pin_out_of_range", "trigger:\n  pin: 40\n"},
+		{"negative_pin", "trigger:\n  pin: -1\n"},
+		{"debounce_too_long", "trigger:\n  pin: 12\n  debounce_ms: 5000\n"},
+		{"negative_timeout", "trigger:\n  pin: 12\n  timeout_ms: -1\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, validYAML+tc.yaml)
+			if _, err := Load(path); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoad_GPS(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML+"gps:\n  gpsd: localhost:2947\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.GPS.GPSD != "localhost:2947" || cfg.GPSMaxAge() != 10*time.Second {
+		t.Errorf("gps = %+v, want gpsd with a 10s max age by default", cfg.GPS)
+	}
+
+	cases := []struct {
+		name string
+		yaml string
+	}{
+		{"no_port", "gps:\n  gpsd: localhost\n"},
+		{"no_host", "gps:\n  gpsd: \":2947\"\n"},
+		{"both", "gps:\n  gpsd: localhost:2947\n  device: /dev/serial0\n"},
+		{"negative_age", "gps:\n  device: /dev/serial0\n  max_age_ms: -1\n"},
+		{"age_too_long", "gps:\n  device: /dev/serial0\n  max_age_ms: 7200000\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load(writeConfig(t, validYAML+tc.yaml)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoad_Gamepad(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML+"gamepad:\n  device: /dev/input/event0\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := GamepadConfig{Device: "/dev/input/event0", PanAxis: "x", TiltAxis: "y", DeadZone: 0.15, Curve: 2, SlowSpeedMs: 20, ShootButton: "south"}
+	if cfg.Gamepad != want {
+		t.Errorf("gamepad = %+v, want %+v", cfg.Gamepad, want)
+	}
+
+	cases := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"right_stick", "gamepad:\n  pan_axis: rx\n  tilt_axis: ry\n  shoot_button: none\n", false},
+		{"unknown_axis", "gamepad:\n  pan_axis: w\n", true},
+		{"same_axes", "gamepad:\n  tilt_axis: x\n", true},
+		{"unknown_button", "gamepad:\n  shoot_button: a\n", true},
+		{"dead_zone_too_large", "gamepad:\n  dead_zone: 0.95\n", true},
+		{"curve_too_small", "gamepad:\n  curve: 0.5\n", true},
+		{"slow_too_slow", "gamepad:\n  slow_speed_ms: 1001\n", true},
+		{"fast_slower_than_slow", "gamepad:\n  slow_speed_ms: 10\n  fast_speed_ms: 11\n", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load(writeConfig(t, validYAML+tc.yaml)); (err != nil) != tc.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_Panel(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML+"panel:\n  pin_a: 16\n  pin_b: 20\n  button_pin: 21\n  button_active_low: true\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := PanelConfig{PinA: 16, PinB: 20, ButtonPin: 21, ButtonActiveLow: true, StepsPerDetent: 4, LongPressMs: 1000}
+	if cfg.Panel != want {
+		t.Errorf("panel = %+v, want %+v", cfg.Panel, want)
+	}
+
+	cases := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"no_button", "panel:\n  pin_a: 16\n  pin_b: 20\n  steps_per_detent: 2\n", false},
+		{"pin_b_missing", "panel:\n  pin_a: 16\n", true},
+		{"button_only", "panel:\n  button_pin: 21\n", true},
+		{"same_pins", "panel:\n  pin_a: 16\n  pin_b: 16\n", true},
+		{"button_on_encoder", "panel:\n  pin_a: 16\n  pin_b: 20\n  button_pin: 20\n", true},
+		{"pin_out_of_range", "panel:\n  pin_a: 16\n  pin_b: 40\n", true},
+		{"steps_per_detent", "panel:\n  pin_a: 16\n  pin_b: 20\n  steps_per_detent: 3\n", true},
+		{"long_press_too_short", "panel:\n  pin_a: 16\n  pin_b: 20\n  long_press_ms: 50\n", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load(writeConfig(t, validYAML+tc.yaml)); (err != nil) != tc.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_Display(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML+"display:\n  bus: /dev/i2c-1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := DisplayConfig{Bus: "/dev/i2c-1", Address: 0x3c, Height: 64, RefreshMs: 500}
+	if cfg.Display != want {
+		t.Errorf("display = %+v, want %+v", cfg.Display, want)
+	}
+
+	cases := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"small", "display:\n  bus: /dev/i2c-1\n  address: 0x3d\n  height: 32\n  rotate: true\n", false},
+		{"address_too_large", "display:\n  bus: /dev/i2c-1\n  address: 0x78\n", true},
+		{"height", "display:\n  bus: /dev/i2c-1\n  height: 48\n", true},
+		{"refresh_too_fast", "display:\n  bus: /dev/i2c-1\n  refresh_ms: 10\n", true},
+		{"no_bus", "display:\n  height: 48\n", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load(writeConfig(t, validYAML+tc.yaml)); (err != nil) != tc.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_Log(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML+"log:\n  file: /var/log/pango/pango.log\n  max_age_days: 30\n  events_file: /var/log/pango/events.jsonl\n  levels:\n    gpio: trace\n    web: warn\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Log.File != "/var/log/pango/pango.log" || cfg.Log.EventsFile != "/var/log/pango/events.jsonl" || cfg.Log.MaxBackups != 5 {
+		t.Errorf("log = %+v, want the files with 5 backups by default", cfg.Log)
+	}
+	if cfg.Log.Levels["gpio"] != "trace" || cfg.Log.Levels["web"] != "warn" {
+		t.Errorf("log levels = %v", cfg.Log.Levels)
+	}
+	if cfg.LogMaxSize() != 10<<20 || cfg.LogMaxAge() != 30*24*time.Hour {
+		t.Errorf("LogMaxSize() = %d, LogMaxAge() = %v, want 10 MiB and 30 days", cfg.LogMaxSize(), cfg.LogMaxAge())
+	}
+
+	cases := []struct {
+		name string
+		yaml string
+	}{
+		{"negative_size", "log:\n  max_size_mb: -1\n"},
+		{"size_too_large", "log:\n  max_size_mb: 2048\n"},
+		{"negative_backups", "log:\n  max_backups: -1\n"},
+		{"negative_age", "log:\n  max_age_days: -1\n"},
+		{"unknown_module", "log:\n  levels:\n    motor: trace\n"},
+		{"unknown_level", "log:\n  levels:\n    gpio: loud\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load(writeConfig(t, validYAML+tc.yaml)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoad_Tracing(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML+"tracing:\n  endpoint: http://collector.local:4318\n  headers:\n    X-Api-Key: secret\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Tracing.Endpoint != "http://collector.local:4318" || cfg.Tracing.Headers["X-Api-Key"] != "secret" {
+		t.Errorf("tracing = %+v", cfg.Tracing)
+	}
+
+	cases := []struct {
+		name string
+		yaml string
+	}{
+		{"no_scheme", "tracing:\n  endpoint: collector.local:4318\n"},
+		{"grpc_scheme", "tracing:\n  endpoint: grpc://collector.local:4317\n"},
+		{"no_host", "tracing:\n  endpoint: http://\n"},
+		{"bad_header", "tracing:\n  endpoint: http://collector.local:4318\n  headers:\n    \"X Key\": secret\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Load(writeConfig(t, validYAML+tc.yaml)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoad_SunTracking(t *testing.T) {
+	yaml := validYAML + "  mode: timelapse\ntimelapse:\n  frames: 10\n  sun_tracking:\n    latitude_deg: 48.85\n    longitude_deg: 2.35\n    reference_azimuth_deg: 180\n    elevation_offset_deg: -2\n"
+	cfg, err := Load(writeConfig(t, yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	st := cfg.Timelapse.SunTracking
+	if st == nil {
+		t.Fatal("sun_tracking should be set")
+	}
+	if st.ReferenceAzimuthDeg != 180 || st.ElevationOffsetDeg != -2 {
+		t.Errorf("sun_tracking = %+v", st)
+	}
+}
+
+func TestLoad_SunTrackingInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		yaml string
+	}{
+		{"latitude", "    latitude_deg: 91\n"},
+		{"longitude", "    longitude_deg: -181\n"},
+		{"reference_azimuth", "    reference_azimuth_deg: 360\n"},
+		{"azimuth_offset", "    azimuth_offset_deg: 200\n"},
+		{"elevation_offset", "    elevation_offset_deg: -95\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			yaml := validYAML + "  mode: timelapse\ntimelapse:\n  frames: 10\n  sun_tracking:\n" + tc.yaml
+			if _, err := Load(writeConfig(t, yaml)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoad_WebAuth(t *testing.T) {
+	path := writeConfig(t, validYAML+"web:\n  auth:\n    token: \"0123456789abcdef\"\n    username: admin\n    password: secret\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Web.Auth.Enabled() || cfg.Web.Auth.Username != "admin" {
+		t.Errorf("web.auth = %+v", cfg.Web.Auth)
+	}
+
+	path = writeConfig(t, validYAML)
+	cfg, err = Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Web.Auth.Enabled() {
+		t.Error("auth should be disabled by default")
+	}
+}
+
+func TestLoad_WebAuthInvalid(t *testing.T) {
+	cases := []struct {
+		name string
+		auth string
+	}{
+		{"short_token", "    token: short\n"},
+		{"username_without_password", "    username: admin\n"},
+		{"password_without_username", "    password: secret\n"},
+		{"colon_in_username", "    username: \"a:b\"\n    password: secret\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, validYAML+"web:\n  auth:\n"+tc.auth)
+			if _, err := Load(path); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestLoad_WebTLS(t *testing.T) {
+	path := writeConfig(t, validYAML+"web:\n  tls:\n    self_signed: true\n")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Web.TLS.Enabled() {
+		t.Error("tls should be enabled with self_signed")
+	}
+
+	path = writeConfig(t, validYAML+"web:\n  tls:\n    cert_file: cert.pem\n")
+	if _, err := Load(path); err == nil {
+		t.Error("expected error for cert_file without key_file")
+	}
+}
+
+func TestLoad_WebMDNS(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML+"web:\n  mdns:\n    enabled: true\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Web.MDNS.Enabled || cfg.Web.MDNS.Name != "pango" {
+		t.Errorf("mdns = %+v, want enabled with default name", cfg.Web.MDNS)
+	}
+
+	cases := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"hyphenated", "pano-rig-2", false},
+		{"dotted", "pango.local", true},
+		{"leading_hyphen", "-pango", true},
+		{"space", "my rig", true},
+		{"too_long", strings.Repeat("a", 64), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, validYAML+"web:\n  mdns:\n    name: \""+tc.value+"\"\n"))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_WebCORS(t *testing.T) {
+	cases := []struct {
+		name    string
+		origin  string
+		wantErr bool
+	}{
+		{"any", "*", false},
+		{"dev_server", "http://localhost:5173", false},
+		{"https", "https://pano.example.org", false},
+		{"trailing_slash", "http://localhost:5173/", true},
+		{"path", "https://pano.example.org/app", true},
+		{"no_scheme", "localhost:5173", true},
+		{"file", "file:///tmp", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, validYAML+"web:\n  cors:\n    allowed_origins: [\""+tc.origin+"\"]\n"))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && cfg.Web.CORS.AllowedOrigins[0] != tc.origin {
+				t.Errorf("origins = %v", cfg.Web.CORS.AllowedOrigins)
+			}
+		})
+	}
+}
+
+func TestLoad_WebLimits(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.CaptureSpacing(); got != 5*time.Second {
+		t.Errorf("default CaptureSpacing = %v, want 5s", got)
+	}
+
+	cases := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"valid", "web:\n  limits:\n    capture_spacing_ms: 1500\n    requests_per_minute:\n      \"POST /run\": 6\n", false},
+		{"negative_spacing", "web:\n  limits:\n    capture_spacing_ms: -1\n", true},
+		{"spacing_too_long", "web:\n  limits:\n    capture_spacing_ms: 3600001\n", true},
+		{"negative_count", "web:\n  limits:\n    requests_per_minute:\n      \"POST /run\": -1\n", true},
+		{"no_method", "web:\n  limits:\n    requests_per_minute:\n      \"/run\": 6\n", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, validYAML+tc.yaml))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.name == "valid" && (cfg.CaptureSpacing() != 1500*time.Millisecond || cfg.Web.Limits.RequestsPerMinute["POST /run"] != 6) {
+				t.Errorf("limits = %+v", cfg.Web.Limits)
+			}
+		})
+	}
+}
+
+func TestLoad_WebWebhooks(t *testing.T) {
+	cases := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"valid", "web:\n  webhooks:\n    - url: https://example.com/hook\n      events: [done, failed]\n      secret: s3cret\n", false},
+		{"all_events", "web:\n  webhooks:\n    - url: http://192.168.1.10:5678/webhook/pango\n", false},
+		{"no_scheme", "web:\n  webhooks:\n    - url: example.com/hook\n", true},
+		{"ftp", "web:\n  webhooks:\n    - url: ftp://example.com/hook\n", true},
+		{"unknown_event", "web:\n  webhooks:\n    - url: https://example.com/hook\n      events: [finished]\n", true},
+		{"too_many", "web:\n  webhooks:\n" + strings.Repeat("    - url: https://example.com/hook\n", MaxWebhooks+1), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, validYAML+tc.yaml))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.name == "valid" {
+				want := WebhookConfig{URL: "https://example.com/hook", Events: []string{"done", "failed"}, Secret: "s3cret"}
+				if len(cfg.Web.Webhooks) != 1 || !reflect.DeepEqual(cfg.Web.Webhooks[0], want) {
+					t.Errorf("webhooks = %+v", cfg.Web.Webhooks)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_WebMultiRig(t *testing.T) {
+	follower := "web:\n  multi_rig:\n    followers:\n      - url: http://pango-right.local:8080\n"
+	cases := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"valid", "web:\n  multi_rig:\n    followers:\n      - url: http://pango-right.local:8080\n        token: 0123456789abcdef\n    ready_timeout_ms: 30000\n", false},
+		{"no_scheme", "web:\n  multi_rig:\n    followers:\n      - url: pango-right.local:8080\n", true},
+		{"negative_timeout", "web:\n  multi_rig:\n    ready_timeout_ms: -1\n", true},
+		{"too_many", "web:\n  multi_rig:\n    followers:\n" + strings.Repeat("      - url: http://pango.local\n", MaxFollowers+1), true},
+		{"timelapse", "  mode: timelapse\ntimelapse:\n  frames: 10\n  interval_ms: 1000\n" + follower, true},
+		{"panoramas", "panoramas:\n  - horizontal_angle_deg: 90\n    vertical_angle_deg: 30\n" + follower, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, validYAML+tc.yaml))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.name == "valid" {
+				want := FollowerConfig{URL: "http://pango-right.local:8080", Token: "0123456789abcdef"}
+				if len(cfg.Web.MultiRig.Followers) != 1 || cfg.Web.MultiRig.Followers[0] != want {
+					t.Errorf("followers = %+v", cfg.Web.MultiRig.Followers)
+				}
+				if got := cfg.FollowerReadyTimeout(); got != 30*time.Second {
+					t.Errorf("FollowerReadyTimeout = %v, want 30s", got)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_WebMQTT(t *testing.T) {
+	cases := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{"disabled", "", false},
+		{"tcp", "web:\n  mqtt:\n    broker: tcp://192.168.1.10\n", false},
+		{"tls_credentials", "web:\n  mqtt:\n    broker: mqtts://broker.lan:8883\n    username: rig\n    password: secret\n    topic_prefix: studio/pango\n", false},
+		{"no_scheme", "web:\n  mqtt:\n    broker: broker.lan:1883\n", true},
+		{"websocket", "web:\n  mqtt:\n    broker: ws://broker.lan\n", true},
+		{"long_client_id", "web:\n  mqtt:\n    broker: tcp://broker.lan\n    client_id: " + strings.Repeat("x", MaxMQTTClientID+1) + "\n", true},
+		{"wildcard_prefix", "web:\n  mqtt:\n    broker: tcp://broker.lan\n    topic_prefix: pango/#\n", true},
+		{"trailing_slash", "web:\n  mqtt:\n    broker: tcp://broker.lan\n    topic_prefix: pango/\n", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, validYAML+tc.yaml))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if tc.name == "tcp" && (cfg.Web.MQTT.ClientID != "pango" || cfg.Web.MQTT.TopicPrefix != "pango") {
+				t.Errorf("defaults = %+v, want client_id and topic_prefix pango", cfg.Web.MQTT)
+			}
+		})
+	}
+}
+
+func TestLoad_WebGRPCPort(t *testing.T) {
+	cases := []struct {
+		yaml    string
+		wantErr bool
+	}{
+		{"", false},
+		{"web:\n  grpc_port: 50051\n", false},
+		{"web:\n  grpc_port: -1\n", true},
+		{"web:\n  grpc_port: 70000\n", true},
+	}
+	for _, tc := range cases {
+		if _, err := Load(writeConfig(t, validYAML+tc.yaml)); (err != nil) != tc.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tc.yaml, err, tc.wantErr)
+		}
+	}
+}
+
+func TestLoad_WebINDIPort(t *testing.T) {
+	cases := []struct {
+		yaml    string
+		wantErr bool
+	}{
+		{"web:\n  indi_port: 7624\n", false},
+		{"web:\n  indi_port: -1\n", true},
+		{"web:\n  indi_port: 65536\n", true},
+		{"web:\n  indi_port: 7624\n  indi_address: 0.0.0.0\n", false},
+		{"web:\n  indi_port: 7624\n  indi_address: pi.local\n", true},
+	}
+	for _, tc := range cases {
+		if _, err := Load(writeConfig(t, validYAML+tc.yaml)); (err != nil) != tc.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tc.yaml, err, tc.wantErr)
+		}
+	}
+
+	cfg, err := Load(writeConfig(t, validYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Web.INDIAddress != "127.0.0.1" {
+		t.Errorf("indi_address = %q, want 127.0.0.1 by default", cfg.Web.INDIAddress)
+	}
+}
+
+func TestLoad_WebUnixSocket(t *testing.T) {
+	cases := []struct {
+		yaml    string
+		wantErr bool
+	}{
+		{"web:\n  unix_socket: /run/pango/pango.sock\n", false},
+		{"web:\n  unix_socket: /run/pango/pango.sock\n  unix_socket_only: true\n", false},
+		{"web:\n  unix_socket_only: true\n", true},
+		{"web:\n  unix_socket: /" + strings.Repeat("s", MaxUnixSocketPath) + "\n", true},
+	}
+	for _, tc := range cases {
+		if _, err := Load(writeConfig(t, validYAML+tc.yaml)); (err != nil) != tc.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tc.yaml, err, tc.wantErr)
+		}
+	}
+}
+
+func TestLoad_WebPprof(t *testing.T) {
+	cases := []struct {
+		yaml    string
+		wantErr bool
+	}{
+		{"web:\n  pprof: true\n  auth:\n    token: 0123456789abcdef\n", false},
+		{"web:\n  pprof: true\n", true},
+	}
+	for _, tc := range cases {
+		if _, err := Load(writeConfig(t, validYAML+tc.yaml)); (err != nil) != tc.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tc.yaml, err, tc.wantErr)
+		}
+	}
+}
+
+func TestLoad_WebTimeouts(t *testing.T) {
+	cfg, err := Load(writeConfig(t, validYAML+"web:\n  timeouts:\n    read_header_ms: 2000\n    write_ms: 30000\n"))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := cfg.Web.Timeouts; got != (TimeoutsConfig{ReadHeaderMs: 2000, WriteMs: 30000}) {
+		t.Errorf("timeouts = %+v", got)
+	}
+	for _, yaml := range []string{
+		"web:\n  timeouts:\n    idle_ms: -1\n",
+		"web:\n  timeouts:\n    shutdown_ms: 7200000\n",
+	} {
+		if _, err := Load(writeConfig(t, validYAML+yaml)); err == nil {
+			t.Errorf("%q: expected an error", yaml)
+		}
+	}
+}
+
+func TestLoad_WebControlNetworks(t *testing.T) {
+	cases := []struct {
+		yaml    string
+		wantErr bool
+	}{
+		{"web:\n  control_networks: [192.168.1.0/24, 10.0.0.5, 'fd00::/8', local]\n", false},
+		{"web:\n  control_networks: [192.168.1.0/33]\n", true},
+		{"web:\n  control_networks: [lan]\n", true},
+	}
+	for _, tc := range cases {
+		if _, err := Load(writeConfig(t, validYAML+tc.yaml)); (err != nil) != tc.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tc.yaml, err, tc.wantErr)
+		}
+	}
+}
+
+func TestParseNetwork(t *testing.T) {
+	cases := map[string]string{
+		"192.168.1.0/24": "192.168.1.0/24",
+		"192.168.1.7/24": "192.168.1.0/24",
+		"10.0.0.5":       "10.0.0.5/32",
+		"fd00::1":        "fd00::1/128",
+	}
+	for in, want := range cases {
+		if p, err := ParseNetwork(in); err != nil || p.String() != want {
+			t.Errorf("ParseNetwork(%q) = %v, %v; want %s", in, p, err, want)
+		}
+	}
+}
+
+func TestLoad_WebBasePath(t *testing.T) {
+	cases := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr bool
+	}{
+		{"root", "", "", false},
+		{"prefix", "web:\n  base_path: /pango\n", "/pango", false},
+		{"trailing_slash", "web:\n  base_path: /tools/pango/\n", "/tools/pango", false},
+		{"slash_only", "web:\n  base_path: /\n", "", false},
+		{"relative", "web:\n  base_path: pango\n", "", true},
+		{"double_slash", "web:\n  base_path: //pango\n", "", true},
+		{"query", "web:\n  base_path: /pango?x=1\n", "", true},
+		{"quote", "web:\n  base_path: '/pa\"ngo'\n", "", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Load(writeConfig(t, validYAML+tc.yaml))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if err == nil && cfg.Web.BasePath != tc.want {
+				t.Errorf("base_path = %q, want %q", cfg.Web.BasePath, tc.want)
+			}
+		})
+	}
+}
+
+// ---------- Parse / Save / RestartRequired ----------
+
+func TestParse_JSON(t *testing.T) {
+	doc := `{"camera":{"type":"nikon_d90_gpio","focus_pin":24,"shutter_pin":25},"lens":{"focal_length_mm":50}}`
+	cfg, err := Parse([]byte(doc))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Lens.FocalLengthMm != 50 {
+		t.Errorf("focal length = %v, want 50", cfg.Lens.FocalLengthMm)
+	}
+	if cfg.Camera.FocusDelayMs != 500 || cfg.PanStepper.StepsPerRev != 200 {
+		t.Error("Parse should apply the same defaults as Load")
+	}
+}
+
+func TestLoad_JSONFile(t *testing.T) {
+	dir := filepath.Dir(writeConfig(t, validYAML))
+	path := filepath.Join(dir, "generated.json")
+	doc := "{\n\t\"camera\": {\"type\": \"nikon_d90_gpio\", \"focus_pin\": 24, \"shutter_pin\": 25},\n\t\"lens\": {\"focal_length_mm\": 50}\n}\n"
+	if err := os.WriteFile(path, []byte(doc), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Lens.FocalLengthMm != 50 {
+		t.Errorf("focal length = %v, want 50", cfg.Lens.FocalLengthMm)
+	}
+}
+
+func TestSave_JSON(t *testing.T) {
+	dir := filepath.Dir(writeConfig(t, validYAML))
+	path := filepath.Join(dir, "generated.json")
+	cfg, err := Parse([]byte(validYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Lens.FocalLengthMm = 85
+	if err := Save(path, cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("saved file is not JSON: %v\n%s", err, data)
+	}
+	if lens, _ := doc["lens"].(map[string]any); lens["focal_length_mm"] != 85.0 {
+		t.Errorf("lens = %v, want the YAML keys", doc["lens"])
+	}
+	if doc["version"] != float64(CurrentVersion()) {
+		t.Errorf("version = %v, want %d", doc["version"], CurrentVersion())
+	}
+	if back, err := Load(path); err != nil || back.Lens.FocalLengthMm != 85 {
+		t.Errorf("reload: %v", err)
+	}
+}
+
+func TestParse_SameRulesAsLoad(t *testing.T) {
+	if _, err := Parse([]byte(validYAML + "  overlap_percent: 150\n")); err == nil {
+		t.Error("expected validation error")
+	}
+	if _, err := Parse(make([]byte, MaxConfigFileBytes+1)); err == nil {
+		t.Error("expected error for an oversized document")
+	}
+}
+
+func TestSave_RoundTripAndBackup(t *testing.T) {
+	path := writeConfig(t, validYAML)
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	cfg.Lens.FocalLengthMm = 85
+	cfg.Camera.PostShotDelayMs = 900
+
+	if err := Save(path, cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	saved, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load saved: %v", err)
+	}
+	if saved.Lens.FocalLengthMm != 85 || saved.Camera.PostShotDelayMs != 900 {
+		t.Errorf("saved config = %+v %+v", saved.Lens, saved.Camera)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o640 {
+		t.Errorf("file mode not preserved: %v %v", info.Mode(), err)
+	}
+
+	backup, err := os.ReadFile(path + ".bak")
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if string(backup) != validYAML {
+		t.Error("backup should hold the previous file content")
+	}
+}
+
+func TestSave_RejectsPathOutsideConfigs(t *testing.T) {
+	cfg, err := Parse([]byte(validYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(filepath.Join(t.TempDir(), "pango.yaml"), cfg); err == nil {
+		t.Error("expected error for a path outside configs/")
+	}
+}
+
+func TestRestartRequiredAndHardwareChanged(t *testing.T) {
+	base, err := Parse([]byte(validYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name         string
+		change       func(c *Config)
+		wantHardware bool
+		wantRestart  bool
+	}{
+		{"unchanged", func(c *Config) {}, false, false},
+		{"lens", func(c *Config) { c.Lens.FocalLengthMm = 85 }, false, false},
+		{"post_shot_delay", func(c *Config) { c.Camera.PostShotDelayMs = 1000 }, false, false},
+		{"overlap", func(c *Config) { c.Defaults.OverlapPercent = 40 }, false, false},
+		{"stepper_pin", func(c *Config) { c.PanStepper.StepPin = 12 }, true, false},
+		{"shutter_pin", func(c *Config) { c.Camera.ShutterPin = 16 }, true, false},
+		{"focus_delay", func(c *Config) { c.Camera.FocusDelayMs = 800 }, true, false},
+		{"move_speed", func(c *Config) { c.Defaults.MoveSpeedMs = 4 }, true, false},
+		{"trigger", func(c *Config) { c.Trigger.Pin = 4 }, true, false},
+		{"mock_gpio", func(c *Config) { c.Defaults.MockGPIO = false }, false, true},
+		{"log_file", func(c *Config) { c.Log.File = "pango.log" }, false, true},
+		{"tracing", func(c *Config) { c.Tracing.Endpoint = "http://collector.local:4318" }, false, true},
+		{"auth", func(c *Config) { c.Web.Auth.Token = "0123456789abcdef" }, false, true},
+		{"cors", func(c *Config) { c.Web.CORS.AllowedOrigins = []string{"*"} }, false, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := *base
+			tc.change(&next)
+			if got := HardwareChanged(base, &next); got != tc.wantHardware {
+				t.Errorf("HardwareChanged = %v, want %v", got, tc.wantHardware)
+			}
+			if got := RestartRequired(base, &next); got != tc.wantRestart {
+				t.Errorf("RestartRequired = %v, want %v", got, tc.wantRestart)
+			}
+		})
+	}
+}
+
+// ---------- Profiles ----------
+
+func TestProfiles(t *testing.T) {
+	dir := filepath.Dir(writeConfig(t, validYAML)) // configs/test.yaml
+	for _, name := range []string{"wide-18mm.yaml", "tele-200mm.yaml", "test.yaml.bak", ".config-123.yaml", "notes.txt", "generated.json", "test.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(validYAML), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "old.yaml"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := Profiles(dir)
+	if err != nil {
+		t.Fatalf("Profiles: %v", err)
+	}
+	want := []string{"generated", "tele-200mm", "test", "wide-18mm"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("Profiles = %v, want %v", names, want)
+	}
+	if path, _ := ProfilePath(dir, "test"); filepath.Ext(path) != ".yaml" {
+		t.Errorf("ProfilePath(test) = %q, want the .yaml file first", path)
+	}
+	if path, _ := ProfilePath(dir, "generated"); ProfileName(path) != "generated" || filepath.Ext(path) != ".json" {
+		t.Errorf("ProfilePath(generated) = %q", path)
+	}
+
+	path, err := ProfilePath(dir, "wide-18mm")
+	if err != nil || path != filepath.Join(dir, "wide-18mm.yaml") {
+		t.Errorf("ProfilePath = %q, %v", path, err)
+	}
+	if got := ProfileName(path); got != "wide-18mm" {
+		t.Errorf("ProfileName = %q", got)
+	}
+	for _, name := range []string{"", "missing", "../configs/test", ".config-123", "old"} {
+		if _, err := ProfilePath(dir, name); !errors.Is(err, ErrProfileNotFound) {
+			t.Errorf("ProfilePath(%q) err = %v, want ErrProfileNotFound", name, err)
+		}
+	}
+}
+
+func TestSaveProfile(t *testing.T) {
+	dir := filepath.Dir(writeConfig(t, validYAML)) // configs/test.yaml
+	doc := "# uploaded from the browser\n" + validYAML
+
+	path, err := SaveProfile(dir, "wide-18mm", []byte(doc), false)
+	if err != nil {
+		t.Fatalf("SaveProfile: %v", err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != doc {
+		t.Errorf("stored document = %q, want it unchanged (comments kept)", got)
+	}
+	if _, err := ProfilePath(dir, "wide-18mm"); err != nil {
+		t.Errorf("uploaded profile not found: %v", err)
+	}
+
+	if _, err := SaveProfile(dir, "test", []byte(validYAML), false); !errors.Is(err, ErrProfileExists) {
+		t.Errorf("existing profile: err = %v, want ErrProfileExists", err)
+	}
+	if _, err := SaveProfile(dir, "test", []byte(doc), true); err != nil {
+		t.Errorf("replace: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test.yaml.bak")); err != nil {
+		t.Errorf("replaced profile not backed up: %v", err)
+	}
+
+	// JSON documents are stored as .json, and .json profiles stay JSON
+	jsonDoc := `{"camera":{"type":"nikon_d90_gpio","focus_pin":24,"shutter_pin":25},"lens":{"focal_length_mm":50}}`
+	if path, err := SaveProfile(dir, "generated", []byte(jsonDoc), false); err != nil || filepath.Ext(path) != ".json" {
+		t.Errorf("JSON profile stored in %q, %v", path, err)
+	}
+	path, err = SaveProfile(dir, "generated", []byte(validYAML), true)
+	if err != nil || filepath.Ext(path) != ".json" {
+		t.Fatalf("replaced JSON profile stored in %q, %v", path, err)
+	}
+	if got, _ := os.ReadFile(path); !json.Valid(got) {
+		t.Errorf("YAML replacing a JSON profile not converted:\n%s", got)
+	}
+
+	for name, data := range map[string]string{
+		"../escape": validYAML,
+		".hidden":   validYAML,
+		"":          validYAML,
+		"broken":    "camera: [",
+		"invalid":   validYAML + "defaults:\n  debug_level: 9\n",
+	} {
+		if _, err := SaveProfile(dir, name, []byte(data), true); !errors.Is(err, ErrInvalidProfile) {
+			t.Errorf("SaveProfile(%q) err = %v, want ErrInvalidProfile", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "broken.yaml")); !os.IsNotExist(err) {
+		t.Error("invalid document must not be stored")
+	}
+}
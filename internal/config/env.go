@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the environment variables overriding config keys: the
+// key path in upper case, sections joined with "_", e.g.
+// PANGO_DEFAULTS_MOCK_GPIO for defaults.mock_gpio or PANGO_WEB_AUTH_TOKEN
+// for web.auth.token. Lists of values are comma-separated. Lists of
+// sections (panoramas, webhooks) and maps cannot be set this way.
+const EnvPrefix = "PANGO_"
+
+// applyEnv sets the config keys named by the PANGO_* variables of environ
+// (os.Environ format). Variables naming no config key are ignored: PANGO_*
+// also holds the settings of pango remote.
+func applyEnv(cfg *Config, environ []string) error {
+	vars := map[string]string{}
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, EnvPrefix) {
+			vars[k] = v
+		}
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+	unused := maps.Clone(vars)
+	if err := setFromEnv(reflect.ValueOf(cfg).Elem(), strings.TrimSuffix(EnvPrefix, "_"), unused); err != nil {
+		return err
+	}
+	cfg.env = nil
+	for k := range vars {
+		if _, ok := unused[k]; !ok {
+			cfg.env = append(cfg.env, k)
+		}
+	}
+	sort.Strings(cfg.env)
+	return nil
+}
+
+// EnvVars returns the names of the PANGO_* variables applied to c, sorted.
+func (c *Config) EnvVars() []string {
+	return c.env
+}
+
+// envOverrides applies the environment of the process to cfg.
+func envOverrides(cfg *Config) error {
+	return applyEnv(cfg, os.Environ())
+}
+
+// setFromEnv sets the fields of the struct v whose variable, name plus
+// their YAML key, is in vars, and removes the variables it used.
+func setFromEnv(v reflect.Value, name string, vars map[string]string) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if key == "" || key == "-" || field.Tag.Get("env") == "-" {
+			continue
+		}
+		key = name + "_" + strings.ToUpper(key)
+		fv := v.Field(i)
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			if err := setFromEnv(fv, key, vars); err != nil {
+				return err
+			}
+		case field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct:
+			if !anyWithPrefix(vars, key+"_") {
+				continue
+			}
+			if fv.IsNil() {
+				fv.Set(reflect.New(field.Type.Elem()))
+			}
+			if err := setFromEnv(fv.Elem(), key, vars); err != nil {
+				return err
+			}
+		default:
+			s, ok := vars[key]
+			if !ok {
+				continue
+			}
+			if err := setEnvValue(fv, s); err != nil {
+				return fmt.Errorf("environment %s: %w", key, err)
+			}
+			delete(vars, key)
+		}
+	}
+	return nil
+}
+
+func anyWithPrefix(vars map[string]string, prefix string) bool {
+	for k := range vars {
+		if strings.HasPrefix(k, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// setEnvValue parses s into the scalar or string list v.
+func setEnvValue(v reflect.Value, s string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", s)
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", s)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("lists of sections cannot be set from the environment")
+		}
+		var list []string
+		for item := range strings.SplitSeq(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("%s values cannot be set from the environment", v.Kind())
+	}
+	return nil
+}
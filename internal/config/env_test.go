@@ -0,0 +1,83 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ---------- applyEnv ----------
+
+func TestApplyEnv(t *testing.T) {
+	var cfg Config
+	cfg.Defaults.OverlapPercent = 30
+	err := applyEnv(&cfg, []string{
+		"PANGO_DEFAULTS_MOCK_GPIO=true",
+		"PANGO_WEB_PORT=9000",
+		"PANGO_WEB_AUTH_TOKEN=0123456789abcdef",
+		"PANGO_LENS_FOCAL_LENGTH_MM=50.5",
+		"PANGO_WEB_CORS_ALLOWED_ORIGINS=https://a.example, https://b.example",
+		"PANGO_SENSOR_WIDTH_MM=36",
+		"PANGO_HOST=pango.local:8080", // pango remote, not a config key
+		"HOME=/root",
+	})
+	if err != nil {
+		t.Fatalf("applyEnv: %v", err)
+	}
+	if !cfg.Defaults.MockGPIO || cfg.Web.Port != 9000 || cfg.Web.Auth.Token != "0123456789abcdef" || cfg.Lens.FocalLengthMm != 50.5 {
+		t.Errorf("scalars not set: %+v %+v %+v", cfg.Defaults, cfg.Web.Auth, cfg.Lens)
+	}
+	if want := []string{"https://a.example", "https://b.example"}; !reflect.DeepEqual(cfg.Web.CORS.AllowedOrigins, want) {
+		t.Errorf("allowed_origins = %q, want %q", cfg.Web.CORS.AllowedOrigins, want)
+	}
+	if cfg.Sensor == nil || cfg.Sensor.WidthMm != 36 {
+		t.Errorf("sensor = %+v, want width 36", cfg.Sensor)
+	}
+	if cfg.Resolution != nil {
+		t.Error("resolution allocated without a variable")
+	}
+	if cfg.Defaults.OverlapPercent != 30 {
+		t.Error("a key without a variable changed")
+	}
+	want := []string{"PANGO_DEFAULTS_MOCK_GPIO", "PANGO_LENS_FOCAL_LENGTH_MM", "PANGO_SENSOR_WIDTH_MM", "PANGO_WEB_AUTH_TOKEN", "PANGO_WEB_CORS_ALLOWED_ORIGINS", "PANGO_WEB_PORT"}
+	if got := cfg.EnvVars(); !reflect.DeepEqual(got, want) {
+		t.Errorf("EnvVars() = %q, want %q", got, want)
+	}
+}
+
+func TestApplyEnv_Invalid(t *testing.T) {
+	cases := []struct {
+		env  string
+		want string
+	}{
+		{"PANGO_DEFAULTS_MOCK_GPIO=maybe", "PANGO_DEFAULTS_MOCK_GPIO"},
+		{"PANGO_WEB_PORT=eighty", "not an integer"},
+		{"PANGO_LENS_FOCAL_LENGTH_MM=wide", "not a number"},
+		{"PANGO_PANORAMAS=a,b", "lists of sections"},
+		{"PANGO_WEB_LIMITS_REQUESTS_PER_MINUTE=10", "map values"},
+	}
+	for _, tc := range cases {
+		var cfg Config
+		if err := applyEnv(&cfg, []string{tc.env}); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: err = %v, want %q", tc.env, err, tc.want)
+		}
+	}
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	t.Setenv("PANGO_DEFAULTS_HORIZONTAL_ANGLE_DEG", "90")
+	t.Setenv("PANGO_WEB_PORT", "9000")
+	cfg, err := Load(writeConfig(t, validYAML))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Defaults.HorizontalAngleDeg != 90 || cfg.Web.Port != 9000 {
+		t.Errorf("angle %v, port %d: environment not applied", cfg.Defaults.HorizontalAngleDeg, cfg.Web.Port)
+	}
+
+	// Values from the environment are validated like the file
+	t.Setenv("PANGO_WEB_PORT", "70000")
+	if _, err := Load(writeConfig(t, validYAML)); err == nil || !strings.Contains(err.Error(), "web port") {
+		t.Errorf("err = %v, want the port range", err)
+	}
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// toYAML converts a document read from a file with extension ext to YAML,
+// which Parse reads. YAML and JSON documents are returned as is.
+func toYAML(ext string, data []byte) ([]byte, error) {
+	if ext != ".toml" {
+		return data, nil
+	}
+	var doc map[string]any
+	if err := toml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse toml: %w", err)
+	}
+	return yaml.Marshal(doc)
+}
+
+// fromYAML converts a YAML document to the format of extension ext, keeping
+// its keys. Comments are lost, and TOML tables are written in key order.
+func fromYAML(ext string, data []byte) ([]byte, error) {
+	if ext != ".json" && ext != ".toml" {
+		return data, nil
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if ext == ".toml" {
+		var out bytes.Buffer
+		if err := toml.NewEncoder(&out).Encode(doc); err != nil {
+			return nil, fmt.Errorf("marshal toml: %w", err)
+		}
+		return out.Bytes(), nil
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal json: %w", err)
+	}
+	return append(out, '\n'), nil
+}
+
+// detectFormat validates a document of unknown format with the rules of
+// Parse. It returns the extension of its format and the document as YAML.
+// Documents that are neither YAML nor JSON are tried as TOML; the YAML
+// error is reported when that fails too.
+func detectFormat(data []byte) (ext string, doc []byte, err error) {
+	_, err = Parse(data)
+	switch {
+	case err == nil && json.Valid(data):
+		return ".json", data, nil
+	case err == nil:
+		return ".yaml", data, nil
+	}
+	converted, tomlErr := toYAML(".toml", data)
+	if tomlErr != nil {
+		return "", nil, err
+	}
+	if _, err := Parse(converted); err != nil {
+		return "", nil, err
+	}
+	return ".toml", converted, nil
+}
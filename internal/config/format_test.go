@@ -0,0 +1,134 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+const validTOML = `# rig settings
+[camera]
+type = "nikon_d90_gpio"
+focus_pin = 24
+shutter_pin = 25
+
+[lens]
+focal_length_mm = 50.0
+
+[[panoramas]]
+name = "wide"
+horizontal_angle_deg = 180.0
+vertical_angle_deg = 30.0
+`
+
+// ---------- TOML ----------
+
+func TestLoad_TOMLFile(t *testing.T) {
+	dir := filepath.Dir(writeConfig(t, validYAML))
+	path := filepath.Join(dir, "rig.toml")
+	if err := os.WriteFile(path, []byte(validTOML), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Lens.FocalLengthMm != 50 || cfg.Camera.FocusPin != 24 {
+		t.Errorf("lens = %+v, camera = %+v", cfg.Lens, cfg.Camera)
+	}
+	if len(cfg.Panoramas) != 1 || cfg.Panoramas[0].Name != "wide" {
+		t.Errorf("panoramas = %+v, want the [[panoramas]] table", cfg.Panoramas)
+	}
+	if cfg.Camera.FocusDelayMs != 500 {
+		t.Error("TOML files should get the same defaults as YAML")
+	}
+}
+
+func TestLoad_TOMLErrors(t *testing.T) {
+	dir := filepath.Dir(writeConfig(t, validYAML))
+	cases := []struct {
+		name, doc, want string
+	}{
+		{"syntax", "[lens\n", "parse toml: toml: line"},
+		{"validation", validTOML + "\n[defaults]\ndebug_level = 9\n", "debug_level"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, tc.name+".toml")
+			if err := os.WriteFile(path, []byte(tc.doc), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Load = %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestSave_TOML(t *testing.T) {
+	dir := filepath.Dir(writeConfig(t, validYAML))
+	path := filepath.Join(dir, "rig.toml")
+	cfg, err := Parse([]byte(validYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Lens.FocalLengthMm = 85
+	if err := Save(path, cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		t.Fatalf("saved file is not TOML: %v\n%s", err, data)
+	}
+	if lens, _ := doc["lens"].(map[string]any); lens["focal_length_mm"] != int64(85) {
+		t.Errorf("lens = %v, want the YAML keys", doc["lens"])
+	}
+	if back, err := Load(path); err != nil || back.Lens.FocalLengthMm != 85 {
+		t.Errorf("reload: %v", err)
+	}
+}
+
+func TestSaveProfile_TOML(t *testing.T) {
+	dir := filepath.Dir(writeConfig(t, validYAML)) // configs/test.yaml
+
+	// TOML documents are stored as is in .toml files
+	path, err := SaveProfile(dir, "rig", []byte(validTOML), false)
+	if err != nil || filepath.Ext(path) != ".toml" {
+		t.Fatalf("TOML profile stored in %q, %v", path, err)
+	}
+	if got, _ := os.ReadFile(path); string(got) != validTOML {
+		t.Errorf("stored document = %q, want it unchanged (comments kept)", got)
+	}
+	if names, _ := Profiles(dir); strings.Join(names, ",") != "rig,test" {
+		t.Errorf("Profiles = %v, want the .toml profile listed", names)
+	}
+
+	// .toml profiles stay TOML when replaced with YAML
+	path, err = SaveProfile(dir, "rig", []byte(validYAML), true)
+	if err != nil || filepath.Ext(path) != ".toml" {
+		t.Fatalf("replaced TOML profile stored in %q, %v", path, err)
+	}
+	got, _ := os.ReadFile(path)
+	var doc map[string]any
+	if _, err := toml.Decode(string(got), &doc); err != nil {
+		t.Errorf("YAML replacing a TOML profile not converted: %v\n%s", err, got)
+	}
+	if _, err := Load(path); err != nil {
+		t.Errorf("reload: %v", err)
+	}
+
+	// A TOML document failing validation reports the error
+	_, err = SaveProfile(dir, "invalid", []byte(validTOML+"\n[defaults]\ndebug_level = 9\n"), false)
+	if !errors.Is(err, ErrInvalidProfile) || !strings.Contains(err.Error(), "debug_level") {
+		t.Errorf("invalid TOML: err = %v, want the validation error", err)
+	}
+}
@@ -0,0 +1,233 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxIncludeDepth limits chains of include: (a file including a file
+// including a file...).
+const MaxIncludeDepth = 8
+
+// readConfig reads the config file path as a YAML document. A file with a
+// use: section or an include: list is merged over the library files it
+// names (see Libraries), then the files it includes, in order: a key set by
+// several files takes the value of the last one, the including file last of
+// all; sections are merged key by key, lists replaced. Each file is
+// migrated to the current layout first (see CurrentVersion). It also
+// returns the files read, in merge order, and the warnings of their
+// migration; a file merged alone is migrated by Parse.
+func readConfig(path string) ([]byte, []string, []string, error) {
+	root, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("resolve config path: %w", err)
+	}
+	data, head, err := readDocument(path)
+	if err != nil || head.empty() {
+		return data, []string{path}, nil, err
+	}
+	return mergeConfig(root, path, data, head)
+}
+
+// mergeConfig merges data, the document of the config file path, over the
+// files named by head, like readConfig.
+func mergeConfig(root, path string, data []byte, head docHead) ([]byte, []string, []string, error) {
+	doc, sources, warnings, err := mergeIncludes(root, path, data, head, []string{path})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	doc["version"] = CurrentVersion()
+	if data, err = yaml.Marshal(doc); err != nil {
+		return nil, nil, nil, fmt.Errorf("marshal yaml: %w", err)
+	}
+	return data, sources, warnings, nil
+}
+
+// docHead holds the keys of a config file naming other files.
+type docHead struct {
+	Include []string          `yaml:"include"`
+	Use     map[string]string `yaml:"use"`
+}
+
+func (h docHead) empty() bool {
+	return len(h.Include) == 0 && len(h.Use) == 0
+}
+
+// readDocument reads a config file as YAML and returns its include: list
+// and use: section. Syntax errors are left to Parse.
+func readDocument(path string) ([]byte, docHead, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, docHead{}, fmt.Errorf("read config file: %w", err)
+	}
+	if info.Size() > MaxConfigFileBytes {
+		return nil, docHead{}, fmt.Errorf("config file too large: %d bytes (max %d)", info.Size(), MaxConfigFileBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, docHead{}, fmt.Errorf("read config file: %w", err)
+	}
+	if data, err = toYAML(filepath.Ext(path), data); err != nil {
+		return nil, docHead{}, err
+	}
+	var head docHead
+	if err := yaml.Unmarshal(data, &head); err != nil {
+		return data, docHead{}, nil
+	}
+	return data, head, nil
+}
+
+// mergeIncludes returns the document data of path merged over its library
+// files and includes, the files read and the warnings of their migration.
+// chain lists the including files, for cycles.
+func mergeIncludes(root, path string, data []byte, head docHead, chain []string) (map[string]any, []string, []string, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: unmarshal yaml: %w", path, err)
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	delete(doc, "include")
+	delete(doc, "use")
+	if len(chain) > MaxIncludeDepth {
+		return nil, nil, nil, fmt.Errorf("%s: includes nested more than %d deep", path, MaxIncludeDepth)
+	}
+	warnings, err := migrateFile(doc, path, len(chain) > 1)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	merged := map[string]any{}
+	var sources, libWarnings []string
+	for kind := range head.Use {
+		if _, ok := Libraries[kind]; !ok {
+			return nil, nil, nil, fmt.Errorf("%s: use: unknown library %q (want %s)", path, kind, strings.Join(LibraryKinds, ", "))
+		}
+	}
+	for _, kind := range LibraryKinds {
+		name, ok := head.Use[kind]
+		if !ok {
+			continue
+		}
+		lib, libPath, err := readLibrary(filepath.Dir(chain[0]), kind, name)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: use %s %q: %w", path, kind, name, err)
+		}
+		w, err := migrateFile(lib, libPath, true)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		libWarnings = append(libWarnings, w...)
+		mergeDocument(merged, lib)
+		sources = append(sources, libPath)
+	}
+	for _, name := range head.Include {
+		inc, err := includePath(root, path, name)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if slices.Contains(chain, inc) {
+			return nil, nil, nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), inc)
+		}
+		incData, incHead, err := readDocument(inc)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: include %s: %w", path, name, err)
+		}
+		incDoc, incSources, incWarnings, err := mergeIncludes(root, inc, incData, incHead, append(chain, inc))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		mergeDocument(merged, incDoc)
+		sources = append(sources, incSources...)
+		libWarnings = append(libWarnings, incWarnings...)
+	}
+	mergeDocument(merged, doc)
+	return merged, append(sources, path), append(libWarnings, warnings...), nil
+}
+
+// migrateFile migrates doc, the document of the config file path, and
+// removes its version: key before it is merged. Warnings name the file
+// unless it is the one given to Load.
+func migrateFile(doc map[string]any, path string, named bool) ([]string, error) {
+	warnings, err := migrateDocument(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	delete(doc, "version")
+	if named {
+		for i, w := range warnings {
+			warnings[i] = path + ": " + w
+		}
+	}
+	return warnings, nil
+}
+
+// includePath resolves name, included by the file from, relative to the
+// directory of from. Included files must have one of Extensions and stay
+// within root, the directory of the file Load was given; they may be in
+// its sub-directories, where they are not profiles.
+func includePath(root, from, name string) (string, error) {
+	if name == "" || filepath.IsAbs(name) {
+		return "", fmt.Errorf("%s: include %q: must be a relative path", from, name)
+	}
+	if !slices.Contains(Extensions, filepath.Ext(name)) {
+		return "", fmt.Errorf("%s: include %q: must have a %s extension", from, name, strings.Join(Extensions, " or "))
+	}
+	path := filepath.Join(filepath.Dir(from), name)
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("resolve include path: %w", err)
+	}
+	if rel, err := filepath.Rel(root, abs); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s: include %q: must stay within %s", from, name, root)
+	}
+	return path, nil
+}
+
+// Library is a kind of library file: definitions shared by config files,
+// which name them in their use: section, e.g. "use: {lens: nikkor-35mm}"
+// for configs/lenses/nikkor-35mm.yaml.
+type Library struct {
+	Dir      string   // directory of the library files in configs/
+	Sections []string // sections a library file may set
+}
+
+// Libraries are the kinds of library files, by use: key.
+var Libraries = map[string]Library{
+	"rig":    {Dir: "rigs", Sections: []string{"pan_stepper", "tilt_stepper", "trigger"}},
+	"camera": {Dir: "cameras", Sections: []string{"camera", "sensor", "resolution"}},
+	"lens":   {Dir: "lenses", Sections: []string{"lens"}},
+}
+
+// LibraryKinds are the keys of Libraries in merge order.
+var LibraryKinds = []string{"rig", "camera", "lens"}
+
+// readLibrary reads the library file name of kind from dir, the configs/
+// directory, trying Extensions in order.
+func readLibrary(dir, kind, name string) (map[string]any, string, error) {
+	lib := Libraries[kind]
+	path, err := ProfilePath(filepath.Join(dir, lib.Dir), name)
+	if err != nil {
+		return nil, "", fmt.Errorf("no %s/%s file", lib.Dir, name)
+	}
+	data, _, err := readDocument(path)
+	if err != nil {
+		return nil, "", err
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, "", fmt.Errorf("%s: unmarshal yaml: %w", path, err)
+	}
+	for section := range doc {
+		if section != "version" && !slices.Contains(lib.Sections, section) {
+			return nil, "", fmt.Errorf("%s: %s cannot be set by a %s file (only %s)", path, section, kind, strings.Join(lib.Sections, ", "))
+		}
+	}
+	return doc, path, nil
+}
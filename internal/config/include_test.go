@@ -0,0 +1,236 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files (path relative to dir: content) under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// ---------- include: ----------
+
+func TestLoad_Include(t *testing.T) {
+	path := writeConfig(t, `include:
+  - base/rig.yaml
+  - lenses/tele.toml
+defaults:
+  horizontal_angle_deg: 90
+`)
+	dir := filepath.Dir(path)
+	writeFiles(t, dir, map[string]string{
+		"base/rig.yaml": validYAML,
+		"lenses/tele.toml": `include = ["../base/site.yaml"]
+
+[lens]
+name = "Nikkor 200mm"
+focal_length_mm = 200.0
+`,
+		"base/site.yaml": "lens:\n  name: Site lens\ndefaults:\n  overlap_percent: 40\n  horizontal_angle_deg: 360\n",
+	})
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Lens.FocalLengthMm != 200 || cfg.Lens.Name != "Nikkor 200mm" {
+		t.Errorf("lens = %+v, want the overlay's", cfg.Lens)
+	}
+	if cfg.Defaults.OverlapPercent != 40 || cfg.Defaults.VerticalAngleDeg != 30 {
+		t.Errorf("defaults = %+v, want keys of every file", cfg.Defaults)
+	}
+	if cfg.Defaults.HorizontalAngleDeg != 90 {
+		t.Errorf("horizontal angle = %v, want the including file's value to win", cfg.Defaults.HorizontalAngleDeg)
+	}
+	want := []string{
+		filepath.Join(dir, "base/rig.yaml"),
+		filepath.Join(dir, "lenses/../base/site.yaml"),
+		filepath.Join(dir, "lenses/tele.toml"),
+		path,
+	}
+	if strings.Join(cfg.Sources, ",") != strings.Join(want, ",") {
+		t.Errorf("Sources = %q, want %q", cfg.Sources, want)
+	}
+
+	// Fragments in sub-directories are not profiles
+	if names, _ := Profiles(dir); strings.Join(names, ",") != "test" {
+		t.Errorf("Profiles = %v", names)
+	}
+}
+
+func TestLoad_NoInclude(t *testing.T) {
+	path := writeConfig(t, validYAML)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Sources) != 1 || cfg.Sources[0] != path {
+		t.Errorf("Sources = %q, want the file alone", cfg.Sources)
+	}
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		main  string
+		files map[string]string
+		want  string
+	}{
+		{"missing", "include: [nope.yaml]\n", nil, "include nope.yaml: read config file"},
+		{"escape", "include: [../outside.yaml]\n", nil, "must stay within"},
+		{"absolute", "include: [/etc/pango.yaml]\n", nil, "must be a relative path"},
+		{"extension", "include: [notes.txt]\n", nil, "must have a .yaml or .json or .toml extension"},
+		{"cycle", "include: [a/one.yaml]\n", map[string]string{
+			"a/one.yaml": "include: [two.yaml]\n",
+			"a/two.yaml": "include: [one.yaml]\n",
+		}, "include cycle: "},
+		{"invalid_result", "include: [base.yaml]\n", map[string]string{
+			"base.yaml": strings.Replace(validYAML, "microstepping: 16", "microstepping: 3", 1),
+		}, "microstepping must be one of"},
+		{"syntax", "include: [bad.yaml]\n", map[string]string{"bad.yaml": "lens: [\n"}, "bad.yaml: unmarshal yaml"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, tc.main)
+			writeFiles(t, filepath.Dir(path), tc.files)
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Load = %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+// ---------- use: ----------
+
+const libraryRig = `pan_stepper:
+  step_pin: 12
+  dir_pin: 13
+  steps_per_rev: 200
+  microstepping: 8
+tilt_stepper:
+  step_pin: 20
+  dir_pin: 21
+  steps_per_rev: 400
+  microstepping: 8
+`
+
+func TestLoad_Use(t *testing.T) {
+	path := writeConfig(t, `use:
+  rig: a4988-hat
+  camera: nikon-d90
+  lens: nikkor-200mm
+include: [site.yaml]
+lens:
+  name: "Nikkor 200mm (rented)"
+defaults:
+  mock_gpio: true
+`)
+	dir := filepath.Dir(path)
+	writeFiles(t, dir, map[string]string{
+		"rigs/a4988-hat.yaml":      libraryRig,
+		"cameras/nikon-d90.json":   `{"camera": {"type": "nikon_d90_gpio", "focus_pin": 24, "shutter_pin": 25}, "sensor": {"width_mm": 23.6, "height_mm": 15.8}}`,
+		"lenses/nikkor-200mm.toml": "[lens]\nname = \"Nikkor 200mm\"\nfocal_length_mm = 200.0\n",
+		"site.yaml":                "defaults:\n  horizontal_angle_deg: 120\n",
+	})
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.PanStepper.StepPin != 12 || cfg.TiltStepper.StepsPerRev != 400 {
+		t.Errorf("steppers = %+v, %+v, want the rig file's", cfg.PanStepper, cfg.TiltStepper)
+	}
+	if cfg.Camera.FocusPin != 24 || cfg.Sensor == nil || cfg.Sensor.WidthMm != 23.6 {
+		t.Errorf("camera = %+v, sensor = %+v, want the camera file's", cfg.Camera, cfg.Sensor)
+	}
+	if cfg.Lens.FocalLengthMm != 200 || cfg.Lens.Name != "Nikkor 200mm (rented)" {
+		t.Errorf("lens = %+v, want the lens file's with the name of the file", cfg.Lens)
+	}
+	if cfg.Defaults.HorizontalAngleDeg != 120 {
+		t.Errorf("horizontal angle = %v, want the included 120", cfg.Defaults.HorizontalAngleDeg)
+	}
+	want := []string{
+		filepath.Join(dir, "rigs/a4988-hat.yaml"),
+		filepath.Join(dir, "cameras/nikon-d90.json"),
+		filepath.Join(dir, "lenses/nikkor-200mm.toml"),
+		filepath.Join(dir, "site.yaml"),
+		path,
+	}
+	if strings.Join(cfg.Sources, ",") != strings.Join(want, ",") {
+		t.Errorf("Sources = %q, want %q", cfg.Sources, want)
+	}
+}
+
+func TestLoad_UseErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		main  string
+		files map[string]string
+		want  string
+	}{
+		{"missing", "use: {lens: zeiss}\n", nil, `use lens "zeiss": no lenses/zeiss file`},
+		{"kind", "use: {tripod: gitzo}\n", nil, `use: unknown library "tripod" (want rig, camera, lens)`},
+		{"escape", "use: {lens: ../test}\n", nil, "no lenses/../test file"},
+		{"section", "use: {lens: wide}\n", map[string]string{
+			"lenses/wide.yaml": "lens:\n  focal_length_mm: 14\ncamera:\n  focus_pin: 3\n",
+		}, "camera cannot be set by a lens file (only lens)"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, tc.main)
+			writeFiles(t, filepath.Dir(path), tc.files)
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Load = %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoad_ShippedLibraries(t *testing.T) {
+	path := writeConfig(t, "use:\n  rig: a4988-default\n  camera: nikon-d90\n  lens: nikkor-35mm\n")
+	dir := filepath.Dir(path)
+	files := map[string]string{}
+	for _, name := range []string{"rigs/a4988-default.yaml", "cameras/nikon-d90.yaml", "lenses/nikkor-35mm.yaml"} {
+		data, err := os.ReadFile(filepath.Join("../../configs", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(data)
+	}
+	writeFiles(t, dir, files)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("configs/ library files do not load: %v", err)
+	}
+	def, err := Parse(mustRead(t, "../../configs/default.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PanStepper != def.PanStepper || cfg.Camera.FocusPin != def.Camera.FocusPin || cfg.Lens != def.Lens || *cfg.Sensor != *def.Sensor {
+		t.Error("configs/ library files differ from configs/default.yaml")
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// rename moves the value of a key path (sections joined by ".") to another.
+// A section holding a list applies the rest of the path to each item: a
+// rename of panoramas.a to panoramas.b renames a in every panorama.
+type rename struct {
+	from, to string
+}
+
+// migrations lists the keys renamed by each version of the config layout:
+// entry i takes a file from version i+1 to i+2. Files without a version: key
+// are version 1, the layout before versions were numbered. Keep renames
+// here for good, so that old files keep loading; append a new entry when a
+// release renames keys.
+var migrations = [][]rename{}
+
+// CurrentVersion returns the version of the config layout this build reads
+// and writes.
+func CurrentVersion() int {
+	return len(migrations) + 1
+}
+
+// migrateDocument renames the keys of doc, a config document of any
+// version, to the current layout. Renames also
+// apply within the entries of the profiles: section. It returns a warning
+// for each key renamed.
+func migrateDocument(doc map[string]any) ([]string, error) {
+	version := 1
+	if v, ok := doc["version"]; ok {
+		n, ok := v.(int)
+		if !ok || n < 1 {
+			return nil, fmt.Errorf("version must be a positive integer, got %v", v)
+		}
+		version = n
+	}
+	if version > CurrentVersion() {
+		return nil, fmt.Errorf("version %d is newer than this PanGo reads (up to %d): upgrade PanGo", version, CurrentVersion())
+	}
+
+	var warnings []string
+	for v := version; v < CurrentVersion(); v++ {
+		for _, r := range migrations[v-1] {
+			from, to := strings.Split(r.from, "."), strings.Split(r.to, ".")
+			docs := map[string]map[string]any{"": doc}
+			if slices.Contains(ProfileSections, from[0]) && slices.Contains(ProfileSections, to[0]) {
+				profiles, _ := doc["profiles"].(map[string]any)
+				for name, entry := range profiles {
+					if m, ok := entry.(map[string]any); ok {
+						docs["profiles."+name+"."] = m
+					}
+				}
+			}
+			for _, prefix := range slices.Sorted(maps.Keys(docs)) {
+				moved, err := moveKey(docs[prefix], from, to)
+				if err != nil {
+					return nil, fmt.Errorf("%s%s was renamed %s%s in version %d: %v", prefix, r.from, prefix, r.to, v+1, err)
+				}
+				if moved {
+					warnings = append(warnings, fmt.Sprintf("%s%s is now %s%s (config version %d); rename it and set version: %d", prefix, r.from, prefix, r.to, v+1, CurrentVersion()))
+				}
+			}
+		}
+	}
+	return warnings, nil
+}
+
+// migrate migrates the YAML document data like migrateDocument. Data is
+// returned as is when no key was renamed, or when it is not a mapping, for
+// Parse to report.
+func migrate(data []byte) ([]byte, []string, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil || doc == nil {
+		return data, nil, nil
+	}
+	warnings, err := migrateDocument(doc)
+	if err != nil || len(warnings) == 0 {
+		return data, nil, err
+	}
+	if data, err = yaml.Marshal(doc); err != nil {
+		return nil, nil, fmt.Errorf("marshal yaml: %w", err)
+	}
+	return data, warnings, nil
+}
+
+// moveKey moves the value at from to to within m, and reports whether there
+// was one. It fails when both keys are set.
+func moveKey(m map[string]any, from, to []string) (bool, error) {
+	if len(from) > 1 && len(to) > 1 && from[0] == to[0] {
+		switch v := m[from[0]].(type) {
+		case map[string]any:
+			return moveKey(v, from[1:], to[1:])
+		case []any:
+			moved := false
+			for _, item := range v {
+				if im, ok := item.(map[string]any); ok {
+					ok, err := moveKey(im, from[1:], to[1:])
+					if err != nil {
+						return false, err
+					}
+					moved = moved || ok
+				}
+			}
+			return moved, nil
+		}
+		return false, nil
+	}
+
+	parent, ok := lookupSection(m, from[:len(from)-1], false)
+	if !ok {
+		return false, nil
+	}
+	value, ok := parent[from[len(from)-1]]
+	if !ok {
+		return false, nil
+	}
+	dst, ok := lookupSection(m, to[:len(to)-1], true)
+	if !ok {
+		return false, fmt.Errorf("%s is not a section", strings.Join(to[:len(to)-1], "."))
+	}
+	if _, set := dst[to[len(to)-1]]; set {
+		return false, fmt.Errorf("both %s and %s set", strings.Join(from, "."), strings.Join(to, "."))
+	}
+	delete(parent, from[len(from)-1])
+	dst[to[len(to)-1]] = value
+	return true, nil
+}
+
+// lookupSection returns the section of m at path, creating missing ones if
+// create is set.
+func lookupSection(m map[string]any, path []string, create bool) (map[string]any, bool) {
+	for _, key := range path {
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			if !create || m[key] != nil {
+				return nil, false
+			}
+			next = map[string]any{}
+			m[key] = next
+		}
+		m = next
+	}
+	return m, true
+}
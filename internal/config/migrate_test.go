@@ -0,0 +1,175 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withMigrations replaces the renames of the config layout for a test.
+func withMigrations(t *testing.T, m [][]rename) {
+	t.Helper()
+	old := migrations
+	migrations = m
+	t.Cleanup(func() { migrations = old })
+}
+
+// testMigrations renames keys in version 2, the layout of validYAML.
+var testMigrations = [][]rename{{
+	{"lens.focal_mm", "lens.focal_length_mm"},
+	{"defaults.mock", "defaults.mock_gpio"},
+	{"panoramas.center_deg", "panoramas.pan_center_deg"},
+}}
+
+// v1YAML is validYAML with the version 1 keys of testMigrations.
+var v1YAML = strings.NewReplacer(
+	"focal_length_mm:", "focal_mm:",
+	"mock_gpio:", "mock:",
+).Replace(validYAML)
+
+// ---------- version ----------
+
+func TestParse_Version(t *testing.T) {
+	cases := []struct {
+		name, version, wantErr string
+	}{
+		{"none", "", ""},
+		{"current", "version: 1\n", ""},
+		{"newer", "version: 2\n", "version 2 is newer than this PanGo reads (up to 1)"},
+		{"zero", "version: 0\n", "version must be a positive integer"},
+		{"text", "version: two\n", "version must be a positive integer"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tc.version + validYAML))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("Parse = %v, want an error with %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if cfg.Version != CurrentVersion() || len(cfg.Warnings) != 0 {
+				t.Errorf("version = %d, warnings = %q, want %d and none", cfg.Version, cfg.Warnings, CurrentVersion())
+			}
+		})
+	}
+}
+
+// ---------- Migration ----------
+
+func TestParse_Migrates(t *testing.T) {
+	withMigrations(t, testMigrations)
+	yaml := v1YAML + `panoramas:
+  - name: left
+    center_deg: -60
+  - name: right
+    center_deg: 60
+profiles:
+  tele:
+    lens:
+      focal_mm: 200
+`
+	cfg, err := Parse([]byte(yaml))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cfg.Version != 2 {
+		t.Errorf("version = %d, want 2", cfg.Version)
+	}
+	if cfg.Lens.FocalLengthMm != 35 || !cfg.Defaults.MockGPIO {
+		t.Errorf("lens = %+v, mock_gpio = %t, want the renamed keys", cfg.Lens, cfg.Defaults.MockGPIO)
+	}
+	if cfg.Panoramas[0].PanCenterDeg != -60 || cfg.Panoramas[1].PanCenterDeg != 60 {
+		t.Errorf("panoramas = %+v", cfg.Panoramas)
+	}
+	if tele, err := cfg.WithProfile("tele"); err != nil || tele.Lens.FocalLengthMm != 200 {
+		t.Errorf("tele = %v, %v, want 200 mm", tele, err)
+	}
+	want := []string{
+		"lens.focal_mm is now lens.focal_length_mm (config version 2); rename it and set version: 2",
+		"profiles.tele.lens.focal_mm is now profiles.tele.lens.focal_length_mm (config version 2); rename it and set version: 2",
+		"defaults.mock is now defaults.mock_gpio (config version 2); rename it and set version: 2",
+		"panoramas.center_deg is now panoramas.pan_center_deg (config version 2); rename it and set version: 2",
+	}
+	if strings.Join(cfg.Warnings, "\n") != strings.Join(want, "\n") {
+		t.Errorf("warnings =\n%s\nwant\n%s", strings.Join(cfg.Warnings, "\n"), strings.Join(want, "\n"))
+	}
+
+	// A file of the current layout is read as is
+	current, err := Parse([]byte("version: 2\n" + validYAML))
+	if err != nil || len(current.Warnings) != 0 {
+		t.Errorf("Parse(version 2) = %v, warnings %q", err, current.Warnings)
+	}
+}
+
+func TestParse_MigrateConflict(t *testing.T) {
+	withMigrations(t, testMigrations)
+	yaml := strings.Replace(v1YAML, "focal_mm: 35.0", "focal_mm: 35.0\n  focal_length_mm: 50.0", 1)
+	_, err := Parse([]byte(yaml))
+	if err == nil || !strings.Contains(err.Error(), "lens.focal_mm was renamed lens.focal_length_mm in version 2: both") {
+		t.Errorf("Parse = %v, want a rename conflict", err)
+	}
+}
+
+func TestLoad_MigratesIncludes(t *testing.T) {
+	withMigrations(t, testMigrations)
+	path := writeConfig(t, "version: 2\ninclude: [old.yaml]\ndefaults:\n  overlap_percent: 40\n")
+	old := filepath.Join(filepath.Dir(path), "old.yaml")
+	writeFiles(t, filepath.Dir(path), map[string]string{"old.yaml": v1YAML})
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Lens.FocalLengthMm != 35 || cfg.Defaults.OverlapPercent != 40 {
+		t.Errorf("lens = %+v, overlap = %v", cfg.Lens, cfg.Defaults.OverlapPercent)
+	}
+	if len(cfg.Warnings) != 2 || !strings.HasPrefix(cfg.Warnings[0], old+": lens.focal_mm is now") {
+		t.Errorf("warnings = %q, want the included file's", cfg.Warnings)
+	}
+
+	// The version of the including file does not apply to included files
+	writeFiles(t, filepath.Dir(path), map[string]string{"old.yaml": "version: 3\n" + validYAML})
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), old+": version 3 is newer") {
+		t.Errorf("Load = %v, want the included file's version refused", err)
+	}
+}
+
+func TestSetValues_Migrates(t *testing.T) {
+	withMigrations(t, testMigrations)
+	path := writeConfig(t, v1YAML)
+	if err := SetValues(path, "", map[string]map[string]any{"lens": {"focal_length_mm": 50.0}}); err != nil {
+		t.Fatalf("SetValues: %v", err)
+	}
+	data := string(mustRead(t, path))
+	if strings.Contains(data, "focal_mm:") || strings.Contains(data, "mock:") || !strings.Contains(data, "version: 2") {
+		t.Errorf("file not migrated:\n%s", data)
+	}
+	cfg, err := Load(path)
+	if err != nil || cfg.Lens.FocalLengthMm != 50 || len(cfg.Warnings) != 0 {
+		t.Errorf("Load = %v, %v", cfg, err)
+	}
+}
+
+func TestSave_WritesVersion(t *testing.T) {
+	withMigrations(t, testMigrations)
+	cfg, err := Parse([]byte(v1YAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(filepath.Dir(writeConfig(t, validYAML)), "saved.yaml")
+	if err := Save(path, cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "version: 2") || strings.Contains(string(data), "focal_mm:") {
+		t.Errorf("saved file:\n%s", data)
+	}
+}
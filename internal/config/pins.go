@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Pins returns the GPIO pins (BCM) used by cfg, by config key. Optional pins
+// left at 0 are not included.
+func (c *Config) Pins() map[string]int {
+	pins := map[string]int{
+		"pan_stepper.step_pin":  c.PanStepper.StepPin,
+		"pan_stepper.dir_pin":   c.PanStepper.DirPin,
+		"tilt_stepper.step_pin": c.TiltStepper.StepPin,
+		"tilt_stepper.dir_pin":  c.TiltStepper.DirPin,
+		"camera.focus_pin":      c.Camera.FocusPin,
+		"camera.shutter_pin":    c.Camera.ShutterPin,
+	}
+	optional := map[string]int{
+		"pan_stepper.enable_pin":  c.PanStepper.EnablePin,
+		"tilt_stepper.enable_pin": c.TiltStepper.EnablePin,
+		"trigger.pin":             c.Trigger.Pin,
+		"panel.pin_a":             c.Panel.PinA,
+		"panel.pin_b":             c.Panel.PinB,
+		"panel.button_pin":        c.Panel.ButtonPin,
+	}
+	for key, pin := range optional {
+		if pin != 0 {
+			pins[key] = pin
+		}
+	}
+	return pins
+}
+
+// PinConflicts lists the GPIO pins of cfg assigned to several config keys,
+// one message per pin in pin order, e.g. "GPIO 17: camera.focus_pin,
+// pan_stepper.step_pin". The enable pins may be shared by both steppers:
+// A4988 boards are often enabled together.
+func PinConflicts(cfg *Config) []string {
+	users := map[int][]string{}
+	for key, pin := range cfg.Pins() {
+		users[pin] = append(users[pin], key)
+	}
+	var pins []int
+	for pin, keys := range users {
+		if len(keys) > 1 && !sharedEnable(keys) {
+			pins = append(pins, pin)
+		}
+	}
+	sort.Ints(pins)
+	conflicts := make([]string, 0, len(pins))
+	for _, pin := range pins {
+		keys := users[pin]
+		sort.Strings(keys)
+		conflicts = append(conflicts, fmt.Sprintf("GPIO %d: %s", pin, strings.Join(keys, ", ")))
+	}
+	return conflicts
+}
+
+// sharedEnable reports whether keys are the two stepper enable pins.
+func sharedEnable(keys []string) bool {
+	for _, k := range keys {
+		if !strings.HasSuffix(k, ".enable_pin") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// ---------- PinConflicts ----------
+
+func TestPinConflicts(t *testing.T) {
+	cases := []struct {
+		name     string
+		old, new string // replaced in validYAML
+		want     []string
+	}{
+		{"none", "", "", nil},
+		{"camera_on_stepper", "focus_pin: 24", "focus_pin: 17",
+			[]string{"GPIO 17: camera.focus_pin, pan_stepper.step_pin"}},
+		{"trigger_on_shutter", "lens:", "trigger:\n  pin: 25\n  debounce_ms: 20\nlens:",
+			[]string{"GPIO 25: camera.shutter_pin, trigger.pin"}},
+		{"panel_on_trigger", "lens:", "trigger:\n  pin: 12\npanel:\n  pin_a: 12\n  pin_b: 16\nlens:",
+			[]string{"GPIO 12: panel.pin_a, trigger.pin"}},
+		{"shared_enable", "enable_pin: 6", "enable_pin: 5", nil},
+		{"enable_on_step", "enable_pin: 6", "enable_pin: 17",
+			[]string{"GPIO 17: pan_stepper.step_pin, tilt_stepper.enable_pin"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(strings.Replace(validYAML, tc.old, tc.new, 1)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := PinConflicts(cfg)
+			if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+				t.Errorf("PinConflicts = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPins_Optional(t *testing.T) {
+	cfg, err := Parse([]byte(validYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pins := cfg.Pins()
+	if _, ok := pins["trigger.pin"]; ok {
+		t.Error("disabled trigger pin listed")
+	}
+	if pins["camera.focus_pin"] != cfg.Camera.FocusPin || pins["pan_stepper.enable_pin"] != cfg.PanStepper.EnablePin {
+		t.Errorf("Pins = %v", pins)
+	}
+}
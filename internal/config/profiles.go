@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileSections are the sections an entry of profiles: may set: the lens
+// and camera bundle, and the capture defaults (angles, overlap, mode).
+var ProfileSections = []string{"camera", "lens", "sensor", "resolution", "defaults"}
+
+// ProfileSeparator joins a config file's profile name and an entry of its
+// profiles: section in qualified names, e.g. "default/tele-200mm". File
+// profile names cannot contain it.
+const ProfileSeparator = "/"
+
+// ErrUnknownProfile is returned by WithProfile for names missing from the
+// profiles: section.
+var ErrUnknownProfile = errors.New("no such entry in profiles")
+
+// ProfileNames returns the names of the profiles: section, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithProfile returns the configuration of entry name of the profiles:
+// section: its keys replace those of c, section by section, and the result
+// is validated like a file. c must be a configuration as loaded, without a
+// profile applied. The result keeps the profiles: section and Sources, and
+// records name in Profile.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	if c.Profile != "" {
+		return nil, fmt.Errorf("profile %q already applied", c.Profile)
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownProfile, name)
+	}
+	base, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("marshal yaml: %w", err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(base, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal yaml: %w", err)
+	}
+	delete(doc, "profiles")
+	mergeDocument(doc, profile)
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal yaml: %w", err)
+	}
+	cfg, err := parse(data, false, readSecret)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	cfg.Profiles, cfg.Profile, cfg.Sources, cfg.Warnings = c.Profiles, name, c.Sources, c.Warnings
+	return cfg, nil
+}
+
+// mergeDocument sets the keys of src in dst, merging the sections present
+// in both rather than replacing them.
+func mergeDocument(dst, src map[string]any) {
+	for k, v := range src {
+		sub, ok := v.(map[string]any)
+		cur, curOK := dst[k].(map[string]any)
+		if ok && curOK {
+			mergeDocument(cur, sub)
+			continue
+		}
+		for _, other := range unitForms(k) {
+			delete(dst, other)
+		}
+		dst[k] = v
+	}
+}
+
+// validateProfiles checks each entry of the profiles: section: names, the
+// sections set, and the resulting configuration.
+func validateProfiles(cfg *Config) error {
+	for _, name := range cfg.ProfileNames() {
+		if name == "" || strings.Contains(name, ProfileSeparator) {
+			return fmt.Errorf("profiles: invalid name %q", name)
+		}
+		for section := range cfg.Profiles[name] {
+			if !slices.Contains(ProfileSections, section) {
+				return fmt.Errorf("profiles: %s: %s cannot be set by a profile (only %s)", name, section, strings.Join(ProfileSections, ", "))
+			}
+		}
+		if _, err := cfg.WithProfile(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const profilesYAML = `profiles:
+  tele-200mm:
+    lens:
+      focal_length_mm: 200
+    defaults:
+      horizontal_angle_deg: 60
+  fisheye:
+    lens:
+      name: "Samyang 8mm"
+      focal_length_mm: 8
+    sensor:
+      width_mm: 36
+      height_mm: 24
+`
+
+// ---------- profiles: section ----------
+
+func TestWithProfile(t *testing.T) {
+	cfg, err := Parse([]byte(validYAML + profilesYAML))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := strings.Join(cfg.ProfileNames(), ","); got != "fisheye,tele-200mm" {
+		t.Errorf("ProfileNames = %s", got)
+	}
+	if cfg.Lens.FocalLengthMm != 35 || cfg.Profile != "" {
+		t.Errorf("base configuration changed by its profiles: lens %+v, profile %q", cfg.Lens, cfg.Profile)
+	}
+
+	tele, err := cfg.WithProfile("tele-200mm")
+	if err != nil {
+		t.Fatalf("WithProfile: %v", err)
+	}
+	if tele.Lens.FocalLengthMm != 200 || tele.Lens.Name != "Nikkor 35mm" {
+		t.Errorf("lens = %+v, want focal length 200 and the inherited name", tele.Lens)
+	}
+	if tele.Defaults.HorizontalAngleDeg != 60 || tele.Defaults.VerticalAngleDeg != 30 || !tele.Defaults.MockGPIO {
+		t.Errorf("defaults = %+v, want the angle replaced and the rest inherited", tele.Defaults)
+	}
+	if tele.Profile != "tele-200mm" || len(tele.Profiles) != 2 {
+		t.Errorf("profile = %q, profiles = %v", tele.Profile, tele.ProfileNames())
+	}
+	if _, err := tele.WithProfile("fisheye"); err == nil {
+		t.Error("WithProfile on a configuration with a profile applied should fail")
+	}
+
+	fisheye, err := cfg.WithProfile("fisheye")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fisheye.Sensor.WidthMm != 36 || fisheye.Lens.Name != "Samyang 8mm" {
+		t.Errorf("sensor = %+v, lens = %+v", fisheye.Sensor, fisheye.Lens)
+	}
+
+	if _, err := cfg.WithProfile("missing"); !errors.Is(err, ErrUnknownProfile) {
+		t.Errorf("unknown profile: err = %v, want ErrUnknownProfile", err)
+	}
+}
+
+func TestParse_InvalidProfiles(t *testing.T) {
+	cases := []struct {
+		name, yaml, want string
+	}{
+		{"section", "profiles:\n  night:\n    trigger:\n      pin: 4\n", "profiles: night: trigger cannot be set by a profile"},
+		{"value", "profiles:\n  macro:\n    lens:\n      focal_length_mm: 0.5\n", "profile macro: lens focal_length_mm must be between"},
+		{"name", "profiles:\n  a/b:\n    lens:\n      focal_length_mm: 50\n", `profiles: invalid name "a/b"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(validYAML + tc.yaml))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Parse = %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SchemaDialect is the JSON Schema version of Schema.
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// schemaHints adds the bounds and choices of Parse to the schema of a key
+// path; list items are "[]" and map values "*".
+var schemaHints = map[string]map[string]any{
+	"version":                    {"minimum": 1, "maximum": CurrentVersion()},
+	"defaults.mode":              {"enum": []string{ModeGrid, ModeTimelapse}},
+	"defaults.debug_level":       {"minimum": 0, "maximum": 4},
+	"defaults.overlap_percent":   {"minimum": 0, "maximum": 100},
+	"dark_frames.mode":           {"enum": []string{DarkFramesPrompt, DarkFramesShutter}},
+	"dark_frames.count":          {"minimum": 0, "maximum": MaxDarkFrames},
+	"lens.focal_length_mm":       {"minimum": MinFocalLengthMm, "maximum": MaxFocalLengthMm},
+	"sensor.width_mm":            {"exclusiveMinimum": 0, "maximum": MaxSensorDimensionMm},
+	"sensor.height_mm":           {"exclusiveMinimum": 0, "maximum": MaxSensorDimensionMm},
+	"pan_stepper.microstepping":  {"enum": []int{1, 2, 4, 8, 16, 32}},
+	"tilt_stepper.microstepping": {"enum": []int{1, 2, 4, 8, 16, 32}},
+	"pan_stepper.steps_per_rev":  {"minimum": 0, "maximum": MaxMotorStepsPerRev},
+	"tilt_stepper.steps_per_rev": {"minimum": 0, "maximum": MaxMotorStepsPerRev},
+	"camera.exposure_time_ms":    {"minimum": 0, "maximum": MaxExposureTimeMs},
+	"panoramas":                  {"maxItems": MaxPanoramas},
+	"webhooks":                   {"maxItems": MaxWebhooks},
+	"web.port":                   {"minimum": 0, "maximum": 65535},
+	"web.multi_rig.followers":    {"maxItems": MaxFollowers},
+}
+
+// Schema returns a JSON Schema of the config file, derived from the Config
+// struct and its YAML keys, for editors to complete and check config files.
+// Unknown keys are flagged. It covers types and the simple bounds of
+// Parse; Parse remains the reference (cross-field rules, e.g. trigger
+// debounce, are not expressed).
+func Schema() map[string]any {
+	s := typeSchema(reflect.TypeFor[Config](), "")
+	props := s["properties"].(map[string]any)
+	sections := map[string]any{}
+	for _, section := range ProfileSections {
+		sections[section] = props[section]
+	}
+	props["profiles"] = map[string]any{
+		"type":                 "object",
+		"additionalProperties": map[string]any{"type": "object", "properties": sections, "additionalProperties": false},
+	}
+	props["include"] = map[string]any{"type": "array", "items": map[string]any{"type": "string"}}
+	use := map[string]any{}
+	for _, kind := range LibraryKinds {
+		use[kind] = map[string]any{"type": "string"}
+	}
+	props["use"] = map[string]any{"type": "object", "properties": use, "additionalProperties": false}
+	s["$schema"] = SchemaDialect
+	s["title"] = "PanGo configuration"
+	return s
+}
+
+func typeSchema(t reflect.Type, path string) map[string]any {
+	var s map[string]any
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem(), path)
+	case reflect.Struct:
+		props := map[string]any{}
+		for i := range t.NumField() {
+			field := t.Field(i)
+			key, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if key == "" || key == "-" {
+				continue
+			}
+			props[key] = typeSchema(field.Type, strings.TrimPrefix(path+"."+key, "."))
+			if alias, suffix, ok := unitAlias(key); ok {
+				props[alias] = unitSchema(strings.TrimPrefix(path+"."+alias, "."), key, suffix)
+			}
+		}
+		s = map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	case reflect.Slice:
+		s = map[string]any{"type": "array", "items": typeSchema(t.Elem(), path+"[]")}
+	case reflect.Map:
+		s = map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), path+".*")}
+	case reflect.String:
+		s = map[string]any{"type": "string"}
+	case reflect.Bool:
+		s = map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		s = map[string]any{"type": "integer"}
+	case reflect.Float64:
+		s = map[string]any{"type": "number"}
+	default:
+		s = map[string]any{}
+	}
+	if strings.HasSuffix(path, "pin") {
+		s["minimum"], s["maximum"] = MinGPIOPin, MaxGPIOPin
+	}
+	for k, v := range schemaHints[path] {
+		s[k] = v
+	}
+	return s
+}
+
+// unitSchema describes the unit-less key path of key, whose value carries
+// the unit (see unitSuffixes).
+func unitSchema(path, key, suffix string) map[string]any {
+	for _, u := range unitSuffixes {
+		if u.suffix != suffix {
+			continue
+		}
+		pattern := u.pattern
+		if path == moveSpeedKey {
+			pattern += `|^[0-9]*\.?[0-9]+ ?(deg|°|rad)/s$`
+		}
+		return map[string]any{
+			"type":        "string",
+			"pattern":     pattern,
+			"description": fmt.Sprintf("%s with a unit, e.g. %s", key, u.example),
+		}
+	}
+	return map[string]any{"type": "string"}
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// schemaCheck reports the keys and values of doc that schema s rejects,
+// checking types, enums and bounds: enough of JSON Schema for Schema.
+func schemaCheck(s map[string]any, doc any, path string) []string {
+	var errs []string
+	switch v := doc.(type) {
+	case map[string]any:
+		if s["type"] != "object" {
+			return []string{fmt.Sprintf("%s: object, want %v", path, s["type"])}
+		}
+		props, _ := s["properties"].(map[string]any)
+		for k, item := range v {
+			sub, ok := props[k].(map[string]any)
+			if !ok {
+				sub, ok = s["additionalProperties"].(map[string]any)
+			}
+			if !ok {
+				errs = append(errs, fmt.Sprintf("%s.%s: unknown key", path, k))
+				continue
+			}
+			errs = append(errs, schemaCheck(sub, item, path+"."+k)...)
+		}
+	case []any:
+		if s["type"] != "array" {
+			return []string{fmt.Sprintf("%s: array, want %v", path, s["type"])}
+		}
+		for i, item := range v {
+			errs = append(errs, schemaCheck(s["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case string:
+		if s["type"] != "string" {
+			errs = append(errs, fmt.Sprintf("%s: string, want %v", path, s["type"]))
+		}
+		if enum, ok := s["enum"].([]string); ok && v != "" && !contains(enum, v) {
+			errs = append(errs, fmt.Sprintf("%s: %q not in %v", path, v, enum))
+		}
+	case bool:
+		if s["type"] != "boolean" {
+			errs = append(errs, fmt.Sprintf("%s: boolean, want %v", path, s["type"]))
+		}
+	case int, float64:
+		if s["type"] != "integer" && s["type"] != "number" {
+			errs = append(errs, fmt.Sprintf("%s: number, want %v", path, s["type"]))
+		}
+		f := fmt.Sprint(v)
+		if lo, ok := s["minimum"]; ok && toFloat(v) < toFloat(lo) {
+			errs = append(errs, fmt.Sprintf("%s: %s below %v", path, f, lo))
+		}
+		if hi, ok := s["maximum"]; ok && toFloat(v) > toFloat(hi) {
+			errs = append(errs, fmt.Sprintf("%s: %s above %v", path, f, hi))
+		}
+	}
+	return errs
+}
+
+func toFloat(v any) float64 {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------- Schema ----------
+
+func TestSchema_DefaultConfig(t *testing.T) {
+	data, err := os.ReadFile("../../configs/default.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range schemaCheck(Schema(), doc, "") {
+		t.Errorf("configs/default.yaml does not match the schema: %s", e)
+	}
+}
+
+func TestSchema_Rejects(t *testing.T) {
+	cases := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"unknown_key", "lens:\n  focal_lenght_mm: 35\n", ".lens.focal_lenght_mm: unknown key"},
+		{"type", "defaults:\n  mock_gpio: 1\n", ".defaults.mock_gpio: number, want boolean"},
+		{"enum", "defaults:\n  mode: panorama\n", `.defaults.mode: "panorama" not in [grid timelapse]`},
+		{"pin_bound", "camera:\n  focus_pin: 40\n", ".camera.focus_pin: 40 above 27"},
+		{"list_item", "panoramas:\n  - name: a\n    pan_centre_deg: 10\n", ".panoramas[0].pan_centre_deg: unknown key"},
+		{"profile_section", "profiles:\n  tele:\n    trigger:\n      pin: 4\n", ".profiles.tele.trigger: unknown key"},
+		{"profile_value", "profiles:\n  tele:\n    lens:\n      focal_length_mm: 5000\n", ".profiles.tele.lens.focal_length_mm: 5000 above 2000"},
+		{"map_value", "web:\n  limits:\n    requests_per_minute:\n      run: fast\n", ".web.limits.requests_per_minute.run: string, want integer"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var doc map[string]any
+			if err := yaml.Unmarshal([]byte(tc.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			errs := schemaCheck(Schema(), doc, "")
+			if len(errs) != 1 || errs[0] != tc.want {
+				t.Errorf("errors = %q, want %q", errs, tc.want)
+			}
+		})
+	}
+}
+
+func TestSchema_JSON(t *testing.T) {
+	data, err := json.Marshal(Schema())
+	if err != nil {
+		t.Fatalf("Schema is not JSON: %v", err)
+	}
+	var s struct {
+		Schema     string                     `json:"$schema"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Schema != SchemaDialect {
+		t.Errorf("$schema = %q", s.Schema)
+	}
+	for _, key := range []string{"camera", "lens", "sensor", "pan_stepper", "defaults", "panoramas", "web"} {
+		if _, ok := s.Properties[key]; !ok {
+			t.Errorf("schema lacks the %s section", key)
+		}
+	}
+}
@@ -0,0 +1,450 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Secret references stand for the whole value of a string key, so that
+// credentials stay out of config files kept in git:
+//
+//	${env:NAME}        the environment variable NAME, which must be set
+//	${file:/abs/path}  the content of a file only its owner can read
+//	                   (e.g. chmod 600), without the trailing newline
+//
+// References are resolved in config files on disk (Load) and written back
+// as is by Save. Documents from clients (Parse, SaveProfile) may not hold
+// new ones: they would read any variable or file of the daemon.
+var secretRef = regexp.MustCompile(`^\$\{(env|file):([^}]+)\}$`)
+
+// ErrSecretRef is returned for secret references in documents received
+// from clients.
+var ErrSecretRef = errors.New("secret references are only read from config files on the rig")
+
+// MaxSecretFileBytes limits the files read by ${file:...} references.
+const MaxSecretFileBytes = 64 << 10
+
+// SecretKeys are the YAML keys holding credentials. pango validate warns
+// when one is set inline rather than by a reference.
+var SecretKeys = []string{"token", "password", "secret"}
+
+// SecretRefs returns the key paths set by a secret reference, sorted.
+func (c *Config) SecretRefs() []string {
+	paths := make([]string, 0, len(c.secrets))
+	for path := range c.secrets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// InlineSecrets returns the key paths of SecretKeys whose value is written
+// in the config rather than given by a secret reference or its PANGO_*
+// environment variable.
+func (c *Config) InlineSecrets() []string {
+	var paths []string
+	walkStrings(reflect.ValueOf(c).Elem(), "", func(path string, v reflect.Value) error {
+		key := path[strings.LastIndex(path, ".")+1:]
+		if _, ref := c.secrets[path]; ref || v.String() == "" || !slices.Contains(SecretKeys, key) {
+			return nil
+		}
+		if _, env := os.LookupEnv(EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))); !env {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths
+}
+
+// Redacted replaces the value of secret keys in Effective. Sent back in a
+// document to ParseUpdate, it stands for the current value.
+const Redacted = "<redacted>"
+
+// Effective returns c as a document keyed like the config file, with every
+// key: included files merged, defaults and PANGO_* variables applied.
+// Credentials are replaced by Redacted (see Redact).
+func (c *Config) Effective() (map[string]any, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("marshal yaml: %w", err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal yaml: %w", err)
+	}
+	Redact(doc)
+	return doc, nil
+}
+
+// Redact replaces the credentials of doc, a document keyed like the config
+// file, by Redacted: the values of SecretKeys that are not secret
+// references, and those of tracing.headers, which carry the API keys of
+// hosted tracing backends.
+func Redact(doc map[string]any) {
+	redact(doc)
+	if tracing, ok := doc["tracing"].(map[string]any); ok {
+		if headers, ok := tracing["headers"].(map[string]any); ok {
+			for name, v := range headers {
+				if s, ok := v.(string); ok && s != "" {
+					headers[name] = Redacted
+				}
+			}
+		}
+	}
+}
+
+// redact replaces the values of SecretKeys in v, a YAML document.
+func redact(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if s, ok := val.(string); ok && slices.Contains(SecretKeys, k) {
+				if s != "" && !secretRef.MatchString(s) {
+					v[k] = Redacted
+				}
+				continue
+			}
+			redact(val)
+		}
+	case []any:
+		for _, item := range v {
+			redact(item)
+		}
+	}
+}
+
+// KeepWeb sets the web section of c to that of src, with its secret
+// references, for web settings that cannot change at runtime.
+func (c *Config) KeepWeb(src *Config) {
+	c.Web = src.Web
+	secrets := map[string]string{}
+	for path, ref := range c.secrets {
+		if !strings.HasPrefix(path, "web.") {
+			secrets[path] = ref
+		}
+	}
+	for path, ref := range src.secrets {
+		if strings.HasPrefix(path, "web.") {
+			secrets[path] = ref
+		}
+	}
+	c.secrets = secrets
+}
+
+// secretReader returns the value of the secret reference ${kind:name} of
+// the key path.
+type secretReader func(path, kind, name string) (string, error)
+
+// resolveSecrets replaces the secret references of cfg by their values,
+// read with read, and records them in cfg.secrets.
+func resolveSecrets(cfg *Config, read secretReader) error {
+	return walkStrings(reflect.ValueOf(cfg).Elem(), "", func(path string, v reflect.Value) error {
+		m := secretRef.FindStringSubmatch(v.String())
+		if m == nil {
+			return nil
+		}
+		value, err := read(path, m[1], m[2])
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if cfg.secrets == nil {
+			cfg.secrets = map[string]string{}
+		}
+		cfg.secrets[path] = m[0]
+		v.SetString(value)
+		return nil
+	})
+}
+
+// readSecret is the secretReader of config files on disk: it returns the
+// value of a ${kind:name} reference.
+func readSecret(_, kind, name string) (string, error) {
+	if kind == "env" {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value, nil
+	}
+	if !filepath.IsAbs(name) {
+		return "", fmt.Errorf("secret file %s: path must be absolute", name)
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return "", fmt.Errorf("secret file: %w", err)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("secret file %s must only be accessible to its owner (chmod 600), got %v", name, info.Mode().Perm())
+	}
+	if info.Size() > MaxSecretFileBytes {
+		return "", fmt.Errorf("secret file %s too large: %d bytes (max %d)", name, info.Size(), MaxSecretFileBytes)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", fmt.Errorf("secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// restoreRedacted returns data, a YAML (or JSON) document replacing
+// current, with the values set to Redacted (as Redact left them) replaced
+// by those of current at the same key paths.
+func restoreRedacted(data []byte, current *Config) ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return data, nil // parsing reports it
+	}
+	var cur any
+	if current != nil {
+		saved, err := yaml.Marshal(current)
+		if err != nil {
+			return nil, fmt.Errorf("marshal yaml: %w", err)
+		}
+		if err := yaml.Unmarshal(saved, &cur); err != nil {
+			return nil, fmt.Errorf("unmarshal yaml: %w", err)
+		}
+	}
+	restored := false
+	var walk func(v, cur any, path string) error
+	walk = func(v, cur any, path string) error {
+		switch v := v.(type) {
+		case map[string]any:
+			curMap, _ := cur.(map[string]any)
+			for k, val := range v {
+				if s, ok := val.(string); ok && s == Redacted {
+					value, ok := curMap[k].(string)
+					if !ok {
+						return fmt.Errorf("%s: %s stands for the current value, and there is none", joinKey(path, k), Redacted)
+					}
+					v[k], restored = value, true
+					continue
+				}
+				if err := walk(val, curMap[k], joinKey(path, k)); err != nil {
+					return err
+				}
+			}
+		case []any:
+			curList, _ := cur.([]any)
+			for i, val := range v {
+				var curVal any
+				if i < len(curList) {
+					curVal = curList[i]
+				}
+				if s, ok := val.(string); ok && s == Redacted {
+					value, ok := curVal.(string)
+					if !ok {
+						return fmt.Errorf("%s: %s stands for the current value, and there is none", joinKey(path, strconv.Itoa(i)), Redacted)
+					}
+					v[i], restored = value, true
+					continue
+				}
+				if err := walk(val, curVal, joinKey(path, strconv.Itoa(i))); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(doc, cur, ""); err != nil {
+		return nil, err
+	}
+	if !restored {
+		return data, nil
+	}
+	return yaml.Marshal(doc)
+}
+
+// checkSecretRefs returns ErrSecretRef, with its key path, for the first
+// secret reference of the document data, in any section (profiles:
+// included), that current (nil = none) does not have at the same path.
+func checkSecretRefs(data []byte, current *Config) error {
+	refs := secretRefsIn(data)
+	if len(refs) == 0 {
+		return nil
+	}
+	known := map[string]string{}
+	if current != nil {
+		if doc, err := yaml.Marshal(current); err == nil {
+			known = secretRefsIn(doc)
+		}
+	}
+	paths := make([]string, 0, len(refs))
+	for path := range refs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if known[path] != refs[path] {
+			return fmt.Errorf("%s: %w", path, ErrSecretRef)
+		}
+	}
+	return nil
+}
+
+// secretRefsIn returns the secret references of the YAML (or JSON)
+// document data by key path. A document that does not decode has none:
+// parsing it reports the error.
+func secretRefsIn(data []byte) map[string]string {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	refs := map[string]string{}
+	var walk func(v any, path string)
+	walk = func(v any, path string) {
+		switch v := v.(type) {
+		case string:
+			if secretRef.MatchString(v) {
+				refs[path] = v
+			}
+		case map[string]any:
+			for k, val := range v {
+				walk(val, joinKey(path, k))
+			}
+		case []any:
+			for i, val := range v {
+				walk(val, joinKey(path, strconv.Itoa(i)))
+			}
+		}
+	}
+	walk(doc, "")
+	return refs
+}
+
+// stringAt returns the string value of c at the key path, e.g.
+// "web.auth.token"; false when c is nil or has none.
+func (c *Config) stringAt(path string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	var value string
+	found := false
+	walkStrings(reflect.ValueOf(c).Elem(), "", func(p string, v reflect.Value) error {
+		if p == path {
+			value, found = v.String(), true
+		}
+		return nil
+	})
+	return value, found
+}
+
+// secretError is an error whose message has the values of secret
+// references replaced by their key paths.
+type secretError struct {
+	msg string
+	err error
+}
+
+func (e *secretError) Error() string { return e.msg }
+func (e *secretError) Unwrap() error { return e.err }
+
+// hideSecrets returns err with the values of the secret references of cfg
+// replaced by their key paths, e.g. <tracing.endpoint>: validation errors
+// quote the values they refuse, and reach web clients.
+func (cfg *Config) hideSecrets(err error) error {
+	msg := err.Error()
+	for _, path := range cfg.SecretRefs() {
+		if value, ok := cfg.stringAt(path); ok && value != "" {
+			msg = strings.ReplaceAll(msg, value, "<"+path+">")
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return &secretError{msg: msg, err: err}
+}
+
+// walkStrings calls fn with the key path of each string value of the
+// struct v, in sections, lists and optional sections alike. Maps (the
+// profiles: section) are skipped.
+func walkStrings(v reflect.Value, path string, fn func(path string, v reflect.Value) error) error {
+	switch v.Kind() {
+	case reflect.String:
+		return fn(path, v)
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return walkStrings(v.Elem(), path, fn)
+	case reflect.Slice:
+		for i := range v.Len() {
+			if err := walkStrings(v.Index(i), joinKey(path, strconv.Itoa(i)), fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if key == "" || key == "-" {
+				continue
+			}
+			if err := walkStrings(v.Field(i), joinKey(path, key), fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// plainConfig is Config without its MarshalYAML method.
+type plainConfig Config
+
+// MarshalYAML writes the secret references of c in place of their values,
+// so that saving a configuration does not copy credentials into its file.
+func (c Config) MarshalYAML() (any, error) {
+	if len(c.secrets) == 0 {
+		return plainConfig(c), nil
+	}
+	var doc yaml.Node
+	if err := doc.Encode(plainConfig(c)); err != nil {
+		return nil, err
+	}
+	for path, ref := range c.secrets {
+		if n := findNode(&doc, strings.Split(path, ".")); n != nil {
+			n.Value, n.Tag, n.Style = ref, "!!str", 0
+		}
+	}
+	return &doc, nil
+}
+
+// findNode returns the node at the key path keys under n, or nil.
+func findNode(n *yaml.Node, keys []string) *yaml.Node {
+	for _, key := range keys {
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == key {
+					next = n.Content[i+1]
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(key); err == nil && i < len(n.Content) {
+				next = n.Content[i]
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		n = next
+	}
+	return n
+}
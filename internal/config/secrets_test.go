@@ -0,0 +1,304 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ---------- Secret references ----------
+
+func TestLoad_SecretRefs(t *testing.T) {
+	t.Setenv("TEST_PANGO_TOKEN", "0123456789abcdef")
+	t.Setenv("TEST_PANGO_HOOK", "hook-secret")
+	secretFile := filepath.Join(t.TempDir(), "mqtt-password")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	path := writeConfig(t, validYAML+`web:
+  auth:
+    token: ${env:TEST_PANGO_TOKEN}
+  mqtt:
+    broker: tcp://broker.lan
+    username: pango
+    password: ${file:`+secretFile+`}
+  webhooks:
+    - url: https://hooks.example.com/pango
+      secret: ${env:TEST_PANGO_HOOK}
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Web.Auth.Token != "0123456789abcdef" || cfg.Web.MQTT.Password != "s3cret" || cfg.Web.Webhooks[0].Secret != "hook-secret" {
+		t.Errorf("resolved token %q, mqtt password %q, webhook secret %q", cfg.Web.Auth.Token, cfg.Web.MQTT.Password, cfg.Web.Webhooks[0].Secret)
+	}
+	want := []string{"web.auth.token", "web.mqtt.password", "web.webhooks.0.secret"}
+	if got := cfg.SecretRefs(); !slices.Equal(got, want) {
+		t.Errorf("SecretRefs() = %v, want %v", got, want)
+	}
+	if got := cfg.InlineSecrets(); len(got) != 0 {
+		t.Errorf("InlineSecrets() = %v, want none", got)
+	}
+
+	// Saving writes the references, not the values.
+	cfg.Lens.FocalLengthMm = 50
+	if err := Save(path, cfg); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	saved := string(mustRead(t, path))
+	for _, secret := range []string{"0123456789abcdef", "s3cret", "hook-secret"} {
+		if strings.Contains(saved, secret) {
+			t.Errorf("saved file holds %q:\n%s", secret, saved)
+		}
+	}
+	reloaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load saved: %v", err)
+	}
+	if reloaded.Web.Auth.Token != "0123456789abcdef" || reloaded.Lens.FocalLengthMm != 50 {
+		t.Errorf("reloaded token %q, focal length %v", reloaded.Web.Auth.Token, reloaded.Lens.FocalLengthMm)
+	}
+}
+
+func TestLoad_SecretRefsInProfile(t *testing.T) {
+	t.Setenv("TEST_PANGO_TOKEN", "0123456789abcdef")
+	path := writeConfig(t, validYAML+`web:
+  auth:
+    token: ${env:TEST_PANGO_TOKEN}
+profiles:
+  tele:
+    lens:
+      focal_length_mm: 200
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	tele, err := cfg.WithProfile("tele")
+	if err != nil {
+		t.Fatalf("WithProfile: %v", err)
+	}
+	if tele.Web.Auth.Token != "0123456789abcdef" || !slices.Equal(tele.SecretRefs(), []string{"web.auth.token"}) {
+		t.Errorf("profile token %q, refs %v", tele.Web.Auth.Token, tele.SecretRefs())
+	}
+}
+
+func TestLoad_SecretRefErrors(t *testing.T) {
+	dir := t.TempDir()
+	open := filepath.Join(dir, "open")
+	if err := os.WriteFile(open, []byte("0123456789abcdef"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		ref  string
+		want string
+	}{
+		{"unset_env", "${env:TEST_PANGO_UNSET}", "TEST_PANGO_UNSET is not set"},
+		{"missing_file", "${file:" + filepath.Join(dir, "missing") + "}", "secret file"},
+		{"relative_file", "${file:token.txt}", "must be absolute"},
+		{"readable_file", "${file:" + open + "}", "chmod 600"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, validYAML+"web:\n  auth:\n    token: "+tc.ref+"\n")
+			_, err := Load(path)
+			if err == nil || !strings.Contains(err.Error(), tc.want) || !strings.Contains(err.Error(), "web.auth.token") {
+				t.Errorf("Load = %v, want an error about web.auth.token and %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestParse_RefusesSecretRefs(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "sec.txt")
+	if err := os.WriteFile(secretFile, []byte("secretvalue-abc"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_PANGO_SECRET", "secretvalue-abc")
+	cases := []struct {
+		name string
+		yaml string
+		path string
+	}{
+		{"file", "tracing:\n  endpoint: ${file:" + secretFile + "}\n", "tracing.endpoint"},
+		{"env", "web:\n  auth:\n    token: ${env:TEST_PANGO_SECRET}\n", "web.auth.token"},
+		{"list", "web:\n  webhooks:\n    - url: https://hooks.example/pango\n      secret: ${env:TEST_PANGO_SECRET}\n", "web.webhooks.0.secret"},
+		{"profile", "profiles:\n  tele:\n    lens:\n      name: ${file:" + secretFile + "}\n", "profiles.tele.lens.name"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse([]byte(validYAML + tc.yaml))
+			if !errors.Is(err, ErrSecretRef) || !strings.Contains(err.Error(), tc.path) || strings.Contains(err.Error(), "secretvalue") {
+				t.Errorf("Parse = %v, want ErrSecretRef at %s", err, tc.path)
+			}
+			if _, err := SaveProfile(t.TempDir(), "uploaded", []byte(validYAML+tc.yaml), false); !errors.Is(err, ErrInvalidProfile) || !strings.Contains(err.Error(), ErrSecretRef.Error()) {
+				t.Errorf("SaveProfile = %v, want the document refused", err)
+			}
+		})
+	}
+}
+
+func TestParseUpdate_KeepsCurrentRefs(t *testing.T) {
+	t.Setenv("TEST_PANGO_TOKEN", "0123456789abcdef")
+	cur, err := Load(writeConfig(t, validYAML+"web:\n  auth:\n    token: ${env:TEST_PANGO_TOKEN}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := yaml.Marshal(cur) // as GET /config sends it
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_PANGO_TOKEN", "changed-since-load")
+	next, err := ParseUpdate(doc, cur)
+	if err != nil {
+		t.Fatalf("ParseUpdate of the current document: %v", err)
+	}
+	if next.Web.Auth.Token != "0123456789abcdef" || !slices.Equal(next.SecretRefs(), []string{"web.auth.token"}) {
+		t.Errorf("token %q, refs %v, want the current value kept", next.Web.Auth.Token, next.SecretRefs())
+	}
+
+	moved := strings.Replace(string(doc), "name: Nikkor 35mm", "name: ${env:TEST_PANGO_TOKEN}", 1)
+	if _, err := ParseUpdate([]byte(moved), cur); !errors.Is(err, ErrSecretRef) || !strings.Contains(err.Error(), "lens.name") {
+		t.Errorf("new reference: err = %v, want ErrSecretRef at lens.name", err)
+	}
+}
+
+func TestParseUpdate_RestoresRedacted(t *testing.T) {
+	cur, err := Load(writeConfig(t, validYAML+"tracing:\n  endpoint: https://otlp.example\n  headers:\n    x-api-key: otlp-key\nweb:\n  auth:\n    token: 0123456789abcdef\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := cur.Effective()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers := doc["tracing"].(map[string]any)["headers"].(map[string]any); headers["x-api-key"] != Redacted {
+		t.Errorf("tracing.headers = %v, want the key redacted", headers)
+	}
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := ParseUpdate(data, cur)
+	if err != nil {
+		t.Fatalf("ParseUpdate: %v", err)
+	}
+	if next.Tracing.Headers["x-api-key"] != "otlp-key" || next.Web.Auth.Token != "0123456789abcdef" {
+		t.Errorf("header %q, token %q, want the current values", next.Tracing.Headers["x-api-key"], next.Web.Auth.Token)
+	}
+
+	added := strings.Replace(string(data), "headers:", "headers:\n        x-new: "+Redacted, 1)
+	if _, err := ParseUpdate([]byte(added), cur); err == nil || !strings.Contains(err.Error(), "tracing.headers.x-new") {
+		t.Errorf("redacted new header: err = %v, want it refused", err)
+	}
+}
+
+func TestLoad_SecretValuesHiddenInErrors(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "sec.txt")
+	if err := os.WriteFile(secretFile, []byte("secretvalue-abc\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := Load(writeConfig(t, validYAML+"tracing:\n  endpoint: ${file:"+secretFile+"}\n"))
+	if err == nil || strings.Contains(err.Error(), "secretvalue") || !strings.Contains(err.Error(), "<tracing.endpoint>") {
+		t.Errorf("Load = %v, want the key path in place of the value", err)
+	}
+}
+
+func TestConfig_InlineSecrets(t *testing.T) {
+	path := writeConfig(t, validYAML+`web:
+  auth:
+    token: 0123456789abcdef
+    username: admin
+    password: hunter22
+  multi_rig:
+    followers:
+      - url: http://rig2.lan:8080
+        token: fedcba9876543210
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := []string{"web.auth.token", "web.auth.password", "web.multi_rig.followers.0.token"}
+	if got := cfg.InlineSecrets(); !slices.Equal(got, want) {
+		t.Errorf("InlineSecrets() = %v, want %v", got, want)
+	}
+
+	t.Setenv("PANGO_WEB_AUTH_PASSWORD", "from-env")
+	if cfg, err = Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.InlineSecrets(); slices.Contains(got, "web.auth.password") {
+		t.Errorf("InlineSecrets() = %v, want web.auth.password given by the environment left out", got)
+	}
+}
+
+func TestConfig_KeepWeb(t *testing.T) {
+	t.Setenv("TEST_PANGO_TOKEN", "0123456789abcdef")
+	t.Setenv("TEST_PANGO_LENS", "Tele")
+	cur, err := Load(writeConfig(t, validYAML+"web:\n  auth:\n    token: ${env:TEST_PANGO_TOKEN}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := Load(writeConfig(t, strings.Replace(validYAML, `name: "Nikkor 35mm"`, "name: ${env:TEST_PANGO_LENS}", 1)+"web:\n  auth:\n    token: fedcba9876543210\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.KeepWeb(cur)
+	if next.Web.Auth.Token != "0123456789abcdef" {
+		t.Errorf("token = %q, want the current one", next.Web.Auth.Token)
+	}
+	if got := next.SecretRefs(); !slices.Equal(got, []string{"lens.name", "web.auth.token"}) {
+		t.Errorf("SecretRefs() = %v, want lens.name and web.auth.token", got)
+	}
+}
+
+// ---------- Effective ----------
+
+func TestConfig_Effective(t *testing.T) {
+	t.Setenv("TEST_PANGO_TOKEN", "0123456789abcdef")
+	t.Setenv("PANGO_WEB_MQTT_PASSWORD", "from-the-environment")
+	yaml := validYAML + `web:
+  auth:
+    token: ${env:TEST_PANGO_TOKEN}
+    username: admin
+    password: written-in-the-file
+  webhooks:
+    - url: https://hooks.example/pango
+      secret: hook-secret
+`
+	cfg, err := Load(writeConfig(t, yaml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := cfg.Effective()
+	if err != nil {
+		t.Fatalf("Effective: %v", err)
+	}
+	web := doc["web"].(map[string]any)
+	auth := web["auth"].(map[string]any)
+	if auth["token"] != "${env:TEST_PANGO_TOKEN}" || auth["password"] != Redacted || auth["username"] != "admin" {
+		t.Errorf("web.auth = %v, want the reference kept and the password redacted", auth)
+	}
+	if mqtt := web["mqtt"].(map[string]any); mqtt["password"] != Redacted {
+		t.Errorf("web.mqtt.password = %v, want it redacted", mqtt["password"])
+	}
+	hook := web["webhooks"].([]any)[0].(map[string]any)
+	if hook["secret"] != Redacted || hook["url"] != "https://hooks.example/pango" {
+		t.Errorf("webhook = %v", hook)
+	}
+	// Defaults are filled in
+	if defaults := doc["defaults"].(map[string]any); defaults["inter_move_delay_ms"] != 500 {
+		t.Errorf("defaults = %v, want inter_move_delay_ms set", defaults)
+	}
+	if cfg.Web.Auth.Password != "written-in-the-file" {
+		t.Error("Effective changed the configuration")
+	}
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownKey is wrapped by the errors of strict loading (see
+// Config.Strict) for keys that are not config keys, e.g. a typo.
+var ErrUnknownKey = errors.New("unknown config key")
+
+// strictDoc is the document decoded in strict mode: the config keys and
+// those read before decoding.
+type strictDoc struct {
+	Config  `yaml:",inline"`
+	Include any `yaml:"include"`
+	Use     any `yaml:"use"`
+}
+
+// unknownField matches the errors of yaml.Decoder.KnownFields.
+var unknownField = regexp.MustCompile(`^(line \d+: )?field (\S+) not found in type [\w.]+\.(\w+)$`)
+
+// decode unmarshals the YAML document data into cfg. Keys that are not
+// config keys are ignored, unless strict is set or the document sets
+// strict: true: then they are refused with ErrUnknownKey.
+func decode(data []byte, cfg *Config, strict bool) error {
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("unmarshal yaml: %w", err)
+	}
+	if !strict && !cfg.Strict {
+		return nil
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	var doc strictDoc
+	err := dec.Decode(&doc)
+	var typeErr *yaml.TypeError
+	if err == nil || errors.Is(err, io.EOF) || !errors.As(err, &typeErr) {
+		return nil // other errors were reported by Unmarshal
+	}
+	msgs := make([]string, len(typeErr.Errors))
+	for i, e := range typeErr.Errors {
+		msgs[i] = unknownKeyMessage(e)
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownKey, strings.Join(msgs, "; "))
+}
+
+// unknownKeyMessage rewrites an error of yaml.Decoder.KnownFields with the
+// section of the key and the closest config key. Line numbers are left
+// out: merged files and profiles are decoded from a rebuilt document.
+func unknownKeyMessage(e string) string {
+	m := unknownField.FindStringSubmatch(e)
+	if m == nil {
+		return e
+	}
+	key, typeName := m[2], m[3]
+	t, sections := sectionsOf(typeName)
+	where := "at the top level"
+	if typeName != "strictDoc" {
+		if len(sections) == 0 {
+			return e
+		}
+		where = "in " + strings.Join(sections, ", ")
+	}
+	msg := fmt.Sprintf("%q %s", key, where)
+	if best := closestKey(key, yamlKeys(t)); best != "" {
+		msg += fmt.Sprintf(" (did you mean %q?)", best)
+	}
+	return msg
+}
+
+// sectionsOf returns the struct type named name among those of Config and
+// the key paths where it is used, list items as "[]".
+func sectionsOf(name string) (reflect.Type, []string) {
+	var found reflect.Type
+	var paths []string
+	var walk func(t reflect.Type, path string)
+	walk = func(t reflect.Type, path string) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+			if t.Kind() == reflect.Slice {
+				path += "[]"
+			}
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		if t.Name() == name {
+			found = t
+			paths = append(paths, path)
+		}
+		for i := range t.NumField() {
+			key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+			if key != "" && key != "-" {
+				walk(t.Field(i).Type, strings.TrimPrefix(path+"."+key, "."))
+			}
+		}
+	}
+	walk(reflect.TypeOf(Config{}), "")
+	if name == "strictDoc" {
+		found = reflect.TypeOf(strictDoc{})
+	}
+	return found, paths
+}
+
+// yamlKeys returns the keys of the struct t, with those of inlined fields
+// and the unit aliases of suffixed keys.
+func yamlKeys(t reflect.Type) []string {
+	if t == nil {
+		return nil
+	}
+	var keys []string
+	for i := range t.NumField() {
+		key, opts, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		switch {
+		case opts == "inline":
+			keys = append(keys, yamlKeys(t.Field(i).Type)...)
+		case key != "" && key != "-":
+			keys = append(keys, key)
+			if alias, _, ok := unitAlias(key); ok {
+				keys = append(keys, alias)
+			}
+		}
+	}
+	return keys
+}
+
+// closestKey returns the key of keys nearest to key, if it is a likely
+// typo: at most 2 edits, or 1 for short keys.
+func closestKey(key string, keys []string) string {
+	best, bestDist := "", 3
+	if len(key) < 5 {
+		bestDist = 2
+	}
+	for _, k := range keys {
+		if d := editDistance(key, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
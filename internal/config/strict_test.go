@@ -0,0 +1,79 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ---------- strict: ----------
+
+func TestLoad_Strict(t *testing.T) {
+	cases := []struct {
+		name, old, new, want string
+	}{
+		{"typo", "overlap_percent:", "overlap_pecent:", `"overlap_pecent" in defaults (did you mean "overlap_percent"?)`},
+		{"top_level", "mock_gpio: true", "mock_gpio: true\ndark_frame:\n  count: 2", `"dark_frame" at the top level (did you mean "dark_frames"?)`},
+		{"shared_type", "step_pin: 17", "step_pn: 17", `"step_pn" in pan_stepper, tilt_stepper (did you mean "step_pin"?)`},
+		{"unit_alias", "focus_pin: 24", "focus_pin: 24\n  focus_dealy: 1s", `"focus_dealy" in camera (did you mean "focus_delay"?)`},
+		{"no_suggestion", "mock_gpio: true", "mock_gpio: true\n  colour: blue", `"colour" in defaults`},
+		{"list_item", "mock_gpio: true", "mock_gpio: true\npanoramas:\n  - name: left\n    pan_centre_deg: 10", `"pan_centre_deg" in panoramas[] (did you mean "pan_center_deg"?)`},
+		{"profile", "mock_gpio: true", "mock_gpio: true\nprofiles:\n  tele:\n    lens:\n      focal_lenght_mm: 200", `profile tele: unknown config key: "focal_lenght_mm" in lens (did you mean "focal_length_mm"?)`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			yaml := strings.Replace(validYAML, tc.old, tc.new, 1)
+			if _, err := Load(writeConfig(t, yaml)); err != nil {
+				t.Fatalf("Load without strict: %v", err)
+			}
+			_, err := Load(writeConfig(t, "strict: true\n"+yaml))
+			if !errors.Is(err, ErrUnknownKey) || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Load = %v, want ErrUnknownKey with %q", err, tc.want)
+			}
+			if _, err := LoadStrict(writeConfig(t, yaml)); !errors.Is(err, ErrUnknownKey) {
+				t.Errorf("LoadStrict = %v, want ErrUnknownKey", err)
+			}
+		})
+	}
+}
+
+func TestLoad_StrictValid(t *testing.T) {
+	yaml := "strict: true\nversion: 1\n" + strings.Replace(validYAML, "focal_length_mm: 35.0", "focal_length: 35mm", 1)
+	path := writeConfig(t, yaml)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !cfg.Strict || cfg.Lens.FocalLengthMm != 35 {
+		t.Errorf("strict = %t, focal length = %v", cfg.Strict, cfg.Lens.FocalLengthMm)
+	}
+
+	// Merged files are checked as a whole, include: and use: are known keys
+	site := filepath.Join(filepath.Dir(path), "site.yaml")
+	writeFiles(t, filepath.Dir(path), map[string]string{"site.yaml": "strict: true\ninclude: [test.yaml]\ndefaults:\n  overlap_percent: 40\n"})
+	if _, err := Load(site); err != nil {
+		t.Errorf("Load(site) = %v", err)
+	}
+	writeFiles(t, filepath.Dir(path), map[string]string{"base.yaml": strings.Replace(validYAML, "overlap_percent:", "overlap_pecent:", 1)})
+	writeFiles(t, filepath.Dir(path), map[string]string{"site.yaml": "strict: true\ninclude: [base.yaml]\n"})
+	if _, err := Load(site); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Load(site) = %v, want the typo of the included file refused", err)
+	}
+}
+
+func TestClosestKey(t *testing.T) {
+	keys := []string{"overlap_percent", "mode", "move_speed_ms", "move_speed"}
+	cases := []struct{ key, want string }{
+		{"overlap_pecent", "overlap_percent"},
+		{"mdoe", ""}, // 2 edits on a short key
+		{"mod", "mode"},
+		{"move_sped", "move_speed"},
+		{"exposure", ""},
+	}
+	for _, tc := range cases {
+		if got := closestKey(tc.key, keys); got != tc.want {
+			t.Errorf("closestKey(%q) = %q, want %q", tc.key, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A key named after its unit (focus_delay_ms, focal_length_mm,
+// horizontal_angle_deg) may also be set without the unit suffix, the unit
+// then written in the value: focus_delay: 1.5s, focal_length: 3.5cm,
+// horizontal_angle: 0.5rad. Parse converts such values to the suffixed key.
+// defaults.move_speed also takes an angular speed of the pan axis, e.g.
+// 5deg/s, converted to a delay between motor steps.
+//
+// unitSuffixes maps the suffixes to the parser of their values, which
+// returns the value in that unit.
+var unitSuffixes = []struct {
+	suffix  string
+	parse   func(string) (float64, error)
+	example string
+	pattern string
+}{
+	{"ms", parseMillis, "500ms", `^([0-9]*\.?[0-9]+(ns|us|µs|ms|s|m|h))+$`},
+	{"mm", parseMillimeters, "35mm", `^[0-9]*\.?[0-9]+ ?(mm|cm|m|in)$`},
+	{"deg", parseDegrees, "180deg", `^[-+]?[0-9]*\.?[0-9]+ ?(deg|°|rad)$`},
+}
+
+// moveSpeedKey is the unit-less key of defaults.move_speed_ms.
+const moveSpeedKey = "defaults.move_speed"
+
+// quantity splits values such as "35mm" or "3.5 cm".
+var quantity = regexp.MustCompile(`^([-+]?[0-9]*\.?[0-9]+(?:[eE][-+]?[0-9]+)?) ?([^0-9 ]+)$`)
+
+func parseQuantity(s string, units map[string]float64) (float64, error) {
+	m := quantity.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	factor, ok := units[m[2]]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q in %q", m[2], s)
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v * factor, nil
+}
+
+func parseMillis(s string) (float64, error) {
+	d, err := time.ParseDuration(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q (e.g. 500ms, 1.5s, 2m)", s)
+	}
+	return float64(d) / float64(time.Millisecond), nil
+}
+
+func parseMillimeters(s string) (float64, error) {
+	return parseQuantity(s, map[string]float64{"mm": 1, "cm": 10, "m": 1000, "in": 25.4})
+}
+
+func parseDegrees(s string) (float64, error) {
+	return parseQuantity(s, map[string]float64{"deg": 1, "°": 1, "rad": 180 / math.Pi})
+}
+
+// unitAlias returns the unit-less key of key and its unit suffix, if key
+// ends in one of unitSuffixes.
+func unitAlias(key string) (alias, suffix string, ok bool) {
+	for _, u := range unitSuffixes {
+		if base, found := strings.CutSuffix(key, "_"+u.suffix); found && base != "" {
+			return base, u.suffix, true
+		}
+	}
+	return "", "", false
+}
+
+// unitForms returns the other keys that may set the same value as key: its
+// unit alias, or its suffixed keys. A merged file setting key replaces them.
+func unitForms(key string) []string {
+	if alias, _, ok := unitAlias(key); ok {
+		return []string{alias}
+	}
+	forms := make([]string, 0, len(unitSuffixes))
+	for _, u := range unitSuffixes {
+		forms = append(forms, key+"_"+u.suffix)
+	}
+	return forms
+}
+
+// normalizeUnits rewrites the unit-less keys of the YAML document data to
+// their suffixed keys. Documents that are not a mapping are returned as is,
+// for Parse to report.
+func normalizeUnits(data []byte) ([]byte, error) {
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil || doc == nil {
+		return data, nil
+	}
+	n := unitNormalizer{root: doc}
+	if err := n.section(doc, reflect.TypeOf(Config{}), ""); err != nil {
+		return nil, err
+	}
+	if !n.changed {
+		return data, nil
+	}
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal yaml: %w", err)
+	}
+	return out, nil
+}
+
+type unitNormalizer struct {
+	root    map[string]any
+	changed bool
+}
+
+// section normalizes doc, a section of struct type t at key path path.
+func (n *unitNormalizer) section(doc map[string]any, t reflect.Type, path string) error {
+	fields := map[string]reflect.Type{}
+	for i := range t.NumField() {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if key != "" && key != "-" {
+			fields[key] = t.Field(i).Type
+		}
+	}
+	for key, v := range doc {
+		if ft, ok := fields[key]; ok {
+			if err := n.value(v, ft, joinKey(path, key)); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, u := range unitSuffixes {
+			full := key + "_" + u.suffix
+			ft, ok := fields[full]
+			if !ok {
+				continue
+			}
+			if _, both := doc[full]; both {
+				return fmt.Errorf("%s: set either %s or %s", path, key, full)
+			}
+			converted, err := n.convert(v, joinKey(path, key), u.suffix, u.parse, u.example)
+			if err != nil {
+				return err
+			}
+			if ft.Kind() == reflect.Int {
+				rounded := math.Round(converted)
+				if rounded == 0 && converted != 0 {
+					return fmt.Errorf("%s: %v is below 1 %s", joinKey(path, key), v, u.suffix)
+				}
+				doc[full] = int(rounded)
+			} else {
+				doc[full] = converted
+			}
+			delete(doc, key)
+			n.changed = true
+			break
+		}
+	}
+	return nil
+}
+
+// value normalizes the sections found in v, of type t.
+func (n *unitNormalizer) value(v any, t reflect.Type, path string) error {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if sub, ok := v.(map[string]any); ok {
+			return n.section(sub, t, path)
+		}
+	case reflect.Slice:
+		items, _ := v.([]any)
+		for i, item := range items {
+			if err := n.value(item, t.Elem(), joinKey(path, strconv.Itoa(i))); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// profiles: each entry holds sections of the file.
+		entries, _ := v.(map[string]any)
+		for name, entry := range entries {
+			if sub, ok := entry.(map[string]any); ok && path == "profiles" {
+				if err := n.section(sub, reflect.TypeOf(Config{}), joinKey(path, name)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// convert parses v, the value of the unit-less key path.
+func (n *unitNormalizer) convert(v any, path, suffix string, parse func(string) (float64, error), example string) (float64, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("%s: %v needs a unit, e.g. %s (or set %s_%s)", path, v, example, path, suffix)
+	}
+	if strings.HasSuffix(path, moveSpeedKey) {
+		if speed, ok := strings.CutSuffix(s, "/s"); ok {
+			return n.stepDelay(path, s, speed)
+		}
+	}
+	value, err := parse(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return value, nil
+}
+
+// stepDelay converts speed, an angle per second of the pan axis, to the
+// delay between two motor steps in ms.
+func (n *unitNormalizer) stepDelay(path, value, speed string) (float64, error) {
+	deg, err := parseDegrees(speed)
+	if err != nil || deg <= 0 {
+		return 0, fmt.Errorf("%s: invalid speed %q (e.g. 5deg/s)", path, value)
+	}
+	steps, micro := 200.0, 16.0 // Parse's defaults
+	if pan, ok := n.root["pan_stepper"].(map[string]any); ok {
+		if v, ok := pan["steps_per_rev"].(int); ok && v > 0 {
+			steps = float64(v)
+			micro = 1
+		}
+		if v, ok := pan["microstepping"].(int); ok && v > 0 {
+			micro = float64(v)
+		}
+	}
+	return 1000 / (deg * steps * micro / 360), nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ---------- Unit-aware values ----------
+
+func TestLoad_UnitValues(t *testing.T) {
+	yaml := strings.NewReplacer(
+		"focal_length_mm: 35.0", "focal_length: 3.5cm",
+		"focus_pin: 24", "focus_pin: 24\n  focus_delay: 1.5s\n  exposure_time: 2m",
+		"horizontal_angle_deg: 180.0", "horizontal_angle: 0.5rad",
+		"vertical_angle_deg: 30.0", "vertical_angle: 45°",
+		"move_speed_ms: 2", "move_speed: 5deg/s",
+	).Replace(validYAML) + `panoramas:
+  - name: facade
+    horizontal_angle: 90deg
+    pan_center: -60 deg
+profiles:
+  tele:
+    lens:
+      focal_length: 200mm
+`
+	cfg, err := Load(writeConfig(t, yaml))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Lens.FocalLengthMm != 35 {
+		t.Errorf("focal_length_mm = %v, want 35", cfg.Lens.FocalLengthMm)
+	}
+	if cfg.Camera.FocusDelayMs != 1500 || cfg.Camera.ExposureTimeMs != 120000 {
+		t.Errorf("focus_delay_ms = %d, exposure_time_ms = %d, want 1500 and 120000", cfg.Camera.FocusDelayMs, cfg.Camera.ExposureTimeMs)
+	}
+	if math.Abs(cfg.Defaults.HorizontalAngleDeg-28.6479) > 1e-3 || cfg.Defaults.VerticalAngleDeg != 45 {
+		t.Errorf("angles = %v, %v, want 28.648 and 45", cfg.Defaults.HorizontalAngleDeg, cfg.Defaults.VerticalAngleDeg)
+	}
+	// 5°/s with 200 steps × 16 microsteps per turn: 44.4 steps/s.
+	if cfg.Defaults.MoveSpeedMs != 23 {
+		t.Errorf("move_speed_ms = %d, want 23", cfg.Defaults.MoveSpeedMs)
+	}
+	if p := cfg.Panoramas[0]; p.HorizontalAngleDeg != 90 || p.PanCenterDeg != -60 {
+		t.Errorf("panorama = %+v", p)
+	}
+	tele, err := cfg.WithProfile("tele")
+	if err != nil || tele.Lens.FocalLengthMm != 200 {
+		t.Errorf("tele = %v, %v, want 200 mm", tele, err)
+	}
+}
+
+func TestLoad_UnitValuesInclude(t *testing.T) {
+	path := writeConfig(t, "include: [base/rig.yaml]\nlens:\n  focal_length: 5cm\ndefaults:\n  horizontal_angle_deg: 90\n")
+	writeFiles(t, filepath.Dir(path), map[string]string{
+		"base/rig.yaml": strings.Replace(validYAML, "horizontal_angle_deg: 180.0", "horizontal_angle: 1rad", 1),
+	})
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Lens.FocalLengthMm != 50 || cfg.Defaults.HorizontalAngleDeg != 90 {
+		t.Errorf("focal length = %v, angle = %v, want the including file's 50 and 90", cfg.Lens.FocalLengthMm, cfg.Defaults.HorizontalAngleDeg)
+	}
+}
+
+func TestLoad_UnitMoveSpeed(t *testing.T) {
+	cases := []struct {
+		name  string
+		speed string
+		want  int
+	}{
+		{"duration", "2ms", 2},
+		{"pan_steps", "10deg/s", 180}, // 200 full steps per turn: 5.56 steps/s
+		{"radians", "0.1rad/s", 314},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			yaml := strings.Replace(validYAML, "move_speed_ms: 2", "move_speed: "+tc.speed, 1)
+			yaml = strings.Replace(yaml, "microstepping: 16", "microstepping: 1", 1)
+			cfg, err := Load(writeConfig(t, yaml))
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.Defaults.MoveSpeedMs != tc.want {
+				t.Errorf("move_speed_ms = %d, want %d", cfg.Defaults.MoveSpeedMs, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoad_UnitValueErrors(t *testing.T) {
+	cases := []struct {
+		name, old, new, want string
+	}{
+		{"both_keys", "focal_length_mm: 35.0", "focal_length_mm: 35.0\n  focal_length: 35mm", "set either focal_length or focal_length_mm"},
+		{"no_unit", "focal_length_mm: 35.0", "focal_length: 35", "lens.focal_length: 35 needs a unit, e.g. 35mm"},
+		{"unknown_unit", "focal_length_mm: 35.0", "focal_length: 35px", `unknown unit "px"`},
+		{"bad_duration", "focus_pin: 24", "focus_pin: 24\n  focus_delay: soon", "camera.focus_delay: invalid duration"},
+		{"below_one_ms", "focus_pin: 24", "focus_pin: 24\n  focus_delay: 100us", "below 1 ms"},
+		{"bad_speed", "move_speed_ms: 2", "move_speed: fast/s", "defaults.move_speed: invalid speed"},
+		{"in_profile", "mock_gpio: true", "mock_gpio: true\nprofiles:\n  tele:\n    lens:\n      focal_length: 200", "profiles.tele.lens.focal_length"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, strings.Replace(validYAML, tc.old, tc.new, 1)))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Load = %v, want an error with %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetValues_ReplacesUnitAlias(t *testing.T) {
+	path := writeConfig(t, strings.Replace(validYAML, "focal_length_mm: 35.0", "focal_length: 35mm", 1))
+	if err := SetValues(path, "", map[string]map[string]any{"lens": {"focal_length_mm": 50.0}}); err != nil {
+		t.Fatalf("SetValues: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil || cfg.Lens.FocalLengthMm != 50 {
+		t.Errorf("Load = %v, %v, want 50 mm", cfg, err)
+	}
+}
+
+func TestSchema_UnitAliases(t *testing.T) {
+	props := Schema()["properties"].(map[string]any)
+	camera := props["camera"].(map[string]any)["properties"].(map[string]any)
+	alias, ok := camera["focus_delay"].(map[string]any)
+	if !ok || alias["type"] != "string" || !strings.Contains(alias["description"].(string), "focus_delay_ms") {
+		t.Errorf("camera.focus_delay = %v", camera["focus_delay"])
+	}
+	defaults := props["defaults"].(map[string]any)["properties"].(map[string]any)
+	if pattern := defaults["move_speed"].(map[string]any)["pattern"].(string); !strings.Contains(pattern, "/s") {
+		t.Errorf("defaults.move_speed pattern = %q, want angular speeds", pattern)
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetValues writes values into the config file path, by section then key as
+// in the file (e.g. values["lens"]["focal_length_mm"]), and keeps its other
+// keys, include: list and use: section. With entry, the values go to that
+// entry of the profiles: section instead, which is created if missing. Keys
+// of an older layout are renamed (see CurrentVersion). The file is written
+// only if it still loads; comments are not preserved.
+func SetValues(path, entry string, values map[string]map[string]any) error {
+	if err := ValidateConfigPath(path); err != nil {
+		return err
+	}
+	root, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("resolve config path: %w", err)
+	}
+	data, _, err := readDocument(path)
+	if err != nil {
+		return err
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("unmarshal yaml: %w", err)
+	}
+	if doc == nil {
+		doc = map[string]any{}
+	}
+	if _, err := migrateDocument(doc); err != nil {
+		return err
+	}
+	doc["version"] = CurrentVersion()
+
+	target := doc
+	if entry != "" {
+		target = section(section(doc, "profiles"), entry)
+	}
+	for name, keys := range values {
+		if entry != "" && !slices.Contains(ProfileSections, name) {
+			return fmt.Errorf("profiles: %s: %s cannot be set by a profile (only %s)", entry, name, strings.Join(ProfileSections, ", "))
+		}
+		sec := section(target, name)
+		for key, v := range keys {
+			sec[key] = v
+			if alias, _, ok := unitAlias(key); ok {
+				delete(sec, alias)
+			}
+		}
+	}
+
+	if data, err = yaml.Marshal(doc); err != nil {
+		return fmt.Errorf("marshal yaml: %w", err)
+	}
+	var head docHead
+	if err := yaml.Unmarshal(data, &head); err != nil {
+		return fmt.Errorf("unmarshal yaml: %w", err)
+	}
+	merged := data
+	if !head.empty() {
+		if merged, _, _, err = mergeConfig(root, path, data, head); err != nil {
+			return err
+		}
+	}
+	if _, err := parse(merged, false, readSecret); err != nil {
+		return err
+	}
+	if data, err = fromYAML(filepath.Ext(path), data); err != nil {
+		return err
+	}
+	return writeFile(path, data)
+}
+
+// section returns the section name of doc, adding an empty one when it is
+// missing or not a mapping.
+func section(doc map[string]any, name string) map[string]any {
+	sec, ok := doc[name].(map[string]any)
+	if !ok {
+		sec = map[string]any{}
+		doc[name] = sec
+	}
+	return sec
+}
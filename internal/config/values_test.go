@@ -0,0 +1,142 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ---------- SetValues ----------
+
+func TestSetValues(t *testing.T) {
+	path := writeConfig(t, validYAML)
+	values := map[string]map[string]any{
+		"defaults": {"horizontal_angle_deg": 270.0, "stabilization_delay_ms": 900},
+		"lens":     {"focal_length_mm": 50.0},
+	}
+	if err := SetValues(path, "", values); err != nil {
+		t.Fatalf("SetValues: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Defaults.HorizontalAngleDeg != 270 || cfg.Defaults.StabilizationDelayMs != 900 || cfg.Lens.FocalLengthMm != 50 {
+		t.Errorf("values not written: %+v, lens %+v", cfg.Defaults, cfg.Lens)
+	}
+	if cfg.Lens.Name != "Nikkor 35mm" || cfg.PanStepper.StepPin != 17 || !cfg.Defaults.MockGPIO {
+		t.Errorf("other keys not kept: %+v", cfg)
+	}
+	if _, err := os.Stat(path + ".bak"); err != nil {
+		t.Errorf("no backup: %v", err)
+	}
+}
+
+func TestSetValues_ProfileEntry(t *testing.T) {
+	path := writeConfig(t, validYAML+`profiles:
+  tele:
+    lens:
+      name: "Tele"
+      focal_length_mm: 200.0
+`)
+	values := map[string]map[string]any{"lens": {"focal_length_mm": 180.0}, "defaults": {"vertical_angle_deg": 20.0}}
+	if err := SetValues(path, "tele", values); err != nil {
+		t.Fatalf("SetValues tele: %v", err)
+	}
+	if err := SetValues(path, "wide", map[string]map[string]any{"lens": {"focal_length_mm": 18.0}}); err != nil {
+		t.Fatalf("SetValues wide: %v", err)
+	}
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Lens.FocalLengthMm != 35 || cfg.Defaults.VerticalAngleDeg != 30 {
+		t.Errorf("base changed: lens %v, vertical %v", cfg.Lens.FocalLengthMm, cfg.Defaults.VerticalAngleDeg)
+	}
+	tele, err := cfg.WithProfile("tele")
+	if err != nil {
+		t.Fatalf("WithProfile tele: %v", err)
+	}
+	if tele.Lens.FocalLengthMm != 180 || tele.Lens.Name != "Tele" || tele.Defaults.VerticalAngleDeg != 20 {
+		t.Errorf("tele = lens %+v, vertical %v", tele.Lens, tele.Defaults.VerticalAngleDeg)
+	}
+	wide, err := cfg.WithProfile("wide")
+	if err != nil {
+		t.Fatalf("WithProfile wide: %v", err)
+	}
+	if wide.Lens.FocalLengthMm != 18 {
+		t.Errorf("wide focal length = %v, want 18", wide.Lens.FocalLengthMm)
+	}
+}
+
+func TestSetValues_Include(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "configs")
+	writeFiles(t, dir, map[string]string{
+		"base.yaml": validYAML,
+		"main.yaml": "include: [base.yaml]\ndefaults:\n  overlap_percent: 40\n",
+	})
+	path := filepath.Join(dir, "main.yaml")
+	if err := SetValues(path, "", map[string]map[string]any{"lens": {"focal_length_mm": 24.0}}); err != nil {
+		t.Fatalf("SetValues: %v", err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Lens.FocalLengthMm != 24 || cfg.Defaults.OverlapPercent != 40 || len(cfg.Sources) != 2 {
+		t.Errorf("focal length %v, overlap %v, sources %v", cfg.Lens.FocalLengthMm, cfg.Defaults.OverlapPercent, cfg.Sources)
+	}
+	if !bytes.Equal(mustRead(t, filepath.Join(dir, "base.yaml")), []byte(validYAML)) {
+		t.Error("included file changed")
+	}
+}
+
+func TestSetValues_KeepsFormat(t *testing.T) {
+	yamlPath := writeConfig(t, validYAML)
+	cfg, err := Load(yamlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(filepath.Dir(yamlPath), "rig.json")
+	if err := Save(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetValues(path, "", map[string]map[string]any{"defaults": {"overlap_percent": 45.0}}); err != nil {
+		t.Fatalf("SetValues: %v", err)
+	}
+	if !json.Valid(mustRead(t, path)) {
+		t.Errorf("rig.json is no longer JSON:\n%s", mustRead(t, path))
+	}
+	if cfg, err = Load(path); err != nil || cfg.Defaults.OverlapPercent != 45 {
+		t.Errorf("Load = %v, %v", cfg, err)
+	}
+}
+
+func TestSetValues_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		entry  string
+		values map[string]map[string]any
+		want   string
+	}{
+		{"invalid_value", "", map[string]map[string]any{"defaults": {"overlap_percent": 150.0}}, "overlap_percent"},
+		{"invalid_in_profile", "tele", map[string]map[string]any{"lens": {"focal_length_mm": -1.0}}, "tele"},
+		{"section_not_in_profile", "tele", map[string]map[string]any{"pan_stepper": {"step_pin": 4}}, "cannot be set by a profile"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, validYAML)
+			err := SetValues(path, tc.entry, tc.values)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("SetValues = %v, want an error about %q", err, tc.want)
+			}
+			if string(mustRead(t, path)) != validYAML {
+				t.Error("file changed despite the error")
+			}
+		})
+	}
+}
@@ -1,10 +1,19 @@
+// Package debug is the debug output of PanGo: structured log records
+// (log/slog) filtered by the debug level of the config, 0 to 4, or by the
+// level set for their module (see Module).
 package debug
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"maps"
 	"os"
+	"slices"
+	"strings"
+	"sync/atomic"
 )
 
 // Debug levels
@@ -16,29 +25,152 @@ const (
 	LevelTrace   = 4 // Trace (GPIO, very low level)
 )
 
+// slog levels of the debug levels: level 1 logs at slog.LevelInfo, 2 at
+// SlogLive, 3 at slog.LevelDebug and 4 at SlogTrace. Errors log at
+// slog.LevelError from level 1. SlogOff is above every record.
+const (
+	SlogLive  = slog.Level(-2)
+	SlogTrace = slog.Level(-8)
+	SlogOff   = slog.Level(12)
+)
+
+// levelNames are the names of the levels in the output, after the tags of
+// the printf-style output they replace.
+var levelNames = map[slog.Level]string{
+	SlogTrace:       "TRACE",
+	slog.LevelDebug: "VERBOSE",
+	SlogLive:        "LIVE",
+	slog.LevelInfo:  "INFO",
+	slog.LevelWarn:  "WARN",
+	slog.LevelError: "ERROR",
+	SlogOff:         "OFF",
+}
+
+// Modules are the subsystems of the debug output, whose level can be set
+// apart from the global one (SetModuleLevels). Package-level functions log
+// as module "pango", the command.
+var Modules = []string{"camera", "capture", "gpio", "pango", "stepper", "trigger", "web"}
+
+// DefaultLevel is the level name returning a module to the global level
+// (SetModuleLevels).
+const DefaultLevel = "default"
+
 var (
-	level  int
-	logger *log.Logger
+	level        int
+	logger       atomic.Pointer[slog.Logger]           // nil before Init
+	moduleLevels atomic.Pointer[map[string]slog.Level] // replaced, never modified; nil = none
+	pango        = For("pango")
 )
 
-// Init initializes the debug system with a level (0-4).
+// Init initializes the debug system with a level (0-4), writing to stdout.
 // 0 = no output
 // 1 = important info (grid, total photo count)
 // 2 = live info (movements, photos taken)
 // 3 = verbose (calculation details, steps, FOV, angles)
 // 4 = trace (GPIO, very low level)
+// Module levels are kept: see SetModuleLevels.
 func Init(debugLevel int) {
 	level = debugLevel
-	if level > LevelOff {
-		logger = log.New(os.Stdout, "[PanGo] ", log.LstdFlags|log.Lmicroseconds)
+	SetOutput(os.Stdout)
+}
+
+// SetOutput sets the output writer for debug logs (e.g. io.MultiWriter for
+// stdout + SSE). Records are written one per line, as slog text, and also
+// passed to the handlers of extra (e.g. the systemd journal).
+func SetOutput(w io.Writer, extra ...slog.Handler) {
+	var h slog.Handler = slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level:       SlogTrace, // filtered by module, see Module.Enabled
+		ReplaceAttr: replaceLevel,
+	})
+	if len(extra) > 0 {
+		h = fanout(append([]slog.Handler{h}, extra...))
+	}
+	logger.Store(slog.New(h))
+}
+
+// fanout is a slog.Handler passing records to several handlers.
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, lvl slog.Level) bool {
+	return slices.ContainsFunc(f, func(h slog.Handler) bool { return h.Enabled(ctx, lvl) })
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := make(fanout, len(f))
+	for i, h := range f {
+		next[i] = h.WithAttrs(attrs)
 	}
+	return next
 }
 
-// SetOutput sets the output writer for debug logs (e.g. io.MultiWriter for stdout + SSE).
-func SetOutput(w io.Writer) {
-	if logger != nil {
-		logger.SetOutput(w)
+func (f fanout) WithGroup(name string) slog.Handler {
+	next := make(fanout, len(f))
+	for i, h := range f {
+		next[i] = h.WithGroup(name)
 	}
+	return next
+}
+
+// SlogLevel returns the least severe slog level logged at debugLevel.
+func SlogLevel(debugLevel int) slog.Level {
+	switch {
+	case debugLevel <= LevelOff:
+		return SlogOff
+	case debugLevel >= LevelTrace:
+		return SlogTrace
+	case debugLevel == LevelVerbose:
+		return slog.LevelDebug
+	case debugLevel == LevelLive:
+		return SlogLive
+	}
+	return slog.LevelInfo
+}
+
+// ParseLevel returns the level of a name: trace, verbose (or debug), live,
+// info, warn, error or off, in any case.
+func ParseLevel(name string) (slog.Level, error) {
+	name = strings.ToUpper(name)
+	if name == "DEBUG" {
+		return slog.LevelDebug, nil
+	}
+	for lvl, n := range levelNames {
+		if n == name {
+			return lvl, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q (want trace, verbose, live, info, warn, error or off)", strings.ToLower(name))
+}
+
+// LevelName returns the name of lvl for ParseLevel, e.g. "trace".
+func LevelName(lvl slog.Level) string {
+	if name, ok := levelNames[lvl]; ok {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(lvl.String())
+}
+
+// replaceLevel writes the levels by their names in levelNames.
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.LevelKey || len(groups) > 0 {
+		return a
+	}
+	// GPIO records also have a level attribute: the pin level, an int.
+	if lvl, ok := a.Value.Any().(slog.Level); ok {
+		if name, ok := levelNames[lvl]; ok {
+			a.Value = slog.StringValue(name)
+		}
+	}
+	return a
 }
 
 // Level returns the current debug level.
@@ -51,147 +183,193 @@ func IsEnabled(minLevel int) bool {
 	return level >= minLevel
 }
 
-// --- Level 1 functions (Info): important info ---
-
-// Info prints a level 1 message (important info).
-func Info(format string, args ...interface{}) {
-	if level >= LevelInfo && logger != nil {
-		logger.Printf("[INFO] "+format, args...)
+// CheckModuleLevels returns an error naming the first unknown module or
+// level of levels, module name to level name (see SetModuleLevels).
+func CheckModuleLevels(levels map[string]string) error {
+	for _, module := range slices.Sorted(maps.Keys(levels)) {
+		if !slices.Contains(Modules, module) {
+			return fmt.Errorf("unknown log module %q (want %s)", module, strings.Join(Modules, ", "))
+		}
+		if name := levels[module]; name != DefaultLevel {
+			if _, err := ParseLevel(name); err != nil {
+				return fmt.Errorf("log module %s: %w", module, err)
+			}
+		}
 	}
+	return nil
 }
 
-// Summary prints an important summary (level 1).
-func Summary(title string) {
-	if level >= LevelOff && logger != nil {
-		logger.Printf("═══════════════════════════════════════")
-		logger.Printf("  %s", title)
-		logger.Printf("═══════════════════════════════════════")
+// SetModuleLevels sets the level of the modules of levels, module name to
+// level name (see ParseLevel), e.g. {"gpio": "trace", "web": "warn"}; the
+// others keep theirs. DefaultLevel returns a module to the global level.
+// Nothing is changed when a module or level is unknown.
+func SetModuleLevels(levels map[string]string) error {
+	if err := CheckModuleLevels(levels); err != nil {
+		return err
+	}
+	next := map[string]slog.Level{}
+	if cur := moduleLevels.Load(); cur != nil {
+		maps.Copy(next, *cur)
 	}
+	for module, name := range levels {
+		if name == DefaultLevel {
+			delete(next, module)
+			continue
+		}
+		next[module], _ = ParseLevel(name)
+	}
+	moduleLevels.Store(&next)
+	return nil
+}
+
+// ResetModuleLevels returns every module to the global level.
+func ResetModuleLevels() {
+	moduleLevels.Store(nil)
 }
 
-// Grid prints important grid info (level 1).
-func Grid(columns, rows, totalPhotos int) {
-	if level >= LevelInfo && logger != nil {
-		logger.Printf("[INFO] Grid: %d columns x %d rows = %d photos total", columns, rows, totalPhotos)
+// ModuleLevels returns the level name of every module, the global level
+// for those without their own.
+func ModuleLevels() map[string]string {
+	levels := make(map[string]string, len(Modules))
+	for _, module := range Modules {
+		levels[module] = LevelName(For(module).level())
 	}
+	return levels
 }
 
-// --- Level 2 functions (Live): real-time info ---
+// --- Modules ---
 
-// Live prints a level 2 message (live info).
-func Live(format string, args ...interface{}) {
-	if level >= LevelLive && logger != nil {
-		logger.Printf("[LIVE] "+format, args...)
-	}
+// Module is the debug output of a subsystem (see Modules): its records have
+// a module attribute and are logged from the level set for the module, or
+// the global level.
+type Module struct {
+	name string
 }
 
-// Move prints a motor movement (level 2).
-func Move(motor string, steps int, direction string) {
-	if level >= LevelLive && logger != nil {
-		logger.Printf("[LIVE] Motor %s: %d steps (%s)", motor, steps, direction)
-	}
+// For returns the debug output of module, one of Modules.
+func For(module string) *Module {
+	return &Module{name: module}
 }
 
-// Shot prints a photo capture (level 2).
-func Shot(col, row int) {
-	if level >= LevelLive && logger != nil {
-		logger.Printf("[LIVE] Photo taken at position (col=%d, row=%d)", col, row)
+// level returns the least severe level m logs.
+func (m *Module) level() slog.Level {
+	if levels := moduleLevels.Load(); levels != nil {
+		if lvl, ok := (*levels)[m.name]; ok {
+			return lvl
+		}
 	}
+	return SlogLevel(level)
+}
+
+// Enabled reports whether m logs records at lvl.
+func (m *Module) Enabled(lvl slog.Level) bool {
+	return lvl >= m.level()
 }
 
-// Column prints the start of a column (level 2).
-func Column(col, totalCols int, direction string) {
-	if level >= LevelLive && logger != nil {
-		logger.Printf("[LIVE] Starting column %d/%d (direction: %s)", col, totalCols, direction)
+// log writes a record at lvl with the key-value pairs of args, as
+// slog.Logger.Log.
+func (m *Module) log(lvl slog.Level, msg string, args ...any) {
+	if !m.Enabled(lvl) {
+		return
+	}
+	if l := logger.Load(); l != nil {
+		l.Log(context.Background(), lvl, msg, append([]any{"module", m.name}, args...)...)
 	}
 }
 
-// --- Level 3 functions (Verbose): everything ---
+// --- Level 1 functions (Info): important info ---
 
-// Verbose prints a level 3 message (verbose).
-func Verbose(format string, args ...interface{}) {
-	if level >= LevelVerbose && logger != nil {
-		logger.Printf("[VERBOSE] "+format, args...)
-	}
+// Info logs important info (level 1), with the key-value pairs of args.
+func (m *Module) Info(msg string, args ...any) {
+	m.log(slog.LevelInfo, msg, args...)
 }
 
-// Print prints a level 3 message (alias for Verbose).
-func Print(format string, args ...interface{}) {
-	Verbose(format, args...)
+// Error logs an error (level 1+), with the key-value pairs of args.
+func (m *Module) Error(msg string, err error, args ...any) {
+	m.log(slog.LevelError, msg, append([]any{"err", err}, args...)...)
 }
 
-// Printf is an alias for Print for compatibility.
-func Printf(format string, args ...interface{}) {
-	Verbose(format, args...)
+// --- Level 2 functions (Live): real-time info ---
+
+// Live logs live info (level 2), with the key-value pairs of args.
+func (m *Module) Live(msg string, args ...any) {
+	m.log(SlogLive, msg, args...)
 }
 
-// Println prints a level 3 message followed by a newline.
-func Println(args ...interface{}) {
-	if level >= LevelVerbose && logger != nil {
-		logger.Println(args...)
-	}
+// Move logs a motor movement (level 2).
+func (m *Module) Move(axis string, steps int, direction string) {
+	m.log(SlogLive, "motor move", "axis", axis, "steps", steps, "direction", direction)
 }
 
-// PrintStruct prints a struct in formatted form (level 3).
-func PrintStruct(name string, v interface{}) {
-	if level >= LevelVerbose && logger != nil {
-		logger.Printf("[VERBOSE] %s: %+v", name, v)
-	}
+// Shot logs a photo capture at a grid cell, counted from 1 (level 2).
+func (m *Module) Shot(col, row int) {
+	m.log(SlogLive, "photo taken", Cell(col, row))
 }
 
-// Section prints a section separator (level 3).
-func Section(name string) {
-	if level >= LevelVerbose && logger != nil {
-		logger.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-		logger.Printf("  %s", name)
-		logger.Printf("━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━")
-	}
+// Column logs the start of a column, counted from 1 (level 2).
+func (m *Module) Column(col, totalCols int, direction string) {
+	m.log(SlogLive, "column started", "column", col, "columns", totalCols, "direction", direction)
 }
 
-// Step prints a numbered step (level 3).
-func Step(num int, description string) {
-	if level >= LevelVerbose && logger != nil {
-		logger.Printf("[VERBOSE] Step %d: %s", num, description)
-	}
+// --- Level 3 functions (Verbose): everything ---
+
+// Verbose logs details (level 3), with the key-value pairs of args.
+func (m *Module) Verbose(msg string, args ...any) {
+	m.log(slog.LevelDebug, msg, args...)
 }
 
-// Value prints a named value in formatted form (level 3).
-func Value(name string, value interface{}) {
-	if level >= LevelInfo && logger != nil {
-		logger.Printf("[INFO]   %s = %v", name, value)
-	}
+// Section logs the start of a stage, e.g. "initialization" (level 3).
+func (m *Module) Section(name string) {
+	m.log(slog.LevelDebug, "section", "name", name)
+}
+
+// Step logs a numbered initialization step (level 3).
+func (m *Module) Step(num int, description string) {
+	m.log(slog.LevelDebug, description, "step", num)
 }
 
 // --- Level 4 functions (Trace): very low level ---
 
-// Trace prints a level 4 message (trace, GPIO).
-func Trace(format string, args ...interface{}) {
-	if level >= LevelTrace && logger != nil {
-		logger.Printf("[TRACE] "+format, args...)
-	}
+// Trace logs low-level operations (level 4), with the key-value pairs of
+// args.
+func (m *Module) Trace(msg string, args ...any) {
+	m.log(SlogTrace, msg, args...)
 }
 
-// GPIO prints a GPIO operation (level 4).
-func GPIO(operation string, pin int, value interface{}) {
-	if level >= LevelTrace && logger != nil {
-		logger.Printf("[GPIO] %s pin=%d value=%v", operation, pin, value)
-	}
+// GPIO logs a GPIO operation on pin (level 4), with the key-value pairs of
+// args (e.g. the level written).
+func (m *Module) GPIO(operation string, pin int, args ...any) {
+	m.log(SlogTrace, "gpio", append([]any{"op", operation, "pin", pin}, args...)...)
 }
 
-// --- General functions ---
+// --- Package-level functions, module "pango" ---
 
-// Error prints a debug error (level 1+).
-func Error(err error) {
-	if level >= LevelInfo && logger != nil {
-		logger.Printf("[ERROR] %v", err)
-	}
-}
+// Info logs important info (level 1), with the key-value pairs of args.
+func Info(msg string, args ...any) { pango.Info(msg, args...) }
 
-// Fmt is a helper function that returns a formatted string
-// only if debug is enabled (to avoid unnecessary allocations).
-func Fmt(format string, args ...interface{}) string {
-	if level > 0 {
-		return fmt.Sprintf(format, args...)
-	}
-	return ""
+// Error logs an error (level 1+), with the key-value pairs of args.
+func Error(msg string, err error, args ...any) { pango.Error(msg, err, args...) }
+
+// Live logs live info (level 2), with the key-value pairs of args.
+func Live(msg string, args ...any) { pango.Live(msg, args...) }
+
+// Verbose logs details (level 3), with the key-value pairs of args.
+func Verbose(msg string, args ...any) { pango.Verbose(msg, args...) }
+
+// Section logs the start of a stage, e.g. "initialization" (level 3).
+func Section(name string) { pango.Section(name) }
+
+// Step logs a numbered initialization step (level 3).
+func Step(num int, description string) { pango.Step(num, description) }
+
+// Trace logs low-level operations (level 4), with the key-value pairs of
+// args.
+func Trace(msg string, args ...any) { pango.Trace(msg, args...) }
+
+// --- Attributes ---
+
+// Cell returns the attribute of a grid cell, counted from 1: cell.col and
+// cell.row in the output.
+func Cell(col, row int) slog.Attr {
+	return slog.Group("cell", "col", col, "row", row)
 }
@@ -0,0 +1,198 @@
+package debug
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// capture sets the debug level and returns the buffer the output goes to.
+func capture(t *testing.T, debugLevel int) *bytes.Buffer {
+	t.Helper()
+	t.Cleanup(func() {
+		Init(LevelOff)
+		ResetModuleLevels()
+	})
+	level = debugLevel
+	ResetModuleLevels()
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	return &buf
+}
+
+// ---------- Levels ----------
+
+func TestSlogLevel(t *testing.T) {
+	cases := []struct {
+		debugLevel int
+		want       slog.Level
+	}{
+		{LevelOff, SlogOff},
+		{LevelInfo, slog.LevelInfo},
+		{LevelLive, SlogLive},
+		{LevelVerbose, slog.LevelDebug},
+		{LevelTrace, SlogTrace},
+		{9, SlogTrace},
+	}
+	for _, c := range cases {
+		if got := SlogLevel(c.debugLevel); got != c.want {
+			t.Errorf("SlogLevel(%d) = %v, want %v", c.debugLevel, got, c.want)
+		}
+	}
+}
+
+func TestLevels_Filter(t *testing.T) {
+	cases := []struct {
+		debugLevel int
+		want       []string
+	}{
+		{LevelInfo, []string{"level=INFO", "level=ERROR"}},
+		{LevelLive, []string{"level=INFO", "level=ERROR", "level=LIVE"}},
+		{LevelVerbose, []string{"level=INFO", "level=ERROR", "level=LIVE", "level=VERBOSE"}},
+		{LevelTrace, []string{"level=INFO", "level=ERROR", "level=LIVE", "level=VERBOSE", "level=TRACE"}},
+	}
+	for _, c := range cases {
+		buf := capture(t, c.debugLevel)
+		Info("info")
+		Error("error", errors.New("boom"))
+		Live("live")
+		Verbose("verbose")
+		Trace("trace")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(c.want) {
+			t.Fatalf("level %d: %d lines, want %d:\n%s", c.debugLevel, len(lines), len(c.want), buf)
+		}
+		for i, want := range c.want {
+			if !strings.Contains(lines[i], want) {
+				t.Errorf("level %d: line %q, want %s", c.debugLevel, lines[i], want)
+			}
+		}
+	}
+}
+
+func TestLevel_Off(t *testing.T) {
+	buf := capture(t, LevelOff)
+	Error("nothing", errors.New("boom"))
+	For("gpio").GPIO("WritePin", 17)
+	if buf.Len() != 0 {
+		t.Errorf("output at level 0: %q", buf.String())
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	cases := []struct {
+		name string
+		want slog.Level
+	}{
+		{"trace", SlogTrace},
+		{"verbose", slog.LevelDebug},
+		{"DEBUG", slog.LevelDebug},
+		{"live", SlogLive},
+		{"Info", slog.LevelInfo},
+		{"warn", slog.LevelWarn},
+		{"error", slog.LevelError},
+		{"off", SlogOff},
+	}
+	for _, c := range cases {
+		got, err := ParseLevel(c.name)
+		if err != nil || got != c.want {
+			t.Errorf("ParseLevel(%q) = %v, %v, want %v", c.name, got, err, c.want)
+		}
+	}
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Error("ParseLevel(loud) succeeded")
+	}
+	if got := LevelName(slog.LevelDebug); got != "verbose" {
+		t.Errorf("LevelName(debug) = %q, want verbose", got)
+	}
+}
+
+// ---------- Modules ----------
+
+func TestSetModuleLevels(t *testing.T) {
+	buf := capture(t, LevelLive)
+	if err := SetModuleLevels(map[string]string{"gpio": "trace", "stepper": "info", "web": "warn"}); err != nil {
+		t.Fatal(err)
+	}
+	For("gpio").GPIO("WritePin", 17, "pin_level", 1)
+	For("stepper").Verbose("stepper move")
+	For("stepper").Info("stepper ready")
+	For("web").Info("client connected")
+	For("capture").Live("dark frame")
+	For("capture").Verbose("aiming")
+
+	want := []string{
+		"msg=gpio module=gpio op=WritePin pin=17 pin_level=1",
+		`msg="stepper ready" module=stepper`,
+		`msg="dark frame" module=capture`,
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("%d lines, want %d:\n%s", len(lines), len(want), buf)
+	}
+	for i := range want {
+		if !strings.Contains(lines[i], want[i]) {
+			t.Errorf("line %q, want %s", lines[i], want[i])
+		}
+	}
+
+	levels := ModuleLevels()
+	if levels["gpio"] != "trace" || levels["web"] != "warn" || levels["capture"] != "live" || len(levels) != len(Modules) {
+		t.Errorf("ModuleLevels() = %v", levels)
+	}
+	if err := SetModuleLevels(map[string]string{"gpio": DefaultLevel}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ModuleLevels()["gpio"]; got != "live" {
+		t.Errorf("gpio level = %q after default, want the global live", got)
+	}
+
+	for _, bad := range []map[string]string{{"motor": "info"}, {"web": "loud"}, {"gpio": "trace", "web": "loud"}} {
+		if err := SetModuleLevels(bad); err == nil {
+			t.Errorf("SetModuleLevels(%v) succeeded", bad)
+		}
+	}
+	if got := ModuleLevels()["gpio"]; got != "live" {
+		t.Errorf("gpio level = %q after a failed update, want it unchanged", got)
+	}
+}
+
+func TestSetOutput_Extra(t *testing.T) {
+	buf := capture(t, LevelLive)
+	var extra bytes.Buffer
+	SetOutput(buf, slog.NewJSONHandler(&extra, &slog.HandlerOptions{Level: SlogTrace}))
+	For("capture").Shot(1, 2)
+	if !strings.Contains(buf.String(), `msg="photo taken" module=capture`) {
+		t.Errorf("text output = %q", buf.String())
+	}
+	if !strings.Contains(extra.String(), `"msg":"photo taken","module":"capture","cell":{"col":1,"row":2}`) {
+		t.Errorf("extra handler output = %q", extra.String())
+	}
+}
+
+// ---------- Attributes ----------
+
+func TestAttributes(t *testing.T) {
+	buf := capture(t, LevelTrace)
+	cases := []struct {
+		log  func()
+		want string
+	}{
+		{func() { For("capture").Move("pan", 120, "right") }, `level=LIVE msg="motor move" module=capture axis=pan steps=120 direction=right`},
+		{func() { For("capture").Shot(2, 3) }, `level=LIVE msg="photo taken" module=capture cell.col=2 cell.row=3`},
+		{func() { For("capture").Column(1, 4, "down") }, `level=LIVE msg="column started" module=capture column=1 columns=4 direction=down`},
+		{func() { For("gpio").GPIO("WritePin", 17, "pin_level", 1) }, `level=TRACE msg=gpio module=gpio op=WritePin pin=17 pin_level=1`},
+		{func() { Error("retry", errors.New("boom"), Cell(1, 2)) }, `level=ERROR msg=retry module=pango err=boom cell.col=1 cell.row=2`},
+		{func() { Step(4, "grid plan") }, `level=VERBOSE msg="grid plan" module=pango step=4`},
+	}
+	for _, c := range cases {
+		buf.Reset()
+		c.log()
+		if !strings.Contains(buf.String(), c.want) {
+			t.Errorf("output %q, want %q", buf.String(), c.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package gigapixel writes the layout of a grid panorama for gigapixel
+// tools: the krpano settings of the stitched panorama and the image list
+// of GigaPan Stitch, so that neither needs the grid typed in by hand.
+package gigapixel
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Image is one picture of a grid: its file, its cell (1-based, row 1 at the
+// top) and the direction of the head when it was taken, yaw positive to the
+// right and pitch positive up.
+type Image struct {
+	Name        string
+	Column, Row int
+	YawDeg      float64
+	PitchDeg    float64
+}
+
+// Layout is a grid panorama.
+type Layout struct {
+	Columns, Rows  int
+	HFOVDeg        float64 // field of view of each image
+	VFOVDeg        float64
+	OverlapPercent float64
+	Images         []Image // in shooting order
+}
+
+// check returns an error when l cannot be written.
+func (l Layout) check() error {
+	if len(l.Images) == 0 {
+		return errors.New("gigapixel: layout without images")
+	}
+	if l.Columns < 1 || l.Rows < 1 {
+		return fmt.Errorf("gigapixel: grid of %d × %d", l.Columns, l.Rows)
+	}
+	if !(l.HFOVDeg > 0 && l.HFOVDeg < 180) || !(l.VFOVDeg > 0 && l.VFOVDeg < 180) {
+		return fmt.Errorf("gigapixel: field of view %g° × %g° out of range", l.HFOVDeg, l.VFOVDeg)
+	}
+	for _, img := range l.Images {
+		if img.Name == "" || strings.ContainsAny(img.Name, "\r\n") {
+			return fmt.Errorf("gigapixel: invalid image name %q", img.Name)
+		}
+		if img.Column < 1 || img.Column > l.Columns || img.Row < 1 || img.Row > l.Rows {
+			return fmt.Errorf("gigapixel: image %s in cell %d,%d, outside the %d × %d grid", img.Name, img.Column, img.Row, l.Columns, l.Rows)
+		}
+	}
+	return nil
+}
+
+// WriteKRPano writes the krpano settings of the panorama stitched from l,
+// in the file pano: the partial sphere the images cover (hfov, vfov and
+// voffset of <image>) and a view centered on it, limited to it. Vertical
+// angles are krpano's, positive down.
+func (l Layout) WriteKRPano(w io.Writer, pano string) error {
+	if err := l.check(); err != nil {
+		return err
+	}
+	minYaw, maxYaw := math.Inf(1), math.Inf(-1)
+	minPitch, maxPitch := math.Inf(1), math.Inf(-1)
+	for _, img := range l.Images {
+		minYaw, maxYaw = math.Min(minYaw, img.YawDeg), math.Max(maxYaw, img.YawDeg)
+		minPitch, maxPitch = math.Min(minPitch, img.PitchDeg), math.Max(maxPitch, img.PitchDeg)
+	}
+	hfov := math.Min(maxYaw-minYaw+l.HFOVDeg, 360)
+	vfov := math.Min(maxPitch-minPitch+l.VFOVDeg, 180)
+	hcenter := (minYaw + maxYaw) / 2
+	vcenter := -(minPitch + maxPitch) / 2 // krpano: positive down
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<krpano>\n")
+	fmt.Fprintf(bw, "\t<!-- %d columns × %d rows of %s° × %s° images -->\n", l.Columns, l.Rows, format(l.HFOVDeg), format(l.VFOVDeg))
+	fmt.Fprintf(bw, "\t<view hlookat=\"%s\" vlookat=\"%s\" fovtype=\"HFOV\" fov=\"%s\" limitview=\"range\" hlookatmin=\"%s\" hlookatmax=\"%s\" vlookatmin=\"%s\" vlookatmax=\"%s\" />\n",
+		format(hcenter), format(vcenter), format(math.Min(hfov, 90)),
+		format(hcenter-hfov/2), format(hcenter+hfov/2), format(vcenter-vfov/2), format(vcenter+vfov/2))
+	fmt.Fprintf(bw, "\t<image type=\"SPHERE\" hfov=\"%s\" vfov=\"%s\" voffset=\"%s\">\n", format(hfov), format(vfov), format(vcenter))
+	fmt.Fprintf(bw, "\t\t<sphere url=\"%s\" />\n", html.EscapeString(pano))
+	fmt.Fprintf(bw, "\t</image>\n")
+	fmt.Fprintf(bw, "</krpano>\n")
+	return bw.Flush()
+}
+
+// WriteGigaPan writes the images of l as a GigaPan Stitch image list: the
+// grid settings Stitch asks for as comments, then one file per line in the
+// order it expects them, column by column from the top left, down then up
+// (serpentine). Each cell holds its last image, that of the retry when its
+// shot was retried; every cell must have one.
+func (l Layout) WriteGigaPan(w io.Writer) error {
+	if err := l.check(); err != nil {
+		return err
+	}
+	cells := make(map[[2]int]string, len(l.Images))
+	for _, img := range l.Images {
+		cells[[2]int{img.Column, img.Row}] = img.Name
+	}
+	var missing []string
+	var names []string
+	for col := 1; col <= l.Columns; col++ {
+		rows := make([]int, 0, l.Rows)
+		for row := 1; row <= l.Rows; row++ {
+			rows = append(rows, row)
+		}
+		if col%2 == 0 {
+			slices.Reverse(rows)
+		}
+		for _, row := range rows {
+			name, ok := cells[[2]int{col, row}]
+			if !ok {
+				missing = append(missing, fmt.Sprintf("%d,%d", col, row))
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("gigapixel: no image in cells %s (column,row)", strings.Join(missing, " "))
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# GigaPan Stitch: add the images below in this order, then set\n")
+	fmt.Fprintf(bw, "# rows: %d\n", l.Rows)
+	fmt.Fprintf(bw, "# columns: %d\n", l.Columns)
+	fmt.Fprintf(bw, "# order: column-major, from the top left, serpentine\n")
+	fmt.Fprintf(bw, "# field of view: %s° × %s° per image, %s%% overlap\n", format(l.HFOVDeg), format(l.VFOVDeg), format(l.OverlapPercent))
+	for _, name := range names {
+		fmt.Fprintln(bw, name)
+	}
+	return bw.Flush()
+}
+
+// format formats an angle: without exponent, to 1e-6°.
+func format(deg float64) string {
+	deg = math.Round(deg*1e6) / 1e6
+	if deg == 0 {
+		deg = 0 // no "-0"
+	}
+	return strconv.FormatFloat(deg, 'f', -1, 64)
+}
@@ -0,0 +1,97 @@
+package gigapixel
+
+import (
+	"strings"
+	"testing"
+)
+
+// testLayout is a 2 × 2 grid shot in serpentine order, its cell 2,2 retried.
+func testLayout() Layout {
+	return Layout{
+		Columns: 2, Rows: 2,
+		HFOVDeg: 36, VFOVDeg: 24, OverlapPercent: 30,
+		Images: []Image{
+			{Name: "DSC_0001.JPG", Column: 1, Row: 1, YawDeg: -12.6, PitchDeg: 20},
+			{Name: "DSC_0002.JPG", Column: 1, Row: 2, YawDeg: -12.6, PitchDeg: 3.2},
+			{Name: "DSC_0003.JPG", Column: 2, Row: 2, YawDeg: 12.6, PitchDeg: 3.2},
+			{Name: "DSC_0004.JPG", Column: 2, Row: 1, YawDeg: 12.6, PitchDeg: 20},
+			{Name: "DSC_0005.JPG", Column: 2, Row: 2, YawDeg: 12.6, PitchDeg: 3.2},
+		},
+	}
+}
+
+// ---------- krpano ----------
+
+func TestLayoutWriteKRPano(t *testing.T) {
+	var b strings.Builder
+	if err := testLayout().WriteKRPano(&b, `pano "1".jpg`); err != nil {
+		t.Fatal(err)
+	}
+	// 25.2° + 36° wide centered on 0°, 16.8° + 24° high centered on
+	// 11.6° up (-11.6 in krpano).
+	want := `<krpano>
+	<!-- 2 columns × 2 rows of 36° × 24° images -->
+	<view hlookat="0" vlookat="-11.6" fovtype="HFOV" fov="61.2" limitview="range" hlookatmin="-30.6" hlookatmax="30.6" vlookatmin="-32" vlookatmax="8.8" />
+	<image type="SPHERE" hfov="61.2" vfov="40.8" voffset="-11.6">
+		<sphere url="pano &#34;1&#34;.jpg" />
+	</image>
+</krpano>
+`
+	if b.String() != want {
+		t.Errorf("krpano:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+// ---------- GigaPan ----------
+
+func TestLayoutWriteGigaPan(t *testing.T) {
+	var b strings.Builder
+	if err := testLayout().WriteGigaPan(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"# rows: 2\n# columns: 2\n",
+		"# field of view: 36° × 24° per image, 30% overlap\n",
+		// Column 2 bottom up, with the retry of cell 2,2.
+		"\nDSC_0001.JPG\nDSC_0002.JPG\nDSC_0005.JPG\nDSC_0004.JPG\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("list does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "DSC_0003.JPG") {
+		t.Errorf("list contains the failed image of cell 2,2:\n%s", out)
+	}
+}
+
+func TestLayoutWrite_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(l *Layout)
+	}{
+		{"no_images", func(l *Layout) { l.Images = nil }},
+		{"no_fov", func(l *Layout) { l.HFOVDeg = 0 }},
+		{"outside_grid", func(l *Layout) { l.Images[0].Column = 3 }},
+		{"bad_name", func(l *Layout) { l.Images[0].Name = "a\nb" }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := testLayout()
+			tc.edit(&l)
+			if err := l.WriteKRPano(&strings.Builder{}, "pano.jpg"); err == nil {
+				t.Error("WriteKRPano: expected error, got nil")
+			}
+			if err := l.WriteGigaPan(&strings.Builder{}); err == nil {
+				t.Error("WriteGigaPan: expected error, got nil")
+			}
+		})
+	}
+
+	// GigaPan Stitch needs every cell.
+	l := testLayout()
+	l.Images = l.Images[:2]
+	if err := l.WriteGigaPan(&strings.Builder{}); err == nil || !strings.Contains(err.Error(), "cells 2,2 2,1 ") {
+		t.Errorf("error = %v, want the missing cells", err)
+	}
+}
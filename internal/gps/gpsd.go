@@ -0,0 +1,74 @@
+package gps
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// watchCommand asks gpsd to stream its reports as JSON lines.
+const watchCommand = `?WATCH={"enable":true,"json":true}` + "\n"
+
+// tpv is the part of a gpsd report read: its class, and for TPV
+// (time-position-velocity) reports the fix.
+type tpv struct {
+	Class  string    `json:"class"`
+	Mode   int       `json:"mode"` // 0-1 = no fix, 2 = 2D, 3 = 3D
+	Time   time.Time `json:"time"`
+	Lat    *float64  `json:"lat"`
+	Lon    *float64  `json:"lon"`
+	Alt    *float64  `json:"alt"`    // gpsd < 3.20: above mean sea level
+	AltMSL *float64  `json:"altMSL"` // gpsd >= 3.20
+}
+
+// readGPSD connects to gpsd at addr and reads its reports until the
+// connection fails or ctx is cancelled.
+func (r *Receiver) readGPSD(ctx context.Context, addr string) error {
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if _, err := io.WriteString(conn, watchCommand); err != nil {
+		return err
+	}
+	return readGPSD(conn, r.set)
+}
+
+// readGPSD reads the JSON reports of gpsd from rd and passes the fixes of
+// its TPV reports to fn. Other reports (VERSION, DEVICES, SKY...) are
+// skipped.
+func readGPSD(rd io.Reader, fn func(Fix)) error {
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		var report tpv
+		if err := json.Unmarshal(sc.Bytes(), &report); err != nil {
+			continue // not a report of interest, or a newer layout
+		}
+		if report.Class != "TPV" || report.Mode < 2 || report.Lat == nil || report.Lon == nil || report.Time.IsZero() {
+			continue
+		}
+		f := Fix{Time: report.Time.UTC(), Latitude: *report.Lat, Longitude: *report.Lon}
+		alt := report.AltMSL
+		if alt == nil {
+			alt = report.Alt
+		}
+		if report.Mode == 3 && alt != nil {
+			f.Altitude, f.Has3D = *alt, true
+		}
+		fn(f)
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return errors.New("gpsd closed the connection")
+}
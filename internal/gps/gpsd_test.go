@@ -0,0 +1,38 @@
+package gps
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// ---------- readGPSD ----------
+
+func TestReadGPSD(t *testing.T) {
+	reports := strings.Join([]string{
+		`{"class":"VERSION","release":"3.25","rev":"3.25","proto_major":3,"proto_minor":15}`,
+		`{"class":"DEVICES","devices":[{"class":"DEVICE","path":"/dev/ttyACM0","driver":"u-blox"}]}`,
+		`{"class":"TPV","device":"/dev/ttyACM0","mode":1}`,
+		`{"class":"TPV","device":"/dev/ttyACM0","mode":2,"time":"2026-10-18T08:30:00.000Z","lat":46.5197,"lon":6.6323}`,
+		`{"class":"SKY","device":"/dev/ttyACM0","satellites":[]}`,
+		`{"class":"TPV","device":"/dev/ttyACM0","mode":3,"time":"2026-10-18T08:30:01.000Z","lat":46.5198,"lon":6.6324,"alt":420.5,"altMSL":372.1}`,
+		`{"class":"TPV","device":"/dev/ttyACM0","mode":3,"time":"2026-10-18T08:30:02.000Z","lat":46.5199,"lon":6.6325,"alt":372.3}`,
+		`not json`,
+	}, "\n") + "\n"
+	var fixes []Fix
+	if err := readGPSD(strings.NewReader(reports), func(f Fix) { fixes = append(fixes, f) }); err == nil {
+		t.Error("readGPSD: expected the closed connection error, got nil")
+	}
+	at := func(sec int) time.Time { return time.Date(2026, 10, 18, 8, 30, sec, 0, time.UTC) }
+	want := []Fix{
+		{Time: at(0), Latitude: 46.5197, Longitude: 6.6323},
+		{Time: at(1), Latitude: 46.5198, Longitude: 6.6324, Altitude: 372.1, Has3D: true}, // altMSL over alt (HAE in gpsd >= 3.20)
+		{Time: at(2), Latitude: 46.5199, Longitude: 6.6325, Altitude: 372.3, Has3D: true},
+	}
+	if len(fixes) != len(want) {
+		t.Fatalf("fixes = %+v, want %+v", fixes, want)
+	}
+	for i := range want {
+		checkFix(t, fixes[i], want[i])
+	}
+}
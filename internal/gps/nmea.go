@@ -0,0 +1,157 @@
+package gps
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nmeaReader turns RMC (position, date and time) and GGA (position and
+// altitude) sentences into fixes. GGA carries no date: its fixes are dated
+// from the last RMC, and RMC fixes take the altitude of a GGA of the same
+// second.
+type nmeaReader struct {
+	date    time.Time     // UTC day of the last valid RMC; zero before it
+	rmcTime time.Duration // time of day of the last valid RMC
+	ggaTime time.Duration // time of day of the last valid GGA, -1 before it
+	ggaAlt  float64
+	ggaHas  bool // the last GGA had an altitude
+}
+
+// readNMEA reads NMEA 0183 sentences from rd, e.g. a GPS module on a
+// serial port, and passes the fixes to fn. Sentences of other types and
+// sentences with a bad checksum (line noise) are skipped.
+func readNMEA(rd io.Reader, fn func(Fix)) error {
+	n := nmeaReader{ggaTime: -1}
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		fields, err := parseSentence(sc.Text())
+		if err != nil {
+			continue
+		}
+		if f, ok := n.sentence(fields); ok {
+			fn(f)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return errors.New("end of NMEA stream")
+}
+
+// sentence processes the fields of one sentence and returns the fix it
+// completes, if any.
+func (n *nmeaReader) sentence(fields []string) (Fix, bool) {
+	if len(fields[0]) != 5 { // talker (GP, GN, GL...) and type
+		return Fix{}, false
+	}
+	switch fields[0][2:] {
+	case "RMC":
+		// RMC,time,status,lat,N/S,lon,E/W,speed,course,date,...
+		if len(fields) < 10 || fields[2] != "A" {
+			return Fix{}, false
+		}
+		tod, err1 := parseTimeOfDay(fields[1])
+		date, err2 := time.Parse("020106", fields[9])
+		lat, lon, err3 := parsePosition(fields[3:7])
+		if err := errors.Join(err1, err2, err3); err != nil {
+			return Fix{}, false
+		}
+		n.date, n.rmcTime = date, tod
+		f := Fix{Time: date.Add(tod), Latitude: lat, Longitude: lon}
+		if n.ggaTime == tod && n.ggaHas {
+			f.Altitude, f.Has3D = n.ggaAlt, true
+		}
+		return f, true
+	case "GGA":
+		// GGA,time,lat,N/S,lon,E/W,quality,satellites,hdop,altitude,M,...
+		if len(fields) < 11 || fields[6] == "" || fields[6] == "0" {
+			return Fix{}, false
+		}
+		tod, err1 := parseTimeOfDay(fields[1])
+		lat, lon, err2 := parsePosition(fields[2:6])
+		if err := errors.Join(err1, err2); err != nil {
+			return Fix{}, false
+		}
+		alt, err := strconv.ParseFloat(fields[9], 64)
+		n.ggaTime, n.ggaAlt, n.ggaHas = tod, alt, err == nil
+		// Dated from the last RMC, unless the day changed since.
+		if n.date.IsZero() || tod < n.rmcTime {
+			return Fix{}, false
+		}
+		return Fix{Time: n.date.Add(tod), Latitude: lat, Longitude: lon, Altitude: alt, Has3D: err == nil}, true
+	}
+	return Fix{}, false
+}
+
+// parseSentence checks the checksum of an NMEA sentence,
+// "$GPRMC,...*hh", and returns its fields, the first being the address.
+func parseSentence(line string) ([]string, error) {
+	line = strings.TrimSpace(line)
+	body, sum, ok := strings.Cut(strings.TrimPrefix(line, "$"), "*")
+	if !ok || !strings.HasPrefix(line, "$") {
+		return nil, fmt.Errorf("nmea: not a sentence: %q", line)
+	}
+	want, err := strconv.ParseUint(sum, 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("nmea: bad checksum %q", sum)
+	}
+	var got byte
+	for i := 0; i < len(body); i++ {
+		got ^= body[i]
+	}
+	if got != byte(want) {
+		return nil, fmt.Errorf("nmea: checksum %02X, want %02X", got, want)
+	}
+	return strings.Split(body, ","), nil
+}
+
+// parseTimeOfDay parses an NMEA time, hhmmss with optional fractional
+// seconds.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	if len(s) < 6 {
+		return 0, fmt.Errorf("nmea: bad time %q", s)
+	}
+	h, err1 := strconv.Atoi(s[0:2])
+	m, err2 := strconv.Atoi(s[2:4])
+	sec, err3 := strconv.ParseFloat(s[4:], 64)
+	if err := errors.Join(err1, err2, err3); err != nil || h > 23 || m > 59 || sec >= 61 {
+		return 0, fmt.Errorf("nmea: bad time %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec*float64(time.Second)).Round(time.Millisecond), nil
+}
+
+// parsePosition parses the latitude, N/S, longitude and E/W fields of a
+// sentence, in degrees and minutes (ddmm.mmmm, dddmm.mmmm).
+func parsePosition(fields []string) (lat, lon float64, err error) {
+	lat, err1 := parseCoordinate(fields[0], fields[1], "N", "S", 90)
+	lon, err2 := parseCoordinate(fields[2], fields[3], "E", "W", 180)
+	return lat, lon, errors.Join(err1, err2)
+}
+
+func parseCoordinate(value, hemisphere, positive, negative string, limit float64) (float64, error) {
+	dot := strings.IndexByte(value, '.')
+	if dot < 0 {
+		dot = len(value)
+	}
+	if dot < 3 {
+		return 0, fmt.Errorf("nmea: bad coordinate %q", value)
+	}
+	deg, err1 := strconv.Atoi(value[:dot-2])
+	minutes, err2 := strconv.ParseFloat(value[dot-2:], 64)
+	v := float64(deg) + minutes/60
+	if err := errors.Join(err1, err2); err != nil || minutes >= 60 || v > limit {
+		return 0, fmt.Errorf("nmea: bad coordinate %q", value)
+	}
+	switch hemisphere {
+	case positive:
+		return v, nil
+	case negative:
+		return -v, nil
+	}
+	return 0, fmt.Errorf("nmea: bad hemisphere %q", hemisphere)
+}
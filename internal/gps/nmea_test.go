@@ -0,0 +1,102 @@
+package gps
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The example sentences of the NMEA 0183 documentation: 48°07.038' N,
+// 11°31.000' E, 545.4 m, on 23 March 1994 at 12:35:19 UTC.
+const (
+	exampleGGA = "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47"
+	exampleRMC = "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A"
+)
+
+func readAllNMEA(t *testing.T, lines ...string) []Fix {
+	t.Helper()
+	var fixes []Fix
+	if err := readNMEA(strings.NewReader(strings.Join(lines, "\r\n")+"\r\n"), func(f Fix) { fixes = append(fixes, f) }); err == nil {
+		t.Error("readNMEA: expected the end of stream error, got nil")
+	}
+	return fixes
+}
+
+func checkFix(t *testing.T, got Fix, want Fix) {
+	t.Helper()
+	if !got.Time.Equal(want.Time) || got.Has3D != want.Has3D ||
+		math.Abs(got.Latitude-want.Latitude) > 1e-9 || math.Abs(got.Longitude-want.Longitude) > 1e-9 || math.Abs(got.Altitude-want.Altitude) > 1e-9 {
+		t.Errorf("fix = %+v, want %+v", got, want)
+	}
+}
+
+// ---------- readNMEA ----------
+
+func TestReadNMEA(t *testing.T) {
+	when := time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC)
+	want := Fix{Time: when, Latitude: 48 + 7.038/60, Longitude: 11 + 31.0/60, Altitude: 545.4, Has3D: true}
+
+	// GGA first: no date yet, then RMC takes its altitude.
+	fixes := readAllNMEA(t, exampleGGA, exampleRMC)
+	if len(fixes) != 1 {
+		t.Fatalf("fixes = %+v, want 1", fixes)
+	}
+	checkFix(t, fixes[0], want)
+
+	// RMC first: a 2D fix, then GGA dated from it.
+	fixes = readAllNMEA(t, exampleRMC, exampleGGA)
+	if len(fixes) != 2 {
+		t.Fatalf("fixes = %+v, want 2", fixes)
+	}
+	checkFix(t, fixes[0], Fix{Time: when, Latitude: want.Latitude, Longitude: want.Longitude})
+	checkFix(t, fixes[1], want)
+}
+
+func TestReadNMEA_Skipped(t *testing.T) {
+	cases := []struct {
+		name string
+		line string
+	}{
+		{"bad_checksum", strings.Replace(exampleRMC, "*6A", "*6B", 1)},
+		{"no_checksum", strings.TrimSuffix(exampleRMC, "*6A")},
+		{"void", sentence("GPRMC,123519,V,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W")},
+		{"bad_latitude", sentence("GPRMC,123519,A,9107.038,N,01131.000,E,022.4,084.4,230394,003.1,W")},
+		{"other_type", sentence("GPGSV,3,1,11,03,03,111,00,04,15,270,00,06,01,010,00,13,06,292,00")},
+		{"noise", "$GP\x00\xff"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if fixes := readAllNMEA(t, tc.line); len(fixes) != 0 {
+				t.Errorf("fixes = %+v, want none", fixes)
+			}
+		})
+	}
+
+	// A GGA without a fix is not dated from the RMC before it.
+	if fixes := readAllNMEA(t, exampleRMC, sentence("GPGGA,123520,,,,,0,00,,,M,,M,,")); len(fixes) != 1 {
+		t.Errorf("fixes = %+v, want the RMC one", fixes)
+	}
+}
+
+func TestReadNMEA_SouthWest(t *testing.T) {
+	fixes := readAllNMEA(t, sentence("GNRMC,235959.50,A,3351.3500,S,15112.8000,W,0.0,0.0,311226,,,A"))
+	if len(fixes) != 1 {
+		t.Fatalf("fixes = %+v, want 1", fixes)
+	}
+	checkFix(t, fixes[0], Fix{
+		Time:      time.Date(2026, 12, 31, 23, 59, 59, 500e6, time.UTC),
+		Latitude:  -(33 + 51.35/60),
+		Longitude: -(151 + 12.8/60),
+	})
+}
+
+// sentence returns the NMEA sentence of body with its checksum.
+func sentence(body string) string {
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return fmt.Sprintf("$%s*%02X", body, sum)
+}
@@ -0,0 +1,137 @@
+// Package gps reads the position of the rig from a GPS receiver, through
+// gpsd or directly from a serial port speaking NMEA 0183, and keeps the
+// last fix for the capture records. Both protocols are line-based text and
+// read without a client library.
+package gps
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reconnection delays, and the fix age default.
+const (
+	dialTimeout   = 10 * time.Second
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+	defaultMaxAge = 10 * time.Second
+)
+
+// Fix is a position reported by the receiver.
+type Fix struct {
+	Time      time.Time // GPS time of the fix (UTC)
+	Latitude  float64   // degrees, positive north
+	Longitude float64   // degrees, positive east
+	Altitude  float64   // meters above mean sea level, when Has3D
+	Has3D     bool      // the fix has an altitude
+}
+
+// Options configures a Receiver. Exactly one of GPSD and Device is set.
+type Options struct {
+	GPSD   string        // gpsd address, "host:port"
+	Device string        // NMEA serial device, e.g. /dev/serial0, its speed already set
+	MaxAge time.Duration // Fix ignores fixes received longer ago; 0 = 10 s
+}
+
+// Receiver reads fixes in the background until closed.
+type Receiver struct {
+	opts Options
+	now  func() time.Time
+
+	mu       sync.Mutex
+	fix      Fix
+	received time.Time // local time of the last fix; zero before the first
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Open starts reading the receiver of o. Read errors are logged and the
+// connection or device is reopened with a growing delay; Fix reports no
+// position meanwhile.
+func Open(o Options) (*Receiver, error) {
+	if (o.GPSD == "") == (o.Device == "") {
+		return nil, errors.New("gps: set one of gpsd and device")
+	}
+	if o.MaxAge == 0 {
+		o.MaxAge = defaultMaxAge
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Receiver{opts: o, now: time.Now, cancel: cancel, done: make(chan struct{})}
+	go r.run(ctx)
+	return r, nil
+}
+
+// Close stops reading and waits for the reader to return.
+func (r *Receiver) Close() {
+	r.cancel()
+	<-r.done
+}
+
+// Fix returns the last fix, false when there is none or it was received
+// longer than MaxAge ago (fix lost, receiver unplugged).
+func (r *Receiver) Fix() (Fix, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.received.IsZero() || r.now().Sub(r.received) > r.opts.MaxAge {
+		return Fix{}, false
+	}
+	return r.fix, true
+}
+
+// set records f as the last fix.
+func (r *Receiver) set(f Fix) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fix = f
+	r.received = r.now()
+}
+
+// run reads the receiver until ctx is cancelled, reopening it when it fails.
+func (r *Receiver) run(ctx context.Context) {
+	defer close(r.done)
+	source := r.opts.GPSD
+	if source == "" {
+		source = r.opts.Device
+	}
+	delay := minRetryDelay
+	for {
+		start := time.Now()
+		var err error
+		if r.opts.GPSD != "" {
+			err = r.readGPSD(ctx, r.opts.GPSD)
+		} else {
+			err = r.readDevice(ctx, r.opts.Device)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > maxRetryDelay {
+			delay = minRetryDelay // it worked for a while
+		}
+		log.Printf("gps: %s: %v; retrying in %v", source, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// readDevice reads NMEA sentences from the serial device path until it
+// fails or ctx is cancelled.
+func (r *Receiver) readDevice(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stop := context.AfterFunc(ctx, func() { f.Close() })
+	defer stop()
+	return readNMEA(f, r.set)
+}
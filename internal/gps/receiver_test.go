@@ -0,0 +1,102 @@
+package gps
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitFix waits for r to report a fix.
+func waitFix(t *testing.T, r *Receiver) Fix {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if f, ok := r.Fix(); ok {
+			return f
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("no fix")
+	return Fix{}
+}
+
+// ---------- Open ----------
+
+func TestOpen_Invalid(t *testing.T) {
+	for _, o := range []Options{{}, {GPSD: "localhost:2947", Device: "/dev/serial0"}} {
+		if _, err := Open(o); err == nil {
+			t.Errorf("Open(%+v): expected error, got nil", o)
+		}
+	}
+}
+
+func TestReceiver_GPSD(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	watch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		watch <- line
+		conn.Write([]byte(`{"class":"TPV","mode":3,"time":"2026-10-18T08:30:00Z","lat":46.5,"lon":6.6,"altMSL":372}` + "\n"))
+		io.Copy(io.Discard, conn) // until the receiver closes
+	}()
+
+	r, err := Open(Options{GPSD: ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	f := waitFix(t, r)
+	if f.Latitude != 46.5 || f.Longitude != 6.6 || f.Altitude != 372 || !f.Has3D {
+		t.Errorf("fix = %+v", f)
+	}
+	if got := <-watch; got != watchCommand {
+		t.Errorf("command = %q, want %q", got, watchCommand)
+	}
+}
+
+func TestReceiver_Device(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ttyS0")
+	if err := os.WriteFile(path, []byte(exampleRMC+"\r\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	r, err := Open(Options{Device: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if f := waitFix(t, r); f.Time != time.Date(1994, 3, 23, 12, 35, 19, 0, time.UTC) || f.Has3D {
+		t.Errorf("fix = %+v", f)
+	}
+}
+
+// ---------- Fix ----------
+
+func TestReceiver_FixMaxAge(t *testing.T) {
+	now := time.Date(2026, 10, 18, 8, 30, 0, 0, time.UTC)
+	r := &Receiver{opts: Options{MaxAge: 10 * time.Second}, now: func() time.Time { return now }}
+	if _, ok := r.Fix(); ok {
+		t.Error("Fix before the first one: got one")
+	}
+	r.set(Fix{Latitude: 46.5, Longitude: 6.6})
+	now = now.Add(10 * time.Second)
+	if f, ok := r.Fix(); !ok || f.Latitude != 46.5 {
+		t.Errorf("Fix() = %+v, %v, want the fix", f, ok)
+	}
+	now = now.Add(time.Millisecond)
+	if _, ok := r.Fix(); ok {
+		t.Error("Fix past MaxAge: got one")
+	}
+}
@@ -0,0 +1,38 @@
+package hugin
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WritePTGui writes p as a PTGui project in the PanoTools script format
+// (.pts), which PTGui opens as a project or applies as a template (Project
+// > Apply Template) to the pictures of a run: an equirectangular panorama
+// covering the images, then an image line per image with its file, yaw,
+// pitch and roll, and the lens parameters set on the first image and
+// linked (=0) on the others.
+func (p Project) WritePTGui(w io.Writer) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	width, height := p.Lens.size()
+	pano := p.panorama()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# ptGui project file")
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "p w%d h%d f2 v%s u0 n\"TIFF_m c:LZW\"\n", pano.width, pano.height, format(pano.hfov))
+	fmt.Fprintln(bw, "m g1 i0")
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "# Image lines")
+	for i, img := range p.Images {
+		lens := fmt.Sprintf("v%s a0 b0 c0 d0 e0 g0 t0", format(p.Lens.HFOVDeg))
+		if i > 0 {
+			lens = "v=0 a=0 b=0 c=0 d=0 e=0 g=0 t=0"
+		}
+		fmt.Fprintf(bw, "#-imgfile %d %d \"%s\"\n", width, height, img.Name)
+		fmt.Fprintf(bw, "o f0 y%s r0 p%s %s\n", format(img.YawDeg), format(img.PitchDeg), lens)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,40 @@
+package hugin
+
+import (
+	"strings"
+	"testing"
+)
+
+// ---------- PTGui ----------
+
+func TestProjectWritePTGui(t *testing.T) {
+	p := Project{
+		Lens: Lens{HFOVDeg: 36, VFOVDeg: 24, WidthPx: 4288, HeightPx: 2848},
+		Images: []Image{
+			{Name: "DSC_0001.JPG", YawDeg: -27, PitchDeg: 12.5},
+			{Name: "DSC_0002.JPG", YawDeg: 0, PitchDeg: -4},
+		},
+	}
+	var b strings.Builder
+	if err := p.WritePTGui(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# ptGui project file
+
+p w7504 h5836 f2 v63 u0 n"TIFF_m c:LZW"
+m g1 i0
+
+# Image lines
+#-imgfile 4288 2848 "DSC_0001.JPG"
+o f0 y-27 r0 p12.5 v36 a0 b0 c0 d0 e0 g0 t0
+#-imgfile 4288 2848 "DSC_0002.JPG"
+o f0 y0 r0 p-4 v=0 a=0 b=0 c=0 d=0 e=0 g=0 t=0
+`
+	if b.String() != want {
+		t.Errorf("project:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	if err := (Project{Lens: p.Lens}).WritePTGui(&b); err == nil {
+		t.Error("project without images written")
+	}
+}
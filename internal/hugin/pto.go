@@ -0,0 +1,171 @@
+// Package hugin writes panorama projects for Hugin (.pto files) and PTGui
+// (.pts templates) with the directions the shots were taken in, so that
+// stitching starts from the geometry of the capture instead of a blind
+// control point search.
+package hugin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Image width of projects whose image size is unknown: Hugin rescales the
+// project when it loads the real images.
+const nominalWidthPx = 6000
+
+// DefaultNames is the file name pattern of the images when the camera's is
+// not given.
+const DefaultNames = "IMG_%04d.JPG"
+
+// Lens is the lens and image size shared by the images of a project.
+type Lens struct {
+	HFOVDeg float64 // horizontal field of view
+	VFOVDeg float64 // vertical field of view, for the aspect ratio when the image size is unknown
+
+	// Image size; 0 for a nominal size with the aspect ratio of the fields
+	// of view.
+	WidthPx, HeightPx int
+}
+
+// size returns the image size of l.
+func (l Lens) size() (width, height int) {
+	if l.WidthPx > 0 && l.HeightPx > 0 {
+		return l.WidthPx, l.HeightPx
+	}
+	ratio := math.Tan(l.VFOVDeg*math.Pi/360) / math.Tan(l.HFOVDeg*math.Pi/360)
+	return nominalWidthPx, int(math.Round(nominalWidthPx * ratio))
+}
+
+// Image is one picture of a project: its file and the direction of the head
+// when it was taken, yaw positive to the right and pitch positive up.
+type Image struct {
+	Name     string
+	YawDeg   float64
+	PitchDeg float64
+}
+
+// Project is a panorama of images taken with the same lens.
+type Project struct {
+	Lens   Lens
+	Images []Image
+}
+
+// Write writes p as a .pto file: an equirectangular panorama covering the
+// images, one lens shared by all of them, and yaw, pitch and roll of every
+// image but the first (the anchor) marked for optimization once control
+// points are found.
+func (p Project) Write(w io.Writer) error {
+	if err := p.check(); err != nil {
+		return err
+	}
+	width, height := p.Lens.size()
+	pano := p.panorama()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# hugin project file")
+	fmt.Fprintln(bw, "#hugin_ptoversion 2")
+	fmt.Fprintf(bw, "p f2 w%d h%d v%s E0 R0 n\"TIFF_m c:LZW r:CROP\"\n", pano.width, pano.height, format(pano.hfov))
+	fmt.Fprintln(bw, "m i0")
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "# image lines")
+	for i, img := range p.Images {
+		// The lens is set on the first image and linked (=0) on the others.
+		lens := fmt.Sprintf("v%s a0 b0 c0 d0 e0 g0 t0", format(p.Lens.HFOVDeg))
+		if i > 0 {
+			lens = "v=0 a=0 b=0 c=0 d=0 e=0 g=0 t=0"
+		}
+		fmt.Fprintf(bw, "i w%d h%d f0 %s Ra0 Rb0 Rc0 Rd0 Re0 Eev0 Er1 Eb1 r0 p%s y%s TrX0 TrY0 TrZ0 Tpy0 Tpp0 j0 Va1 Vb0 Vc0 Vd0 Vx0 Vy0 Vm5 n\"%s\"\n",
+			width, height, lens, format(img.PitchDeg), format(img.YawDeg), img.Name)
+	}
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "# specify variables that should be optimized")
+	for i := 1; i < len(p.Images); i++ {
+		fmt.Fprintf(bw, "v y%d p%d r%d\n", i, i, i)
+	}
+	fmt.Fprintln(bw, "v")
+	return bw.Flush()
+}
+
+// check returns an error when p cannot be written.
+func (p Project) check() error {
+	if len(p.Images) == 0 {
+		return errors.New("hugin: project without images")
+	}
+	if !(p.Lens.HFOVDeg > 0 && p.Lens.HFOVDeg < 180) || !(p.Lens.VFOVDeg > 0 && p.Lens.VFOVDeg < 180) {
+		return fmt.Errorf("hugin: field of view %g° × %g° out of range", p.Lens.HFOVDeg, p.Lens.VFOVDeg)
+	}
+	for _, img := range p.Images {
+		if img.Name == "" || strings.ContainsAny(img.Name, "\"\r\n") {
+			return fmt.Errorf("hugin: invalid image name %q", img.Name)
+		}
+	}
+	return nil
+}
+
+// panorama is the output of a project: an equirectangular image.
+type panorama struct {
+	width, height int
+	hfov          float64
+}
+
+// panorama returns the output of p: the span of the images at the
+// resolution of the shots, the equirectangular image centered on the
+// horizon.
+func (p Project) panorama() panorama {
+	width, _ := p.Lens.size()
+	minYaw, maxYaw, maxPitch := math.Inf(1), math.Inf(-1), 0.0
+	for _, img := range p.Images {
+		minYaw, maxYaw = math.Min(minYaw, img.YawDeg), math.Max(maxYaw, img.YawDeg)
+		maxPitch = math.Max(maxPitch, math.Abs(img.PitchDeg))
+	}
+	pxPerDeg := float64(width) / p.Lens.HFOVDeg
+	hfov := math.Min(maxYaw-minYaw+p.Lens.HFOVDeg, 360)
+	vfov := math.Min(2*maxPitch+p.Lens.VFOVDeg, 180)
+	return panorama{
+		width:  int(math.Round(hfov * pxPerDeg)),
+		height: int(math.Round(vfov * pxPerDeg)),
+		hfov:   hfov,
+	}
+}
+
+// format formats an angle for a .pto file: without exponent, to 1e-6°.
+func format(deg float64) string {
+	deg = math.Round(deg*1e6) / 1e6
+	if deg == 0 {
+		deg = 0 // no "-0"
+	}
+	return strconv.FormatFloat(deg, 'f', -1, 64)
+}
+
+// nameVerb matches the integer verb of a file name pattern.
+var nameVerb = regexp.MustCompile(`%0?[0-9]*d`)
+
+// Namer names the image files of the shots from a printf pattern holding
+// one integer verb, e.g. "DSC_%04d.JPG", and the number of the first file.
+type Namer struct {
+	pattern string
+	first   int
+}
+
+// NewNamer returns the Namer of pattern, numbering files from first.
+func NewNamer(pattern string, first int) (Namer, error) {
+	rest := strings.ReplaceAll(nameVerb.ReplaceAllString(pattern, ""), "%%", "")
+	if len(nameVerb.FindAllString(pattern, -1)) != 1 || strings.Contains(rest, "%") {
+		return Namer{}, fmt.Errorf("image names %q: want one integer verb such as %%04d", pattern)
+	}
+	if first < 0 {
+		return Namer{}, fmt.Errorf("first image number %d is negative", first)
+	}
+	return Namer{pattern: pattern, first: first}, nil
+}
+
+// Name returns the file name of the shot-th shot (1-based).
+func (n Namer) Name(shot int) string {
+	return fmt.Sprintf(n.pattern, n.first+shot-1)
+}
@@ -0,0 +1,116 @@
+package hugin
+
+import (
+	"strings"
+	"testing"
+)
+
+// ---------- Project ----------
+
+func TestProjectWrite(t *testing.T) {
+	p := Project{
+		Lens: Lens{HFOVDeg: 36, VFOVDeg: 24, WidthPx: 4288, HeightPx: 2848},
+		Images: []Image{
+			{Name: "DSC_0001.JPG", YawDeg: -27, PitchDeg: 12.5},
+			{Name: "DSC_0002.JPG", YawDeg: -27, PitchDeg: -4.0000001},
+			{Name: "DSC_0003.JPG", YawDeg: 0, PitchDeg: -4},
+		},
+	}
+	var b strings.Builder
+	if err := p.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"#hugin_ptoversion 2\n",
+		// 27° + 36° wide and 2 × 12.5° + 24° high at 4288 / 36 px per degree.
+		"p f2 w7504 h5836 v63 ",
+		"i w4288 h2848 f0 v36 a0 b0 c0 d0 e0 g0 t0 ",
+		" r0 p12.5 y-27 ",
+		" r0 p-4 y-27 ",
+		`n"DSC_0001.JPG"` + "\n",
+		"i w4288 h2848 f0 v=0 a=0 b=0 c=0 d=0 e=0 g=0 t=0 ",
+		" r0 p-4 y0 TrX0",
+		"\nv y1 p1 r1\nv y2 p2 r2\nv\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("project does not contain %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "v y0") {
+		t.Error("the anchor image is optimized")
+	}
+	if n := strings.Count(out, "\ni "); n != 3 {
+		t.Errorf("%d image lines, want 3", n)
+	}
+}
+
+func TestProjectWrite_NominalSize(t *testing.T) {
+	p := Project{Lens: Lens{HFOVDeg: 90, VFOVDeg: 90}, Images: []Image{{Name: "a.jpg"}}}
+	var b strings.Builder
+	if err := p.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "i w6000 h6000 ") {
+		t.Errorf("want a nominal 6000 × 6000 image:\n%s", b.String())
+	}
+}
+
+func TestProjectWrite_Invalid(t *testing.T) {
+	lens := Lens{HFOVDeg: 36, VFOVDeg: 24}
+	cases := []struct {
+		name string
+		p    Project
+	}{
+		{"no_images", Project{Lens: lens}},
+		{"no_fov", Project{Images: []Image{{Name: "a.jpg"}}}},
+		{"wide_fov", Project{Lens: Lens{HFOVDeg: 180, VFOVDeg: 24}, Images: []Image{{Name: "a.jpg"}}}},
+		{"empty_name", Project{Lens: lens, Images: []Image{{}}}},
+		{"quoted_name", Project{Lens: lens, Images: []Image{{Name: `a".jpg`}}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.p.Write(&strings.Builder{}); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+// ---------- Namer ----------
+
+func TestNamer(t *testing.T) {
+	cases := []struct {
+		pattern string
+		first   int
+		want    string // name of shot 3; "" for an invalid pattern
+	}{
+		{DefaultNames, 1, "IMG_0003.JPG"},
+		{"DSC_%04d.NEF", 1234, "DSC_1236.NEF"},
+		{"pano %d (100%%).jpg", 0, "pano 2 (100%).jpg"},
+		{"DSC.JPG", 1, ""},
+		{"%d_%d.jpg", 1, ""},
+		{"%s.jpg", 1, ""},
+		{"%04d%.jpg", 1, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.pattern, func(t *testing.T) {
+			n, err := NewNamer(tc.pattern, tc.first)
+			if tc.want == "" {
+				if err == nil {
+					t.Errorf("NewNamer(%q) succeeded, want an error", tc.pattern)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := n.Name(3); got != tc.want {
+				t.Errorf("Name(3) = %q, want %q", got, tc.want)
+			}
+		})
+	}
+	if _, err := NewNamer(DefaultNames, -1); err == nil {
+		t.Error("negative first number accepted")
+	}
+}
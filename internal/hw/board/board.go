@@ -0,0 +1,157 @@
+// Package board identifies the Raspberry Pi PanGo runs on and checks GPIO
+// pins (BCM numbering) against its header and the interfaces enabled on it.
+package board
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrNotRaspberryPi is returned by Detect on other machines.
+var ErrNotRaspberryPi = errors.New("not a Raspberry Pi")
+
+// modelFiles hold the model string of the device tree, the first one found
+// is read.
+var modelFiles = []string{"proc/device-tree/model", "sys/firmware/devicetree/base/model"}
+
+// Headers list the GPIOs wired to the pin header of each board layout.
+var (
+	header26Rev1 = []int{0, 1, 4, 7, 8, 9, 10, 11, 14, 15, 17, 18, 21, 22, 23, 24, 25}
+	header26Rev2 = []int{2, 3, 4, 7, 8, 9, 10, 11, 14, 15, 17, 18, 22, 23, 24, 25, 27}
+	header40     = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27}
+)
+
+// Function is a special function of GPIOs, in use when one of its devices
+// exists: the interface is enabled in config.txt.
+type Function struct {
+	Name    string // e.g. "I2C"
+	Pins    []int
+	Devices []string // paths relative to the root given to Detect
+	Disable string   // how to turn it off, for messages
+}
+
+// Functions are the interfaces checked by Detect, with their default pins.
+var Functions = []Function{
+	{"I2C", []int{2, 3}, []string{"dev/i2c-1"}, "disable i2c (raspi-config, dtparam=i2c_arm=off)"},
+	{"SPI", []int{7, 8, 9, 10, 11}, []string{"dev/spidev0.0", "dev/spidev0.1"}, "disable spi (raspi-config, dtparam=spi=off)"},
+	{"SPI1", []int{16, 17, 18, 19, 20, 21}, []string{"dev/spidev1.0", "dev/spidev1.1", "dev/spidev1.2"}, "remove dtoverlay=spi1-* from config.txt"},
+	{"the serial port (UART)", []int{14, 15}, []string{"dev/serial0"}, "disable the serial port (raspi-config, enable_uart=0)"},
+	{"1-Wire", []int{4}, []string{"sys/bus/w1/devices/w1_bus_master1"}, "remove dtoverlay=w1-gpio from config.txt"},
+}
+
+// Board is a detected Raspberry Pi.
+type Board struct {
+	Model   string     // from the device tree, e.g. "Raspberry Pi 4 Model B Rev 1.4"
+	Header  []int      // GPIOs on the pin header
+	Enabled []Function // interfaces in use
+	// IDPins are GPIO 0 and 1 on 40-pin boards: they read the EEPROM of
+	// HATs at boot and are left alone.
+	IDPins bool
+}
+
+// Detect identifies the board from the device tree under root ("/" on the
+// Pi) and the interfaces enabled on it.
+func Detect(root string) (*Board, error) {
+	var model string
+	for _, f := range modelFiles {
+		data, err := os.ReadFile(filepath.Join(root, f))
+		if err == nil {
+			model = strings.TrimSpace(string(bytes.TrimRight(data, "\x00")))
+			break
+		}
+	}
+	if !strings.HasPrefix(model, "Raspberry Pi") {
+		if model == "" {
+			return nil, ErrNotRaspberryPi
+		}
+		return nil, fmt.Errorf("%w: %s", ErrNotRaspberryPi, model)
+	}
+
+	b := &Board{Model: model, Header: header40, IDPins: true}
+	switch {
+	case strings.HasPrefix(model, "Raspberry Pi Compute Module"):
+		b.IDPins = false // GPIO 0-45 on the module; the carrier board decides
+	case strings.HasPrefix(model, "Raspberry Pi Model B Rev 1"):
+		b.Header, b.IDPins = header26Rev1, false
+	case strings.HasPrefix(model, "Raspberry Pi Model A Rev"), strings.HasPrefix(model, "Raspberry Pi Model B Rev"):
+		b.Header, b.IDPins = header26Rev2, false
+	}
+	for _, fn := range Functions {
+		for _, dev := range fn.Devices {
+			if _, err := os.Stat(filepath.Join(root, dev)); err == nil {
+				b.Enabled = append(b.Enabled, fn)
+				break
+			}
+		}
+	}
+	return b, nil
+}
+
+// HeaderPins returns the number of pins of the header of b: 26 or 40.
+func (b *Board) HeaderPins() int {
+	if len(b.Header) < len(header40) {
+		return 26
+	}
+	return 40
+}
+
+// Check returns a message for each problem with pins, config keys by GPIO
+// (see config.Config.Pins): GPIOs not on the header, the HAT ID pins and
+// pins of enabled interfaces. Messages say what to change.
+func (b *Board) Check(pins map[string]int) []string {
+	users := map[int][]string{}
+	for key, pin := range pins {
+		users[pin] = append(users[pin], key)
+	}
+	used := func(gpios []int) string {
+		var keys []string
+		for _, pin := range gpios {
+			for _, key := range users[pin] {
+				keys = append(keys, fmt.Sprintf("%s = %d", key, pin))
+			}
+		}
+		sort.Strings(keys)
+		return strings.Join(keys, ", ")
+	}
+
+	var problems []string
+	var missing []int
+	for pin := range users {
+		if !slices.Contains(b.Header, pin) {
+			missing = append(missing, pin)
+		}
+	}
+	sort.Ints(missing)
+	for _, pin := range missing {
+		problems = append(problems, fmt.Sprintf("GPIO %d is not on the %d-pin header of the %s (%s); pick another pin", pin, b.HeaderPins(), b.Model, used([]int{pin})))
+	}
+	if b.IDPins {
+		if keys := used([]int{0, 1}); keys != "" {
+			problems = append(problems, fmt.Sprintf("GPIO 0/1 are reserved for the HAT ID EEPROM (%s); pick other pins", keys))
+		}
+	}
+	for _, fn := range b.Enabled {
+		if keys := used(fn.Pins); keys != "" {
+			verb, other := "are", "other pins"
+			if len(fn.Pins) == 1 {
+				verb, other = "is", "another pin"
+			}
+			problems = append(problems, fmt.Sprintf("GPIO %s %s %s (%s); %s or pick %s", joinPins(fn.Pins), verb, fn.Name, keys, fn.Disable, other))
+		}
+	}
+	return problems
+}
+
+func joinPins(pins []int) string {
+	s := make([]string, len(pins))
+	for i, p := range pins {
+		s[i] = fmt.Sprint(p)
+	}
+	return strings.Join(s, "/")
+}
@@ -0,0 +1,138 @@
+package board
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRoot returns a directory holding model as the device tree model and
+// the files devices, relative to it.
+func fakeRoot(t *testing.T, model string, devices ...string) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{}
+	if model != "" {
+		files["proc/device-tree/model"] = model + "\x00"
+	}
+	for _, dev := range devices {
+		files[dev] = ""
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// defaultPins are the pins of configs/default.yaml.
+var defaultPins = map[string]int{
+	"pan_stepper.step_pin":    17,
+	"pan_stepper.dir_pin":     27,
+	"pan_stepper.enable_pin":  5,
+	"tilt_stepper.step_pin":   22,
+	"tilt_stepper.dir_pin":    23,
+	"tilt_stepper.enable_pin": 6,
+	"camera.focus_pin":        24,
+	"camera.shutter_pin":      25,
+}
+
+// ---------- Detect ----------
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		model  string
+		header int
+		idPins bool
+	}{
+		{"Raspberry Pi 4 Model B Rev 1.4", 40, true},
+		{"Raspberry Pi Zero 2 W Rev 1.0", 40, true},
+		{"Raspberry Pi 5 Model B Rev 1.0", 40, true},
+		{"Raspberry Pi Model B Rev 1", 26, false},
+		{"Raspberry Pi Model B Rev 2", 26, false},
+		{"Raspberry Pi Model B Plus Rev 1.2", 40, true},
+		{"Raspberry Pi Compute Module 4 Rev 1.0", 40, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.model, func(t *testing.T) {
+			b, err := Detect(fakeRoot(t, tc.model))
+			if err != nil {
+				t.Fatalf("Detect: %v", err)
+			}
+			if b.Model != tc.model || b.HeaderPins() != tc.header || b.IDPins != tc.idPins || len(b.Enabled) != 0 {
+				t.Errorf("board = %+v, want %d pins, ID pins %t", b, tc.header, tc.idPins)
+			}
+		})
+	}
+}
+
+func TestDetect_NotRaspberryPi(t *testing.T) {
+	for _, model := range []string{"", "Pine64 RockPro64"} {
+		if _, err := Detect(fakeRoot(t, model)); !errors.Is(err, ErrNotRaspberryPi) {
+			t.Errorf("Detect(%q) = %v, want ErrNotRaspberryPi", model, err)
+		}
+	}
+}
+
+func TestDetect_Interfaces(t *testing.T) {
+	b, err := Detect(fakeRoot(t, "Raspberry Pi 3 Model B Rev 1.2", "dev/i2c-1", "dev/spidev0.1", "dev/serial0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, fn := range b.Enabled {
+		names = append(names, fn.Name)
+	}
+	if got := strings.Join(names, ", "); got != "I2C, SPI, the serial port (UART)" {
+		t.Errorf("enabled = %s", got)
+	}
+}
+
+// ---------- Check ----------
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		name    string
+		model   string
+		devices []string
+		pins    map[string]int
+		want    []string
+	}{
+		{"default_pins", "Raspberry Pi 4 Model B Rev 1.4", []string{"dev/i2c-1", "dev/spidev0.0", "dev/serial0"}, defaultPins, nil},
+		{"i2c", "Raspberry Pi 4 Model B Rev 1.4", []string{"dev/i2c-1"}, map[string]int{"camera.focus_pin": 2, "camera.shutter_pin": 3}, []string{
+			"GPIO 2/3 are I2C (camera.focus_pin = 2, camera.shutter_pin = 3); disable i2c (raspi-config, dtparam=i2c_arm=off) or pick other pins",
+		}},
+		{"i2c_disabled", "Raspberry Pi 4 Model B Rev 1.4", nil, map[string]int{"camera.focus_pin": 2}, nil},
+		{"one_wire", "Raspberry Pi 4 Model B Rev 1.4", []string{"sys/bus/w1/devices/w1_bus_master1"}, map[string]int{"trigger.pin": 4}, []string{
+			"GPIO 4 is 1-Wire (trigger.pin = 4); remove dtoverlay=w1-gpio from config.txt or pick another pin",
+		}},
+		{"id_pins", "Raspberry Pi 4 Model B Rev 1.4", nil, map[string]int{"trigger.pin": 1}, []string{
+			"GPIO 0/1 are reserved for the HAT ID EEPROM (trigger.pin = 1); pick other pins",
+		}},
+		{"not_on_header", "Raspberry Pi Model B Rev 2", nil, defaultPins, []string{
+			"GPIO 5 is not on the 26-pin header of the Raspberry Pi Model B Rev 2 (pan_stepper.enable_pin = 5); pick another pin",
+			"GPIO 6 is not on the 26-pin header of the Raspberry Pi Model B Rev 2 (tilt_stepper.enable_pin = 6); pick another pin",
+		}},
+		{"rev1_header", "Raspberry Pi Model B Rev 1", nil, map[string]int{"pan_stepper.dir_pin": 27, "camera.focus_pin": 1}, []string{
+			"GPIO 27 is not on the 26-pin header of the Raspberry Pi Model B Rev 1 (pan_stepper.dir_pin = 27); pick another pin",
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := Detect(fakeRoot(t, tc.model, tc.devices...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.Check(tc.pins); strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("Check =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package camera
 
+import (
+	"context"
+	"time"
+)
+
 // Camera is the high-level interface used by the rest of the application.
 // It represents an abstract "camera", regardless of how it's controlled
 // (GPIO, USB, network protocol, etc.).
@@ -7,3 +12,45 @@ type Camera interface {
 	// Shoot triggers a single photo capture (simple mode).
 	Shoot() error
 }
+
+// BulbCamera is implemented by cameras that can hold the shutter open for a
+// caller-chosen duration (camera set to Bulb mode). Used for exposure ramping.
+type BulbCamera interface {
+	Camera
+	// ShootBulb triggers a photo and keeps the shutter open for exposure.
+	ShootBulb(exposure time.Duration) error
+}
+
+// LiveViewCamera is implemented by cameras that can stream their live view
+// (USB or network backends such as gphoto2 or Canon CCAPI). GPIO-triggered
+// cameras cannot.
+type LiveViewCamera interface {
+	Camera
+	// LiveViewFrame returns the current live view frame as a JPEG image.
+	LiveViewFrame(ctx context.Context) ([]byte, error)
+}
+
+// DownloadCamera is implemented by tethered cameras (USB or network
+// backends) that can transfer the pictures they take. GPIO-triggered
+// cameras cannot.
+type DownloadCamera interface {
+	Camera
+	// DownloadLast returns the last picture taken as a JPEG image (its
+	// embedded preview for RAW files).
+	DownloadLast(ctx context.Context) ([]byte, error)
+}
+
+// Checker is implemented by cameras that can tell whether they are connected
+// and responding (USB, network backends). GPIO-wired cameras cannot.
+type Checker interface {
+	// Check returns an error when the camera cannot be reached.
+	Check(ctx context.Context) error
+}
+
+// Releaser is implemented by cameras triggered through lines that a failed
+// capture could leave active (GPIO-wired cameras).
+type Releaser interface {
+	// Release sets the trigger lines back to inactive, ending any exposure
+	// or autofocus in progress.
+	Release() error
+}
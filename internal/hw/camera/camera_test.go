@@ -1,6 +1,8 @@
 package camera
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -118,5 +120,63 @@ func TestNikonD90GPIO_ShootReturnsNoError(t *testing.T) {
 func TestNikonD90GPIO_ImplementsCamera(t *testing.T) {
 	drv := &recordingDriver{}
 	cam := NewNikonD90GPIO(drv, 24, 25, time.Millisecond, time.Millisecond)
-	var _ Camera = cam // compile-time check
+	var _ Camera = cam     // compile-time check
+	var _ BulbCamera = cam // compile-time check
+}
+
+func TestNikonD90GPIO_ShootBulbHoldsShutter(t *testing.T) {
+	drv := &recordingDriver{}
+	cam := NewNikonD90GPIO(drv, 24, 25, 1*time.Microsecond, 1*time.Microsecond)
+	drv.calls = nil
+
+	start := time.Now()
+	if err := cam.ShootBulb(30 * time.Millisecond); err != nil {
+		t.Fatalf("ShootBulb: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("ShootBulb returned after %v, want >= 30ms exposure", elapsed)
+	}
+
+	writes := drv.writeCalls()
+	if len(writes) != 4 {
+		t.Fatalf("expected 4 writes, got %d: %v", len(writes), writes)
+	}
+	if writes[1].pin != 25 || writes[1].level != gpio.Low || writes[2].pin != 25 || writes[2].level != gpio.High {
+		t.Errorf("shutter should go LOW then HIGH, got %v", writes)
+	}
+}
+
+func TestNikonD90GPIO_Release(t *testing.T) {
+	drv := &recordingDriver{}
+	cam := NewNikonD90GPIO(drv, 24, 25, time.Millisecond, time.Millisecond)
+	var _ Releaser = cam // compile-time check
+	drv.calls = nil
+
+	if err := cam.Release(); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	want := []gpioCall{{op: "write", pin: 25, level: gpio.High}, {op: "write", pin: 24, level: gpio.High}}
+	if got := drv.writeCalls(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("writes = %v, want shutter then focus HIGH %v", got, want)
+	}
+}
+
+// ---------- DryRun ----------
+
+func TestDryRun(t *testing.T) {
+	var lines []string
+	cam := NewDryRun(func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+	var _ BulbCamera = cam // compile-time check
+	if err := cam.Shoot(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cam.ShootBulb(2 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"dry run: camera shot", "dry run: camera bulb shot, 2ms"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Errorf("log = %q, want %q", lines, want)
+	}
 }
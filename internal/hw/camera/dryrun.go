@@ -0,0 +1,28 @@
+package camera
+
+import "time"
+
+// DryRun is a camera that only logs the shots it is asked for, to rehearse
+// a capture without triggering the real camera.
+type DryRun struct {
+	logf func(format string, args ...any)
+}
+
+// NewDryRun returns a DryRun camera logging with logf (e.g. log.Printf).
+func NewDryRun(logf func(format string, args ...any)) *DryRun {
+	return &DryRun{logf: logf}
+}
+
+// Shoot logs a shot.
+func (d *DryRun) Shoot() error {
+	d.logf("dry run: camera shot")
+	return nil
+}
+
+// ShootBulb logs a bulb shot, returning after the exposure like a real
+// one.
+func (d *DryRun) ShootBulb(exposure time.Duration) error {
+	d.logf("dry run: camera bulb shot, %v", exposure)
+	time.Sleep(exposure)
+	return nil
+}
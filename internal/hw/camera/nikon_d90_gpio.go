@@ -1,12 +1,16 @@
 package camera
 
 import (
+	"errors"
 	"time"
 
 	"github.com/cjeanneret/PanGo/internal/debug"
 	"github.com/cjeanneret/PanGo/internal/hw/gpio"
 )
 
+// dbg is the debug output of the package.
+var dbg = debug.For("camera")
+
 // NikonD90GPIO is a Camera implementation for a Nikon D90
 // controlled via the 3-pin remote connector:
 // - GND: connected to Raspberry Pi ground
@@ -52,20 +56,39 @@ func NewNikonD90GPIO(g gpio.Driver, focusPin, shutterPin int, focusDelay, shutte
 // Shoot triggers a photo on the D90.
 // Sequence: FOCUS -> wait for AF -> SHUTTER -> hold -> release
 func (n *NikonD90GPIO) Shoot() error {
-	debug.Printf("Camera: triggering shot (focus=%d, shutter=%d)", n.focusPin, n.shutterPin)
+	dbg.Verbose("camera: triggering shot", "focus_pin", n.focusPin, "shutter_pin", n.shutterPin)
+	if err := n.trigger(n.shutterDelay); err != nil {
+		return err
+	}
+	dbg.Verbose("camera: shot triggered")
+	return nil
+}
 
+// ShootBulb triggers a photo with the shutter held for the given exposure.
+// The camera must be in Bulb mode: the exposure lasts as long as SHUTTER is LOW.
+func (n *NikonD90GPIO) ShootBulb(exposure time.Duration) error {
+	dbg.Verbose("camera: triggering bulb shot", "exposure", exposure, "focus_pin", n.focusPin, "shutter_pin", n.shutterPin)
+	if err := n.trigger(exposure); err != nil {
+		return err
+	}
+	dbg.Verbose("camera: bulb shot complete")
+	return nil
+}
+
+// trigger runs the FOCUS/SHUTTER sequence, holding SHUTTER LOW for hold.
+func (n *NikonD90GPIO) trigger(hold time.Duration) error {
 	// 1. Activate FOCUS (autofocus)
-	debug.Verbose("Camera: activating FOCUS (pin %d -> LOW)", n.focusPin)
+	dbg.Verbose("camera: activating focus", "pin", n.focusPin, "pin_level", "LOW")
 	if err := n.gpio.WritePin(n.focusPin, gpio.Low); err != nil {
 		return err
 	}
 
 	// 2. Wait for autofocus to complete
-	debug.Verbose("Camera: waiting for autofocus (%v)", n.focusDelay)
+	dbg.Verbose("camera: waiting for autofocus", "delay", n.focusDelay)
 	time.Sleep(n.focusDelay)
 
 	// 3. Activate SHUTTER (trigger)
-	debug.Verbose("Camera: activating SHUTTER (pin %d -> LOW)", n.shutterPin)
+	dbg.Verbose("camera: activating shutter", "pin", n.shutterPin, "pin_level", "LOW")
 	if err := n.gpio.WritePin(n.shutterPin, gpio.Low); err != nil {
 		// Release FOCUS on error
 		_ = n.gpio.WritePin(n.focusPin, gpio.High)
@@ -73,20 +96,25 @@ func (n *NikonD90GPIO) Shoot() error {
 	}
 
 	// 4. Hold shutter
-	debug.Verbose("Camera: holding shutter (%v)", n.shutterDelay)
-	time.Sleep(n.shutterDelay)
+	dbg.Verbose("camera: holding shutter", "hold", hold)
+	time.Sleep(hold)
 
 	// 5. Release SHUTTER then FOCUS
-	debug.Verbose("Camera: releasing SHUTTER (pin %d -> HIGH)", n.shutterPin)
+	dbg.Verbose("camera: releasing shutter", "pin", n.shutterPin, "pin_level", "HIGH")
 	if err := n.gpio.WritePin(n.shutterPin, gpio.High); err != nil {
 		return err
 	}
 
-	debug.Verbose("Camera: releasing FOCUS (pin %d -> HIGH)", n.focusPin)
+	dbg.Verbose("camera: releasing focus", "pin", n.focusPin, "pin_level", "HIGH")
 	if err := n.gpio.WritePin(n.focusPin, gpio.High); err != nil {
 		return err
 	}
-
-	debug.Print("Camera: shot triggered successfully")
 	return nil
 }
+
+// Release sets SHUTTER then FOCUS back to HIGH (inactive), e.g. after a
+// panic in the middle of a shot. Both are released even if one fails.
+func (n *NikonD90GPIO) Release() error {
+	dbg.Verbose("camera: releasing trigger lines", "focus_pin", n.focusPin, "shutter_pin", n.shutterPin)
+	return errors.Join(n.gpio.WritePin(n.shutterPin, gpio.High), n.gpio.WritePin(n.focusPin, gpio.High))
+}
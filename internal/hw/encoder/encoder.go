@@ -0,0 +1,175 @@
+// Package encoder reads a rotary encoder with a push button wired to GPIO
+// inputs, e.g. a KY-040 module: turns of the knob, one event per detent,
+// and presses of the button, short or long. The lines are polled, as the
+// trigger input is, often enough for a knob turned by hand.
+package encoder
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+)
+
+// pollInterval is how often the lines are sampled. A quick turn of a 20
+// detent knob is a few hundred transitions per second.
+const pollInterval = time.Millisecond
+
+// Event is an input of the encoder.
+type Event int
+
+const (
+	Clockwise        Event = iota + 1 // the knob turned one detent clockwise
+	Counterclockwise                  // one detent counterclockwise
+	Press                             // the button was pressed and released
+	LongPress                         // the button has been held for Options.LongPress
+)
+
+func (e Event) String() string {
+	switch e {
+	case Clockwise:
+		return "clockwise"
+	case Counterclockwise:
+		return "counterclockwise"
+	case Press:
+		return "press"
+	case LongPress:
+		return "long press"
+	}
+	return fmt.Sprintf("Event(%d)", int(e))
+}
+
+// Options configures an Encoder. The lines need pull-up resistors (KY-040
+// modules have them), or the Pi's internal ones.
+type Options struct {
+	PinA, PinB      int           // quadrature outputs (CLK and DT on KY-040), BCM; swap them to reverse the direction
+	ButtonPin       int           // push button (SW), BCM; 0 = none
+	ButtonActiveLow bool          // pressing pulls the line LOW
+	StepsPerDetent  int           // quadrature transitions per detent: 4 on most encoders, 2 or 1 on some
+	Debounce        time.Duration // the button must hold its level this long to count
+	LongPress       time.Duration // holding the button this long is a LongPress instead of a Press
+}
+
+// transitions gives the direction of a quadrature transition, indexed by
+// the previous state and the new one (A<<1 | B): +1 clockwise, -1
+// counterclockwise, 0 for no change or a skipped state (bounce).
+var transitions = [16]int{
+	0, -1, 1, 0,
+	1, 0, 0, -1,
+	-1, 0, 0, 1,
+	0, 1, -1, 0,
+}
+
+// Encoder decodes the lines of a rotary encoder.
+type Encoder struct {
+	gpio gpio.Driver
+	opts Options
+
+	state int // last quadrature state, A<<1 | B; -1 before the first sample
+	count int // transitions since the last detent, signed
+
+	pressed     bool      // debounced button state
+	changing    time.Time // when the raw button level started to differ from pressed; zero when it does not
+	pressedAt   time.Time
+	longPressed bool // LongPress sent for the current press
+}
+
+// New configures the pins of o as inputs.
+func New(g gpio.Driver, o Options) *Encoder {
+	if o.StepsPerDetent <= 0 {
+		o.StepsPerDetent = 4
+	}
+	for _, pin := range []int{o.PinA, o.PinB, o.ButtonPin} {
+		if pin != 0 {
+			_ = g.SetupPin(pin, gpio.Input)
+		}
+	}
+	return &Encoder{gpio: g, opts: o, state: -1}
+}
+
+// Run samples the lines until ctx is cancelled or a read fails, passing
+// the events to fn.
+func (e *Encoder) Run(ctx context.Context, fn func(Event)) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		if err := e.sample(time.Now(), fn); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// sample reads the lines once, at time now.
+func (e *Encoder) sample(now time.Time, fn func(Event)) error {
+	a, err := e.gpio.ReadPin(e.opts.PinA)
+	if err != nil {
+		return fmt.Errorf("read encoder pin %d: %w", e.opts.PinA, err)
+	}
+	b, err := e.gpio.ReadPin(e.opts.PinB)
+	if err != nil {
+		return fmt.Errorf("read encoder pin %d: %w", e.opts.PinB, err)
+	}
+	e.turn(bit(a)<<1|bit(b), fn)
+
+	if e.opts.ButtonPin == 0 {
+		return nil
+	}
+	level, err := e.gpio.ReadPin(e.opts.ButtonPin)
+	if err != nil {
+		return fmt.Errorf("read button pin %d: %w", e.opts.ButtonPin, err)
+	}
+	e.button(level == gpio.Level(!e.opts.ButtonActiveLow), now, fn)
+	return nil
+}
+
+func bit(l gpio.Level) int {
+	if l == gpio.High {
+		return 1
+	}
+	return 0
+}
+
+// turn takes the quadrature state into account.
+func (e *Encoder) turn(state int, fn func(Event)) {
+	if e.state >= 0 {
+		e.count += transitions[e.state<<2|state]
+	}
+	e.state = state
+	switch {
+	case e.count >= e.opts.StepsPerDetent:
+		e.count = 0
+		fn(Clockwise)
+	case e.count <= -e.opts.StepsPerDetent:
+		e.count = 0
+		fn(Counterclockwise)
+	}
+}
+
+// button takes the raw state of the button into account, at time now.
+func (e *Encoder) button(down bool, now time.Time, fn func(Event)) {
+	if down == e.pressed {
+		e.changing = time.Time{}
+	} else {
+		if e.changing.IsZero() {
+			e.changing = now
+		}
+		if now.Sub(e.changing) >= e.opts.Debounce {
+			e.pressed, e.changing = down, time.Time{}
+			if down {
+				e.pressedAt, e.longPressed = now, false
+			} else if !e.longPressed {
+				fn(Press)
+			}
+		}
+	}
+	if e.pressed && !e.longPressed && e.opts.LongPress > 0 && now.Sub(e.pressedAt) >= e.opts.LongPress {
+		e.longPressed = true
+		fn(LongPress)
+	}
+}
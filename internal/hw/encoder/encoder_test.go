@@ -0,0 +1,183 @@
+package encoder
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+)
+
+// lineDriver holds the levels of the lines, set by the test.
+type lineDriver struct {
+	levels map[int]gpio.Level
+	inputs []int
+	err    error
+}
+
+func (d *lineDriver) SetupPin(pin int, mode gpio.PinMode) error {
+	if mode == gpio.Input {
+		d.inputs = append(d.inputs, pin)
+	}
+	return nil
+}
+
+func (d *lineDriver) WritePin(pin int, level gpio.Level) error { return nil }
+
+func (d *lineDriver) ReadPin(pin int) (gpio.Level, error) {
+	return d.levels[pin], d.err
+}
+
+func (d *lineDriver) Close() error { return nil }
+
+const (
+	pinA   = 5
+	pinB   = 6
+	button = 13
+)
+
+// newTestEncoder returns an encoder on a lineDriver with both quadrature
+// lines high and the button (active low) released, as at rest.
+func newTestEncoder(o Options) (*Encoder, *lineDriver) {
+	d := &lineDriver{levels: map[int]gpio.Level{pinA: gpio.High, pinB: gpio.High, button: gpio.High}}
+	o.PinA, o.PinB, o.ButtonPin, o.ButtonActiveLow = pinA, pinB, button, true
+	return New(d, o), d
+}
+
+// recorder collects events.
+type recorder []Event
+
+func (r *recorder) add(e Event) { *r = append(*r, e) }
+
+// ---------- Turns ----------
+
+// quadrature is the sequence of the lines (A, B) over one detent clockwise,
+// from rest.
+var quadrature = [][2]gpio.Level{
+	{gpio.Low, gpio.High}, {gpio.Low, gpio.Low}, {gpio.High, gpio.Low}, {gpio.High, gpio.High},
+}
+
+func TestEncoder_Turns(t *testing.T) {
+	cases := []struct {
+		name    string
+		steps   int
+		reverse bool
+		detents int
+		want    []Event
+	}{
+		{"one detent clockwise", 4, false, 1, []Event{Clockwise}},
+		{"two detents counterclockwise", 4, true, 2, []Event{Counterclockwise, Counterclockwise}},
+		{"half-step encoder", 2, false, 1, []Event{Clockwise, Clockwise}},
+		{"one event per transition", 1, true, 1, []Event{Counterclockwise, Counterclockwise, Counterclockwise, Counterclockwise}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, d := newTestEncoder(Options{StepsPerDetent: tc.steps})
+			var got recorder
+			now := time.Unix(0, 0)
+			if err := e.sample(now, got.add); err != nil {
+				t.Fatal(err)
+			}
+			for range tc.detents {
+				for i := range quadrature {
+					s := quadrature[i]
+					if tc.reverse {
+						s = quadrature[(len(quadrature)-2-i+len(quadrature))%len(quadrature)]
+					}
+					d.levels[pinA], d.levels[pinB] = s[0], s[1]
+					if err := e.sample(now, got.add); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			if !reflect.DeepEqual([]Event(got), tc.want) {
+				t.Errorf("events = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEncoder_BounceIsNotATurn(t *testing.T) {
+	e, d := newTestEncoder(Options{})
+	var got recorder
+	now := time.Unix(0, 0)
+	e.sample(now, got.add)
+	// A contact chattering between two states goes back and forth.
+	for range 10 {
+		d.levels[pinB] = gpio.Low
+		e.sample(now, got.add)
+		d.levels[pinB] = gpio.High
+		e.sample(now, got.add)
+	}
+	if len(got) != 0 {
+		t.Errorf("events = %v, want none", got)
+	}
+}
+
+// ---------- Button ----------
+
+func TestEncoder_Button(t *testing.T) {
+	cases := []struct {
+		name string
+		// held is how long the button is pressed; glitch is a press shorter
+		// than the debounce.
+		held   time.Duration
+		glitch bool
+		want   []Event
+	}{
+		{"short press", 200 * time.Millisecond, false, []Event{Press}},
+		{"long press", 1500 * time.Millisecond, false, []Event{LongPress}},
+		{"glitch", 0, true, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, d := newTestEncoder(Options{Debounce: 20 * time.Millisecond, LongPress: time.Second})
+			var got recorder
+			now := time.Unix(0, 0)
+			step := func(level gpio.Level, dur time.Duration) {
+				d.levels[button] = level
+				for end := now.Add(dur); !now.After(end); now = now.Add(time.Millisecond) {
+					if err := e.sample(now, got.add); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+			step(gpio.High, 10*time.Millisecond)
+			if tc.glitch {
+				step(gpio.Low, 5*time.Millisecond)
+			} else {
+				step(gpio.Low, tc.held)
+			}
+			step(gpio.High, 100*time.Millisecond)
+			if !reflect.DeepEqual([]Event(got), tc.want) {
+				t.Errorf("events = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// ---------- Run ----------
+
+func TestNew_SetsUpPinsAsInputs(t *testing.T) {
+	_, d := newTestEncoder(Options{})
+	if want := []int{pinA, pinB, button}; !reflect.DeepEqual(d.inputs, want) {
+		t.Errorf("input pins = %v, want %v", d.inputs, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	e, _ := newTestEncoder(Options{})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := e.Run(ctx, func(Event) {}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Run = %v, want the context error", err)
+	}
+
+	e, d := newTestEncoder(Options{})
+	d.err = errors.New("gpio gone")
+	if err := e.Run(context.Background(), func(Event) {}); err == nil || !errors.Is(err, d.err) {
+		t.Errorf("Run = %v, want the read error", err)
+	}
+}
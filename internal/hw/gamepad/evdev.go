@@ -0,0 +1,90 @@
+package gamepad
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+// Event types of linux/input-event-codes.h.
+const (
+	evKey = 0x01
+	evAbs = 0x03
+)
+
+// axisCodes are the absolute axes of gamepads and joysticks by name: the
+// left stick (x, y), the right stick (rx, ry, or z, rz on some pads) and
+// the directional pad (hat0x, hat0y).
+var axisCodes = map[string]uint16{
+	"x": 0x00, "y": 0x01, "z": 0x02,
+	"rx": 0x03, "ry": 0x04, "rz": 0x05,
+	"hat0x": 0x10, "hat0y": 0x11,
+}
+
+// buttonCodes are the buttons of gamepads (BTN_SOUTH...) and joysticks
+// (BTN_TRIGGER...) by name. south is A on Xbox pads, cross on PlayStation
+// ones.
+var buttonCodes = map[string]uint16{
+	"trigger": 0x120, "thumb": 0x121, "thumb2": 0x122, "top": 0x123,
+	"south": 0x130, "east": 0x131, "c": 0x132, "north": 0x133, "west": 0x134, "z": 0x135,
+	"tl": 0x136, "tr": 0x137, "tl2": 0x138, "tr2": 0x139,
+	"select": 0x13a, "start": 0x13b, "mode": 0x13c, "thumbl": 0x13d, "thumbr": 0x13e,
+}
+
+// AxisCode returns the evdev code of the axis called name.
+func AxisCode(name string) (uint16, error) {
+	code, ok := axisCodes[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown axis %q (one of %s)", name, names(axisCodes))
+	}
+	return code, nil
+}
+
+// ButtonCode returns the evdev code of the button called name.
+func ButtonCode(name string) (uint16, error) {
+	code, ok := buttonCodes[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown button %q (one of %s)", name, names(buttonCodes))
+	}
+	return code, nil
+}
+
+// names lists the keys of codes in order of their codes.
+func names(codes map[string]uint16) string {
+	var list []string
+	for name := range codes {
+		list = append(list, name)
+	}
+	sort.Slice(list, func(i, j int) bool { return codes[list[i]] < codes[list[j]] })
+	return strings.Join(list, ", ")
+}
+
+// event is an input event: an axis moved (evAbs) or a button changed
+// (evKey, value 1 pressed, 0 released, 2 repeated).
+type event struct {
+	typ, code uint16
+	value     int32
+}
+
+// eventSize is the size of struct input_event: a timeval of two longs, then
+// type, code and value.
+const eventSize = 2*bits.UintSize/8 + 8
+
+// readEvents reads input events from rd until it fails, passing each to fn.
+func readEvents(rd io.Reader, fn func(event)) error {
+	buf := make([]byte, eventSize)
+	for {
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return err
+		}
+		b := buf[eventSize-8:]
+		fn(event{
+			typ:   binary.NativeEndian.Uint16(b),
+			code:  binary.NativeEndian.Uint16(b[2:]),
+			value: int32(binary.NativeEndian.Uint32(b[4:])),
+		})
+	}
+}
@@ -0,0 +1,41 @@
+//go:build linux
+
+package gamepad
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// absInfo is struct input_absinfo: the state and range of an axis.
+type absInfo struct {
+	Value, Minimum, Maximum, Fuzz, Flat, Resolution int32
+}
+
+// absRange returns the range of the axis code of the device f, from the
+// EVIOCGABS ioctl. It goes through SyscallConn, as Fd would make f
+// blocking and Close could then not interrupt a read.
+func absRange(f *os.File, code uint16) (minimum, maximum int32, err error) {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return 0, 0, err
+	}
+	var info absInfo
+	const iocRead = 2
+	req := uintptr(iocRead<<30 | unsafe.Sizeof(info)<<16 | 'E'<<8 | (0x40 + uintptr(code)))
+	var errno syscall.Errno
+	if err := rc.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(unsafe.Pointer(&info)))
+	}); err != nil {
+		return 0, 0, err
+	}
+	if errno != 0 {
+		return 0, 0, fmt.Errorf("range of axis %#x: %w", code, errno)
+	}
+	if info.Maximum <= info.Minimum {
+		return 0, 0, fmt.Errorf("axis %#x has no range", code)
+	}
+	return info.Minimum, info.Maximum, nil
+}
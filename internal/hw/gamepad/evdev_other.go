@@ -0,0 +1,10 @@
+//go:build !linux
+
+package gamepad
+
+import "os"
+
+// absRange needs the evdev ioctls of Linux; Open refuses other platforms.
+func absRange(f *os.File, code uint16) (minimum, maximum int32, err error) {
+	return 0, 0, ErrUnsupported
+}
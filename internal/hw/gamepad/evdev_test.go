@@ -0,0 +1,70 @@
+package gamepad
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+)
+
+// encode returns the input_event bytes of events.
+func encode(events ...event) []byte {
+	var b bytes.Buffer
+	for _, e := range events {
+		b.Write(make([]byte, eventSize-8)) // timeval
+		binary.Write(&b, binary.NativeEndian, e.typ)
+		binary.Write(&b, binary.NativeEndian, e.code)
+		binary.Write(&b, binary.NativeEndian, e.value)
+	}
+	return b.Bytes()
+}
+
+// ---------- readEvents ----------
+
+func TestReadEvents(t *testing.T) {
+	want := []event{
+		{evAbs, axisCodes["x"], -32768},
+		{evAbs, axisCodes["y"], 255},
+		{evKey, buttonCodes["south"], 1},
+		{0, 0, 0}, // EV_SYN
+	}
+	var got []event
+	err := readEvents(bytes.NewReader(encode(want...)), func(e event) { got = append(got, e) })
+	if err != io.EOF {
+		t.Errorf("err = %v, want EOF", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReadEvents_Truncated(t *testing.T) {
+	data := encode(event{evAbs, 0, 1})
+	err := readEvents(bytes.NewReader(data[:eventSize-1]), func(event) { t.Error("event from a truncated read") })
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("err = %v, want ErrUnexpectedEOF", err)
+	}
+}
+
+// ---------- AxisCode / ButtonCode ----------
+
+func TestCodes(t *testing.T) {
+	if code, err := AxisCode("hat0y"); err != nil || code != 0x11 {
+		t.Errorf("AxisCode(hat0y) = %#x, %v", code, err)
+	}
+	if code, err := ButtonCode("start"); err != nil || code != 0x13b {
+		t.Errorf("ButtonCode(start) = %#x, %v", code, err)
+	}
+	if _, err := AxisCode("w"); err == nil || !strings.Contains(err.Error(), "x, y, z, rx") {
+		t.Errorf("AxisCode(w) = %v, want the list of axes", err)
+	}
+	if _, err := ButtonCode("a"); err == nil {
+		t.Error("ButtonCode(a): no error")
+	}
+}
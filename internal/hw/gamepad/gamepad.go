@@ -0,0 +1,347 @@
+// Package gamepad jogs the head with a gamepad or joystick plugged into the
+// Pi, read through the Linux evdev interface (/dev/input/event*): a stick
+// moves the head, faster the further it is pushed, and a button takes a
+// test shot. Events are decoded without a client library.
+package gamepad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Reconnection delays, and the steps of each move of the head between two
+// looks at the stick.
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 30 * time.Second
+	sliceSteps    = 32
+)
+
+// Mover moves the head and shoots, as the web jog does.
+type Mover interface {
+	// Jog moves one axis ("pan" or "tilt") by steps, with speedMs between
+	// steps, until done or ctx is cancelled.
+	Jog(ctx context.Context, axis string, steps, speedMs int) error
+	// Shoot takes a test shot.
+	Shoot(ctx context.Context) error
+}
+
+// Options configures a Controller. Axes and buttons are named as for
+// AxisCode and ButtonCode.
+type Options struct {
+	Device      string  // evdev device, e.g. /dev/input/by-id/usb-...-event-joystick
+	PanAxis     string  // axis moving pan: right is clockwise
+	TiltAxis    string  // axis moving tilt: pushed up (negative values) is up
+	InvertPan   bool    // reverse the pan axis
+	InvertTilt  bool    // reverse the tilt axis
+	DeadZone    float64 // fraction of the travel around the center ignored, [0, 1)
+	Curve       float64 // exponent of the speed curve, >= 1: 1 linear, more for finer control near the center
+	SlowSpeedMs int     // delay between steps just past the dead zone
+	FastSpeedMs int     // delay between steps at full deflection
+	ShootButton string  // button taking a test shot; "" = none
+}
+
+// axis is a stick axis: its code and range, and the deflection last read.
+type axis struct {
+	code       uint16
+	min, max   int32
+	invert     bool
+	deflection float64 // -1..1 in the direction of motion, 0 at the center
+}
+
+// set records the raw value v of a.
+func (a *axis) set(v int32) {
+	d := 2*float64(v-a.min)/float64(a.max-a.min) - 1
+	d = math.Max(-1, math.Min(1, d))
+	if a.invert {
+		d = -d
+	}
+	a.deflection = d
+}
+
+// motion is where an axis moves: its direction (-1, 0 or 1) and the delay
+// between steps.
+type motion struct {
+	dir     int
+	speedMs int
+}
+
+// Controller reads a gamepad in the background and moves the head until
+// closed.
+type Controller struct {
+	opts  Options
+	mover Mover
+	shoot uint16 // 0 = no shoot button
+
+	mu        sync.Mutex
+	pan, tilt axis
+
+	changed chan struct{} // the stick moved
+	shots   chan struct{} // the shoot button was pressed
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// ErrUnsupported is returned by Open on platforms without evdev, all but
+// Linux.
+var ErrUnsupported = errors.New("gamepad unsupported")
+
+// Open starts reading the gamepad of o and moving the head through m. Read
+// errors are logged and the device is reopened with a growing delay, so
+// the gamepad may be plugged in later or unplugged and replugged; the head
+// stops meanwhile.
+func Open(o Options, m Mover) (*Controller, error) {
+	if runtime.GOOS != "linux" {
+		return nil, ErrUnsupported
+	}
+	c, err := newController(o, m)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.wg.Add(2)
+	go func() {
+		defer c.wg.Done()
+		c.run(ctx)
+	}()
+	go func() {
+		defer c.wg.Done()
+		c.drive(ctx)
+	}()
+	return c, nil
+}
+
+// newController checks o and returns its controller, not started.
+func newController(o Options, m Mover) (*Controller, error) {
+	if o.Device == "" {
+		return nil, errors.New("gamepad: no device")
+	}
+	pan, err := AxisCode(o.PanAxis)
+	if err != nil {
+		return nil, fmt.Errorf("gamepad: pan: %w", err)
+	}
+	tilt, err := AxisCode(o.TiltAxis)
+	if err != nil {
+		return nil, fmt.Errorf("gamepad: tilt: %w", err)
+	}
+	if pan == tilt {
+		return nil, errors.New("gamepad: pan and tilt need different axes")
+	}
+	if o.DeadZone < 0 || o.DeadZone >= 1 || o.Curve < 1 || o.FastSpeedMs < 1 || o.SlowSpeedMs < o.FastSpeedMs {
+		return nil, fmt.Errorf("gamepad: invalid speed curve %+v", o)
+	}
+	c := &Controller{
+		opts:    o,
+		mover:   m,
+		pan:     axis{code: pan, min: -1, max: 1, invert: o.InvertPan},
+		tilt:    axis{code: tilt, min: -1, max: 1, invert: !o.InvertTilt},
+		changed: make(chan struct{}, 1),
+		shots:   make(chan struct{}, 1),
+	}
+	if o.ShootButton != "" {
+		if c.shoot, err = ButtonCode(o.ShootButton); err != nil {
+			return nil, fmt.Errorf("gamepad: shoot: %w", err)
+		}
+	}
+	return c, nil
+}
+
+// Close stops reading, stops the head and waits for both.
+func (c *Controller) Close() {
+	c.cancel()
+	c.wg.Wait()
+}
+
+// run reads the device until ctx is cancelled, reopening it when it fails.
+func (c *Controller) run(ctx context.Context) {
+	delay := minRetryDelay
+	for {
+		start := time.Now()
+		err := c.read(ctx)
+		c.center()
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > maxRetryDelay {
+			delay = minRetryDelay // it worked for a while
+		}
+		log.Printf("gamepad: %s: %v; retrying in %v", c.opts.Device, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// read reads the events of the device until it fails or ctx is cancelled.
+func (c *Controller) read(ctx context.Context) error {
+	f, err := os.Open(c.opts.Device)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, a := range []*axis{&c.pan, &c.tilt} {
+		lo, hi, err := absRange(f, a.code)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		a.min, a.max = lo, hi
+		c.mu.Unlock()
+	}
+	stop := context.AfterFunc(ctx, func() { f.Close() })
+	defer stop()
+	return readEvents(f, c.handle)
+}
+
+// handle takes an event of the device into account.
+func (c *Controller) handle(e event) {
+	switch {
+	case e.typ == evAbs && (e.code == c.pan.code || e.code == c.tilt.code):
+		c.mu.Lock()
+		if e.code == c.pan.code {
+			c.pan.set(e.value)
+		} else {
+			c.tilt.set(e.value)
+		}
+		c.mu.Unlock()
+		notify(c.changed)
+	case e.typ == evKey && c.shoot != 0 && e.code == c.shoot && e.value == 1:
+		notify(c.shots)
+	}
+}
+
+// center records the stick as released, e.g. when the gamepad is unplugged.
+func (c *Controller) center() {
+	c.mu.Lock()
+	c.pan.deflection, c.tilt.deflection = 0, 0
+	c.mu.Unlock()
+	notify(c.changed)
+}
+
+// notify signals ch without blocking: a signal pending is enough.
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// motions returns where the stick moves each axis.
+func (c *Controller) motions() (pan, tilt motion) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.motion(c.pan.deflection), c.motion(c.tilt.deflection)
+}
+
+// motion returns the motion of an axis deflected by d: none within the dead
+// zone, then from SlowSpeedMs to FastSpeedMs along the curve.
+func (c *Controller) motion(d float64) motion {
+	a := math.Abs(d)
+	if a <= c.opts.DeadZone {
+		return motion{}
+	}
+	n := (a - c.opts.DeadZone) / (1 - c.opts.DeadZone)
+	slow, fast := float64(c.opts.SlowSpeedMs), float64(c.opts.FastSpeedMs)
+	m := motion{dir: 1, speedMs: int(math.Round(slow - math.Pow(n, c.opts.Curve)*(slow-fast)))}
+	if d < 0 {
+		m.dir = -1
+	}
+	return m
+}
+
+// drive moves the head as the stick says, in slices of sliceSteps on one
+// axis at a time, alternating axes when both move, until ctx is cancelled.
+// A slice stops early when its axis is released or reversed.
+func (c *Controller) drive(ctx context.Context) {
+	onTilt := false
+	failing := false // log the first of consecutive errors only
+	for {
+		select {
+		case <-c.shots:
+			c.takeShot(ctx)
+		default:
+		}
+		pan, tilt := c.motions()
+		if pan.dir == 0 && tilt.dir == 0 {
+			select {
+			case <-c.changed:
+			case <-c.shots:
+				c.takeShot(ctx)
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		if pan.dir == 0 || tilt.dir == 0 {
+			onTilt = tilt.dir != 0
+		}
+		name, m := "pan", pan
+		if onTilt {
+			name, m = "tilt", tilt
+		}
+		onTilt = !onTilt
+
+		err := c.slice(ctx, name, m)
+		switch {
+		case err == nil, ctx.Err() != nil, errors.Is(err, context.Canceled):
+			failing = false
+		default:
+			if !failing {
+				log.Printf("gamepad: %v", err)
+			}
+			failing = true
+			select { // e.g. a capture holds the head: do not spin
+			case <-time.After(minRetryDelay):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// slice moves the axis called name by sliceSteps as m says.
+func (c *Controller) slice(ctx context.Context, name string, m motion) error {
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	done := make(chan error, 1)
+	go func() { done <- c.mover.Jog(ctx, name, m.dir*sliceSteps, m.speedMs) }()
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-c.changed:
+			pan, tilt := c.motions()
+			now := pan
+			if name == "tilt" {
+				now = tilt
+			}
+			if now.dir != m.dir {
+				stop()
+			}
+		}
+	}
+}
+
+// takeShot takes a test shot, logging the outcome.
+func (c *Controller) takeShot(ctx context.Context) {
+	if err := c.mover.Shoot(ctx); err != nil {
+		log.Printf("gamepad: test shot: %v", err)
+		return
+	}
+	log.Printf("gamepad: test shot taken")
+}
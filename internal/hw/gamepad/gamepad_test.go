@@ -0,0 +1,264 @@
+package gamepad
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// move is a Jog call of fakeMover.
+type move struct {
+	axis           string
+	steps, speedMs int
+}
+
+// fakeMover records moves, each lasting 5 ms, and shots.
+type fakeMover struct {
+	moves chan move
+	shots chan struct{}
+	err   error
+}
+
+func newFakeMover() *fakeMover {
+	return &fakeMover{moves: make(chan move, 100), shots: make(chan struct{}, 10)}
+}
+
+func (m *fakeMover) Jog(ctx context.Context, axis string, steps, speedMs int) error {
+	m.moves <- move{axis, steps, speedMs}
+	if m.err != nil {
+		return m.err
+	}
+	select {
+	case <-time.After(5 * time.Millisecond):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *fakeMover) Shoot(ctx context.Context) error {
+	m.shots <- struct{}{}
+	return nil
+}
+
+func testOptions() Options {
+	return Options{
+		Device:      "/dev/input/event0",
+		PanAxis:     "x",
+		TiltAxis:    "y",
+		DeadZone:    0.2,
+		Curve:       2,
+		SlowSpeedMs: 21,
+		FastSpeedMs: 1,
+		ShootButton: "south",
+	}
+}
+
+// startController returns a controller of o driving m, with the axes of
+// an 8-bit stick (0-255), and stops it at the end of the test.
+func startController(t *testing.T, o Options, m Mover) *Controller {
+	t.Helper()
+	c, err := newController(o, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.pan.min, c.pan.max = 0, 255
+	c.tilt.min, c.tilt.max = 0, 255
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.drive(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return c
+}
+
+// expectStill fails if a move starts within 50 ms, after those running.
+func expectStill(t *testing.T, moves chan move) {
+	t.Helper()
+	time.Sleep(10 * time.Millisecond)
+	for len(moves) > 0 {
+		<-moves
+	}
+	select {
+	case m := <-moves:
+		t.Errorf("head still moving: %+v", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// ---------- newController ----------
+
+func TestNewController(t *testing.T) {
+	cases := []struct {
+		name   string
+		modify func(o *Options)
+	}{
+		{"no_device", func(o *Options) { o.Device = "" }},
+		{"unknown_axis", func(o *Options) { o.PanAxis = "w" }},
+		{"same_axis", func(o *Options) { o.TiltAxis = "x" }},
+		{"unknown_button", func(o *Options) { o.ShootButton = "a" }},
+		{"dead_zone", func(o *Options) { o.DeadZone = 1 }},
+		{"curve", func(o *Options) { o.Curve = 0.5 }},
+		{"fast_slower", func(o *Options) { o.FastSpeedMs = 30 }},
+	}
+	if _, err := newController(testOptions(), newFakeMover()); err != nil {
+		t.Fatalf("valid options: %v", err)
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			o := testOptions()
+			tc.modify(&o)
+			if _, err := newController(o, newFakeMover()); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+// ---------- motion ----------
+
+func TestMotion(t *testing.T) {
+	c, err := newController(testOptions(), newFakeMover())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		deflection float64
+		want       motion
+	}{
+		{0, motion{}},
+		{0.2, motion{}},
+		{-0.15, motion{}},
+		{1, motion{1, 1}},
+		{-1, motion{-1, 1}},
+		{0.6, motion{1, 16}}, // halfway past the dead zone: 21 - 0.25*20
+		{-0.6, motion{-1, 16}},
+	}
+	for _, tc := range cases {
+		if got := c.motion(tc.deflection); got != tc.want {
+			t.Errorf("motion(%v) = %+v, want %+v", tc.deflection, got, tc.want)
+		}
+	}
+}
+
+func TestAxisSet(t *testing.T) {
+	a := axis{min: -32768, max: 32767}
+	a.set(32767)
+	if a.deflection != 1 {
+		t.Errorf("full right = %v, want 1", a.deflection)
+	}
+	a.invert = true
+	a.set(-32768)
+	if a.deflection != 1 {
+		t.Errorf("full left inverted = %v, want 1", a.deflection)
+	}
+}
+
+// ---------- drive ----------
+
+func TestDrive(t *testing.T) {
+	m := newFakeMover()
+	c := startController(t, testOptions(), m)
+
+	c.handle(event{evAbs, axisCodes["x"], 255}) // full right
+	if got := <-m.moves; got != (move{"pan", sliceSteps, 1}) {
+		t.Errorf("move = %+v, want pan clockwise at full speed", got)
+	}
+
+	c.handle(event{evAbs, axisCodes["y"], 0}) // and full up
+	seen := map[string]bool{}
+	for range 4 {
+		got := <-m.moves
+		seen[got.axis] = true
+		if got.axis == "tilt" && got.steps != sliceSteps {
+			t.Errorf("tilt move = %+v, want up", got)
+		}
+	}
+	if !seen["pan"] || !seen["tilt"] {
+		t.Errorf("axes moved: %v, want both", seen)
+	}
+
+	c.handle(event{evAbs, axisCodes["x"], 128})
+	c.handle(event{evAbs, axisCodes["y"], 128}) // released
+	expectStill(t, m.moves)
+}
+
+func TestDrive_Shoot(t *testing.T) {
+	m := newFakeMover()
+	c := startController(t, testOptions(), m)
+
+	c.handle(event{evKey, buttonCodes["south"], 1})
+	select {
+	case <-m.shots:
+	case <-time.After(time.Second):
+		t.Fatal("no test shot")
+	}
+	c.handle(event{evKey, buttonCodes["south"], 0}) // released
+	c.handle(event{evKey, buttonCodes["east"], 1})  // another button
+	select {
+	case <-m.shots:
+		t.Error("shot on release or another button")
+	case <-time.After(30 * time.Millisecond):
+	}
+}
+
+func TestDrive_Unplugged(t *testing.T) {
+	m := newFakeMover()
+	c := startController(t, testOptions(), m)
+
+	c.handle(event{evAbs, axisCodes["x"], 0})
+	if got := <-m.moves; got.axis != "pan" || got.steps != -sliceSteps {
+		t.Errorf("move = %+v, want pan counterclockwise", got)
+	}
+	c.center()
+	expectStill(t, m.moves)
+}
+
+func TestDrive_Busy(t *testing.T) {
+	m := newFakeMover()
+	m.err = errors.New("head is busy")
+	c := startController(t, testOptions(), m)
+
+	c.handle(event{evAbs, axisCodes["x"], 255})
+	<-m.moves
+	// The controller waits before trying again.
+	select {
+	case got := <-m.moves:
+		t.Errorf("retried at once: %+v", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// ---------- Open ----------
+
+func TestOpen_MissingDevice(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		if _, err := Open(testOptions(), newFakeMover()); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Open on %s = %v, want ErrUnsupported", runtime.GOOS, err)
+		}
+		return
+	}
+	o := testOptions()
+	o.Device = t.TempDir() + "/event0"
+	c, err := Open(o, newFakeMover())
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		c.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return")
+	}
+}
@@ -0,0 +1,111 @@
+package gpio
+
+import (
+	"sync"
+	"time"
+)
+
+// dryRunIdle is how long a run of pulses on a pin may pause before it is
+// logged.
+const dryRunIdle = 100 * time.Millisecond
+
+// DryRunDriver is a mock driver that logs every operation with logf, for
+// rehearsing a command without hardware. Pulses (HIGH then LOW) repeated
+// on a pin, like the steps of a move, are logged as one line with their
+// count once another pin is used or the pin stays idle.
+type DryRunDriver struct {
+	logf func(format string, args ...any)
+
+	mu     sync.Mutex
+	pin    int   // pin of the pending pulses
+	level  Level // last level written to pin
+	pulses int   // pending pulses on pin; 0 = none
+	idle   *time.Timer
+}
+
+// NewDryRunDriver returns a DryRunDriver logging with logf (e.g.
+// log.Printf).
+func NewDryRunDriver(logf func(format string, args ...any)) *DryRunDriver {
+	d := &DryRunDriver{logf: logf}
+	d.idle = time.AfterFunc(time.Hour, d.Flush)
+	d.idle.Stop()
+	return d
+}
+
+func (d *DryRunDriver) SetupPin(pin int, mode PinMode) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.flush()
+	name := "input"
+	if mode == Output {
+		name = "output"
+	}
+	d.logf("dry run: GPIO %d set up as %s", pin, name)
+	return nil
+}
+
+func (d *DryRunDriver) WritePin(pin int, level Level) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch {
+	case d.pulses > 0 && pin == d.pin && level != d.level:
+		// continuing the pulses: count each rising edge
+		if level == High {
+			d.pulses++
+		}
+		d.level = level
+		d.idle.Reset(dryRunIdle)
+		return nil
+	case level == High:
+		d.flush()
+		d.pin, d.level, d.pulses = pin, level, 1
+		d.idle.Reset(dryRunIdle)
+		return nil
+	}
+	d.flush()
+	d.logf("dry run: GPIO %d -> LOW", pin)
+	return nil
+}
+
+func (d *DryRunDriver) ReadPin(pin int) (Level, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.flush()
+	d.logf("dry run: GPIO %d read (LOW)", pin)
+	return Low, nil
+}
+
+func (d *DryRunDriver) Close() error {
+	d.Flush()
+	d.logf("dry run: GPIO closed")
+	return nil
+}
+
+// Flush logs the pending pulses.
+func (d *DryRunDriver) Flush() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.flush()
+}
+
+func (d *DryRunDriver) flush() {
+	d.idle.Stop()
+	switch {
+	case d.pulses == 0:
+		return
+	case d.pulses == 1 && d.level == High:
+		d.logf("dry run: GPIO %d -> HIGH", d.pin)
+	case d.level == High:
+		d.logf("dry run: GPIO %d: %d %s, left HIGH", d.pin, d.pulses, plural(d.pulses, "pulse"))
+	default:
+		d.logf("dry run: GPIO %d: %d %s", d.pin, d.pulses, plural(d.pulses, "pulse"))
+	}
+	d.pulses = 0
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
@@ -0,0 +1,62 @@
+package gpio
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// ---------- DryRunDriver ----------
+
+func TestDryRunDriver(t *testing.T) {
+	var lines []string
+	d := NewDryRunDriver(func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	})
+
+	_ = d.SetupPin(17, Output)
+	_ = d.WritePin(27, High) // direction
+	for range 3 {
+		_ = d.WritePin(17, High)
+		_ = d.WritePin(17, Low)
+	}
+	_ = d.WritePin(24, Low)
+	_ = d.WritePin(24, High)
+	_, _ = d.ReadPin(5)
+	_ = d.WritePin(17, High)
+	_ = d.WritePin(17, Low)
+	_ = d.WritePin(17, High)
+	_ = d.Close()
+
+	want := []string{
+		"dry run: GPIO 17 set up as output",
+		"dry run: GPIO 27 -> HIGH",
+		"dry run: GPIO 17: 3 pulses",
+		"dry run: GPIO 24 -> LOW",
+		"dry run: GPIO 24 -> HIGH",
+		"dry run: GPIO 5 read (LOW)",
+		"dry run: GPIO 17: 2 pulses, left HIGH",
+		"dry run: GPIO closed",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("log =\n%q\nwant\n%q", lines, want)
+	}
+}
+
+func TestDryRunDriver_IdleFlush(t *testing.T) {
+	logged := make(chan string, 1)
+	d := NewDryRunDriver(func(format string, args ...any) {
+		logged <- fmt.Sprintf(format, args...)
+	})
+	_ = d.WritePin(17, High)
+	_ = d.WritePin(17, Low)
+	select {
+	case line := <-logged:
+		if line != "dry run: GPIO 17: 1 pulse" {
+			t.Errorf("log = %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("pulses not logged when the pin stayed idle")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"github.com/cjeanneret/PanGo/internal/debug"
 )
 
+// dbg is the debug output of the package.
+var dbg = debug.For("gpio")
+
 // Level represents the logical state of a GPIO pin.
 type Level bool
 
@@ -39,28 +42,28 @@ type MockDriver struct{}
 // If mock is false, returns a real RPiDriver (for Raspberry Pi).
 func NewDriver(mock bool) (Driver, error) {
 	if mock {
-		debug.Info("Using MOCK GPIO driver (development mode)")
+		dbg.Info("initializing GPIO driver", "driver", "mock")
 		return &MockDriver{}, nil
 	}
 	return NewRPiRealDriver()
 }
 
 func (m *MockDriver) SetupPin(pin int, mode PinMode) error {
-	debug.GPIO("SetupPin", pin, mode)
+	dbg.GPIO("SetupPin", pin, "mode", mode)
 	return nil
 }
 
 func (m *MockDriver) WritePin(pin int, level Level) error {
-	debug.GPIO("WritePin", pin, level)
+	dbg.GPIO("WritePin", pin, "pin_level", level)
 	return nil
 }
 
 func (m *MockDriver) ReadPin(pin int) (Level, error) {
-	debug.GPIO("ReadPin", pin, nil)
+	dbg.GPIO("ReadPin", pin)
 	return Low, nil
 }
 
 func (m *MockDriver) Close() error {
-	debug.Trace("GPIO Close (mock)")
+	dbg.Trace("gpio close", "driver", "mock")
 	return nil
 }
@@ -0,0 +1,30 @@
+package gpio
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/debug"
+)
+
+// ---------- MockDriver ----------
+
+func TestMockDriver_TraceAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	debug.Init(debug.LevelTrace)
+	debug.SetOutput(&buf)
+	t.Cleanup(func() { debug.Init(debug.LevelOff) })
+
+	var m MockDriver
+	if err := m.WritePin(17, High); err != nil {
+		t.Fatal(err)
+	}
+	line := strings.TrimSpace(buf.String())
+	if !strings.Contains(line, "op=WritePin pin=17 pin_level=true") {
+		t.Errorf("trace = %q, want the pin level as pin_level", line)
+	}
+	if n := strings.Count(line, " level="); n != 1 {
+		t.Errorf("trace = %q, want slog's level key once", line)
+	}
+}
@@ -0,0 +1,315 @@
+package gpio
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordingHeader is the first line of a recording.
+const recordingHeader = "# pango gpio recording 1"
+
+// OpKind is the kind of a recorded GPIO operation.
+type OpKind string
+
+const (
+	OpSetup OpKind = "setup"
+	OpWrite OpKind = "write"
+	OpRead  OpKind = "read"
+	OpClose OpKind = "close"
+)
+
+// Op is a recorded GPIO operation.
+type Op struct {
+	At    time.Duration // since the recording started
+	Kind  OpKind
+	Pin   int     // 0 for close
+	Level Level   // written or read
+	Mode  PinMode // set up
+}
+
+// String returns op as in a recording, without its time, e.g.
+// "write 17 high".
+func (op Op) String() string {
+	switch op.Kind {
+	case OpSetup:
+		mode := "input"
+		if op.Mode == Output {
+			mode = "output"
+		}
+		return fmt.Sprintf("setup %d %s", op.Pin, mode)
+	case OpWrite, OpRead:
+		level := "low"
+		if op.Level == High {
+			level = "high"
+		}
+		return fmt.Sprintf("%s %d %s", op.Kind, op.Pin, level)
+	}
+	return string(op.Kind)
+}
+
+// output reports whether op drives the pins: pin setups and writes.
+func (op Op) output() bool {
+	return op.Kind == OpSetup || op.Kind == OpWrite
+}
+
+// Recorder is a driver recording every operation of another one, with its
+// time, to replay or compare runs without hardware. A recording is a text
+// file of one operation per line after a header, e.g.
+//
+//	# pango gpio recording 1
+//	# started 2026-10-17T22:00:00.123456Z
+//	0.000000 setup 17 output
+//	0.000120 write 17 high
+//	0.001130 write 17 low
+//	0.250002 read 5 high
+//	1.204511 close
+//
+// with the time in seconds since the recording started.
+type Recorder struct {
+	d Driver
+
+	mu    sync.Mutex
+	w     *bufio.Writer
+	c     io.Closer
+	start time.Time
+	err   error // first error writing the recording
+}
+
+// NewRecorder returns a driver doing the operations of d and recording
+// them to w, closed with the driver.
+func NewRecorder(d Driver, w io.WriteCloser) *Recorder {
+	r := &Recorder{d: d, w: bufio.NewWriter(w), c: w, start: time.Now()}
+	_, r.err = fmt.Fprintf(r.w, "%s\n# started %s\n", recordingHeader, r.start.UTC().Format(time.RFC3339Nano))
+	return r
+}
+
+func (r *Recorder) record(op Op) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	_, r.err = fmt.Fprintf(r.w, "%.6f %s\n", time.Since(r.start).Seconds(), op)
+}
+
+func (r *Recorder) SetupPin(pin int, mode PinMode) error {
+	err := r.d.SetupPin(pin, mode)
+	r.record(Op{Kind: OpSetup, Pin: pin, Mode: mode})
+	return err
+}
+
+func (r *Recorder) WritePin(pin int, level Level) error {
+	err := r.d.WritePin(pin, level)
+	r.record(Op{Kind: OpWrite, Pin: pin, Level: level})
+	return err
+}
+
+func (r *Recorder) ReadPin(pin int) (Level, error) {
+	level, err := r.d.ReadPin(pin)
+	if err == nil {
+		r.record(Op{Kind: OpRead, Pin: pin, Level: level})
+	}
+	return level, err
+}
+
+// Close closes the driver, then the recording. It returns the first error
+// of the driver or of the recording.
+func (r *Recorder) Close() error {
+	err := r.d.Close()
+	r.record(Op{Kind: OpClose})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if flushErr := r.w.Flush(); r.err == nil {
+		r.err = flushErr
+	}
+	if closeErr := r.c.Close(); r.err == nil {
+		r.err = closeErr
+	}
+	if r.err != nil {
+		r.err = fmt.Errorf("gpio recording: %w", r.err)
+	}
+	return errors.Join(err, r.err)
+}
+
+// ReadRecording reads the operations of a recording written by a
+// Recorder.
+func ReadRecording(rd io.Reader) ([]Op, error) {
+	sc := bufio.NewScanner(rd)
+	if !sc.Scan() || sc.Text() != recordingHeader {
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("not a GPIO recording")
+	}
+	var ops []Op
+	for n := 2; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		op, err := parseOp(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, sc.Err()
+}
+
+// parseOp parses a line of a recording.
+func parseOp(line string) (Op, error) {
+	f := strings.Fields(line)
+	if len(f) < 2 {
+		return Op{}, fmt.Errorf("invalid operation %q", line)
+	}
+	secs, err := strconv.ParseFloat(f[0], 64)
+	if err != nil || secs < 0 {
+		return Op{}, fmt.Errorf("invalid time %q", f[0])
+	}
+	op := Op{At: time.Duration(secs * float64(time.Second)), Kind: OpKind(f[1])}
+	if op.Kind == OpClose && len(f) == 2 {
+		return op, nil
+	}
+	if len(f) != 4 {
+		return Op{}, fmt.Errorf("invalid operation %q", line)
+	}
+	if op.Pin, err = strconv.Atoi(f[2]); err != nil || op.Pin < 0 {
+		return Op{}, fmt.Errorf("invalid pin %q", f[2])
+	}
+	switch {
+	case op.Kind == OpSetup && f[3] == "input":
+		op.Mode = Input
+	case op.Kind == OpSetup && f[3] == "output":
+		op.Mode = Output
+	case (op.Kind == OpWrite || op.Kind == OpRead) && f[3] == "low":
+		op.Level = Low
+	case (op.Kind == OpWrite || op.Kind == OpRead) && f[3] == "high":
+		op.Level = High
+	default:
+		return Op{}, fmt.Errorf("invalid operation %q", line)
+	}
+	return op, nil
+}
+
+// Replay does the operations of ops on d, in order, with their recorded
+// timing divided by speed; speed 0 replays them at once. It stops at the
+// recorded close, without closing d, or when ctx is done.
+func Replay(ctx context.Context, d Driver, ops []Op, speed float64) error {
+	start := time.Now()
+	for _, op := range ops {
+		if speed > 0 {
+			if wait := time.Until(start.Add(time.Duration(float64(op.At) / speed))); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var err error
+		switch op.Kind {
+		case OpSetup:
+			err = d.SetupPin(op.Pin, op.Mode)
+		case OpWrite:
+			err = d.WritePin(op.Pin, op.Level)
+		case OpRead:
+			_, err = d.ReadPin(op.Pin)
+		case OpClose:
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("replay %s at %v: %w", op, op.At, err)
+		}
+	}
+	return nil
+}
+
+// Difference is a difference between the pin setups and writes of two
+// recordings.
+type Difference struct {
+	Index    int           // of the setup or write, from 0
+	Old, New *Op           // nil when missing from that recording
+	Interval time.Duration // Timing only: interval of New since the previous operation
+	Was      time.Duration // Timing only: interval of Old since the previous operation
+	Timing   bool          // same operation, at another interval from the previous one
+}
+
+func (d Difference) String() string {
+	switch {
+	case d.Timing:
+		return fmt.Sprintf("#%d %s: %v after the previous operation, was %v", d.Index, d.New, d.Interval, d.Was)
+	case d.Old == nil:
+		return fmt.Sprintf("#%d: %s added", d.Index, d.New)
+	case d.New == nil:
+		return fmt.Sprintf("#%d: %s removed", d.Index, d.Old)
+	}
+	return fmt.Sprintf("#%d: %s, was %s", d.Index, d.New, d.Old)
+}
+
+// Diff compares the pin setups and writes of two recordings: the operations
+// and their interval from the previous one, which may differ by up to
+// tolerance. Reads are left out: their number depends on how long inputs
+// are polled. After the first differing operation the recordings are out
+// of step, so Diff stops there.
+func Diff(oldOps, newOps []Op, tolerance time.Duration) []Difference {
+	old, cur := outputs(oldOps), outputs(newOps)
+	var diffs []Difference
+	for i := 0; i < max(len(old), len(cur)); i++ {
+		switch {
+		case i >= len(old):
+			return append(diffs, Difference{Index: i, New: &cur[i]})
+		case i >= len(cur):
+			return append(diffs, Difference{Index: i, Old: &old[i]})
+		}
+		o, n := old[i], cur[i]
+		if o.Kind != n.Kind || o.Pin != n.Pin || o.Level != n.Level || o.Mode != n.Mode {
+			return append(diffs, Difference{Index: i, Old: &old[i], New: &cur[i]})
+		}
+		if i == 0 {
+			continue
+		}
+		was, interval := o.At-old[i-1].At, n.At-cur[i-1].At
+		if d := interval - was; d > tolerance || d < -tolerance {
+			diffs = append(diffs, Difference{Index: i, Old: &old[i], New: &cur[i], Interval: interval, Was: was, Timing: true})
+		}
+	}
+	return diffs
+}
+
+// outputs returns the pin setups and writes of ops.
+func outputs(ops []Op) []Op {
+	var out []Op
+	for _, op := range ops {
+		if op.output() {
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// Pulses returns the number of rising edges written to each pin in ops,
+// e.g. the steps sent to a driver or the shots of a camera.
+func Pulses(ops []Op) map[int]int {
+	pulses := map[int]int{}
+	levels := map[int]Level{}
+	for _, op := range ops {
+		if op.Kind != OpWrite {
+			continue
+		}
+		if op.Level == High && levels[op.Pin] == Low {
+			pulses[op.Pin]++
+		}
+		levels[op.Pin] = op.Level
+	}
+	return pulses
+}
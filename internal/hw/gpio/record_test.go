@@ -0,0 +1,204 @@
+package gpio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// closeBuffer is a bytes.Buffer closed with the recording.
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+// record records a pulse on 17 and a read of 5 through a Recorder of a
+// MockDriver, and returns the recording.
+func record(t *testing.T) *closeBuffer {
+	t.Helper()
+	var buf closeBuffer
+	r := NewRecorder(&MockDriver{}, &buf)
+	_ = r.SetupPin(17, Output)
+	_ = r.WritePin(17, High)
+	_ = r.WritePin(17, Low)
+	if _, err := r.ReadPin(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// ---------- Recorder ----------
+
+func TestRecorder(t *testing.T) {
+	buf := record(t)
+	if !buf.closed {
+		t.Error("recording not closed with the driver")
+	}
+	ops, err := ReadRecording(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("ReadRecording: %v\n%s", err, buf.String())
+	}
+	var lines []string
+	for i, op := range ops {
+		lines = append(lines, op.String())
+		if i > 0 && op.At < ops[i-1].At {
+			t.Errorf("%s at %v, before the previous operation", op, op.At)
+		}
+	}
+	want := []string{"setup 17 output", "write 17 high", "write 17 low", "read 5 low", "close"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("recorded %q, want %q", lines, want)
+	}
+}
+
+func TestReadRecording_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		data string
+	}{
+		{"no_header", "0.000001 write 17 high\n"},
+		{"bad_time", recordingHeader + "\nsoon write 17 high\n"},
+		{"bad_pin", recordingHeader + "\n0.1 write GPIO17 high\n"},
+		{"bad_level", recordingHeader + "\n0.1 write 17 on\n"},
+		{"bad_kind", recordingHeader + "\n0.1 toggle 17 high\n"},
+		{"missing_level", recordingHeader + "\n0.1 write 17\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ReadRecording(strings.NewReader(tc.data)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+// ---------- Replay ----------
+
+// opsDriver is a driver listing the operations done on it.
+type opsDriver struct {
+	ops []string
+}
+
+func (d *opsDriver) SetupPin(pin int, mode PinMode) error {
+	d.ops = append(d.ops, Op{Kind: OpSetup, Pin: pin, Mode: mode}.String())
+	return nil
+}
+
+func (d *opsDriver) WritePin(pin int, level Level) error {
+	if pin == 99 {
+		return errors.New("no such pin")
+	}
+	d.ops = append(d.ops, Op{Kind: OpWrite, Pin: pin, Level: level}.String())
+	return nil
+}
+
+func (d *opsDriver) ReadPin(pin int) (Level, error) {
+	d.ops = append(d.ops, Op{Kind: OpRead, Pin: pin}.String())
+	return Low, nil
+}
+
+func (d *opsDriver) Close() error { return nil }
+
+func TestReplay(t *testing.T) {
+	ops := []Op{
+		{At: 0, Kind: OpSetup, Pin: 17, Mode: Output},
+		{At: 20 * time.Millisecond, Kind: OpWrite, Pin: 17, Level: High},
+		{At: 40 * time.Millisecond, Kind: OpWrite, Pin: 17, Level: Low},
+		{At: 40 * time.Millisecond, Kind: OpClose},
+		{At: 60 * time.Millisecond, Kind: OpWrite, Pin: 17, Level: High},
+	}
+	d := &opsDriver{}
+	start := time.Now()
+	if err := Replay(context.Background(), d, ops, 2); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("replayed in %v, want the recorded 40 ms at speed 2", elapsed)
+	}
+	want := []string{"setup 17 output", "write 17 high", "write 17 low"}
+	if !reflect.DeepEqual(d.ops, want) {
+		t.Errorf("replayed %q, want %q (up to close)", d.ops, want)
+	}
+
+	err := Replay(context.Background(), d, []Op{{Kind: OpWrite, Pin: 99, Level: High}}, 0)
+	if err == nil || !strings.Contains(err.Error(), "write 99 high") {
+		t.Errorf("error = %v, want the failed operation", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Replay(ctx, d, ops, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("error = %v, want context.Canceled", err)
+	}
+}
+
+// ---------- Diff ----------
+
+func TestDiff(t *testing.T) {
+	ms := time.Millisecond
+	base := []Op{
+		{At: 0, Kind: OpSetup, Pin: 17, Mode: Output},
+		{At: 1 * ms, Kind: OpWrite, Pin: 17, Level: High},
+		{At: 2 * ms, Kind: OpWrite, Pin: 17, Level: Low},
+		{At: 3 * ms, Kind: OpWrite, Pin: 17, Level: High},
+		{At: 4 * ms, Kind: OpWrite, Pin: 17, Level: Low},
+	}
+	with := func(edit func(ops []Op) []Op) []Op {
+		return edit(append([]Op(nil), base...))
+	}
+	cases := []struct {
+		name string
+		new  []Op
+		want []string
+	}{
+		{"same", base, nil},
+		{"jitter", with(func(ops []Op) []Op { ops[2].At += ms / 4; return ops }), nil},
+		{"reads_ignored", with(func(ops []Op) []Op {
+			return append(ops[:2], append([]Op{{At: ms, Kind: OpRead, Pin: 5}, {At: ms, Kind: OpRead, Pin: 5}}, ops[2:]...)...)
+		}), nil},
+		{"slower", with(func(ops []Op) []Op { ops[3].At += 3 * ms; ops[4].At += 3 * ms; return ops }),
+			[]string{"#3 write 17 high: 4ms after the previous operation, was 1ms"}},
+		{"changed", with(func(ops []Op) []Op { ops[2].Pin = 27; return ops }),
+			[]string{"#2: write 27 low, was write 17 low"}},
+		{"removed", base[:4], []string{"#4: write 17 low removed"}},
+		{"added", append(with(func(ops []Op) []Op { return ops }), Op{At: 5 * ms, Kind: OpWrite, Pin: 22, Level: High}),
+			[]string{"#5: write 22 high added"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			for _, d := range Diff(base, tc.new, ms/2) {
+				got = append(got, d.String())
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Diff = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPulses(t *testing.T) {
+	ops := []Op{
+		{Kind: OpWrite, Pin: 27, Level: High}, // direction
+		{Kind: OpWrite, Pin: 17, Level: High},
+		{Kind: OpWrite, Pin: 17, Level: Low},
+		{Kind: OpRead, Pin: 17, Level: High},
+		{Kind: OpWrite, Pin: 17, Level: High},
+		{Kind: OpWrite, Pin: 17, Level: High}, // no edge
+		{Kind: OpWrite, Pin: 17, Level: Low},
+	}
+	if got, want := Pulses(ops), map[int]int{17: 2, 27: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pulses = %v, want %v", got, want)
+	}
+}
@@ -3,7 +3,6 @@ package gpio
 import (
 	"fmt"
 
-	"github.com/cjeanneret/PanGo/internal/debug"
 	"github.com/stianeikeland/go-rpio/v4"
 )
 
@@ -15,13 +14,13 @@ type RPiDriver struct {
 // NewRPiRealDriver creates a real GPIO driver for Raspberry Pi.
 // Requires running on a Raspberry Pi with access to /dev/gpiomem or as root.
 func NewRPiRealDriver() (*RPiDriver, error) {
-	debug.Info("Initializing real GPIO driver (go-rpio)")
+	dbg.Info("initializing GPIO driver", "driver", "go-rpio")
 
 	if err := rpio.Open(); err != nil {
 		return nil, fmt.Errorf("failed to open GPIO: %w (are you running on a Raspberry Pi?)", err)
 	}
 
-	debug.Verbose("GPIO memory mapped successfully")
+	dbg.Verbose("GPIO memory mapped")
 
 	return &RPiDriver{
 		pins: make(map[int]rpio.Pin),
@@ -29,7 +28,7 @@ func NewRPiRealDriver() (*RPiDriver, error) {
 }
 
 func (r *RPiDriver) SetupPin(pin int, mode PinMode) error {
-	debug.GPIO("SetupPin", pin, mode)
+	dbg.GPIO("SetupPin", pin, "mode", mode)
 
 	p := rpio.Pin(pin)
 	r.pins[pin] = p
@@ -47,7 +46,7 @@ func (r *RPiDriver) SetupPin(pin int, mode PinMode) error {
 }
 
 func (r *RPiDriver) WritePin(pin int, level Level) error {
-	debug.GPIO("WritePin", pin, level)
+	dbg.GPIO("WritePin", pin, "pin_level", level)
 
 	p, ok := r.pins[pin]
 	if !ok {
@@ -68,7 +67,7 @@ func (r *RPiDriver) WritePin(pin int, level Level) error {
 }
 
 func (r *RPiDriver) ReadPin(pin int) (Level, error) {
-	debug.GPIO("ReadPin", pin, nil)
+	dbg.GPIO("ReadPin", pin)
 
 	p, ok := r.pins[pin]
 	if !ok {
@@ -87,11 +86,11 @@ func (r *RPiDriver) ReadPin(pin int) (Level, error) {
 }
 
 func (r *RPiDriver) Close() error {
-	debug.Trace("GPIO Close (real driver)")
+	dbg.Trace("gpio close", "driver", "go-rpio")
 
 	// Reset all pins to input (safe state)
 	for pin, p := range r.pins {
-		debug.Verbose("Resetting pin %d to input", pin)
+		dbg.Verbose("resetting pin to input", "pin", pin)
 		p.Input()
 	}
 
@@ -0,0 +1,37 @@
+package ssd1306
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// i2cSlave is the I2C_SLAVE ioctl of linux/i2c-dev.h: it sets the address
+// the writes to the bus device go to.
+const i2cSlave = 0x0703
+
+// OpenI2C opens the I2C bus device dev (e.g. /dev/i2c-1) for writes to the
+// device at addr. Each write is one I2C transaction.
+func OpenI2C(dev string, addr int) (*os.File, error) {
+	f, err := os.OpenFile(dev, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	var errno syscall.Errno
+	if err := rc.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, i2cSlave, uintptr(addr))
+	}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if errno != 0 {
+		f.Close()
+		return nil, fmt.Errorf("%s: address %#x: %w", dev, addr, errno)
+	}
+	return f, nil
+}
@@ -0,0 +1,167 @@
+// Package ssd1306 drives a monochrome OLED display of 128x64 or 128x32
+// pixels with an SSD1306 controller, wired to the I2C bus of the Pi. It
+// shows lines of text in a built-in 5x7 font: 21 characters by 8 lines, or
+// 4 on the smaller displays. The controller is driven without a library.
+package ssd1306
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// Width is the width of the displays, in pixels.
+const Width = 128
+
+// DefaultAddress is the I2C address of most modules; 0x3D is the other one.
+const DefaultAddress = 0x3c
+
+// Control bytes of I2C transactions: what follows is commands or data.
+const (
+	controlCommand = 0x00
+	controlData    = 0x40
+)
+
+// Display is an SSD1306 display written through an I2C device.
+type Display struct {
+	w      io.Writer
+	height int
+	buf    []byte // display memory: a byte per column of 8 pixels, page after page
+	shown  []byte // buf as last sent; nil before the first Show
+}
+
+// New initializes the display of height pixels (64 or 32) written through
+// w, e.g. from OpenI2C, and clears it. rotate turns the picture upside
+// down, for displays mounted that way.
+func New(w io.Writer, height int, rotate bool) (*Display, error) {
+	if height != 64 && height != 32 {
+		return nil, fmt.Errorf("ssd1306: height must be 64 or 32, got %d", height)
+	}
+	comPins := byte(0x12) // alternative COM configuration of 128x64 panels
+	if height == 32 {
+		comPins = 0x02
+	}
+	segRemap, comScan := byte(0xa1), byte(0xc8) // column 0 on the left, row 0 at the top
+	if rotate {
+		segRemap, comScan = 0xa0, 0xc0
+	}
+	d := &Display{w: w, height: height, buf: make([]byte, Width*height/8)}
+	err := d.command(
+		0xae,       // display off
+		0xd5, 0x80, // clock divide ratio and oscillator frequency: reset values
+		0xa8, byte(height-1), // multiplex ratio
+		0xd3, 0x00, // no display offset
+		0x40,       // start line 0
+		0x8d, 0x14, // charge pump on: modules run from 3.3V
+		0x20, 0x00, // horizontal addressing: Show writes the memory in one go
+		segRemap,
+		comScan,
+		0xda, comPins,
+		0x81, 0xcf, // contrast
+		0xd9, 0xf1, // pre-charge period, for the charge pump
+		0xdb, 0x40, // VCOMH deselect level
+		0xa4, // show the memory
+		0xa6, // not inverted
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Show(); err != nil {
+		return nil, err
+	}
+	if err := d.command(0xaf); err != nil { // display on
+		return nil, err
+	}
+	return d, nil
+}
+
+// Rows returns the number of lines of text the display shows.
+func (d *Display) Rows() int {
+	return d.height / 8
+}
+
+// Columns returns the number of characters a line shows.
+func (d *Display) Columns() int {
+	return Width / CharWidth
+}
+
+// Clear blanks the picture, until Show.
+func (d *Display) Clear() {
+	clear(d.buf)
+}
+
+// Text draws s on line row, from the left edge, until Show. Characters
+// past the right edge are dropped, those the font lacks are drawn as a
+// box. inverse draws dark text on a lit band across the line, to highlight
+// it.
+func (d *Display) Text(row int, s string, inverse bool) {
+	if row < 0 || row >= d.Rows() {
+		return
+	}
+	line := d.buf[row*Width : (row+1)*Width]
+	var mask byte
+	if inverse {
+		mask = 0xff
+	}
+	for i := range line {
+		line[i] = mask
+	}
+	x := 0
+	for _, r := range s {
+		if x+glyphWidth > Width {
+			break
+		}
+		g := glyph(r)
+		for i, col := range g {
+			line[x+i] = col ^ mask
+		}
+		x += CharWidth
+	}
+}
+
+// Lines draws lines, one per line of the display from the top, the
+// highlighted one (an index in lines; -1 for none) in inverse, and shows
+// them.
+func (d *Display) Lines(lines []string, highlighted int) error {
+	d.Clear()
+	for i, s := range lines {
+		d.Text(i, s, i == highlighted)
+	}
+	return d.Show()
+}
+
+// Show sends the picture to the display, when it changed.
+func (d *Display) Show() error {
+	if d.shown != nil && bytes.Equal(d.buf, d.shown) {
+		return nil
+	}
+	pages := byte(d.height/8 - 1)
+	if err := d.command(0x21, 0, Width-1, 0x22, 0, pages); err != nil {
+		return err
+	}
+	for off := 0; off < len(d.buf); off += Width {
+		if err := d.write(controlData, d.buf[off:off+Width]...); err != nil {
+			return err
+		}
+	}
+	d.shown = append(d.shown[:0], d.buf...)
+	return nil
+}
+
+// Off turns the display off, e.g. on exit: OLED pixels wear when lit.
+func (d *Display) Off() error {
+	return d.command(0xae)
+}
+
+// command sends commands to the controller.
+func (d *Display) command(cmds ...byte) error {
+	return d.write(controlCommand, cmds...)
+}
+
+// write sends the control byte and b in one I2C transaction.
+func (d *Display) write(control byte, b ...byte) error {
+	if _, err := d.w.Write(append([]byte{control}, b...)); err != nil {
+		return fmt.Errorf("ssd1306: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package ssd1306
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// bus records the I2C transactions written.
+type bus struct {
+	writes [][]byte
+	err    error
+}
+
+func (b *bus) Write(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	b.writes = append(b.writes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+// data returns the display memory written by the data transactions.
+func (b *bus) data() []byte {
+	var mem []byte
+	for _, w := range b.writes {
+		if w[0] == controlData {
+			mem = append(mem, w[1:]...)
+		}
+	}
+	return mem
+}
+
+// ---------- New ----------
+
+func TestNew(t *testing.T) {
+	cases := []struct {
+		name    string
+		height  int
+		rotate  bool
+		wantCmd []byte // expected in the init sequence
+	}{
+		{"128x64", 64, false, []byte{0xa1, 0xc8, 0xda, 0x12}},
+		{"128x32", 32, false, []byte{0xa1, 0xc8, 0xda, 0x02}},
+		{"rotated", 64, true, []byte{0xa0, 0xc0}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := &bus{}
+			d, err := New(b, tc.height, tc.rotate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			init := b.writes[0]
+			if init[0] != controlCommand || init[1] != 0xae || !bytes.Contains(init, tc.wantCmd) ||
+				!bytes.Contains(init, []byte{0xa8, byte(tc.height - 1)}) {
+				t.Errorf("init = % x, want display off first, the multiplex ratio and % x", init, tc.wantCmd)
+			}
+			if last := b.writes[len(b.writes)-1]; !bytes.Equal(last, []byte{controlCommand, 0xaf}) {
+				t.Errorf("last write = % x, want display on", last)
+			}
+			if mem := b.data(); len(mem) != Width*tc.height/8 || bytes.Count(mem, []byte{0}) != len(mem) {
+				t.Errorf("memory = %d bytes, want %d cleared", len(mem), Width*tc.height/8)
+			}
+			if d.Rows() != tc.height/8 || d.Columns() != 21 {
+				t.Errorf("text = %dx%d", d.Columns(), d.Rows())
+			}
+		})
+	}
+
+	if _, err := New(&bus{}, 48, false); err == nil {
+		t.Error("New(48): want an error")
+	}
+	failing := &bus{err: errors.New("no ack")}
+	if _, err := New(failing, 64, false); !errors.Is(err, failing.err) {
+		t.Errorf("New on a failing bus = %v", err)
+	}
+}
+
+// ---------- Text ----------
+
+func TestLines(t *testing.T) {
+	b := &bus{}
+	d, err := New(b, 32, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.writes = nil
+	if err := d.Lines([]string{"A!", "", "x", "too many", "lines"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	mem := b.data()
+	if len(mem) != Width*4 {
+		t.Fatalf("memory = %d bytes, want %d", len(mem), Width*4)
+	}
+	if got := mem[:CharWidth*2]; !bytes.Equal(got, []byte{0x7e, 0x11, 0x11, 0x11, 0x7e, 0, 0, 0, 0x5f, 0, 0, 0}) {
+		t.Errorf("line 0 = % x, want A then !", got)
+	}
+	if line := mem[2*Width : 3*Width]; line[0] != ^byte(0x44) || line[Width-1] != 0xff {
+		t.Errorf("line 2 = % x..., want x inverse on a lit band", line[:6])
+	}
+	if line := mem[3*Width : 4*Width]; line[1] != 0x3f {
+		t.Errorf("line 3 = % x..., want the fourth line (too many)", line[:6])
+	}
+
+	// Unchanged text is not sent again.
+	b.writes = nil
+	d.Lines([]string{"A!", "", "x", "too many"}, 2)
+	if len(b.writes) != 0 {
+		t.Errorf("%d writes for the same picture", len(b.writes))
+	}
+}
+
+func TestText_Clipping(t *testing.T) {
+	d, err := New(&bus{}, 64, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Text(0, "0123456789012345678901234", false) // 25 characters
+	if got := d.buf[20*CharWidth : 20*CharWidth+glyphWidth]; !bytes.Equal(got, font['0'-' '][:]) {
+		t.Errorf("character 21 = % x, want 0", got)
+	}
+	if rest := d.buf[21*CharWidth : Width]; bytes.Count(rest, []byte{0}) != len(rest) {
+		t.Errorf("past the edge = % x, want blank", rest)
+	}
+	d.Text(8, "off the display", false) // ignored
+	d.Text(1, "é", false)
+	if got := d.buf[Width : Width+glyphWidth]; !bytes.Equal(got, unknownGlyph[:]) {
+		t.Errorf("é = % x, want the unknown glyph", got)
+	}
+}
+
+func TestFont(t *testing.T) {
+	if len(font) != '~'-' '+1 {
+		t.Errorf("font has %d glyphs, want %d", len(font), '~'-' '+1)
+	}
+	for i, g := range font {
+		for _, col := range g {
+			if col&0x80 != 0 {
+				t.Errorf("glyph %q uses the bottom row, kept blank between lines", rune(' '+i))
+			}
+		}
+	}
+}
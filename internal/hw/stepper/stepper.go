@@ -1,12 +1,17 @@
 package stepper
 
 import (
+	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/cjeanneret/PanGo/internal/debug"
 	"github.com/cjeanneret/PanGo/internal/hw/gpio"
 )
 
+// dbg is the debug output of the package.
+var dbg = debug.For("stepper")
+
 // Config holds the hardware configuration for a stepper motor.
 type Config struct {
 	StepPin       int
@@ -14,15 +19,17 @@ type Config struct {
 	EnablePin     int           // A4988 ENABLE pin (BCM). 0 = not used. Active LOW (LOW=enabled).
 	StepsPerRev   int
 	Microstepping int
-	StepDelay     time.Duration // delay per half-cycle of STEP pulse. Total step = 2*StepDelay.
+	StepDelay     time.Duration  // delay per half-cycle of STEP pulse. Total step = 2*StepDelay.
+	Moved         *atomic.Uint64 // optional: incremented for each step made, in either direction
 }
 
 // Stepper provides a simple API for moving a stepper motor.
 // Acceleration, ramping, etc. can be added later.
 type Stepper struct {
-	gpio  gpio.Driver
-	cfg   Config
-	delay time.Duration // delay between STEP pulse half-cycles
+	gpio     gpio.Driver
+	cfg      Config
+	delay    time.Duration // delay between STEP pulse half-cycles
+	position atomic.Int64  // steps moved since creation (forward = positive)
 }
 
 // NewStepper creates a new stepper motor controller.
@@ -53,44 +60,72 @@ func NewStepper(g gpio.Driver, cfg Config) *Stepper {
 
 // MoveSteps moves the motor by a number of steps (positive or negative).
 func (s *Stepper) MoveSteps(steps int) error {
+	return s.MoveStepsContext(context.Background(), steps, 0)
+}
+
+// MoveStepsContext moves the motor like MoveSteps, with a step period
+// (0 = configured speed) and cancellation: once ctx is done the motor stops
+// after the current pulse and ctx.Err() is returned. Position counts only the
+// steps actually made.
+func (s *Stepper) MoveStepsContext(ctx context.Context, steps int, stepPeriod time.Duration) error {
 	if steps == 0 {
 		return nil
 	}
 
 	var dirLevel gpio.Level
 	var direction string
+	sign := 1
 	if steps > 0 {
 		dirLevel = gpio.High
 		direction = "forward"
 	} else {
 		dirLevel = gpio.Low
 		direction = "backward"
+		sign = -1
 		steps = -steps
 	}
 
-	debug.Printf("Stepper: moving %d steps (%s) on pin %d", steps, direction, s.cfg.StepPin)
+	dbg.Verbose("stepper move", "steps", steps, "direction", direction, "step_pin", s.cfg.StepPin)
 
 	if err := s.gpio.WritePin(s.cfg.DirPin, dirLevel); err != nil {
 		return err
 	}
 
+	delay := s.delay
+	if stepPeriod > 0 {
+		delay = stepPeriod / 2
+	}
 	for i := 0; i < steps; i++ {
-		if err := s.stepPulse(); err != nil {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
+		if err := s.stepPulse(delay); err != nil {
+			return err
+		}
+		s.position.Add(int64(sign))
+		if s.cfg.Moved != nil {
+			s.cfg.Moved.Add(1)
+		}
 	}
 	return nil
 }
 
-func (s *Stepper) stepPulse() error {
+// Position returns the number of steps moved since the stepper was created
+// (forward = positive). Only completed step pulses are counted. It may be
+// called during a move, e.g. to report the head position live.
+func (s *Stepper) Position() int {
+	return int(s.position.Load())
+}
+
+func (s *Stepper) stepPulse(delay time.Duration) error {
 	if err := s.gpio.WritePin(s.cfg.StepPin, gpio.High); err != nil {
 		return err
 	}
-	time.Sleep(s.delay)
+	time.Sleep(delay)
 	if err := s.gpio.WritePin(s.cfg.StepPin, gpio.Low); err != nil {
 		return err
 	}
-	time.Sleep(s.delay)
+	time.Sleep(delay)
 	return nil
 }
 
@@ -1,6 +1,9 @@
 package stepper
 
 import (
+	"context"
+	"errors"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -251,3 +254,103 @@ func TestStepper_StepPulsePattern(t *testing.T) {
 		t.Error("second pulse should be LOW")
 	}
 }
+
+func TestStepper_PositionTracking(t *testing.T) {
+	drv := &recordingDriver{}
+	s := NewStepper(drv, Config{
+		StepPin:       17,
+		DirPin:        27,
+		StepsPerRev:   200,
+		Microstepping: 16,
+		StepDelay:     1 * time.Microsecond,
+	})
+
+	if got := s.Position(); got != 0 {
+		t.Fatalf("initial position = %d, want 0", got)
+	}
+	s.MoveSteps(25)
+	s.MoveSteps(-10)
+	s.MoveSteps(0)
+	if got := s.Position(); got != 15 {
+		t.Errorf("position = %d, want 15", got)
+	}
+}
+
+func TestStepper_MovedCounter(t *testing.T) {
+	var moved atomic.Uint64
+	cfg := Config{StepPin: 17, DirPin: 27, StepsPerRev: 200, Microstepping: 16, StepDelay: time.Microsecond, Moved: &moved}
+	s := NewStepper(&recordingDriver{}, cfg)
+	s.MoveSteps(25)
+	s.MoveSteps(-10)
+	// A re-created stepper keeps adding to the same counter.
+	NewStepper(&recordingDriver{}, cfg).MoveSteps(5)
+	if got := moved.Load(); got != 40 {
+		t.Errorf("moved = %d, want 40 (steps in either direction)", got)
+	}
+}
+
+// cancellingDriver cancels a context once a number of step pulses were sent.
+type cancellingDriver struct {
+	recordingDriver
+	stepPin int
+	after   int
+	cancel  context.CancelFunc
+}
+
+func (d *cancellingDriver) WritePin(pin int, level gpio.Level) error {
+	d.recordingDriver.WritePin(pin, level)
+	if pin == d.stepPin && level == gpio.Low && len(d.writeCallsForPin(pin))/2 == d.after {
+		d.cancel()
+	}
+	return nil
+}
+
+func TestStepper_MoveStepsContext_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	drv := &cancellingDriver{stepPin: 17, after: 5, cancel: cancel}
+	s := NewStepper(drv, Config{
+		StepPin:       17,
+		DirPin:        27,
+		StepsPerRev:   200,
+		Microstepping: 16,
+		StepDelay:     1 * time.Microsecond,
+	})
+
+	err := s.MoveStepsContext(ctx, -100, 0)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if got := s.Position(); got != -5 {
+		t.Errorf("position = %d, want -5 (steps made before the stop)", got)
+	}
+
+	// An already cancelled context does not move at all
+	if err := s.MoveStepsContext(ctx, 10, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if got := s.Position(); got != -5 {
+		t.Errorf("position = %d, want -5", got)
+	}
+}
+
+func TestStepper_MoveStepsContext_StepPeriod(t *testing.T) {
+	s := NewStepper(&recordingDriver{}, Config{
+		StepPin:       17,
+		DirPin:        27,
+		StepsPerRev:   200,
+		Microstepping: 16,
+		StepDelay:     1 * time.Microsecond,
+	})
+
+	start := time.Now()
+	if err := s.MoveStepsContext(context.Background(), 5, 4*time.Millisecond); err != nil {
+		t.Fatalf("MoveStepsContext: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("5 steps at 4ms took %v, want at least 20ms", elapsed)
+	}
+	if got := s.Position(); got != 5 {
+		t.Errorf("position = %d, want 5", got)
+	}
+}
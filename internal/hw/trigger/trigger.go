@@ -0,0 +1,100 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/debug"
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+)
+
+// dbg is the debug output of the package.
+var dbg = debug.For("trigger")
+
+// pollInterval is how often the input pin is sampled while waiting.
+const pollInterval = 5 * time.Millisecond
+
+// ErrTimeout is returned by Wait when no pulse arrived within the timeout.
+var ErrTimeout = errors.New("timed out waiting for external trigger")
+
+// Input waits for pulses on a GPIO input line, e.g. a flash "ready" output
+// or a hand switch. A pulse is a transition from the inactive to the active
+// level that holds for at least the debounce time: a line that is already
+// active when Wait starts must be released first, so a held switch does not
+// fire twice.
+//
+// The line needs a pull-up (active LOW) or pull-down (active HIGH) resistor.
+type Input struct {
+	gpio     gpio.Driver
+	pin      int
+	active   gpio.Level
+	debounce time.Duration
+	timeout  time.Duration // 0 = wait forever
+}
+
+// NewInput configures pin as an input. If activeLow is true, a pulse pulls
+// the line LOW. debounce is the minimum time the active level must hold;
+// timeout bounds each Wait (0 = no limit).
+func NewInput(g gpio.Driver, pin int, activeLow bool, debounce, timeout time.Duration) *Input {
+	_ = g.SetupPin(pin, gpio.Input)
+
+	active := gpio.High
+	if activeLow {
+		active = gpio.Low
+	}
+	return &Input{
+		gpio:     g,
+		pin:      pin,
+		active:   active,
+		debounce: debounce,
+		timeout:  timeout,
+	}
+}
+
+// Wait blocks until a pulse is seen on the input, ctx is done, or the
+// timeout expires (ErrTimeout).
+func (in *Input) Wait(ctx context.Context) error {
+	dbg.Verbose("trigger: waiting for pulse", "pin", in.pin)
+
+	if in.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, in.timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	armed := false
+	var activeSince time.Time
+	for {
+		level, err := in.gpio.ReadPin(in.pin)
+		if err != nil {
+			return fmt.Errorf("read trigger pin %d: %w", in.pin, err)
+		}
+		switch {
+		case level != in.active:
+			armed = true
+			activeSince = time.Time{}
+		case armed:
+			if activeSince.IsZero() {
+				activeSince = time.Now()
+			}
+			if time.Since(activeSince) >= in.debounce {
+				dbg.Verbose("trigger: pulse received", "pin", in.pin)
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return ErrTimeout
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
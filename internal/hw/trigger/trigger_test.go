@@ -0,0 +1,120 @@
+package trigger
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/gpio"
+)
+
+// scriptedDriver returns levels from a script, one per ReadPin call,
+// then repeats the last level forever.
+type scriptedDriver struct {
+	mu     sync.Mutex
+	levels []gpio.Level
+	reads  int
+	inputs []int
+}
+
+func (d *scriptedDriver) SetupPin(pin int, mode gpio.PinMode) error {
+	if mode == gpio.Input {
+		d.inputs = append(d.inputs, pin)
+	}
+	return nil
+}
+
+func (d *scriptedDriver) WritePin(pin int, level gpio.Level) error { return nil }
+
+func (d *scriptedDriver) ReadPin(pin int) (gpio.Level, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := min(d.reads, len(d.levels)-1)
+	d.reads++
+	return d.levels[i], nil
+}
+
+func (d *scriptedDriver) Close() error { return nil }
+
+func TestInput_SetsUpPinAsInput(t *testing.T) {
+	drv := &scriptedDriver{levels: []gpio.Level{gpio.Low}}
+	NewInput(drv, 12, false, 0, 0)
+	if len(drv.inputs) != 1 || drv.inputs[0] != 12 {
+		t.Errorf("input pins = %v, want [12]", drv.inputs)
+	}
+}
+
+func TestInput_WaitActiveHigh(t *testing.T) {
+	drv := &scriptedDriver{levels: []gpio.Level{gpio.Low, gpio.Low, gpio.High}}
+	in := NewInput(drv, 12, false, 0, time.Second)
+	if err := in.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+}
+
+func TestInput_WaitActiveLow(t *testing.T) {
+	drv := &scriptedDriver{levels: []gpio.Level{gpio.High, gpio.Low}}
+	in := NewInput(drv, 12, true, 0, time.Second)
+	if err := in.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+}
+
+func TestInput_HeldLineMustBeReleasedFirst(t *testing.T) {
+	// Line already active: no pulse until it goes inactive and back.
+	drv := &scriptedDriver{levels: []gpio.Level{gpio.High, gpio.High, gpio.High}}
+	in := NewInput(drv, 12, false, 0, 50*time.Millisecond)
+	if err := in.Wait(context.Background()); !errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want ErrTimeout for a held line", err)
+	}
+
+	drv = &scriptedDriver{levels: []gpio.Level{gpio.High, gpio.Low, gpio.High}}
+	in = NewInput(drv, 12, false, 0, time.Second)
+	if err := in.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait after release: %v", err)
+	}
+}
+
+func TestInput_DebounceIgnoresGlitch(t *testing.T) {
+	// One-sample glitch, then a long pulse.
+	levels := []gpio.Level{gpio.Low, gpio.High, gpio.Low}
+	for range 20 {
+		levels = append(levels, gpio.High)
+	}
+	drv := &scriptedDriver{levels: levels}
+	in := NewInput(drv, 12, false, 20*time.Millisecond, time.Second)
+	if err := in.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if drv.reads < 5 {
+		t.Errorf("pulse accepted after %d reads, glitch should have been ignored", drv.reads)
+	}
+}
+
+func TestInput_Timeout(t *testing.T) {
+	drv := &scriptedDriver{levels: []gpio.Level{gpio.Low}}
+	in := NewInput(drv, 12, false, 0, 30*time.Millisecond)
+	start := time.Now()
+	err := in.Wait(context.Background())
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("err = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("returned after %v, before the timeout", elapsed)
+	}
+}
+
+func TestInput_ContextCancelled(t *testing.T) {
+	drv := &scriptedDriver{levels: []gpio.Level{gpio.Low}}
+	in := NewInput(drv, 12, false, 0, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := in.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
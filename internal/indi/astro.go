@@ -0,0 +1,58 @@
+package indi
+
+import (
+	"math"
+	"time"
+)
+
+// Site is where the head stands on Earth.
+type Site struct {
+	LatitudeDeg  float64 // positive north
+	LongitudeDeg float64 // positive east
+	ElevationM   float64
+}
+
+// localSiderealDeg returns the local mean sidereal time at longitude lon
+// (degrees east) at t, in degrees [0, 360).
+func localSiderealDeg(t time.Time, lon float64) float64 {
+	jd := float64(t.UnixNano())/86400e9 + 2440587.5
+	gmst := 280.46061837 + 360.98564736629*(jd-2451545.0)
+	return norm360(gmst + lon)
+}
+
+// horizontal converts equatorial coordinates of the date (right ascension
+// in hours, declination in degrees) to azimuth (degrees from north,
+// eastwards) and altitude at site and t. Refraction is ignored.
+func horizontal(raHours, decDeg float64, site Site, t time.Time) (azDeg, altDeg float64) {
+	ha := rad(localSiderealDeg(t, site.LongitudeDeg) - raHours*15)
+	dec, lat := rad(decDeg), rad(site.LatitudeDeg)
+	alt := math.Asin(math.Sin(dec)*math.Sin(lat) + math.Cos(dec)*math.Cos(lat)*math.Cos(ha))
+	az := math.Atan2(-math.Cos(dec)*math.Sin(ha), math.Sin(dec)*math.Cos(lat)-math.Cos(dec)*math.Sin(lat)*math.Cos(ha))
+	return norm360(deg(az)), deg(alt)
+}
+
+// equatorial converts azimuth and altitude at site and t to equatorial
+// coordinates of the date, the inverse of horizontal.
+func equatorial(azDeg, altDeg float64, site Site, t time.Time) (raHours, decDeg float64) {
+	az, alt, lat := rad(azDeg), rad(altDeg), rad(site.LatitudeDeg)
+	dec := math.Asin(math.Sin(alt)*math.Sin(lat) + math.Cos(alt)*math.Cos(lat)*math.Cos(az))
+	ha := math.Atan2(-math.Cos(alt)*math.Sin(az), math.Sin(alt)*math.Cos(lat)-math.Cos(alt)*math.Sin(lat)*math.Cos(az))
+	return norm360(localSiderealDeg(t, site.LongitudeDeg)-deg(ha)) / 15, deg(dec)
+}
+
+// norm360 returns a in [0, 360).
+func norm360(a float64) float64 {
+	a = math.Mod(a, 360)
+	if a < 0 {
+		a += 360
+	}
+	return a
+}
+
+// wrap180 returns a in [-180, 180).
+func wrap180(a float64) float64 {
+	return norm360(a+180) - 180
+}
+
+func rad(d float64) float64 { return d * math.Pi / 180 }
+func deg(r float64) float64 { return r * 180 / math.Pi }
@@ -0,0 +1,76 @@
+package indi
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+// ---------- sidereal time ----------
+
+func TestLocalSiderealDeg(t *testing.T) {
+	cases := []struct {
+		name string
+		t    time.Time
+		lon  float64
+		want float64
+	}{
+		{"J2000", time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC), 0, 280.46061837},
+		// Meeus, Astronomical Algorithms, example 12.a: 13h10m46.3668s.
+		{"meeus_12a", time.Date(1987, 4, 10, 0, 0, 0, 0, time.UTC), 0, 197.693195},
+		{"east", time.Date(1987, 4, 10, 0, 0, 0, 0, time.UTC), 6.6323, 204.325495},
+		{"west_wraps", time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC), -300, 340.46061837},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := localSiderealDeg(tc.t, tc.lon); !near(got, tc.want, 1e-5) {
+				t.Errorf("localSiderealDeg = %.6f, want %.6f", got, tc.want)
+			}
+		})
+	}
+}
+
+// ---------- conversions ----------
+
+func TestHorizontal(t *testing.T) {
+	// Duffett-Smith, Practical Astronomy with your Calculator: hour angle
+	// 5h51m44s, declination 23°13'10" at latitude 52° N is at altitude
+	// 19°20'04", azimuth 283°16'16".
+	when := time.Date(1987, 4, 10, 0, 0, 0, 0, time.UTC) // sidereal time 197.693195° at Greenwich
+	ra := (197.693195 - (5+51.0/60+44.0/3600)*15) / 15
+	dec := 23 + 13.0/60 + 10.0/3600
+	site := Site{LatitudeDeg: 52}
+	az, alt := horizontal(ra, dec, site, when)
+	if !near(az, 283+16.0/60+16.0/3600, 1e-3) || !near(alt, 19+20.0/60+4.0/3600, 1e-3) {
+		t.Errorf("horizontal = az %.4f, alt %.4f, want 283.2711, 19.3344", az, alt)
+	}
+
+	// The celestial pole stands at the latitude, due north.
+	if az, alt := horizontal(3, 90, Site{LatitudeDeg: 46.5}, when); !near(alt, 46.5, 1e-9) || !(near(az, 0, 1e-6) || near(az, 360, 1e-6)) {
+		t.Errorf("pole: az %.6f, alt %.6f, want 0, 46.5", az, alt)
+	}
+}
+
+func TestEquatorial_RoundTrip(t *testing.T) {
+	when := time.Date(2026, 10, 18, 21, 30, 0, 0, time.UTC)
+	site := Site{LatitudeDeg: 46.5197, LongitudeDeg: 6.6323}
+	for _, c := range []struct{ ra, dec float64 }{{5.919, 7.407}, {18.615, 38.784}, {0.712, 41.269}, {6.752, -16.716}} {
+		az, alt := horizontal(c.ra, c.dec, site, when)
+		ra, dec := equatorial(az, alt, site, when)
+		if !near(ra, c.ra, 1e-9) || !near(dec, c.dec, 1e-9) {
+			t.Errorf("ra %g, dec %g -> az %g, alt %g -> ra %g, dec %g", c.ra, c.dec, az, alt, ra, dec)
+		}
+	}
+}
+
+func TestWrap180(t *testing.T) {
+	for in, want := range map[float64]float64{0: 0, 190: -170, -190: 170, 540: -180, 359.5: -0.5} {
+		if got := wrap180(in); !near(got, want, 1e-9) {
+			t.Errorf("wrap180(%g) = %g, want %g", in, got, want)
+		}
+	}
+}
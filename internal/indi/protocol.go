@@ -0,0 +1,180 @@
+package indi
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Property states.
+const (
+	stateIdle  = "Idle"
+	stateOk    = "Ok"
+	stateBusy  = "Busy"
+	stateAlert = "Alert"
+)
+
+// Property kinds, the infix of their XML elements (defNumberVector...).
+const (
+	kindNumber = "Number"
+	kindSwitch = "Switch"
+	kindText   = "Text"
+)
+
+// element is a member of a property: a number, a switch (On, Off) or a
+// text.
+type element struct {
+	name, label string
+	text        string  // texts, and switches: "On" or "Off"
+	number      float64 // numbers
+	format      string  // numbers: printf format, %m for sexagesimal
+	min, max    float64 // numbers
+}
+
+// property is a vector of elements of one kind.
+type property struct {
+	kind               string
+	name, label, group string
+	perm               string // "ro" or "rw"
+	rule               string // switches: OneOfMany, AtMostOne or AnyOfMany
+	state              string
+	elements           []element
+}
+
+// get returns the element called name, nil if p has none.
+func (p *property) get(name string) *element {
+	for i := range p.elements {
+		if p.elements[i].name == name {
+			return &p.elements[i]
+		}
+	}
+	return nil
+}
+
+// on returns the name of the first switch of p that is on, "" if none.
+func (p *property) on() string {
+	for _, e := range p.elements {
+		if e.text == "On" {
+			return e.name
+		}
+	}
+	return ""
+}
+
+// def returns the definition of p sent to clients: its elements with
+// their labels, formats and ranges.
+func (p *property) def(device string, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<def%sVector device=\"%s\" name=\"%s\" label=\"%s\" group=\"%s\" state=\"%s\" perm=\"%s\"",
+		p.kind, esc(device), p.name, esc(p.label), esc(p.group), p.state, p.perm)
+	if p.kind == kindSwitch {
+		fmt.Fprintf(&b, " rule=\"%s\"", p.rule)
+	}
+	fmt.Fprintf(&b, " timeout=\"60\" timestamp=\"%s\">\n", timestamp(now))
+	for _, e := range p.elements {
+		switch p.kind {
+		case kindNumber:
+			fmt.Fprintf(&b, "  <defNumber name=\"%s\" label=\"%s\" format=\"%s\" min=\"%s\" max=\"%s\" step=\"0\">%s</defNumber>\n",
+				e.name, esc(e.label), e.format, formatNumber(e.min), formatNumber(e.max), formatNumber(e.number))
+		default:
+			fmt.Fprintf(&b, "  <def%s name=\"%s\" label=\"%s\">%s</def%s>\n", p.kind, e.name, esc(e.label), esc(e.text), p.kind)
+		}
+	}
+	fmt.Fprintf(&b, "</def%sVector>\n", p.kind)
+	return b.String()
+}
+
+// set returns the update of p sent to clients: its state and values, with
+// an optional message.
+func (p *property) set(device string, now time.Time, message string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<set%sVector device=\"%s\" name=\"%s\" state=\"%s\" timeout=\"60\" timestamp=\"%s\"", p.kind, esc(device), p.name, p.state, timestamp(now))
+	if message != "" {
+		fmt.Fprintf(&b, " message=\"%s\"", esc(message))
+	}
+	b.WriteString(">\n")
+	for _, e := range p.elements {
+		v := esc(e.text)
+		if p.kind == kindNumber {
+			v = formatNumber(e.number)
+		}
+		fmt.Fprintf(&b, "  <one%s name=\"%s\">%s</one%s>\n", p.kind, e.name, v, p.kind)
+	}
+	fmt.Fprintf(&b, "</set%sVector>\n", p.kind)
+	return b.String()
+}
+
+// message returns a message of the device for the clients' logs.
+func message(device string, now time.Time, text string) string {
+	return fmt.Sprintf("<message device=\"%s\" timestamp=\"%s\" message=\"%s\"/>\n", esc(device), timestamp(now), esc(text))
+}
+
+// command is an element sent by a client: getProperties, or a
+// new*Vector with the elements it sets.
+type command struct {
+	XMLName  xml.Name
+	Device   string `xml:"device,attr"`
+	Name     string `xml:"name,attr"`
+	Elements []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+// values returns the elements of c by name, trimmed.
+func (c command) values() map[string]string {
+	v := make(map[string]string, len(c.Elements))
+	for _, e := range c.Elements {
+		v[e.Name] = strings.TrimSpace(e.Value)
+	}
+	return v
+}
+
+// parseNumber parses an INDI number: decimal, or sexagesimal with the
+// parts separated by colons or spaces ("-5:30:15", "5 30.25").
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == ' ' })
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	negative := strings.HasPrefix(s, "-")
+	v, scale := 0.0, 1.0
+	for i, part := range parts {
+		if i == 0 {
+			part = strings.TrimPrefix(part, "-")
+		}
+		f, err := strconv.ParseFloat(part, 64)
+		if err != nil || f < 0 || (i > 0 && f >= 60) {
+			return 0, fmt.Errorf("invalid number %q", s)
+		}
+		v += f / scale
+		scale *= 60
+	}
+	if negative {
+		v = -v
+	}
+	return v, nil
+}
+
+// formatNumber formats a number value: decimal, without exponent.
+func formatNumber(v float64) string {
+	v = math.Round(v*1e9) / 1e9
+	if v == 0 {
+		v = 0 // no "-0"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// timestamp formats t as an INDI timestamp, UTC without a zone.
+func timestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05")
+}
+
+func esc(s string) string {
+	return html.EscapeString(s)
+}
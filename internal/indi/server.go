@@ -0,0 +1,493 @@
+// Package indi serves the head as an INDI telescope, an alt-azimuth mount,
+// so that astronomy software (KStars/Ekos, Stellarium, CCDciel) can point
+// it at objects of the sky, e.g. to frame the tiles of a mosaic, while
+// PanGo keeps running its own captures. The INDI protocol is XML over TCP;
+// the server implements the standard telescope properties it needs.
+package indi
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the port of INDI servers.
+const DefaultPort = 7624
+
+// Timings of the server.
+const (
+	pollInterval = time.Second // coordinates are sent to clients when they change, at most this often
+	writeTimeout = 10 * time.Second
+)
+
+// driverInterface is the INDI interface of the device: a telescope.
+const driverInterface = "1"
+
+// Mount is the head pointed by the clients, in angles from where it
+// started: pan clockwise seen from above, tilt up.
+type Mount interface {
+	// Position returns the head angles; false when the hardware is not
+	// set up.
+	Position() (panDeg, tiltDeg float64, ok bool)
+	// Goto moves the head to the angles and returns when it stops: there,
+	// on an error, or when ctx is cancelled (aborted slew).
+	Goto(ctx context.Context, panDeg, tiltDeg float64) error
+}
+
+// Options configures a Server.
+type Options struct {
+	Device  string // device name shown to the clients; "" = PanGo
+	Version string // driver version shown to the clients
+
+	// Site returns where the head stands until a client sets it
+	// (GEOGRAPHIC_COORD, e.g. from the Ekos profile), e.g. from a GPS.
+	// Optional; false when unknown.
+	Site func() (Site, bool)
+
+	// Allow returns an error when the client at remote may not point the
+	// head (slew or sync), e.g. from outside the allowed networks. INDI
+	// has no authentication. Optional; nil lets every client point it.
+	Allow func(remote net.Addr) error
+}
+
+// Limits of the slews: the head does not point below the horizon, and its
+// tilt stays within straight down and straight up.
+const (
+	minAltitudeDeg = 0
+	maxTiltDeg     = 90
+)
+
+// Server is an INDI server with one device, the head. Azimuth and altitude
+// are the head angles plus offsets, zero until a client syncs the mount on
+// a known object (ON_COORD_SET to SYNC, then the coordinates of the object
+// the head points at): the head starts up pointing north, level, until
+// then. Slews take the shortest way in azimuth; the head does not track
+// the sky once there.
+type Server struct {
+	mount  Mount
+	device string
+	site   func() (Site, bool)
+	allow  func(remote net.Addr) error
+	now    func() time.Time
+
+	mu                  sync.Mutex
+	props               []*property
+	clients             map[*client]struct{}
+	clientSite          *Site // set by a client, over the Site option
+	azOffset, altOffset float64
+	move                context.CancelFunc // cancels the slew in progress
+	moveDone            chan struct{}      // closed when the last slew returns
+}
+
+// NewServer returns a server pointing m.
+func NewServer(m Mount, o Options) *Server {
+	if o.Device == "" {
+		o.Device = "PanGo"
+	}
+	if o.Site == nil {
+		o.Site = func() (Site, bool) { return Site{}, false }
+	}
+	if o.Allow == nil {
+		o.Allow = func(net.Addr) error { return nil }
+	}
+	const main, site = "Main Control", "Site Management"
+	s := &Server{
+		mount:   m,
+		device:  o.Device,
+		site:    o.Site,
+		allow:   o.Allow,
+		now:     time.Now,
+		clients: make(map[*client]struct{}),
+		props: []*property{
+			{kind: kindSwitch, name: "CONNECTION", label: "Connection", group: main, perm: "rw", rule: "OneOfMany", state: stateIdle, elements: []element{
+				{name: "CONNECT", label: "Connect", text: "Off"},
+				{name: "DISCONNECT", label: "Disconnect", text: "On"},
+			}},
+			{kind: kindText, name: "DRIVER_INFO", label: "Driver Info", group: "General Info", perm: "ro", state: stateIdle, elements: []element{
+				{name: "DRIVER_NAME", label: "Name", text: "PanGo"},
+				{name: "DRIVER_EXEC", label: "Exec", text: "pango"},
+				{name: "DRIVER_VERSION", label: "Version", text: o.Version},
+				{name: "DRIVER_INTERFACE", label: "Interface", text: driverInterface},
+			}},
+			{kind: kindNumber, name: "EQUATORIAL_EOD_COORD", label: "Eq. Coordinates", group: main, perm: "rw", state: stateIdle, elements: []element{
+				{name: "RA", label: "RA (hh:mm:ss)", format: "%010.6m", min: 0, max: 24},
+				{name: "DEC", label: "DEC (dd:mm:ss)", format: "%010.6m", min: -90, max: 90},
+			}},
+			{kind: kindNumber, name: "HORIZONTAL_COORD", label: "Horizontal Coord", group: main, perm: "rw", state: stateIdle, elements: []element{
+				{name: "AZ", label: "AZ D:M:S", format: "%010.6m", min: 0, max: 360},
+				{name: "ALT", label: "ALT D:M:S", format: "%010.6m", min: -90, max: 90},
+			}},
+			{kind: kindSwitch, name: "ON_COORD_SET", label: "On Set", group: main, perm: "rw", rule: "OneOfMany", state: stateIdle, elements: []element{
+				{name: "SLEW", label: "Slew", text: "On"},
+				{name: "SYNC", label: "Sync", text: "Off"},
+			}},
+			{kind: kindSwitch, name: "TELESCOPE_ABORT_MOTION", label: "Abort Motion", group: main, perm: "rw", rule: "AtMostOne", state: stateIdle, elements: []element{
+				{name: "ABORT", label: "Abort", text: "Off"},
+			}},
+			{kind: kindNumber, name: "GEOGRAPHIC_COORD", label: "Scope Location", group: site, perm: "rw", state: stateIdle, elements: []element{
+				{name: "LAT", label: "Lat (dd:mm:ss)", format: "%010.6m", min: -90, max: 90},
+				{name: "LONG", label: "Lon (dd:mm:ss)", format: "%010.6m", min: 0, max: 360},
+				{name: "ELEV", label: "Elevation (m)", format: "%g", min: -200, max: 10000},
+			}},
+		},
+	}
+	return s
+}
+
+// Serve accepts INDI clients on ln until ctx is cancelled, which also
+// aborts the slew in progress.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	stop := context.AfterFunc(ctx, func() { ln.Close() })
+	defer stop()
+	go s.poll(ctx)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				s.mu.Lock()
+				s.abortLocked()
+				s.mu.Unlock()
+				return nil
+			}
+			return err
+		}
+		go s.serveConn(ctx, conn)
+	}
+}
+
+// client is a connection to an INDI client.
+type client struct {
+	mu   sync.Mutex // serializes writes
+	conn net.Conn
+}
+
+// send writes msg to c. A failed write closes the connection, which ends
+// its reader.
+func (c *client) send(msg string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.conn.Write([]byte(msg)); err != nil {
+		c.conn.Close()
+	}
+}
+
+// serveConn reads the commands of a client until it disconnects.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	c := &client{conn: conn}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+	s.mu.Lock()
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c)
+		s.mu.Unlock()
+	}()
+	log.Printf("indi: client %s connected", conn.RemoteAddr())
+
+	dec := xml.NewDecoder(conn)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			log.Printf("indi: client %s disconnected", conn.RemoteAddr())
+			return
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var cmd command
+		if err := dec.DecodeElement(&cmd, &start); err != nil {
+			log.Printf("indi: client %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+		s.handle(c, cmd)
+	}
+}
+
+// handle runs a command of client c.
+func (s *Server) handle(c *client, cmd command) {
+	if cmd.Device != "" && cmd.Device != s.device {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch cmd.XMLName.Local {
+	case "getProperties":
+		s.refreshLocked()
+		for _, p := range s.props {
+			if cmd.Name == "" || cmd.Name == p.name {
+				c.send(p.def(s.device, s.now()))
+			}
+		}
+	case "newNumberVector", "newSwitchVector", "newTextVector":
+		if p := s.property(cmd.Name); p != nil && p.perm == "rw" && "new"+p.kind+"Vector" == cmd.XMLName.Local {
+			s.update(c, p, cmd.values())
+		}
+	}
+}
+
+// property returns the property called name, nil if none.
+func (s *Server) property(name string) *property {
+	for _, p := range s.props {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// update applies the values set by client c to p.
+func (s *Server) update(c *client, p *property, values map[string]string) {
+	switch p.name {
+	case "CONNECTION", "ON_COORD_SET":
+		setSwitches(p, values)
+		p.state = stateOk
+		s.broadcast(p.set(s.device, s.now(), ""))
+	case "TELESCOPE_ABORT_MOTION":
+		if values["ABORT"] != "On" {
+			return
+		}
+		s.abortLocked()
+		p.state = stateOk
+		s.broadcast(p.set(s.device, s.now(), "Motion aborted"))
+	case "GEOGRAPHIC_COORD":
+		v, err := numbers(p, values)
+		if err != nil {
+			s.alert(p, err)
+			return
+		}
+		s.clientSite = &Site{LatitudeDeg: v["LAT"], LongitudeDeg: wrap180(v["LONG"]), ElevationM: v["ELEV"]}
+		s.refreshLocked()
+		p.state = stateOk
+		s.broadcast(p.set(s.device, s.now(), ""))
+	case "EQUATORIAL_EOD_COORD", "HORIZONTAL_COORD":
+		s.point(c, p, values)
+	}
+}
+
+// point slews the head to, or syncs it on, the coordinates set in p by
+// client c.
+func (s *Server) point(c *client, p *property, values map[string]string) {
+	if s.property("CONNECTION").on() != "CONNECT" {
+		s.alert(p, errors.New("not connected"))
+		return
+	}
+	if err := s.allow(c.conn.RemoteAddr()); err != nil {
+		s.alert(p, err)
+		return
+	}
+	v, err := numbers(p, values)
+	if err != nil {
+		s.alert(p, err)
+		return
+	}
+	az, alt := v["AZ"], v["ALT"]
+	if p.name == "EQUATORIAL_EOD_COORD" {
+		site, ok := s.siteLocked()
+		if !ok {
+			s.alert(p, errors.New("site unknown: set the scope location, or configure a GPS"))
+			return
+		}
+		az, alt = horizontal(v["RA"], v["DEC"], site, s.now())
+	}
+	pan, tilt, ok := s.mount.Position()
+	if !ok {
+		s.alert(p, errors.New("head not ready"))
+		return
+	}
+
+	if s.property("ON_COORD_SET").on() == "SYNC" {
+		s.azOffset, s.altOffset = az-pan, alt-tilt
+		s.refreshLocked()
+		s.setCoordinates(stateOk, fmt.Sprintf("Synced: azimuth %.4f°, altitude %.4f°", norm360(az), alt))
+		return
+	}
+	tilt = alt - s.altOffset
+	switch {
+	case alt < minAltitudeDeg:
+		s.alert(p, fmt.Errorf("altitude %.4f° is below the horizon", alt))
+		return
+	case tilt < -maxTiltDeg || tilt > maxTiltDeg:
+		s.alert(p, fmt.Errorf("altitude %.4f° needs a tilt of %.4f° from level, beyond %d°", alt, tilt, maxTiltDeg))
+		return
+	}
+	s.slew(pan+wrap180(az-s.azOffset-pan), tilt)
+}
+
+// slew moves the head to pan, tilt in the background, after aborting the
+// slew in progress.
+func (s *Server) slew(pan, tilt float64) {
+	s.abortLocked()
+	ctx, cancel := context.WithCancel(context.Background())
+	prev, done := s.moveDone, make(chan struct{})
+	s.move, s.moveDone = cancel, done
+	s.setCoordinates(stateBusy, "")
+
+	go func() {
+		defer close(done)
+		defer cancel()
+		if prev != nil {
+			<-prev // the head is released by the previous slew
+		}
+		err := s.mount.Goto(ctx, pan, tilt)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.moveDone != done {
+			return // a newer slew reports
+		}
+		s.refreshLocked()
+		switch {
+		case ctx.Err() != nil:
+			s.setCoordinates(stateIdle, "Slew aborted")
+		case err != nil:
+			s.setCoordinates(stateAlert, "Slew failed: "+err.Error())
+		default:
+			s.setCoordinates(stateOk, "Slew complete")
+		}
+	}()
+}
+
+// abortLocked cancels the slew in progress, if any.
+func (s *Server) abortLocked() {
+	if s.move != nil {
+		s.move()
+	}
+}
+
+// setCoordinates sets the state of both coordinate properties and sends
+// them.
+func (s *Server) setCoordinates(state, msg string) {
+	for _, name := range []string{"EQUATORIAL_EOD_COORD", "HORIZONTAL_COORD"} {
+		p := s.property(name)
+		p.state = state
+		s.broadcast(p.set(s.device, s.now(), msg))
+		msg = "" // once
+	}
+}
+
+// alert rejects a command on p with err.
+func (s *Server) alert(p *property, err error) {
+	p.state = stateAlert
+	s.broadcast(p.set(s.device, s.now(), err.Error()))
+}
+
+// siteLocked returns the site set by a client, else that of the Site
+// option.
+func (s *Server) siteLocked() (Site, bool) {
+	if s.clientSite != nil {
+		return *s.clientSite, true
+	}
+	return s.site()
+}
+
+// refreshLocked updates the coordinates and site properties from the head
+// and reports whether the coordinates changed.
+func (s *Server) refreshLocked() bool {
+	site, siteOK := s.siteLocked()
+	if siteOK {
+		g := s.property("GEOGRAPHIC_COORD")
+		g.get("LAT").number, g.get("LONG").number, g.get("ELEV").number = site.LatitudeDeg, norm360(site.LongitudeDeg), site.ElevationM
+	}
+	pan, tilt, ok := s.mount.Position()
+	if !ok {
+		return false
+	}
+	hor, eq := s.property("HORIZONTAL_COORD"), s.property("EQUATORIAL_EOD_COORD")
+	before := [4]string{}
+	for i, e := range []*element{hor.get("AZ"), hor.get("ALT"), eq.get("RA"), eq.get("DEC")} {
+		before[i] = formatNumber(e.number)
+	}
+	az, alt := norm360(pan+s.azOffset), tilt+s.altOffset
+	hor.get("AZ").number, hor.get("ALT").number = az, alt
+	if siteOK {
+		eq.get("RA").number, eq.get("DEC").number = equatorial(az, alt, site, s.now())
+	}
+	for i, e := range []*element{hor.get("AZ"), hor.get("ALT"), eq.get("RA"), eq.get("DEC")} {
+		if before[i] != formatNumber(e.number) {
+			return true
+		}
+	}
+	return false
+}
+
+// poll sends the coordinates to the clients when they change, until ctx is
+// cancelled: while the head moves, and as the sky turns.
+func (s *Server) poll(ctx context.Context) {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+		s.mu.Lock()
+		if len(s.clients) > 0 && s.refreshLocked() {
+			for _, name := range []string{"EQUATORIAL_EOD_COORD", "HORIZONTAL_COORD"} {
+				s.broadcast(s.property(name).set(s.device, s.now(), ""))
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// broadcast sends msg to all clients.
+func (s *Server) broadcast(msg string) {
+	for c := range s.clients {
+		c.send(msg)
+	}
+}
+
+// setSwitches turns on the switches of p set On in values; with rule
+// OneOfMany the others turn off.
+func setSwitches(p *property, values map[string]string) {
+	on := ""
+	for name, v := range values {
+		if e := p.get(name); e != nil && v == "On" {
+			on = name
+		}
+	}
+	for i := range p.elements {
+		e := &p.elements[i]
+		switch {
+		case e.name == on:
+			e.text = "On"
+		case p.rule == "OneOfMany" && on != "":
+			e.text = "Off"
+		case values[e.name] == "Off":
+			e.text = "Off"
+		}
+	}
+}
+
+// numbers returns the values of the numbers of p set in values, and the
+// current values of the others, checked against their ranges.
+func numbers(p *property, values map[string]string) (map[string]float64, error) {
+	v := make(map[string]float64, len(p.elements))
+	for _, e := range p.elements {
+		v[e.name] = e.number
+		s, ok := values[e.name]
+		if !ok {
+			continue
+		}
+		n, err := parseNumber(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.name, err)
+		}
+		if n < e.min || n > e.max {
+			return nil, fmt.Errorf("%s %g out of range [%g, %g]", e.name, n, e.min, e.max)
+		}
+		v[e.name] = n
+	}
+	return v, nil
+}
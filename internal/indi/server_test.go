@@ -0,0 +1,316 @@
+package indi
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMount is a head that reaches its targets at once, or when released
+// with hold set.
+type fakeMount struct {
+	mu        sync.Mutex
+	pan, tilt float64
+	hold      bool
+	gotos     chan [2]float64
+}
+
+func newFakeMount() *fakeMount {
+	return &fakeMount{gotos: make(chan [2]float64, 10)}
+}
+
+func (m *fakeMount) Position() (float64, float64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pan, m.tilt, true
+}
+
+func (m *fakeMount) Goto(ctx context.Context, pan, tilt float64) error {
+	m.gotos <- [2]float64{pan, tilt}
+	m.mu.Lock()
+	hold := m.hold
+	m.mu.Unlock()
+	if hold {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	m.mu.Lock()
+	m.pan, m.tilt = pan, tilt
+	m.mu.Unlock()
+	return nil
+}
+
+// reply is an element sent by the server.
+type reply struct {
+	XMLName  xml.Name
+	Name     string `xml:"name,attr"`
+	State    string `xml:"state,attr"`
+	Message  string `xml:"message,attr"`
+	Elements []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:",any"`
+}
+
+func (r reply) value(name string) string {
+	for _, e := range r.Elements {
+		if e.Name == name {
+			return strings.TrimSpace(e.Value)
+		}
+	}
+	return ""
+}
+
+// testClient is an INDI client connected to a test server.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	dec  *xml.Decoder
+}
+
+// startServer serves s on a local port and connects a client to it.
+func startServer(t *testing.T, s *Server) *testClient {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	})
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, dec: xml.NewDecoder(conn)}
+}
+
+func (c *testClient) send(format string, args ...any) {
+	c.t.Helper()
+	if _, err := fmt.Fprintf(c.conn, format, args...); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// expect reads replies until the element local of the property name, with
+// the given state when not empty.
+func (c *testClient) expect(local, name, state string) reply {
+	c.t.Helper()
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		tok, err := c.dec.Token()
+		if err != nil {
+			c.t.Fatalf("waiting for %s %s %s: %v", local, name, state, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var r reply
+		if err := c.dec.DecodeElement(&r, &start); err != nil {
+			c.t.Fatal(err)
+		}
+		if r.XMLName.Local == local && r.Name == name && (state == "" || r.State == state) {
+			return r
+		}
+	}
+}
+
+func (c *testClient) connect() {
+	c.t.Helper()
+	c.send(`<newSwitchVector device="PanGo" name="CONNECTION"><oneSwitch name="CONNECT">On</oneSwitch></newSwitchVector>`)
+	if r := c.expect("setSwitchVector", "CONNECTION", stateOk); r.value("CONNECT") != "On" || r.value("DISCONNECT") != "Off" {
+		c.t.Fatalf("connection = %+v", r)
+	}
+}
+
+func expectGoto(t *testing.T, m *fakeMount, pan, tilt float64) {
+	t.Helper()
+	select {
+	case got := <-m.gotos:
+		if !near(got[0], pan, 1e-6) || !near(got[1], tilt, 1e-6) {
+			t.Errorf("goto pan %g, tilt %g, want %g, %g", got[0], got[1], pan, tilt)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no goto")
+	}
+}
+
+// ---------- properties ----------
+
+func TestServer_GetProperties(t *testing.T) {
+	c := startServer(t, NewServer(newFakeMount(), Options{Version: "1.4.0"}))
+	c.send(`<getProperties version="1.7"/>`)
+	info := c.expect("defTextVector", "DRIVER_INFO", "")
+	if info.value("DRIVER_INTERFACE") != "1" || info.value("DRIVER_VERSION") != "1.4.0" {
+		t.Errorf("driver info = %+v", info)
+	}
+	hor := c.expect("defNumberVector", "HORIZONTAL_COORD", "")
+	if hor.value("AZ") != "0" || hor.value("ALT") != "0" {
+		t.Errorf("horizontal = %+v, want north, level", hor)
+	}
+	c.expect("defSwitchVector", "ON_COORD_SET", "")
+	c.expect("defNumberVector", "GEOGRAPHIC_COORD", "")
+
+	// One property, of another device: ignored.
+	c.send(`<getProperties version="1.7" device="CCD Simulator"/><getProperties version="1.7" device="PanGo" name="CONNECTION"/>`)
+	if r := c.expect("defSwitchVector", "CONNECTION", ""); r.value("DISCONNECT") != "On" {
+		t.Errorf("connection = %+v", r)
+	}
+}
+
+// ---------- slews ----------
+
+func TestServer_Slew(t *testing.T) {
+	m := newFakeMount()
+	c := startServer(t, NewServer(m, Options{}))
+
+	c.send(`<newNumberVector device="PanGo" name="HORIZONTAL_COORD"><oneNumber name="AZ">90</oneNumber><oneNumber name="ALT">30</oneNumber></newNumberVector>`)
+	if r := c.expect("setNumberVector", "HORIZONTAL_COORD", stateAlert); r.Message != "not connected" {
+		t.Errorf("message = %q", r.Message)
+	}
+
+	c.connect()
+	c.send(`<newNumberVector device="PanGo" name="HORIZONTAL_COORD"><oneNumber name="AZ">90:30</oneNumber><oneNumber name="ALT">30</oneNumber></newNumberVector>`)
+	c.expect("setNumberVector", "HORIZONTAL_COORD", stateBusy)
+	expectGoto(t, m, 90.5, 30)
+	if r := c.expect("setNumberVector", "HORIZONTAL_COORD", stateOk); r.value("AZ") != "90.5" || r.value("ALT") != "30" {
+		t.Errorf("horizontal = %+v", r)
+	}
+
+	// Sync: the head points at azimuth 100°, altitude 35°.
+	c.send(`<newSwitchVector device="PanGo" name="ON_COORD_SET"><oneSwitch name="SYNC">On</oneSwitch></newSwitchVector>`)
+	c.expect("setSwitchVector", "ON_COORD_SET", stateOk)
+	c.send(`<newNumberVector device="PanGo" name="HORIZONTAL_COORD"><oneNumber name="AZ">100</oneNumber><oneNumber name="ALT">35</oneNumber></newNumberVector>`)
+	if r := c.expect("setNumberVector", "EQUATORIAL_EOD_COORD", stateOk); !strings.HasPrefix(r.Message, "Synced") {
+		t.Errorf("sync message = %q", r.Message)
+	}
+
+	// Then the slew to azimuth 350° turns 110° left, the shortest way.
+	c.send(`<newSwitchVector device="PanGo" name="ON_COORD_SET"><oneSwitch name="SLEW">On</oneSwitch></newSwitchVector>`)
+	c.expect("setSwitchVector", "ON_COORD_SET", stateOk)
+	c.send(`<newNumberVector device="PanGo" name="HORIZONTAL_COORD"><oneNumber name="AZ">350</oneNumber></newNumberVector>`)
+	expectGoto(t, m, -19.5, 30) // pan 90.5° + 9.5° offset; altitude unchanged: 35°
+	if r := c.expect("setNumberVector", "HORIZONTAL_COORD", stateOk); r.value("AZ") != "350" || r.value("ALT") != "35" {
+		t.Errorf("horizontal = %+v", r)
+	}
+
+	c.send(`<newNumberVector device="PanGo" name="HORIZONTAL_COORD"><oneNumber name="ALT">91</oneNumber></newNumberVector>`)
+	if r := c.expect("setNumberVector", "HORIZONTAL_COORD", stateAlert); !strings.Contains(r.Message, "out of range") {
+		t.Errorf("message = %q", r.Message)
+	}
+	c.send(`<newNumberVector device="PanGo" name="HORIZONTAL_COORD"><oneNumber name="ALT">-2</oneNumber></newNumberVector>`)
+	if r := c.expect("setNumberVector", "HORIZONTAL_COORD", stateAlert); !strings.Contains(r.Message, "below the horizon") {
+		t.Errorf("message = %q", r.Message)
+	}
+	select {
+	case g := <-m.gotos:
+		t.Errorf("goto %v below the horizon", g)
+	default:
+	}
+}
+
+func TestServer_Allow(t *testing.T) {
+	m := newFakeMount()
+	var got net.Addr
+	allow := func(remote net.Addr) error {
+		got = remote
+		return errors.New("control claimed by studio")
+	}
+	c := startServer(t, NewServer(m, Options{Allow: allow}))
+	c.connect()
+	c.send(`<newNumberVector device="PanGo" name="HORIZONTAL_COORD"><oneNumber name="AZ">90</oneNumber><oneNumber name="ALT">30</oneNumber></newNumberVector>`)
+	if r := c.expect("setNumberVector", "HORIZONTAL_COORD", stateAlert); r.Message != "control claimed by studio" {
+		t.Errorf("message = %q", r.Message)
+	}
+	if got == nil || got.String() != c.conn.LocalAddr().String() {
+		t.Errorf("allow called with %v, want the client at %v", got, c.conn.LocalAddr())
+	}
+	select {
+	case g := <-m.gotos:
+		t.Errorf("goto %v while refused", g)
+	default:
+	}
+}
+
+func TestServer_SlewEquatorial(t *testing.T) {
+	m := newFakeMount()
+	s := NewServer(m, Options{})
+	when := time.Date(2026, 10, 19, 4, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return when }
+	c := startServer(t, s)
+	c.connect()
+
+	// Betelgeuse, without a site.
+	c.send(`<newNumberVector device="PanGo" name="EQUATORIAL_EOD_COORD"><oneNumber name="RA">5:55:10.3</oneNumber><oneNumber name="DEC">7:24:25</oneNumber></newNumberVector>`)
+	if r := c.expect("setNumberVector", "EQUATORIAL_EOD_COORD", stateAlert); !strings.Contains(r.Message, "site unknown") {
+		t.Errorf("message = %q", r.Message)
+	}
+
+	// The site as Ekos sets it: longitude east, 0-360°.
+	c.send(`<newNumberVector device="PanGo" name="GEOGRAPHIC_COORD"><oneNumber name="LAT">46.5197</oneNumber><oneNumber name="LONG">353.3677</oneNumber><oneNumber name="ELEV">372</oneNumber></newNumberVector>`)
+	c.expect("setNumberVector", "GEOGRAPHIC_COORD", stateOk)
+	c.send(`<newNumberVector device="PanGo" name="EQUATORIAL_EOD_COORD"><oneNumber name="RA">5:55:10.3</oneNumber><oneNumber name="DEC">7:24:25</oneNumber></newNumberVector>`)
+	ra, _ := parseNumber("5:55:10.3")
+	dec, _ := parseNumber("7:24:25")
+	az, alt := horizontal(ra, dec, Site{LatitudeDeg: 46.5197, LongitudeDeg: -6.6323}, when)
+	expectGoto(t, m, wrap180(az), alt)
+	r := c.expect("setNumberVector", "EQUATORIAL_EOD_COORD", stateOk)
+	if got, _ := parseNumber(r.value("RA")); !near(got, ra, 1e-6) {
+		t.Errorf("RA = %s, want %g", r.value("RA"), ra)
+	}
+}
+
+func TestServer_Abort(t *testing.T) {
+	m := newFakeMount()
+	m.hold = true
+	c := startServer(t, NewServer(m, Options{Site: func() (Site, bool) { return Site{LatitudeDeg: 46.5}, true }}))
+	c.connect()
+	c.send(`<newNumberVector device="PanGo" name="HORIZONTAL_COORD"><oneNumber name="AZ">45</oneNumber></newNumberVector>`)
+	expectGoto(t, m, 45, 0)
+	c.send(`<newSwitchVector device="PanGo" name="TELESCOPE_ABORT_MOTION"><oneSwitch name="ABORT">On</oneSwitch></newSwitchVector>`)
+	c.expect("setSwitchVector", "TELESCOPE_ABORT_MOTION", stateOk)
+	if r := c.expect("setNumberVector", "EQUATORIAL_EOD_COORD", stateIdle); r.Message != "Slew aborted" {
+		t.Errorf("message = %q", r.Message)
+	}
+}
+
+// ---------- numbers ----------
+
+func TestParseNumber(t *testing.T) {
+	cases := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"12.5", 12.5, true},
+		{"5:30", 5.5, true},
+		{"-5:30:36", -5.51, true},
+		{"-0:30", -0.5, true},
+		{" 5 30 36 ", 5.51, true},
+		{"5:60", 0, false},
+		{"5:-1", 0, false},
+		{"", 0, false},
+		{"1:2:3:4", 0, false},
+		{"north", 0, false},
+	}
+	for _, tc := range cases {
+		got, err := parseNumber(tc.in)
+		if (err == nil) != tc.ok || (tc.ok && !near(got, tc.want, 1e-9)) {
+			t.Errorf("parseNumber(%q) = %g, %v", tc.in, got, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package logfile writes the log to a file rotated by size, keeping a
+// bounded number of rotated files for a bounded time, so that a headless
+// rig leaves a trace of its sessions without filling its SD card.
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTime is the layout of the time suffix of rotated files, e.g.
+// pango.log.20261017-225653.000: their names sort in rotation order.
+const backupTime = "20060102-150405.000"
+
+// Options are the rotation limits of a Writer.
+type Options struct {
+	MaxSize    int64         // rotate before the file grows past this many bytes; 0 = never
+	MaxAge     time.Duration // remove rotated files last written longer ago; 0 = keep them
+	MaxBackups int           // rotated files kept, the most recent ones; 0 = all
+}
+
+// Writer is an io.Writer appending to a log file, rotated by Options: the
+// file is renamed with the time as suffix and a new one started. It is
+// safe for concurrent use.
+type Writer struct {
+	path string
+	opts Options
+	now  func() time.Time
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+// Open opens the log file at path for appending, creating it and its
+// directory when missing, and removes the rotated files past the limits.
+func Open(path string, opts Options) (*Writer, error) {
+	w := &Writer{path: path, opts: opts, now: time.Now}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("log file: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	w.prune()
+	return w, nil
+}
+
+// Path returns the path of the log file.
+func (w *Writer) Path() string {
+	return w.path
+}
+
+// Write appends p to the log file, rotating it first when p would make it
+// grow past MaxSize.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	return err
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("log file: %w", err)
+	}
+	w.f, w.size = f, info.Size()
+	return nil
+}
+
+// rotate renames the log file with the time as suffix and starts a new
+// one. w.mu must be held.
+func (w *Writer) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	w.f = nil
+	backup := w.path + "." + w.now().Format(backupTime)
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			break
+		}
+		backup = fmt.Sprintf("%s.%s-%d", w.path, w.now().Format(backupTime), i)
+	}
+	if err := os.Rename(w.path, backup); err != nil {
+		return fmt.Errorf("log file: %w", err)
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.prune()
+	return nil
+}
+
+// prune removes the rotated files beyond MaxBackups or older than MaxAge.
+// Errors are ignored: the files are left for the next rotation.
+func (w *Writer) prune() {
+	backups := w.backups()
+	if n := w.opts.MaxBackups; n > 0 && len(backups) > n {
+		for _, b := range backups[:len(backups)-n] {
+			os.Remove(b)
+		}
+		backups = backups[len(backups)-n:]
+	}
+	if w.opts.MaxAge <= 0 {
+		return
+	}
+	cutoff := w.now().Add(-w.opts.MaxAge)
+	for _, b := range backups {
+		if info, err := os.Stat(b); err == nil && info.ModTime().Before(cutoff) {
+			os.Remove(b)
+		}
+	}
+}
+
+// backups returns the rotated files of the log file, oldest first.
+func (w *Writer) backups() []string {
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return nil
+	}
+	prefix := filepath.Base(w.path) + "."
+	var backups []string
+	for _, e := range entries {
+		suffix, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() || len(suffix) < len(backupTime) {
+			continue
+		}
+		if _, err := time.Parse(backupTime, suffix[:len(backupTime)]); err != nil {
+			continue
+		}
+		backups = append(backups, filepath.Join(filepath.Dir(w.path), e.Name()))
+	}
+	slices.Sort(backups)
+	return backups
+}
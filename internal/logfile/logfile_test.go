@@ -0,0 +1,147 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClock returns times one second apart, for distinct backup names.
+func fakeClock() func() time.Time {
+	t := time.Date(2026, 10, 17, 22, 0, 0, 0, time.UTC)
+	return func() time.Time {
+		t = t.Add(time.Second)
+		return t
+	}
+}
+
+// logFiles returns the names of the files in dir.
+func logFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+// ---------- Open ----------
+
+func TestOpen_AppendsAndCreatesDir(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "pango.log")
+	for _, line := range []string{"first\n", "second\n"} {
+		w, err := Open(path, Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("log = %q", data)
+	}
+}
+
+func TestWrite_Closed(t *testing.T) {
+	w, err := Open(filepath.Join(t.TempDir(), "pango.log"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if _, err := w.Write([]byte("late\n")); err == nil {
+		t.Error("write after Close succeeded")
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+// ---------- Rotation ----------
+
+func TestWrite_RotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pango.log")
+	w, err := Open(path, Options{MaxSize: 12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.now = fakeClock()
+	for _, line := range []string{"12345\n", "6789\n", "abcdef\n", "this line is longer than the limit\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"pango.log", "pango.log.20261017-220001.000", "pango.log.20261017-220002.000"}
+	if got := logFiles(t, dir); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	for name, content := range map[string]string{
+		"pango.log.20261017-220001.000": "12345\n6789\n",
+		"pango.log.20261017-220002.000": "abcdef\n",
+		"pango.log":                     "this line is longer than the limit\n",
+	} {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		if string(data) != content {
+			t.Errorf("%s = %q, want %q", name, data, content)
+		}
+	}
+}
+
+func TestWrite_KeepsMaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(filepath.Join(dir, "pango.log"), Options{MaxSize: 4, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.now = fakeClock()
+	for range 5 {
+		w.Write([]byte("line\n"))
+	}
+
+	want := []string{"pango.log", "pango.log.20261017-220003.000", "pango.log.20261017-220004.000"}
+	if got := logFiles(t, dir); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("files = %v, want the log and the 2 last backups %v", got, want)
+	}
+}
+
+func TestOpen_RemovesOldBackups(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pango.log")
+	old := path + ".20260101-000000.000"
+	recent := path + ".20261016-000000.000"
+	other := filepath.Join(dir, "pango.log.notes")
+	for _, p := range []string{old, recent, other} {
+		if err := os.WriteFile(p, []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chtimes(old, time.Now().AddDate(0, 0, -30), time.Now().AddDate(0, 0, -30)); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := Open(path, Options{MaxAge: 7 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	want := []string{"pango.log", "pango.log.20261016-000000.000", "pango.log.notes"}
+	if got := logFiles(t, dir); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/camera"
+)
+
+// DarkFrameParams defines a dark-frame series shot at the end of a session.
+// Dark frames use the same exposure as the light frames so the sensor noise
+// can be subtracted during stacking.
+type DarkFrameParams struct {
+	Count    int           // number of dark frames; 0 = disabled
+	CapDelay time.Duration // time given to cap the lens after the announcement; 0 = shutter-only, no wait
+
+	ShotDelay      time.Duration // delay before each shot
+	PostShotDelay  time.Duration // minimum hold after each shot
+	ExposureTime   time.Duration // exposure set on the camera
+	ExposureMargin time.Duration // extra hold after the exposure
+
+	BulbExposure time.Duration // when > 0, shoot in bulb mode with this exposure (e.g. last ramped frame)
+}
+
+// RunDarkFrames announces the dark-frame series on the notifier, waits
+// p.CapDelay for the lens to be capped, then shoots p.Count frames without
+// moving (motors disabled). A pending pause is waited out first.
+func (s *Sequence) RunDarkFrames(ctx context.Context, p DarkFrameParams) error {
+	if p.Count <= 0 {
+		return nil
+	}
+	var bulb camera.BulbCamera
+	if p.BulbExposure > 0 {
+		b, ok := s.camera.(camera.BulbCamera)
+		if !ok {
+			return fmt.Errorf("bulb dark frames require a camera with bulb support")
+		}
+		bulb = b
+	}
+	// A pause requested during the last cell holds until resumed: finishing
+	// is not reachable from paused, and the user stopped the rig.
+	if _, err := s.waitIfPaused(ctx); err != nil {
+		return err
+	}
+	s.setState(StateFinishing)
+
+	if p.CapDelay > 0 {
+		s.announce("warning", fmt.Sprintf("Cap the lens now: %d dark frames start in %s", p.Count, p.CapDelay.Round(time.Second)))
+		if err := sleepUntil(ctx, time.Now().Add(p.CapDelay)); err != nil {
+			return err
+		}
+	}
+	s.announce("info", fmt.Sprintf("Shooting %d dark frames", p.Count))
+
+	_ = s.motion.DisableMotors()
+	defer func() { _ = s.motion.EnableMotors() }()
+
+	hold := GridShotParams{
+		PostShotDelay:  p.PostShotDelay,
+		ExposureTime:   p.ExposureTime,
+		ExposureMargin: p.ExposureMargin,
+	}.ShotHold()
+	for i := 0; i < p.Count; i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		time.Sleep(p.ShotDelay)
+		if bulb != nil {
+			if err := bulb.ShootBulb(p.BulbExposure); err != nil {
+				return err
+			}
+			time.Sleep(max(p.PostShotDelay, p.ExposureMargin))
+		} else {
+			if err := s.camera.Shoot(); err != nil {
+				return err
+			}
+			time.Sleep(hold)
+		}
+		dbg.Live("dark frame", "frame", i+1, "frames", p.Count)
+	}
+
+	s.announce("info", "Dark frames complete: the lens can be uncapped")
+	return nil
+}
@@ -0,0 +1,148 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingNotifier collects announcements.
+type recordingNotifier struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (r *recordingNotifier) notify(level, msg string) {
+	r.mu.Lock()
+	r.msgs = append(r.msgs, level+": "+msg)
+	r.mu.Unlock()
+}
+
+func (r *recordingNotifier) all() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.msgs...)
+}
+
+func TestRunDarkFrames_Disabled(t *testing.T) {
+	cam := &mockCamera{}
+	seq := NewSequence(newTestController(), cam)
+	if err := seq.RunDarkFrames(context.Background(), DarkFrameParams{}); err != nil {
+		t.Fatalf("RunDarkFrames: %v", err)
+	}
+	if cam.shotCount() != 0 {
+		t.Errorf("shots = %d, want 0", cam.shotCount())
+	}
+}
+
+func TestRunDarkFrames_PromptAnnouncesAndWaits(t *testing.T) {
+	cam := &mockCamera{}
+	rec := &recordingNotifier{}
+	seq := NewSequence(newTestController(), cam)
+	seq.SetNotifier(rec.notify)
+
+	start := time.Now()
+	err := seq.RunDarkFrames(context.Background(), DarkFrameParams{
+		Count:    3,
+		CapDelay: 30 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("RunDarkFrames: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("elapsed = %v, want >= 30ms cap delay", elapsed)
+	}
+	if cam.shotCount() != 3 {
+		t.Errorf("shots = %d, want 3", cam.shotCount())
+	}
+	msgs := rec.all()
+	if len(msgs) == 0 || !strings.HasPrefix(msgs[0], "warning: Cap the lens") {
+		t.Errorf("first announcement = %v, want a cap-the-lens warning", msgs)
+	}
+	if last := msgs[len(msgs)-1]; !strings.Contains(last, "uncapped") {
+		t.Errorf("last announcement = %q, want completion notice", last)
+	}
+}
+
+func TestRunDarkFrames_ShutterOnlyNoPrompt(t *testing.T) {
+	cam := &mockCamera{}
+	rec := &recordingNotifier{}
+	seq := NewSequence(newTestController(), cam)
+	seq.SetNotifier(rec.notify)
+
+	if err := seq.RunDarkFrames(context.Background(), DarkFrameParams{Count: 2}); err != nil {
+		t.Fatalf("RunDarkFrames: %v", err)
+	}
+	for _, m := range rec.all() {
+		if strings.Contains(m, "Cap the lens") {
+			t.Errorf("shutter-only mode should not prompt, got %q", m)
+		}
+	}
+	if cam.shotCount() != 2 {
+		t.Errorf("shots = %d, want 2", cam.shotCount())
+	}
+}
+
+func TestRunDarkFrames_Bulb(t *testing.T) {
+	cam := &bulbCamera{}
+	seq := NewSequence(newTestController(), cam)
+	err := seq.RunDarkFrames(context.Background(), DarkFrameParams{
+		Count:        2,
+		BulbExposure: 3 * time.Microsecond,
+	})
+	if err != nil {
+		t.Fatalf("RunDarkFrames: %v", err)
+	}
+	if len(cam.exposures) != 2 || cam.exposures[0] != 3*time.Microsecond {
+		t.Errorf("bulb exposures = %v, want [3µs 3µs]", cam.exposures)
+	}
+}
+
+func TestRunDarkFrames_CancelDuringCapDelay(t *testing.T) {
+	cam := &mockCamera{}
+	seq := NewSequence(newTestController(), cam)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := seq.RunDarkFrames(ctx, DarkFrameParams{Count: 5, CapDelay: time.Minute})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if cam.shotCount() != 0 {
+		t.Errorf("shots = %d, want 0", cam.shotCount())
+	}
+}
+
+func TestRunDarkFrames_WaitsOutPause(t *testing.T) {
+	// Paused during the last cell: the grid ends, the dark frames wait.
+	l := NewLifecycle()
+	l.Transition(StatePlanning)
+	l.Transition(StateShooting)
+	if err := l.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	cam := &mockCamera{}
+	seq := NewSequence(newTestController(), cam)
+	seq.SetLifecycle(l)
+
+	done := make(chan error, 1)
+	go func() { done <- seq.RunDarkFrames(context.Background(), DarkFrameParams{Count: 2}) }()
+	time.Sleep(20 * time.Millisecond)
+	if cam.shotCount() != 0 || l.State() != StatePaused {
+		t.Fatalf("while paused: %d shots, state %s", cam.shotCount(), l.State())
+	}
+
+	if err := l.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("RunDarkFrames: %v", err)
+	}
+	if cam.shotCount() != 2 || l.State() != StateFinishing {
+		t.Errorf("after resume: %d shots, state %s, want 2 in finishing", cam.shotCount(), l.State())
+	}
+}
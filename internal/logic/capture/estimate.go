@@ -0,0 +1,81 @@
+package capture
+
+import (
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+)
+
+// EstimateGridDuration estimates how long RunGridShot takes with p: the move
+// to the start position, every tilt and pan move (p.MoveSpeed per step), the
+// delays after each move and the stabilization and hold around each shot.
+// Camera latency and external trigger waits are not included.
+func EstimateGridDuration(p GridShotParams) time.Duration {
+	plan := p.GridPlan
+	if plan == nil || plan.PanColumns <= 0 || plan.TiltRows <= 0 {
+		return 0
+	}
+	shots := plan.PanColumns * plan.TiltRows
+	tiltMoves := plan.PanColumns * (plan.TiltRows - 1)
+	panMoves := plan.PanColumns - 1
+
+	steps := abs(plan.StartPanSteps) + abs(plan.StartTiltSteps) +
+		tiltMoves*abs(plan.TiltStepSize) + panMoves*abs(plan.PanStepSize)
+
+	d := time.Duration(steps) * p.MoveSpeed
+	d += time.Duration(tiltMoves+panMoves) * p.Delay
+	d += time.Duration(shots) * (p.ShotDelay + p.ShotHold())
+	return d
+}
+
+// EstimatePanoramasDuration estimates how long RunPanoramas takes: each grid,
+// plus the return to the origin between two panoramas.
+func EstimatePanoramasDuration(panoramas []Panorama, p GridShotParams) time.Duration {
+	var d time.Duration
+	for i, pano := range panoramas {
+		if i > 0 {
+			endPan, endTilt := gridEnd(panoramas[i-1].Plan)
+			d += time.Duration(abs(endPan)+abs(endTilt)) * p.MoveSpeed
+		}
+		gp := p
+		gp.GridPlan = pano.Plan
+		d += EstimateGridDuration(gp)
+	}
+	return d
+}
+
+// gridEnd returns where a serpentine traversal of plan ends, relative to
+// the position it started from: last column, and bottom row when the last
+// column goes down (odd column count) or top row otherwise.
+func gridEnd(plan *geometry.GridPlan) (pan, tilt int) {
+	pan = plan.StartPanSteps + (plan.PanColumns-1)*plan.PanStepSize
+	tilt = plan.StartTiltSteps
+	if plan.PanColumns%2 == 1 {
+		tilt -= (plan.TiltRows - 1) * plan.TiltStepSize
+	}
+	return pan, tilt
+}
+
+// EstimateTimelapseDuration estimates how long RunTimelapse takes with p:
+// one interval per frame but the last, plus the last frame itself.
+func EstimateTimelapseDuration(p TimelapseParams) time.Duration {
+	if p.Frames <= 0 {
+		return 0
+	}
+	last := p.ShotDelay + GridShotParams{
+		PostShotDelay:  p.PostShotDelay,
+		ExposureTime:   p.ExposureTime,
+		ExposureMargin: p.ExposureMargin,
+	}.ShotHold()
+	if p.Ramp != nil {
+		last = p.ShotDelay + p.Ramp.End + max(p.PostShotDelay, p.ExposureMargin)
+	}
+	return time.Duration(p.Frames-1)*p.Interval + last
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
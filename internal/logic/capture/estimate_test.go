@@ -0,0 +1,69 @@
+package capture
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+)
+
+func TestEstimateGridDuration(t *testing.T) {
+	p := GridShotParams{
+		GridPlan: &geometry.GridPlan{
+			PanColumns: 3, TiltRows: 2,
+			PanStepSize: 100, TiltStepSize: 50,
+			StartPanSteps: -100, StartTiltSteps: 25,
+		},
+		Delay:         500 * time.Millisecond,
+		MoveSpeed:     time.Millisecond,
+		ShotDelay:     300 * time.Millisecond,
+		PostShotDelay: 200 * time.Millisecond,
+	}
+	// Steps: start 125 + 3 tilt moves of 50 + 2 pan moves of 100 = 475 → 475ms
+	// Delays: 5 moves × 500ms = 2.5s
+	// Shots: 6 × (300ms + 200ms) = 3s
+	want := 475*time.Millisecond + 2500*time.Millisecond + 3*time.Second
+	if got := EstimateGridDuration(p); got != want {
+		t.Errorf("EstimateGridDuration = %v, want %v", got, want)
+	}
+}
+
+func TestEstimateGridDuration_LongExposure(t *testing.T) {
+	plan := &geometry.GridPlan{PanColumns: 1, TiltRows: 1}
+	short := EstimateGridDuration(GridShotParams{GridPlan: plan, PostShotDelay: time.Second})
+	long := EstimateGridDuration(GridShotParams{GridPlan: plan, PostShotDelay: time.Second, ExposureTime: 30 * time.Second})
+	if short != time.Second || long != 30*time.Second {
+		t.Errorf("estimates = %v / %v, want 1s / 30s (hold covers the exposure)", short, long)
+	}
+}
+
+func TestEstimateGridDuration_NoPlan(t *testing.T) {
+	if d := EstimateGridDuration(GridShotParams{}); d != 0 {
+		t.Errorf("EstimateGridDuration without plan = %v, want 0", d)
+	}
+}
+
+func TestEstimatePanoramasDuration(t *testing.T) {
+	plan := &geometry.GridPlan{PanColumns: 3, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50, StartPanSteps: -100, StartTiltSteps: 25}
+	p := GridShotParams{MoveSpeed: time.Millisecond}
+	one := EstimatePanoramasDuration([]Panorama{{Plan: plan}}, p)
+	two := EstimatePanoramasDuration([]Panorama{{Plan: plan}, {Plan: plan}}, p)
+	// The first grid ends at column 3, bottom row: pan +100, tilt -25 from the origin
+	if want := 2*one + 125*time.Millisecond; two != want {
+		t.Errorf("two panoramas = %v, want %v", two, want)
+	}
+}
+
+func TestEstimateTimelapseDuration(t *testing.T) {
+	p := TimelapseParams{Frames: 10, Interval: 5 * time.Second, ShotDelay: 300 * time.Millisecond, PostShotDelay: 200 * time.Millisecond}
+	if got, want := EstimateTimelapseDuration(p), 45*time.Second+500*time.Millisecond; got != want {
+		t.Errorf("EstimateTimelapseDuration = %v, want %v", got, want)
+	}
+	p.Ramp = &ExposureRamp{Start: time.Second, End: 4 * time.Second}
+	if got, want := EstimateTimelapseDuration(p), 45*time.Second+4500*time.Millisecond; got != want {
+		t.Errorf("with ramp = %v, want %v", got, want)
+	}
+	if d := EstimateTimelapseDuration(TimelapseParams{}); d != 0 {
+		t.Errorf("no frames = %v, want 0", d)
+	}
+}
@@ -0,0 +1,102 @@
+package capture
+
+import (
+	"math"
+	"time"
+)
+
+// Sources of an ETA.
+const (
+	ETAMeasured = "measured" // from the time taken by the shots done so far
+	ETAPlan     = "plan"     // from the plan estimate, before the first shot
+	ETANone     = "none"     // no capture running, or nothing to estimate from
+)
+
+// ETA is the estimated end of the running capture, served at GET /eta and
+// sent with each progress event.
+type ETA struct {
+	State          State      `json:"state"`
+	ShotsDone      int        `json:"shots_done"`
+	ShotsRemaining int        `json:"shots_remaining"`
+	PerShotSec     float64    `json:"per_shot_seconds,omitempty"` // measured mean time per shot, pauses excluded
+	RemainingSec   float64    `json:"remaining_seconds"`
+	CompletesAt    *time.Time `json:"completes_at,omitempty"`
+	Source         string     `json:"source"` // ETAMeasured, ETAPlan or ETANone
+}
+
+// runTiming is what the lifecycle measures of a run to estimate its end.
+type runTiming struct {
+	estimate      time.Duration // plan estimate, if set (SetEstimate)
+	shootingSince time.Time     // first entry in the shooting state
+	pausedSince   time.Time     // entry in the paused state, while paused
+	paused        time.Duration // total time spent paused, past pauses only
+	lastShot      time.Time
+	active        time.Duration // time shooting, pauses excluded, until lastShot
+}
+
+// shot records a shot completed at now.
+func (t *runTiming) shot(now time.Time) {
+	t.lastShot = now
+	if !t.shootingSince.IsZero() {
+		t.active = now.Sub(t.shootingSince) - t.paused
+	}
+}
+
+// pausedFor returns the time spent paused so far, including the current pause.
+func (t *runTiming) pausedFor(now time.Time) time.Duration {
+	if t.pausedSince.IsZero() {
+		return t.paused
+	}
+	return t.paused + now.Sub(t.pausedSince)
+}
+
+// SetEstimate records the plan estimate of the run's duration, used for
+// the ETA until the first shot is done. Call it after entering planning.
+func (l *Lifecycle) SetEstimate(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.timing.estimate = d
+}
+
+// ETA estimates when the running capture completes.
+func (l *Lifecycle) ETA() ETA {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.etaLocked(time.Now())
+}
+
+func (l *Lifecycle) etaLocked(now time.Time) ETA {
+	st, t := l.status, l.timing
+	eta := ETA{State: st.State, ShotsDone: st.ShotsDone, Source: ETANone}
+	switch st.State {
+	case StateIdle, StateError:
+		return eta
+	}
+	eta.ShotsRemaining = max(st.ShotsTotal-st.ShotsDone, 0)
+
+	var end time.Time
+	switch {
+	case st.ShotsDone > 0 && t.active > 0:
+		perShot := t.active / time.Duration(st.ShotsDone)
+		eta.PerShotSec = roundSec(perShot)
+		eta.Source = ETAMeasured
+		// From the last shot, pushed back by the current pause, if any
+		end = t.lastShot.Add(perShot*time.Duration(eta.ShotsRemaining) + t.pausedFor(now) - t.paused)
+	case t.estimate > 0 && st.StartedAt != nil:
+		eta.Source = ETAPlan
+		end = st.StartedAt.Add(t.estimate + t.pausedFor(now))
+	default:
+		return eta
+	}
+	if end.Before(now) {
+		end = now // running late
+	}
+	eta.CompletesAt = &end
+	eta.RemainingSec = roundSec(end.Sub(now))
+	return eta
+}
+
+// roundSec returns d in seconds, rounded to 0.1 s.
+func roundSec(d time.Duration) float64 {
+	return math.Round(d.Seconds()*10) / 10
+}
@@ -0,0 +1,96 @@
+package capture
+
+import (
+	"testing"
+	"time"
+)
+
+// shootingLifecycle returns a lifecycle shooting shot 4 of 10 planned
+// shots, the first 3 having taken 30 s of shooting and 10 s of pause.
+func shootingLifecycle(start time.Time) *Lifecycle {
+	l := NewLifecycle()
+	l.status = Status{State: StateShooting, StartedAt: &start, ShotsDone: 3, ShotsTotal: 10}
+	l.timing = runTiming{
+		estimate:      time.Minute,
+		shootingSince: start,
+		paused:        10 * time.Second,
+		lastShot:      start.Add(40 * time.Second),
+		active:        30 * time.Second,
+	}
+	return l
+}
+
+func TestLifecycle_ETA(t *testing.T) {
+	start := time.Date(2026, 6, 21, 21, 0, 0, 0, time.UTC)
+
+	l := shootingLifecycle(start)
+	eta := l.etaLocked(start.Add(45 * time.Second))
+	if eta.Source != ETAMeasured || eta.PerShotSec != 10 || eta.ShotsRemaining != 7 {
+		t.Fatalf("eta = %+v, want measured, 10 s per shot, 7 remaining", eta)
+	}
+	// 7 shots of 10 s after the last shot
+	if want := start.Add(110 * time.Second); eta.CompletesAt == nil || !eta.CompletesAt.Equal(want) || eta.RemainingSec != 65 {
+		t.Errorf("completes at %v in %vs, want %v in 65s", eta.CompletesAt, eta.RemainingSec, want)
+	}
+
+	// A pause pushes the end back by its duration
+	l.status.State = StatePaused
+	l.timing.pausedSince = start.Add(50 * time.Second)
+	eta = l.etaLocked(start.Add(80 * time.Second))
+	if want := start.Add(140 * time.Second); !eta.CompletesAt.Equal(want) {
+		t.Errorf("paused: completes at %v, want %v", eta.CompletesAt, want)
+	}
+
+	// Running late: the end is now
+	l = shootingLifecycle(start)
+	now := start.Add(time.Hour)
+	if eta := l.etaLocked(now); !eta.CompletesAt.Equal(now) || eta.RemainingSec != 0 {
+		t.Errorf("late: eta = %+v, want now", eta)
+	}
+}
+
+func TestLifecycle_ETABeforeFirstShot(t *testing.T) {
+	start := time.Date(2026, 6, 21, 21, 0, 0, 0, time.UTC)
+	l := shootingLifecycle(start)
+	l.status.ShotsDone = 0
+	l.timing.paused, l.timing.active = 0, 0
+
+	eta := l.etaLocked(start.Add(20 * time.Second))
+	if eta.Source != ETAPlan || !eta.CompletesAt.Equal(start.Add(time.Minute)) || eta.RemainingSec != 40 {
+		t.Errorf("eta = %+v, want the plan estimate", eta)
+	}
+
+	l.timing.estimate = 0
+	if eta := l.etaLocked(start); eta.Source != ETANone || eta.CompletesAt != nil {
+		t.Errorf("without estimate: eta = %+v, want none", eta)
+	}
+}
+
+func TestLifecycle_ETAIdle(t *testing.T) {
+	l := NewLifecycle()
+	if eta := l.ETA(); eta.Source != ETANone || eta.State != StateIdle || eta.CompletesAt != nil {
+		t.Errorf("eta = %+v, want none", eta)
+	}
+}
+
+func TestLifecycle_ETAMeasuresShots(t *testing.T) {
+	l := NewLifecycle()
+	l.Transition(StatePlanning)
+	l.SetShotsTotal(3)
+	l.SetEstimate(time.Hour)
+	l.Transition(StateShooting)
+	time.Sleep(20 * time.Millisecond)
+	l.ShotDone()
+
+	eta := l.ETA()
+	if eta.Source != ETAMeasured || eta.ShotsRemaining != 2 || eta.RemainingSec > 1 {
+		t.Errorf("eta = %+v, want measured from the first shot, not the 1 h estimate", eta)
+	}
+
+	// Planning a new run resets the measures
+	l.Transition(StateIdle)
+	l.Transition(StatePlanning)
+	if eta := l.ETA(); eta.Source != ETANone {
+		t.Errorf("new run: eta = %+v, want none", eta)
+	}
+}
@@ -0,0 +1,67 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+)
+
+// Panorama is one grid of a multi-panorama run.
+type Panorama struct {
+	Name string
+	Plan *geometry.GridPlan // start steps are relative to the run's origin
+}
+
+// RunPanoramas shoots several grids back-to-back. Each grid's start position
+// is relative to the origin (the position held when the run starts), so the
+// head returns to the origin before each panorama. Per-panorama progress is
+// announced on the notifier. p.GridPlan is ignored; the other parameters
+// apply to every panorama.
+func (s *Sequence) RunPanoramas(ctx context.Context, panoramas []Panorama, p GridShotParams) error {
+	originPan, originTilt := s.motion.Position()
+
+	err := s.runPanoramas(ctx, panoramas, p, originPan, originTilt)
+	if err != nil && p.ReturnHomeOnAbort {
+		if homeErr := s.returnHome(originPan, originTilt); homeErr != nil {
+			dbg.Error("return to start position", homeErr)
+			return fmt.Errorf("%w (return to start position failed: %v)", err, homeErr)
+		}
+	}
+	return err
+}
+
+func (s *Sequence) runPanoramas(ctx context.Context, panoramas []Panorama, p GridShotParams, originPan, originTilt int) error {
+	for i, pano := range panoramas {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		label := fmt.Sprintf("Panorama %d/%d", i+1, len(panoramas))
+		if pano.Name != "" {
+			label += " (" + pano.Name + ")"
+		}
+		total := pano.Plan.PanColumns * pano.Plan.TiltRows
+		s.announce("info", fmt.Sprintf("%s: %d columns x %d rows = %d photos", label, pano.Plan.PanColumns, pano.Plan.TiltRows, total))
+
+		if i > 0 {
+			if _, err := s.waitIfPaused(ctx); err != nil {
+				return err
+			}
+			s.setState(StateHoming)
+			dbg.Live("returning to origin before next panorama", "panorama", i+2)
+			_ = s.motion.EnableMotors()
+			if err := s.motion.MoveTo(originPan, originTilt); err != nil {
+				return fmt.Errorf("%s: return to origin: %w", label, err)
+			}
+			s.emitMove()
+		}
+
+		gp := p
+		gp.GridPlan = pano.Plan
+		if err := s.runGrid(ctx, gp); err != nil {
+			return fmt.Errorf("%s: %w", label, err)
+		}
+		s.announce("info", label+" complete")
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package capture
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+)
+
+func TestRunPanoramas_ShotsAndProgress(t *testing.T) {
+	ctrl := newTestController()
+	cam := &mockCamera{}
+	rec := &recordingNotifier{}
+	seq := NewSequence(ctrl, cam)
+	seq.SetNotifier(rec.notify)
+
+	panoramas := []Panorama{
+		{Name: "left", Plan: &geometry.GridPlan{
+			PanColumns: 2, TiltRows: 2, PanStepSize: 10, TiltStepSize: 10,
+			StartPanSteps: -100, StartTiltSteps: 20,
+		}},
+		{Name: "right", Plan: &geometry.GridPlan{
+			PanColumns: 3, TiltRows: 1, PanStepSize: 10, TiltStepSize: 10,
+			StartPanSteps: 50, StartTiltSteps: 0,
+		}},
+	}
+
+	err := seq.RunPanoramas(context.Background(), panoramas, GridShotParams{
+		Delay:         time.Microsecond,
+		ShotDelay:     time.Microsecond,
+		PostShotDelay: time.Microsecond,
+	})
+	if err != nil {
+		t.Fatalf("RunPanoramas: %v", err)
+	}
+	if cam.shotCount() != 7 {
+		t.Errorf("shots = %d, want 7 (2x2 + 3x1)", cam.shotCount())
+	}
+	// Second panorama starts from the origin: 50 + 2 pan moves of 10.
+	if pan, tilt := ctrl.Position(); pan != 70 || tilt != 0 {
+		t.Errorf("final position = (%d, %d), want (70, 0)", pan, tilt)
+	}
+
+	msgs := strings.Join(rec.all(), "\n")
+	for _, want := range []string{"Panorama 1/2 (left)", "Panorama 2/2 (right): 3 columns x 1 rows", "Panorama 2/2 (right) complete"} {
+		if !strings.Contains(msgs, want) {
+			t.Errorf("announcements missing %q:\n%s", want, msgs)
+		}
+	}
+}
+
+func TestRunPanoramas_ReturnHomeOnError(t *testing.T) {
+	ctrl := newTestController()
+	cam := &failingCamera{failOn: 3}
+	seq := NewSequence(ctrl, cam)
+
+	panoramas := []Panorama{
+		{Plan: &geometry.GridPlan{PanColumns: 1, TiltRows: 2, TiltStepSize: 10, StartPanSteps: -40}},
+		{Plan: &geometry.GridPlan{PanColumns: 2, TiltRows: 1, PanStepSize: 10, StartPanSteps: 40}},
+	}
+	err := seq.RunPanoramas(context.Background(), panoramas, GridShotParams{
+		Delay:             time.Microsecond,
+		ReturnHomeOnAbort: true,
+	})
+	if err == nil || !strings.Contains(err.Error(), "Panorama 2/2") {
+		t.Fatalf("err = %v, want failure in panorama 2", err)
+	}
+	if pan, tilt := ctrl.Position(); pan != 0 || tilt != 0 {
+		t.Errorf("position after abort = (%d, %d), want (0, 0)", pan, tilt)
+	}
+}
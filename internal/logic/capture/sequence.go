@@ -2,21 +2,40 @@ package capture
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/cjeanneret/PanGo/internal/debug"
 	"github.com/cjeanneret/PanGo/internal/hw/camera"
 	"github.com/cjeanneret/PanGo/internal/logic/geometry"
 	"github.com/cjeanneret/PanGo/internal/logic/motion"
+	"github.com/cjeanneret/PanGo/internal/session"
+	"github.com/cjeanneret/PanGo/internal/tracing"
 )
 
+// dbg is the debug output of the package.
+var dbg = debug.For("capture")
+
 // Sequence contains high-level logic for photo capture
 // (grids, timelapse, panoramas, etc.).
 type Sequence struct {
-	motion *motion.Controller
-	camera camera.Camera
+	motion    *motion.Controller
+	camera    camera.Camera
+	notify    Notifier
+	lifecycle *Lifecycle
+	recorder  *session.Recorder
+	events    EventFunc
+	images    ImageFunc
 }
 
+// ImageFunc receives the picture of a recorded shot (1-based index in the
+// session), downloaded from the camera as a JPEG image.
+type ImageFunc func(index int, image []byte)
+
+// Notifier receives operator-facing announcements (e.g. "cap the lens now").
+// Level is one of "info", "warning", "error".
+type Notifier func(level, msg string)
+
 func NewSequence(m *motion.Controller, c camera.Camera) *Sequence {
 	return &Sequence{
 		motion: m,
@@ -24,6 +43,147 @@ func NewSequence(m *motion.Controller, c camera.Camera) *Sequence {
 	}
 }
 
+// SetNotifier sets where operator announcements are sent.
+// Without a notifier, announcements only go to the debug log.
+func (s *Sequence) SetNotifier(n Notifier) {
+	s.notify = n
+}
+
+// SetLifecycle sets the state machine updated as the sequence progresses
+// (homing, shooting, paused, current cell). Optional.
+func (s *Sequence) SetLifecycle(l *Lifecycle) {
+	s.lifecycle = l
+}
+
+// SetRecorder sets where per-shot records and timings are collected. Optional.
+func (s *Sequence) SetRecorder(r *session.Recorder) {
+	s.recorder = r
+}
+
+// SetEvents sets where shot and move events are sent. Optional.
+func (s *Sequence) SetEvents(fn EventFunc) {
+	s.events = fn
+}
+
+// SetImages downloads each successful shot from the camera, when it
+// supports it (camera.DownloadCamera), and passes it to fn. Requires a
+// recorder, which numbers the shots. Optional.
+func (s *Sequence) SetImages(fn ImageFunc) {
+	s.images = fn
+}
+
+// recordShot adds a shot to the recorder, if any, at the current head
+// position, and emits it as a shot event. The picture is then downloaded
+// when SetImages is used.
+func (s *Sequence) recordShot(ctx context.Context, shot session.Shot) {
+	shot.PanSteps, shot.TiltSteps = s.motion.Position()
+	if s.recorder != nil {
+		shot.Index = s.recorder.AddShot(shot)
+	}
+	if s.events != nil {
+		s.events(EventShot, shot)
+	}
+	if shot.Error == "" && shot.Index > 0 && s.images != nil {
+		s.downloadImage(ctx, shot.Index)
+	}
+}
+
+// downloadImage passes the last picture to the image function. A failed
+// download is reported but does not stop the capture.
+func (s *Sequence) downloadImage(ctx context.Context, index int) {
+	dc, ok := s.camera.(camera.DownloadCamera)
+	if !ok {
+		return
+	}
+	image, err := dc.DownloadLast(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.announce("warning", fmt.Sprintf("Could not download shot %d: %v", index, err))
+		}
+		return
+	}
+	s.images(index, image)
+}
+
+// emitMove sends the head position after a movement as a move event.
+func (s *Sequence) emitMove() {
+	if s.events == nil {
+		return
+	}
+	pan, tilt := s.motion.Position()
+	s.events(EventMove, Position{PanSteps: pan, TiltSteps: tilt})
+}
+
+// shotTimer measures the move and settle phases leading to a shot.
+type shotTimer struct {
+	move    time.Duration // duration of the last move
+	moveEnd time.Time     // end of the last move; settle time starts here
+}
+
+// moved records a move that started at start and just ended.
+func (t *shotTimer) moved(start time.Time) {
+	t.moveEnd = time.Now()
+	t.move = t.moveEnd.Sub(start)
+}
+
+// shoot releases the shutter with release (e.g. camera.Shoot) and returns
+// the shot record with its timings.
+func (s *Sequence) shoot(t *shotTimer, release func() error) (session.Shot, error) {
+	start := time.Now()
+	err := release()
+	shot := session.Shot{
+		Time:    start,
+		Move:    session.Duration(t.move),
+		Settle:  session.Duration(start.Sub(t.moveEnd)),
+		Shutter: session.Duration(time.Since(start)),
+	}
+	if err != nil {
+		shot.Error = err.Error()
+	}
+	*t = shotTimer{moveEnd: time.Now()}
+	return shot, err
+}
+
+// setState moves the lifecycle, if any, to st. Invalid transitions are
+// logged and ignored: they never abort a capture.
+func (s *Sequence) setState(st State) {
+	if s.lifecycle == nil {
+		return
+	}
+	if err := s.lifecycle.Transition(st); err != nil {
+		dbg.Verbose("state transition", "err", err)
+	}
+}
+
+// waitIfPaused blocks while the lifecycle is paused. It reports whether
+// the sequence was paused.
+func (s *Sequence) waitIfPaused(ctx context.Context) (bool, error) {
+	if s.lifecycle == nil || s.lifecycle.State() != StatePaused {
+		return false, nil
+	}
+	dbg.Live("paused")
+	if err := s.lifecycle.WaitIfPaused(ctx); err != nil {
+		return true, err
+	}
+	dbg.Live("resumed")
+	return true, nil
+}
+
+// announce sends msg to the notifier, or to the debug log if none is set.
+func (s *Sequence) announce(level, msg string) {
+	if s.notify != nil {
+		s.notify(level, msg)
+		return
+	}
+	dbg.Info(msg)
+}
+
+// Trigger blocks until an external signal allows the sequence to go on
+// (see hw/trigger). Wait must return ctx.Err() when ctx is done.
+type Trigger interface {
+	Wait(ctx context.Context) error
+}
+
 // GridShotParams defines the parameters for a grid traversal.
 type GridShotParams struct {
 	GridPlan *geometry.GridPlan // calculated grid plan
@@ -32,17 +192,43 @@ type GridShotParams struct {
 	MoveSpeed     time.Duration // reserved for future improvements (ramping, etc.)
 	ShotDelay     time.Duration // delay before shot (stabilization)
 	PostShotDelay time.Duration // delay after shot before movement
+
+	ExposureTime   time.Duration // exposure set on the camera; 0 = short exposure
+	ExposureMargin time.Duration // extra hold after ExposureTime before moving
+
+	ReturnHomeOnAbort bool // on cancel/error, drive back to the position held when the sequence started
+	RetryFailedShots  bool // on a shoot error, continue the grid and reshoot failed cells at the end
+
+	Trigger Trigger // optional: wait for an external pulse before advancing to the next cell
+
+	// BeforeShot, when set, is called once the head is in place at cell,
+	// right before the shutter, e.g. to release the shutters of several
+	// rigs together. An error stops the sequence. Retried cells do not call it.
+	BeforeShot func(ctx context.Context, cell Cell) error
+}
+
+// ShotHold returns how long the rig must stay still (motors disabled) after
+// the shutter is released: the post-shot delay, or the full exposure plus
+// margin when that is longer.
+func (p GridShotParams) ShotHold() time.Duration {
+	hold := p.PostShotDelay
+	if p.ExposureTime > 0 {
+		if exp := p.ExposureTime + p.ExposureMargin; exp > hold {
+			hold = exp
+		}
+	}
+	return hold
 }
 
 // InitializePosition moves the head to the start position (far left, top).
 func (s *Sequence) InitializePosition(plan *geometry.GridPlan) error {
-	debug.Section("Initializing Position")
-	debug.Live("Moving to start position (left, top)")
+	dbg.Section("initializing position")
+	dbg.Live("moving to start position (left, top)")
 
 	// Go to start position from center (assuming we start from center)
 	// First go left (negative pan)
 	if plan.StartPanSteps != 0 {
-		debug.Verbose("Moving pan: %d steps (to left)", plan.StartPanSteps)
+		dbg.Verbose("moving to start", "axis", "pan", "steps", plan.StartPanSteps)
 		if err := s.motion.MovePan(plan.StartPanSteps); err != nil {
 			return err
 		}
@@ -50,13 +236,13 @@ func (s *Sequence) InitializePosition(plan *geometry.GridPlan) error {
 
 	// Then go up (positive tilt)
 	if plan.StartTiltSteps != 0 {
-		debug.Verbose("Moving tilt: %d steps (to top)", plan.StartTiltSteps)
+		dbg.Verbose("moving to start", "axis", "tilt", "steps", plan.StartTiltSteps)
 		if err := s.motion.MoveTilt(plan.StartTiltSteps); err != nil {
 			return err
 		}
 	}
 
-	debug.Live("Initialization complete")
+	dbg.Live("initialization complete")
 	return nil
 }
 
@@ -64,16 +250,87 @@ func (s *Sequence) InitializePosition(plan *geometry.GridPlan) error {
 // Column 0: top to bottom, then horizontal shift
 // Column 1: bottom to top, then horizontal shift
 // etc.
-func (s *Sequence) RunGridShot(ctx context.Context, p GridShotParams) error {
+//
+// If p.ReturnHomeOnAbort is set and the traversal is cancelled or fails,
+// the head is driven back to where it was when the sequence started
+// (motors enabled) before the error is returned.
+func (s *Sequence) RunGridShot(ctx context.Context, p GridShotParams) (err error) {
+	ctx, span := tracing.Start(ctx, "sequence", "columns", p.GridPlan.PanColumns, "rows", p.GridPlan.TiltRows)
+	defer func() { span.End(err) }()
+	homePan, homeTilt := s.motion.Position()
+
+	err = s.runGrid(ctx, p)
+	if err != nil && p.ReturnHomeOnAbort {
+		if homeErr := traced(ctx, "return_home", func() error { return s.returnHome(homePan, homeTilt) }); homeErr != nil {
+			dbg.Error("return to start position", homeErr)
+			return fmt.Errorf("%w (return to start position failed: %v)", err, homeErr)
+		}
+	}
+	return err
+}
+
+// traced runs fn in a span named name, child of the span of ctx, with the
+// key-value pairs of attrs as attributes.
+func traced(ctx context.Context, name string, fn func() error, attrs ...any) error {
+	_, span := tracing.Start(ctx, name, attrs...)
+	err := fn()
+	span.End(err)
+	return err
+}
+
+// returnHome re-enables the motors and moves back to the given tracked position.
+func (s *Sequence) returnHome(pan, tilt int) error {
+	curPan, curTilt := s.motion.Position()
+	dbg.Live("aborted: returning to start position", "pan_steps", pan-curPan, "tilt_steps", tilt-curTilt)
+	s.setState(StateHoming)
+	if err := s.motion.EnableMotors(); err != nil {
+		return err
+	}
+	err := s.motion.MoveTo(pan, tilt)
+	s.emitMove()
+	if err != nil {
+		return err
+	}
+	dbg.Live("returned to start position")
+	return nil
+}
+
+// failedCell is a grid cell whose shot failed, kept for the end-of-grid retry.
+type failedCell struct {
+	column, row int // 1-based, row 1 = top
+	pan, tilt   int // absolute head position (steps)
+	err         error
+}
+
+func (s *Sequence) runGrid(ctx context.Context, p GridShotParams) (err error) {
 	plan := p.GridPlan
+	var failed []failedCell
+	// Spans of the current column and cell, ended with the error of the
+	// traversal when it stops in them.
+	var colSpan, cellSpan *tracing.Span
+	defer func() {
+		cellSpan.End(err)
+		colSpan.End(err)
+	}()
 
 	// Ensure motors are enabled before any movement
 	_ = s.motion.EnableMotors()
 
+	if s.lifecycle != nil {
+		s.lifecycle.SetGrid(plan.PanColumns, plan.TiltRows)
+	}
+
 	// Initialize: go to start position (left, top)
-	if err := s.InitializePosition(plan); err != nil {
+	s.setState(StateHoming)
+	var timer shotTimer
+	moveStart := time.Now()
+	err = traced(ctx, "initialize", func() error { return s.InitializePosition(plan) })
+	s.emitMove()
+	if err != nil {
 		return err
 	}
+	timer.moved(moveStart)
+	s.setState(StateShooting)
 
 	// Column traversal (serpentine)
 	for col := 0; col < plan.PanColumns; col++ {
@@ -89,7 +346,9 @@ func (s *Sequence) RunGridShot(ctx context.Context, p GridShotParams) error {
 		if goingDown {
 			direction = "down"
 		}
-		debug.Column(col+1, plan.PanColumns, direction)
+		dbg.Column(col+1, plan.PanColumns, direction)
+		var colCtx context.Context
+		colCtx, colSpan = tracing.Start(ctx, "column", "column", col+1, "direction", direction)
 
 		// Traverse column vertically
 		for row := 0; row < plan.TiltRows; row++ {
@@ -99,49 +358,174 @@ func (s *Sequence) RunGridShot(ctx context.Context, p GridShotParams) error {
 			default:
 			}
 
+			// Pause only at cell boundaries, never mid-move or mid-exposure
+			if _, err := s.waitIfPaused(ctx); err != nil {
+				return err
+			}
+
+			// Report the physical row (1 = top), whatever the traversal direction
+			physRow := row + 1
+			if !goingDown {
+				physRow = plan.TiltRows - row
+			}
+			var cellCtx context.Context
+			cellCtx, cellSpan = tracing.Start(colCtx, "cell", "column", col+1, "row", physRow)
+
 			// If not the first photo in the column, move vertically
 			if row > 0 {
+				moveStart := time.Now()
 				// Vertical movement: always in the same direction based on column
 				if goingDown {
 					// Go down (negative tilt)
-					debug.Move("tilt", plan.TiltStepSize, "down")
-					if err := s.motion.MoveTilt(-plan.TiltStepSize); err != nil {
+					dbg.Move("tilt", plan.TiltStepSize, "down")
+					if err := traced(cellCtx, "move", func() error { return s.motion.MoveTilt(-plan.TiltStepSize) }, "axis", "tilt", "steps", -plan.TiltStepSize); err != nil {
 						return err
 					}
 				} else {
 					// Go up (positive tilt)
-					debug.Move("tilt", plan.TiltStepSize, "up")
-					if err := s.motion.MoveTilt(plan.TiltStepSize); err != nil {
+					dbg.Move("tilt", plan.TiltStepSize, "up")
+					if err := traced(cellCtx, "move", func() error { return s.motion.MoveTilt(plan.TiltStepSize) }, "axis", "tilt", "steps", plan.TiltStepSize); err != nil {
 						return err
 					}
 				}
+				timer.moved(moveStart)
+				s.emitMove()
 				time.Sleep(p.Delay)
 			} else {
-				debug.Verbose("  Row %d/%d: at start position", row+1, plan.TiltRows)
+				dbg.Verbose("row at start position", "row", row+1, "rows", plan.TiltRows)
+			}
+
+			if s.lifecycle != nil {
+				s.lifecycle.SetCell(col+1, physRow)
 			}
 
 			// Disable motors during capture (reduces vibration, no holding torque)
 			_ = s.motion.DisableMotors()
 			time.Sleep(p.ShotDelay)
-			if err := s.camera.Shoot(); err != nil {
+			if p.BeforeShot != nil {
+				if err := p.BeforeShot(ctx, Cell{Column: col + 1, Row: physRow}); err != nil {
+					_ = s.motion.EnableMotors()
+					return err
+				}
+			}
+			_, shootSpan := tracing.Start(cellCtx, "shoot")
+			shot, err := s.shoot(&timer, s.camera.Shoot)
+			shootSpan.End(err)
+			shot.Column, shot.Row = col+1, physRow
+			s.recordShot(ctx, shot)
+			if err != nil && !p.RetryFailedShots {
 				_ = s.motion.EnableMotors()
 				return err
 			}
-			debug.Shot(col+1, row+1)
-			time.Sleep(p.PostShotDelay)
+			if err != nil {
+				pan, tilt := s.motion.Position()
+				failed = append(failed, failedCell{column: col + 1, row: physRow, pan: pan, tilt: tilt, err: err})
+				s.announce("warning", fmt.Sprintf("Shot failed at column %d, row %d (%v): will retry at the end", col+1, physRow, err))
+			} else {
+				dbg.Shot(col+1, row+1)
+				if s.lifecycle != nil {
+					s.lifecycle.ShotDone()
+				}
+			}
+			hold := p.ShotHold()
+			if hold > p.PostShotDelay {
+				dbg.Verbose("holding still for exposure", "hold", hold)
+			}
+			time.Sleep(hold)
+			if p.Trigger != nil && !(col == plan.PanColumns-1 && row == plan.TiltRows-1) {
+				// Motors stay disabled while waiting: the wait can be long.
+				dbg.Live("waiting for external trigger")
+				if err := p.Trigger.Wait(ctx); err != nil {
+					_ = s.motion.EnableMotors()
+					return fmt.Errorf("external trigger: %w", err)
+				}
+			}
 			// Re-enable motors for next movement
 			_ = s.motion.EnableMotors()
+			cellSpan.End(nil)
 		}
 
 		// Horizontal shift to the right (except for the last column)
 		if col < plan.PanColumns-1 {
-			debug.Move("pan", plan.PanStepSize, "right")
-			if err := s.motion.MovePan(plan.PanStepSize); err != nil {
+			dbg.Move("pan", plan.PanStepSize, "right")
+			moveStart := time.Now()
+			if err := traced(colCtx, "move", func() error { return s.motion.MovePan(plan.PanStepSize) }, "axis", "pan", "steps", plan.PanStepSize); err != nil {
 				return err
 			}
+			timer.moved(moveStart)
+			s.emitMove()
 			time.Sleep(p.Delay)
 		}
+		colSpan.End(nil)
+	}
+
+	if len(failed) > 0 {
+		return s.retryCells(ctx, p, failed)
+	}
+	return nil
+}
+
+// retryCells revisits each failed cell once and reshoots it. It returns an
+// error if any cell still fails.
+func (s *Sequence) retryCells(ctx context.Context, p GridShotParams, cells []failedCell) (err error) {
+	ctx, span := tracing.Start(ctx, "retry", "cells", len(cells))
+	var cellSpan *tracing.Span
+	defer func() {
+		cellSpan.End(err)
+		span.End(err)
+	}()
+	s.announce("info", fmt.Sprintf("Retrying %d failed shot(s)", len(cells)))
+	var timer shotTimer
+	var stillFailed []failedCell
+	for _, c := range cells {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.waitIfPaused(ctx); err != nil {
+			return err
+		}
+
+		dbg.Live("retry", debug.Cell(c.column, c.row))
+		var cellCtx context.Context
+		cellCtx, cellSpan = tracing.Start(ctx, "cell", "column", c.column, "row", c.row)
+		_ = s.motion.EnableMotors()
+		moveStart := time.Now()
+		if err := traced(cellCtx, "move", func() error { return s.motion.MoveTo(c.pan, c.tilt) }, "pan_steps", c.pan, "tilt_steps", c.tilt); err != nil {
+			return err
+		}
+		timer.moved(moveStart)
+		s.emitMove()
+		time.Sleep(p.Delay)
+		if s.lifecycle != nil {
+			s.lifecycle.SetCell(c.column, c.row)
+		}
+
+		_ = s.motion.DisableMotors()
+		time.Sleep(p.ShotDelay)
+		_, shootSpan := tracing.Start(cellCtx, "shoot")
+		shot, err := s.shoot(&timer, s.camera.Shoot)
+		shootSpan.End(err)
+		shot.Column, shot.Row = c.column, c.row
+		s.recordShot(ctx, shot)
+		if err != nil {
+			c.err = err
+			stillFailed = append(stillFailed, c)
+			dbg.Error("retry", err, debug.Cell(c.column, c.row))
+		} else {
+			dbg.Shot(c.column, c.row)
+			if s.lifecycle != nil {
+				s.lifecycle.ShotDone()
+			}
+		}
+		time.Sleep(p.ShotHold())
+		_ = s.motion.EnableMotors()
+		cellSpan.End(nil)
 	}
 
+	if len(stillFailed) > 0 {
+		c := stillFailed[0]
+		return fmt.Errorf("%d shot(s) failed after retry, first at column %d, row %d: %w", len(stillFailed), c.column, c.row, c.err)
+	}
+	s.announce("info", "All failed shots retaken")
 	return nil
 }
@@ -2,6 +2,12 @@ package capture
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -10,6 +16,8 @@ import (
 	"github.com/cjeanneret/PanGo/internal/hw/stepper"
 	"github.com/cjeanneret/PanGo/internal/logic/geometry"
 	"github.com/cjeanneret/PanGo/internal/logic/motion"
+	"github.com/cjeanneret/PanGo/internal/session"
+	"github.com/cjeanneret/PanGo/internal/tracing"
 )
 
 // mockCamera records Shoot calls.
@@ -262,3 +270,588 @@ func TestRunGridShot_LargeGrid(t *testing.T) {
 		t.Errorf("shots = %d, want 35 (5x7)", cam.shotCount())
 	}
 }
+
+// failingCamera fails on the n-th Shoot call (1-based).
+type failingCamera struct {
+	failOn int
+	shots  int
+}
+
+func (f *failingCamera) Shoot() error {
+	f.shots++
+	if f.shots == f.failOn {
+		return errors.New("shutter jammed")
+	}
+	return nil
+}
+
+func TestRunGridShot_ReturnHomeOnError(t *testing.T) {
+	ctrl := newTestController()
+	cam := &failingCamera{failOn: 3}
+	seq := NewSequence(ctrl, cam)
+
+	plan := &geometry.GridPlan{
+		PanColumns:     3,
+		TiltRows:       2,
+		PanStepSize:    100,
+		TiltStepSize:   50,
+		StartPanSteps:  -150,
+		StartTiltSteps: 25,
+	}
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:          plan,
+		Delay:             1 * time.Microsecond,
+		ShotDelay:         1 * time.Microsecond,
+		PostShotDelay:     1 * time.Microsecond,
+		ReturnHomeOnAbort: true,
+	})
+	if err == nil {
+		t.Fatal("expected shoot error, got nil")
+	}
+	if pan, tilt := ctrl.Position(); pan != 0 || tilt != 0 {
+		t.Errorf("position after abort = (%d, %d), want (0, 0)", pan, tilt)
+	}
+}
+
+func TestRunGridShot_ReturnHomeOnCancel(t *testing.T) {
+	ctrl := newTestController()
+	// Start away from zero: home is wherever the sequence begins.
+	if err := ctrl.MovePanTilt(30, -20); err != nil {
+		t.Fatal(err)
+	}
+	cam := &mockCamera{}
+	seq := NewSequence(ctrl, cam)
+
+	plan := &geometry.GridPlan{
+		PanColumns:     10,
+		TiltRows:       10,
+		PanStepSize:    10,
+		TiltStepSize:   10,
+		StartPanSteps:  -50,
+		StartTiltSteps: 50,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	err := seq.RunGridShot(ctx, GridShotParams{
+		GridPlan:          plan,
+		Delay:             5 * time.Millisecond,
+		ShotDelay:         1 * time.Microsecond,
+		PostShotDelay:     1 * time.Microsecond,
+		ReturnHomeOnAbort: true,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if pan, tilt := ctrl.Position(); pan != 30 || tilt != -20 {
+		t.Errorf("position after cancel = (%d, %d), want (30, -20)", pan, tilt)
+	}
+}
+
+func TestRunGridShot_NoReturnHomeByDefault(t *testing.T) {
+	ctrl := newTestController()
+	cam := &failingCamera{failOn: 2}
+	seq := NewSequence(ctrl, cam)
+
+	plan := &geometry.GridPlan{
+		PanColumns:     2,
+		TiltRows:       2,
+		PanStepSize:    100,
+		TiltStepSize:   50,
+		StartPanSteps:  -100,
+		StartTiltSteps: 25,
+	}
+
+	if err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      plan,
+		Delay:         1 * time.Microsecond,
+		ShotDelay:     1 * time.Microsecond,
+		PostShotDelay: 1 * time.Microsecond,
+	}); err == nil {
+		t.Fatal("expected shoot error, got nil")
+	}
+	// Failed on the second shot: start position plus one tilt move down.
+	if pan, tilt := ctrl.Position(); pan != -100 || tilt != -25 {
+		t.Errorf("position = (%d, %d), want (-100, -25)", pan, tilt)
+	}
+}
+
+// countingTrigger counts Wait calls and returns err from each of them.
+type countingTrigger struct {
+	waits int
+	err   error
+}
+
+func (c *countingTrigger) Wait(ctx context.Context) error {
+	c.waits++
+	return c.err
+}
+
+func TestRunGridShot_WaitsForTriggerBetweenCells(t *testing.T) {
+	ctrl := newTestController()
+	cam := &mockCamera{}
+	seq := NewSequence(ctrl, cam)
+	trig := &countingTrigger{}
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      &geometry.GridPlan{PanColumns: 2, TiltRows: 3, PanStepSize: 100, TiltStepSize: 50},
+		Delay:         1 * time.Microsecond,
+		ShotDelay:     1 * time.Microsecond,
+		PostShotDelay: 1 * time.Microsecond,
+		Trigger:       trig,
+	})
+	if err != nil {
+		t.Fatalf("RunGridShot: %v", err)
+	}
+	// One wait before each move to the next cell: none after the last shot.
+	if trig.waits != 5 {
+		t.Errorf("trigger waits = %d, want 5 (6 cells - 1)", trig.waits)
+	}
+}
+
+func TestRunGridShot_TriggerErrorAborts(t *testing.T) {
+	ctrl := newTestController()
+	cam := &mockCamera{}
+	seq := NewSequence(ctrl, cam)
+	timeout := errors.New("timed out")
+	trig := &countingTrigger{err: timeout}
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      &geometry.GridPlan{PanColumns: 2, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:         1 * time.Microsecond,
+		ShotDelay:     1 * time.Microsecond,
+		PostShotDelay: 1 * time.Microsecond,
+		Trigger:       trig,
+	})
+	if !errors.Is(err, timeout) {
+		t.Fatalf("err = %v, want wrapped trigger error", err)
+	}
+	if cam.shotCount() != 1 {
+		t.Errorf("shots = %d, want 1 (abort after first cell)", cam.shotCount())
+	}
+}
+
+func TestRunGridShot_BeforeShot(t *testing.T) {
+	ctrl := newTestController()
+	cam := &mockCamera{}
+	seq := NewSequence(ctrl, cam)
+	var cells []Cell
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      &geometry.GridPlan{PanColumns: 2, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:         1 * time.Microsecond,
+		ShotDelay:     1 * time.Microsecond,
+		PostShotDelay: 1 * time.Microsecond,
+		BeforeShot: func(_ context.Context, cell Cell) error {
+			if len(cells) != cam.shotCount() {
+				t.Errorf("cell %+v: called after the shot", cell)
+			}
+			cells = append(cells, cell)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunGridShot: %v", err)
+	}
+	// Serpentine: down the first column, up the second
+	want := []Cell{{1, 1}, {1, 2}, {2, 2}, {2, 1}}
+	if !slices.Equal(cells, want) {
+		t.Errorf("cells = %v, want %v", cells, want)
+	}
+}
+
+func TestRunGridShot_BeforeShotErrorAborts(t *testing.T) {
+	ctrl := newTestController()
+	cam := &mockCamera{}
+	seq := NewSequence(ctrl, cam)
+	stop := errors.New("follower lost")
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      &geometry.GridPlan{PanColumns: 2, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:         1 * time.Microsecond,
+		ShotDelay:     1 * time.Microsecond,
+		PostShotDelay: 1 * time.Microsecond,
+		BeforeShot: func(context.Context, Cell) error {
+			if cam.shotCount() == 1 {
+				return stop
+			}
+			return nil
+		},
+	})
+	if !errors.Is(err, stop) {
+		t.Fatalf("err = %v, want the BeforeShot error", err)
+	}
+	if cam.shotCount() != 1 {
+		t.Errorf("shots = %d, want 1", cam.shotCount())
+	}
+}
+
+func TestGridShotParams_ShotHold(t *testing.T) {
+	cases := []struct {
+		name string
+		p    GridShotParams
+		want time.Duration
+	}{
+		{"post_shot_only", GridShotParams{PostShotDelay: 300 * time.Millisecond}, 300 * time.Millisecond},
+		{"exposure_longer", GridShotParams{
+			PostShotDelay:  300 * time.Millisecond,
+			ExposureTime:   2 * time.Second,
+			ExposureMargin: 250 * time.Millisecond,
+		}, 2250 * time.Millisecond},
+		{"exposure_shorter", GridShotParams{
+			PostShotDelay:  time.Second,
+			ExposureTime:   100 * time.Millisecond,
+			ExposureMargin: 250 * time.Millisecond,
+		}, time.Second},
+		{"margin_ignored_without_exposure", GridShotParams{
+			PostShotDelay:  300 * time.Millisecond,
+			ExposureMargin: 5 * time.Second,
+		}, 300 * time.Millisecond},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.p.ShotHold(); got != tc.want {
+				t.Errorf("ShotHold() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// ---------- Session recording ----------
+
+func TestRunGridShot_RecordsShots(t *testing.T) {
+	ctrl := newTestController()
+	seq := NewSequence(ctrl, &mockCamera{})
+	rec := session.NewRecorder("grid", session.Params{})
+	seq.SetRecorder(rec)
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      &geometry.GridPlan{PanColumns: 3, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:         time.Microsecond,
+		ShotDelay:     time.Microsecond,
+		PostShotDelay: time.Microsecond,
+	})
+	if err != nil {
+		t.Fatalf("RunGridShot: %v", err)
+	}
+	shots := rec.Snapshot().Shots
+	if len(shots) != 6 {
+		t.Fatalf("recorded %d shots, want 6", len(shots))
+	}
+	for i, s := range shots {
+		if s.Index != i+1 {
+			t.Errorf("shot %d: index = %d", i, s.Index)
+		}
+		if s.Column < 1 || s.Column > 3 || s.Row < 1 || s.Row > 2 {
+			t.Errorf("shot %d: cell = %d/%d, out of the 3x2 grid", i, s.Column, s.Row)
+		}
+		if s.Move < 0 || s.Settle < 0 || s.Shutter < 0 {
+			t.Errorf("shot %d: negative timing %+v", i, s)
+		}
+		if s.Time.IsZero() {
+			t.Errorf("shot %d: shutter time not set", i)
+		}
+	}
+}
+
+func TestRunGridShot_RecordsFailedShot(t *testing.T) {
+	ctrl := newTestController()
+	seq := NewSequence(ctrl, &failingCamera{failOn: 2})
+	rec := session.NewRecorder("grid", session.Params{})
+	seq.SetRecorder(rec)
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      &geometry.GridPlan{PanColumns: 3, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:         time.Microsecond,
+		ShotDelay:     time.Microsecond,
+		PostShotDelay: time.Microsecond,
+	})
+	if err == nil {
+		t.Fatal("expected error from failing camera")
+	}
+	shots := rec.Snapshot().Shots
+	if len(shots) != 2 {
+		t.Fatalf("recorded %d shots, want 2", len(shots))
+	}
+	if shots[0].Error != "" || shots[1].Error == "" {
+		t.Errorf("errors = %q / %q, want only the second shot failed", shots[0].Error, shots[1].Error)
+	}
+}
+
+// ---------- Image download ----------
+
+// downloadCamera is a camera whose pictures can be downloaded; it fails on
+// the listed downloads (1-based).
+type downloadCamera struct {
+	mockCamera
+	failOn map[int]bool
+	calls  int
+}
+
+func (d *downloadCamera) DownloadLast(ctx context.Context) ([]byte, error) {
+	d.calls++
+	if d.failOn[d.calls] {
+		return nil, errors.New("usb timeout")
+	}
+	return []byte{byte(d.calls)}, nil
+}
+
+func TestRunGridShot_DownloadsImages(t *testing.T) {
+	cam := &downloadCamera{failOn: map[int]bool{2: true}}
+	seq := NewSequence(newTestController(), cam)
+	notes := &recordingNotifier{}
+	seq.SetNotifier(notes.notify)
+	seq.SetRecorder(session.NewRecorder("grid", session.Params{}))
+	var indexes []int
+	seq.SetImages(func(index int, image []byte) {
+		if len(image) != 1 || int(image[0]) != index {
+			t.Errorf("shot %d: image = %v", index, image)
+		}
+		indexes = append(indexes, index)
+	})
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      &geometry.GridPlan{PanColumns: 2, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:         time.Microsecond,
+		ShotDelay:     time.Microsecond,
+		PostShotDelay: time.Microsecond,
+	})
+	if err != nil {
+		t.Fatalf("RunGridShot: %v", err) // a failed download does not stop the run
+	}
+	if want := []int{1, 3, 4}; !slices.Equal(indexes, want) {
+		t.Errorf("images = %v, want %v", indexes, want)
+	}
+	warned := false
+	for _, m := range notes.all() {
+		warned = warned || strings.HasPrefix(m, "warning: Could not download shot 2")
+	}
+	if !warned {
+		t.Errorf("announcements = %q, want a download warning for shot 2", notes.all())
+	}
+}
+
+func TestRunGridShot_NoDownloadWithoutSupport(t *testing.T) {
+	seq := NewSequence(newTestController(), &mockCamera{})
+	seq.SetRecorder(session.NewRecorder("grid", session.Params{}))
+	called := false
+	seq.SetImages(func(int, []byte) { called = true })
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan: &geometry.GridPlan{PanColumns: 2, TiltRows: 1, PanStepSize: 100, TiltStepSize: 50},
+		Delay:    time.Microsecond,
+	})
+	if err != nil {
+		t.Fatalf("RunGridShot: %v", err)
+	}
+	if called {
+		t.Error("image function called for a camera without downloads")
+	}
+}
+
+// ---------- Failed-shot retry ----------
+
+// flakyCamera fails on the listed Shoot calls (1-based).
+type flakyCamera struct {
+	failOn map[int]bool
+	shots  int
+}
+
+func (f *flakyCamera) Shoot() error {
+	f.shots++
+	if f.failOn[f.shots] {
+		return errors.New("card busy")
+	}
+	return nil
+}
+
+func TestRunGridShot_RetryFailedShots(t *testing.T) {
+	ctrl := newTestController()
+	cam := &flakyCamera{failOn: map[int]bool{2: true, 5: true}}
+	seq := NewSequence(ctrl, cam)
+	rec := session.NewRecorder("grid", session.Params{})
+	seq.SetRecorder(rec)
+	l := NewLifecycle()
+	seq.SetLifecycle(l)
+	l.Transition(StatePlanning)
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:         &geometry.GridPlan{PanColumns: 3, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:            time.Microsecond,
+		ShotDelay:        time.Microsecond,
+		PostShotDelay:    time.Microsecond,
+		RetryFailedShots: true,
+	})
+	if err != nil {
+		t.Fatalf("RunGridShot: %v", err)
+	}
+	// 6 cells + 2 retries
+	if cam.shots != 8 {
+		t.Errorf("shots = %d, want 8", cam.shots)
+	}
+	if done := l.Snapshot().ShotsDone; done != 6 {
+		t.Errorf("shots_done = %d, want 6", done)
+	}
+
+	shots := rec.Snapshot().Shots
+	if len(shots) != 8 {
+		t.Fatalf("recorded %d shots, want 8", len(shots))
+	}
+	// Retries revisit the failed cells, in order, at the same head position
+	for i, failedIdx := range []int{1, 4} {
+		orig, retry := shots[failedIdx], shots[6+i]
+		if orig.Error == "" || retry.Error != "" {
+			t.Errorf("retry %d: errors = %q / %q, want failed then ok", i, orig.Error, retry.Error)
+		}
+		if orig.Column != retry.Column || orig.Row != retry.Row {
+			t.Errorf("retry %d: cell %d/%d, want %d/%d", i, retry.Column, retry.Row, orig.Column, orig.Row)
+		}
+		if orig.PanSteps != retry.PanSteps || orig.TiltSteps != retry.TiltSteps {
+			t.Errorf("retry %d: position (%d, %d), want (%d, %d)", i, retry.PanSteps, retry.TiltSteps, orig.PanSteps, orig.TiltSteps)
+		}
+	}
+}
+
+func TestRunGridShot_RetryStillFailing(t *testing.T) {
+	ctrl := newTestController()
+	// Cell 3 fails, and so does its retry (7th shot)
+	cam := &flakyCamera{failOn: map[int]bool{3: true, 7: true}}
+	seq := NewSequence(ctrl, cam)
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:         &geometry.GridPlan{PanColumns: 3, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:            time.Microsecond,
+		ShotDelay:        time.Microsecond,
+		PostShotDelay:    time.Microsecond,
+		RetryFailedShots: true,
+	})
+	if err == nil {
+		t.Fatal("expected error when a retry fails")
+	}
+	if cam.shots != 7 {
+		t.Errorf("shots = %d, want 7", cam.shots)
+	}
+}
+
+func TestRunGridShot_NoRetryAbortsOnFailure(t *testing.T) {
+	ctrl := newTestController()
+	cam := &flakyCamera{failOn: map[int]bool{2: true}}
+	seq := NewSequence(ctrl, cam)
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      &geometry.GridPlan{PanColumns: 3, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:         time.Microsecond,
+		ShotDelay:     time.Microsecond,
+		PostShotDelay: time.Microsecond,
+	})
+	if err == nil {
+		t.Fatal("expected error without retry")
+	}
+	if cam.shots != 2 {
+		t.Errorf("shots = %d, want 2 (abort on first failure)", cam.shots)
+	}
+}
+
+// ---------- Tracing ----------
+
+// traceCollector stands in for an OTLP/HTTP collector and records the spans
+// it receives.
+type traceCollector struct {
+	mu    sync.Mutex
+	spans []tracedSpan
+}
+
+type tracedSpan struct {
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Status       struct {
+		Code int `json:"code"`
+	} `json:"status"`
+}
+
+func (c *traceCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ResourceSpans []struct {
+			ScopeSpans []struct {
+				Spans []tracedSpan `json:"spans"`
+			} `json:"scopeSpans"`
+		} `json:"resourceSpans"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, rs := range req.ResourceSpans {
+		for _, ss := range rs.ScopeSpans {
+			c.spans = append(c.spans, ss.Spans...)
+		}
+	}
+}
+
+func TestRunGridShot_Traced(t *testing.T) {
+	c := &traceCollector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+	exp, err := tracing.NewExporter(srv.URL, tracing.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracing.SetExporter(exp)
+	defer tracing.SetExporter(nil)
+
+	seq := NewSequence(newTestController(), &flakyCamera{failOn: map[int]bool{2: true}})
+	err = seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:         &geometry.GridPlan{PanColumns: 2, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:            time.Microsecond,
+		ShotDelay:        time.Microsecond,
+		PostShotDelay:    time.Microsecond,
+		RetryFailedShots: true,
+	})
+	if err != nil {
+		t.Fatalf("RunGridShot: %v", err)
+	}
+	if !exp.Shutdown(5 * time.Second) {
+		t.Fatal("exports still running")
+	}
+
+	byID := map[string]tracedSpan{}
+	count := map[string]int{}
+	failed := 0
+	for _, s := range c.spans {
+		byID[s.SpanID] = s
+		count[s.Name]++
+		if s.Status.Code != 0 {
+			failed++
+		}
+	}
+	// 4 cells and the retry of the failed one; 2 tilt moves, 1 pan move
+	// and the move back to the failed cell
+	want := map[string]int{"sequence": 1, "initialize": 1, "column": 2, "retry": 1, "cell": 5, "move": 4, "shoot": 5}
+	for name, n := range want {
+		if count[name] != n {
+			t.Errorf("%d %s span(s), want %d (spans %v)", count[name], name, n, count)
+		}
+	}
+	if failed != 1 {
+		t.Errorf("%d span(s) with an error status, want the failed shot only", failed)
+	}
+	parents := map[string][]string{
+		"initialize": {"sequence"},
+		"column":     {"sequence"},
+		"retry":      {"sequence"},
+		"cell":       {"column", "retry"},
+		"move":       {"column", "cell"},
+		"shoot":      {"cell"},
+	}
+	for _, s := range c.spans {
+		if s.Name == "sequence" {
+			continue
+		}
+		if parent := byID[s.ParentSpanID].Name; !slices.Contains(parents[s.Name], parent) {
+			t.Errorf("%s span in a %q span, want one of %v", s.Name, parent, parents[s.Name])
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State is a step of the capture lifecycle.
+type State string
+
+const (
+	StateIdle      State = "idle"      // no capture running
+	StatePlanning  State = "planning"  // computing the grid plan
+	StateHoming    State = "homing"    // moving to the start (or back to the home) position
+	StateShooting  State = "shooting"  // traversing cells and shooting
+	StatePaused    State = "paused"    // shooting suspended, waiting for Resume
+	StateFinishing State = "finishing" // end-of-session work (dark frames, ...)
+	StateError     State = "error"     // last capture failed
+)
+
+// transitions lists the states reachable from each state.
+// Any active state may go back to idle (cancellation) or to error.
+var transitions = map[State][]State{
+	StateIdle:      {StatePlanning},
+	StatePlanning:  {StateHoming, StateShooting, StateIdle, StateError},
+	StateHoming:    {StateShooting, StateFinishing, StateIdle, StateError},
+	StateShooting:  {StateHoming, StatePaused, StateFinishing, StateIdle, StateError},
+	StatePaused:    {StateShooting, StateHoming, StateIdle, StateError},
+	StateFinishing: {StateIdle, StateError},
+	StateError:     {StatePlanning, StateIdle},
+}
+
+// Cell identifies a grid position (1-based, 0 = none). Column 1 is the
+// leftmost column, row 1 the top row.
+type Cell struct {
+	Column int `json:"column"`
+	Row    int `json:"row"`
+}
+
+// Status is a snapshot of the capture lifecycle, served at GET /status.
+type Status struct {
+	State      State      `json:"state"`
+	StateSince time.Time  `json:"state_since"`
+	StartedAt  *time.Time `json:"started_at,omitempty"` // start of the current/last run
+	EndedAt    *time.Time `json:"ended_at,omitempty"`   // end of the last run
+	Cell       Cell       `json:"cell"`                 // cell being shot
+	Columns    int        `json:"columns"`              // grid size of the current plan
+	Rows       int        `json:"rows"`
+	ShotsDone  int        `json:"shots_done"`
+	ShotsTotal int        `json:"shots_total"`
+	LastError  string     `json:"last_error,omitempty"`
+}
+
+// Kinds of structured events emitted while a capture runs (see EventFunc).
+const (
+	EventState    = "state"    // data: Status, after each state change
+	EventProgress = "progress" // data: Progress, after each completed shot
+	EventShot     = "shot"     // data: session.Shot, after each shutter release
+	EventMove     = "move"     // data: Position, after each head movement
+	EventPosition = "position" // data: HeadPosition, sampled during movements
+)
+
+// EventFunc receives structured capture events (kind is one of the Event*
+// constants). It is called from the capture goroutine and must not block.
+type EventFunc func(kind string, data any)
+
+// Progress is the payload of EventProgress.
+type Progress struct {
+	ShotsDone  int  `json:"shots_done"`
+	ShotsTotal int  `json:"shots_total"`
+	Cell       Cell `json:"cell"`
+	ETA        *ETA `json:"eta,omitempty"` // estimate after this shot
+}
+
+// Position is the payload of EventMove: the tracked head position.
+type Position struct {
+	PanSteps  int `json:"pan_steps"`
+	TiltSteps int `json:"tilt_steps"`
+}
+
+// HeadPosition is the payload of EventPosition: the tracked head position
+// with its angles, sampled while the head moves so that clients can follow
+// it live. The last sample of a movement has Moving false.
+type HeadPosition struct {
+	PanSteps  int     `json:"pan_steps"`
+	TiltSteps int     `json:"tilt_steps"`
+	PanDeg    float64 `json:"pan_deg"`
+	TiltDeg   float64 `json:"tilt_deg"`
+	Moving    bool    `json:"moving"`
+}
+
+// Lifecycle is the capture state machine. It is safe for concurrent use:
+// the capture goroutine drives it while HTTP handlers read snapshots.
+type Lifecycle struct {
+	mu      sync.Mutex
+	status  Status
+	resumed chan struct{} // closed on Resume; nil when not paused
+	events  EventFunc
+	timing  runTiming // for ETA
+}
+
+// NewLifecycle returns a lifecycle in the idle state.
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{status: Status{State: StateIdle, StateSince: time.Now()}}
+}
+
+// SetEvents sets where state and progress events are sent. Optional.
+func (l *Lifecycle) SetEvents(fn EventFunc) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = fn
+}
+
+// update runs fn under the lock, then emits a state event if the state
+// changed. Events are sent after unlocking so receivers may call back.
+func (l *Lifecycle) update(fn func() error) error {
+	l.mu.Lock()
+	from := l.status.State
+	err := fn()
+	st, events := l.status, l.events
+	l.mu.Unlock()
+	if events != nil && st.State != from {
+		events(EventState, st)
+	}
+	return err
+}
+
+// Snapshot returns a copy of the current status.
+func (l *Lifecycle) Snapshot() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.status
+}
+
+// State returns the current state.
+func (l *Lifecycle) State() State {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.status.State
+}
+
+// Transition moves to state to. It fails if the transition is not allowed
+// from the current state. Entering planning starts a new run and resets the
+// progress counters.
+func (l *Lifecycle) Transition(to State) error {
+	return l.update(func() error { return l.transitionLocked(to) })
+}
+
+func (l *Lifecycle) transitionLocked(to State) error {
+	from := l.status.State
+	if from == to {
+		return nil
+	}
+	allowed := false
+	for _, s := range transitions[from] {
+		if s == to {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("invalid capture state transition %s -> %s", from, to)
+	}
+
+	now := time.Now()
+	switch to {
+	case StatePlanning:
+		l.status = Status{StartedAt: &now}
+		l.timing = runTiming{}
+	case StateShooting:
+		if l.timing.shootingSince.IsZero() {
+			l.timing.shootingSince = now
+		}
+	case StatePaused:
+		l.timing.pausedSince = now
+	case StateIdle, StateError:
+		l.status.EndedAt = &now
+		l.status.Cell = Cell{}
+	}
+	if from == StatePaused {
+		l.timing.paused += now.Sub(l.timing.pausedSince)
+		l.timing.pausedSince = time.Time{}
+		if l.resumed != nil {
+			close(l.resumed)
+			l.resumed = nil
+		}
+	}
+	l.status.State = to
+	l.status.StateSince = now
+	return nil
+}
+
+// Fail records err and moves to the error state.
+func (l *Lifecycle) Fail(err error) {
+	_ = l.update(func() error {
+		l.status.LastError = err.Error()
+		return l.transitionLocked(StateError)
+	})
+}
+
+// SetShotsTotal records the number of shots planned for the run.
+func (l *Lifecycle) SetShotsTotal(shots int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.status.ShotsTotal = shots
+}
+
+// SetGrid records the size of the grid being shot.
+func (l *Lifecycle) SetGrid(columns, rows int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.status.Columns = columns
+	l.status.Rows = rows
+}
+
+// SetCell records the cell being shot.
+func (l *Lifecycle) SetCell(column, row int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.status.Cell = Cell{Column: column, Row: row}
+}
+
+// ShotDone increments the completed shot counter.
+func (l *Lifecycle) ShotDone() {
+	l.mu.Lock()
+	l.status.ShotsDone++
+	now := time.Now()
+	l.timing.shot(now)
+	eta := l.etaLocked(now)
+	p := Progress{ShotsDone: l.status.ShotsDone, ShotsTotal: l.status.ShotsTotal, Cell: l.status.Cell, ETA: &eta}
+	events := l.events
+	l.mu.Unlock()
+	if events != nil {
+		events(EventProgress, p)
+	}
+}
+
+// Pause requests a pause: the sequence stops at the next cell boundary.
+// Only valid while shooting.
+func (l *Lifecycle) Pause() error {
+	return l.update(func() error {
+		if l.status.State != StateShooting {
+			return fmt.Errorf("cannot pause while %s", l.status.State)
+		}
+		if err := l.transitionLocked(StatePaused); err != nil {
+			return err
+		}
+		l.resumed = make(chan struct{})
+		return nil
+	})
+}
+
+// Resume continues a paused capture.
+func (l *Lifecycle) Resume() error {
+	return l.update(func() error {
+		if l.status.State != StatePaused {
+			return fmt.Errorf("cannot resume while %s", l.status.State)
+		}
+		return l.transitionLocked(StateShooting)
+	})
+}
+
+// WaitIfPaused blocks while the lifecycle is paused, until Resume or ctx is done.
+func (l *Lifecycle) WaitIfPaused(ctx context.Context) error {
+	l.mu.Lock()
+	ch := l.resumed
+	l.mu.Unlock()
+	if ch == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-ch:
+		return nil
+	}
+}
@@ -0,0 +1,328 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/geometry"
+)
+
+func TestLifecycle_InitialState(t *testing.T) {
+	l := NewLifecycle()
+	st := l.Snapshot()
+	if st.State != StateIdle {
+		t.Errorf("state = %q, want idle", st.State)
+	}
+	if st.StartedAt != nil {
+		t.Error("StartedAt should be nil before any run")
+	}
+}
+
+func TestLifecycle_Transitions(t *testing.T) {
+	cases := []struct {
+		name  string
+		path  []State
+		valid bool
+	}{
+		{"grid_run", []State{StatePlanning, StateHoming, StateShooting, StateFinishing, StateIdle}, true},
+		{"timelapse_run", []State{StatePlanning, StateShooting, StateIdle}, true},
+		{"pause_resume", []State{StatePlanning, StateHoming, StateShooting, StatePaused, StateShooting}, true},
+		{"cancel_while_paused", []State{StatePlanning, StateHoming, StateShooting, StatePaused, StateIdle}, true},
+		{"abort_return_home", []State{StatePlanning, StateHoming, StateShooting, StateHoming, StateIdle}, true},
+		{"retry_after_error", []State{StatePlanning, StateError, StatePlanning}, true},
+		{"idle_to_shooting", []State{StateShooting}, false},
+		{"idle_to_paused", []State{StatePaused}, false},
+		{"finishing_to_shooting", []State{StatePlanning, StateShooting, StateFinishing, StateShooting}, false},
+		{"error_to_shooting", []State{StatePlanning, StateError, StateShooting}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewLifecycle()
+			var err error
+			for _, s := range tc.path {
+				if err = l.Transition(s); err != nil {
+					break
+				}
+			}
+			if tc.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("expected invalid transition error, got nil")
+			}
+		})
+	}
+}
+
+func TestLifecycle_PlanningResetsRun(t *testing.T) {
+	l := NewLifecycle()
+	l.Transition(StatePlanning)
+	l.SetShotsTotal(10)
+	l.ShotDone()
+	l.Fail(errors.New("boom"))
+
+	st := l.Snapshot()
+	if st.State != StateError || st.LastError != "boom" {
+		t.Fatalf("state = %q, last_error = %q, want error/boom", st.State, st.LastError)
+	}
+	if st.EndedAt == nil {
+		t.Error("EndedAt should be set after a failure")
+	}
+
+	l.Transition(StatePlanning)
+	st = l.Snapshot()
+	if st.ShotsDone != 0 || st.ShotsTotal != 0 || st.LastError != "" || st.EndedAt != nil {
+		t.Errorf("new run should reset progress, got %+v", st)
+	}
+	if st.StartedAt == nil {
+		t.Error("StartedAt should be set when planning starts")
+	}
+}
+
+func TestLifecycle_PauseResume(t *testing.T) {
+	l := NewLifecycle()
+	if err := l.Pause(); err == nil {
+		t.Error("pause while idle should fail")
+	}
+	l.Transition(StatePlanning)
+	l.Transition(StateShooting)
+	if err := l.Pause(); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- l.WaitIfPaused(context.Background()) }()
+	select {
+	case <-done:
+		t.Fatal("WaitIfPaused returned while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if err := l.Resume(); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("WaitIfPaused: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitIfPaused did not return after Resume")
+	}
+	if err := l.Resume(); err == nil {
+		t.Error("resume while shooting should fail")
+	}
+}
+
+func TestLifecycle_WaitIfPausedCancelled(t *testing.T) {
+	l := NewLifecycle()
+	l.Transition(StatePlanning)
+	l.Transition(StateShooting)
+	l.Pause()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.WaitIfPaused(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestRunGridShot_UpdatesLifecycle(t *testing.T) {
+	ctrl := newTestController()
+	cam := &mockCamera{}
+	seq := NewSequence(ctrl, cam)
+	l := NewLifecycle()
+	seq.SetLifecycle(l)
+	l.Transition(StatePlanning)
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      &geometry.GridPlan{PanColumns: 3, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+		Delay:         time.Microsecond,
+		ShotDelay:     time.Microsecond,
+		PostShotDelay: time.Microsecond,
+	})
+	if err != nil {
+		t.Fatalf("RunGridShot: %v", err)
+	}
+	st := l.Snapshot()
+	if st.State != StateShooting {
+		t.Errorf("state = %q, want shooting", st.State)
+	}
+	if st.Columns != 3 || st.Rows != 2 {
+		t.Errorf("grid = %dx%d, want 3x2", st.Columns, st.Rows)
+	}
+	if st.ShotsDone != 6 {
+		t.Errorf("shots_done = %d, want 6", st.ShotsDone)
+	}
+	// Serpentine: last cell is column 3, bottom row (going down on odd-numbered columns)
+	if st.Cell != (Cell{Column: 3, Row: 2}) {
+		t.Errorf("last cell = %+v, want {3 2}", st.Cell)
+	}
+}
+
+// pausingCamera pauses the lifecycle on its first shot.
+type pausingCamera struct {
+	mockCamera
+	lifecycle *Lifecycle
+	paused    chan struct{}
+}
+
+func (p *pausingCamera) Shoot() error {
+	p.mockCamera.Shoot()
+	if p.shotCount() == 1 {
+		p.lifecycle.Pause()
+		close(p.paused)
+	}
+	return nil
+}
+
+func TestRunGridShot_PausesAtCellBoundary(t *testing.T) {
+	ctrl := newTestController()
+	l := NewLifecycle()
+	cam := &pausingCamera{lifecycle: l, paused: make(chan struct{})}
+	seq := NewSequence(ctrl, cam)
+	seq.SetLifecycle(l)
+	l.Transition(StatePlanning)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- seq.RunGridShot(context.Background(), GridShotParams{
+			GridPlan:      &geometry.GridPlan{PanColumns: 2, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50},
+			Delay:         time.Microsecond,
+			ShotDelay:     time.Microsecond,
+			PostShotDelay: time.Microsecond,
+		})
+	}()
+
+	<-cam.paused
+	time.Sleep(30 * time.Millisecond)
+	if n := cam.shotCount(); n != 1 {
+		t.Fatalf("shots while paused = %d, want 1", n)
+	}
+	if st := l.State(); st != StatePaused {
+		t.Fatalf("state = %q, want paused", st)
+	}
+
+	l.Resume()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunGridShot: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("sequence did not finish after Resume")
+	}
+	if n := cam.shotCount(); n != 4 {
+		t.Errorf("shots = %d, want 4", n)
+	}
+}
+
+// eventLog collects capture events.
+type eventLog struct {
+	mu     sync.Mutex
+	kinds  []string
+	events []any
+}
+
+func (e *eventLog) record(kind string, data any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.kinds = append(e.kinds, kind)
+	e.events = append(e.events, data)
+}
+
+func (e *eventLog) of(kind string) []any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var out []any
+	for i, k := range e.kinds {
+		if k == kind {
+			out = append(out, e.events[i])
+		}
+	}
+	return out
+}
+
+func TestLifecycle_Events(t *testing.T) {
+	l := NewLifecycle()
+	var log eventLog
+	l.SetEvents(log.record)
+
+	l.Transition(StatePlanning)
+	l.SetShotsTotal(2)
+	l.Transition(StateShooting)
+	l.Transition(StateShooting) // no change: no event
+	l.SetCell(1, 1)
+	l.ShotDone()
+	l.Transition(StateIdle)
+
+	states := log.of(EventState)
+	if len(states) != 3 {
+		t.Fatalf("state events = %d, want 3", len(states))
+	}
+	if st := states[1].(Status); st.State != StateShooting || st.ShotsTotal != 2 {
+		t.Errorf("second state event = %+v", st)
+	}
+	progress := log.of(EventProgress)
+	if len(progress) != 1 {
+		t.Fatalf("progress events = %d, want 1", len(progress))
+	}
+	p := progress[0].(Progress)
+	if p.ETA == nil || p.ETA.ShotsRemaining != 1 {
+		t.Errorf("progress ETA = %+v, want 1 shot remaining", p.ETA)
+	}
+	if p.ETA = nil; p != (Progress{ShotsDone: 1, ShotsTotal: 2, Cell: Cell{1, 1}}) {
+		t.Errorf("progress = %+v", p)
+	}
+}
+
+func TestLifecycle_EventsMayReadLifecycle(t *testing.T) {
+	// Receivers run outside the lock and may read the lifecycle back
+	l := NewLifecycle()
+	done := make(chan State, 1)
+	l.SetEvents(func(string, any) { done <- l.State() })
+	l.Transition(StatePlanning)
+	select {
+	case st := <-done:
+		if st != StatePlanning {
+			t.Errorf("state = %q, want planning", st)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("event receiver deadlocked")
+	}
+}
+
+func TestRunGridShot_EmitsShotAndMoveEvents(t *testing.T) {
+	ctrl := newTestController()
+	seq := NewSequence(ctrl, &mockCamera{})
+	var log eventLog
+	seq.SetEvents(log.record)
+
+	err := seq.RunGridShot(context.Background(), GridShotParams{
+		GridPlan:      &geometry.GridPlan{PanColumns: 2, TiltRows: 2, PanStepSize: 100, TiltStepSize: 50, StartPanSteps: -50},
+		Delay:         time.Microsecond,
+		ShotDelay:     time.Microsecond,
+		PostShotDelay: time.Microsecond,
+	})
+	if err != nil {
+		t.Fatalf("RunGridShot: %v", err)
+	}
+	if n := len(log.of(EventShot)); n != 4 {
+		t.Errorf("shot events = %d, want 4", n)
+	}
+	// Start position, one tilt move per column, one pan move
+	moves := log.of(EventMove)
+	if len(moves) != 4 {
+		t.Fatalf("move events = %d, want 4", len(moves))
+	}
+	if first := moves[0].(Position); first.PanSteps != -50 {
+		t.Errorf("first move = %+v, want pan -50", first)
+	}
+	pan, tilt := ctrl.Position()
+	if last := moves[len(moves)-1].(Position); last != (Position{pan, tilt}) {
+		t.Errorf("last move = %+v, want (%d, %d)", last, pan, tilt)
+	}
+}
@@ -0,0 +1,182 @@
+package capture
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/camera"
+)
+
+// ExposureRamp describes a bulb exposure ramp over a timelapse session
+// ("holy grail" timelapse). Exposures are interpolated evenly in EV (stops),
+// i.e. geometrically, which matches how light falls off around sunset.
+type ExposureRamp struct {
+	Start time.Duration // exposure of the first frame
+	End   time.Duration // exposure of the last frame
+}
+
+// At returns the exposure for frame (0-based) out of frames.
+func (r ExposureRamp) At(frame, frames int) time.Duration {
+	if frames <= 1 || frame <= 0 {
+		return r.Start
+	}
+	if frame >= frames-1 {
+		return r.End
+	}
+	t := float64(frame) / float64(frames-1)
+	ratio := float64(r.End) / float64(r.Start)
+	return time.Duration(math.Round(float64(r.Start) * math.Pow(ratio, t)))
+}
+
+// TimelapseParams defines the parameters for a fixed-position timelapse.
+type TimelapseParams struct {
+	Frames   int           // number of frames to shoot
+	Interval time.Duration // time between the start of two frames
+	Jitter   time.Duration // random ± offset on each frame start (0 = none)
+
+	ShotDelay      time.Duration // delay before each shot (stabilization)
+	PostShotDelay  time.Duration // minimum hold after each shot
+	ExposureTime   time.Duration // exposure set on the camera (ignored when Ramp is set)
+	ExposureMargin time.Duration // extra hold after the exposure
+
+	Ramp *ExposureRamp // optional bulb ramp; requires a camera.BulbCamera
+
+	// Aim, if set, returns the absolute position (steps) to shoot from at t,
+	// e.g. to follow the sun. ok=false keeps the current position.
+	Aim func(t time.Time) (pan, tilt int, ok bool)
+}
+
+// RunTimelapse shoots p.Frames photos from the current position, one every
+// p.Interval. Motors are disabled for the whole session (no movement needed)
+// and re-enabled at the end. When p.Ramp is set, each frame is shot in bulb
+// mode with the ramped exposure. When p.Aim is set, the rig is moved to the
+// aimed position before each frame (motors enabled only while moving).
+func (s *Sequence) RunTimelapse(ctx context.Context, p TimelapseParams) error {
+	var bulb camera.BulbCamera
+	if p.Ramp != nil {
+		b, ok := s.camera.(camera.BulbCamera)
+		if !ok {
+			return fmt.Errorf("exposure ramp requires a camera with bulb support")
+		}
+		bulb = b
+		dbg.Info("bulb ramp", "start", p.Ramp.Start, "end", p.Ramp.End, "frames", p.Frames)
+	}
+	dbg.Info("timelapse", "frames", p.Frames, "interval", p.Interval)
+	if p.Jitter > 0 {
+		dbg.Info("timelapse jitter", "jitter", p.Jitter)
+	}
+
+	_ = s.motion.DisableMotors()
+	defer func() { _ = s.motion.EnableMotors() }()
+
+	if s.lifecycle != nil {
+		s.lifecycle.SetShotsTotal(p.Frames)
+	}
+	s.setState(StateShooting)
+
+	next := time.Now()
+	for frame := 0; frame < p.Frames; frame++ {
+		paused, err := s.waitIfPaused(ctx)
+		if err != nil {
+			return err
+		}
+		if paused {
+			// Restart the schedule instead of catching up missed frames.
+			next = time.Now()
+		}
+		// The jitter offsets each frame around its nominal time without
+		// accumulating, so the session keeps its overall duration.
+		if err := sleepUntil(ctx, next.Add(jitterOffset(p.Jitter))); err != nil {
+			return err
+		}
+		next = next.Add(p.Interval)
+
+		timer := shotTimer{moveEnd: time.Now()}
+		if p.Aim != nil {
+			moveStart := time.Now()
+			if err := s.aim(p.Aim, frame); err != nil {
+				return err
+			}
+			timer.moved(moveStart)
+		}
+
+		time.Sleep(p.ShotDelay)
+		hold := GridShotParams{
+			PostShotDelay:  p.PostShotDelay,
+			ExposureTime:   p.ExposureTime,
+			ExposureMargin: p.ExposureMargin,
+		}.ShotHold()
+		release := s.camera.Shoot
+		if bulb != nil {
+			exposure := p.Ramp.At(frame, p.Frames)
+			dbg.Live("frame", "frame", frame+1, "frames", p.Frames, "exposure", exposure)
+			release = func() error { return bulb.ShootBulb(exposure) }
+			// ShootBulb returns once the exposure is over.
+			hold = max(p.PostShotDelay, p.ExposureMargin)
+		} else {
+			dbg.Live("frame", "frame", frame+1, "frames", p.Frames)
+		}
+		shot, err := s.shoot(&timer, release)
+		s.recordShot(ctx, shot)
+		if err != nil {
+			return err
+		}
+		time.Sleep(hold)
+		if s.lifecycle != nil {
+			s.lifecycle.ShotDone()
+		}
+
+		if late := time.Since(next); late > 0 && frame < p.Frames-1 {
+			dbg.Verbose("frame overran the interval", "frame", frame+1, "late", late)
+		}
+	}
+	return nil
+}
+
+// aim moves the rig to the position returned by aimFn for the current time.
+func (s *Sequence) aim(aimFn func(time.Time) (int, int, bool), frame int) error {
+	pan, tilt, ok := aimFn(time.Now())
+	if !ok {
+		dbg.Verbose("target not visible, holding position", "frame", frame+1)
+		return nil
+	}
+	curPan, curTilt := s.motion.Position()
+	if pan == curPan && tilt == curTilt {
+		return nil
+	}
+	dbg.Verbose("aiming", "frame", frame+1, "pan_steps", pan, "tilt_steps", tilt)
+	if err := s.motion.EnableMotors(); err != nil {
+		return err
+	}
+	err := s.motion.MoveTo(pan, tilt)
+	_ = s.motion.DisableMotors()
+	s.emitMove()
+	return err
+}
+
+// jitterOffset returns a uniformly random offset in [-j, +j].
+func jitterOffset(j time.Duration) time.Duration {
+	if j <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(2*j)+1)) - j
+}
+
+// sleepUntil waits until t or until ctx is done.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
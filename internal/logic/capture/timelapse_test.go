@@ -0,0 +1,231 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bulbCamera records bulb exposures.
+type bulbCamera struct {
+	mockCamera
+	mu        sync.Mutex
+	exposures []time.Duration
+}
+
+func (b *bulbCamera) ShootBulb(exposure time.Duration) error {
+	b.mu.Lock()
+	b.exposures = append(b.exposures, exposure)
+	b.mu.Unlock()
+	return nil
+}
+
+func TestExposureRamp_At(t *testing.T) {
+	r := ExposureRamp{Start: 100 * time.Millisecond, End: 1600 * time.Millisecond}
+	cases := []struct {
+		frame, frames int
+		want          time.Duration
+	}{
+		{0, 5, 100 * time.Millisecond},
+		{1, 5, 200 * time.Millisecond}, // +1 EV per step (4 stops over 4 steps)
+		{2, 5, 400 * time.Millisecond},
+		{3, 5, 800 * time.Millisecond},
+		{4, 5, 1600 * time.Millisecond},
+		{0, 1, 100 * time.Millisecond},
+		{9, 5, 1600 * time.Millisecond},
+	}
+	for _, tc := range cases {
+		if got := r.At(tc.frame, tc.frames); got != tc.want {
+			t.Errorf("At(%d, %d) = %v, want %v", tc.frame, tc.frames, got, tc.want)
+		}
+	}
+}
+
+func TestExposureRamp_Decreasing(t *testing.T) {
+	r := ExposureRamp{Start: 8 * time.Second, End: time.Second}
+	prev := r.At(0, 10)
+	for i := 1; i < 10; i++ {
+		cur := r.At(i, 10)
+		if cur >= prev {
+			t.Fatalf("frame %d exposure %v should be shorter than %v (sunrise ramp)", i, cur, prev)
+		}
+		prev = cur
+	}
+}
+
+func TestRunTimelapse_FrameCount(t *testing.T) {
+	ctrl := newTestController()
+	cam := &mockCamera{}
+	seq := NewSequence(ctrl, cam)
+
+	err := seq.RunTimelapse(context.Background(), TimelapseParams{
+		Frames:        5,
+		Interval:      time.Millisecond,
+		ShotDelay:     time.Microsecond,
+		PostShotDelay: time.Microsecond,
+	})
+	if err != nil {
+		t.Fatalf("RunTimelapse: %v", err)
+	}
+	if cam.shotCount() != 5 {
+		t.Errorf("shots = %d, want 5", cam.shotCount())
+	}
+	if pan, tilt := ctrl.Position(); pan != 0 || tilt != 0 {
+		t.Errorf("timelapse should not move the head, position = (%d, %d)", pan, tilt)
+	}
+}
+
+func TestRunTimelapse_IntervalRespected(t *testing.T) {
+	ctrl := newTestController()
+	cam := &mockCamera{}
+	seq := NewSequence(ctrl, cam)
+
+	start := time.Now()
+	err := seq.RunTimelapse(context.Background(), TimelapseParams{
+		Frames:   3,
+		Interval: 20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("RunTimelapse: %v", err)
+	}
+	// Frames start at 0, 20 and 40ms.
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("elapsed = %v, want >= 40ms", elapsed)
+	}
+}
+
+func TestJitterOffset(t *testing.T) {
+	if d := jitterOffset(0); d != 0 {
+		t.Errorf("jitterOffset(0) = %v, want 0", d)
+	}
+	const j = 10 * time.Millisecond
+	var sawNeg, sawPos bool
+	for range 1000 {
+		d := jitterOffset(j)
+		if d < -j || d > j {
+			t.Fatalf("jitterOffset(%v) = %v, out of range", j, d)
+		}
+		sawNeg = sawNeg || d < 0
+		sawPos = sawPos || d > 0
+	}
+	if !sawNeg || !sawPos {
+		t.Error("jitter should spread on both sides of the nominal time")
+	}
+}
+
+func TestRunTimelapse_JitterKeepsSchedule(t *testing.T) {
+	ctrl := newTestController()
+	cam := &mockCamera{}
+	seq := NewSequence(ctrl, cam)
+
+	start := time.Now()
+	err := seq.RunTimelapse(context.Background(), TimelapseParams{
+		Frames:   4,
+		Interval: 20 * time.Millisecond,
+		Jitter:   5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("RunTimelapse: %v", err)
+	}
+	if cam.shotCount() != 4 {
+		t.Errorf("shots = %d, want 4", cam.shotCount())
+	}
+	// The last frame is due at 60ms ± 5ms; jitter does not accumulate.
+	if elapsed := time.Since(start); elapsed < 55*time.Millisecond {
+		t.Errorf("elapsed = %v, want >= 55ms", elapsed)
+	}
+}
+
+func TestRunTimelapse_BulbRamp(t *testing.T) {
+	ctrl := newTestController()
+	cam := &bulbCamera{}
+	seq := NewSequence(ctrl, cam)
+
+	err := seq.RunTimelapse(context.Background(), TimelapseParams{
+		Frames:   3,
+		Interval: time.Millisecond,
+		Ramp:     &ExposureRamp{Start: time.Microsecond, End: 4 * time.Microsecond},
+	})
+	if err != nil {
+		t.Fatalf("RunTimelapse: %v", err)
+	}
+	want := []time.Duration{time.Microsecond, 2 * time.Microsecond, 4 * time.Microsecond}
+	if len(cam.exposures) != len(want) {
+		t.Fatalf("bulb exposures = %v, want %v", cam.exposures, want)
+	}
+	for i := range want {
+		if cam.exposures[i] != want[i] {
+			t.Errorf("frame %d exposure = %v, want %v", i, cam.exposures[i], want[i])
+		}
+	}
+	if cam.shotCount() != 0 {
+		t.Errorf("Shoot() called %d times, want 0 with a bulb ramp", cam.shotCount())
+	}
+}
+
+func TestRunTimelapse_RampRequiresBulbCamera(t *testing.T) {
+	seq := NewSequence(newTestController(), &mockCamera{})
+	err := seq.RunTimelapse(context.Background(), TimelapseParams{
+		Frames:   1,
+		Interval: time.Millisecond,
+		Ramp:     &ExposureRamp{Start: time.Millisecond, End: time.Millisecond},
+	})
+	if err == nil {
+		t.Fatal("expected error for ramp without bulb camera, got nil")
+	}
+}
+
+func TestRunTimelapse_ContextCancellation(t *testing.T) {
+	cam := &mockCamera{}
+	seq := NewSequence(newTestController(), cam)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	err := seq.RunTimelapse(ctx, TimelapseParams{
+		Frames:   100,
+		Interval: 10 * time.Millisecond,
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if n := cam.shotCount(); n == 0 || n >= 100 {
+		t.Errorf("shots = %d, want some but not all", n)
+	}
+}
+
+func TestRunTimelapse_AimMovesBeforeEachFrame(t *testing.T) {
+	ctrl := newTestController()
+	cam := &mockCamera{}
+	seq := NewSequence(ctrl, cam)
+
+	var calls int
+	aim := func(time.Time) (int, int, bool) {
+		calls++
+		if calls == 3 {
+			return 0, 0, false // not visible: hold position
+		}
+		return calls * 10, calls * 5, true
+	}
+	err := seq.RunTimelapse(context.Background(), TimelapseParams{
+		Frames:        4,
+		Interval:      time.Millisecond,
+		ShotDelay:     time.Microsecond,
+		PostShotDelay: time.Microsecond,
+		Aim:           aim,
+	})
+	if err != nil {
+		t.Fatalf("RunTimelapse: %v", err)
+	}
+	if calls != 4 {
+		t.Errorf("aim calls = %d, want 4 (one per frame)", calls)
+	}
+	if pan, tilt := ctrl.Position(); pan != 40 || tilt != 20 {
+		t.Errorf("final position = (%d, %d), want (40, 20)", pan, tilt)
+	}
+	if cam.shotCount() != 4 {
+		t.Errorf("shots = %d, want 4", cam.shotCount())
+	}
+}
@@ -35,6 +35,13 @@ type GridPlan struct {
 // and FOV/steps calculators. Returns an error if the calculated grid
 // would require excessive resources (preventing overflow/DoS).
 func CalculateGridPlan(cfg *config.Config, fovCalc *FOVCalculator, stepsCalc *StepsCalculator) (*GridPlan, error) {
+	return CalculateGridPlanAt(cfg, fovCalc, stepsCalc, 0, 0)
+}
+
+// CalculateGridPlanAt is like CalculateGridPlan, but centers the grid on the
+// given direction (degrees from the rig's start position; pan right and tilt
+// up are positive) instead of straight ahead.
+func CalculateGridPlanAt(cfg *config.Config, fovCalc *FOVCalculator, stepsCalc *StepsCalculator, panCenterDeg, tiltCenterDeg float64) (*GridPlan, error) {
 	// Rotation angles between each photo
 	panRotationAngle := fovCalc.HorizontalRotationAngle()
 	tiltRotationAngle := fovCalc.VerticalRotationAngle()
@@ -80,8 +87,8 @@ func CalculateGridPlan(cfg *config.Config, fovCalc *FOVCalculator, stepsCalc *St
 
 	// Start position: far left (negative) and top (positive)
 	// Note: we assume "up" = positive angle for tilt
-	startPanAngle := -cfg.HorizontalHalfAngleDeg() // left
-	startTiltAngle := cfg.VerticalHalfAngleDeg()   // top
+	startPanAngle := panCenterDeg - cfg.HorizontalHalfAngleDeg() // left
+	startTiltAngle := tiltCenterDeg + cfg.VerticalHalfAngleDeg() // top
 
 	startPanSteps := stepsCalc.PanStepsFromAngle(startPanAngle)
 	startTiltSteps := stepsCalc.TiltStepsFromAngle(startTiltAngle)
@@ -97,3 +104,32 @@ func CalculateGridPlan(cfg *config.Config, fovCalc *FOVCalculator, stepsCalc *St
 		StartTiltSteps: startTiltSteps,
 	}, nil
 }
+
+// GridCell is one shot of a grid plan: its cell (1-based, row 1 at the top)
+// and the head position there, in motor steps from the grid's origin.
+type GridCell struct {
+	Column, Row         int
+	PanSteps, TiltSteps int
+}
+
+// Cells returns the shots of the plan in capture order: columns from left
+// to right, traversed top to bottom then bottom to top (serpentine), as
+// capture.Sequence shoots them.
+func (p *GridPlan) Cells() []GridCell {
+	cells := make([]GridCell, 0, p.PanColumns*p.TiltRows)
+	for col := 0; col < p.PanColumns; col++ {
+		for i := 0; i < p.TiltRows; i++ {
+			row := i
+			if col%2 == 1 {
+				row = p.TiltRows - 1 - i
+			}
+			cells = append(cells, GridCell{
+				Column:    col + 1,
+				Row:       row + 1,
+				PanSteps:  p.StartPanSteps + col*p.PanStepSize,
+				TiltSteps: p.StartTiltSteps - row*p.TiltStepSize,
+			})
+		}
+	}
+	return cells
+}
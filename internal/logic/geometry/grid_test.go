@@ -216,3 +216,57 @@ func TestCalculateGridPlan_AlwaysAtLeastOnePhoto(t *testing.T) {
 		t.Errorf("TiltRows = %d, must be >= 1", plan.TiltRows)
 	}
 }
+
+func TestCalculateGridPlanAt_Offset(t *testing.T) {
+	cfg := newGridConfig(35, 23.6, 15.8, 30, 90, 30)
+	fovCalc, _ := NewFOVCalculator(cfg)
+	stepsCalc := NewStepsCalculator(cfg)
+
+	centered, err := CalculateGridPlan(cfg, fovCalc, stepsCalc)
+	if err != nil {
+		t.Fatalf("CalculateGridPlan failed: %v", err)
+	}
+	plan, err := CalculateGridPlanAt(cfg, fovCalc, stepsCalc, 60, -10)
+	if err != nil {
+		t.Fatalf("CalculateGridPlanAt failed: %v", err)
+	}
+
+	// Centered on 60° right, 10° down: spans 15°..105° and +5°..-25°.
+	if math.Abs(plan.StartPanAngle-15.0) > epsilon {
+		t.Errorf("StartPanAngle = %f, want 15.0", plan.StartPanAngle)
+	}
+	if math.Abs(plan.StartTiltAngle-5.0) > epsilon {
+		t.Errorf("StartTiltAngle = %f, want 5.0", plan.StartTiltAngle)
+	}
+	if plan.StartPanSteps != stepsCalc.PanStepsFromAngle(15) {
+		t.Errorf("StartPanSteps = %d, want %d", plan.StartPanSteps, stepsCalc.PanStepsFromAngle(15))
+	}
+	// The offset only moves the grid; its size is unchanged.
+	if plan.PanColumns != centered.PanColumns || plan.TiltRows != centered.TiltRows {
+		t.Errorf("grid = %dx%d, want %dx%d", plan.PanColumns, plan.TiltRows, centered.PanColumns, centered.TiltRows)
+	}
+	if plan.PanStepSize != centered.PanStepSize || plan.TiltStepSize != centered.TiltStepSize {
+		t.Error("step sizes should not depend on the grid center")
+	}
+}
+
+func TestGridPlan_Cells(t *testing.T) {
+	plan := &GridPlan{
+		PanColumns: 2, TiltRows: 3,
+		PanStepSize: 100, TiltStepSize: 50,
+		StartPanSteps: -100, StartTiltSteps: 40,
+	}
+	want := []GridCell{
+		{1, 1, -100, 40}, {1, 2, -100, -10}, {1, 3, -100, -60},
+		{2, 3, 0, -60}, {2, 2, 0, -10}, {2, 1, 0, 40},
+	}
+	got := plan.Cells()
+	if len(got) != len(want) {
+		t.Fatalf("Cells = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cell %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
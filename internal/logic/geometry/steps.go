@@ -35,6 +35,28 @@ func (s *StepsCalculator) TiltStepsFromAngle(angleDegrees float64) int {
 	return int(angleDegrees * s.tiltStepsPerDegree)
 }
 
+// PanAngleFromSteps converts pan motor steps to a horizontal angle (in
+// degrees), the inverse of PanStepsFromAngle.
+func (s *StepsCalculator) PanAngleFromSteps(steps int) float64 {
+	return float64(steps) / s.panStepsPerDegree
+}
+
+// TiltAngleFromSteps converts tilt motor steps to a vertical angle (in
+// degrees), the inverse of TiltStepsFromAngle.
+func (s *StepsCalculator) TiltAngleFromSteps(steps int) float64 {
+	return float64(steps) / s.tiltStepsPerDegree
+}
+
+// PanStepsPerDegree returns the number of pan motor (micro)steps per degree.
+func (s *StepsCalculator) PanStepsPerDegree() float64 {
+	return s.panStepsPerDegree
+}
+
+// TiltStepsPerDegree returns the number of tilt motor (micro)steps per degree.
+func (s *StepsCalculator) TiltStepsPerDegree() float64 {
+	return s.tiltStepsPerDegree
+}
+
 // PanStepsForOverlap calculates the number of pan steps needed to achieve
 // the configured overlap between two photos.
 func (s *StepsCalculator) PanStepsForOverlap(fovCalc *FOVCalculator) int {
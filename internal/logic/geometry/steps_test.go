@@ -1,6 +1,7 @@
 package geometry
 
 import (
+	"math"
 	"testing"
 
 	"github.com/cjeanneret/PanGo/internal/config"
@@ -94,6 +95,35 @@ func TestStepsCalculator_AsymmetricAxes(t *testing.T) {
 	}
 }
 
+func TestStepsCalculator_AngleFromSteps(t *testing.T) {
+	cfg := &config.Config{
+		PanStepper:  config.StepperConfig{StepsPerRev: 200, Microstepping: 16},
+		TiltStepper: config.StepperConfig{StepsPerRev: 400, Microstepping: 8},
+	}
+	sc := NewStepsCalculator(cfg)
+	cases := []struct {
+		steps int
+		want  float64
+	}{
+		{0, 0},
+		{800, 90},
+		{-1600, -180},
+		{3200, 360},
+	}
+	for _, tc := range cases {
+		if got := sc.PanAngleFromSteps(tc.steps); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("PanAngleFromSteps(%d) = %v, want %v", tc.steps, got, tc.want)
+		}
+		if got := sc.TiltAngleFromSteps(tc.steps); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("TiltAngleFromSteps(%d) = %v, want %v", tc.steps, got, tc.want)
+		}
+	}
+	// Round trip, up to the truncation of PanStepsFromAngle
+	if got := sc.PanAngleFromSteps(sc.PanStepsFromAngle(47.3)); math.Abs(got-47.3) > 1/sc.PanStepsPerDegree() {
+		t.Errorf("round trip of 47.3° = %v", got)
+	}
+}
+
 func TestStepsCalculator_ForOverlap(t *testing.T) {
 	cfg := &config.Config{
 		Lens:   config.LensConfig{FocalLengthMm: 35},
@@ -0,0 +1,70 @@
+package geometry
+
+import (
+	"math"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+)
+
+const (
+	deg2rad = math.Pi / 180
+	rad2deg = 180 / math.Pi
+)
+
+// SunPosition returns the sun's azimuth (degrees clockwise from true north,
+// 0-360) and elevation (degrees above the horizon) seen from latDeg/lonDeg
+// (north and east positive) at t.
+//
+// Uses the low-precision solar coordinates from the Astronomical Almanac,
+// good to about 0.01° between 1950 and 2050. Atmospheric refraction is
+// ignored (up to ~0.5° near the horizon).
+func SunPosition(t time.Time, latDeg, lonDeg float64) (azimuthDeg, elevationDeg float64) {
+	// Days since J2000.0 (2000-01-01 12:00 UTC)
+	jd := float64(t.UnixNano())/float64(24*time.Hour) + 2440587.5
+	n := jd - 2451545.0
+
+	// Ecliptic coordinates
+	meanLon := normalizeDeg(280.460 + 0.9856474*n)
+	meanAnomaly := normalizeDeg(357.528+0.9856003*n) * deg2rad
+	eclipticLon := (meanLon + 1.915*math.Sin(meanAnomaly) + 0.020*math.Sin(2*meanAnomaly)) * deg2rad
+	obliquity := (23.439 - 0.0000004*n) * deg2rad
+
+	// Equatorial coordinates
+	rightAscension := math.Atan2(math.Cos(obliquity)*math.Sin(eclipticLon), math.Cos(eclipticLon))
+	declination := math.Asin(math.Sin(obliquity) * math.Sin(eclipticLon))
+
+	// Local hour angle from Greenwich mean sidereal time
+	gmstDeg := normalizeDeg(280.46061837 + 360.98564736629*n)
+	hourAngle := (gmstDeg+lonDeg)*deg2rad - rightAscension
+
+	lat := latDeg * deg2rad
+	elevation := math.Asin(math.Sin(lat)*math.Sin(declination) + math.Cos(lat)*math.Cos(declination)*math.Cos(hourAngle))
+	azimuth := math.Atan2(-math.Sin(hourAngle), math.Tan(declination)*math.Cos(lat)-math.Sin(lat)*math.Cos(hourAngle))
+
+	return normalizeDeg(azimuth * rad2deg), elevation * rad2deg
+}
+
+// SunPointing returns the pan/tilt angles (degrees, relative to the rig's
+// start position) that aim the camera at the sun plus the configured offsets
+// at t. The rig is assumed to start level, facing ReferenceAzimuthDeg.
+// Pan is the angle of that direction nearest to fromPanDeg, the previous
+// pan (positive = right/clockwise): a track crossing due behind the start
+// direction goes on smoothly instead of jumping a full turn back. From 0
+// it is within -180..180.
+// visible is false when the sun is below the horizon.
+func SunPointing(cfg config.SunTrackingConfig, t time.Time, fromPanDeg float64) (panDeg, tiltDeg float64, visible bool) {
+	azimuth, elevation := SunPosition(t, cfg.LatitudeDeg, cfg.LongitudeDeg)
+	panDeg = fromPanDeg + normalizeDeg(azimuth+cfg.AzimuthOffsetDeg-cfg.ReferenceAzimuthDeg-fromPanDeg+180) - 180
+	tiltDeg = math.Max(-90, math.Min(90, elevation+cfg.ElevationOffsetDeg))
+	return panDeg, tiltDeg, elevation >= 0
+}
+
+// normalizeDeg wraps an angle into [0, 360).
+func normalizeDeg(a float64) float64 {
+	a = math.Mod(a, 360)
+	if a < 0 {
+		a += 360
+	}
+	return a
+}
@@ -0,0 +1,120 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/config"
+)
+
+func TestSunPosition(t *testing.T) {
+	cases := []struct {
+		name           string
+		t              time.Time
+		lat, lon       float64
+		wantAz, wantEl float64
+	}{
+		// Solar noon at Greenwich on the June solstice: due south, 90 - 51.48 + 23.44
+		{"greenwich_solstice_noon", time.Date(2024, 6, 20, 12, 2, 0, 0, time.UTC), 51.4769, 0, 180, 61.96},
+		// Subsolar point at the March equinox: sun at the zenith
+		{"equator_equinox", time.Date(2024, 3, 20, 12, 7, 0, 0, time.UTC), 0, 0, -1, 89.9},
+		// Sydney at local midnight: below the horizon
+		{"sydney_night", time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC), -33.8688, 151.2093, -1, -1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			az, el := SunPosition(tc.t, tc.lat, tc.lon)
+			if az < 0 || az >= 360 {
+				t.Errorf("azimuth = %.2f, want within [0, 360)", az)
+			}
+			if tc.wantAz >= 0 && math.Abs(az-tc.wantAz) > 1 {
+				t.Errorf("azimuth = %.2f, want %.2f", az, tc.wantAz)
+			}
+			switch {
+			case tc.wantEl < 0:
+				if el >= 0 {
+					t.Errorf("elevation = %.2f, want below the horizon", el)
+				}
+			case math.Abs(el-tc.wantEl) > 0.5:
+				t.Errorf("elevation = %.2f, want %.2f", el, tc.wantEl)
+			}
+		})
+	}
+}
+
+func TestSunPosition_SymmetricAroundNoon(t *testing.T) {
+	// Greenwich solar noon on the June solstice is ~12:02 UTC.
+	noon := time.Date(2024, 6, 20, 12, 2, 0, 0, time.UTC)
+	azAM, elAM := SunPosition(noon.Add(-3*time.Hour), 51.4769, 0)
+	azPM, elPM := SunPosition(noon.Add(3*time.Hour), 51.4769, 0)
+	if math.Abs(elAM-elPM) > 0.3 {
+		t.Errorf("elevation 3h before/after noon = %.2f / %.2f, want equal", elAM, elPM)
+	}
+	if math.Abs((azAM+azPM)/2-180) > 0.5 {
+		t.Errorf("azimuths %.2f / %.2f should mirror around 180", azAM, azPM)
+	}
+	if azAM >= 180 || azPM <= 180 {
+		t.Errorf("morning sun should be east of south (%.2f), afternoon west (%.2f)", azAM, azPM)
+	}
+}
+
+func TestSunPointing(t *testing.T) {
+	noon := time.Date(2024, 6, 20, 12, 2, 0, 0, time.UTC)
+	cfg := config.SunTrackingConfig{LatitudeDeg: 51.4769, LongitudeDeg: 0, ReferenceAzimuthDeg: 170}
+
+	pan, tilt, visible := SunPointing(cfg, noon, 0)
+	if !visible {
+		t.Fatal("sun should be visible at noon")
+	}
+	if math.Abs(pan-10) > 1 {
+		t.Errorf("pan = %.2f, want ~10 (sun at 180, rig facing 170)", pan)
+	}
+	if math.Abs(tilt-61.96) > 0.5 {
+		t.Errorf("tilt = %.2f, want ~61.96", tilt)
+	}
+
+	cfg.AzimuthOffsetDeg = -5
+	cfg.ElevationOffsetDeg = 3
+	pan2, tilt2, _ := SunPointing(cfg, noon, 0)
+	if math.Abs(pan2-(pan-5)) > 1e-9 || math.Abs(tilt2-(tilt+3)) > 1e-9 {
+		t.Errorf("offsets not applied: pan %.2f -> %.2f, tilt %.2f -> %.2f", pan, pan2, tilt, tilt2)
+	}
+}
+
+func TestSunPointing_WrapsPan(t *testing.T) {
+	// Rig facing north (0), sun to the north-west: pan must go left, not 300° right.
+	cfg := config.SunTrackingConfig{LatitudeDeg: 60, LongitudeDeg: 0, ReferenceAzimuthDeg: 0}
+	evening := time.Date(2024, 6, 20, 21, 0, 0, 0, time.UTC)
+	pan, _, _ := SunPointing(cfg, evening, 0)
+	if pan < -180 || pan > 0 {
+		t.Errorf("pan = %.2f, want within [-180, 0] for a north-west sun", pan)
+	}
+}
+
+func TestSunPointing_Unwrapped(t *testing.T) {
+	// Midnight sun at 69°N, the rig facing south: around midnight the sun
+	// passes due north, right behind the start direction (pan ±180°).
+	cfg := config.SunTrackingConfig{LatitudeDeg: 69, LongitudeDeg: 0, ReferenceAzimuthDeg: 180}
+	start := time.Date(2024, 6, 20, 22, 0, 0, 0, time.UTC)
+
+	pan := 0.0
+	crossed := false
+	for i := 0; i <= 24; i++ { // 22:00 to 02:00, every 10 minutes
+		prev := pan
+		next, _, _ := SunPointing(cfg, start.Add(time.Duration(i)*10*time.Minute), pan)
+		if i > 0 && math.Abs(next-prev) > 5 {
+			t.Fatalf("step %d: pan %.2f -> %.2f, want a smooth track", i, prev, next)
+		}
+		if math.Abs(next) > 180 {
+			crossed = true
+		}
+		pan = next
+	}
+	if !crossed {
+		t.Fatalf("pan ended at %.2f: the track did not cross ±180°", pan)
+	}
+	if wrapped, _, _ := SunPointing(cfg, start.Add(4*time.Hour), 0); math.Abs(wrapped) > 180 || math.Abs(wrapped-pan) < 300 {
+		t.Errorf("from 0: pan %.2f, want the same direction wrapped to ±180 (unwrapped %.2f)", wrapped, pan)
+	}
+}
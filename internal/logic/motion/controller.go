@@ -1,6 +1,11 @@
 package motion
 
-import "github.com/cjeanneret/PanGo/internal/hw/stepper"
+import (
+	"context"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hw/stepper"
+)
 
 // Controller orchestrates pan/tilt movements via two stepper motors.
 // It's an intermediate layer between business logic (photo sequences,
@@ -25,6 +30,17 @@ func (c *Controller) MoveTilt(steps int) error {
 	return c.tilt.MoveSteps(steps)
 }
 
+// MovePanContext moves the pan axis until done or ctx is cancelled, with an
+// optional step period (0 = configured speed). Used for manual jogs.
+func (c *Controller) MovePanContext(ctx context.Context, steps int, stepPeriod time.Duration) error {
+	return c.pan.MoveStepsContext(ctx, steps, stepPeriod)
+}
+
+// MoveTiltContext is MovePanContext for the tilt axis.
+func (c *Controller) MoveTiltContext(ctx context.Context, steps int, stepPeriod time.Duration) error {
+	return c.tilt.MoveStepsContext(ctx, steps, stepPeriod)
+}
+
 // MovePanTilt performs a combined movement (sequential for now).
 // Later, you can improve this method to synchronize the axes.
 func (c *Controller) MovePanTilt(panSteps, tiltSteps int) error {
@@ -37,6 +53,17 @@ func (c *Controller) MovePanTilt(panSteps, tiltSteps int) error {
 	return nil
 }
 
+// Position returns the tracked pan and tilt positions in motor steps.
+func (c *Controller) Position() (pan, tilt int) {
+	return c.pan.Position(), c.tilt.Position()
+}
+
+// MoveTo moves both axes to an absolute tracked position (in motor steps).
+func (c *Controller) MoveTo(pan, tilt int) error {
+	curPan, curTilt := c.Position()
+	return c.MovePanTilt(pan-curPan, tilt-curTilt)
+}
+
 // EnableMotors enables both drivers (A4988 ENABLE=LOW). Motors hold position.
 func (c *Controller) EnableMotors() error {
 	if err := c.pan.Enable(); err != nil {
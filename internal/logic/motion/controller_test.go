@@ -1,6 +1,8 @@
 package motion
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -80,3 +82,48 @@ func TestController_MovePanZero(t *testing.T) {
 		t.Errorf("MovePan(0): %v", err)
 	}
 }
+
+func TestController_PositionAndMoveTo(t *testing.T) {
+	pan, _ := newMockStepper()
+	tilt, _ := newMockStepper()
+	ctrl := NewController(pan, tilt)
+
+	if err := ctrl.MovePanTilt(120, -40); err != nil {
+		t.Fatalf("MovePanTilt: %v", err)
+	}
+	if p, tl := ctrl.Position(); p != 120 || tl != -40 {
+		t.Fatalf("Position() = (%d, %d), want (120, -40)", p, tl)
+	}
+
+	if err := ctrl.MoveTo(0, 0); err != nil {
+		t.Fatalf("MoveTo: %v", err)
+	}
+	if p, tl := ctrl.Position(); p != 0 || tl != 0 {
+		t.Errorf("Position() after MoveTo(0, 0) = (%d, %d), want (0, 0)", p, tl)
+	}
+}
+
+func TestController_MoveContext(t *testing.T) {
+	pan, _ := newMockStepper()
+	tilt, _ := newMockStepper()
+	ctrl := NewController(pan, tilt)
+
+	if err := ctrl.MovePanContext(context.Background(), 30, 0); err != nil {
+		t.Fatalf("MovePanContext: %v", err)
+	}
+	if err := ctrl.MoveTiltContext(context.Background(), -12, 10*time.Microsecond); err != nil {
+		t.Fatalf("MoveTiltContext: %v", err)
+	}
+	if p, tl := ctrl.Position(); p != 30 || tl != -12 {
+		t.Fatalf("Position() = (%d, %d), want (30, -12)", p, tl)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := ctrl.MovePanContext(ctx, 10, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if p, _ := ctrl.Position(); p != 30 {
+		t.Errorf("pan position = %d, want 30 after a cancelled move", p)
+	}
+}
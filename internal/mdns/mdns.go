@@ -0,0 +1,282 @@
+// Package mdns advertises a service on the local network with multicast DNS
+// (RFC 6762) and DNS-SD (RFC 6763), so that it can be found without knowing
+// its IP address: a browser opens http://<host>.local, and service browsers
+// list the instance under its service type.
+package mdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// mDNS multicast group and port (IPv4).
+var groupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// Record TTLs recommended by RFC 6762 §10: host-related records short,
+// the others long.
+const (
+	hostTTL  = 120
+	otherTTL = 4500
+)
+
+// cacheFlush marks records this host is the only owner of (RFC 6762 §10.2).
+const cacheFlush = dnsmessage.Class(1 << 15)
+
+// servicesName lists the service types of a host (RFC 6763 §9).
+const servicesName = "_services._dns-sd._udp.local."
+
+// maxMessageBytes is the largest mDNS message handled (RFC 6762 §17).
+const maxMessageBytes = 9000
+
+// Service describes what is advertised.
+type Service struct {
+	Instance string   // instance name shown by service browsers, e.g. "PanGo"
+	Type     string   // service type, e.g. "_pango._tcp"
+	Host     string   // host name without ".local", e.g. "pango"
+	Port     int      // TCP port of the service
+	TXT      []string // key=value metadata, e.g. "path=/"
+	// IPs returns the IPv4 addresses to advertise; nil uses the addresses
+	// of the up, non-loopback interfaces at each answer.
+	IPs func() []net.IP
+}
+
+// Validate checks that s can be advertised.
+func (s Service) Validate() error {
+	switch {
+	case s.Instance == "" || strings.Contains(s.Instance, "."):
+		return fmt.Errorf("mdns: invalid instance name %q", s.Instance)
+	case !strings.HasPrefix(s.Type, "_") || !strings.HasSuffix(s.Type, "._tcp") && !strings.HasSuffix(s.Type, "._udp"):
+		return fmt.Errorf("mdns: invalid service type %q", s.Type)
+	case s.Host == "" || strings.Contains(s.Host, "."):
+		return fmt.Errorf("mdns: invalid host name %q", s.Host)
+	case s.Port <= 0 || s.Port > 65535:
+		return fmt.Errorf("mdns: invalid port %d", s.Port)
+	}
+	return nil
+}
+
+func (s Service) typeName() string     { return s.Type + ".local." }
+func (s Service) instanceName() string { return s.Instance + "." + s.typeName() }
+func (s Service) hostName() string     { return s.Host + ".local." }
+
+func (s Service) addresses() []net.IP {
+	if s.IPs != nil {
+		return s.IPs()
+	}
+	return interfaceIPs()
+}
+
+// Advertise announces svc on the network and answers queries for it until
+// ctx is done; it then sends a goodbye so that browsers drop it at once.
+func Advertise(ctx context.Context, svc Service) error {
+	if err := svc.Validate(); err != nil {
+		return err
+	}
+	conn, err := net.ListenMulticastUDP("udp4", nil, groupAddr)
+	if err != nil {
+		return fmt.Errorf("mdns: listen: %w", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Announce twice, one second apart (RFC 6762 §8.3).
+	announce := func(ttlScale uint32) {
+		if msg, err := svc.announcement(ttlScale); err == nil {
+			conn.WriteToUDP(msg, groupAddr)
+		}
+	}
+	announce(1)
+	go func() {
+		select {
+		case <-time.After(time.Second):
+			announce(1)
+		case <-ctx.Done():
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		announce(0) // goodbye
+		conn.Close()
+	}()
+
+	buf := make([]byte, maxMessageBytes)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("mdns: read: %w", err)
+		}
+		resp, unicast, err := svc.answer(buf[:n], src.Port != groupAddr.Port)
+		if err != nil || resp == nil {
+			continue // not for us, or malformed
+		}
+		dst := groupAddr
+		if unicast {
+			dst = src
+		}
+		conn.WriteToUDP(resp, dst)
+	}
+}
+
+// announcement returns an unsolicited response with all the records of svc,
+// TTLs multiplied by ttlScale (0 for a goodbye).
+func (s Service) announcement(ttlScale uint32) ([]byte, error) {
+	answers := append(s.pointerRecords(), s.instanceRecords()...)
+	answers = append(answers, s.addressRecords()...)
+	for i := range answers {
+		answers[i].Header.TTL *= ttlScale
+	}
+	return build(dnsmessage.Header{Response: true, Authoritative: true}, nil, answers, nil)
+}
+
+// answer returns the response to query, or nil when none of its questions
+// are about svc. Queries from a port other than 5353 are legacy unicast
+// queries (RFC 6762 §6.7): the response goes back to the sender, with the
+// query ID and questions.
+func (s Service) answer(query []byte, legacy bool) (resp []byte, unicast bool, err error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return nil, false, err
+	}
+	if h.Response {
+		return nil, false, nil
+	}
+	questions, err := p.AllQuestions()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var answers, additionals []dnsmessage.Resource
+	for _, q := range questions {
+		name := strings.ToLower(q.Name.String())
+		switch {
+		case name == strings.ToLower(s.typeName()) && matches(q.Type, dnsmessage.TypePTR):
+			answers = append(answers, s.pointerRecords()[0])
+			additionals = append(additionals, s.instanceRecords()...)
+			additionals = append(additionals, s.addressRecords()...)
+		case name == servicesName && matches(q.Type, dnsmessage.TypePTR):
+			answers = append(answers, s.pointerRecords()[1])
+		case name == strings.ToLower(s.instanceName()) && (matches(q.Type, dnsmessage.TypeSRV) || matches(q.Type, dnsmessage.TypeTXT)):
+			answers = append(answers, s.instanceRecords()...)
+			additionals = append(additionals, s.addressRecords()...)
+		case name == strings.ToLower(s.hostName()) && matches(q.Type, dnsmessage.TypeA):
+			answers = append(answers, s.addressRecords()...)
+		}
+	}
+	if len(answers) == 0 {
+		return nil, false, nil
+	}
+
+	header := dnsmessage.Header{Response: true, Authoritative: true}
+	if legacy {
+		header.ID = h.ID
+		for i := range answers {
+			answers[i].Header.Class &^= cacheFlush
+			answers[i].Header.TTL = min(answers[i].Header.TTL, 10) // RFC 6762 §6.7
+		}
+		additionals = nil
+	} else {
+		questions = nil
+	}
+	resp, err = build(header, questions, answers, additionals)
+	return resp, legacy, err
+}
+
+// matches reports whether a question of type qt asks for records of type t.
+func matches(qt, t dnsmessage.Type) bool {
+	return qt == t || qt == dnsmessage.TypeALL
+}
+
+// pointerRecords returns the PTR records: service type to instance, and
+// service enumeration to service type.
+func (s Service) pointerRecords() []dnsmessage.Resource {
+	return []dnsmessage.Resource{
+		{
+			Header: header(s.typeName(), dnsmessage.TypePTR, dnsmessage.ClassINET, otherTTL),
+			Body:   &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(s.instanceName())},
+		},
+		{
+			Header: header(servicesName, dnsmessage.TypePTR, dnsmessage.ClassINET, otherTTL),
+			Body:   &dnsmessage.PTRResource{PTR: dnsmessage.MustNewName(s.typeName())},
+		},
+	}
+}
+
+// instanceRecords returns the SRV (host and port) and TXT records of the instance.
+func (s Service) instanceRecords() []dnsmessage.Resource {
+	txt := s.TXT
+	if len(txt) == 0 {
+		txt = []string{""} // a TXT record holds at least one string (RFC 6763 §6.1)
+	}
+	return []dnsmessage.Resource{
+		{
+			Header: header(s.instanceName(), dnsmessage.TypeSRV, dnsmessage.ClassINET|cacheFlush, hostTTL),
+			Body:   &dnsmessage.SRVResource{Target: dnsmessage.MustNewName(s.hostName()), Port: uint16(s.Port)},
+		},
+		{
+			Header: header(s.instanceName(), dnsmessage.TypeTXT, dnsmessage.ClassINET|cacheFlush, otherTTL),
+			Body:   &dnsmessage.TXTResource{TXT: txt},
+		},
+	}
+}
+
+// addressRecords returns the A records of the host.
+func (s Service) addressRecords() []dnsmessage.Resource {
+	var rs []dnsmessage.Resource
+	for _, ip := range s.addresses() {
+		ip4 := ip.To4()
+		if ip4 == nil {
+			continue
+		}
+		rs = append(rs, dnsmessage.Resource{
+			Header: header(s.hostName(), dnsmessage.TypeA, dnsmessage.ClassINET|cacheFlush, hostTTL),
+			Body:   &dnsmessage.AResource{A: [4]byte(ip4)},
+		})
+	}
+	return rs
+}
+
+func header(name string, typ dnsmessage.Type, class dnsmessage.Class, ttl uint32) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Type: typ, Class: class, TTL: ttl}
+}
+
+// build packs a DNS message.
+func build(h dnsmessage.Header, questions []dnsmessage.Question, answers, additionals []dnsmessage.Resource) ([]byte, error) {
+	msg := dnsmessage.Message{Header: h, Questions: questions, Answers: answers, Additionals: additionals}
+	return msg.Pack()
+}
+
+// interfaceIPs returns the IPv4 addresses of the up, non-loopback interfaces.
+func interfaceIPs() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var ips []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				ips = append(ips, ipnet.IP)
+			}
+		}
+	}
+	return ips
+}
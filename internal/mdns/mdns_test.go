@@ -0,0 +1,214 @@
+package mdns
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func testService() Service {
+	return Service{
+		Instance: "PanGo",
+		Type:     "_pango._tcp",
+		Host:     "pango",
+		Port:     8080,
+		TXT:      []string{"path=/"},
+		IPs:      func() []net.IP { return []net.IP{net.IPv4(192, 168, 1, 20), net.ParseIP("fe80::1")} },
+	}
+}
+
+func query(t *testing.T, id uint16, name string, typ dnsmessage.Type) []byte {
+	t.Helper()
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: typ, Class: dnsmessage.ClassINET}},
+	}
+	data, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func parse(t *testing.T, data []byte) dnsmessage.Message {
+	t.Helper()
+	var msg dnsmessage.Message
+	if err := msg.Unpack(data); err != nil {
+		t.Fatalf("unpack: %v", err)
+	}
+	return msg
+}
+
+// types lists the record types of rs, in order.
+func types(rs []dnsmessage.Resource) []dnsmessage.Type {
+	var ts []dnsmessage.Type
+	for _, r := range rs {
+		ts = append(ts, r.Header.Type)
+	}
+	return ts
+}
+
+func equalTypes(a, b []dnsmessage.Type) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ---------- answer ----------
+
+func TestAnswer_ServiceBrowse(t *testing.T) {
+	resp, unicast, err := testService().answer(query(t, 0, "_pango._tcp.local.", dnsmessage.TypePTR), false)
+	if err != nil || resp == nil {
+		t.Fatalf("answer = %v, %v", resp, err)
+	}
+	if unicast {
+		t.Error("mDNS queries are answered on the multicast group")
+	}
+	msg := parse(t, resp)
+	if !msg.Header.Response || !msg.Header.Authoritative || len(msg.Questions) != 0 {
+		t.Errorf("header = %+v, %d questions", msg.Header, len(msg.Questions))
+	}
+	if len(msg.Answers) != 1 || msg.Answers[0].Body.(*dnsmessage.PTRResource).PTR.String() != "PanGo._pango._tcp.local." {
+		t.Fatalf("answers = %+v", msg.Answers)
+	}
+	want := []dnsmessage.Type{dnsmessage.TypeSRV, dnsmessage.TypeTXT, dnsmessage.TypeA}
+	if got := types(msg.Additionals); !equalTypes(got, want) {
+		t.Fatalf("additional types = %v, want %v (IPv6 addresses skipped)", got, want)
+	}
+	srv := msg.Additionals[0].Body.(*dnsmessage.SRVResource)
+	if srv.Target.String() != "pango.local." || srv.Port != 8080 {
+		t.Errorf("SRV = %+v", srv)
+	}
+	if txt := msg.Additionals[1].Body.(*dnsmessage.TXTResource); len(txt.TXT) != 1 || txt.TXT[0] != "path=/" {
+		t.Errorf("TXT = %+v", txt)
+	}
+	if a := msg.Additionals[2].Body.(*dnsmessage.AResource); a.A != [4]byte{192, 168, 1, 20} {
+		t.Errorf("A = %v", a.A)
+	}
+}
+
+func TestAnswer_Questions(t *testing.T) {
+	cases := []struct {
+		name string
+		qn   string
+		qt   dnsmessage.Type
+		want []dnsmessage.Type
+	}{
+		{"host", "pango.local.", dnsmessage.TypeA, []dnsmessage.Type{dnsmessage.TypeA}},
+		{"host_case_insensitive", "PanGo.Local.", dnsmessage.TypeA, []dnsmessage.Type{dnsmessage.TypeA}},
+		{"instance_srv", "PanGo._pango._tcp.local.", dnsmessage.TypeSRV, []dnsmessage.Type{dnsmessage.TypeSRV, dnsmessage.TypeTXT}},
+		{"instance_any", "pango._pango._tcp.local.", dnsmessage.TypeALL, []dnsmessage.Type{dnsmessage.TypeSRV, dnsmessage.TypeTXT}},
+		{"service_types", "_services._dns-sd._udp.local.", dnsmessage.TypePTR, []dnsmessage.Type{dnsmessage.TypePTR}},
+		{"other_host", "printer.local.", dnsmessage.TypeA, nil},
+		{"other_service", "_http._tcp.local.", dnsmessage.TypePTR, nil},
+		{"host_aaaa", "pango.local.", dnsmessage.TypeAAAA, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, _, err := testService().answer(query(t, 0, tc.qn, tc.qt), false)
+			if err != nil {
+				t.Fatalf("answer: %v", err)
+			}
+			if tc.want == nil {
+				if resp != nil {
+					t.Errorf("expected no response, got %+v", parse(t, resp).Answers)
+				}
+				return
+			}
+			if got := types(parse(t, resp).Answers); !equalTypes(got, tc.want) {
+				t.Errorf("answer types = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAnswer_LegacyUnicast(t *testing.T) {
+	resp, unicast, err := testService().answer(query(t, 4242, "pango.local.", dnsmessage.TypeA), true)
+	if err != nil || resp == nil {
+		t.Fatalf("answer = %v, %v", resp, err)
+	}
+	if !unicast {
+		t.Error("legacy queries are answered to the sender")
+	}
+	msg := parse(t, resp)
+	if msg.Header.ID != 4242 || len(msg.Questions) != 1 {
+		t.Errorf("ID = %d, %d questions; want the query's", msg.Header.ID, len(msg.Questions))
+	}
+	a := msg.Answers[0].Header
+	if a.TTL > 10 || a.Class != dnsmessage.ClassINET {
+		t.Errorf("answer header = %+v, want TTL <= 10 and no cache-flush bit", a)
+	}
+}
+
+func TestAnswer_IgnoresResponsesAndGarbage(t *testing.T) {
+	resp, err := testService().announcement(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, _, _ := testService().answer(resp, false); out != nil {
+		t.Error("responses from other hosts must not be answered")
+	}
+	if _, _, err := testService().answer([]byte{1, 2, 3}, false); err == nil {
+		t.Error("expected error for a malformed message")
+	}
+}
+
+// ---------- announcement ----------
+
+func TestAnnouncement(t *testing.T) {
+	data, err := testService().announcement(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := parse(t, data)
+	want := []dnsmessage.Type{dnsmessage.TypePTR, dnsmessage.TypePTR, dnsmessage.TypeSRV, dnsmessage.TypeTXT, dnsmessage.TypeA}
+	if got := types(msg.Answers); !equalTypes(got, want) {
+		t.Fatalf("announced types = %v, want %v", got, want)
+	}
+	if msg.Answers[2].Header.Class != dnsmessage.ClassINET|cacheFlush {
+		t.Error("SRV should carry the cache-flush bit")
+	}
+
+	data, err = testService().announcement(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range parse(t, data).Answers {
+		if r.Header.TTL != 0 {
+			t.Errorf("goodbye %v TTL = %d, want 0", r.Header.Type, r.Header.TTL)
+		}
+	}
+}
+
+// ---------- Validate ----------
+
+func TestService_Validate(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(s *Service)
+	}{
+		{"empty_instance", func(s *Service) { s.Instance = "" }},
+		{"dotted_host", func(s *Service) { s.Host = "pango.local" }},
+		{"bad_type", func(s *Service) { s.Type = "pango" }},
+		{"bad_port", func(s *Service) { s.Port = 0 }},
+	}
+	if err := testService().Validate(); err != nil {
+		t.Fatalf("valid service: %v", err)
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testService()
+			tc.change(&s)
+			if err := s.Validate(); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+// Package metrics is a minimal Prometheus-compatible metrics registry:
+// counters, gauges and histograms written in the text exposition format
+// (version 0.0.4), without the client library and its dependencies.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the exposition format written by Write.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var nameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// Registry holds metric families and writes them in registration order.
+// It is safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+	byName   map[string]*family
+}
+
+// family is a metric name with its help text, type and labelled series.
+type family struct {
+	name   string
+	help   string
+	typ    string // counter, gauge or histogram
+	series []series
+}
+
+type series struct {
+	labels string // rendered label pairs, without braces: axis="pan"
+	write  func(w io.Writer, name, labels string) error
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{byName: make(map[string]*family)}
+}
+
+// register adds a series to the family name, creating it on first use.
+// labels are name/value pairs. It panics on invalid names, a type mismatch
+// or a duplicate series: these are programming errors.
+func (r *Registry) register(name, help, typ string, labels []string, write func(w io.Writer, name, labels string) error) {
+	if !nameRe.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid name %q", name))
+	}
+	rendered := renderLabels(labels)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.byName[name]
+	if f == nil {
+		f = &family{name: name, help: help, typ: typ}
+		r.families = append(r.families, f)
+		r.byName[name] = f
+	}
+	if f.typ != typ {
+		panic(fmt.Sprintf("metrics: %s registered as %s and %s", name, f.typ, typ))
+	}
+	for _, s := range f.series {
+		if s.labels == rendered {
+			panic(fmt.Sprintf("metrics: duplicate series %s{%s}", name, rendered))
+		}
+	}
+	f.series = append(f.series, series{labels: rendered, write: write})
+}
+
+// Counter registers and returns a counter. labels are name/value pairs,
+// e.g. Counter("steps_total", "...", "axis", "pan").
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	c := &Counter{}
+	r.register(name, help, "counter", labels, func(w io.Writer, name, labels string) error {
+		return writeSample(w, name, labels, c.Value())
+	})
+	return c
+}
+
+// CounterFunc registers a counter whose value is read from fn at each
+// scrape. fn must be safe for concurrent use and never decrease.
+func (r *Registry) CounterFunc(name, help string, fn func() float64, labels ...string) {
+	r.register(name, help, "counter", labels, func(w io.Writer, name, labels string) error {
+		return writeSample(w, name, labels, fn())
+	})
+}
+
+// Gauge registers and returns a gauge.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{}
+	r.register(name, help, "gauge", labels, func(w io.Writer, name, labels string) error {
+		return writeSample(w, name, labels, g.Value())
+	})
+	return g
+}
+
+// GaugeFunc registers a gauge whose value is read from fn at each scrape.
+// fn must be safe for concurrent use.
+func (r *Registry) GaugeFunc(name, help string, fn func() float64, labels ...string) {
+	r.register(name, help, "gauge", labels, func(w io.Writer, name, labels string) error {
+		return writeSample(w, name, labels, fn())
+	})
+}
+
+// Histogram registers and returns a histogram with the given bucket upper
+// bounds (sorted, +Inf is implicit).
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	for _, l := range labels {
+		if l == "le" {
+			panic(fmt.Sprintf("metrics: %s: label le is reserved for histograms", name))
+		}
+	}
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metrics: %s: buckets must be sorted", name))
+	}
+	h := &Histogram{bounds: append([]float64(nil), buckets...), counts: make([]uint64, len(buckets))}
+	r.register(name, help, "histogram", labels, h.write)
+	return h
+}
+
+// Write writes all metrics in the text exposition format.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	families := make([]family, len(r.families))
+	for i, f := range r.families {
+		families[i] = *f
+		families[i].series = append([]series(nil), f.series...)
+	}
+	r.mu.Unlock()
+
+	for _, f := range families {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.typ); err != nil {
+			return err
+		}
+		for _, s := range f.series {
+			if err := s.write(w, f.name, s.labels); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Counter is a value that only goes up.
+type Counter struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// Inc adds 1.
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add adds v, which must not be negative.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.mu.Lock()
+	c.v += v
+	c.mu.Unlock()
+}
+
+// Value returns the current count.
+func (c *Counter) Value() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.v
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	mu sync.Mutex
+	v  float64
+}
+
+// Set sets the gauge to v.
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	g.v = v
+	g.mu.Unlock()
+}
+
+// Add adds v (may be negative).
+func (g *Gauge) Add(v float64) {
+	g.mu.Lock()
+	g.v += v
+	g.mu.Unlock()
+}
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.v
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Observe records v.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i := sort.SearchFloat64s(h.bounds, v); i < len(h.bounds) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *Histogram) write(w io.Writer, name, labels string) error {
+	h.mu.Lock()
+	counts := append([]uint64(nil), h.counts...)
+	count, sum := h.count, h.sum
+	h.mu.Unlock()
+
+	var cumulative uint64
+	for i, bound := range h.bounds {
+		cumulative += counts[i]
+		if err := writeSample(w, name+"_bucket", joinLabels(labels, `le="`+formatFloat(bound)+`"`), float64(cumulative)); err != nil {
+			return err
+		}
+	}
+	if err := writeSample(w, name+"_bucket", joinLabels(labels, `le="+Inf"`), float64(count)); err != nil {
+		return err
+	}
+	if err := writeSample(w, name+"_sum", labels, sum); err != nil {
+		return err
+	}
+	return writeSample(w, name+"_count", labels, float64(count))
+}
+
+func writeSample(w io.Writer, name, labels string, v float64) error {
+	if labels != "" {
+		name += "{" + labels + "}"
+	}
+	_, err := fmt.Fprintf(w, "%s %s\n", name, formatFloat(v))
+	return err
+}
+
+// renderLabels renders name/value pairs as a="b",c="d".
+func renderLabels(pairs []string) string {
+	if len(pairs)%2 != 0 {
+		panic("metrics: labels must be name/value pairs")
+	}
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		if !nameRe.MatchString(pairs[i]) || strings.Contains(pairs[i], ":") {
+			panic(fmt.Sprintf("metrics: invalid label name %q", pairs[i]))
+		}
+		parts = append(parts, pairs[i]+`="`+escapeLabel(pairs[i+1])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+func joinLabels(a, b string) string {
+	if a == "" {
+		return b
+	}
+	return a + "," + b
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := r.Write(&b); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return b.String()
+}
+
+// ---------- Write ----------
+
+func TestWrite_CountersAndGauges(t *testing.T) {
+	r := NewRegistry()
+	shots := r.Counter("pango_shots_total", "Shots taken.")
+	pan := r.Counter("pango_steps_total", "Steps moved.", "axis", "pan")
+	r.CounterFunc("pango_steps_total", "Steps moved.", func() float64 { return 40 }, "axis", "tilt")
+	g := r.Gauge("pango_clients", "Connected clients.")
+	r.GaugeFunc("pango_ratio", "A ratio.", func() float64 { return 0.25 })
+
+	shots.Inc()
+	shots.Inc()
+	pan.Add(1200)
+	g.Set(3)
+	g.Add(-1)
+
+	want := `# HELP pango_shots_total Shots taken.
+# TYPE pango_shots_total counter
+pango_shots_total 2
+# HELP pango_steps_total Steps moved.
+# TYPE pango_steps_total counter
+pango_steps_total{axis="pan"} 1200
+pango_steps_total{axis="tilt"} 40
+# HELP pango_clients Connected clients.
+# TYPE pango_clients gauge
+pango_clients 2
+# HELP pango_ratio A ratio.
+# TYPE pango_ratio gauge
+pango_ratio 0.25
+`
+	if got := render(t, r); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWrite_Histogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.Histogram("pango_duration_seconds", "Duration.", []float64{1, 10})
+	for _, v := range []float64{0.5, 1, 5, 30} {
+		h.Observe(v)
+	}
+	want := `# HELP pango_duration_seconds Duration.
+# TYPE pango_duration_seconds histogram
+pango_duration_seconds_bucket{le="1"} 2
+pango_duration_seconds_bucket{le="10"} 3
+pango_duration_seconds_bucket{le="+Inf"} 4
+pango_duration_seconds_sum 36.5
+pango_duration_seconds_count 4
+`
+	if got := render(t, r); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWrite_Escaping(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("x_total", "Line one\nline two \\.", "name", `a "b"`+"\n")
+	got := render(t, r)
+	if !strings.Contains(got, `# HELP x_total Line one\nline two \\.`) {
+		t.Errorf("help not escaped:\n%s", got)
+	}
+	if !strings.Contains(got, `x_total{name="a \"b\"\n"} 0`) {
+		t.Errorf("label not escaped:\n%s", got)
+	}
+}
+
+// ---------- registration errors ----------
+
+func TestRegister_Panics(t *testing.T) {
+	cases := []struct {
+		name string
+		fn   func(r *Registry)
+	}{
+		{"invalid_name", func(r *Registry) { r.Counter("pango-shots", "") }},
+		{"odd_labels", func(r *Registry) { r.Counter("a_total", "", "axis") }},
+		{"invalid_label", func(r *Registry) { r.Counter("a_total", "", "a-xis", "pan") }},
+		{"duplicate", func(r *Registry) { r.Counter("a_total", ""); r.Counter("a_total", "") }},
+		{"type_mismatch", func(r *Registry) { r.Counter("a", ""); r.Gauge("a", "", "x", "y") }},
+		{"unsorted_buckets", func(r *Registry) { r.Histogram("h", "", []float64{2, 1}) }},
+		{"le_label", func(r *Registry) { r.Histogram("h", "", nil, "le", "1") }},
+		{"negative_add", func(r *Registry) { r.Counter("a_total", "").Add(-1) }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			tc.fn(NewRegistry())
+		})
+	}
+}
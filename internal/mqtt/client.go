@@ -0,0 +1,258 @@
+// Package mqtt is a minimal MQTT 3.1.1 client: QoS 0 publish and
+// subscribe, keep-alive, last will and automatic reconnection. It covers
+// what home and studio automation brokers (Mosquitto, Home Assistant) need
+// from the rig, without a client library and its dependencies.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Connection timings.
+const (
+	defaultKeepAlive = 30 * time.Second
+	dialTimeout      = 10 * time.Second
+	minRetryDelay    = time.Second
+	maxRetryDelay    = time.Minute
+)
+
+// ErrNotConnected is returned by Publish while the broker is unreachable.
+// Messages are not queued: QoS 0 delivers at most once.
+var ErrNotConnected = errors.New("mqtt: not connected")
+
+// Message is an application message.
+type Message struct {
+	Topic   string
+	Payload []byte
+	Retain  bool // the broker keeps the last retained message of a topic for new subscribers
+}
+
+// Options configures a Client.
+type Options struct {
+	Broker    string // "tcp://host[:1883]" or "tls://host[:8883]" (also mqtt://, mqtts://, ssl://)
+	ClientID  string
+	Username  string
+	Password  string
+	KeepAlive time.Duration // 0 = 30 s
+	Will      *Message      // published when the connection drops, and by Run on a clean stop; optional
+	Topics    []string      // subscribed on every connection (filters may use + and #)
+	TLS       *tls.Config   // for tls:// brokers; nil = system roots
+}
+
+// Client is a connection to a broker, re-established by Run when it drops.
+type Client struct {
+	opts      Options
+	handler   func(Message)
+	onConnect func()
+
+	mu     sync.Mutex // guards conn and serializes writes
+	conn   net.Conn
+	nextID uint16
+}
+
+// NewClient returns a client for o. handler receives the messages of the
+// subscribed topics, one at a time from the connection's reader: it must
+// not block.
+func NewClient(o Options, handler func(Message)) *Client {
+	if o.KeepAlive == 0 {
+		o.KeepAlive = defaultKeepAlive
+	}
+	return &Client{opts: o, handler: handler}
+}
+
+// OnConnect sets a function called after each connection, e.g. to publish
+// retained state. Set it before Run.
+func (c *Client) OnConnect(fn func()) {
+	c.onConnect = fn
+}
+
+// Connected reports whether the client is connected to the broker.
+func (c *Client) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
+
+// Publish sends m at QoS 0. It fails with ErrNotConnected while the broker
+// is unreachable.
+func (c *Client) Publish(m Message) error {
+	return c.write(publishPacket(m))
+}
+
+func (c *Client) write(p packet) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+	_, err := c.conn.Write(p.encode())
+	return err
+}
+
+// Run connects to the broker and reconnects, with a growing delay, until ctx
+// is cancelled; it then disconnects cleanly. It only returns early for an
+// invalid broker address.
+func (c *Client) Run(ctx context.Context) error {
+	network, addr, useTLS, err := ParseBroker(c.opts.Broker)
+	if err != nil {
+		return err
+	}
+	delay := minRetryDelay
+	for {
+		connected, err := c.session(ctx, network, addr, useTLS)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if connected {
+			delay = minRetryDelay
+		}
+		log.Printf("mqtt: %s: %v; retrying in %v", c.opts.Broker, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// session runs one connection until it fails or ctx is cancelled. connected
+// reports whether the broker accepted it.
+func (c *Client) session(ctx context.Context, network, addr string, useTLS bool) (connected bool, err error) {
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+	var conn net.Conn
+	if useTLS {
+		conn, err = (&tls.Dialer{Config: c.opts.TLS}).DialContext(dialCtx, network, addr)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(dialCtx, network, addr)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	if _, err := conn.Write(connectPacket(c.opts).encode()); err != nil {
+		return false, err
+	}
+	ack, err := readPacket(r)
+	if err != nil {
+		return false, err
+	}
+	if ack.kind() != typeConnack || len(ack.body) != 2 {
+		return false, errors.New("mqtt: expected CONNACK")
+	}
+	if ack.body[1] != 0 {
+		return false, connackError(ack.body[1])
+	}
+	conn.SetDeadline(time.Time{})
+
+	c.mu.Lock()
+	c.conn = conn
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+	}()
+	log.Printf("mqtt: connected to %s", c.opts.Broker)
+
+	if len(c.opts.Topics) > 0 {
+		c.mu.Lock()
+		c.nextID++
+		id := c.nextID
+		c.mu.Unlock()
+		if err := c.write(subscribePacket(id, c.opts.Topics)); err != nil {
+			return true, err
+		}
+	}
+	if c.onConnect != nil {
+		c.onConnect()
+	}
+
+	// Close the connection on cancellation, to unblock the reader. The
+	// broker drops the will on a clean DISCONNECT: publish it first, so that
+	// subscribers see the client leave either way.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(c.opts.KeepAlive / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				c.write(packet{header: typePingreq << 4})
+			case <-ctx.Done():
+				if c.opts.Will != nil {
+					c.write(publishPacket(*c.opts.Will))
+				}
+				c.write(packet{header: typeDisconnect << 4})
+				conn.Close()
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		// The broker answers pings: silence means a dead connection.
+		conn.SetReadDeadline(time.Now().Add(c.opts.KeepAlive * 3 / 2))
+		p, err := readPacket(r)
+		if err != nil {
+			return true, err
+		}
+		switch p.kind() {
+		case typePublish:
+			m, qos, id, err := parsePublish(p)
+			if err != nil {
+				return true, err
+			}
+			if qos == 1 {
+				c.write(packet{header: typePuback << 4, body: []byte{byte(id >> 8), byte(id)}})
+			}
+			if c.handler != nil {
+				c.handler(m)
+			}
+		case typeSuback:
+			for _, code := range p.body[min(2, len(p.body)):] {
+				if code == 0x80 {
+					log.Printf("mqtt: %s refused a subscription to %v", c.opts.Broker, c.opts.Topics)
+					break
+				}
+			}
+		}
+	}
+}
+
+// ParseBroker checks a broker address and returns where to dial it.
+func ParseBroker(broker string) (network, addr string, useTLS bool, err error) {
+	u, err := url.Parse(broker)
+	if err != nil || u.Hostname() == "" || (u.Path != "" && u.Path != "/") {
+		return "", "", false, fmt.Errorf("mqtt broker %q must be scheme://host[:port]", broker)
+	}
+	port := "1883"
+	switch u.Scheme {
+	case "tcp", "mqtt":
+	case "tls", "ssl", "mqtts":
+		useTLS, port = true, "8883"
+	default:
+		return "", "", false, fmt.Errorf("mqtt broker %q: scheme must be tcp, mqtt, tls, ssl or mqtts", broker)
+	}
+	if u.Port() != "" {
+		port = u.Port()
+	}
+	return "tcp", net.JoinHostPort(u.Hostname(), port), useTLS, nil
+}
@@ -0,0 +1,239 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBroker accepts one connection at a time, answers CONNECT with code,
+// SUBSCRIBE with SUBACK and PINGREQ with PINGRESP, and records the other
+// packets it receives.
+type fakeBroker struct {
+	ln   net.Listener
+	code byte
+
+	mu      sync.Mutex
+	conn    net.Conn
+	connect packet
+	topics  []string
+	got     []packet
+}
+
+func newFakeBroker(t *testing.T, code byte) *fakeBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{ln: ln, code: code}
+	t.Cleanup(func() { ln.Close() })
+	go b.serve()
+	return b
+}
+
+func (b *fakeBroker) url() string { return "tcp://" + b.ln.Addr().String() }
+
+func (b *fakeBroker) serve() {
+	for {
+		conn, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		b.session(conn)
+	}
+}
+
+func (b *fakeBroker) session(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	p, err := readPacket(r)
+	if err != nil || p.kind() != typeConnect {
+		return
+	}
+	b.mu.Lock()
+	b.connect = p
+	b.conn = conn
+	b.mu.Unlock()
+	conn.Write(packet{header: typeConnack << 4, body: []byte{0, b.code}}.encode())
+	for {
+		p, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		switch p.kind() {
+		case typeSubscribe:
+			rest := p.body[2:]
+			b.mu.Lock()
+			for len(rest) > 0 {
+				var topic string
+				topic, rest, _ = readString(rest)
+				b.topics = append(b.topics, topic)
+				rest = rest[1:]
+			}
+			b.mu.Unlock()
+			conn.Write(packet{header: typeSuback << 4, body: []byte{p.body[0], p.body[1], 0}}.encode())
+		case typePingreq:
+			conn.Write(packet{header: typePingresp << 4}.encode())
+		default:
+			b.mu.Lock()
+			b.got = append(b.got, p)
+			b.mu.Unlock()
+		}
+	}
+}
+
+// send publishes m to the connected client.
+func (b *fakeBroker) send(m Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.conn.Write(publishPacket(m).encode())
+}
+
+// waitFor polls cond for up to 2 s.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// ---------- Client ----------
+
+func TestClient_PublishSubscribe(t *testing.T) {
+	broker := newFakeBroker(t, 0)
+	received := make(chan Message, 1)
+	c := NewClient(Options{
+		Broker:   broker.url(),
+		ClientID: "pango",
+		Topics:   []string{"pango/cmd/+"},
+		Will:     &Message{Topic: "pango/status", Payload: []byte("offline"), Retain: true},
+	}, func(m Message) { received <- m })
+	connected := make(chan struct{}, 1)
+	c.OnConnect(func() { connected <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	select {
+	case <-connected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("not connected")
+	}
+	if !c.Connected() {
+		t.Error("Connected() = false after OnConnect")
+	}
+	waitFor(t, "subscription", func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.topics) == 1 && broker.topics[0] == "pango/cmd/+"
+	})
+
+	broker.send(Message{Topic: "pango/cmd/stop", Payload: []byte("now")})
+	select {
+	case m := <-received:
+		if m.Topic != "pango/cmd/stop" || string(m.Payload) != "now" {
+			t.Errorf("received %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not received")
+	}
+
+	if err := c.Publish(Message{Topic: "pango/state", Payload: []byte("idle"), Retain: true}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	waitFor(t, "publish", func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.got) == 1
+	})
+
+	// A clean stop publishes the will, then disconnects.
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run: %v", err)
+	}
+	waitFor(t, "disconnect", func() bool {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		return len(broker.got) == 3
+	})
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	m, _, _, _ := parsePublish(broker.got[0])
+	if m.Topic != "pango/state" || !m.Retain {
+		t.Errorf("published %+v, want retained pango/state", m)
+	}
+	if will, _, _, _ := parsePublish(broker.got[1]); will.Topic != "pango/status" || string(will.Payload) != "offline" {
+		t.Errorf("on stop: published %+v, want the will", will)
+	}
+	if broker.got[2].kind() != typeDisconnect {
+		t.Errorf("last packet type = %d, want DISCONNECT", broker.got[2].kind())
+	}
+	if c.Connected() {
+		t.Error("Connected() = true after Run returned")
+	}
+}
+
+func TestClient_Refused(t *testing.T) {
+	broker := newFakeBroker(t, 5)
+	c := NewClient(Options{Broker: broker.url(), ClientID: "pango"}, nil)
+	network, addr, _, _ := ParseBroker(broker.url())
+	connected, err := c.session(context.Background(), network, addr, false)
+	if connected || err == nil || err.Error() != "mqtt: connection refused: not authorized" {
+		t.Errorf("session = %v, %v; want refused", connected, err)
+	}
+	if err := c.Publish(Message{Topic: "x"}); err != ErrNotConnected {
+		t.Errorf("Publish = %v, want ErrNotConnected", err)
+	}
+}
+
+func TestClient_RunInvalidBroker(t *testing.T) {
+	c := NewClient(Options{Broker: "http://broker"}, nil)
+	if err := c.Run(context.Background()); err == nil {
+		t.Error("want an error for an http:// broker")
+	}
+}
+
+// ---------- ParseBroker ----------
+
+func TestParseBroker(t *testing.T) {
+	cases := []struct {
+		broker  string
+		addr    string
+		useTLS  bool
+		wantErr bool
+	}{
+		{"tcp://broker.lan", "broker.lan:1883", false, false},
+		{"mqtt://10.0.0.5:1884", "10.0.0.5:1884", false, false},
+		{"tls://broker.lan", "broker.lan:8883", true, false},
+		{"mqtts://broker.lan:443/", "broker.lan:443", true, false},
+		{"ssl://[::1]", "[::1]:8883", true, false},
+		{"broker.lan:1883", "", false, true},
+		{"ws://broker.lan", "", false, true},
+		{"tcp://broker.lan/topic", "", false, true},
+		{"tcp://", "", false, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.broker, func(t *testing.T) {
+			network, addr, useTLS, err := ParseBroker(tc.broker)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want an error, got %s", addr)
+				}
+				return
+			}
+			if err != nil || network != "tcp" || addr != tc.addr || useTLS != tc.useTLS {
+				t.Errorf("ParseBroker = %s %s %v, %v; want tcp %s %v", network, addr, useTLS, err, tc.addr, tc.useTLS)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Control packet types (MQTT 3.1.1, section 2.2.1).
+const (
+	typeConnect    = 1
+	typeConnack    = 2
+	typePublish    = 3
+	typePuback     = 4
+	typeSubscribe  = 8
+	typeSuback     = 9
+	typePingreq    = 12
+	typePingresp   = 13
+	typeDisconnect = 14
+)
+
+// Maximum size of a packet read from the broker. Commands are small; this
+// only guards against a misbehaving broker.
+const maxPacketBytes = 256 << 10
+
+// packet is a control packet: its fixed header byte and the rest.
+type packet struct {
+	header byte // type << 4 | flags
+	body   []byte
+}
+
+func (p packet) kind() byte { return p.header >> 4 }
+
+// readPacket reads one control packet.
+func readPacket(r *bufio.Reader) (packet, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+	// Remaining length: up to 4 bytes, 7 bits each, least significant first
+	n, shift := 0, 0
+	for i := 0; ; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		n |= int(b&0x7f) << shift
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return packet{}, errors.New("mqtt: malformed remaining length")
+		}
+		shift += 7
+	}
+	if n > maxPacketBytes {
+		return packet{}, fmt.Errorf("mqtt: packet of %d bytes too large", n)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+	return packet{header: header, body: body}, nil
+}
+
+// encode returns the wire form of p.
+func (p packet) encode() []byte {
+	out := []byte{p.header}
+	n := len(p.body)
+	for {
+		b := byte(n & 0x7f)
+		n >>= 7
+		if n > 0 {
+			b |= 0x80
+		}
+		out = append(out, b)
+		if n == 0 {
+			break
+		}
+	}
+	return append(out, p.body...)
+}
+
+// appendString appends s as a length-prefixed UTF-8 string.
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// readString reads a length-prefixed string from b, returning the rest.
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("mqtt: truncated string")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("mqtt: truncated string")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+// connectPacket builds CONNECT with a clean session.
+func connectPacket(o Options) packet {
+	flags := byte(0x02) // clean session
+	if o.Will != nil {
+		flags |= 0x04
+		if o.Will.Retain {
+			flags |= 0x20
+		}
+	}
+	if o.Username != "" {
+		flags |= 0x80
+		if o.Password != "" {
+			flags |= 0x40
+		}
+	}
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // protocol level 4 = 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(o.KeepAlive.Seconds()))
+	body = appendString(body, o.ClientID)
+	if o.Will != nil {
+		body = appendString(body, o.Will.Topic)
+		body = binary.BigEndian.AppendUint16(body, uint16(len(o.Will.Payload)))
+		body = append(body, o.Will.Payload...)
+	}
+	if o.Username != "" {
+		body = appendString(body, o.Username)
+		if o.Password != "" {
+			body = appendString(body, o.Password)
+		}
+	}
+	return packet{header: typeConnect << 4, body: body}
+}
+
+// connackError explains a CONNACK return code.
+func connackError(code byte) error {
+	reasons := map[byte]string{
+		1: "unacceptable protocol version",
+		2: "client identifier rejected",
+		3: "server unavailable",
+		4: "bad user name or password",
+		5: "not authorized",
+	}
+	if reason, ok := reasons[code]; ok {
+		return fmt.Errorf("mqtt: connection refused: %s", reason)
+	}
+	return fmt.Errorf("mqtt: connection refused (code %d)", code)
+}
+
+// publishPacket builds a QoS 0 PUBLISH.
+func publishPacket(m Message) packet {
+	header := byte(typePublish << 4)
+	if m.Retain {
+		header |= 0x01
+	}
+	body := appendString(nil, m.Topic)
+	return packet{header: header, body: append(body, m.Payload...)}
+}
+
+// parsePublish decodes an incoming PUBLISH. id is the packet identifier of
+// QoS 1 and 2 messages, 0 otherwise.
+func parsePublish(p packet) (m Message, qos byte, id uint16, err error) {
+	qos = (p.header >> 1) & 0x03
+	m.Retain = p.header&0x01 != 0
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return Message{}, 0, 0, err
+	}
+	m.Topic = topic
+	if qos > 0 {
+		if len(rest) < 2 {
+			return Message{}, 0, 0, errors.New("mqtt: truncated publish")
+		}
+		id = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	m.Payload = rest
+	return m, qos, id, nil
+}
+
+// subscribePacket builds SUBSCRIBE for topics at QoS 0.
+func subscribePacket(id uint16, topics []string) packet {
+	body := binary.BigEndian.AppendUint16(nil, id)
+	for _, t := range topics {
+		body = appendString(body, t)
+		body = append(body, 0)
+	}
+	return packet{header: typeSubscribe<<4 | 0x02, body: body}
+}
@@ -0,0 +1,104 @@
+package mqtt
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ---------- Encoding ----------
+
+func TestPacket_RemainingLength(t *testing.T) {
+	cases := []struct {
+		size   int
+		header []byte // fixed header after the type byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x80, 0x01}},
+		{16383, []byte{0xff, 0x7f}},
+		{16384, []byte{0x80, 0x80, 0x01}},
+	}
+	for _, tc := range cases {
+		p := packet{header: typePublish << 4, body: make([]byte, tc.size)}
+		wire := p.encode()
+		if !bytes.Equal(wire[1:1+len(tc.header)], tc.header) {
+			t.Errorf("size %d: length bytes % x, want % x", tc.size, wire[1:1+len(tc.header)], tc.header)
+		}
+		got, err := readPacket(bufio.NewReader(bytes.NewReader(wire)))
+		if err != nil || got.header != p.header || len(got.body) != tc.size {
+			t.Errorf("size %d: read back %d bytes, %v", tc.size, len(got.body), err)
+		}
+	}
+}
+
+func TestReadPacket_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		wire []byte
+	}{
+		{"malformed_length", []byte{0x30, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"too_large", []byte{0x30, 0xff, 0xff, 0x7f}},
+		{"truncated", []byte{0x30, 0x05, 'a'}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := readPacket(bufio.NewReader(bytes.NewReader(tc.wire))); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
+
+func TestConnectPacket(t *testing.T) {
+	p := connectPacket(Options{
+		ClientID:  "pango",
+		Username:  "rig",
+		Password:  "secret",
+		KeepAlive: 30 * time.Second,
+		Will:      &Message{Topic: "pango/status", Payload: []byte("offline"), Retain: true},
+	})
+	want := []byte{0, 4, 'M', 'Q', 'T', 'T', 4, 0xe6, 0, 30, 0, 5, 'p', 'a', 'n', 'g', 'o'}
+	if !bytes.HasPrefix(p.body, want) {
+		t.Fatalf("CONNECT variable header = % x, want prefix % x", p.body[:len(want)], want)
+	}
+	rest := p.body[len(want):]
+	for _, s := range []string{"pango/status", "offline", "rig", "secret"} {
+		var got string
+		var err error
+		if got, rest, err = readString(rest); err != nil || got != s {
+			t.Fatalf("payload field = %q, %v; want %q", got, err, s)
+		}
+	}
+}
+
+func TestPublishPacket_RoundTrip(t *testing.T) {
+	m := Message{Topic: "pango/state", Payload: []byte(`{"state":"idle"}`), Retain: true}
+	got, qos, id, err := parsePublish(publishPacket(m))
+	if err != nil || qos != 0 || id != 0 {
+		t.Fatalf("parsePublish: qos %d, id %d, %v", qos, id, err)
+	}
+	if got.Topic != m.Topic || string(got.Payload) != string(m.Payload) || !got.Retain {
+		t.Errorf("round trip = %+v, want %+v", got, m)
+	}
+}
+
+func TestParsePublish_QoS1(t *testing.T) {
+	body := appendString(nil, "pango/cmd/stop")
+	body = append(body, 0x12, 0x34)
+	m, qos, id, err := parsePublish(packet{header: typePublish<<4 | 0x02, body: body})
+	if err != nil || qos != 1 || id != 0x1234 || m.Topic != "pango/cmd/stop" || len(m.Payload) != 0 {
+		t.Errorf("parsePublish = %+v, qos %d, id %#x, %v", m, qos, id, err)
+	}
+}
+
+func TestConnackError(t *testing.T) {
+	if err := connackError(4); !strings.Contains(err.Error(), "bad user name or password") {
+		t.Errorf("code 4: %v", err)
+	}
+	if err := connackError(42); !strings.Contains(err.Error(), "code 42") {
+		t.Errorf("code 42: %v", err)
+	}
+}
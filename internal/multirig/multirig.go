@@ -0,0 +1,142 @@
+// Package multirig coordinates a capture across several PanGo rigs, e.g.
+// the two heads of a stereo panorama rig or rigs shooting a scene from
+// several viewpoints at once. The leader rig starts the same capture on its
+// followers over their HTTP API (POST /sync/run), then, at each cell, waits
+// until every follower is in place (GET /sync/ready) and releases all the
+// shots together (POST /sync/shoot) right before its own.
+package multirig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cjeanneret/PanGo/client"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+// DefaultReadyTimeout is how long the leader waits for a follower to reach
+// a cell when no timeout is configured: long enough for a move and a long
+// exposure at the previous cell.
+const DefaultReadyTimeout = 2 * time.Minute
+
+// Follower is a rig driven by the leader.
+type Follower struct {
+	Name   string // for messages, e.g. its URL
+	Client *client.Client
+}
+
+// Leader drives the followers of one capture. Its methods are called from
+// the capture goroutine, one at a time.
+type Leader struct {
+	followers    []Follower
+	readyTimeout time.Duration
+	shots        int // BeforeShot calls so far
+}
+
+// NewLeader returns a leader for followers; readyTimeout bounds the wait
+// for each follower at each cell (0 = DefaultReadyTimeout).
+func NewLeader(followers []Follower, readyTimeout time.Duration) *Leader {
+	if readyTimeout <= 0 {
+		readyTimeout = DefaultReadyTimeout
+	}
+	return &Leader{followers: followers, readyTimeout: readyTimeout}
+}
+
+// Start checks that every follower plans the same number of shots with o,
+// then starts the capture on each. If one cannot start, those already
+// started are cancelled.
+func (l *Leader) Start(ctx context.Context, o web.Overrides, shots int) error {
+	for _, f := range l.followers {
+		plan, err := f.Client.PlanCapture(ctx, o)
+		if err != nil {
+			return fmt.Errorf("follower %s: plan: %w", f.Name, err)
+		}
+		if plan.Mode != "grid" || len(plan.Panoramas) > 0 || plan.TotalShots != shots {
+			return fmt.Errorf("follower %s plans %d %s shots, this rig %d grid shots: the rigs must share a single grid", f.Name, plan.TotalShots, plan.Mode, shots)
+		}
+	}
+	for i, f := range l.followers {
+		if _, err := f.Client.SyncRun(ctx, o); err != nil {
+			l.cancel(ctx, l.followers[:i])
+			return fmt.Errorf("follower %s: start: %w", f.Name, err)
+		}
+	}
+	l.shots = 0
+	return nil
+}
+
+// BeforeShot waits until every follower is in place at the same shot as
+// this rig, then releases their shots. Its signature matches
+// capture.GridShotParams.BeforeShot.
+func (l *Leader) BeforeShot(ctx context.Context, cell capture.Cell) error {
+	l.shots++
+	want := web.SyncCell{Shot: l.shots, Column: cell.Column, Row: cell.Row}
+
+	readyCtx, cancel := context.WithTimeout(ctx, l.readyTimeout)
+	defer cancel()
+	err := l.each(readyCtx, func(ctx context.Context, f Follower) error {
+		got, err := f.Client.SyncReady(ctx)
+		switch {
+		case err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+			return fmt.Errorf("follower %s not in place for shot %d after %v", f.Name, want.Shot, l.readyTimeout)
+		case err != nil:
+			return fmt.Errorf("follower %s: %w", f.Name, err)
+		case got != want:
+			return fmt.Errorf("follower %s at shot %d (column %d, row %d), this rig at shot %d (column %d, row %d)",
+				f.Name, got.Shot, got.Column, got.Row, want.Shot, want.Column, want.Row)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return l.each(ctx, func(ctx context.Context, f Follower) error {
+		if _, err := f.Client.SyncShoot(ctx, want); err != nil {
+			return fmt.Errorf("follower %s: release shot %d: %w", f.Name, want.Shot, err)
+		}
+		return nil
+	})
+}
+
+// Cancel stops the capture of every follower, e.g. when the leader's
+// capture fails or is cancelled. Errors are ignored: a follower may have
+// stopped already.
+func (l *Leader) Cancel(ctx context.Context) {
+	l.cancel(ctx, l.followers)
+}
+
+func (l *Leader) cancel(ctx context.Context, followers []Follower) {
+	var wg sync.WaitGroup
+	for _, f := range followers {
+		wg.Go(func() { f.Client.CancelCapture(ctx) })
+	}
+	wg.Wait()
+}
+
+// each calls fn for every follower concurrently and returns the first
+// error, cancelling the context of the other calls.
+func (l *Leader) each(ctx context.Context, fn func(context.Context, Follower) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg    sync.WaitGroup
+		once  sync.Once
+		first error
+	)
+	for _, f := range l.followers {
+		wg.Go(func() {
+			if err := fn(ctx, f); err != nil {
+				once.Do(func() {
+					first = err
+					cancel()
+				})
+			}
+		})
+	}
+	wg.Wait()
+	return first
+}
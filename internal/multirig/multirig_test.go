@@ -0,0 +1,180 @@
+package multirig
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/client"
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/web"
+)
+
+var testOverrides = web.Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35}
+
+var testCells = []capture.Cell{{Column: 1, Row: 1}, {Column: 1, Row: 2}, {Column: 2, Row: 1}}
+
+// follower is a PanGo web server whose capture takes cells as a follower
+// and records the shots taken.
+type follower struct {
+	Follower
+	mu      sync.Mutex
+	shots   []capture.Cell
+	started chan struct{} // closed when the capture starts
+	ended   chan error    // capture result
+}
+
+func newFollower(t *testing.T, name string, cells []capture.Cell) *follower {
+	t.Helper()
+	f := &follower{started: make(chan struct{}), ended: make(chan error, 1)}
+	runCapture := func(ctx context.Context, _ web.Overrides) error {
+		close(f.started)
+		err := func() error {
+			gate := web.SyncGateFrom(ctx)
+			if gate == nil {
+				return errors.New("not synchronized")
+			}
+			defer gate.Close()
+			for _, cell := range cells {
+				if err := gate.Wait(ctx, cell); err != nil {
+					return err
+				}
+				f.mu.Lock()
+				f.shots = append(f.shots, cell)
+				f.mu.Unlock()
+			}
+			return nil
+		}()
+		f.ended <- err
+		return err
+	}
+	srv := web.NewServer(":0", web.NewStatusBroadcaster(), runCapture, web.FormConfig{})
+	srv.SetAccessLog(nil)
+	srv.Handlers().Lifecycle = capture.NewLifecycle()
+	srv.Handlers().Plan = func(web.Overrides) (web.Plan, error) {
+		return web.Plan{Mode: "grid", Columns: 2, Rows: 2, TotalShots: len(cells)}, nil
+	}
+	ts := httptest.NewServer(srv.Mux())
+	t.Cleanup(ts.Close)
+	f.Follower = Follower{Name: name, Client: client.New(ts.URL)}
+	return f
+}
+
+func (f *follower) taken() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.shots)
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// ---------- Leader ----------
+
+func TestLeader(t *testing.T) {
+	ctx := testContext(t)
+	a, b := newFollower(t, "a", testCells), newFollower(t, "b", testCells)
+	l := NewLeader([]Follower{a.Follower, b.Follower}, 0)
+
+	if err := l.Start(ctx, testOverrides, len(testCells)); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	for i, cell := range testCells {
+		if err := l.BeforeShot(ctx, cell); err != nil {
+			t.Fatalf("BeforeShot(%+v): %v", cell, err)
+		}
+		// A released follower shoots, then moves to the next cell and
+		// waits there: it never gets ahead of the leader.
+		deadline := time.Now().Add(5 * time.Second)
+		for (a.taken() != i+1 || b.taken() != i+1) && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if a.taken() != i+1 || b.taken() != i+1 {
+			t.Fatalf("after shot %d: followers took %d and %d shots", i+1, a.taken(), b.taken())
+		}
+	}
+	for _, f := range []*follower{a, b} {
+		if err := <-f.ended; err != nil {
+			t.Errorf("follower %s: %v", f.Name, err)
+		}
+	}
+}
+
+func TestLeader_StartPlanMismatch(t *testing.T) {
+	ctx := testContext(t)
+	a, b := newFollower(t, "a", testCells), newFollower(t, "b", testCells[:2])
+	l := NewLeader([]Follower{a.Follower, b.Follower}, 0)
+
+	err := l.Start(ctx, testOverrides, len(testCells))
+	if err == nil || !strings.Contains(err.Error(), "follower b plans 2 grid shots") {
+		t.Fatalf("Start = %v, want the plan mismatch of b", err)
+	}
+	select {
+	case <-a.started:
+		t.Error("a started although b cannot follow")
+	default:
+	}
+}
+
+func TestLeader_CellMismatch(t *testing.T) {
+	ctx := testContext(t)
+	a := newFollower(t, "a", testCells)
+	l := NewLeader([]Follower{a.Follower}, 0)
+	if err := l.Start(ctx, testOverrides, len(testCells)); err != nil {
+		t.Fatal(err)
+	}
+	err := l.BeforeShot(ctx, capture.Cell{Column: 2, Row: 2})
+	if err == nil || !strings.Contains(err.Error(), "follower a at shot 1 (column 1, row 1)") {
+		t.Fatalf("BeforeShot = %v, want a cell mismatch", err)
+	}
+	if a.taken() != 0 {
+		t.Error("follower shot although the cells differ")
+	}
+
+	l.Cancel(ctx)
+	if err := <-a.ended; !errors.Is(err, context.Canceled) {
+		t.Errorf("follower capture = %v, want cancelled", err)
+	}
+}
+
+func TestLeader_FollowerEnded(t *testing.T) {
+	ctx := testContext(t)
+	a := newFollower(t, "a", nil) // ends at once: never ready
+	l := NewLeader([]Follower{a.Follower}, 0)
+	if err := l.Start(ctx, testOverrides, 0); err != nil {
+		t.Fatal(err)
+	}
+	<-a.ended
+	err := l.BeforeShot(ctx, testCells[0])
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusConflict {
+		t.Errorf("BeforeShot = %v, want the 409 of the ended capture", err)
+	}
+}
+
+func TestLeader_ReadyTimeout(t *testing.T) {
+	ctx := testContext(t)
+	// A follower stuck on its way to the cell
+	stuck := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(stuck.Close)
+	l := NewLeader([]Follower{{Name: "stuck", Client: client.New(stuck.URL)}}, 50*time.Millisecond)
+
+	start := time.Now()
+	err := l.BeforeShot(ctx, testCells[0])
+	if err == nil || !strings.Contains(err.Error(), "follower stuck not in place for shot 1 after 50ms") {
+		t.Errorf("BeforeShot = %v, want the ready timeout", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("BeforeShot returned after %v", d)
+	}
+}
@@ -0,0 +1,43 @@
+package onvif
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDuration parses an xs:duration of hours, minutes and seconds, the
+// timeouts of the ONVIF operations, e.g. "PT5S" or "PT1M30.5S". Days and
+// longer are refused.
+func ParseDuration(s string) (time.Duration, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(s), "PT")
+	if !ok || rest == "" {
+		return 0, fmt.Errorf("onvif: unsupported duration %q", s)
+	}
+	var d time.Duration
+	for _, unit := range []struct {
+		suffix byte
+		scale  time.Duration
+	}{{'H', time.Hour}, {'M', time.Minute}, {'S', time.Second}} {
+		i := strings.IndexByte(rest, unit.suffix)
+		if i < 0 {
+			continue
+		}
+		v, err := strconv.ParseFloat(rest[:i], 64)
+		if err != nil || v < 0 || i == 0 {
+			return 0, fmt.Errorf("onvif: invalid duration %q", s)
+		}
+		d += time.Duration(v * float64(unit.scale))
+		rest = rest[i+1:]
+	}
+	if rest != "" {
+		return 0, fmt.Errorf("onvif: invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// FormatDuration formats d as an xs:duration in seconds, e.g. "PT5S".
+func FormatDuration(d time.Duration) string {
+	return "PT" + strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + "S"
+}
@@ -0,0 +1,43 @@
+package onvif
+
+import (
+	"testing"
+	"time"
+)
+
+// ---------- ParseDuration ----------
+
+func TestParseDuration(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"PT5S", 5 * time.Second, false},
+		{"PT0.5S", 500 * time.Millisecond, false},
+		{"PT1M30.5S", 90*time.Second + 500*time.Millisecond, false},
+		{"PT1H", time.Hour, false},
+		{" PT2M ", 2 * time.Minute, false},
+		{"P1D", 0, true},
+		{"PT", 0, true},
+		{"PTS", 0, true},
+		{"PT5", 0, true},
+		{"PT-5S", 0, true},
+		{"5S", 0, true},
+	}
+	for _, tc := range cases {
+		got, err := ParseDuration(tc.in)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v, wantErr %v", tc.in, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	if got := FormatDuration(10 * time.Second); got != "PT10S" {
+		t.Errorf("FormatDuration(10s) = %q", got)
+	}
+	if d, err := ParseDuration(FormatDuration(1500 * time.Millisecond)); err != nil || d != 1500*time.Millisecond {
+		t.Errorf("round trip = %v, %v", d, err)
+	}
+}
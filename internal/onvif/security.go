@@ -0,0 +1,53 @@
+package onvif
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"time"
+)
+
+// MaxClockSkew is how far the creation time of a password digest may be
+// from the clock of the server, which bounds replays.
+const MaxClockSkew = 5 * time.Minute
+
+// UsernameToken is the WS-Security UsernameToken of a request: a user name
+// with its password, in clear text or as a digest of the nonce, the
+// creation time and the password.
+type UsernameToken struct {
+	Username string
+	Password string // clear text, or the base64 digest
+	Digest   bool
+	Nonce    string // base64
+	Created  string // RFC 3339
+}
+
+// Verify reports whether t carries user and password. Digests must have
+// been created within MaxClockSkew of now.
+func (t *UsernameToken) Verify(user, password string, now time.Time) bool {
+	if t == nil || subtle.ConstantTimeCompare([]byte(t.Username), []byte(user)) != 1 {
+		return false
+	}
+	if !t.Digest {
+		return subtle.ConstantTimeCompare([]byte(t.Password), []byte(password)) == 1
+	}
+	created, err := time.Parse(time.RFC3339Nano, t.Created)
+	if err != nil || created.Sub(now).Abs() > MaxClockSkew {
+		return false
+	}
+	nonce, err := base64.StdEncoding.DecodeString(t.Nonce)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(t.Password), []byte(PasswordDigest(nonce, t.Created, password))) == 1
+}
+
+// PasswordDigest returns the digest of password for a UsernameToken:
+// base64(SHA-1(nonce + created + password)).
+func PasswordDigest(nonce []byte, created, password string) string {
+	h := sha1.New()
+	h.Write(nonce)
+	h.Write([]byte(created))
+	h.Write([]byte(password))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,57 @@
+package onvif
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+// ---------- PasswordDigest ----------
+
+func TestPasswordDigest(t *testing.T) {
+	// Example of the ONVIF Application Programmer's Guide.
+	nonce, _ := base64.StdEncoding.DecodeString("LKqI6G/AikKCQrN0zqZFlg==")
+	if got, want := PasswordDigest(nonce, "2010-09-16T07:50:45Z", "userpassword"), "tuOSpGlFlIXsozq4HFNeeGeFLEI="; got != want {
+		t.Errorf("PasswordDigest = %q, want %q", got, want)
+	}
+}
+
+// ---------- Verify ----------
+
+func TestUsernameToken_Verify(t *testing.T) {
+	created := "2010-09-16T07:50:45Z"
+	now, _ := time.Parse(time.RFC3339, created)
+	digest := &UsernameToken{
+		Username: "admin",
+		Password: "tuOSpGlFlIXsozq4HFNeeGeFLEI=",
+		Digest:   true,
+		Nonce:    "LKqI6G/AikKCQrN0zqZFlg==",
+		Created:  created,
+	}
+	text := &UsernameToken{Username: "admin", Password: "userpassword"}
+
+	cases := []struct {
+		name     string
+		token    *UsernameToken
+		user     string
+		password string
+		now      time.Time
+		want     bool
+	}{
+		{"digest", digest, "admin", "userpassword", now, true},
+		{"digest_skewed", digest, "admin", "userpassword", now.Add(-MaxClockSkew + time.Second), true},
+		{"digest_wrong_password", digest, "admin", "other", now, false},
+		{"digest_wrong_user", digest, "root", "userpassword", now, false},
+		{"digest_replayed", digest, "admin", "userpassword", now.Add(MaxClockSkew + time.Second), false},
+		{"text", text, "admin", "userpassword", now, true},
+		{"text_wrong_password", text, "admin", "other", now, false},
+		{"none", nil, "admin", "userpassword", now, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.token.Verify(tc.user, tc.password, tc.now); got != tc.want {
+				t.Errorf("Verify = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
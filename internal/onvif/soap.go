@@ -0,0 +1,168 @@
+// Package onvif implements the SOAP layer of ONVIF services: the envelopes
+// of requests and responses, faults, WS-Security UsernameToken
+// authentication and the durations of the schemas. The services themselves
+// (device, media, PTZ) are served by package web; ONVIF clients (video
+// management software, camera apps) speak SOAP 1.2 over HTTP POST.
+package onvif
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MaxRequestBytes bounds the size of a request envelope.
+const MaxRequestBytes = 64 << 10
+
+// Namespaces of the ONVIF services and schemas, bound to the prefixes the
+// responses use.
+const (
+	NamespaceDevice = "http://www.onvif.org/ver10/device/wsdl" // tds
+	NamespaceMedia  = "http://www.onvif.org/ver10/media/wsdl"  // trt
+	NamespacePTZ    = "http://www.onvif.org/ver20/ptz/wsdl"    // tptz
+	NamespaceSchema = "http://www.onvif.org/ver10/schema"      // tt
+	NamespaceError  = "http://www.onvif.org/ver10/error"       // ter
+	NamespaceSOAP   = "http://www.w3.org/2003/05/soap-envelope"
+)
+
+// Request is a SOAP request: the operation called, the name of the body
+// element (e.g. {NamespacePTZ ContinuousMove}), its content and the
+// credentials of its security header.
+type Request struct {
+	Action xml.Name
+	Body   []byte // content of the body element, see Decode
+	Token  *UsernameToken
+}
+
+// envelope is a SOAP envelope as read. Names match in any namespace.
+type envelope struct {
+	Header struct {
+		Security struct {
+			UsernameToken *struct {
+				Username string `xml:"Username"`
+				Password struct {
+					Type  string `xml:"Type,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"Password"`
+				Nonce   string `xml:"Nonce"`
+				Created string `xml:"Created"`
+			} `xml:"UsernameToken"`
+		} `xml:"Security"`
+	} `xml:"Header"`
+	Body struct {
+		Operation *struct {
+			XMLName xml.Name
+			Inner   []byte `xml:",innerxml"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
+
+// ReadRequest reads a SOAP envelope from r.
+func ReadRequest(r io.Reader) (*Request, error) {
+	var env envelope
+	if err := xml.NewDecoder(io.LimitReader(r, MaxRequestBytes)).Decode(&env); err != nil {
+		return nil, fmt.Errorf("onvif: invalid envelope: %w", err)
+	}
+	op := env.Body.Operation
+	if op == nil {
+		return nil, errors.New("onvif: empty body")
+	}
+	req := &Request{Action: op.XMLName, Body: op.Inner}
+	if t := env.Header.Security.UsernameToken; t != nil {
+		req.Token = &UsernameToken{
+			Username: strings.TrimSpace(t.Username),
+			Password: strings.TrimSpace(t.Password.Value),
+			Digest:   strings.HasSuffix(t.Password.Type, "#PasswordDigest"),
+			Nonce:    strings.TrimSpace(t.Nonce),
+			Created:  strings.TrimSpace(t.Created),
+		}
+	}
+	return req, nil
+}
+
+// Decode decodes the content of the body element into v, whose field tags
+// name the elements without their namespace.
+func (r *Request) Decode(v any) error {
+	data := append(append([]byte("<body>"), r.Body...), "</body>"...)
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("onvif: invalid %s: %w", r.Action.Local, err)
+	}
+	return nil
+}
+
+// envelopeStart opens the envelopes of the responses, with the prefixes of
+// the namespaces.
+const envelopeStart = `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+	`<s:Envelope xmlns:s="` + NamespaceSOAP + `" xmlns:tds="` + NamespaceDevice + `" xmlns:trt="` + NamespaceMedia +
+	`" xmlns:tptz="` + NamespacePTZ + `" xmlns:tt="` + NamespaceSchema + `" xmlns:ter="` + NamespaceError + `">` + "\n" +
+	"<s:Body>\n"
+
+const envelopeEnd = "</s:Body>\n</s:Envelope>\n"
+
+// WriteResponse writes a response envelope holding body, XML with the
+// prefixes tds, trt, tptz and tt.
+func WriteResponse(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	io.WriteString(w, envelopeStart+body+"\n"+envelopeEnd)
+}
+
+// Fault is a SOAP fault: its code (Sender or Receiver) and ONVIF subcode,
+// e.g. ter:ActionNotSupported, and a reason for people.
+type Fault struct {
+	Code    string
+	Subcode string
+	Reason  string
+}
+
+func (f *Fault) Error() string {
+	return f.Reason
+}
+
+// Faults of the ONVIF core specification.
+var (
+	ErrNotAuthorized      = &Fault{"Sender", "ter:NotAuthorized", "Sender not authorized"}
+	ErrActionNotSupported = &Fault{"Receiver", "ter:ActionNotSupported", "Optional action not implemented"}
+)
+
+// InvalidArgs returns the fault of a request with invalid arguments.
+func InvalidArgs(reason string) *Fault {
+	return &Fault{"Sender", "ter:InvalidArgVal", reason}
+}
+
+// Prohibited returns the fault of an operation this client may not call
+// now, e.g. while another one controls the device.
+func Prohibited(reason string) *Fault {
+	return &Fault{"Sender", "ter:OperationProhibited", reason}
+}
+
+// ActionFailed returns the fault of an operation the device could not
+// carry out, e.g. a move while the head is busy.
+func ActionFailed(reason string) *Fault {
+	return &Fault{"Receiver", "ter:Action", reason}
+}
+
+// WriteFault writes f: with status 400 for Sender faults (401 for
+// ter:NotAuthorized), 500 for Receiver ones, as SOAP 1.2 over HTTP has it.
+func WriteFault(w http.ResponseWriter, f *Fault) {
+	status := http.StatusInternalServerError
+	switch {
+	case f.Subcode == ErrNotAuthorized.Subcode:
+		status = http.StatusUnauthorized
+	case f.Code == "Sender":
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	w.WriteHeader(status)
+	io.WriteString(w, envelopeStart+fmt.Sprintf(`<s:Fault><s:Code><s:Value>s:%s</s:Value><s:Subcode><s:Value>%s</s:Value></s:Subcode></s:Code><s:Reason><s:Text xml:lang="en">%s</s:Text></s:Reason></s:Fault>`,
+		f.Code, f.Subcode, Escape(f.Reason))+"\n"+envelopeEnd)
+}
+
+// Escape escapes s for XML text and attribute values.
+func Escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
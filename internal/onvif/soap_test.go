@@ -0,0 +1,133 @@
+package onvif
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const continuousMove = `<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl" xmlns:tt="http://www.onvif.org/ver10/schema">
+  <s:Header>
+    <Security xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">
+      <UsernameToken>
+        <Username>admin</Username>
+        <Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest">tuOSpGlFlIXsozq4HFNeeGeFLEI=</Password>
+        <Nonce>LKqI6G/AikKCQrN0zqZFlg==</Nonce>
+        <Created xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">2010-09-16T07:50:45Z</Created>
+      </UsernameToken>
+    </Security>
+  </s:Header>
+  <s:Body>
+    <tptz:ContinuousMove>
+      <tptz:ProfileToken>pango</tptz:ProfileToken>
+      <tptz:Velocity><tt:PanTilt x="0.5" y="-1"/></tptz:Velocity>
+      <tptz:Timeout>PT5S</tptz:Timeout>
+    </tptz:ContinuousMove>
+  </s:Body>
+</s:Envelope>`
+
+// ---------- ReadRequest ----------
+
+func TestReadRequest(t *testing.T) {
+	req, err := ReadRequest(strings.NewReader(continuousMove))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Action.Space != NamespacePTZ || req.Action.Local != "ContinuousMove" {
+		t.Errorf("Action = %v, want {%s ContinuousMove}", req.Action, NamespacePTZ)
+	}
+	tok := req.Token
+	if tok == nil {
+		t.Fatal("no UsernameToken")
+	}
+	if tok.Username != "admin" || !tok.Digest || tok.Nonce != "LKqI6G/AikKCQrN0zqZFlg==" || tok.Created != "2010-09-16T07:50:45Z" {
+		t.Errorf("Token = %+v", tok)
+	}
+
+	var v struct {
+		ProfileToken string `xml:"ProfileToken"`
+		Velocity     struct {
+			PanTilt struct {
+				X float64 `xml:"x,attr"`
+				Y float64 `xml:"y,attr"`
+			} `xml:"PanTilt"`
+		} `xml:"Velocity"`
+		Timeout string `xml:"Timeout"`
+	}
+	if err := req.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ProfileToken != "pango" || v.Velocity.PanTilt.X != 0.5 || v.Velocity.PanTilt.Y != -1 || v.Timeout != "PT5S" {
+		t.Errorf("decoded %+v", v)
+	}
+}
+
+func TestReadRequest_Invalid(t *testing.T) {
+	for _, body := range []string{
+		"",
+		"not xml",
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body></s:Body></s:Envelope>`,
+	} {
+		if _, err := ReadRequest(strings.NewReader(body)); err == nil {
+			t.Errorf("ReadRequest(%q): no error", body)
+		}
+	}
+}
+
+func TestReadRequest_NoSecurity(t *testing.T) {
+	req, err := ReadRequest(strings.NewReader(`<Envelope xmlns="http://www.w3.org/2003/05/soap-envelope"><Body><GetSystemDateAndTime xmlns="http://www.onvif.org/ver10/device/wsdl"/></Body></Envelope>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Action.Local != "GetSystemDateAndTime" || req.Token != nil {
+		t.Errorf("request = %+v", req)
+	}
+}
+
+// ---------- WriteResponse / WriteFault ----------
+
+func TestWriteResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteResponse(rec, "<tptz:StopResponse/>")
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/soap+xml") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<s:Body>\n<tptz:StopResponse/>") {
+		t.Errorf("%d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestWriteFault(t *testing.T) {
+	cases := []struct {
+		fault *Fault
+		want  int
+	}{
+		{ErrNotAuthorized, http.StatusUnauthorized},
+		{InvalidArgs("bad <token>"), http.StatusBadRequest},
+		{Prohibited("control claimed"), http.StatusBadRequest},
+		{ErrActionNotSupported, http.StatusInternalServerError},
+		{ActionFailed("head is busy"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		WriteFault(rec, tc.fault)
+		body := rec.Body.String()
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.fault.Subcode, rec.Code, tc.want)
+		}
+		if !strings.Contains(body, "<s:Value>s:"+tc.fault.Code+"</s:Value>") || !strings.Contains(body, "<s:Value>"+tc.fault.Subcode+"</s:Value>") {
+			t.Errorf("%s: body %s", tc.fault.Subcode, body)
+		}
+		if !strings.Contains(body, Escape(tc.fault.Reason)) {
+			t.Errorf("%s: reason missing from %s", tc.fault.Subcode, body)
+		}
+	}
+	// The response is a valid envelope.
+	rec := httptest.NewRecorder()
+	WriteFault(rec, InvalidArgs("bad <token>"))
+	if _, err := ReadRequest(rec.Body); err != nil {
+		t.Errorf("fault envelope: %v", err)
+	}
+}
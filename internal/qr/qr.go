@@ -0,0 +1,551 @@
+// Package qr encodes short texts, such as the URL of the web interface, as
+// QR codes (ISO/IEC 18004) and renders them as PNG, SVG or terminal text.
+// Only what a URL needs is implemented: byte mode, error correction level M
+// and versions 1 to 10 (up to 213 bytes).
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// MaxVersion is the largest symbol version Encode produces (57×57 modules).
+const MaxVersion = 10
+
+// quietZone is the light margin around the symbol, in modules (ISO/IEC
+// 18004 §6.3.8).
+const quietZone = 4
+
+// ErrTooLong is returned for texts that do not fit in a version 10 symbol.
+var ErrTooLong = errors.New("qr: text too long")
+
+// Error correction level M: format bits and, per version, the error
+// correction codewords per block and the data codewords of each block
+// (ISO/IEC 18004 table 9).
+const levelMBits = 0b00
+
+var blocksM = [MaxVersion + 1]struct {
+	ecPerBlock int
+	data       []int
+}{
+	1:  {10, []int{16}},
+	2:  {16, []int{28}},
+	3:  {26, []int{44}},
+	4:  {18, []int{32, 32}},
+	5:  {24, []int{43, 43}},
+	6:  {16, []int{27, 27, 27, 27}},
+	7:  {18, []int{31, 31, 31, 31}},
+	8:  {22, []int{38, 38, 39, 39}},
+	9:  {22, []int{36, 36, 36, 37, 37}},
+	10: {26, []int{43, 43, 43, 43, 44}},
+}
+
+// alignmentCenters are the row and column coordinates of the alignment
+// patterns (ISO/IEC 18004 annex E).
+var alignmentCenters = [MaxVersion + 1][]int{
+	2:  {6, 18},
+	3:  {6, 22},
+	4:  {6, 26},
+	5:  {6, 30},
+	6:  {6, 34},
+	7:  {6, 22, 38},
+	8:  {6, 24, 42},
+	9:  {6, 26, 46},
+	10: {6, 28, 50},
+}
+
+// Code is an encoded QR symbol.
+type Code struct {
+	Version  int
+	Size     int // modules per side, without the quiet zone
+	modules  [][]bool
+	function [][]bool // finder, timing, alignment, format and version modules
+}
+
+// Encode returns the smallest symbol holding text.
+func Encode(text string) (*Code, error) {
+	version := 0
+	for v := 1; v <= MaxVersion; v++ {
+		if byteModeBits(v, len(text)) <= 8*dataCapacity(v) {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLong, len(text))
+	}
+
+	c := newCode(version)
+	c.placeCodewords(c.codewords([]byte(text)))
+	best, bestPenalty := 0, -1
+	for mask := range 8 {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // undo: masks are XOR
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+	return c, nil
+}
+
+// Dark reports whether the module at column x, row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+func dataCapacity(version int) int {
+	n := 0
+	for _, d := range blocksM[version].data {
+		n += d
+	}
+	return n
+}
+
+func countBits(version int) int {
+	if version < 10 {
+		return 8
+	}
+	return 16
+}
+
+func byteModeBits(version, n int) int {
+	return 4 + countBits(version) + 8*n
+}
+
+func newCode(version int) *Code {
+	size := 17 + 4*version
+	c := &Code{Version: version, Size: size, modules: grid(size), function: grid(size)}
+
+	for i := range size {
+		c.setFunction(6, i, i%2 == 0)
+		c.setFunction(i, 6, i%2 == 0)
+	}
+	c.drawFinder(3, 3)
+	c.drawFinder(size-4, 3)
+	c.drawFinder(3, size-4)
+	centers := alignmentCenters[version]
+	last := len(centers) - 1
+	for i, x := range centers {
+		for j, y := range centers {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue // overlaps a finder pattern
+			}
+			c.drawAlignment(x, y)
+		}
+	}
+	c.drawFormat(0) // reserves the format modules
+	c.drawVersion()
+	return c
+}
+
+func grid(size int) [][]bool {
+	g := make([][]bool, size)
+	for i := range g {
+		g[i] = make([]bool, size)
+	}
+	return g
+}
+
+func (c *Code) setFunction(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+// drawFinder draws a finder pattern centered at x, y with its separator.
+func (c *Code) drawFinder(x, y int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			xx, yy := x+dx, y+dy
+			if xx < 0 || xx >= c.Size || yy < 0 || yy >= c.Size {
+				continue
+			}
+			d := max(abs(dx), abs(dy))
+			c.setFunction(xx, yy, d != 2 && d != 4)
+		}
+	}
+}
+
+func (c *Code) drawAlignment(x, y int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			c.setFunction(x+dx, y+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+// formatBits returns the 15 format bits of mask: level and mask with a
+// BCH(15,5) code, XORed with 101010000010010.
+func formatBits(mask int) int {
+	data := levelMBits<<3 | mask
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionBits returns the 18 version bits, with a BCH(18,6) code.
+func versionBits(version int) int {
+	rem := version
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1f25
+	}
+	return version<<12 | rem
+}
+
+func bit(v, i int) bool {
+	return v>>i&1 != 0
+}
+
+// drawFormat draws both copies of the format bits of mask.
+func (c *Code) drawFormat(mask int) {
+	bits := formatBits(mask)
+	for i := range 6 {
+		c.setFunction(8, i, bit(bits, i))
+	}
+	c.setFunction(8, 7, bit(bits, 6))
+	c.setFunction(8, 8, bit(bits, 7))
+	c.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		c.setFunction(14-i, 8, bit(bits, i))
+	}
+	for i := range 8 {
+		c.setFunction(c.Size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		c.setFunction(8, c.Size-15+i, bit(bits, i))
+	}
+	c.setFunction(8, c.Size-8, true) // dark module
+}
+
+// drawVersion draws both copies of the version bits (version 7 and up).
+func (c *Code) drawVersion() {
+	if c.Version < 7 {
+		return
+	}
+	bits := versionBits(c.Version)
+	for i := range 18 {
+		a, b := c.Size-11+i%3, i/3
+		c.setFunction(a, b, bit(bits, i))
+		c.setFunction(b, a, bit(bits, i))
+	}
+}
+
+// codewords returns the data in byte mode, padded, split in blocks with
+// their error correction codewords, and interleaved.
+func (c *Code) codewords(data []byte) []byte {
+	capacity := dataCapacity(c.Version)
+	var w bitWriter
+	w.write(0b0100, 4) // byte mode
+	w.write(len(data), countBits(c.Version))
+	for _, b := range data {
+		w.write(int(b), 8)
+	}
+	w.write(0, min(4, 8*capacity-w.n)) // terminator
+	w.write(0, (8-w.n%8)%8)
+	for pad := 0; len(w.buf) < capacity; pad++ {
+		w.write([]int{0xec, 0x11}[pad%2], 8)
+	}
+
+	layout := blocksM[c.Version]
+	divisor := rsDivisor(layout.ecPerBlock)
+	var blocks, ecc [][]byte
+	for _, n := range layout.data {
+		blocks = append(blocks, w.buf[:n])
+		ecc = append(ecc, rsRemainder(w.buf[:n], divisor))
+		w.buf = w.buf[n:]
+	}
+	var out []byte
+	for i := range layout.data[len(layout.data)-1] {
+		for _, b := range blocks {
+			if i < len(b) {
+				out = append(out, b[i])
+			}
+		}
+	}
+	for i := range layout.ecPerBlock {
+		for _, e := range ecc {
+			out = append(out, e[i])
+		}
+	}
+	return out
+}
+
+// bitWriter appends bits, most significant first.
+type bitWriter struct {
+	buf []byte
+	n   int // bits written
+}
+
+func (w *bitWriter) write(v, bits int) {
+	for i := bits - 1; i >= 0; i-- {
+		if w.n%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		if bit(v, i) {
+			w.buf[len(w.buf)-1] |= 0x80 >> (w.n % 8)
+		}
+		w.n++
+	}
+}
+
+// gfMul multiplies in GF(2^8) modulo x^8+x^4+x^3+x^2+1.
+func gfMul(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = z<<1 ^ (z>>7)*0x11d
+		if bit(int(y), i) {
+			z ^= int(x)
+		}
+	}
+	return byte(z)
+}
+
+// rsDivisor returns the Reed-Solomon generator polynomial of the given
+// degree, highest coefficient (1) omitted.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 2)
+	}
+	return result
+}
+
+// rsRemainder returns the error correction codewords of data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// placeCodewords fills the non-function modules in the zigzag order of
+// ISO/IEC 18004 §7.7.3, two columns at a time from the bottom right.
+func (c *Code) placeCodewords(data []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.function[y][x] || i >= 8*len(data) {
+					continue
+				}
+				c.modules[y][x] = bit(int(data[i/8]), 7-i%8)
+				i++
+			}
+		}
+	}
+}
+
+func maskBit(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+func (c *Code) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.function[y][x] && maskBit(mask, x, y) {
+				c.modules[y][x] = !c.modules[y][x]
+			}
+		}
+	}
+}
+
+// Finder-like patterns penalized by rule 3, light margin on either side.
+var (
+	finderBefore = []bool{false, false, false, false, true, false, true, true, true, false, true}
+	finderAfter  = []bool{true, false, true, true, true, false, true, false, false, false, false}
+)
+
+// penalty scores the symbol with the four rules of ISO/IEC 18004 §7.8.3;
+// the mask with the lowest score is kept.
+func (c *Code) penalty() int {
+	p := 0
+	dark := 0
+	for i := range c.Size {
+		row := make([]bool, c.Size)
+		col := make([]bool, c.Size)
+		for j := range c.Size {
+			row[j], col[j] = c.modules[i][j], c.modules[j][i]
+			if row[j] {
+				dark++
+			}
+		}
+		p += runPenalty(row) + runPenalty(col)
+		p += 40 * (patternCount(row, finderBefore) + patternCount(row, finderAfter))
+		p += 40 * (patternCount(col, finderBefore) + patternCount(col, finderAfter))
+	}
+	for y := range c.Size - 1 {
+		for x := range c.Size - 1 {
+			m := c.modules[y][x]
+			if m == c.modules[y][x+1] && m == c.modules[y+1][x] && m == c.modules[y+1][x+1] {
+				p += 3
+			}
+		}
+	}
+	total := c.Size * c.Size
+	return p + 10*(abs(dark*100/total-50)/5)
+}
+
+// runPenalty scores runs of five or more modules of the same color.
+func runPenalty(line []bool) int {
+	p, run := 0, 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			p += run - 2
+		}
+		run = 1
+	}
+	return p
+}
+
+func patternCount(line, pattern []bool) int {
+	n := 0
+	for i := 0; i+len(pattern) <= len(line); i++ {
+		match := true
+		for j, v := range pattern {
+			if line[i+j] != v {
+				match = false
+				break
+			}
+		}
+		if match {
+			n++
+		}
+	}
+	return n
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// PNG renders the symbol with its quiet zone, scale pixels per module.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	side := (c.Size + 2*quietZone) * scale
+	img := image.NewPaletted(image.Rect(0, 0, side, side), color.Palette{color.White, color.Black})
+	for y := range c.Size {
+		for x := range c.Size {
+			if !c.modules[y][x] {
+				continue
+			}
+			for dy := range scale {
+				for dx := range scale {
+					img.SetColorIndex((quietZone+x)*scale+dx, (quietZone+y)*scale+dy, 1)
+				}
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the symbol with its quiet zone as a scalable image, one unit
+// per module.
+func (c *Code) SVG() string {
+	side := c.Size + 2*quietZone
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, side, side)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, side, side)
+	for y := range c.Size {
+		for x := 0; x < c.Size; x++ {
+			if !c.modules[y][x] {
+				continue
+			}
+			start := x
+			for x+1 < c.Size && c.modules[y][x+1] {
+				x++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", quietZone+start, quietZone+y, x-start+1, x-start+1)
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.String()
+}
+
+// Terminal renders the symbol as text, two rows of modules per line with
+// half-block characters. Light modules are drawn, so that the code reads on
+// a dark terminal background.
+func (c *Code) Terminal() string {
+	const margin = 2
+	light := func(x, y int) bool {
+		x, y = x-margin, y-margin
+		return x < 0 || y < 0 || x >= c.Size || y >= c.Size || !c.modules[y][x]
+	}
+	side := c.Size + 2*margin
+	var b strings.Builder
+	for y := 0; y < side; y += 2 {
+		for x := range side {
+			top, bottom := light(x, y), y+1 < side && light(x, y+1)
+			switch {
+			case top && bottom:
+				b.WriteString("█")
+			case top:
+				b.WriteString("▀")
+			case bottom:
+				b.WriteString("▄")
+			default:
+				b.WriteString(" ")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
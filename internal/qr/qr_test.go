@@ -0,0 +1,249 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// ---------- Building blocks ----------
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" at 1-M, ISO/IEC 18004 annex I
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v, want %v", got, want)
+	}
+}
+
+func TestFormatBits(t *testing.T) {
+	// Level M rows of the format information table (ISO/IEC 18004 annex C)
+	want := []int{
+		0b101010000010010, 0b101000100100101, 0b101111001111100, 0b101101101001011,
+		0b100010111111001, 0b100000011001110, 0b100111110010111, 0b100101010100000,
+	}
+	for mask, w := range want {
+		if got := formatBits(mask); got != w {
+			t.Errorf("formatBits(%d) = %015b, want %015b", mask, got, w)
+		}
+	}
+}
+
+func TestVersionBits(t *testing.T) {
+	cases := map[int]int{7: 0b000111110010010100, 10: 0b001010010011010011}
+	for version, want := range cases {
+		if got := versionBits(version); got != want {
+			t.Errorf("versionBits(%d) = %018b, want %018b", version, got, want)
+		}
+	}
+}
+
+func TestBlockTable(t *testing.T) {
+	// Codewords per version (ISO/IEC 18004 table 1): data + error correction
+	// fill the symbol.
+	total := []int{0, 26, 44, 70, 100, 134, 172, 196, 242, 292, 346}
+	for v := 1; v <= MaxVersion; v++ {
+		l := blocksM[v]
+		if got := dataCapacity(v) + l.ecPerBlock*len(l.data); got != total[v] {
+			t.Errorf("version %d: %d codewords, want %d", v, got, total[v])
+		}
+		c := newCode(v)
+		free := 0
+		for y := range c.Size {
+			for x := range c.Size {
+				if !c.function[y][x] {
+					free++
+				}
+			}
+		}
+		if free/8 != total[v] {
+			t.Errorf("version %d: %d data modules hold %d codewords, want %d", v, free, free/8, total[v])
+		}
+	}
+}
+
+// ---------- Encode ----------
+
+// decode reads a symbol back: format, mask, codewords, error correction
+// and byte-mode text.
+func decode(t *testing.T, c *Code) string {
+	t.Helper()
+	var format int
+	for i := range 6 {
+		if c.Dark(8, i) {
+			format |= 1 << i
+		}
+	}
+	for i, p := range [][2]int{{8, 7}, {8, 8}, {7, 8}} {
+		if c.Dark(p[0], p[1]) {
+			format |= 1 << (6 + i)
+		}
+	}
+	for i := 9; i < 15; i++ {
+		if c.Dark(14-i, 8) {
+			format |= 1 << i
+		}
+	}
+	mask := -1
+	for m := range 8 {
+		if formatBits(m) == format {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		t.Fatalf("format bits %015b are not level M", format)
+	}
+
+	ref := newCode(c.Version)
+	var w bitWriter
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if ref.function[y][x] {
+					continue
+				}
+				v := 0
+				if c.Dark(x, y) != maskBit(mask, x, y) {
+					v = 1
+				}
+				w.write(v, 1)
+			}
+		}
+	}
+	raw := w.buf
+
+	layout := blocksM[c.Version]
+	blocks := make([][]byte, len(layout.data))
+	i := 0
+	for col := range layout.data[len(layout.data)-1] {
+		for b, n := range layout.data {
+			if col < n {
+				blocks[b] = append(blocks[b], raw[i])
+				i++
+			}
+		}
+	}
+	ecc := make([][]byte, len(layout.data))
+	for range layout.ecPerBlock {
+		for b := range ecc {
+			ecc[b] = append(ecc[b], raw[i])
+			i++
+		}
+	}
+	var data []byte
+	for b := range blocks {
+		if got := rsRemainder(blocks[b], rsDivisor(layout.ecPerBlock)); !bytes.Equal(got, ecc[b]) {
+			t.Fatalf("block %d: error correction codewords do not match", b)
+		}
+		data = append(data, blocks[b]...)
+	}
+
+	if data[0]>>4 != 0b0100 {
+		t.Fatalf("mode %04b, want byte mode", data[0]>>4)
+	}
+	var n, start int
+	if c.Version < 10 {
+		n, start = int(data[0]&0xf)<<4|int(data[1]>>4), 1
+	} else {
+		n, start = int(data[0]&0xf)<<12|int(data[1])<<4|int(data[2]>>4), 2
+	}
+	text := make([]byte, n)
+	for k := range n {
+		text[k] = data[start+k]<<4 | data[start+k+1]>>4
+	}
+	return string(text)
+}
+
+func TestEncode(t *testing.T) {
+	cases := []struct {
+		text    string
+		version int
+	}{
+		{"http://pango.local:8080/", 2},
+		{"https://192.168.1.20:8443/pango/?token=0123456789abcdef", 4},
+		{"http://" + strings.Repeat("a", 150), 9},
+		{"http://" + strings.Repeat("b", 200), 10},
+	}
+	for _, tc := range cases {
+		c, err := Encode(tc.text)
+		if err != nil {
+			t.Fatalf("Encode(%q): %v", tc.text, err)
+		}
+		if c.Version != tc.version || c.Size != 17+4*tc.version {
+			t.Errorf("%d bytes: version %d, size %d; want version %d", len(tc.text), c.Version, c.Size, tc.version)
+		}
+		if got := decode(t, c); got != tc.text {
+			t.Errorf("decoded %q, want %q", got, tc.text)
+		}
+	}
+}
+
+func TestEncode_TooLong(t *testing.T) {
+	if _, err := Encode(strings.Repeat("x", 214)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("err = %v, want ErrTooLong", err)
+	}
+}
+
+// ---------- Rendering ----------
+
+func TestPNG(t *testing.T) {
+	c, _ := Encode("http://pango.local:8080/")
+	b, err := c.PNG(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("png.Decode: %v", err)
+	}
+	if side := (c.Size + 8) * 4; img.Bounds().Dx() != side || img.Bounds().Dy() != side {
+		t.Errorf("bounds %v, want %d×%d", img.Bounds(), side, side)
+	}
+	// Top-left finder corner is dark, the quiet zone light
+	if r, _, _, _ := img.At(16, 16).RGBA(); r != 0 {
+		t.Error("finder corner is not dark")
+	}
+	if r, _, _, _ := img.At(15, 15).RGBA(); r == 0 {
+		t.Error("quiet zone is not light")
+	}
+}
+
+func TestSVG(t *testing.T) {
+	c, _ := Encode("http://pango.local:8080/")
+	svg := c.SVG()
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 33 33"`) || !strings.HasSuffix(svg, "</svg>") {
+		t.Errorf("svg = %.80s…", svg)
+	}
+	// First row: finder (7 dark), light, data, light, finder
+	if !strings.Contains(svg, `M4 4h7v1h-7z`) {
+		t.Error("top-left finder row missing")
+	}
+}
+
+func TestTerminal(t *testing.T) {
+	c, _ := Encode("http://pango.local:8080/")
+	lines := strings.Split(strings.TrimSuffix(c.Terminal(), "\n"), "\n")
+	if want := (c.Size + 4 + 1) / 2; len(lines) != want {
+		t.Fatalf("%d lines, want %d", len(lines), want)
+	}
+	// Light modules are drawn: the margin is full, the finder's dark
+	// corner blank.
+	if lines[0] != strings.Repeat("█", c.Size+4) {
+		t.Errorf("first line = %q", lines[0])
+	}
+	if got := []rune(lines[1]); string(got[:3]) != "██ " {
+		t.Errorf("second line starts with %q, want margin then finder", string(got[:3]))
+	}
+}
@@ -0,0 +1,289 @@
+// Package rpc serves gRPC on net/http, which speaks HTTP/2 without TLS
+// (h2c) since Go 1.24. Messages are framed, and their status carried in
+// trailers, as the gRPC over HTTP/2 protocol describes; they are encoded in
+// the protocol buffers wire format by hand (Encoder, Decode). It covers
+// unary and server-streaming methods without a code generator or the
+// grpc-go dependency tree; the tests of internal/web call the service with
+// grpc-go and messages compiled from api/pango.proto.
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Code is a gRPC status code.
+type Code int
+
+// Status codes used by the services (https://grpc.io/docs/guides/status-codes/).
+const (
+	OK                 Code = 0
+	Canceled           Code = 1
+	Unknown            Code = 2
+	InvalidArgument    Code = 3
+	DeadlineExceeded   Code = 4
+	NotFound           Code = 5
+	PermissionDenied   Code = 7
+	ResourceExhausted  Code = 8
+	FailedPrecondition Code = 9
+	Unimplemented      Code = 12
+	Internal           Code = 13
+	Unavailable        Code = 14
+	Unauthenticated    Code = 16
+)
+
+// Largest message accepted from a client. Requests are small.
+const maxMessageBytes = 1 << 20
+
+// Status is an error with a gRPC status code.
+type Status struct {
+	Code    Code
+	Message string
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("rpc error: code = %d desc = %s", s.Code, s.Message)
+}
+
+// Errorf returns a Status error.
+func Errorf(code Code, format string, args ...any) error {
+	return &Status{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// StatusOf returns the status of err: its own when it wraps a Status,
+// Canceled or DeadlineExceeded for context errors, Unknown otherwise.
+func StatusOf(err error) *Status {
+	var st *Status
+	switch {
+	case err == nil:
+		return &Status{Code: OK}
+	case errors.As(err, &st):
+		return st
+	case errors.Is(err, context.Canceled):
+		return &Status{Code: Canceled, Message: err.Error()}
+	case errors.Is(err, context.DeadlineExceeded):
+		return &Status{Code: DeadlineExceeded, Message: err.Error()}
+	default:
+		return &Status{Code: Unknown, Message: err.Error()}
+	}
+}
+
+// UnaryFunc handles a unary method: one request, one response.
+type UnaryFunc func(ctx context.Context, req []byte) ([]byte, error)
+
+// StreamFunc handles a server-streaming method: one request, then the
+// responses passed to send until it returns.
+type StreamFunc func(ctx context.Context, req []byte, send func([]byte) error) error
+
+// Server is an http.Handler serving the methods of one gRPC service.
+type Server struct {
+	service string
+	unary   map[string]UnaryFunc
+	stream  map[string]StreamFunc
+}
+
+// NewServer returns a server for service, its full name (e.g.
+// "pango.v1.PanGo").
+func NewServer(service string) *Server {
+	return &Server{service: service, unary: map[string]UnaryFunc{}, stream: map[string]StreamFunc{}}
+}
+
+// Unary registers a unary method.
+func (s *Server) Unary(name string, fn UnaryFunc) { s.unary[name] = fn }
+
+// Stream registers a server-streaming method.
+func (s *Server) Stream(name string, fn StreamFunc) { s.stream[name] = fn }
+
+// ServeHTTP serves POST /<service>/<method>.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "content type must be application/grpc", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+
+	name, _ := strings.CutPrefix(r.URL.Path, "/"+s.service+"/")
+	unary, stream := s.unary[name], s.stream[name]
+	if unary == nil && stream == nil {
+		writeStatus(w, &Status{Code: Unimplemented, Message: "unknown method " + r.URL.Path})
+		return
+	}
+
+	ctx := r.Context()
+	if timeout, ok := parseTimeout(r.Header.Get("Grpc-Timeout")); ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := ReadMessage(r.Body)
+	if err != nil {
+		writeStatus(w, &Status{Code: InvalidArgument, Message: err.Error()})
+		return
+	}
+
+	if unary != nil {
+		resp, err := unary(ctx, req)
+		if err == nil {
+			err = WriteMessage(w, resp)
+		}
+		writeStatus(w, StatusOf(err))
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	err = stream(ctx, req, func(m []byte) error {
+		if err := WriteMessage(w, m); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	writeStatus(w, StatusOf(err))
+}
+
+// writeStatus sets the grpc-status and grpc-message trailers.
+func writeStatus(w http.ResponseWriter, st *Status) {
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(int(st.Code)))
+	if st.Message != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", encodeMessage(st.Message))
+	}
+}
+
+// encodeMessage percent-encodes a grpc-message value.
+func encodeMessage(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// parseTimeout reads a grpc-timeout header, e.g. "5S" or "250m".
+func parseTimeout(v string) (time.Duration, bool) {
+	if len(v) < 2 {
+		return 0, false
+	}
+	units := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second, 'm': time.Millisecond, 'u': time.Microsecond, 'n': time.Nanosecond}
+	unit, ok := units[v[len(v)-1]]
+	n, err := strconv.ParseInt(v[:len(v)-1], 10, 64)
+	if !ok || err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// ReadMessage reads one length-prefixed message. Compressed messages are
+// refused: the server announces no grpc-accept-encoding.
+func ReadMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, errors.New("rpc: truncated message prefix")
+	}
+	if prefix[0] != 0 {
+		return nil, errors.New("rpc: compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > maxMessageBytes {
+		return nil, fmt.Errorf("rpc: message of %d bytes too large", size)
+	}
+	m := make([]byte, size)
+	if _, err := io.ReadFull(r, m); err != nil {
+		return nil, errors.New("rpc: truncated message")
+	}
+	return m, nil
+}
+
+// WriteMessage writes one length-prefixed, uncompressed message.
+func WriteMessage(w io.Writer, m []byte) error {
+	out := make([]byte, 5, 5+len(m))
+	binary.BigEndian.PutUint32(out[1:], uint32(len(m)))
+	_, err := w.Write(append(out, m...))
+	return err
+}
+
+// NewClient returns an HTTP client speaking gRPC's HTTP/2 without TLS.
+func NewClient() *http.Client {
+	var p http.Protocols
+	p.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: &p}}
+}
+
+// Call invokes method (e.g. "/pango.v1.PanGo/Plan") on the server at base
+// (e.g. "http://pango.local:50051") and passes each response to recv: one
+// for unary methods, any number for streaming ones. header adds request
+// metadata (authorization, ...). The error carries the call's Status.
+func Call(ctx context.Context, client *http.Client, base, method string, header http.Header, req []byte, recv func([]byte) error) error {
+	var body bytes.Buffer
+	WriteMessage(&body, req)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(base, "/")+method, &body)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		httpReq.Header[k] = v
+	}
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	httpReq.Header.Set("Te", "trailers")
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Errorf(Unavailable, "HTTP status %s", resp.Status)
+	}
+	for {
+		m, err := ReadMessage(resp.Body)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := recv(m); err != nil {
+			return err
+		}
+	}
+
+	// Trailers, or the headers of a trailers-only response
+	status := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return Errorf(Internal, "missing grpc-status")
+	}
+	if code != int(OK) {
+		message, _ = url.PathUnescape(message)
+		return &Status{Code: Code(code), Message: message}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestServer serves s over unencrypted HTTP/2.
+func newTestServer(t *testing.T, s http.Handler) string {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(s)
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func echoServer() *Server {
+	s := NewServer("test.v1.Echo")
+	s.Unary("Echo", func(ctx context.Context, req []byte) ([]byte, error) {
+		return req, nil
+	})
+	s.Unary("Fail", func(ctx context.Context, req []byte) ([]byte, error) {
+		return nil, Errorf(FailedPrecondition, "head busy: 100%% of\nthe time")
+	})
+	s.Unary("Deadline", func(ctx context.Context, req []byte) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	s.Stream("Count", func(ctx context.Context, req []byte, send func([]byte) error) error {
+		for i := range 3 {
+			if err := send([]byte{byte(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return s
+}
+
+// ---------- Server / Call ----------
+
+func TestCall_Unary(t *testing.T) {
+	url := newTestServer(t, echoServer())
+	var got [][]byte
+	err := Call(context.Background(), NewClient(), url, "/test.v1.Echo/Echo", nil, []byte("ping"), func(m []byte) error {
+		got = append(got, m)
+		return nil
+	})
+	if err != nil || len(got) != 1 || string(got[0]) != "ping" {
+		t.Errorf("Echo = %q, %v", got, err)
+	}
+}
+
+func TestCall_Stream(t *testing.T) {
+	url := newTestServer(t, echoServer())
+	var got []byte
+	err := Call(context.Background(), NewClient(), url, "/test.v1.Echo/Count", nil, nil, func(m []byte) error {
+		got = append(got, m...)
+		return nil
+	})
+	if err != nil || !bytes.Equal(got, []byte{0, 1, 2}) {
+		t.Errorf("Count = %v, %v", got, err)
+	}
+}
+
+func TestCall_Errors(t *testing.T) {
+	url := newTestServer(t, echoServer())
+	cases := []struct {
+		method string
+		header http.Header
+		code   Code
+		msg    string
+	}{
+		{"/test.v1.Echo/Fail", nil, FailedPrecondition, "head busy: 100% of\nthe time"},
+		{"/test.v1.Echo/Missing", nil, Unimplemented, "unknown method /test.v1.Echo/Missing"},
+		{"/test.v1.Echo/Deadline", http.Header{"Grpc-Timeout": {"20m"}}, DeadlineExceeded, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.method, func(t *testing.T) {
+			err := Call(context.Background(), NewClient(), url, tc.method, tc.header, nil, func([]byte) error { return nil })
+			var st *Status
+			if !errors.As(err, &st) || st.Code != tc.code || (tc.msg != "" && st.Message != tc.msg) {
+				t.Errorf("err = %v, want code %d %q", err, tc.code, tc.msg)
+			}
+		})
+	}
+}
+
+func TestServer_RejectsNonGRPC(t *testing.T) {
+	url := newTestServer(t, echoServer())
+	resp, err := NewClient().Post(url+"/test.v1.Echo/Echo", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("status %d, want 415", resp.StatusCode)
+	}
+}
+
+func TestReadMessage_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"compressed", []byte{1, 0, 0, 0, 0}},
+		{"too_large", []byte{0, 0x10, 0, 0, 1}},
+		{"truncated", []byte{0, 0, 0, 0, 4, 'a'}},
+		{"short_prefix", []byte{0, 0}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ReadMessage(bytes.NewReader(tc.data)); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	cases := []struct {
+		v    string
+		want time.Duration
+		ok   bool
+	}{
+		{"5S", 5 * time.Second, true},
+		{"250m", 250 * time.Millisecond, true},
+		{"1H", time.Hour, true},
+		{"S", 0, false},
+		{"5x", 0, false},
+		{"-1S", 0, false},
+	}
+	for _, tc := range cases {
+		got, ok := parseTimeout(tc.v)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("parseTimeout(%q) = %v, %v; want %v, %v", tc.v, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestStatusOf(t *testing.T) {
+	cases := []struct {
+		err  error
+		code Code
+	}{
+		{nil, OK},
+		{Errorf(NotFound, "x"), NotFound},
+		{context.Canceled, Canceled},
+		{errors.New("boom"), Unknown},
+	}
+	for _, tc := range cases {
+		if got := StatusOf(tc.err).Code; got != tc.code {
+			t.Errorf("StatusOf(%v) = %d, want %d", tc.err, got, tc.code)
+		}
+	}
+}
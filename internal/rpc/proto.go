@@ -0,0 +1,149 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Protocol buffers wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Encoder builds a protocol buffers message. Like proto3, it omits fields
+// holding their zero value.
+type Encoder struct {
+	buf []byte
+}
+
+// Bytes returns the encoded message.
+func (e *Encoder) Bytes() []byte { return e.buf }
+
+func (e *Encoder) tag(field, wire int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wire))
+}
+
+// Int encodes an int32 or int64 field.
+func (e *Encoder) Int(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, uint64(v))
+}
+
+// Uint encodes a uint32 or uint64 field.
+func (e *Encoder) Uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+// Bool encodes a bool field.
+func (e *Encoder) Bool(field int, v bool) {
+	if v {
+		e.Uint(field, 1)
+	}
+}
+
+// Double encodes a double field.
+func (e *Encoder) Double(field int, v float64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireFixed64)
+	e.buf = binary.LittleEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+// String encodes a string field.
+func (e *Encoder) String(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// Message encodes an embedded message field; repeated fields call it once
+// per element. Unlike scalars, an empty message is kept.
+func (e *Encoder) Message(field int, m []byte) {
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(m)))
+	e.buf = append(e.buf, m...)
+}
+
+// Field is a decoded field: Varint holds varint and fixed-width values,
+// Data the contents of length-delimited ones.
+type Field struct {
+	Num    int
+	Wire   int
+	Varint uint64
+	Data   []byte
+}
+
+// Int returns an int32 or int64 value.
+func (f Field) Int() int64 { return int64(f.Varint) }
+
+// Bool returns a bool value.
+func (f Field) Bool() bool { return f.Varint != 0 }
+
+// Double returns a double value.
+func (f Field) Double() float64 { return math.Float64frombits(f.Varint) }
+
+// String returns a string value.
+func (f Field) String() string { return string(f.Data) }
+
+var errTruncated = errors.New("rpc: truncated message")
+
+// Decode calls fn for each field of the message b, in order. Unknown
+// fields are for fn to skip.
+func Decode(b []byte, fn func(Field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return errTruncated
+		}
+		b = b[n:]
+		f := Field{Num: int(key >> 3), Wire: int(key & 7)}
+		switch f.Wire {
+		case wireVarint:
+			if f.Varint, n = binary.Uvarint(b); n <= 0 {
+				return errTruncated
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return errTruncated
+			}
+			f.Varint, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireFixed32:
+			if len(b) < 4 {
+				return errTruncated
+			}
+			f.Varint, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case wireBytes:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return errTruncated
+			}
+			f.Data, b = b[n:n+int(size)], b[n+int(size):]
+		default:
+			return fmt.Errorf("rpc: unsupported wire type %d", f.Wire)
+		}
+		if f.Num == 0 {
+			return errors.New("rpc: invalid field number 0")
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package rpc
+
+import (
+	"bytes"
+	"testing"
+)
+
+// ---------- Encoder / Decode ----------
+
+func TestEncoder_WireFormat(t *testing.T) {
+	var e Encoder
+	e.Int(1, 150)
+	e.String(2, "testing")
+	e.Int(3, 0) // omitted
+	want := []byte{0x08, 0x96, 0x01, 0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g'}
+	if !bytes.Equal(e.Bytes(), want) {
+		t.Errorf("encoded % x, want % x", e.Bytes(), want)
+	}
+}
+
+func TestEncoder_RoundTrip(t *testing.T) {
+	var sub Encoder
+	sub.String(1, "wide")
+	var e Encoder
+	e.Double(1, 35.5)
+	e.Int(2, -3)
+	e.Bool(3, true)
+	e.Uint(4, 1<<40)
+	e.Message(5, sub.Bytes())
+	e.Message(5, nil)
+
+	var (
+		d       float64
+		i       int64
+		b       bool
+		u       uint64
+		subs    []string
+		unknown int
+	)
+	err := Decode(e.Bytes(), func(f Field) error {
+		switch f.Num {
+		case 1:
+			d = f.Double()
+		case 2:
+			i = f.Int()
+		case 3:
+			b = f.Bool()
+		case 4:
+			u = f.Varint
+		case 5:
+			var name string
+			Decode(f.Data, func(f Field) error {
+				name = f.String()
+				return nil
+			})
+			subs = append(subs, name)
+		default:
+			unknown++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if d != 35.5 || i != -3 || !b || u != 1<<40 || len(subs) != 2 || subs[0] != "wide" || subs[1] != "" || unknown != 0 {
+		t.Errorf("decoded %v %v %v %v %q (%d unknown)", d, i, b, u, subs, unknown)
+	}
+}
+
+func TestDecode_Fixed32(t *testing.T) {
+	var got uint64
+	err := Decode([]byte{0x0d, 0x01, 0x00, 0x00, 0x00}, func(f Field) error {
+		got = f.Varint
+		return nil
+	})
+	if err != nil || got != 1 {
+		t.Errorf("fixed32 = %d, %v", got, err)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated_varint", []byte{0x08, 0x96}},
+		{"truncated_bytes", []byte{0x12, 0x07, 't'}},
+		{"truncated_double", []byte{0x09, 0x00, 0x00}},
+		{"group", []byte{0x0b}},
+		{"field_zero", []byte{0x00, 0x01}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := Decode(tc.data, func(Field) error { return nil }); err == nil {
+				t.Error("want an error")
+			}
+		})
+	}
+}
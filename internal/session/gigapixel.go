@@ -0,0 +1,46 @@
+package session
+
+import (
+	"errors"
+
+	"github.com/cjeanneret/PanGo/internal/gigapixel"
+	"github.com/cjeanneret/PanGo/internal/hugin"
+)
+
+// Layout returns the grid of r for gigapixel tools: its successful shots in
+// shooting order, in their cells and at their head angles, with the file
+// names given by names from the shot numbers. It fails for timelapses,
+// multi-panorama runs and records without the fields of view or the motor
+// steps per degree.
+func (r Record) Layout(names hugin.Namer) (gigapixel.Layout, error) {
+	if err := r.checkGeometry(); err != nil {
+		return gigapixel.Layout{}, err
+	}
+	p := r.Params
+	if p.Columns == 0 || p.Rows == 0 {
+		return gigapixel.Layout{}, errors.New("not a single-grid capture")
+	}
+	layout := gigapixel.Layout{
+		Columns:        p.Columns,
+		Rows:           p.Rows,
+		HFOVDeg:        p.HorizontalFOVDeg,
+		VFOVDeg:        p.VerticalFOVDeg,
+		OverlapPercent: p.OverlapPercent,
+	}
+	for _, shot := range r.Shots {
+		if shot.Error != "" {
+			continue
+		}
+		layout.Images = append(layout.Images, gigapixel.Image{
+			Name:     names.Name(shot.Index),
+			Column:   shot.Column,
+			Row:      shot.Row,
+			YawDeg:   float64(shot.PanSteps) / p.PanStepsPerDeg,
+			PitchDeg: float64(shot.TiltSteps) / p.TiltStepsPerDeg,
+		})
+	}
+	if len(layout.Images) == 0 {
+		return gigapixel.Layout{}, errors.New("no successful shots")
+	}
+	return layout, nil
+}
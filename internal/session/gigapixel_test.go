@@ -0,0 +1,47 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/hugin"
+)
+
+func TestRecord_Layout(t *testing.T) {
+	names, err := hugin.NewNamer(hugin.DefaultNames, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := testReportRecord()
+	rec.Params.Columns, rec.Params.Rows, rec.Params.OverlapPercent = 2, 1, 30
+	rec.Params.HorizontalFOVDeg, rec.Params.VerticalFOVDeg = 37.4, 25.4
+	rec.Shots = append(rec.Shots, Shot{Index: 3, Column: 2, Row: 1, PanSteps: 400, TiltSteps: -89})
+
+	l, err := rec.Layout(names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Columns != 2 || l.Rows != 1 || l.HFOVDeg != 37.4 || l.OverlapPercent != 30 {
+		t.Errorf("layout = %+v", l)
+	}
+	if len(l.Images) != 2 || l.Images[1].Name != "IMG_0003.JPG" || l.Images[1].Column != 2 || l.Images[1].YawDeg != 45 {
+		t.Errorf("images = %+v, want shots 1 and 3", l.Images)
+	}
+
+	cases := []struct {
+		name string
+		edit func(r *Record)
+	}{
+		{"no_grid", func(r *Record) { r.Params.Columns, r.Params.Rows = 0, 0 }},
+		{"no_fov", func(r *Record) { r.Params.VerticalFOVDeg = 0 }},
+		{"no_shots", func(r *Record) { r.Shots = r.Shots[1:2] }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rec
+			tc.edit(&r)
+			if _, err := r.Layout(names); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
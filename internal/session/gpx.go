@@ -0,0 +1,60 @@
+package session
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hugin"
+)
+
+// WriteGPX writes the locations of the successful shots of r as a GPX
+// track, a point per shot with its time and the file name given by names
+// from the shot number, to geotag the pictures by their time (exiftool
+// -geotag, darktable, digiKam). It fails when no shot has a location.
+func (r Record) WriteGPX(w io.Writer, names hugin.Namer) error {
+	located := 0
+	for _, shot := range r.Shots {
+		if shot.Error == "" && shot.Location != nil {
+			located++
+		}
+	}
+	if located == 0 {
+		return errors.New("no shot with a GPS location")
+	}
+	name := html.EscapeString("pango-" + r.ID)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(bw, `<gpx version="1.1" creator="PanGo" xmlns="http://www.topografix.com/GPX/1/1">`)
+	fmt.Fprintf(bw, "\t<metadata>\n\t\t<name>%s</name>\n\t\t<time>%s</time>\n\t</metadata>\n", name, gpxTime(r.StartedAt))
+	fmt.Fprintf(bw, "\t<trk>\n\t\t<name>%s</name>\n\t\t<trkseg>\n", name)
+	for _, shot := range r.Shots {
+		l := shot.Location
+		if shot.Error != "" || l == nil {
+			continue
+		}
+		fmt.Fprintf(bw, "\t\t\t<trkpt lat=\"%s\" lon=\"%s\">\n", gpxFloat(l.LatitudeDeg), gpxFloat(l.LongitudeDeg))
+		if l.AltitudeM != nil {
+			fmt.Fprintf(bw, "\t\t\t\t<ele>%s</ele>\n", gpxFloat(*l.AltitudeM))
+		}
+		fmt.Fprintf(bw, "\t\t\t\t<time>%s</time>\n", gpxTime(shot.Time))
+		fmt.Fprintf(bw, "\t\t\t\t<name>%s</name>\n", html.EscapeString(names.Name(shot.Index)))
+		fmt.Fprintf(bw, "\t\t\t</trkpt>\n")
+	}
+	fmt.Fprintf(bw, "\t\t</trkseg>\n\t</trk>\n</gpx>\n")
+	return bw.Flush()
+}
+
+// gpxTime formats t as a GPX time, in UTC to the millisecond.
+func gpxTime(t time.Time) string {
+	return t.UTC().Truncate(time.Millisecond).Format(time.RFC3339Nano)
+}
+
+func gpxFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/hugin"
+)
+
+func TestRecord_WriteGPX(t *testing.T) {
+	names, err := hugin.NewNamer("DSC_%04d.JPG", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := testReportRecord()
+	start := rec.StartedAt
+	// A 2D fix for the retry of shot 2 (failed, left out).
+	rec.Shots = append(rec.Shots, Shot{Index: 3, Column: 2, Row: 1, Time: start.Add(2500 * time.Millisecond),
+		Location: &Location{LatitudeDeg: -33.8558, LongitudeDeg: 151.2133, Time: start}})
+	rec.Shots[1].Location = rec.Shots[2].Location
+
+	var b strings.Builder
+	if err := rec.WriteGPX(&b, names); err != nil {
+		t.Fatal(err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="PanGo" xmlns="http://www.topografix.com/GPX/1/1">
+	<metadata>
+		<name>pango-20260621-213000-000</name>
+		<time>2026-06-21T21:30:00Z</time>
+	</metadata>
+	<trk>
+		<name>pango-20260621-213000-000</name>
+		<trkseg>
+			<trkpt lat="46.5197" lon="6.6323">
+				<ele>372.1</ele>
+				<time>2026-06-21T21:30:00Z</time>
+				<name>DSC_0100.JPG</name>
+			</trkpt>
+			<trkpt lat="-33.8558" lon="151.2133">
+				<time>2026-06-21T21:30:02.5Z</time>
+				<name>DSC_0102.JPG</name>
+			</trkpt>
+		</trkseg>
+	</trk>
+</gpx>
+`
+	if b.String() != want {
+		t.Errorf("GPX:\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	// Without a GPS
+	rec = testReportRecord()
+	rec.Shots[0].Location = nil
+	if err := rec.WriteGPX(&strings.Builder{}, names); err == nil {
+		t.Error("expected error without locations, got nil")
+	}
+}
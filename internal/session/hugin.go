@@ -0,0 +1,51 @@
+package session
+
+import (
+	"errors"
+
+	"github.com/cjeanneret/PanGo/internal/hugin"
+)
+
+// Hugin returns the Hugin project of the successful shots of r, at the
+// head angles they were taken at (from where the head started up), with
+// the file names given by names from the shot numbers. It fails for
+// records without the fields of view or the motor steps per degree.
+func (r Record) Hugin(names hugin.Namer) (hugin.Project, error) {
+	if err := r.checkGeometry(); err != nil {
+		return hugin.Project{}, err
+	}
+	p := r.Params
+	project := hugin.Project{Lens: hugin.Lens{
+		HFOVDeg:  p.HorizontalFOVDeg,
+		VFOVDeg:  p.VerticalFOVDeg,
+		WidthPx:  p.ImageWidthPx,
+		HeightPx: p.ImageHeightPx,
+	}}
+	for _, shot := range r.Shots {
+		if shot.Error != "" {
+			continue
+		}
+		project.Images = append(project.Images, hugin.Image{
+			Name:     names.Name(shot.Index),
+			YawDeg:   float64(shot.PanSteps) / p.PanStepsPerDeg,
+			PitchDeg: float64(shot.TiltSteps) / p.TiltStepsPerDeg,
+		})
+	}
+	if len(project.Images) == 0 {
+		return hugin.Project{}, errors.New("no successful shots")
+	}
+	return project, nil
+}
+
+// checkGeometry returns an error when r lacks the fields of view or the
+// motor steps per degree, needed to place its shots in a panorama.
+func (r Record) checkGeometry() error {
+	p := r.Params
+	if p.HorizontalFOVDeg == 0 || p.VerticalFOVDeg == 0 {
+		return errors.New("field of view unknown: no sensor configured, or recorded by an older version")
+	}
+	if p.PanStepsPerDeg == 0 || p.TiltStepsPerDeg == 0 {
+		return errors.New("head angles unknown: recorded by an older version")
+	}
+	return nil
+}
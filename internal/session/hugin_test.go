@@ -0,0 +1,54 @@
+package session
+
+import (
+	"math"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/hugin"
+)
+
+func TestRecord_Hugin(t *testing.T) {
+	names, err := hugin.NewNamer("DSC_%04d.JPG", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := testReportRecord()
+	rec.Params.HorizontalFOVDeg, rec.Params.VerticalFOVDeg = 37.4, 25.4
+	rec.Shots = append(rec.Shots, Shot{Index: 3, Column: 2, Row: 1, PanSteps: 400, TiltSteps: -89})
+
+	p, err := rec.Hugin(names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Lens.HFOVDeg != 37.4 || p.Lens.VFOVDeg != 25.4 {
+		t.Errorf("lens = %+v, want the recorded fields of view", p.Lens)
+	}
+	// The failed shot 2 is left out; the retry keeps its own number.
+	want := []hugin.Image{{Name: "DSC_0100.JPG"}, {Name: "DSC_0102.JPG", YawDeg: 45, PitchDeg: -10.0125}}
+	if len(p.Images) != len(want) {
+		t.Fatalf("images = %+v, want %+v", p.Images, want)
+	}
+	for i, img := range p.Images {
+		if img.Name != want[i].Name || img.YawDeg != want[i].YawDeg || math.Abs(img.PitchDeg-want[i].PitchDeg) > 1e-9 {
+			t.Errorf("image %d = %+v, want %+v", i, img, want[i])
+		}
+	}
+
+	cases := []struct {
+		name string
+		edit func(r *Record)
+	}{
+		{"no_fov", func(r *Record) { r.Params.HorizontalFOVDeg = 0 }},
+		{"no_steps_per_deg", func(r *Record) { r.Params.PanStepsPerDeg = 0 }},
+		{"no_shots", func(r *Record) { r.Shots = r.Shots[1:2] }},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rec
+			tc.edit(&r)
+			if _, err := r.Hugin(names); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
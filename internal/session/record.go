@@ -0,0 +1,324 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Outcomes of a run.
+const (
+	OutcomeDone      = "done"
+	OutcomeFailed    = "failed"
+	OutcomeCancelled = "cancelled"
+)
+
+// Duration is a time.Duration serialized as (fractional) milliseconds in JSON.
+type Duration time.Duration
+
+// MarshalJSON encodes d as milliseconds.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	ms := float64(d) / float64(time.Millisecond)
+	return json.Marshal(math.Round(ms*1000) / 1000)
+}
+
+// UnmarshalJSON decodes milliseconds into d.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var ms float64
+	if err := json.Unmarshal(data, &ms); err != nil {
+		return err
+	}
+	*d = Duration(ms * float64(time.Millisecond))
+	return nil
+}
+
+// Shot is one shutter release of a run, with its timings.
+type Shot struct {
+	Index     int       `json:"index"`            // 1-based shot number in the run
+	Column    int       `json:"column,omitempty"` // grid cell (1-based, 0 for timelapse frames)
+	Row       int       `json:"row,omitempty"`
+	PanSteps  int       `json:"pan_steps"`  // head position when shooting
+	TiltSteps int       `json:"tilt_steps"` // head position when shooting
+	Time      time.Time `json:"time"`       // shutter release
+
+	Move    Duration `json:"move_ms"`    // motor movement to reach the cell
+	Settle  Duration `json:"settle_ms"`  // wait between the end of the move and the shutter release
+	Shutter Duration `json:"shutter_ms"` // camera trigger (focus + shutter hold, or bulb exposure)
+
+	Error string `json:"error,omitempty"` // set when the shot failed
+
+	// Location is where the head stood, with a GPS configured and a fix.
+	Location *Location `json:"location,omitempty"`
+}
+
+// Location is a position from a GPS receiver.
+type Location struct {
+	LatitudeDeg  float64   `json:"latitude_deg"`         // positive north
+	LongitudeDeg float64   `json:"longitude_deg"`        // positive east
+	AltitudeM    *float64  `json:"altitude_m,omitempty"` // above mean sea level; nil without a 3D fix
+	Time         time.Time `json:"time"`                 // GPS time of the fix
+}
+
+// Stats summarizes one timing over the shots of a run.
+type Stats struct {
+	Count int      `json:"count"`
+	Min   Duration `json:"min_ms"`
+	Mean  Duration `json:"mean_ms"`
+	P95   Duration `json:"p95_ms"`
+	Max   Duration `json:"max_ms"`
+}
+
+// Timings summarizes move, settle and shutter times of a run.
+type Timings struct {
+	Move    Stats `json:"move"`
+	Settle  Stats `json:"settle"`
+	Shutter Stats `json:"shutter"`
+}
+
+// Params are the capture parameters a run was started with.
+type Params struct {
+	HorizontalAngleDeg float64 `json:"horizontal_angle_deg,omitempty"`
+	VerticalAngleDeg   float64 `json:"vertical_angle_deg,omitempty"`
+	FocalLengthMm      float64 `json:"focal_length_mm,omitempty"`
+	OverlapPercent     float64 `json:"overlap_percent,omitempty"`
+	Columns            int     `json:"columns,omitempty"`
+	Rows               int     `json:"rows,omitempty"`
+	ShotsPlanned       int     `json:"shots_planned"`
+
+	// Motor steps per degree, to convert shot positions to angles in
+	// reports. Zero in records saved before they were kept.
+	PanStepsPerDeg  float64 `json:"pan_steps_per_deg,omitempty"`
+	TiltStepsPerDeg float64 `json:"tilt_steps_per_deg,omitempty"`
+
+	// Field of view of the shots and size of the pictures, for stitching
+	// projects. Zero when unknown (no sensor or resolution configured) and
+	// in records saved before they were kept.
+	HorizontalFOVDeg float64 `json:"horizontal_fov_deg,omitempty"`
+	VerticalFOVDeg   float64 `json:"vertical_fov_deg,omitempty"`
+	ImageWidthPx     int     `json:"image_width_px,omitempty"`
+	ImageHeightPx    int     `json:"image_height_px,omitempty"`
+}
+
+// Position is a head position in motor steps.
+type Position struct {
+	PanSteps  int `json:"pan_steps"`
+	TiltSteps int `json:"tilt_steps"`
+}
+
+// Record is everything known about one capture run.
+type Record struct {
+	ID        string    `json:"id"`
+	Mode      string    `json:"mode"` // "grid" or "timelapse"
+	Params    Params    `json:"params"`
+	StartedAt time.Time `json:"started_at"`
+	EndedAt   time.Time `json:"ended_at,omitzero"`
+	Outcome   string    `json:"outcome,omitempty"` // empty while running
+	Error     string    `json:"error,omitempty"`
+	Shots     []Shot    `json:"shots"`
+	Timings   Timings   `json:"timings"`
+
+	// Build is the PanGo build that ran the capture, e.g. "v1.4.0 (commit
+	// 0123456789ab, ...)". Empty in records saved before it was kept.
+	Build string `json:"build,omitempty"`
+
+	// EndPosition is where the head stood when the run ended (relative to
+	// where it started up), e.g. to resume after an interrupted run.
+	EndPosition *Position `json:"end_position,omitempty"`
+
+	// Location is where the run started, with a GPS configured and a fix.
+	Location *Location `json:"location,omitempty"`
+}
+
+// Duration returns how long the run took (so far, if still running).
+func (r Record) Duration() time.Duration {
+	if r.EndedAt.IsZero() {
+		return time.Since(r.StartedAt)
+	}
+	return r.EndedAt.Sub(r.StartedAt)
+}
+
+// Summary is a record without its shots, for listings.
+type Summary struct {
+	ID          string    `json:"id"`
+	Mode        string    `json:"mode"`
+	Params      Params    `json:"params"`
+	StartedAt   time.Time `json:"started_at"`
+	EndedAt     time.Time `json:"ended_at,omitzero"`
+	DurationSec float64   `json:"duration_seconds"`
+	ShotsTaken  int       `json:"shots_taken"`  // successful shots
+	ShotsFailed int       `json:"shots_failed"` // shots that returned an error
+	Outcome     string    `json:"outcome,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Location    *Location `json:"location,omitempty"`
+}
+
+// Summary returns the listing entry of r.
+func (r Record) Summary() Summary {
+	s := Summary{
+		ID:          r.ID,
+		Mode:        r.Mode,
+		Params:      r.Params,
+		StartedAt:   r.StartedAt,
+		EndedAt:     r.EndedAt,
+		DurationSec: math.Round(r.Duration().Seconds()*10) / 10,
+		Outcome:     r.Outcome,
+		Error:       r.Error,
+		Location:    r.Location,
+	}
+	for _, shot := range r.Shots {
+		if shot.Error != "" {
+			s.ShotsFailed++
+		} else {
+			s.ShotsTaken++
+		}
+	}
+	return s
+}
+
+// NewID returns a session ID derived from the start time, to the
+// millisecond so that runs started within a second (a capture cancelled at
+// once, then restarted) do not share one and replace each other's record.
+func NewID(t time.Time) string {
+	return fmt.Sprintf("%s-%03d", t.Format("20060102-150405"), t.Nanosecond()/int(time.Millisecond))
+}
+
+// Summarize computes timing statistics over the successful shots.
+func Summarize(shots []Shot) Timings {
+	var move, settle, shutter []time.Duration
+	for _, s := range shots {
+		if s.Error != "" {
+			continue
+		}
+		move = append(move, time.Duration(s.Move))
+		settle = append(settle, time.Duration(s.Settle))
+		shutter = append(shutter, time.Duration(s.Shutter))
+	}
+	return Timings{
+		Move:    stats(move),
+		Settle:  stats(settle),
+		Shutter: stats(shutter),
+	}
+}
+
+func stats(ds []time.Duration) Stats {
+	if len(ds) == 0 {
+		return Stats{}
+	}
+	slices.Sort(ds)
+	var sum time.Duration
+	for _, d := range ds {
+		sum += d
+	}
+	// Nearest-rank percentile
+	p95 := ds[int(math.Ceil(0.95*float64(len(ds))))-1]
+	return Stats{
+		Count: len(ds),
+		Min:   Duration(ds[0]),
+		Mean:  Duration(sum / time.Duration(len(ds))),
+		P95:   Duration(p95),
+		Max:   Duration(ds[len(ds)-1]),
+	}
+}
+
+// Recorder builds the record of a run while it progresses. It is safe for
+// concurrent use (the capture goroutine writes, HTTP handlers read).
+type Recorder struct {
+	mu     sync.Mutex
+	rec    Record
+	locate func() *Location // optional, see SetLocator
+}
+
+// NewRecorder starts a record for a run started now.
+func NewRecorder(mode string, params Params) *Recorder {
+	now := time.Now()
+	return &Recorder{rec: Record{
+		ID:        NewID(now),
+		Mode:      mode,
+		Params:    params,
+		StartedAt: now,
+		Shots:     []Shot{},
+	}}
+}
+
+// SetPlan records the planned grid size and shot count.
+func (r *Recorder) SetPlan(columns, rows, shots int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.Params.Columns = columns
+	r.rec.Params.Rows = rows
+	r.rec.Params.ShotsPlanned = shots
+}
+
+// ID returns the ID of the run being recorded.
+func (r *Recorder) ID() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.rec.ID
+}
+
+// SetLocator sets where the positions of the head come from, e.g. a GPS
+// receiver: locate returns the current one, nil when unknown. It records
+// the position of the run now, and that of each shot when added.
+func (r *Recorder) SetLocator(locate func() *Location) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.locate = locate
+	r.rec.Location = locate()
+}
+
+// AddShot appends a shot and returns its Index, assigned by the recorder.
+func (r *Recorder) AddShot(s Shot) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.Index = len(r.rec.Shots) + 1
+	if r.locate != nil && s.Location == nil {
+		s.Location = r.locate()
+	}
+	r.rec.Shots = append(r.rec.Shots, s)
+	return s.Index
+}
+
+// SetBuild records the PanGo build running the capture.
+func (r *Recorder) SetBuild(build string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.Build = build
+}
+
+// SetEndPosition records where the head stands at the end of the run.
+func (r *Recorder) SetEndPosition(pan, tilt int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.EndPosition = &Position{PanSteps: pan, TiltSteps: tilt}
+}
+
+// Finish closes the record with the run outcome and computes the timing summary.
+func (r *Recorder) Finish(outcome string, err error) Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rec.EndedAt = time.Now()
+	r.rec.Outcome = outcome
+	if err != nil {
+		r.rec.Error = err.Error()
+	}
+	r.rec.Timings = Summarize(r.rec.Shots)
+	return r.snapshotLocked()
+}
+
+// Snapshot returns a copy of the record so far.
+func (r *Recorder) Snapshot() Record {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := r.snapshotLocked()
+	rec.Timings = Summarize(rec.Shots)
+	return rec
+}
+
+func (r *Recorder) snapshotLocked() Record {
+	rec := r.rec
+	rec.Shots = slices.Clone(r.rec.Shots)
+	return rec
+}
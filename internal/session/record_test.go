@@ -0,0 +1,165 @@
+package session
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDuration_JSONRoundTrip(t *testing.T) {
+	d := Duration(1500 * time.Microsecond)
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(data) != "1.5" {
+		t.Errorf("marshal = %s, want 1.5 (ms)", data)
+	}
+	var back Duration
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if back != d {
+		t.Errorf("round trip = %v, want %v", time.Duration(back), time.Duration(d))
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	var shots []Shot
+	for i := 1; i <= 20; i++ {
+		shots = append(shots, Shot{
+			Move:    Duration(time.Duration(i) * time.Millisecond),
+			Settle:  Duration(500 * time.Millisecond),
+			Shutter: Duration(200 * time.Millisecond),
+		})
+	}
+	// Failed shots are excluded from the statistics
+	shots = append(shots, Shot{Move: Duration(time.Hour), Error: "camera unplugged"})
+
+	tm := Summarize(shots)
+	if tm.Move.Count != 20 {
+		t.Errorf("move count = %d, want 20", tm.Move.Count)
+	}
+	if tm.Move.Min != Duration(time.Millisecond) || tm.Move.Max != Duration(20*time.Millisecond) {
+		t.Errorf("move min/max = %v/%v, want 1ms/20ms", time.Duration(tm.Move.Min), time.Duration(tm.Move.Max))
+	}
+	if tm.Move.Mean != Duration(10500*time.Microsecond) {
+		t.Errorf("move mean = %v, want 10.5ms", time.Duration(tm.Move.Mean))
+	}
+	if tm.Move.P95 != Duration(19*time.Millisecond) {
+		t.Errorf("move p95 = %v, want 19ms", time.Duration(tm.Move.P95))
+	}
+	if tm.Settle.Mean != Duration(500*time.Millisecond) || tm.Shutter.Max != Duration(200*time.Millisecond) {
+		t.Errorf("settle/shutter = %+v / %+v", tm.Settle, tm.Shutter)
+	}
+}
+
+func TestSummarize_Empty(t *testing.T) {
+	if tm := Summarize(nil); tm != (Timings{}) {
+		t.Errorf("Summarize(nil) = %+v, want zero", tm)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder("grid", Params{HorizontalAngleDeg: 180})
+	r.SetPlan(3, 2, 6)
+	r.AddShot(Shot{Column: 1, Row: 1, Shutter: Duration(time.Millisecond)})
+	r.AddShot(Shot{Column: 1, Row: 2, Shutter: Duration(3 * time.Millisecond)})
+
+	snap := r.Snapshot()
+	if snap.Outcome != "" || !snap.EndedAt.IsZero() {
+		t.Errorf("running record should have no outcome, got %q", snap.Outcome)
+	}
+	if len(snap.Shots) != 2 || snap.Shots[1].Index != 2 {
+		t.Fatalf("shots = %+v, want 2 with sequential indexes", snap.Shots)
+	}
+
+	if snap.EndPosition != nil {
+		t.Errorf("running record end position = %+v, want none", snap.EndPosition)
+	}
+	r.SetEndPosition(120, -40)
+	r.SetBuild("v1.4.0 (commit 0123456789ab)")
+
+	rec := r.Finish(OutcomeFailed, errors.New("boom"))
+	if rec.Build != "v1.4.0 (commit 0123456789ab)" {
+		t.Errorf("build = %q", rec.Build)
+	}
+	if rec.Outcome != OutcomeFailed || rec.Error != "boom" {
+		t.Errorf("outcome = %q, error = %q", rec.Outcome, rec.Error)
+	}
+	if rec.EndPosition == nil || *rec.EndPosition != (Position{PanSteps: 120, TiltSteps: -40}) {
+		t.Errorf("end position = %+v, want pan 120, tilt -40", rec.EndPosition)
+	}
+	if rec.Params.Columns != 3 || rec.Params.ShotsPlanned != 6 || rec.Params.HorizontalAngleDeg != 180 {
+		t.Errorf("params = %+v", rec.Params)
+	}
+	if rec.Timings.Shutter.Mean != Duration(2*time.Millisecond) {
+		t.Errorf("shutter mean = %v, want 2ms", time.Duration(rec.Timings.Shutter.Mean))
+	}
+	if rec.Duration() < 0 || rec.EndedAt.Before(rec.StartedAt) {
+		t.Errorf("invalid timestamps: %v -> %v", rec.StartedAt, rec.EndedAt)
+	}
+
+	// Locations from SetLocator, for the run and each shot
+	located := NewRecorder("grid", Params{})
+	loc := &Location{LatitudeDeg: 46.5197, LongitudeDeg: 6.6323}
+	located.SetLocator(func() *Location { return loc })
+	loc = nil // fix lost
+	located.AddShot(Shot{})
+	loc = &Location{LatitudeDeg: 46.5198, LongitudeDeg: 6.6324}
+	located.AddShot(Shot{})
+	lrec := located.Finish(OutcomeDone, nil)
+	if lrec.Location == nil || lrec.Location.LatitudeDeg != 46.5197 || lrec.Summary().Location != lrec.Location {
+		t.Errorf("run location = %+v, want the one at the start", lrec.Location)
+	}
+	if lrec.Shots[0].Location != nil || lrec.Shots[1].Location != loc {
+		t.Errorf("shot locations = %+v, %+v, want none then the second fix", lrec.Shots[0].Location, lrec.Shots[1].Location)
+	}
+
+	// The snapshot taken earlier must not see later shots
+	r.AddShot(Shot{})
+	if len(snap.Shots) != 2 {
+		t.Error("snapshot shares its shots slice with the recorder")
+	}
+}
+
+func TestNewID(t *testing.T) {
+	start := time.Date(2024, 6, 20, 21, 5, 9, 42_500_000, time.UTC)
+	id := NewID(start)
+	if id != "20240620-210509-042" {
+		t.Errorf("NewID = %q", id)
+	}
+	if strings.ContainsAny(id, `/\.`) {
+		t.Errorf("NewID %q is not a safe file name", id)
+	}
+	if next := NewID(start.Add(300 * time.Millisecond)); next == id || next < id {
+		t.Errorf("NewID 300 ms later = %q, want a later ID than %q", next, id)
+	}
+}
+
+func TestRecord_Summary(t *testing.T) {
+	start := time.Date(2026, 6, 21, 21, 30, 0, 0, time.UTC)
+	rec := Record{
+		ID:        NewID(start),
+		Mode:      "grid",
+		Params:    Params{HorizontalAngleDeg: 180, Columns: 3, Rows: 1, ShotsPlanned: 3},
+		StartedAt: start,
+		EndedAt:   start.Add(90*time.Second + 260*time.Millisecond),
+		Outcome:   OutcomeFailed,
+		Error:     "1 shot(s) failed after retry",
+		Shots:     []Shot{{Index: 1}, {Index: 2, Error: "camera timeout"}, {Index: 3}},
+	}
+
+	s := rec.Summary()
+	if s.ShotsTaken != 2 || s.ShotsFailed != 1 {
+		t.Errorf("shots taken/failed = %d/%d, want 2/1", s.ShotsTaken, s.ShotsFailed)
+	}
+	if s.DurationSec != 90.3 {
+		t.Errorf("duration = %v, want 90.3", s.DurationSec)
+	}
+	if s.ID != rec.ID || s.Params != rec.Params || s.Outcome != OutcomeFailed || s.Error != rec.Error {
+		t.Errorf("summary = %+v", s)
+	}
+}
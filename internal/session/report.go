@@ -0,0 +1,110 @@
+package session
+
+import (
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// ReportShot is one shot of a report, with the head angles when the run
+// recorded the motor steps per degree.
+type ReportShot struct {
+	Shot
+	PanDeg  *float64 `json:"pan_deg,omitempty"`
+	TiltDeg *float64 `json:"tilt_deg,omitempty"`
+}
+
+// Report is a run with its summary and per-shot details, for stitching or
+// archiving tools.
+type Report struct {
+	Summary
+	Timings     Timings      `json:"timings"`
+	EndPosition *Position    `json:"end_position,omitempty"`
+	Shots       []ReportShot `json:"shots"`
+}
+
+// Report returns the report of r.
+func (r Record) Report() Report {
+	rep := Report{
+		Summary:     r.Summary(),
+		Timings:     r.Timings,
+		EndPosition: r.EndPosition,
+		Shots:       make([]ReportShot, len(r.Shots)),
+	}
+	for i, shot := range r.Shots {
+		rep.Shots[i] = ReportShot{
+			Shot:    shot,
+			PanDeg:  degrees(shot.PanSteps, r.Params.PanStepsPerDeg),
+			TiltDeg: degrees(shot.TiltSteps, r.Params.TiltStepsPerDeg),
+		}
+	}
+	return rep
+}
+
+// degrees converts steps to degrees (rounded to 0.01°), or returns nil when
+// the ratio is unknown.
+func degrees(steps int, stepsPerDeg float64) *float64 {
+	if stepsPerDeg == 0 {
+		return nil
+	}
+	deg := math.Round(float64(steps)/stepsPerDeg*100) / 100
+	return &deg
+}
+
+// reportColumns is the CSV header of WriteCSV.
+var reportColumns = []string{
+	"index", "column", "row", "pan_deg", "tilt_deg", "pan_steps", "tilt_steps",
+	"time", "move_ms", "settle_ms", "shutter_ms", "error",
+	"latitude_deg", "longitude_deg", "altitude_m",
+}
+
+// WriteCSV writes the shots of the report as CSV, one row per shot. Angles
+// and locations are left empty when unknown.
+func (rep Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(reportColumns); err != nil {
+		return err
+	}
+	for _, s := range rep.Shots {
+		row := []string{
+			strconv.Itoa(s.Index),
+			strconv.Itoa(s.Column),
+			strconv.Itoa(s.Row),
+			formatDeg(s.PanDeg),
+			formatDeg(s.TiltDeg),
+			strconv.Itoa(s.PanSteps),
+			strconv.Itoa(s.TiltSteps),
+			s.Time.Format(time.RFC3339Nano),
+			formatMs(s.Move),
+			formatMs(s.Settle),
+			formatMs(s.Shutter),
+			s.Error,
+			"", "", "",
+		}
+		if l := s.Location; l != nil {
+			row[12] = strconv.FormatFloat(l.LatitudeDeg, 'f', -1, 64)
+			row[13] = strconv.FormatFloat(l.LongitudeDeg, 'f', -1, 64)
+			row[14] = formatDeg(l.AltitudeM)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatDeg(deg *float64) string {
+	if deg == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*deg, 'f', -1, 64)
+}
+
+// formatMs formats d as milliseconds, like its JSON encoding.
+func formatMs(d Duration) string {
+	ms := float64(d) / float64(time.Millisecond)
+	return strconv.FormatFloat(math.Round(ms*1000)/1000, 'f', -1, 64)
+}
@@ -0,0 +1,66 @@
+package session
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// altitude is the altitude of the first shot of testReportRecord.
+var altitude = 372.1
+
+func testReportRecord() Record {
+	start := time.Date(2026, 6, 21, 21, 30, 0, 0, time.UTC)
+	return Record{
+		ID:        NewID(start),
+		Mode:      "grid",
+		Params:    Params{ShotsPlanned: 2, PanStepsPerDeg: 8.888888888888889, TiltStepsPerDeg: 8.888888888888889},
+		StartedAt: start,
+		EndedAt:   start.Add(time.Minute),
+		Outcome:   OutcomeDone,
+		Shots: []Shot{
+			{Index: 1, Column: 1, Row: 1, Time: start, Move: Duration(1500 * time.Microsecond), Location: &Location{LatitudeDeg: 46.5197, LongitudeDeg: 6.6323, AltitudeM: &altitude, Time: start}},
+			{Index: 2, Column: 2, Row: 1, PanSteps: 400, TiltSteps: -89, Time: start.Add(time.Second), Error: "camera unplugged"},
+		},
+	}
+}
+
+func TestRecord_Report(t *testing.T) {
+	rep := testReportRecord().Report()
+	if rep.ShotsTaken != 1 || rep.ShotsFailed != 1 || len(rep.Shots) != 2 {
+		t.Fatalf("report = %+v", rep)
+	}
+	shot := rep.Shots[1]
+	if shot.PanDeg == nil || *shot.PanDeg != 45 || shot.TiltDeg == nil || *shot.TiltDeg != -10.01 {
+		t.Errorf("angles = %v, %v, want 45 and -10.01", shot.PanDeg, shot.TiltDeg)
+	}
+
+	// Records saved before steps per degree were kept have no angles
+	rec := testReportRecord()
+	rec.Params.PanStepsPerDeg, rec.Params.TiltStepsPerDeg = 0, 0
+	if shot := rec.Report().Shots[1]; shot.PanDeg != nil || shot.TiltDeg != nil {
+		t.Errorf("angles = %v, %v, want none", shot.PanDeg, shot.TiltDeg)
+	}
+}
+
+func TestReport_WriteCSV(t *testing.T) {
+	var b strings.Builder
+	if err := testReportRecord().Report().WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(b.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v", err)
+	}
+	if len(rows) != 3 || strings.Join(rows[0], ",") != strings.Join(reportColumns, ",") {
+		t.Fatalf("CSV = %q", rows)
+	}
+	want := []string{"1", "1", "1", "0", "0", "0", "0", "2026-06-21T21:30:00Z", "1.5", "0", "0", "", "46.5197", "6.6323", "372.1"}
+	if strings.Join(rows[1], ",") != strings.Join(want, ",") {
+		t.Errorf("row 1 = %q, want %q", rows[1], want)
+	}
+	if rows[2][3] != "45" || rows[2][11] != "camera unplugged" || rows[2][12] != "" {
+		t.Errorf("row 2 = %q", rows[2])
+	}
+}
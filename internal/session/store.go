@@ -0,0 +1,119 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MaxRecords is the number of records kept in memory (and loaded at startup).
+const MaxRecords = 200
+
+// Store keeps the records of past runs, most recent last. With a directory,
+// each record is also written to <dir>/<id>.json and reloaded at startup.
+type Store struct {
+	mu      sync.Mutex
+	dir     string
+	records []Record
+}
+
+// NewStore creates a store. dir == "" keeps records in memory only.
+func NewStore(dir string) (*Store, error) {
+	s := &Store{dir: dir}
+	if dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create sessions dir: %w", err)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the most recent records from dir (file names sort by start time).
+func (s *Store) load() error {
+	names, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	slices.Sort(names)
+	if len(names) > MaxRecords {
+		names = names[len(names)-MaxRecords:]
+	}
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("read session %s: %w", filepath.Base(name), err)
+		}
+		var rec Record
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return fmt.Errorf("parse session %s: %w", filepath.Base(name), err)
+		}
+		s.records = append(s.records, rec)
+	}
+	return nil
+}
+
+// Save adds rec to the store (replacing a record with the same ID) and
+// writes it to disk when the store has a directory.
+func (s *Store) Save(rec Record) error {
+	if !validID(rec.ID) {
+		return fmt.Errorf("invalid session id %q", rec.ID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := slices.IndexFunc(s.records, func(r Record) bool { return r.ID == rec.ID }); i >= 0 {
+		s.records[i] = rec
+	} else {
+		s.records = append(s.records, rec)
+		if len(s.records) > MaxRecords {
+			s.records = s.records[len(s.records)-MaxRecords:]
+		}
+	}
+
+	if s.dir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write then rename so a crash never leaves a truncated record.
+	path := filepath.Join(s.dir, rec.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write session: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// List returns all records, most recent last.
+func (s *Store) List() []Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.records)
+}
+
+// Get returns the record with the given ID.
+func (s *Store) Get(id string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.records {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return Record{}, false
+}
+
+// validID reports whether id is safe to use as a file name.
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\.`)
+}
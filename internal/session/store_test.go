@@ -0,0 +1,90 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testRecord(id string, shots int) Record {
+	rec := Record{ID: id, Mode: "grid", StartedAt: time.Now(), Outcome: OutcomeDone}
+	for i := range shots {
+		rec.Shots = append(rec.Shots, Shot{Index: i + 1, Move: Duration(time.Millisecond)})
+	}
+	return rec
+}
+
+func TestStore_MemoryOnly(t *testing.T) {
+	s, err := NewStore("")
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	if err := s.Save(testRecord("20240101-000000", 2)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := s.List(); len(got) != 1 {
+		t.Fatalf("List = %d records, want 1", len(got))
+	}
+	if _, ok := s.Get("20240101-000000"); !ok {
+		t.Error("Get: record not found")
+	}
+	if _, ok := s.Get("nope"); ok {
+		t.Error("Get: unknown id should not be found")
+	}
+}
+
+func TestStore_PersistsAndReloads(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	s.Save(testRecord("20240101-000000", 1))
+	s.Save(testRecord("20240102-000000", 3))
+
+	if _, err := os.Stat(filepath.Join(dir, "20240102-000000.json")); err != nil {
+		t.Fatalf("record file not written: %v", err)
+	}
+
+	reloaded, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	list := reloaded.List()
+	if len(list) != 2 {
+		t.Fatalf("reloaded %d records, want 2", len(list))
+	}
+	if list[1].ID != "20240102-000000" || len(list[1].Shots) != 3 {
+		t.Errorf("last record = %s with %d shots, want 20240102-000000 with 3", list[1].ID, len(list[1].Shots))
+	}
+}
+
+func TestStore_SaveReplacesSameID(t *testing.T) {
+	s, _ := NewStore("")
+	s.Save(testRecord("20240101-000000", 1))
+	s.Save(testRecord("20240101-000000", 4))
+	list := s.List()
+	if len(list) != 1 || len(list[0].Shots) != 4 {
+		t.Errorf("List = %+v, want a single updated record", list)
+	}
+}
+
+func TestStore_Bounded(t *testing.T) {
+	s, _ := NewStore("")
+	for i := range MaxRecords + 10 {
+		s.Save(testRecord(NewID(time.Unix(int64(i), 0)), 0))
+	}
+	if n := len(s.List()); n != MaxRecords {
+		t.Errorf("List = %d records, want %d", n, MaxRecords)
+	}
+}
+
+func TestStore_RejectsUnsafeID(t *testing.T) {
+	s, _ := NewStore(t.TempDir())
+	for _, id := range []string{"", "../escape", "a/b", "x.json"} {
+		if err := s.Save(testRecord(id, 0)); err == nil {
+			t.Errorf("Save(%q): expected error", id)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package session
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ThumbnailSize is the longest side of a thumbnail, in pixels.
+const ThumbnailSize = 320
+
+// Thumbnails keeps small previews of the pictures of a run, to check
+// exposures while it progresses. With a directory, the thumbnails of each
+// run are written to <dir>/<id>/<index>.jpg next to its record; without,
+// only those of the latest run are kept in memory.
+type Thumbnails struct {
+	mu     sync.Mutex
+	dir    string
+	latest string         // run that received the last thumbnail
+	mem    map[int][]byte // thumbnails of latest, without dir
+}
+
+// NewThumbnails creates a thumbnail store. dir == "" keeps them in memory.
+func NewThumbnails(dir string) *Thumbnails {
+	return &Thumbnails{dir: dir, mem: map[int][]byte{}}
+}
+
+// Add stores a thumbnail of image (JPEG) as shot index of run id.
+func (t *Thumbnails) Add(id string, index int, img []byte) error {
+	if !validID(id) {
+		return fmt.Errorf("invalid session id %q", id)
+	}
+	thumb, err := Thumbnail(img, ThumbnailSize)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if id != t.latest {
+		t.latest = id
+		clear(t.mem)
+	}
+	if t.dir == "" {
+		t.mem[index] = thumb
+		return nil
+	}
+	dir := filepath.Join(t.dir, id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create thumbnails dir: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, strconv.Itoa(index)+".jpg"), thumb, 0o644)
+}
+
+// Latest returns the run that received the last thumbnail, or "".
+func (t *Thumbnails) Latest() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.latest
+}
+
+// Get returns the thumbnail of shot index of run id.
+func (t *Thumbnails) Get(id string, index int) ([]byte, bool) {
+	if !validID(id) {
+		return nil, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dir == "" {
+		if id != t.latest {
+			return nil, false
+		}
+		thumb, ok := t.mem[index]
+		return thumb, ok
+	}
+	thumb, err := os.ReadFile(filepath.Join(t.dir, id, strconv.Itoa(index)+".jpg"))
+	return thumb, err == nil
+}
+
+// List returns the shot indexes of run id that have a thumbnail, in order.
+func (t *Thumbnails) List(id string) []int {
+	indexes := []int{}
+	if !validID(id) {
+		return indexes
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dir == "" {
+		if id == t.latest {
+			for i := range t.mem {
+				indexes = append(indexes, i)
+			}
+		}
+	} else {
+		entries, _ := os.ReadDir(filepath.Join(t.dir, id))
+		for _, e := range entries {
+			if i, err := strconv.Atoi(strings.TrimSuffix(e.Name(), ".jpg")); err == nil {
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	slices.Sort(indexes)
+	return indexes
+}
+
+// Thumbnail decodes a JPEG image and returns it scaled down (averaging
+// pixels) so that its longest side is at most size, re-encoded as JPEG.
+func Thumbnail(data []byte, size int) ([]byte, error) {
+	src, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		for x := range w {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			var r, g, bl, n uint64
+			for sy := y0; sy < max(y1, y0+1); sy++ {
+				for sx := x0; sx < max(x1, x0+1); sx++ {
+					cr, cg, cb, _ := src.At(sx, sy).RGBA()
+					r, g, bl, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), n+1
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n >> 8)
+			dst.Pix[i+1] = uint8(g / n >> 8)
+			dst.Pix[i+2] = uint8(bl / n >> 8)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
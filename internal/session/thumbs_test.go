@@ -0,0 +1,143 @@
+package session
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testJPEG returns a w×h JPEG image with a horizontal gradient.
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / w), 128, 64, 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decodeSize(t *testing.T, data []byte) (int, int) {
+	t.Helper()
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("decode thumbnail: %v", err)
+	}
+	return cfg.Width, cfg.Height
+}
+
+// ---------- Thumbnail ----------
+
+func TestThumbnail(t *testing.T) {
+	cases := []struct {
+		name         string
+		w, h         int
+		wantW, wantH int
+	}{
+		{"landscape", 640, 480, 320, 240},
+		{"portrait", 300, 900, 106, 320},
+		{"already small", 200, 100, 200, 100},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			thumb, err := Thumbnail(testJPEG(t, tc.w, tc.h), ThumbnailSize)
+			if err != nil {
+				t.Fatalf("Thumbnail: %v", err)
+			}
+			if w, h := decodeSize(t, thumb); w != tc.wantW || h != tc.wantH {
+				t.Errorf("size = %dx%d, want %dx%d", w, h, tc.wantW, tc.wantH)
+			}
+		})
+	}
+}
+
+func TestThumbnail_InvalidImage(t *testing.T) {
+	if _, err := Thumbnail([]byte("not a jpeg"), ThumbnailSize); err == nil {
+		t.Error("expected an error for invalid data")
+	}
+}
+
+// ---------- Thumbnails ----------
+
+func TestThumbnails_Memory(t *testing.T) {
+	store := NewThumbnails("")
+	img := testJPEG(t, 64, 48)
+	for _, i := range []int{2, 1} {
+		if err := store.Add("run1", i, img); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if got := store.Latest(); got != "run1" {
+		t.Errorf("Latest = %q, want run1", got)
+	}
+	if got := store.List("run1"); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("List = %v, want [1 2]", got)
+	}
+	if thumb, ok := store.Get("run1", 2); !ok || len(thumb) == 0 {
+		t.Error("Get(run1, 2) not found")
+	}
+	if _, ok := store.Get("run1", 3); ok {
+		t.Error("Get(run1, 3) found a shot that was never added")
+	}
+
+	// Only the latest run is kept in memory
+	if err := store.Add("run2", 1, img); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if got := store.List("run1"); len(got) != 0 {
+		t.Errorf("List(run1) = %v after a new run, want none", got)
+	}
+	if _, ok := store.Get("run1", 1); ok {
+		t.Error("Get(run1, 1) found a shot of a previous run")
+	}
+	if got := store.List("run2"); !slices.Equal(got, []int{1}) {
+		t.Errorf("List(run2) = %v, want [1]", got)
+	}
+}
+
+func TestThumbnails_Dir(t *testing.T) {
+	dir := t.TempDir()
+	store := NewThumbnails(dir)
+	img := testJPEG(t, 64, 48)
+	if err := store.Add("run1", 1, img); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := store.Add("run2", 1, img); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run1", "1.jpg")); err != nil {
+		t.Errorf("thumbnail file: %v", err)
+	}
+
+	// Previous runs stay available, also from a new store
+	store = NewThumbnails(dir)
+	if got := store.List("run1"); !slices.Equal(got, []int{1}) {
+		t.Errorf("List(run1) = %v, want [1]", got)
+	}
+	if _, ok := store.Get("run1", 1); !ok {
+		t.Error("Get(run1, 1) not found")
+	}
+}
+
+func TestThumbnails_InvalidID(t *testing.T) {
+	store := NewThumbnails(t.TempDir())
+	if err := store.Add("../escape", 1, testJPEG(t, 8, 8)); err == nil {
+		t.Error("Add accepted a path as session id")
+	}
+	if got := store.List("../escape"); len(got) != 0 {
+		t.Errorf("List = %v, want none", got)
+	}
+	if _, ok := store.Get("..", 1); ok {
+		t.Error("Get accepted a path as session id")
+	}
+}
@@ -0,0 +1,215 @@
+package systemd
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// journalSocket is where journald receives native log entries
+// (systemd-journald.service(8)); a variable for tests.
+var journalSocket = "/run/systemd/journal/socket"
+
+// Journal priorities (PRIORITY=, the syslog levels) PanGo logs at.
+const (
+	PriorityErr     = 3
+	PriorityWarning = 4
+	PriorityInfo    = 6
+	PriorityDebug   = 7
+)
+
+// JournalStream reports whether stderr is connected to the journal: systemd
+// sets JOURNAL_STREAM to its device and inode for a service with
+// StandardError=journal (the default). Logging natively is then better than
+// writing lines that all get the same priority.
+func JournalStream() bool {
+	dev, ino, ok := strings.Cut(os.Getenv("JOURNAL_STREAM"), ":")
+	if !ok {
+		return false
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(os.Stderr.Fd()), &st); err != nil {
+		return false
+	}
+	return dev == strconv.FormatUint(uint64(st.Dev), 10) && ino == strconv.FormatUint(uint64(st.Ino), 10)
+}
+
+// Journal sends entries to journald with the native protocol, each with a
+// priority and structured fields, as sd_journal_send(3). It is safe for
+// concurrent use.
+type Journal struct {
+	conn       *net.UnixConn
+	identifier string // SYSLOG_IDENTIFIER, e.g. "pango"
+}
+
+// OpenJournal connects to journald; entries are sent with identifier as
+// SYSLOG_IDENTIFIER.
+func OpenJournal(identifier string) (*Journal, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("systemd: journal: %w", err)
+	}
+	return &Journal{conn: conn, identifier: identifier}, nil
+}
+
+// Close closes the connection to journald.
+func (j *Journal) Close() error {
+	return j.conn.Close()
+}
+
+// Send sends an entry with msg as MESSAGE at priority, and fields, names
+// already valid journal field names (see FieldName).
+func (j *Journal) Send(priority int, msg string, fields map[string]string) error {
+	var b bytes.Buffer
+	appendField(&b, "PRIORITY", strconv.Itoa(priority))
+	appendField(&b, "SYSLOG_IDENTIFIER", j.identifier)
+	appendField(&b, "MESSAGE", msg)
+	for name, value := range fields {
+		appendField(&b, name, value)
+	}
+	if _, err := j.conn.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("systemd: journal: %w", err)
+	}
+	return nil
+}
+
+// appendField encodes a field of an entry: NAME=value on a line, or the
+// length-prefixed binary form when value has newlines.
+func appendField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// FieldName returns the journal field name of a log attribute key: upper
+// case letters, digits and underscores, not starting with an underscore
+// (reserved to journald) or a digit, e.g. "cell.col" becomes CELL_COL.
+func FieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "F_" + name
+	}
+	return name
+}
+
+// Write sends p, a line of the log package, as an entry: at PriorityErr or
+// PriorityWarning when it has an "[error]" or "[warning]" tag (operator
+// announcements), PriorityInfo otherwise.
+func (j *Journal) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	priority := PriorityInfo
+	switch {
+	case strings.Contains(msg, "[error]"):
+		priority = PriorityErr
+	case strings.Contains(msg, "[warning]"):
+		priority = PriorityWarning
+	}
+	if err := j.Send(priority, msg, nil); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Handler returns a slog.Handler sending records to j: the level sets the
+// priority (error, warning, info, debug below slog.LevelInfo), the
+// attributes become fields (see FieldName), groups joined by underscores.
+// Records are not filtered by level.
+func (j *Journal) Handler() slog.Handler {
+	return &journalHandler{j: j}
+}
+
+type journalHandler struct {
+	j      *Journal
+	attrs  map[string]string // from WithAttrs
+	prefix string            // from WithGroup, e.g. "CELL_"
+}
+
+func (h *journalHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+func (h *journalHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := make(map[string]string, len(h.attrs)+r.NumAttrs())
+	for name, value := range h.attrs {
+		fields[name] = value
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(fields, h.prefix, a)
+		return true
+	})
+	return h.j.Send(priority(r.Level), r.Message, fields)
+}
+
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next := &journalHandler{j: h.j, attrs: make(map[string]string, len(h.attrs)+len(attrs)), prefix: h.prefix}
+	for name, value := range h.attrs {
+		next.attrs[name] = value
+	}
+	for _, a := range attrs {
+		addAttr(next.attrs, h.prefix, a)
+	}
+	return next
+}
+
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &journalHandler{j: h.j, attrs: h.attrs, prefix: h.prefix + FieldName(name) + "_"}
+}
+
+// addAttr adds the fields of a to fields, those of groups flattened.
+func addAttr(fields map[string]string, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += FieldName(a.Key) + "_"
+		}
+		for _, ga := range v.Group() {
+			addAttr(fields, p, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	fields[prefix+FieldName(a.Key)] = v.String()
+}
+
+// priority returns the journal priority of a log level.
+func priority(lvl slog.Level) int {
+	switch {
+	case lvl >= slog.LevelError:
+		return PriorityErr
+	case lvl >= slog.LevelWarn:
+		return PriorityWarning
+	case lvl >= slog.LevelInfo:
+		return PriorityInfo
+	}
+	return PriorityDebug
+}
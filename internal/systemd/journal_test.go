@@ -0,0 +1,158 @@
+package systemd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// listenJournal returns a datagram socket standing in for journald, and a
+// Journal sending to it.
+func listenJournal(t *testing.T) (*net.UnixConn, *Journal) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	old := journalSocket
+	journalSocket = path
+	t.Cleanup(func() { journalSocket = old })
+	j, err := OpenJournal("pango")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { j.Close() })
+	return conn, j
+}
+
+// readEntry reads an entry sent to conn and decodes its fields.
+func readEntry(t *testing.T, conn *net.UnixConn) map[string]string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read entry: %v", err)
+	}
+	fields := map[string]string{}
+	data := buf[:n]
+	for len(data) > 0 {
+		line, rest, _ := bytes.Cut(data, []byte("\n"))
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields[string(name)] = string(value)
+			data = rest
+			continue
+		}
+		size := binary.LittleEndian.Uint64(rest[:8])
+		fields[string(line)] = string(rest[8 : 8+size])
+		data = rest[8+size+1:]
+	}
+	return fields
+}
+
+// ---------- Journal ----------
+
+func TestJournal_Handler(t *testing.T) {
+	conn, j := listenJournal(t)
+	logger := slog.New(j.Handler()).With("module", "gpio")
+
+	cases := []struct {
+		log  func()
+		want map[string]string
+	}{
+		{
+			func() { logger.Log(t.Context(), slog.Level(-8), "gpio", "op", "WritePin", "pin", 17) },
+			map[string]string{"PRIORITY": "7", "SYSLOG_IDENTIFIER": "pango", "MESSAGE": "gpio", "MODULE": "gpio", "OP": "WritePin", "PIN": "17"},
+		},
+		{
+			func() { logger.Info("photo taken", slog.Group("cell", "col", 2, "row", 3)) },
+			map[string]string{"PRIORITY": "6", "MESSAGE": "photo taken", "CELL_COL": "2", "CELL_ROW": "3"},
+		},
+		{
+			func() { logger.Warn("low battery", "step_pin", 18) },
+			map[string]string{"PRIORITY": "4", "STEP_PIN": "18"},
+		},
+		{
+			func() { logger.Error("retry", "err", errors.New("line 1\nline 2")) },
+			map[string]string{"PRIORITY": "3", "MESSAGE": "retry", "ERR": "line 1\nline 2"},
+		},
+	}
+	for _, c := range cases {
+		c.log()
+		got := readEntry(t, conn)
+		for name, want := range c.want {
+			if got[name] != want {
+				t.Errorf("%s = %q, want %q (entry %v)", name, got[name], want, got)
+			}
+		}
+	}
+}
+
+func TestJournal_Write(t *testing.T) {
+	conn, j := listenJournal(t)
+	cases := []struct {
+		line string
+		want string
+	}{
+		{"2026/10/17 22:00:00 web server listening on :8080\n", "6"},
+		{"2026/10/17 22:00:00 [warning] Shot failed at column 2\n", "4"},
+		{"2026/10/17 22:00:00 [error] capture failed\n", "3"},
+	}
+	for _, c := range cases {
+		if _, err := j.Write([]byte(c.line)); err != nil {
+			t.Fatal(err)
+		}
+		got := readEntry(t, conn)
+		if got["PRIORITY"] != c.want || got["MESSAGE"]+"\n" != c.line {
+			t.Errorf("entry of %q = %v, want priority %s", c.line, got, c.want)
+		}
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	cases := map[string]string{
+		"pin":       "PIN",
+		"step_pin":  "STEP_PIN",
+		"cell.col":  "CELL_COL",
+		"_internal": "INTERNAL",
+		"2d":        "F_2D",
+		"":          "F_",
+	}
+	for key, want := range cases {
+		if got := FieldName(key); got != want {
+			t.Errorf("FieldName(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestJournalStream(t *testing.T) {
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(os.Stderr.Fd()), &st); err != nil {
+		t.Skip(err)
+	}
+	stream := strconv.FormatUint(uint64(st.Dev), 10) + ":" + strconv.FormatUint(uint64(st.Ino), 10)
+	cases := []struct {
+		env  string
+		want bool
+	}{
+		{stream, true},
+		{"", false},
+		{"1:2", false},
+	}
+	for _, c := range cases {
+		t.Setenv("JOURNAL_STREAM", c.env)
+		if got := JournalStream(); got != c.want {
+			t.Errorf("JournalStream() with %q = %v, want %v", c.env, got, c.want)
+		}
+	}
+}
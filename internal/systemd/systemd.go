@@ -0,0 +1,131 @@
+// Package systemd implements the parts of the systemd service protocol PanGo
+// uses, without linking libsystemd: socket activation (sd_listen_fds(3)),
+// readiness and status notification (sd_notify(3)), the service watchdog
+// (sd_watchdog_enabled(3)) and native logging to the journal
+// (sd_journal_send(3)). Outside systemd every function is a no-op.
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// listenFDsStart is the first file descriptor passed by socket activation.
+const listenFDsStart = 3
+
+// Notification states (sd_notify(3)).
+const (
+	Ready     = "READY=1"
+	Stopping  = "STOPPING=1"
+	Reloading = "RELOADING=1"
+	Watchdog  = "WATCHDOG=1"
+)
+
+// Listeners returns the sockets passed by socket activation (a .socket
+// unit), in the order of its Listen* lines, or none when the process was not
+// socket-activated. The LISTEN_* variables are cleared so that child
+// processes do not inherit them.
+func Listeners() ([]net.Listener, error) {
+	defer os.Unsetenv("LISTEN_PID")
+	defer os.Unsetenv("LISTEN_FDS")
+	defer os.Unsetenv("LISTEN_FDNAMES")
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("systemd: invalid LISTEN_FDS %q", os.Getenv("LISTEN_FDS"))
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	var listeners []net.Listener
+	for i := range n {
+		fd := listenFDsStart + i
+		syscall.CloseOnExec(fd)
+		name := "LISTEN_FD_" + strconv.Itoa(fd)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), name)
+		ln, err := net.FileListener(f)
+		f.Close() // FileListener dups the descriptor
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, fmt.Errorf("systemd: socket %s: %w", name, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	return listeners, nil
+}
+
+// Notify sends state (e.g. Ready, or "STATUS=shooting 12/40") to the service
+// manager. It reports false without error when not run by systemd with
+// Type=notify (NOTIFY_SOCKET unset).
+func Notify(state string) (bool, error) {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return false, nil
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:] // abstract socket
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("systemd: notify: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("systemd: notify: %w", err)
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the service watchdog timeout (WatchdogSec=), or 0
+// when the watchdog is disabled or meant for another process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if p := os.Getenv("WATCHDOG_PID"); p != "" {
+		if pid, err := strconv.Atoi(p); err != nil || pid != os.Getpid() {
+			return 0
+		}
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// RunWatchdog pings the service watchdog at half its timeout until ctx is
+// done, as long as healthy reports nil; systemd then restarts the service
+// when PanGo hangs or reports itself unhealthy for a whole timeout. It
+// returns at once when the watchdog is disabled. healthy may be nil.
+func RunWatchdog(ctx context.Context, healthy func() error) error {
+	interval := WatchdogInterval()
+	if interval == 0 {
+		return nil
+	}
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		if healthy == nil || healthy() == nil {
+			if _, err := Notify(Watchdog); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package systemd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listenNotify returns a datagram socket standing in for systemd's
+// NOTIFY_SOCKET.
+func listenNotify(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	return conn
+}
+
+func readNotify(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read notification: %v", err)
+	}
+	return string(buf[:n])
+}
+
+// ---------- Notify ----------
+
+func TestNotify(t *testing.T) {
+	conn := listenNotify(t)
+	sent, err := Notify(Ready)
+	if err != nil || !sent {
+		t.Fatalf("Notify = %v, %v; want true, nil", sent, err)
+	}
+	if got := readNotify(t, conn); got != Ready {
+		t.Errorf("received %q, want %q", got, Ready)
+	}
+}
+
+func TestNotify_NotUnderSystemd(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := Notify(Ready); sent || err != nil {
+		t.Errorf("Notify = %v, %v; want false, nil", sent, err)
+	}
+}
+
+// ---------- Watchdog ----------
+
+func TestWatchdogInterval(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	cases := []struct {
+		name string
+		usec string
+		pid  string
+		want time.Duration
+	}{
+		{"disabled", "", "", 0},
+		{"enabled", "30000000", "", 30 * time.Second},
+		{"this_process", "30000000", pid, 30 * time.Second},
+		{"other_process", "30000000", "1", 0},
+		{"invalid", "soon", "", 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WATCHDOG_USEC", tc.usec)
+			t.Setenv("WATCHDOG_PID", tc.pid)
+			if got := WatchdogInterval(); got != tc.want {
+				t.Errorf("WatchdogInterval() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunWatchdog(t *testing.T) {
+	conn := listenNotify(t)
+	t.Setenv("WATCHDOG_USEC", "20000") // pings every 10 ms
+	t.Setenv("WATCHDOG_PID", "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	healthy := make(chan error, 1)
+	healthy <- nil
+	done := make(chan error, 1)
+	go func() {
+		done <- RunWatchdog(ctx, func() error {
+			select {
+			case err := <-healthy:
+				return err
+			default:
+				return errors.New("stuck")
+			}
+		})
+	}()
+
+	if got := readNotify(t, conn); got != Watchdog {
+		t.Errorf("received %q, want %q", got, Watchdog)
+	}
+	// Unhealthy: no more pings
+	conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if n, err := conn.Read(make([]byte, 64)); err == nil {
+		t.Errorf("pinged (%d bytes) while unhealthy", n)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("RunWatchdog: %v", err)
+	}
+}
+
+func TestRunWatchdog_Disabled(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "")
+	if err := RunWatchdog(context.Background(), nil); err != nil {
+		t.Errorf("RunWatchdog: %v", err)
+	}
+}
+
+// ---------- Listeners ----------
+
+func TestListeners_NotActivated(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	t.Setenv("LISTEN_FDS", "")
+	if ls, err := Listeners(); len(ls) != 0 || err != nil {
+		t.Errorf("Listeners = %v, %v; want none", ls, err)
+	}
+}
+
+// TestListeners passes a listening socket as descriptor 3 to a child test
+// process, as systemd does.
+func TestListeners(t *testing.T) {
+	if os.Getenv("PANGO_SYSTEMD_CHILD") == "1" {
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		ls, err := Listeners()
+		if err != nil || len(ls) != 1 {
+			t.Fatalf("Listeners = %v, %v; want one", ls, err)
+		}
+		if os.Getenv("LISTEN_FDS") != "" {
+			t.Error("LISTEN_FDS still set")
+		}
+		conn, err := ls[0].Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Write([]byte("activated"))
+		conn.Close()
+		return
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestListeners$")
+	cmd.Env = append(os.Environ(), "PANGO_SYSTEMD_CHILD=1", "LISTEN_FDS=1", "LISTEN_FDNAMES=web")
+	cmd.ExtraFiles = []*os.File{f}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 16)
+	n, _ := conn.Read(buf)
+	conn.Close()
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("child: %v", err)
+	}
+	if got := string(buf[:n]); got != "activated" {
+		t.Errorf("read %q from the activated socket, want %q", got, "activated")
+	}
+}
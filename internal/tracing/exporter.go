@@ -0,0 +1,255 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Export limits: ended spans are sent in batches of at most maxBatch, at
+// most flushInterval after they end, and at once when a trace (its root
+// span) ends.
+const (
+	maxBatch      = 512
+	flushInterval = 5 * time.Second
+	exportTimeout = 10 * time.Second
+)
+
+// tracesPath is the OTLP/HTTP path of trace exports.
+const tracesPath = "/v1/traces"
+
+// Options configure an Exporter.
+type Options struct {
+	ServiceName    string            // service.name of the spans (default "pango")
+	ServiceVersion string            // service.version, e.g. the PanGo version
+	Headers        map[string]string // sent with each export, e.g. an API key
+	Client         *http.Client      // nil = a client with a 10 s timeout
+}
+
+// Exporter sends ended spans to an OTLP/HTTP collector in the background,
+// so that a slow or unreachable collector never delays a capture. Failed
+// exports are logged, and their spans dropped.
+type Exporter struct {
+	url  string
+	opts Options
+
+	mu      sync.Mutex
+	pending []*Span
+	timer   *time.Timer // flushes pending spans; nil when none are pending
+	failing bool        // the last export failed, already logged
+
+	wg sync.WaitGroup
+}
+
+// NewExporter returns an exporter to the collector at endpoint, e.g.
+// "http://collector.local:4318"; /v1/traces is added unless the path
+// already ends with it.
+func NewExporter(endpoint string, opts Options) (*Exporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("tracing: endpoint %q is not an http(s) URL", endpoint)
+	}
+	if !strings.HasSuffix(u.Path, tracesPath) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + tracesPath
+	}
+	if opts.ServiceName == "" {
+		opts.ServiceName = "pango"
+	}
+	return &Exporter{url: u.String(), opts: opts}, nil
+}
+
+// add queues the ended span s.
+func (e *Exporter) add(s *Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pending = append(e.pending, s)
+	switch {
+	case len(e.pending) >= maxBatch || s.parentID == [8]byte{}:
+		e.flushLocked()
+	case e.timer == nil:
+		e.timer = time.AfterFunc(flushInterval, func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			e.flushLocked()
+		})
+	}
+}
+
+// flushLocked starts the export of the pending spans. e.mu must be held.
+func (e *Exporter) flushLocked() {
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+	}
+	if len(e.pending) == 0 {
+		return
+	}
+	batch := e.pending
+	e.pending = nil
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		err := e.export(batch)
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		switch {
+		case err != nil && !e.failing:
+			log.Printf("tracing: export of %d span(s) failed, dropped until the collector answers: %v", len(batch), err)
+		case err == nil && e.failing:
+			log.Printf("tracing: exporting again")
+		}
+		e.failing = err != nil
+	}()
+}
+
+// Shutdown exports the pending spans and waits for the exports in
+// progress, at most timeout. It reports false when some did not finish in
+// time.
+func (e *Exporter) Shutdown(timeout time.Duration) bool {
+	e.mu.Lock()
+	e.flushLocked()
+	e.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// export posts spans to the collector.
+func (e *Exporter) export(spans []*Span) error {
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PanGo")
+	for name, value := range e.opts.Headers {
+		req.Header.Set(name, value)
+	}
+	client := e.opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: exportTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
+
+// request returns the OTLP export request of spans.
+func (e *Exporter) request(spans []*Span) exportRequest {
+	resourceAttrs := []keyValue{{Key: "service.name", Value: anyValueOf(e.opts.ServiceName)}}
+	if e.opts.ServiceVersion != "" {
+		resourceAttrs = append(resourceAttrs, keyValue{Key: "service.version", Value: anyValueOf(e.opts.ServiceVersion)})
+	}
+	out := make([]span, len(spans))
+	for i, s := range spans {
+		out[i] = span{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        s.attributes(),
+		}
+		if s.parentID != [8]byte{} {
+			out[i].ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != nil {
+			out[i].Status = status{Code: statusError, Message: s.err.Error()}
+		}
+	}
+	return exportRequest{ResourceSpans: []resourceSpans{{
+		Resource:   resource{Attributes: resourceAttrs},
+		ScopeSpans: []scopeSpans{{Scope: scope{Name: "github.com/cjeanneret/PanGo"}, Spans: out}},
+	}}}
+}
+
+// OTLP trace export request, JSON encoding (opentelemetry-proto
+// ExportTraceServiceRequest): IDs in hex, 64-bit integers as strings.
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope  `json:"scope"`
+	Spans []span `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+// Span kinds and status codes of the OTLP span.
+const (
+	spanKindInternal = 1
+	statusError      = 2
+)
+
+type span struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
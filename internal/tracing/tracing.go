@@ -0,0 +1,148 @@
+// Package tracing records the spans of capture runs (sequence, columns,
+// cells, moves and shots) and exports them to an OpenTelemetry collector
+// with OTLP over HTTP, in its JSON encoding, without the OpenTelemetry SDK.
+// Spans are not recorded until SetExporter is called.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var exporter atomic.Pointer[Exporter] // nil = tracing off
+
+// SetExporter records the spans started from now on and sends them to e;
+// nil stops recording.
+func SetExporter(e *Exporter) {
+	exporter.Store(e)
+}
+
+// Span is a timed operation of a trace, with attributes and an error
+// status. A nil *Span (tracing off) is valid and does nothing.
+type Span struct {
+	exp      *Exporter
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte // zero for the root span
+	name     string
+	start    time.Time
+
+	mu    sync.Mutex
+	attrs []any // key-value pairs, as slog
+	end   time.Time
+	err   error
+}
+
+type spanKey struct{}
+
+// Start starts a span named name, child of the span of ctx if any, with the
+// key-value pairs of attrs as attributes (as log/slog, e.g. "axis", "pan").
+// It returns a context carrying the span, for its children. The span is
+// nil when tracing is off.
+func Start(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	e := exporter.Load()
+	if parent != nil {
+		e = parent.exp
+	}
+	if e == nil {
+		return ctx, nil
+	}
+	s := &Span{exp: e, name: name, start: time.Now(), attrs: attrs}
+	if parent != nil {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		rand.Read(s.traceID[:])
+	}
+	rand.Read(s.spanID[:])
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// FromContext returns the span carried by ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// TraceID returns the trace ID of s in hex, as shown by tracing backends;
+// "" when s is nil.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// SetAttributes adds the key-value pairs of attrs to the attributes of s.
+func (s *Span) SetAttributes(attrs ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// End ends s, with an error status when err is not nil, and queues it for
+// export. Ending a span again does nothing.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if !s.end.IsZero() {
+		s.mu.Unlock()
+		return
+	}
+	s.end, s.err = time.Now(), err
+	s.mu.Unlock()
+	s.exp.add(s)
+}
+
+// attributes returns the attributes of s as OTLP key-values. Keys that are
+// not strings are skipped, like their value.
+func (s *Span) attributes() []keyValue {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kvs := make([]keyValue, 0, len(s.attrs)/2)
+	for i := 0; i+1 < len(s.attrs); i += 2 {
+		if key, ok := s.attrs[i].(string); ok {
+			kvs = append(kvs, keyValue{Key: key, Value: anyValueOf(s.attrs[i+1])})
+		}
+	}
+	return kvs
+}
+
+// anyValueOf returns the OTLP value of v: integers, floats and booleans as
+// such, durations in milliseconds, anything else as text.
+func anyValueOf(v any) anyValue {
+	switch v := v.(type) {
+	case string:
+		return anyValue{StringValue: &v}
+	case bool:
+		return anyValue{BoolValue: &v}
+	case int:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint64:
+		return intValue(int64(v))
+	case float64:
+		return anyValue{DoubleValue: &v}
+	case time.Duration:
+		ms := float64(v) / float64(time.Millisecond)
+		return anyValue{DoubleValue: &ms}
+	}
+	str := fmt.Sprint(v)
+	return anyValue{StringValue: &str}
+}
+
+func intValue(n int64) anyValue {
+	s := fmt.Sprint(n) // int64 values are strings in OTLP JSON
+	return anyValue{IntValue: &s}
+}
@@ -0,0 +1,183 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector is an OTLP/HTTP collector recording the spans it receives.
+type collector struct {
+	mu      sync.Mutex
+	spans   []span
+	headers http.Header
+	status  int
+}
+
+func newCollector(t *testing.T) (*collector, *Exporter) {
+	t.Helper()
+	c := &collector{status: http.StatusOK}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tracesPath || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("export to %s as %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		var req exportRequest
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("decode export: %v", err)
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.headers = r.Header
+		for _, rs := range req.ResourceSpans {
+			if got := *rs.Resource.Attributes[0].Value.StringValue; got != "pango" {
+				t.Errorf("service.name = %q", got)
+			}
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+		}
+		w.WriteHeader(c.status)
+	}))
+	t.Cleanup(srv.Close)
+	e, err := NewExporter(srv.URL, Options{Headers: map[string]string{"X-Api-Key": "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetExporter(e)
+	t.Cleanup(func() { SetExporter(nil) })
+	return c, e
+}
+
+// received returns the spans received by c, by name.
+func (c *collector) received() map[string]span {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	spans := map[string]span{}
+	for _, s := range c.spans {
+		spans[s.Name] = s
+	}
+	return spans
+}
+
+// ---------- Span ----------
+
+func TestStart_Off(t *testing.T) {
+	ctx, s := Start(context.Background(), "sequence")
+	if s != nil || FromContext(ctx) != nil {
+		t.Fatal("span recorded with tracing off")
+	}
+	s.SetAttributes("columns", 4) // no-op on nil spans
+	s.End(nil)
+	if s.TraceID() != "" {
+		t.Error("nil span has a trace ID")
+	}
+}
+
+func TestSpans_Exported(t *testing.T) {
+	c, e := newCollector(t)
+
+	ctx, root := Start(context.Background(), "capture", "mode", "grid")
+	cellCtx, cell := Start(ctx, "cell", "column", 2, "row", 3)
+	_, move := Start(cellCtx, "move", "axis", "tilt", "steps", -120, "settle", 250*time.Millisecond)
+	move.End(nil)
+	_, shoot := Start(cellCtx, "shoot")
+	shoot.End(errors.New("camera not responding"))
+	shoot.End(nil) // already ended
+	cell.SetAttributes("retried", true)
+	cell.End(nil)
+	root.End(context.Canceled)
+	if !e.Shutdown(5 * time.Second) {
+		t.Fatal("exports still running")
+	}
+
+	spans := c.received()
+	if len(c.spans) != 4 {
+		t.Fatalf("received %d spans, want 4: %v", len(c.spans), c.spans)
+	}
+	if c.headers.Get("X-Api-Key") != "secret" {
+		t.Errorf("headers = %v, want the configured X-Api-Key", c.headers)
+	}
+	capture, cellSpan, moveSpan, shootSpan := spans["capture"], spans["cell"], spans["move"], spans["shoot"]
+	if capture.TraceID != root.TraceID() || capture.ParentSpanID != "" {
+		t.Errorf("root span = %+v, want trace %s without parent", capture, root.TraceID())
+	}
+	for _, s := range []span{cellSpan, moveSpan, shootSpan} {
+		if s.TraceID != capture.TraceID {
+			t.Errorf("span %s in trace %s, want %s", s.Name, s.TraceID, capture.TraceID)
+		}
+	}
+	if cellSpan.ParentSpanID != capture.SpanID || moveSpan.ParentSpanID != cellSpan.SpanID || shootSpan.ParentSpanID != cellSpan.SpanID {
+		t.Error("spans not nested capture > cell > move, shoot")
+	}
+	if shootSpan.Status.Code != statusError || shootSpan.Status.Message != "camera not responding" || moveSpan.Status.Code != 0 {
+		t.Errorf("statuses: shoot %+v, move %+v", shootSpan.Status, moveSpan.Status)
+	}
+	start, _ := strconv.ParseInt(capture.StartTimeUnixNano, 10, 64)
+	end, _ := strconv.ParseInt(capture.EndTimeUnixNano, 10, 64)
+	if start == 0 || end < start {
+		t.Errorf("capture ends (%s) before it starts (%s)", capture.EndTimeUnixNano, capture.StartTimeUnixNano)
+	}
+
+	attrs := map[string]anyValue{}
+	for _, kv := range moveSpan.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	if *attrs["axis"].StringValue != "tilt" || *attrs["steps"].IntValue != "-120" || *attrs["settle"].DoubleValue != 250 {
+		t.Errorf("move attributes = %+v", moveSpan.Attributes)
+	}
+	if len(cellSpan.Attributes) != 3 || *cellSpan.Attributes[2].Value.BoolValue != true {
+		t.Errorf("cell attributes = %+v, want column, row and retried", cellSpan.Attributes)
+	}
+}
+
+func TestExporter_RootEndFlushes(t *testing.T) {
+	c, e := newCollector(t)
+	ctx, root := Start(context.Background(), "capture")
+	_, col := Start(ctx, "column")
+	col.End(nil)
+	time.Sleep(50 * time.Millisecond)
+	if len(c.received()) != 0 {
+		t.Fatal("child span exported before the flush interval")
+	}
+	root.End(nil)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.received()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(c.received()) != 2 {
+		t.Errorf("received %d spans after the root ended, want 2", len(c.received()))
+	}
+	e.Shutdown(time.Second)
+}
+
+func TestNewExporter(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		want     string
+		wantErr  bool
+	}{
+		{"http://collector.local:4318", "http://collector.local:4318/v1/traces", false},
+		{"https://otel.example.com/otlp/", "https://otel.example.com/otlp/v1/traces", false},
+		{"http://collector.local:4318/v1/traces", "http://collector.local:4318/v1/traces", false},
+		{"collector.local:4318", "", true},
+		{"ftp://collector.local", "", true},
+	}
+	for _, tc := range cases {
+		e, err := NewExporter(tc.endpoint, Options{})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("NewExporter(%q) error = %v", tc.endpoint, err)
+			continue
+		}
+		if err == nil && e.url != tc.want {
+			t.Errorf("NewExporter(%q) exports to %s, want %s", tc.endpoint, e.url, tc.want)
+		}
+	}
+}
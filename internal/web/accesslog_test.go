@@ -0,0 +1,156 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func decodeAccess(t *testing.T, buf *bytes.Buffer) []AccessEntry {
+	t.Helper()
+	var entries []AccessEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var e AccessEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestAccessLog_LogsRequest(t *testing.T) {
+	var buf bytes.Buffer
+	var seen string
+	h := AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestID(r.Context())
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}), &buf)
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs?x=1", nil)
+	req.RemoteAddr = "192.168.1.30:51234"
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	entries := decodeAccess(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("got %d lines, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Method != "POST" || e.Path != "/jobs" || e.Status != 201 || e.Bytes != 5 || e.ClientIP != "192.168.1.30" {
+		t.Errorf("entry = %+v", e)
+	}
+	if len(e.RequestID) != 16 || e.RequestID != seen || w.Header().Get(RequestIDHeader) != e.RequestID {
+		t.Errorf("request ID: logged %q, handler %q, header %q", e.RequestID, seen, w.Header().Get(RequestIDHeader))
+	}
+	if _, err := time.Parse(time.RFC3339, e.Time); err != nil {
+		t.Errorf("time %q: %v", e.Time, err)
+	}
+}
+
+func TestAccessLog_ClientRequestID(t *testing.T) {
+	cases := []struct {
+		name string
+		id   string
+		keep bool
+	}{
+		{"valid", "proxy-42.a_b", true},
+		{"newline", "abc\ninjected", false},
+		{"space", "a b", false},
+		{"too_long", strings.Repeat("a", maxRequestIDLength+1), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), &buf)
+			req := httptest.NewRequest(http.MethodGet, "/status", nil)
+			req.Header.Set(RequestIDHeader, tc.id)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			got := w.Header().Get(RequestIDHeader)
+			if (got == tc.id) != tc.keep {
+				t.Errorf("request ID = %q, keep client ID %t", got, tc.keep)
+			}
+			if e := decodeAccess(t, &buf)[0]; e.Status != http.StatusOK {
+				t.Errorf("status without explicit WriteHeader = %d, want 200", e.Status)
+			}
+		})
+	}
+}
+
+func TestAccessLog_KeepsFlusher(t *testing.T) {
+	h := AccessLog(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("SSE needs the wrapped writer to be an http.Flusher")
+		}
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("Flush: %v", err)
+		}
+	}), &bytes.Buffer{})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/status/stream", nil))
+}
+
+func TestServer_AccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAuth(AuthConfig{Token: testToken})
+	srv.SetAccessLog(&buf)
+	mux := srv.Mux()
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/config", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	entries := decodeAccess(t, &buf)
+	if len(entries) != 2 || entries[0].Status != http.StatusUnauthorized || entries[1].Path != "/healthz" {
+		t.Errorf("entries = %+v, want rejected and public requests logged", entries)
+	}
+
+	srv.SetAccessLog(nil)
+	buf.Reset()
+	w := httptest.NewRecorder()
+	srv.Mux().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if buf.Len() != 0 || w.Header().Get(RequestIDHeader) != "" {
+		t.Error("access log should be disabled")
+	}
+}
+
+func TestJobQueue_RequestID(t *testing.T) {
+	b := NewStatusBroadcaster()
+	ch, unsub := b.Subscribe()
+	defer unsub()
+	runCtx := make(chan context.Context, 1)
+	q := NewJobQueue(func(ctx context.Context, o Overrides) error {
+		runCtx <- ctx
+		return nil
+	}, b)
+
+	job, err := q.StartNow(WithRequestID(context.Background(), "req-1"), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.RequestID != "req-1" {
+		t.Errorf("job request ID = %q", job.RequestID)
+	}
+	if got := RequestID(<-runCtx); got != "req-1" {
+		t.Errorf("capture context request ID = %q", got)
+	}
+	select {
+	case msg := <-ch:
+		var evt StatusEvent
+		if err := json.Unmarshal([]byte(msg), &evt); err != nil {
+			t.Fatal(err)
+		}
+		if evt.RequestID != "req-1" || evt.Msg != "Sequence complete" {
+			t.Errorf("event = %+v", evt)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no completion broadcast")
+	}
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// ErrNetworkNotAllowed rejects control requests from clients outside
+// Handlers.ControlNetworks.
+var ErrNetworkNotAllowed = errors.New("control of the rig is not allowed from this network")
+
+// checkNetwork returns ErrNetworkNotAllowed when ControlNetworks is set and
+// the client of r is outside of it. Clients on the Unix socket are local and
+// always allowed.
+func (h *Handlers) checkNetwork(r *http.Request) error {
+	if local, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && local.Network() == "unix" {
+		return nil
+	}
+	return h.checkRemote(r.RemoteAddr)
+}
+
+// checkRemote returns ErrNetworkNotAllowed when ControlNetworks is set and
+// the client at remote (host:port) is outside of it.
+func (h *Handlers) checkRemote(remote string) error {
+	if len(h.ControlNetworks) == 0 {
+		return nil
+	}
+	ap, err := netip.ParseAddrPort(remote)
+	if err != nil {
+		return ErrNetworkNotAllowed
+	}
+	addr := ap.Addr().Unmap()
+	for _, p := range h.ControlNetworks {
+		if p.Contains(addr) {
+			return nil
+		}
+	}
+	return ErrNetworkNotAllowed
+}
+
+// CheckControl checks that a client of another protocol, at remote, may
+// act on the rig as a local command would: from ControlNetworks, and
+// while no web client holds control. For servers without authentication
+// of their own (INDI).
+func (h *Handlers) CheckControl(remote net.Addr) error {
+	if remote.Network() != "unix" {
+		if err := h.checkRemote(remote.String()); err != nil {
+			return err
+		}
+	}
+	if h.Control != nil {
+		return h.Control.check("")
+	}
+	return nil
+}
+
+// requireNetwork wraps next to answer 403 Forbidden to clients outside
+// ControlNetworks.
+func (h *Handlers) requireNetwork(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := h.checkNetwork(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
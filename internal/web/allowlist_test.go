@@ -0,0 +1,143 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/rpc"
+)
+
+// ---------- checkNetwork ----------
+
+func TestCheckNetwork(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	req := func(remote string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/run", nil)
+		r.RemoteAddr = remote
+		return r
+	}
+	if err := h.checkNetwork(req("203.0.113.7:4000")); err != nil {
+		t.Errorf("without allowlist: %v", err)
+	}
+
+	h.ControlNetworks = []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24"), netip.MustParsePrefix("::1/128")}
+	cases := []struct {
+		remote string
+		allow  bool
+	}{
+		{"192.168.1.42:5000", true},
+		{"[::ffff:192.168.1.42]:5000", true}, // IPv4 on a dual-stack socket
+		{"[::1]:5000", true},
+		{"192.168.2.42:5000", false},
+		{"203.0.113.7:4000", false},
+		{"garbage", false},
+	}
+	for _, tc := range cases {
+		if err := h.checkNetwork(req(tc.remote)); (err == nil) != tc.allow {
+			t.Errorf("%s: err = %v, want allowed %v", tc.remote, err, tc.allow)
+		}
+	}
+}
+
+func TestCheckControl(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.ControlNetworks = []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}
+	lan := &net.TCPAddr{IP: net.ParseIP("192.168.1.42"), Port: 50000}
+	if err := h.CheckControl(lan); err != nil {
+		t.Errorf("from the LAN: %v", err)
+	}
+	if err := h.CheckControl(&net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 50000}); !errors.Is(err, ErrNetworkNotAllowed) {
+		t.Errorf("from outside = %v, want ErrNetworkNotAllowed", err)
+	}
+
+	if _, err := h.Control.Claim("", "studio", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.CheckControl(lan); err == nil || !strings.Contains(err.Error(), "studio") {
+		t.Errorf("while claimed = %v, want the claim of studio", err)
+	}
+}
+
+func TestServer_ControlNetworks(t *testing.T) {
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35})
+	srv.SetAccessLog(nil)
+	srv.Handlers().ControlNetworks = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	mux := srv.Mux()
+	do := func(method, path string) int {
+		r := httptest.NewRequest(method, path, strings.NewReader(`{}`))
+		r.RemoteAddr = "192.168.1.42:5000"
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for _, path := range []string{APIPrefix + "/run", APIPrefix + "/cancel", "/jog"} {
+		if code := do(http.MethodPost, path); code != http.StatusForbidden {
+			t.Errorf("POST %s: status %d, want 403", path, code)
+		}
+	}
+	// Reading the state stays open
+	if code := do(http.MethodGet, APIPrefix+"/config"); code != http.StatusOK {
+		t.Errorf("GET /config: status %d, want 200", code)
+	}
+}
+
+func TestHandleWS_ControlNetworks(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.ControlNetworks = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	conn := dialWS(t, h) // from 127.0.0.1: events, but no commands
+
+	if reply := sendWS(t, conn, WSCommand{Cmd: "cancel", ID: "1"}); reply.OK || reply.Error != ErrNetworkNotAllowed.Error() {
+		t.Errorf("reply = %+v, want %v", reply, ErrNetworkNotAllowed)
+	}
+}
+
+func TestGRPC_ControlNetworks(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.ControlNetworks = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	c := newGRPCTestClient(t, h, AuthConfig{})
+	if _, err := c.call("Stop", nil); grpcCode(err) != rpc.PermissionDenied {
+		t.Errorf("Stop from outside: %v, want PermissionDenied", err)
+	}
+}
+
+func TestServer_ControlNetworksUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pango.sock")
+	srv := NewServer("", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	srv.SetUnixSocket(path)
+	srv.Handlers().ControlNetworks = []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+	ready := make(chan struct{})
+	srv.RegisterOnReady(func() { close(ready) })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Run(ctx)
+	select {
+	case <-ready:
+	case <-time.After(2 * time.Second):
+		t.Fatal("not ready")
+	}
+
+	c := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := c.Post("http://pango"+APIPrefix+"/cancel", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict { // allowed: no capture to cancel
+		t.Errorf("status %d over the Unix socket, want 409", resp.StatusCode)
+	}
+}
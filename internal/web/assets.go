@@ -0,0 +1,206 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Cache-Control of the static assets: revalidated with their ETag, or kept
+// for a year when requested with their current version (?v=, added to the
+// index page by versionAssets) since a new build changes the URL.
+const (
+	revalidate = "no-cache"
+	immutable  = "public, max-age=31536000, immutable"
+)
+
+// compressible lists the extensions of the assets served compressed;
+// images already are.
+var compressible = map[string]bool{
+	".html": true, ".css": true, ".js": true, ".json": true, ".svg": true, ".ico": true, ".md": true, ".txt": true,
+}
+
+// asset is a static file ready to serve.
+type asset struct {
+	body        []byte
+	gzip        []byte // nil when not worth it
+	br          []byte // brotli, set by brotli; nil when not worth it
+	brOnce      sync.Once
+	contentType string
+	version     string // short content hash
+}
+
+// assets serves the embedded static files brotli- or gzip-compressed when
+// the client accepts it, with ETags and cache headers. The files are read
+// and compressed once, at startup; those of the override directory, if
+// any, at each request so that edits show on the next reload. Brotli at its
+// best compression takes a while on a Pi, so it waits for the first client
+// asking for it.
+type assets struct {
+	files map[string]*asset // by path, e.g. "img/logo-96.png"
+	dir   fs.FS             // override directory (Server.SetStaticDir); nil = embedded files only
+}
+
+func newAssets(fsys fs.FS) *assets {
+	a := &assets{files: map[string]*asset{}}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		a.files[name] = newAsset(name, data)
+		return nil
+	})
+	if err != nil {
+		log.Printf("web: static files: %v", err)
+	}
+	return a
+}
+
+func newAsset(name string, data []byte) *asset {
+	sum := sha256.Sum256(data)
+	f := &asset{body: data, version: hex.EncodeToString(sum[:8])}
+	if f.contentType = mime.TypeByExtension(path.Ext(name)); f.contentType == "" {
+		f.contentType = http.DetectContentType(data)
+	}
+	if compressible[path.Ext(name)] {
+		var buf bytes.Buffer
+		zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		zw.Write(data)
+		zw.Close()
+		if buf.Len() < len(data) {
+			f.gzip = buf.Bytes()
+		}
+	}
+	return f
+}
+
+// brotli returns the body compressed with brotli, compressing it on the
+// first call; nil when it would not be smaller.
+func (f *asset) brotli() []byte {
+	f.brOnce.Do(func() {
+		var buf bytes.Buffer
+		bw := brotli.NewWriterLevel(&buf, brotli.BestCompression)
+		bw.Write(f.body)
+		bw.Close()
+		if buf.Len() < len(f.body) {
+			f.br = buf.Bytes()
+		}
+	})
+	return f.br
+}
+
+// lookup returns the asset name, from the override directory when it has
+// it, else embedded.
+func (a *assets) lookup(name string) (*asset, bool) {
+	if a.dir != nil {
+		if data, err := fs.ReadFile(a.dir, name); err == nil {
+			return newAsset(name, data), true
+		}
+	}
+	f, ok := a.files[name]
+	return f, ok
+}
+
+// ServeHTTP serves the asset at the request path, relative to the static
+// directory (see http.StripPrefix).
+func (a *assets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f, ok := a.lookup(strings.TrimPrefix(r.URL.Path, "/"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if v := r.URL.Query().Get("v"); v != "" && v == f.version {
+		w.Header().Set("Cache-Control", immutable)
+	} else {
+		w.Header().Set("Cache-Control", revalidate)
+	}
+	f.serve(w, r)
+}
+
+// serve writes the asset compressed with the accepted coding of highest
+// q-value, brotli on a tie; brotli is only tried where gzip gains.
+// Conditional and range requests are handled by http.ServeContent.
+func (f *asset) serve(w http.ResponseWriter, r *http.Request) {
+	h := w.Header()
+	h.Set("Content-Type", f.contentType)
+	body, etag := f.body, `"`+f.version+`"`
+	if f.gzip != nil {
+		h.Add("Vary", "Accept-Encoding")
+		brQ, gzipQ := acceptQ(r, "br"), acceptQ(r, "gzip")
+		switch {
+		case brQ > 0 && brQ >= gzipQ && f.brotli() != nil:
+			body, etag = f.br, `"`+f.version+`-br"`
+			h.Set("Content-Encoding", "br")
+		case gzipQ > 0:
+			body, etag = f.gzip, `"`+f.version+`-gz"`
+			h.Set("Content-Encoding", "gzip")
+		}
+	}
+	h.Set("ETag", etag)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
+
+// acceptQ returns the q-value the Accept-Encoding header of r gives to
+// coding ("gzip" also matching x-gzip) or, when coding is not listed, to
+// *; 0 when neither is.
+func acceptQ(r *http.Request, coding string) float64 {
+	codingQ, anyQ := -1.0, -1.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(key) != "q" {
+				continue
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				v = 0
+			}
+			q = v
+		}
+		switch c := strings.ToLower(strings.TrimSpace(name)); {
+		case c == coding || coding == "gzip" && c == "x-gzip":
+			codingQ = q
+		case c == "*":
+			anyQ = q
+		}
+	}
+	if codingQ >= 0 {
+		return codingQ
+	}
+	return max(anyQ, 0)
+}
+
+// assetRef matches the static asset references of the index page.
+var assetRef = regexp.MustCompile(`((?:href|src)="static/)([^"?#]+)"`)
+
+// versionAssets appends the version of each static asset referenced by
+// page to its URL, so that browsers cache them until the next build.
+func (a *assets) versionAssets(page []byte) []byte {
+	return assetRef.ReplaceAllFunc(page, func(m []byte) []byte {
+		sub := assetRef.FindSubmatch(m)
+		f, ok := a.lookup(string(sub[2]))
+		if !ok {
+			return m
+		}
+		return []byte(string(sub[1]) + string(sub[2]) + "?v=" + f.version + `"`)
+	})
+}
@@ -0,0 +1,176 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/andybalholm/brotli"
+)
+
+var testAssetsFS = fstest.MapFS{
+	"index.html":   &fstest.MapFile{Data: []byte(`<html><head><link rel="stylesheet" href="static/style.css"><script src="static/app.js"></script><img src="static/img/missing.png"></head></html>`)},
+	"app.js":       &fstest.MapFile{Data: []byte(strings.Repeat("console.log('pango');\n", 100))},
+	"style.css":    &fstest.MapFile{Data: []byte("body{}")},
+	"img/logo.png": &fstest.MapFile{Data: []byte("\x89PNG\r\n\x1a\nnot really")},
+}
+
+func serveAsset(h http.Handler, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+// ---------- assets ----------
+
+func TestAssets_Gzip(t *testing.T) {
+	a := newAssets(testAssetsFS)
+	js := string(testAssetsFS["app.js"].Data)
+
+	w := serveAsset(a, "/app.js", http.Header{"Accept-Encoding": {"br;q=0.5, gzip;q=0.8"}})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("status %d, headers %v", w.Code, w.Header())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/javascript") {
+		t.Errorf("Content-Type = %q", w.Header().Get("Content-Type"))
+	}
+	for _, accept := range []string{"*;q=0, gzip", "GZIP;level=1;q=0.5", "identity, x-gzip", "br;q=0, *"} {
+		w := serveAsset(a, "/app.js", http.Header{"Accept-Encoding": {accept}})
+		if w.Header().Get("Content-Encoding") != "gzip" {
+			t.Errorf("Accept-Encoding %q: Content-Encoding %q, want gzip", accept, w.Header().Get("Content-Encoding"))
+		}
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(zr); string(body) != js {
+		t.Error("gunzipped body differs from app.js")
+	}
+
+	for _, accept := range []string{"", "identity", "gzip;q=0", "gzip;q=0, br;q=0, *", "*;q=0", "gzip; q=0.000"} {
+		w := serveAsset(a, "/app.js", http.Header{"Accept-Encoding": {accept}})
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != js {
+			t.Errorf("Accept-Encoding %q: Content-Encoding %q", accept, w.Header().Get("Content-Encoding"))
+		}
+	}
+
+	// Too small to gain, or already compressed
+	for _, path := range []string{"/style.css", "/img/logo.png"} {
+		w := serveAsset(a, path, http.Header{"Accept-Encoding": {"gzip"}})
+		if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != "" || w.Header().Get("Vary") != "" {
+			t.Errorf("%s: status %d, Content-Encoding %q", path, w.Code, w.Header().Get("Content-Encoding"))
+		}
+	}
+	if w := serveAsset(a, "/img/", nil); w.Code != http.StatusNotFound {
+		t.Errorf("directory: status %d, want 404", w.Code)
+	}
+}
+
+func TestAssets_Brotli(t *testing.T) {
+	a := newAssets(testAssetsFS)
+	js := string(testAssetsFS["app.js"].Data)
+
+	for _, accept := range []string{"br", "gzip, br", "*", "gzip;q=0, *", "br;q=0.9, gzip;q=0.9", "BR;q=0.8, gzip;q=0.5"} {
+		w := serveAsset(a, "/app.js", http.Header{"Accept-Encoding": {accept}})
+		if w.Header().Get("Content-Encoding") != "br" || w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("Accept-Encoding %q: Content-Encoding %q, want br", accept, w.Header().Get("Content-Encoding"))
+			continue
+		}
+		if body, err := io.ReadAll(brotli.NewReader(w.Body)); err != nil || string(body) != js {
+			t.Errorf("Accept-Encoding %q: decompressed body differs from app.js (%v)", accept, err)
+		}
+	}
+	if br := a.files["app.js"].br; br == nil || len(br) >= len(a.files["app.js"].gzip) {
+		t.Error("brotli body not smaller than the gzipped one")
+	}
+	if w := serveAsset(a, "/style.css", http.Header{"Accept-Encoding": {"br"}}); w.Header().Get("Content-Encoding") != "" {
+		t.Errorf("style.css: Content-Encoding %q, want none (too small to gain)", w.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestAssets_Caching(t *testing.T) {
+	a := newAssets(testAssetsFS)
+	version := a.files["app.js"].version
+
+	w := serveAsset(a, "/app.js", nil)
+	etag := w.Header().Get("ETag")
+	if etag != `"`+version+`"` || w.Header().Get("Cache-Control") != revalidate {
+		t.Errorf("ETag %q, Cache-Control %q", etag, w.Header().Get("Cache-Control"))
+	}
+	if w := serveAsset(a, "/app.js", http.Header{"If-None-Match": {etag}}); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("If-None-Match: status %d, want 304", w.Code)
+	}
+	gz := serveAsset(a, "/app.js", http.Header{"Accept-Encoding": {"gzip"}}).Header().Get("ETag")
+	br := serveAsset(a, "/app.js", http.Header{"Accept-Encoding": {"br"}}).Header().Get("ETag")
+	if gz == etag || br == etag || br == gz {
+		t.Errorf("identity, gzip and brotli ETags %q, %q, %q not distinct", etag, gz, br)
+	}
+
+	if w := serveAsset(a, "/app.js?v="+version, nil); w.Header().Get("Cache-Control") != immutable {
+		t.Errorf("current version: Cache-Control %q, want immutable", w.Header().Get("Cache-Control"))
+	}
+	if w := serveAsset(a, "/app.js?v=0123", nil); w.Header().Get("Cache-Control") != revalidate {
+		t.Errorf("stale version: Cache-Control %q, want revalidation", w.Header().Get("Cache-Control"))
+	}
+}
+
+func TestAssets_OverrideDir(t *testing.T) {
+	a := newAssets(testAssetsFS)
+	a.dir = fstest.MapFS{
+		"style.css": &fstest.MapFile{Data: []byte("body{color:red}")},
+		"img":       &fstest.MapFile{Mode: fs.ModeDir},
+	}
+	if w := serveAsset(a, "/style.css", nil); w.Body.String() != "body{color:red}" {
+		t.Errorf("overridden file: %q", w.Body)
+	}
+	if w := serveAsset(a, "/app.js", nil); w.Code != http.StatusOK || w.Body.String() != string(testAssetsFS["app.js"].Data) {
+		t.Errorf("embedded fallback: status %d", w.Code)
+	}
+	if w := serveAsset(a, "/img/logo.png", nil); w.Code != http.StatusOK {
+		t.Errorf("embedded fallback in a subdirectory: status %d", w.Code)
+	}
+
+	// Edits show at once, with a new version
+	a.dir.(fstest.MapFS)["style.css"] = &fstest.MapFile{Data: []byte("body{color:blue}")}
+	page := a.versionAssets([]byte(`<link href="static/style.css">`))
+	want := `<link href="static/style.css?v=` + newAsset("style.css", []byte("body{color:blue}")).version + `">`
+	if string(page) != want {
+		t.Errorf("versioned page = %s, want %s", page, want)
+	}
+	if w := serveAsset(a, "/style.css", nil); w.Body.String() != "body{color:blue}" {
+		t.Errorf("edited file: %q", w.Body)
+	}
+}
+
+func TestServeIndex_VersionedAssets(t *testing.T) {
+	h := NewHandlers(NewStatusBroadcaster(), noopCapture, FormConfig{}, testAssetsFS)
+	w := serveAsset(http.HandlerFunc(h.ServeIndex), "/", http.Header{"Accept-Encoding": {"gzip"}})
+	if w.Header().Get("Cache-Control") != revalidate || w.Header().Get("ETag") == "" {
+		t.Errorf("Cache-Control %q, ETag %q", w.Header().Get("Cache-Control"), w.Header().Get("ETag"))
+	}
+	body := w.Body.Bytes()
+	if w.Header().Get("Content-Encoding") == "gzip" {
+		zr, _ := gzip.NewReader(bytes.NewReader(body))
+		body, _ = io.ReadAll(zr)
+	}
+	for _, want := range []string{
+		`href="static/style.css?v=` + h.assets.files["style.css"].version + `"`,
+		`src="static/app.js?v=` + h.assets.files["app.js"].version + `"`,
+		`src="static/img/missing.png"`,
+	} {
+		if !bytes.Contains(body, []byte(want)) {
+			t.Errorf("index misses %s:\n%s", want, body)
+		}
+	}
+}
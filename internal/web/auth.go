@@ -0,0 +1,95 @@
+package web
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// authCookie holds the token once a browser has opened the page with ?token=,
+// so that fetch, EventSource and WebSocket requests (which cannot set an
+// Authorization header) are authenticated too.
+const authCookie = "pango_token"
+
+// AuthConfig configures access control for all routes. With Token, requests
+// must carry "Authorization: Bearer <token>", the pango_token cookie or a
+// ?token= query parameter. With Username and Password, HTTP basic auth is
+// accepted. Both may be set. All empty disables authentication.
+type AuthConfig struct {
+	Token    string
+	Username string
+	Password string
+}
+
+func (a AuthConfig) enabled() bool {
+	return a.Token != "" || a.Username != ""
+}
+
+// RequireAuth wraps next so that only authenticated requests reach it.
+// A valid ?token= also sets the token cookie for the following requests,
+// under base (see StripBasePath) so that the other services of a reverse
+// proxy do not receive it.
+func RequireAuth(next http.Handler, auth AuthConfig, base string) http.Handler {
+	if !auth.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.Token != "" {
+			if q := r.URL.Query().Get("token"); q != "" && equal(q, auth.Token) {
+				http.SetCookie(w, &http.Cookie{
+					Name:     authCookie,
+					Value:    auth.Token,
+					Path:     base + "/",
+					HttpOnly: true,
+					SameSite: http.SameSiteStrictMode,
+					Secure:   r.TLS != nil,
+				})
+				next.ServeHTTP(w, r)
+				return
+			}
+			if validToken(r, auth.Token) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		if auth.Username != "" {
+			if user, pass, ok := r.BasicAuth(); ok && equal(user, auth.Username) && equal(pass, auth.Password) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="PanGo", charset="UTF-8"`)
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// validToken reports whether r carries token as a bearer token or cookie.
+func validToken(r *http.Request, token string) bool {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if bearer, ok := strings.CutPrefix(h, "Bearer "); ok && equal(bearer, token) {
+			return true
+		}
+	}
+	if c, err := r.Cookie(authCookie); err == nil && equal(c.Value, token) {
+		return true
+	}
+	return false
+}
+
+// authorized reports whether r carries the bearer token or basic auth
+// credentials of a, for clients that cannot use the token cookie.
+func (a AuthConfig) authorized(r *http.Request) bool {
+	if a.Token != "" && validToken(r, a.Token) {
+		return true
+	}
+	if a.Username != "" {
+		user, pass, ok := r.BasicAuth()
+		return ok && equal(user, a.Username) && equal(pass, a.Password)
+	}
+	return false
+}
+
+// equal compares secrets in constant time.
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
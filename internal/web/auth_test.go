@@ -0,0 +1,168 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+const testToken = "0123456789abcdef"
+
+func TestRequireAuth_Disabled(t *testing.T) {
+	h := RequireAuth(okHandler, AuthConfig{}, "")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/run", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 without auth configured", w.Code)
+	}
+}
+
+func TestRequireAuth_Token(t *testing.T) {
+	h := RequireAuth(okHandler, AuthConfig{Token: testToken}, "")
+	cases := []struct {
+		name  string
+		setup func(r *http.Request)
+		want  int
+	}{
+		{"missing", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+testToken) }, http.StatusOK},
+		{"wrong_bearer", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"cookie", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: authCookie, Value: testToken}) }, http.StatusOK},
+		{"wrong_cookie", func(r *http.Request) { r.AddCookie(&http.Cookie{Name: authCookie, Value: "x"}) }, http.StatusUnauthorized},
+		{"basic_not_configured", func(r *http.Request) { r.SetBasicAuth("admin", testToken) }, http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/run", nil)
+			tc.setup(req)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d", w.Code, tc.want)
+			}
+		})
+	}
+}
+
+func TestRequireAuth_QueryTokenSetsCookie(t *testing.T) {
+	h := RequireAuth(okHandler, AuthConfig{Token: testToken}, "")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?token="+testToken, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != authCookie || !cookies[0].HttpOnly || cookies[0].Path != "/" {
+		t.Fatalf("cookies = %+v, want an HttpOnly %s cookie for /", cookies, authCookie)
+	}
+
+	// The cookie authenticates the following requests
+	req := httptest.NewRequest(http.MethodGet, "/status/stream", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("status with cookie = %d, want 200", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?token=wrong", nil))
+	if w.Code != http.StatusUnauthorized || len(w.Result().Cookies()) != 0 {
+		t.Errorf("wrong query token: status = %d, cookies = %v", w.Code, w.Result().Cookies())
+	}
+}
+
+func TestRequireAuth_CookieUnderBasePath(t *testing.T) {
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35})
+	srv.SetAuth(AuthConfig{Token: testToken})
+	srv.SetBasePath("/pango")
+
+	w := httptest.NewRecorder()
+	srv.Mux().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pango/?token="+testToken, nil))
+	cookies := w.Result().Cookies()
+	if w.Code != http.StatusOK || len(cookies) != 1 || cookies[0].Path != "/pango/" {
+		t.Errorf("status %d, cookies %+v, want the token cookie for /pango/", w.Code, cookies)
+	}
+}
+
+func TestRequireAuth_Basic(t *testing.T) {
+	h := RequireAuth(okHandler, AuthConfig{Username: "admin", Password: "secret"}, "")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", w.Code)
+	}
+	if w.Header().Get("WWW-Authenticate") == "" {
+		t.Error("missing WWW-Authenticate header: browsers would not prompt")
+	}
+
+	cases := []struct {
+		user, pass string
+		want       int
+	}{
+		{"admin", "secret", http.StatusOK},
+		{"admin", "wrong", http.StatusUnauthorized},
+		{"root", "secret", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth(tc.user, tc.pass)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s/%s: status = %d, want %d", tc.user, tc.pass, w.Code, tc.want)
+		}
+	}
+}
+
+func TestRequireAuth_TokenOrBasic(t *testing.T) {
+	h := RequireAuth(okHandler, AuthConfig{Token: testToken, Username: "admin", Password: "secret"}, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("bearer: status = %d, want 200", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetBasicAuth("admin", "secret")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("basic: status = %d, want 200", w.Code)
+	}
+}
+
+func TestServerMux_RequiresAuth(t *testing.T) {
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAuth(AuthConfig{Token: testToken})
+	mux := srv.Mux()
+
+	for _, path := range []string{"/", "/config", "/status/stream", "/static/app.js"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s: status = %d, want 401", path, w.Code)
+		}
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/cancel", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("POST /cancel: status = %d, want 401", w.Code)
+	}
+	for _, path := range []string{"/healthz", "/readyz"} {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d, want 200 without credentials", path, w.Code)
+		}
+	}
+}
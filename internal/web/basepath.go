@@ -0,0 +1,55 @@
+package web
+
+import (
+	"bytes"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StripBasePath serves next under base (e.g. "/pango"), for a reverse proxy
+// that publishes the rig alongside other services. Requests under base reach
+// next without it; base itself redirects to base + "/". Other requests are
+// served as is, so that the server also works behind a proxy that strips
+// the prefix and when reached directly. An empty base returns next.
+func StripBasePath(next http.Handler, base string) http.Handler {
+	if base == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == base {
+			target := base + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		rest, ok := strings.CutPrefix(r.URL.Path, base+"/")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = "/" + rest
+		r2.URL.RawPath = ""
+		next.ServeHTTP(w, r2)
+	})
+}
+
+// withBase inserts a <base> element at the start of the <head> of page, so
+// that its relative URLs (assets, API) resolve under base whatever URL the
+// page was reached at.
+func withBase(page []byte, base string) []byte {
+	tag := []byte("\n  <base href=\"" + html.EscapeString(base) + "/\">")
+	i := bytes.Index(page, []byte("<head>"))
+	if i < 0 {
+		return page
+	}
+	i += len("<head>")
+	return append(page[:i:i], append(tag, page[i:]...)...)
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ---------- StripBasePath ----------
+
+func TestStripBasePath(t *testing.T) {
+	var got string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Path
+	})
+	h := StripBasePath(next, "/pango")
+
+	cases := []struct {
+		path     string
+		wantPath string // path seen by next; "" = not called
+		location string // redirect target
+	}{
+		{"/pango/", "/", ""},
+		{"/pango/api/v1/status", "/api/v1/status", ""},
+		{"/api/v1/status", "/api/v1/status", ""}, // prefix stripped by the proxy
+		{"/pangolin/static/app.js", "/pangolin/static/app.js", ""},
+		{"/pango", "", "/pango/"},
+		{"/pango?token=abc", "", "/pango/?token=abc"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			got = ""
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if got != tc.wantPath {
+				t.Errorf("next saw %q, want %q", got, tc.wantPath)
+			}
+			if tc.location != "" && (w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != tc.location) {
+				t.Errorf("status %d, Location %q; want 301 to %q", w.Code, w.Header().Get("Location"), tc.location)
+			}
+		})
+	}
+}
+
+func TestWithBase(t *testing.T) {
+	page := []byte("<html>\n<head>\n  <title>x</title>\n</head></html>")
+	got := string(withBase(page, "/pango"))
+	if !strings.Contains(got, "<head>\n  <base href=\"/pango/\">\n  <title>") {
+		t.Errorf("page = %s", got)
+	}
+	if string(withBase([]byte("<p>no head</p>"), "/pango")) != "<p>no head</p>" {
+		t.Error("page without <head> changed")
+	}
+}
+
+// ---------- Server ----------
+
+func TestServer_BasePath(t *testing.T) {
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	srv.SetBasePath("/pango")
+	mux := srv.Mux()
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	for _, path := range []string{"/pango/", "/pango/api/v1/config", "/pango/healthz", "/pango/static/app.js", "/", "/api/v1/config"} {
+		if w := get(path); w.Code != http.StatusOK {
+			t.Errorf("GET %s: status %d, want 200", path, w.Code)
+		}
+	}
+
+	if body := get("/pango/").Body.String(); !strings.Contains(body, `<base href="/pango/">`) {
+		t.Errorf("index without base element:\n%s", body)
+	}
+
+	if got, want := get("/pango/config").Header().Get("Link"), `</pango/api/v1/config>; rel="successor-version"`; got != want {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+
+	var doc struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+	}
+	json.Unmarshal(get("/pango/api/v1/openapi.json").Body.Bytes(), &doc)
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != "/pango/api/v1" {
+		t.Errorf("servers = %+v, want /pango/api/v1", doc.Servers)
+	}
+}
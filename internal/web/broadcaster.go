@@ -2,66 +2,236 @@ package web
 
 import (
 	"encoding/json"
+	"io"
 	"strings"
 	"sync"
 	"time"
 )
 
+// Event types carried in StatusEvent.Type.
+const (
+	EventLog      = "log"      // free-text message in Msg, with Level
+	EventState    = "state"    // capture state change; Data is a capture.Status
+	EventProgress = "progress" // shot completed; Data is a capture.Progress
+	EventShot     = "shot"     // shutter released; Data is a session.Shot
+	EventMove     = "move"     // head moved; Data is a capture.Position
+	EventPosition = "position" // head position sampled during moves; Data is a capture.HeadPosition
+	EventControl  = "control"  // control claimed or released; Data is a ControlStatus
+	EventShutdown = "shutdown" // server stopping, last event of every stream; Msg says why
+)
+
+// Number of recent events kept to replay to reconnecting clients
+// (SubscribeSince).
+const replayBufferSize = 256
+
+// transient lists the event types not kept for replay: they are sent
+// several times a second and outdated by the next one, and would push the
+// other events out of the buffer.
+var transient = map[string]bool{EventPosition: true}
+
 // StatusEvent represents a single status message for SSE.
+// Log events carry Level and Msg; other types carry a structured Data payload.
+// RequestID links messages about a capture to the request that started it.
+// ID numbers events in send order (from 1, per server run).
 type StatusEvent struct {
-	Time  string `json:"t"`
-	Level string `json:"l,omitempty"`
-	Msg   string `json:"msg"`
+	ID        uint64 `json:"id"`
+	Time      string `json:"t"`
+	Type      string `json:"type"`
+	Level     string `json:"l,omitempty"`
+	Msg       string `json:"msg,omitempty"`
+	Data      any    `json:"data,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
 }
 
 // StatusBroadcaster distributes status messages to multiple SSE clients.
+// Recent events are kept for SubscribeSince.
 type StatusBroadcaster struct {
 	mu      sync.RWMutex
-	clients map[chan string]struct{}
+	clients map[chan string]EventFilter
+	closed  bool
+	lastID  uint64
+	recent  []sentEvent // oldest first, at most replayBufferSize
+	record  *recorder   // every event as a JSON line (SetRecorder); nil = none
+}
+
+// sentEvent is a sent event kept for replay, with its JSON encoding.
+type sentEvent struct {
+	evt     StatusEvent
+	payload string
 }
 
 // NewStatusBroadcaster creates a new broadcaster.
 func NewStatusBroadcaster() *StatusBroadcaster {
 	return &StatusBroadcaster{
-		clients: make(map[chan string]struct{}),
+		clients: make(map[chan string]EventFilter),
 	}
 }
 
 // Subscribe returns a channel that receives broadcast messages and a cleanup function.
 // The caller must call the returned cleanup when done (e.g. on client disconnect).
+// After Close, the channel is returned closed.
 func (b *StatusBroadcaster) Subscribe() (<-chan string, func()) {
-	ch := make(chan string, 64)
+	return b.SubscribeFiltered(EventFilter{})
+}
+
+// SubscribeFiltered is Subscribe for the events matching f only.
+func (b *StatusBroadcaster) SubscribeFiltered(f EventFilter) (<-chan string, func()) {
 	b.mu.Lock()
-	b.clients[ch] = struct{}{}
-	b.mu.Unlock()
+	defer b.mu.Unlock()
+	return b.subscribeLocked(f)
+}
+
+// SubscribeSince is SubscribeFiltered for a client that already received
+// events up to lastID (e.g. SSE Last-Event-ID): it also returns the kept
+// events matching f sent after lastID, with no gap or duplicate before those
+// on the channel. If lastID is unknown (from before a server restart), all
+// kept events are returned. Events older than the replay buffer are lost.
+func (b *StatusBroadcaster) SubscribeSince(lastID uint64, f EventFilter) ([]string, <-chan string, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if lastID > b.lastID {
+		lastID = 0
+	}
+	var missed []string
+	for _, sent := range b.recent {
+		if sent.evt.ID > lastID && f.Match(sent.evt) {
+			missed = append(missed, sent.payload)
+		}
+	}
+	ch, unsub := b.subscribeLocked(f)
+	return missed, ch, unsub
+}
+
+func (b *StatusBroadcaster) subscribeLocked(f EventFilter) (<-chan string, func()) {
+	ch := make(chan string, 64)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.clients[ch] = f
 
 	unsub := func() {
 		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.clients[ch]; ok { // not already closed by Close
+			delete(b.clients, ch)
+			close(ch)
+		}
+	}
+	return ch, unsub
+}
+
+// Close sends a final EventShutdown event with msg, then closes all client
+// channels so that streams end (letting the HTTP server shut down).
+func (b *StatusBroadcaster) Close(msg string) {
+	b.send(StatusEvent{
+		Time: time.Now().Format(time.RFC3339),
+		Type: EventShutdown,
+		Msg:  msg,
+	})
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.clients {
 		delete(b.clients, ch)
-		b.mu.Unlock()
 		close(ch)
 	}
-	return ch, unsub
 }
 
-// Broadcast sends a message to all subscribed clients.
-// Messages are sent as JSON: {"t":"...","l":"info","msg":"..."}
+// SetRecorder writes every event sent from now on to w, one JSON line each
+// in ID order, whether or not clients are subscribed: an on-disk event log
+// of an unattended capture. The lines are written from a goroutine of their
+// own, so that a slow disk does not hold up the clients; SetRecorder
+// returns once those of the previous recorder are written, so the caller
+// may close it. Write errors are ignored. nil stops recording.
+func (b *StatusBroadcaster) SetRecorder(w io.Writer) {
+	var rec *recorder
+	if w != nil {
+		rec = newRecorder(w)
+	}
+	b.mu.Lock()
+	old := b.record
+	b.record = rec
+	b.mu.Unlock()
+	if old != nil {
+		old.stop()
+	}
+}
+
+// Clients returns the number of subscribed clients.
+func (b *StatusBroadcaster) Clients() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.clients)
+}
+
+// Recent returns the last n events sent, oldest first, at most
+// replayBufferSize; the frequent position samples are not kept.
+func (b *StatusBroadcaster) Recent(n int) []StatusEvent {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	sent := b.recent[max(len(b.recent)-n, 0):]
+	events := make([]StatusEvent, len(sent))
+	for i, s := range sent {
+		events[i] = s.evt
+	}
+	return events
+}
+
+// Broadcast sends a log message to all subscribed clients.
+// Messages are sent as JSON: {"t":"...","type":"log","l":"info","msg":"..."}
 // Slow clients may miss messages (non-blocking, buffered).
 func (b *StatusBroadcaster) Broadcast(level, msg string) {
-	evt := StatusEvent{
-		Time:  time.Now().Format(time.RFC3339),
-		Level: level,
-		Msg:   msg,
-	}
+	b.BroadcastRequest("", level, msg)
+}
+
+// BroadcastRequest sends a log message about the request requestID (e.g. the
+// capture it started); the ID is omitted when empty.
+func (b *StatusBroadcaster) BroadcastRequest(requestID, level, msg string) {
+	b.send(StatusEvent{
+		Time:      time.Now().Format(time.RFC3339),
+		Type:      EventLog,
+		Level:     level,
+		Msg:       msg,
+		RequestID: requestID,
+	})
+}
+
+// Emit sends a structured event of the given type to all subscribed clients:
+// {"t":"...","type":"progress","data":{...}}. Its signature matches
+// capture.EventFunc.
+func (b *StatusBroadcaster) Emit(eventType string, data any) {
+	b.send(StatusEvent{
+		Time: time.Now().Format(time.RFC3339),
+		Type: eventType,
+		Data: data,
+	})
+}
+
+func (b *StatusBroadcaster) send(evt StatusEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	evt.ID = b.lastID + 1
 	data, err := json.Marshal(evt)
 	if err != nil {
 		return
 	}
 	payload := string(data)
+	b.lastID = evt.ID
+	if b.record != nil {
+		b.record.add(append(data, '\n'))
+	}
+	if !transient[evt.Type] {
+		if len(b.recent) == replayBufferSize {
+			b.recent = append(b.recent[:0], b.recent[1:]...)
+		}
+		b.recent = append(b.recent, sentEvent{evt: evt, payload: payload})
+	}
 
-	b.mu.RLock()
-	defer b.mu.RUnlock()
-	for ch := range b.clients {
+	for ch, f := range b.clients {
+		if !f.Match(evt) {
+			continue
+		}
 		select {
 		case ch <- payload:
 		default:
@@ -70,12 +240,72 @@ func (b *StatusBroadcaster) Broadcast(level, msg string) {
 	}
 }
 
+// recorder writes the lines passed to add to w, in order, from its own
+// goroutine.
+type recorder struct {
+	w       io.Writer
+	mu      sync.Mutex
+	pending [][]byte
+	stopped bool
+	wake    chan struct{} // buffered: lines pending or stopped
+	done    chan struct{} // closed once the last lines are written
+}
+
+func newRecorder(w io.Writer) *recorder {
+	r := &recorder{w: w, wake: make(chan struct{}, 1), done: make(chan struct{})}
+	go r.run()
+	return r
+}
+
+// add queues line; it never waits for the disk.
+func (r *recorder) add(line []byte) {
+	r.mu.Lock()
+	r.pending = append(r.pending, line)
+	r.mu.Unlock()
+	notify(r.wake)
+}
+
+// stop writes the lines still pending, then ends the goroutine.
+func (r *recorder) stop() {
+	r.mu.Lock()
+	r.stopped = true
+	r.mu.Unlock()
+	notify(r.wake)
+	<-r.done
+}
+
+func (r *recorder) run() {
+	defer close(r.done)
+	for range r.wake {
+		r.mu.Lock()
+		lines, stopped := r.pending, r.stopped
+		r.pending = nil
+		r.mu.Unlock()
+		for _, line := range lines {
+			r.w.Write(line)
+		}
+		if stopped {
+			return
+		}
+	}
+}
+
+// notify wakes the goroutine waiting on ch, unless it is already due to
+// wake up.
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
 // BroadcastMsg is a convenience for level "info".
 func (b *StatusBroadcaster) BroadcastMsg(msg string) {
 	b.Broadcast("info", msg)
 }
 
-// BroadcastWriter implements io.Writer; each Write broadcasts the content to SSE clients.
+// BroadcastWriter implements io.Writer; each Write broadcasts the content to
+// SSE clients, at the level of its debug tag (see debugLineLevel).
 func BroadcastWriter(b *StatusBroadcaster) *broadcastWriter {
 	return &broadcastWriter{b: b}
 }
@@ -88,7 +318,7 @@ type broadcastWriter struct {
 func (w *broadcastWriter) Write(p []byte) (n int, err error) {
 	msg := strings.TrimSpace(string(p))
 	if msg != "" {
-		w.b.BroadcastMsg(msg)
+		w.b.Broadcast(debugLineLevel(msg), msg)
 	}
 	return len(p), nil
 }
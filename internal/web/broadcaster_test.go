@@ -1,7 +1,11 @@
 package web
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -25,11 +29,48 @@ func TestBroadcaster_SubscribeAndReceive(t *testing.T) {
 		if evt.Level != "info" {
 			t.Errorf("level = %q, want \"info\"", evt.Level)
 		}
+		if evt.Type != EventLog {
+			t.Errorf("type = %q, want %q", evt.Type, EventLog)
+		}
 	case <-time.After(time.Second):
 		t.Fatal("timeout waiting for broadcast")
 	}
 }
 
+func TestBroadcaster_EmitStructuredEvent(t *testing.T) {
+	b := NewStatusBroadcaster()
+	ch, unsub := b.Subscribe()
+	defer unsub()
+
+	b.Emit(EventProgress, map[string]int{"shots_done": 3, "shots_total": 12})
+
+	select {
+	case msg := <-ch:
+		var evt struct {
+			Type string `json:"type"`
+			Msg  string `json:"msg"`
+			Data struct {
+				ShotsDone  int `json:"shots_done"`
+				ShotsTotal int `json:"shots_total"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(msg), &evt); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if evt.Type != EventProgress {
+			t.Errorf("type = %q, want %q", evt.Type, EventProgress)
+		}
+		if evt.Data.ShotsDone != 3 || evt.Data.ShotsTotal != 12 {
+			t.Errorf("data = %+v, want 3/12", evt.Data)
+		}
+		if evt.Msg != "" {
+			t.Errorf("structured event should have no msg, got %q", evt.Msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+}
+
 func TestBroadcaster_MultipleSubscribers(t *testing.T) {
 	b := NewStatusBroadcaster()
 	ch1, unsub1 := b.Subscribe()
@@ -55,6 +96,20 @@ func TestBroadcaster_MultipleSubscribers(t *testing.T) {
 	}
 }
 
+func TestBroadcaster_Clients(t *testing.T) {
+	b := NewStatusBroadcaster()
+	_, unsub1 := b.Subscribe()
+	_, unsub2 := b.Subscribe()
+	if got := b.Clients(); got != 2 {
+		t.Errorf("Clients() = %d, want 2", got)
+	}
+	unsub1()
+	unsub2()
+	if got := b.Clients(); got != 0 {
+		t.Errorf("Clients() after unsubscribe = %d, want 0", got)
+	}
+}
+
 func TestBroadcaster_UnsubscribeClosesChannel(t *testing.T) {
 	b := NewStatusBroadcaster()
 	ch, unsub := b.Subscribe()
@@ -67,6 +122,187 @@ func TestBroadcaster_UnsubscribeClosesChannel(t *testing.T) {
 	}
 }
 
+func TestBroadcaster_Close(t *testing.T) {
+	b := NewStatusBroadcaster()
+	ch, unsub := b.Subscribe()
+	b.Close("bye")
+	unsub() // after Close: must not close the channel twice
+
+	msg, ok := <-ch
+	if !ok {
+		t.Fatal("expected the shutdown event before the channel closes")
+	}
+	var evt StatusEvent
+	if err := json.Unmarshal([]byte(msg), &evt); err != nil {
+		t.Fatal(err)
+	}
+	if evt.Type != EventShutdown || evt.Msg != "bye" {
+		t.Errorf("event = %+v, want shutdown with msg", evt)
+	}
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after Close")
+	}
+
+	late, _ := b.Subscribe()
+	if _, ok := <-late; ok {
+		t.Error("Subscribe after Close: expected a closed channel")
+	}
+	if b.Clients() != 0 {
+		t.Errorf("Clients = %d, want 0", b.Clients())
+	}
+}
+
+func TestBroadcaster_SubscribeSince(t *testing.T) {
+	b := NewStatusBroadcaster()
+	for i := 0; i < replayBufferSize+10; i++ {
+		b.Broadcast("info", fmt.Sprint(i))
+	}
+	ids := func(msgs []string) []uint64 {
+		var out []uint64
+		for _, msg := range msgs {
+			var evt StatusEvent
+			if err := json.Unmarshal([]byte(msg), &evt); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, evt.ID)
+		}
+		return out
+	}
+
+	missed, _, unsub := b.SubscribeSince(replayBufferSize+7, EventFilter{})
+	unsub()
+	if got := ids(missed); len(got) != 3 || got[0] != replayBufferSize+8 || got[2] != replayBufferSize+10 {
+		t.Errorf("missed IDs = %v, want the 3 after %d", got, replayBufferSize+7)
+	}
+
+	// Too old: only what the buffer kept; unknown (server restarted): everything kept.
+	for _, last := range []uint64{0, 1, replayBufferSize + 100} {
+		missed, _, unsub := b.SubscribeSince(last, EventFilter{})
+		unsub()
+		if got := ids(missed); len(got) != replayBufferSize || got[0] != 11 {
+			t.Errorf("SubscribeSince(%d): %d events from %v, want %d from 11", last, len(got), got[:1], replayBufferSize)
+		}
+	}
+
+	missed, ch, unsub := b.SubscribeSince(replayBufferSize+10, EventFilter{})
+	defer unsub()
+	b.Broadcast("info", "live")
+	if len(missed) != 0 {
+		t.Errorf("up to date: missed = %v, want none", missed)
+	}
+	if got := ids([]string{<-ch}); got[0] != replayBufferSize+11 {
+		t.Errorf("live event ID = %d, want %d", got[0], replayBufferSize+11)
+	}
+}
+
+func TestBroadcaster_PositionNotReplayed(t *testing.T) {
+	b := NewStatusBroadcaster()
+	ch, unsub := b.Subscribe()
+	defer unsub()
+	b.Emit(EventMove, map[string]int{"pan_steps": 10})
+	for range replayBufferSize + 1 {
+		b.Emit(EventPosition, map[string]int{"pan_steps": 5})
+	}
+	if msg := <-ch; !strings.Contains(msg, `"type":"move"`) {
+		t.Errorf("first live event = %s", msg)
+	}
+	if msg := <-ch; !strings.Contains(msg, `"type":"position"`) {
+		t.Errorf("second live event = %s, want a position", msg)
+	}
+
+	missed, _, unsub2 := b.SubscribeSince(0, EventFilter{})
+	unsub2()
+	if len(missed) != 1 || !strings.Contains(missed[0], `"type":"move"`) {
+		t.Errorf("replayed %d events, want only the move: %v", len(missed), missed)
+	}
+}
+
+func TestBroadcaster_Recorder(t *testing.T) {
+	b := NewStatusBroadcaster()
+	b.BroadcastMsg("before recording")
+	var rec bytes.Buffer
+	b.SetRecorder(&rec)
+	b.Emit(EventProgress, map[string]int{"shots_done": 1}) // no client subscribed
+	b.Emit(EventPosition, map[string]int{"pan_steps": 5})
+	b.Broadcast("error", "shot failed")
+	b.Close("done")
+	b.SetRecorder(nil)
+	b.BroadcastMsg("after recording")
+
+	lines := strings.Split(strings.TrimSuffix(rec.String(), "\n"), "\n")
+	want := []string{EventProgress, EventPosition, EventLog, EventShutdown}
+	if len(lines) != len(want) {
+		t.Fatalf("recorded %d lines, want %d:\n%s", len(lines), len(want), rec.String())
+	}
+	for i, line := range lines {
+		var evt StatusEvent
+		if err := json.Unmarshal([]byte(line), &evt); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if evt.Type != want[i] || evt.ID != uint64(i+2) {
+			t.Errorf("line %d = %s, want event %d of type %s", i+1, line, i+2, want[i])
+		}
+	}
+}
+
+// blockingWriter blocks each Write until release is closed.
+type blockingWriter struct {
+	started chan struct{} // closed at the first Write
+	release chan struct{}
+	once    sync.Once
+	buf     bytes.Buffer
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	return w.buf.Write(p)
+}
+
+func TestBroadcaster_SlowRecorder(t *testing.T) {
+	b := NewStatusBroadcaster()
+	w := &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+	b.SetRecorder(w)
+	ch, unsub := b.Subscribe()
+	defer unsub()
+
+	b.BroadcastMsg("first")
+	<-w.started
+	// The recorder is stuck writing the first event: sending and
+	// subscribing go on.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := range 100 {
+			b.Broadcast("info", fmt.Sprint("event ", i))
+		}
+		_, unsub := b.Subscribe()
+		unsub()
+		b.Clients()
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("broadcaster blocked by the recorder")
+	}
+	if n := len(ch); n != 64 {
+		t.Errorf("client received %d events, want a full channel", n)
+	}
+
+	close(w.release)
+	b.SetRecorder(nil)
+	lines := strings.Split(strings.TrimSuffix(w.buf.String(), "\n"), "\n")
+	if len(lines) != 101 {
+		t.Fatalf("recorded %d lines, want 101", len(lines))
+	}
+	for i, line := range lines {
+		var evt StatusEvent
+		if err := json.Unmarshal([]byte(line), &evt); err != nil || evt.ID != uint64(i+1) {
+			t.Errorf("line %d = %s, want event %d", i+1, line, i+1)
+		}
+	}
+}
+
 func TestBroadcaster_FullChannelDropsMessage(t *testing.T) {
 	b := NewStatusBroadcaster()
 	ch, unsub := b.Subscribe()
@@ -0,0 +1,245 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ControlHeader carries the token of a control claim (see ControlLock).
+// WebSocket clients, which cannot set headers from a browser, pass it in
+// the control_token query parameter instead.
+const ControlHeader = "X-Control-Token"
+
+// How long a claim lasts without renewal. The page renews its claim well
+// before, so a closed tab or a phone gone to sleep releases control soon.
+const controlLease = 60 * time.Second
+
+// Maximum length of the holder name shown to other clients.
+const maxHolderLength = 64
+
+// controlledRoutes are the routes that act on the head or the capture: once
+// control is claimed, only the holder may call them.
+var controlledRoutes = map[string]bool{
+	"POST /run":                      true,
+	"POST /cancel":                   true,
+	"POST /pause":                    true,
+	"POST /resume":                   true,
+	"POST /jog":                      true,
+	"POST /stop":                     true,
+	"GET /jog/ws":                    true,
+	"POST /jobs":                     true,
+	"POST /jobs/{id}/cancel":         true,
+	"POST /jobs/{id}/move":           true,
+	"PUT /config":                    true,
+	"POST /config/upload":            true,
+	"POST /config/defaults":          true,
+	"POST /profiles/{name}/activate": true,
+	"PUT /log-level":                 true,
+	"POST /sync/run":                 true,
+	"POST /sync/shoot":               true,
+}
+
+// ErrControlClaimed is returned when another client holds control.
+var ErrControlClaimed = errors.New("control is claimed by another client")
+
+// ControlLock lets one client claim control of the rig, so that two people
+// on the same network do not jog or cancel each other's capture. Unclaimed,
+// every client has control; claimed, the others only read the status until
+// the holder releases it or the claim expires (controlLease without
+// renewal). Claims are advisory: any client can take over with force.
+type ControlLock struct {
+	mu        sync.Mutex
+	token     string
+	holder    string
+	claimedAt time.Time
+	expires   time.Time
+	now       func() time.Time
+}
+
+// NewControlLock returns an unclaimed lock.
+func NewControlLock() *ControlLock {
+	return &ControlLock{now: time.Now}
+}
+
+// ControlClaim is a control request (POST /control/claim).
+type ControlClaim struct {
+	Holder string `json:"holder,omitempty"` // name shown to other clients
+	Force  bool   `json:"force,omitempty"`  // take over a claim held by another client
+}
+
+// ControlStatus describes who holds control (GET /control).
+type ControlStatus struct {
+	Claimed   bool       `json:"claimed"`
+	Holder    string     `json:"holder,omitempty"`
+	Mine      bool       `json:"mine"` // the request carried the holder's token
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Token     string     `json:"token,omitempty"` // only in the reply to a claim
+}
+
+// Claim gives control to the client with token (a renewal when it already
+// holds it, "" for a new claim) and returns the token to send with
+// controlled requests. It fails with ErrControlClaimed when another client
+// holds control, unless force is set.
+func (c *ControlLock) Claim(token, holder string, force bool) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	held := c.heldLocked(now)
+	if held && token != c.token {
+		if !force {
+			return "", fmt.Errorf("%w (%s)", ErrControlClaimed, c.holderLocked())
+		}
+		held = false
+	}
+	if !held {
+		c.token = newRequestID() + newRequestID()
+		c.claimedAt = now
+	}
+	c.holder = holder
+	c.expires = now.Add(controlLease)
+	return c.token, nil
+}
+
+// Release gives up control. It reports false when token does not hold it.
+func (c *ControlLock) Release(token string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.heldLocked(c.now()) || token != c.token {
+		return false
+	}
+	c.token = ""
+	return true
+}
+
+// Status returns who holds control, as seen by the client with token.
+func (c *ControlLock) Status(token string) ControlStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.heldLocked(c.now()) {
+		return ControlStatus{}
+	}
+	claimedAt, expires := c.claimedAt, c.expires
+	return ControlStatus{
+		Claimed:   true,
+		Holder:    c.holder,
+		Mine:      token == c.token,
+		ClaimedAt: &claimedAt,
+		ExpiresAt: &expires,
+	}
+}
+
+// holderLocked names the client holding control, for error messages.
+func (c *ControlLock) holderLocked() string {
+	if c.holder == "" {
+		return "another client"
+	}
+	return c.holder
+}
+
+func (c *ControlLock) heldLocked(now time.Time) bool {
+	return c.token != "" && now.Before(c.expires)
+}
+
+// controlToken returns the control token of r, from ControlHeader or the
+// control_token query parameter.
+func controlToken(r *http.Request) string {
+	if t := r.Header.Get(ControlHeader); t != "" {
+		return t
+	}
+	return r.URL.Query().Get("control_token")
+}
+
+// RequireControl wraps next to answer 423 Locked when another client holds
+// control of the rig. With a nil lock, next is returned as is.
+func RequireControl(next http.Handler, lock *ControlLock) http.Handler {
+	if lock == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := lock.check(controlToken(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusLocked)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// check returns an error naming the holder when token may not act on the rig.
+func (c *ControlLock) check(token string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.heldLocked(c.now()) || token == c.token {
+		return nil
+	}
+	return fmt.Errorf("%w (%s)", ErrControlClaimed, c.holderLocked())
+}
+
+// HandleControl handles GET /control: who holds control of the rig.
+func (h *Handlers) HandleControl(w http.ResponseWriter, r *http.Request) {
+	if h.Control == nil {
+		writeJSON(w, http.StatusOK, ControlStatus{})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Control.Status(controlToken(r)))
+}
+
+// HandleClaimControl handles POST /control/claim: claims control of the rig,
+// or renews the claim of the client sending its token. The reply carries
+// the token to send in the X-Control-Token header of controlled requests.
+// Fails with 409 when another client holds control, unless force is set.
+func (h *Handlers) HandleClaimControl(w http.ResponseWriter, r *http.Request) {
+	if h.Control == nil {
+		http.Error(w, "control lock not available", http.StatusServiceUnavailable)
+		return
+	}
+	var claim ControlClaim
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&claim); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	claim.Holder = strings.TrimSpace(claim.Holder)
+	if len(claim.Holder) > maxHolderLength {
+		http.Error(w, fmt.Sprintf("holder must be at most %d characters", maxHolderLength), http.StatusBadRequest)
+		return
+	}
+	prev := controlToken(r)
+	token, err := h.Control.Claim(prev, claim.Holder, claim.Force)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	status := h.Control.Status(token)
+	if token != prev {
+		h.emitControl(status)
+	}
+	status.Token = token
+	writeJSON(w, http.StatusOK, status)
+}
+
+// HandleReleaseControl handles POST /control/release: gives up control.
+// Fails with 409 when the client does not hold it.
+func (h *Handlers) HandleReleaseControl(w http.ResponseWriter, r *http.Request) {
+	if h.Control == nil || !h.Control.Release(controlToken(r)) {
+		http.Error(w, "control is not held by this client", http.StatusConflict)
+		return
+	}
+	h.emitControl(ControlStatus{})
+	writeJSON(w, http.StatusOK, ControlStatus{})
+}
+
+// emitControl tells status clients that control changed hands.
+func (h *Handlers) emitControl(status ControlStatus) {
+	if h.Broadcaster == nil {
+		return
+	}
+	status.Mine = false
+	h.Broadcaster.Emit(EventControl, status)
+}
@@ -0,0 +1,238 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestControlLock returns a lock whose clock is advanced by moving *now.
+func newTestControlLock(now *time.Time) *ControlLock {
+	c := NewControlLock()
+	c.now = func() time.Time { return *now }
+	return c
+}
+
+// ---------- ControlLock ----------
+
+func TestControlLock_ClaimRelease(t *testing.T) {
+	now := time.Date(2026, 6, 21, 21, 30, 0, 0, time.UTC)
+	c := newTestControlLock(&now)
+	if err := c.check(""); err != nil {
+		t.Fatalf("unclaimed lock refused a client: %v", err)
+	}
+
+	token, err := c.Claim("", "Alice", false)
+	if err != nil || token == "" {
+		t.Fatalf("Claim = %q, %v", token, err)
+	}
+	if err := c.check(token); err != nil {
+		t.Errorf("holder refused: %v", err)
+	}
+	err = c.check("")
+	if !errors.Is(err, ErrControlClaimed) || !strings.Contains(err.Error(), "Alice") {
+		t.Errorf("other client: err = %v, want ErrControlClaimed naming Alice", err)
+	}
+	if _, err := c.Claim("", "Bob", false); !errors.Is(err, ErrControlClaimed) {
+		t.Errorf("second claim: err = %v, want ErrControlClaimed", err)
+	}
+	if st := c.Status(token); !st.Claimed || !st.Mine || st.Holder != "Alice" {
+		t.Errorf("holder status = %+v", st)
+	}
+	if st := c.Status("other"); !st.Claimed || st.Mine {
+		t.Errorf("other status = %+v", st)
+	}
+
+	if c.Release("other") {
+		t.Error("Release succeeded without the token")
+	}
+	if !c.Release(token) {
+		t.Fatal("Release failed with the token")
+	}
+	if st := c.Status(token); st.Claimed {
+		t.Errorf("status after release = %+v", st)
+	}
+	if err := c.check(""); err != nil {
+		t.Errorf("released lock refused a client: %v", err)
+	}
+}
+
+func TestControlLock_RenewAndExpire(t *testing.T) {
+	now := time.Date(2026, 6, 21, 21, 30, 0, 0, time.UTC)
+	c := newTestControlLock(&now)
+	token, _ := c.Claim("", "Alice", false)
+
+	now = now.Add(controlLease - time.Second)
+	renewed, err := c.Claim(token, "Alice", false)
+	if err != nil || renewed != token {
+		t.Fatalf("renewal = %q, %v, want the same token", renewed, err)
+	}
+	now = now.Add(controlLease - time.Second)
+	if err := c.check(""); err == nil {
+		t.Error("renewed claim expired early")
+	}
+
+	now = now.Add(2 * time.Second)
+	if err := c.check(""); err != nil {
+		t.Errorf("expired claim still refuses clients: %v", err)
+	}
+	if _, err := c.Claim("", "Bob", false); err != nil {
+		t.Errorf("claim after expiry: %v", err)
+	}
+}
+
+func TestControlLock_Force(t *testing.T) {
+	now := time.Now()
+	c := newTestControlLock(&now)
+	old, _ := c.Claim("", "Alice", false)
+	token, err := c.Claim("", "Bob", true)
+	if err != nil || token == old {
+		t.Fatalf("forced claim = %q, %v, want a new token", token, err)
+	}
+	if err := c.check(old); err == nil {
+		t.Error("previous holder keeps control after a takeover")
+	}
+}
+
+// ---------- Handlers ----------
+
+func postControl(h *Handlers, handler http.HandlerFunc, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/control/claim", strings.NewReader(body))
+	if token != "" {
+		req.Header.Set(ControlHeader, token)
+	}
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+func TestHandleClaimControl(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	ch, unsub := h.Broadcaster.Subscribe()
+	defer unsub()
+
+	w := postControl(h, h.HandleClaimControl, "", `{"holder":"Alice"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("claim: status = %d, body %s", w.Code, w.Body)
+	}
+	var st ControlStatus
+	json.NewDecoder(w.Body).Decode(&st)
+	if !st.Claimed || !st.Mine || st.Holder != "Alice" || st.Token == "" || st.ExpiresAt == nil {
+		t.Fatalf("claim reply = %+v", st)
+	}
+	select {
+	case msg := <-ch:
+		if !strings.Contains(msg, `"type":"control"`) || strings.Contains(msg, st.Token) {
+			t.Errorf("event = %s, want a control event without the token", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("no control event")
+	}
+
+	if w := postControl(h, h.HandleClaimControl, "", `{"holder":"Bob"}`); w.Code != http.StatusConflict {
+		t.Errorf("claim by another client: status = %d, want 409", w.Code)
+	}
+	if w := postControl(h, h.HandleClaimControl, st.Token, ""); w.Code != http.StatusOK {
+		t.Errorf("renewal without body: status = %d, want 200", w.Code)
+	}
+	if w := postControl(h, h.HandleClaimControl, "", `{"holder":"`+strings.Repeat("x", maxHolderLength+1)+`"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("long holder: status = %d, want 400", w.Code)
+	}
+	if w := postControl(h, h.HandleClaimControl, "", `{`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid JSON: status = %d, want 400", w.Code)
+	}
+
+	// GET /control as seen by the holder and by others
+	for _, tc := range []struct {
+		token string
+		mine  bool
+	}{{st.Token, true}, {"", false}} {
+		req := httptest.NewRequest(http.MethodGet, "/control", nil)
+		req.Header.Set(ControlHeader, tc.token)
+		w := httptest.NewRecorder()
+		h.HandleControl(w, req)
+		var got ControlStatus
+		json.NewDecoder(w.Body).Decode(&got)
+		if !got.Claimed || got.Mine != tc.mine || got.Token != "" {
+			t.Errorf("GET /control with token %q = %+v", tc.token, got)
+		}
+	}
+
+	if w := postControl(h, h.HandleReleaseControl, "", ""); w.Code != http.StatusConflict {
+		t.Errorf("release without token: status = %d, want 409", w.Code)
+	}
+	if w := postControl(h, h.HandleReleaseControl, st.Token, ""); w.Code != http.StatusOK {
+		t.Errorf("release: status = %d, want 200", w.Code)
+	}
+}
+
+func TestHandleClaimControl_NotAvailable(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Control = nil
+	if w := postControl(h, h.HandleClaimControl, "", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
+
+// ---------- Enforcement ----------
+
+func TestServer_ControlledRoutes(t *testing.T) {
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	mux := srv.Mux()
+	token, _ := srv.Handlers().Control.Claim("", "Alice", false)
+
+	send := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set(ControlHeader, token)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, path := range []string{APIPrefix + "/cancel", "/cancel", APIPrefix + "/stop"} {
+		if code := send(http.MethodPost, path, ""); code != http.StatusLocked {
+			t.Errorf("POST %s without control: status = %d, want 423", path, code)
+		}
+		if code := send(http.MethodPost, path, token); code == http.StatusLocked {
+			t.Errorf("POST %s by the holder: status 423", path)
+		}
+	}
+	if code := send(http.MethodPost, APIPrefix+"/cancel?control_token="+token, ""); code == http.StatusLocked {
+		t.Error("control_token query parameter not accepted")
+	}
+
+	// Reading the status stays open to everyone
+	for _, path := range []string{APIPrefix + "/config", APIPrefix + "/control"} {
+		if code := send(http.MethodGet, path, ""); code != http.StatusOK {
+			t.Errorf("GET %s without control: status = %d, want 200", path, code)
+		}
+	}
+
+	// Every controlled route exists
+	known := map[string]bool{}
+	for _, rt := range srv.routes() {
+		known[rt.pattern] = true
+	}
+	for pattern := range controlledRoutes {
+		if !known[pattern] {
+			t.Errorf("controlled route %q is not registered", pattern)
+		}
+	}
+}
+
+func TestHandleWS_ControlClaimed(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Control.Claim("", "Alice", false)
+	conn := dialWS(t, h)
+	reply := sendWS(t, conn, WSCommand{Cmd: "stop"})
+	if reply.OK || !strings.Contains(reply.Error, "Alice") {
+		t.Errorf("reply = %+v, want an error naming the holder", reply)
+	}
+}
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// How long browsers may cache a preflight response (seconds).
+const corsMaxAge = 600
+
+// Request headers cross-origin callers may send.
+var corsAllowedHeaders = []string{"Authorization", "Content-Type", RequestIDHeader, ControlHeader}
+
+// CORSConfig lists the origins allowed to call the API from a browser, as
+// "scheme://host[:port]". "*" allows any origin, but then without
+// credentials (cookies, basic auth): use a bearer token. Empty disables CORS.
+type CORSConfig struct {
+	AllowedOrigins []string
+}
+
+func (c CORSConfig) enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// CORS wraps next to answer preflight requests and add CORS headers for the
+// allowed origins. It must wrap authentication: browsers send preflight
+// requests without credentials.
+func CORS(next http.Handler, cfg CORSConfig) http.Handler {
+	if !cfg.enabled() {
+		return next
+	}
+	anyOrigin := slices.Contains(cfg.AllowedOrigins, "*")
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		w.Header().Add("Vary", "Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		explicit := slices.Contains(cfg.AllowedOrigins, origin)
+		if !explicit && !anyOrigin {
+			// Not allowed: no CORS headers, the browser blocks the response.
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		if explicit {
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Allow-Credentials", "true")
+		} else {
+			h.Set("Access-Control-Allow-Origin", "*")
+		}
+		h.Set("Access-Control-Expose-Headers", RequestIDHeader)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+			h.Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+			h.Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
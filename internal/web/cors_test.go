@@ -0,0 +1,78 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func corsRequest(h http.Handler, method, origin string, preflight bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/status", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if preflight {
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) })
+	cases := []struct {
+		name        string
+		origins     []string
+		origin      string
+		preflight   bool
+		wantStatus  int
+		wantOrigin  string
+		wantCookies bool
+	}{
+		{"listed", []string{"http://localhost:5173"}, "http://localhost:5173", false, http.StatusTeapot, "http://localhost:5173", true},
+		{"listed_preflight", []string{"http://localhost:5173"}, "http://localhost:5173", true, http.StatusNoContent, "http://localhost:5173", true},
+		{"unlisted", []string{"http://localhost:5173"}, "http://evil.example", false, http.StatusTeapot, "", false},
+		{"unlisted_preflight", []string{"http://localhost:5173"}, "http://evil.example", true, http.StatusTeapot, "", false},
+		{"wildcard", []string{"*"}, "http://any.example", false, http.StatusTeapot, "*", false},
+		{"same_origin", []string{"*"}, "", false, http.StatusTeapot, "", false},
+		{"disabled", nil, "http://localhost:5173", true, http.StatusTeapot, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			method := http.MethodGet
+			if tc.preflight {
+				method = http.MethodOptions
+			}
+			w := corsRequest(CORS(ok, CORSConfig{AllowedOrigins: tc.origins}), method, tc.origin, tc.preflight)
+			if w.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tc.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tc.wantOrigin {
+				t.Errorf("Allow-Origin = %q, want %q", got, tc.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials") == "true"; got != tc.wantCookies {
+				t.Errorf("Allow-Credentials = %t, want %t", got, tc.wantCookies)
+			}
+			if tc.wantStatus == http.StatusNoContent && w.Header().Get("Access-Control-Allow-Headers") == "" {
+				t.Error("preflight should list the allowed headers")
+			}
+		})
+	}
+}
+
+func TestServer_CORSPreflightSkipsAuth(t *testing.T) {
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAuth(AuthConfig{Token: testToken})
+	srv.SetCORS(CORSConfig{AllowedOrigins: []string{"http://localhost:5173"}})
+	srv.SetAccessLog(nil)
+	mux := srv.Mux()
+
+	if w := corsRequest(mux, http.MethodOptions, "http://localhost:5173", true); w.Code != http.StatusNoContent {
+		t.Errorf("preflight status = %d, want 204 without credentials", w.Code)
+	}
+	w := corsRequest(mux, http.MethodGet, "http://localhost:5173", false)
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Access-Control-Allow-Origin") == "" {
+		t.Errorf("GET: status = %d, Allow-Origin = %q; want 401 readable by the page", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
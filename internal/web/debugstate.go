@@ -0,0 +1,70 @@
+package web
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// Number of recent events in a DebugState.
+const debugStateEvents = 50
+
+// DebugState is a dump of the state of the program, for GET /debug/state
+// and SIGUSR1: what to look at when a long run seems hung.
+type DebugState struct {
+	Time          time.Time       `json:"time"`
+	Goroutines    int             `json:"goroutines"`
+	Capture       *capture.Status `json:"capture,omitempty"`       // nil without a capture lifecycle
+	Head          *HeadState      `json:"head,omitempty"`          // nil when the head is not known
+	StreamClients int             `json:"stream_clients"`          // status stream subscribers
+	RecentEvents  []StatusEvent   `json:"recent_events,omitempty"` // oldest first
+}
+
+// HeadState is the state of the head in a DebugState: the positions
+// tracked by the motors, from where they were set up, and the steps made.
+type HeadState struct {
+	Busy           bool    `json:"busy"` // a capture or jog holds the head
+	PanSteps       int     `json:"pan_steps"`
+	TiltSteps      int     `json:"tilt_steps"`
+	PanDeg         float64 `json:"pan_deg"`
+	TiltDeg        float64 `json:"tilt_deg"`
+	PanMovedSteps  uint64  `json:"pan_moved_steps"` // since the start, both directions
+	TiltMovedSteps uint64  `json:"tilt_moved_steps"`
+}
+
+// HeadStateFunc returns the state of the head, without waiting for it to
+// be free.
+type HeadStateFunc func() HeadState
+
+// CollectDebugState returns the state of the program from l, b and head,
+// each optional.
+func CollectDebugState(l *capture.Lifecycle, b *StatusBroadcaster, head HeadStateFunc) DebugState {
+	state := DebugState{Time: time.Now(), Goroutines: runtime.NumGoroutine()}
+	if l != nil {
+		status := l.Snapshot()
+		state.Capture = &status
+	}
+	if head != nil {
+		h := head()
+		state.Head = &h
+	}
+	if b != nil {
+		state.StreamClients = b.Clients()
+		state.RecentEvents = b.Recent(debugStateEvents)
+	}
+	return state
+}
+
+// DebugState returns the state of the program as known to h.
+func (h *Handlers) DebugState() DebugState {
+	return CollectDebugState(h.Lifecycle, h.Broadcaster, h.Head)
+}
+
+// HandleDebugState handles GET /debug/state: the capture state and grid
+// position, the tracked motor positions, the goroutine count and the recent
+// events.
+func (h *Handlers) HandleDebugState(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.DebugState())
+}
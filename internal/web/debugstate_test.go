@@ -0,0 +1,58 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// ---------- HandleDebugState ----------
+
+func TestHandleDebugState(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Lifecycle = capture.NewLifecycle()
+	_ = h.Lifecycle.Transition(capture.StatePlanning)
+	_ = h.Lifecycle.Transition(capture.StateShooting)
+	h.Lifecycle.SetGrid(4, 3)
+	h.Lifecycle.SetCell(2, 3)
+	h.Head = func() HeadState {
+		return HeadState{Busy: true, PanSteps: 1200, TiltSteps: -80, PanMovedSteps: 5000}
+	}
+	for i := range debugStateEvents + 10 {
+		h.Broadcaster.Broadcast("info", fmt.Sprintf("message %d", i))
+	}
+	h.Broadcaster.Emit(EventPosition, capture.HeadPosition{PanSteps: 1200, Moving: true})
+
+	w := httptest.NewRecorder()
+	h.HandleDebugState(w, httptest.NewRequest(http.MethodGet, "/debug/state", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var got DebugState
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Capture == nil || got.Capture.State != capture.StateShooting || got.Capture.Cell != (capture.Cell{Column: 2, Row: 3}) {
+		t.Errorf("capture = %+v, want shooting at column 2, row 3", got.Capture)
+	}
+	if got.Head == nil || !got.Head.Busy || got.Head.PanSteps != 1200 || got.Head.PanMovedSteps != 5000 {
+		t.Errorf("head = %+v", got.Head)
+	}
+	if got.Goroutines < 1 || got.Time.IsZero() {
+		t.Errorf("goroutines = %d, time = %v", got.Goroutines, got.Time)
+	}
+	if n := len(got.RecentEvents); n != debugStateEvents || got.RecentEvents[n-1].Msg != fmt.Sprintf("message %d", debugStateEvents+9) {
+		t.Errorf("%d recent events, want the last %d messages without position samples", n, debugStateEvents)
+	}
+}
+
+func TestCollectDebugState_Optional(t *testing.T) {
+	got := CollectDebugState(nil, nil, nil)
+	if got.Capture != nil || got.Head != nil || got.RecentEvents != nil || got.Goroutines < 1 {
+		t.Errorf("state = %+v, want the goroutine count only", got)
+	}
+}
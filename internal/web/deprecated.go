@@ -0,0 +1,23 @@
+package web
+
+import (
+	"log"
+	"net/http"
+	"sync"
+)
+
+// deprecated serves an API route at its unversioned path, kept for clients
+// written before APIPrefix. Responses carry a Deprecation header and a Link
+// to the versioned route; the first call of each route is logged so that
+// operators can find the scripts to update. base is the server's base path.
+func deprecated(next http.Handler, base string) http.Handler {
+	var once sync.Once
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			log.Printf("web: %s %s is deprecated, use %s%s%s (client %s)", r.Method, r.URL.Path, base, APIPrefix, r.URL.Path, clientIP(r))
+		})
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+base+APIPrefix+r.URL.Path+`>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,92 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Log levels of EventLog events, from the most to the least verbose. Debug
+// output (see package debug) is broadcast as "trace" (GPIO, level 4),
+// "debug" (verbose, level 3), "warning", "error" or "info".
+var logLevels = []string{"trace", "debug", "info", "warning", "error"}
+
+// Event types a client can select (see EventFilter).
+var eventTypes = []string{EventLog, EventState, EventProgress, EventShot, EventMove, EventPosition, EventControl, EventShutdown}
+
+// EventFilter selects the status events a subscriber receives, so that
+// mobile clients are not flooded at high debug levels. The zero value
+// accepts everything. EventShutdown events always pass.
+type EventFilter struct {
+	Types    []string // accepted event types; empty = all
+	MinLevel string   // least severe log level accepted (see logLevels); "" = all
+}
+
+// ParseEventFilter reads a filter from the query parameters of a status
+// stream: types (comma-separated event types) and level (minimum log level),
+// e.g. ?types=progress,log&level=warning.
+func ParseEventFilter(q url.Values) (EventFilter, error) {
+	var f EventFilter
+	if v := q.Get("types"); v != "" {
+		for _, typ := range strings.Split(v, ",") {
+			typ = strings.TrimSpace(typ)
+			if !slices.Contains(eventTypes, typ) {
+				return EventFilter{}, fmt.Errorf("unknown event type %q (want %s)", typ, strings.Join(eventTypes, ", "))
+			}
+			f.Types = append(f.Types, typ)
+		}
+	}
+	if v := q.Get("level"); v != "" {
+		if !slices.Contains(logLevels, v) {
+			return EventFilter{}, fmt.Errorf("unknown level %q (want %s)", v, strings.Join(logLevels, ", "))
+		}
+		f.MinLevel = v
+	}
+	return f, nil
+}
+
+// Match reports whether evt passes the filter. Log events with an unknown
+// level count as "info".
+func (f EventFilter) Match(evt StatusEvent) bool {
+	if evt.Type == EventShutdown {
+		return true
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, evt.Type) {
+		return false
+	}
+	if evt.Type == EventLog && f.MinLevel != "" {
+		return levelRank(evt.Level) >= levelRank(f.MinLevel)
+	}
+	return true
+}
+
+func levelRank(level string) int {
+	if i := slices.Index(logLevels, level); i >= 0 {
+		return i
+	}
+	return slices.Index(logLevels, "info")
+}
+
+// debugLineLevel returns the log level of a line written by package debug,
+// from its first level= field (slog text, e.g. "time=... level=TRACE msg=gpio").
+func debugLineLevel(line string) string {
+	for _, field := range strings.Fields(line) {
+		name, ok := strings.CutPrefix(field, "level=")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "TRACE":
+			return "trace"
+		case "VERBOSE":
+			return "debug"
+		case "WARN":
+			return "warning"
+		case "ERROR":
+			return "error"
+		}
+		break
+	}
+	return "info"
+}
@@ -0,0 +1,124 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestParseEventFilter(t *testing.T) {
+	cases := []struct {
+		name    string
+		query   string
+		want    EventFilter
+		wantErr bool
+	}{
+		{"empty", "", EventFilter{}, false},
+		{"types", "types=progress, log", EventFilter{Types: []string{"progress", "log"}}, false},
+		{"level", "level=warning", EventFilter{MinLevel: "warning"}, false},
+		{"unknown_type", "types=progress,gpio", EventFilter{}, true},
+		{"unknown_level", "level=loud", EventFilter{}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tc.query)
+			got, err := ParseEventFilter(q)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+			if len(got.Types) != len(tc.want.Types) || got.MinLevel != tc.want.MinLevel {
+				t.Errorf("filter = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEventFilter_Match(t *testing.T) {
+	progressAndWarnings := EventFilter{Types: []string{EventProgress, EventLog}, MinLevel: "warning"}
+	cases := []struct {
+		name   string
+		filter EventFilter
+		evt    StatusEvent
+		want   bool
+	}{
+		{"zero_accepts_trace", EventFilter{}, StatusEvent{Type: EventLog, Level: "trace"}, true},
+		{"type_selected", progressAndWarnings, StatusEvent{Type: EventProgress}, true},
+		{"type_not_selected", progressAndWarnings, StatusEvent{Type: EventMove}, false},
+		{"level_below", progressAndWarnings, StatusEvent{Type: EventLog, Level: "info"}, false},
+		{"level_equal", progressAndWarnings, StatusEvent{Type: EventLog, Level: "warning"}, true},
+		{"level_above", progressAndWarnings, StatusEvent{Type: EventLog, Level: "error"}, true},
+		{"unknown_level_is_info", EventFilter{MinLevel: "info"}, StatusEvent{Type: EventLog, Level: "success"}, true},
+		{"level_ignores_other_types", EventFilter{MinLevel: "error"}, StatusEvent{Type: EventState}, true},
+		{"shutdown_always", EventFilter{Types: []string{EventProgress}}, StatusEvent{Type: EventShutdown}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.filter.Match(tc.evt); got != tc.want {
+				t.Errorf("Match = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDebugLineLevel(t *testing.T) {
+	cases := map[string]string{
+		"time=2026-10-17T12:00:00Z level=TRACE msg=gpio op=WritePin pin=17 pin_level=1": "trace",
+		"time=2026-10-17T12:00:00Z level=VERBOSE msg=\"grid plan\" step=4":              "debug",
+		"time=2026-10-17T12:00:00Z level=WARN msg=\"low battery\"":                      "warning",
+		"time=2026-10-17T12:00:00Z level=ERROR msg=retry err=boom":                      "error",
+		"time=2026-10-17T12:00:00Z level=LIVE msg=\"photo taken\" cell.col=1":           "info",
+		"level=INFO msg=\"level=ERROR in a message\"":                                   "info",
+		"plain": "info",
+	}
+	for line, want := range cases {
+		if got := debugLineLevel(line); got != want {
+			t.Errorf("debugLineLevel(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestBroadcaster_Filtered(t *testing.T) {
+	b := NewStatusBroadcaster()
+	ch, unsub := b.SubscribeFiltered(EventFilter{Types: []string{EventProgress, EventLog}, MinLevel: "warning"})
+	defer unsub()
+
+	w := BroadcastWriter(b)
+	w.Write([]byte("time=2026-10-17T12:00:00Z level=TRACE msg=gpio op=WritePin pin=17 pin_level=1"))
+	b.Emit(EventMove, map[string]int{"pan_steps": 1})
+	b.Emit(EventProgress, map[string]int{"shots_done": 1})
+	b.Broadcast("error", "shot failed")
+
+	var got []string
+	for len(ch) > 0 {
+		var evt StatusEvent
+		if err := json.Unmarshal([]byte(<-ch), &evt); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, evt.Type+"/"+evt.Level)
+	}
+	if len(got) != 2 || got[0] != "progress/" || got[1] != "log/error" {
+		t.Errorf("received %v, want progress then the error", got)
+	}
+
+	missed, _, unsub2 := b.SubscribeSince(0, EventFilter{MinLevel: "trace", Types: []string{EventLog}})
+	unsub2()
+	if len(missed) != 2 {
+		t.Errorf("replayed %d log events, want 2 (GPIO trace and error)", len(missed))
+	}
+}
+
+func TestStreams_BadFilter(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	for path, handler := range map[string]http.HandlerFunc{
+		"/status/stream": h.HandleStatusStream,
+		"/ws":            h.HandleWS,
+	} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, path+"?level=loud", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/rpc"
+)
+
+// GRPCService is the full name of the gRPC service, defined in
+// api/pango.proto. It mirrors the HTTP API for integrations that want typed
+// messages and streaming: plan, run, cancel, jog, stop, status and events.
+const GRPCService = "pango.v1.PanGo"
+
+// Field numbers of the messages of api/pango.proto.
+const (
+	// Overrides
+	fieldHorizontalAngle = 1
+	fieldVerticalAngle   = 2
+	fieldFocalLength     = 3
+	fieldInterMoveDelay  = 4
+	fieldStabilization   = 5
+	fieldOverlap         = 6
+	fieldShotDelay       = 7
+	fieldMoveSpeed       = 8
+
+	// PlanResponse
+	fieldPlanMode       = 1
+	fieldPlanColumns    = 2
+	fieldPlanRows       = 3
+	fieldPlanTotalShots = 4
+	fieldPlanPanStep    = 5
+	fieldPlanTiltStep   = 6
+	fieldPlanPanoramas  = 7
+	fieldPlanEstimated  = 8
+
+	// PlanGrid
+	fieldGridName     = 1
+	fieldGridColumns  = 2
+	fieldGridRows     = 3
+	fieldGridPanStep  = 4
+	fieldGridTiltStep = 5
+
+	// RunResponse
+	fieldRunJobID = 1
+
+	// JogRequest
+	fieldJogAxis    = 1
+	fieldJogDegrees = 2
+	fieldJogSteps   = 3
+	fieldJogSpeedMs = 4
+
+	// JogResponse
+	fieldJogStopped = 1
+
+	// Status
+	fieldStatusState      = 1
+	fieldStatusSince      = 2
+	fieldStatusStartedAt  = 3
+	fieldStatusEndedAt    = 4
+	fieldStatusColumn     = 5
+	fieldStatusRow        = 6
+	fieldStatusColumns    = 7
+	fieldStatusRows       = 8
+	fieldStatusShotsDone  = 9
+	fieldStatusShotsTotal = 10
+	fieldStatusLastError  = 11
+
+	// EventFilter
+	fieldFilterTypes    = 1
+	fieldFilterMinLevel = 2
+
+	// Event
+	fieldEventID        = 1
+	fieldEventTime      = 2
+	fieldEventType      = 3
+	fieldEventLevel     = 4
+	fieldEventMsg       = 5
+	fieldEventDataJSON  = 6
+	fieldEventRequestID = 7
+)
+
+// controlTokenKey carries the control token of a gRPC call (ControlHeader
+// metadata) in its context.
+type controlTokenKey struct{}
+
+// networkErrKey holds the checkNetwork result of the call in its context.
+type networkErrKey struct{}
+
+// GRPCHandler returns the gRPC service as an http.Handler, to be served
+// over HTTP/2. It requires the credentials of auth, as bearer token or basic
+// auth metadata, and the control token (ControlHeader metadata, lowercase
+// x-control-token) for the methods acting on the rig.
+func (h *Handlers) GRPCHandler(auth AuthConfig) http.Handler {
+	srv := rpc.NewServer(GRPCService)
+	srv.Unary("Plan", h.grpcPlan)
+	srv.Unary("Run", h.grpcRun)
+	srv.Unary("Cancel", h.grpcCancel)
+	srv.Unary("Jog", h.grpcJog)
+	srv.Unary("Stop", h.grpcStop)
+	srv.Unary("GetStatus", h.grpcGetStatus)
+	srv.Stream("StreamEvents", h.grpcStreamEvents)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.enabled() && !auth.authorized(r) {
+			w.Header().Set("Content-Type", "application/grpc")
+			w.Header().Set("Grpc-Status", "16") // rpc.Unauthenticated
+			w.Header().Set("Grpc-Message", "unauthorized")
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		ctx := context.WithValue(r.Context(), controlTokenKey{}, r.Header.Get(ControlHeader))
+		ctx = context.WithValue(ctx, networkErrKey{}, h.checkNetwork(r))
+		srv.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// grpcControl checks that the caller may act on the rig.
+func (h *Handlers) grpcControl(ctx context.Context) error {
+	if err, _ := ctx.Value(networkErrKey{}).(error); err != nil {
+		return rpc.Errorf(rpc.PermissionDenied, "%v", err)
+	}
+	if h.Control == nil {
+		return nil
+	}
+	token, _ := ctx.Value(controlTokenKey{}).(string)
+	if err := h.Control.check(token); err != nil {
+		return rpc.Errorf(rpc.PermissionDenied, "%v", err)
+	}
+	return nil
+}
+
+func decodeOverrides(req []byte) (Overrides, error) {
+	var o Overrides
+	err := rpc.Decode(req, func(f rpc.Field) error {
+		switch f.Num {
+		case fieldHorizontalAngle:
+			o.HorizontalAngleDeg = f.Double()
+		case fieldVerticalAngle:
+			o.VerticalAngleDeg = f.Double()
+		case fieldFocalLength:
+			o.FocalLengthMm = f.Double()
+		case fieldInterMoveDelay:
+			o.InterMoveDelayMs = int(int32(f.Int()))
+		case fieldStabilization:
+			o.StabilizationDelayMs = int(int32(f.Int()))
+		case fieldOverlap:
+			o.OverlapPercent = f.Double()
+		case fieldShotDelay:
+			o.ShotDelayMs = int(int32(f.Int()))
+		case fieldMoveSpeed:
+			o.MoveSpeedMs = int(int32(f.Int()))
+		}
+		return nil
+	})
+	if err != nil {
+		return Overrides{}, rpc.Errorf(rpc.InvalidArgument, "%v", err)
+	}
+	if err := ValidateOverrides(o); err != nil {
+		return Overrides{}, rpc.Errorf(rpc.InvalidArgument, "%v", err)
+	}
+	return o, nil
+}
+
+func encodePlan(p Plan) []byte {
+	var e rpc.Encoder
+	e.String(fieldPlanMode, p.Mode)
+	e.Int(fieldPlanColumns, int64(p.Columns))
+	e.Int(fieldPlanRows, int64(p.Rows))
+	e.Int(fieldPlanTotalShots, int64(p.TotalShots))
+	e.Int(fieldPlanPanStep, int64(p.PanStepSize))
+	e.Int(fieldPlanTiltStep, int64(p.TiltStepSize))
+	for _, g := range p.Panoramas {
+		var ge rpc.Encoder
+		ge.String(fieldGridName, g.Name)
+		ge.Int(fieldGridColumns, int64(g.Columns))
+		ge.Int(fieldGridRows, int64(g.Rows))
+		ge.Int(fieldGridPanStep, int64(g.PanStepSize))
+		ge.Int(fieldGridTiltStep, int64(g.TiltStepSize))
+		e.Message(fieldPlanPanoramas, ge.Bytes())
+	}
+	e.Double(fieldPlanEstimated, p.EstimatedSec)
+	return e.Bytes()
+}
+
+// grpcPlan handles Plan(Overrides) returns (PlanResponse), as POST /plan.
+func (h *Handlers) grpcPlan(ctx context.Context, req []byte) ([]byte, error) {
+	o, err := decodeOverrides(req)
+	if err != nil {
+		return nil, err
+	}
+	if h.Plan == nil {
+		return nil, rpc.Errorf(rpc.Unavailable, "planning not available")
+	}
+	plan, err := h.Plan(o)
+	if err != nil {
+		return nil, rpc.Errorf(rpc.InvalidArgument, "%v", err)
+	}
+	return encodePlan(plan), nil
+}
+
+// grpcRun handles Run(Overrides) returns (RunResponse), as POST /run.
+func (h *Handlers) grpcRun(ctx context.Context, req []byte) ([]byte, error) {
+	o, err := decodeOverrides(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.grpcControl(ctx); err != nil {
+		return nil, err
+	}
+	if h.Jobs == nil {
+		return nil, rpc.Errorf(rpc.Unavailable, "capture not configured")
+	}
+	job, err := h.Jobs.StartNow(ctx, o)
+	if err != nil {
+		var tooSoon *TooSoonError
+		switch {
+		case errors.As(err, &tooSoon):
+			return nil, rpc.Errorf(rpc.ResourceExhausted, "%v", err)
+		case errors.Is(err, ErrShuttingDown):
+			return nil, rpc.Errorf(rpc.Unavailable, "%v", err)
+		default:
+			return nil, rpc.Errorf(rpc.FailedPrecondition, "%v", err)
+		}
+	}
+	var e rpc.Encoder
+	e.String(fieldRunJobID, job.ID)
+	return e.Bytes(), nil
+}
+
+// grpcCancel handles Cancel(Empty) returns (Empty), as POST /cancel.
+func (h *Handlers) grpcCancel(ctx context.Context, req []byte) ([]byte, error) {
+	if err := h.grpcControl(ctx); err != nil {
+		return nil, err
+	}
+	if h.Jobs == nil || !h.Jobs.CancelRunning() {
+		return nil, rpc.Errorf(rpc.FailedPrecondition, "no capture in progress")
+	}
+	return nil, nil
+}
+
+// grpcJog handles Jog(JogRequest) returns (JogResponse), as POST /jog: it
+// returns once the head stopped, with stopped set when Stop interrupted it.
+func (h *Handlers) grpcJog(ctx context.Context, req []byte) ([]byte, error) {
+	var jog JogRequest
+	err := rpc.Decode(req, func(f rpc.Field) error {
+		switch f.Num {
+		case fieldJogAxis:
+			jog.Axis = f.String()
+		case fieldJogDegrees:
+			jog.Degrees = f.Double()
+		case fieldJogSteps:
+			jog.Steps = int(int32(f.Int()))
+		case fieldJogSpeedMs:
+			jog.SpeedMs = int(int32(f.Int()))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, rpc.Errorf(rpc.InvalidArgument, "%v", err)
+	}
+	if err := ValidateJog(jog); err != nil {
+		return nil, rpc.Errorf(rpc.InvalidArgument, "%v", err)
+	}
+	if err := h.grpcControl(ctx); err != nil {
+		return nil, err
+	}
+	if h.Jog == nil {
+		return nil, rpc.Errorf(rpc.Unavailable, "jog not available")
+	}
+
+	var e rpc.Encoder
+	err = h.runJog(ctx, jog)
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled) && ctx.Err() == nil:
+		e.Bool(fieldJogStopped, true)
+	case errors.Is(err, ErrHeadBusy):
+		return nil, rpc.Errorf(rpc.FailedPrecondition, "%v", err)
+	default:
+		return nil, err
+	}
+	return e.Bytes(), nil
+}
+
+// grpcStop handles Stop(Empty) returns (Empty), as POST /stop.
+func (h *Handlers) grpcStop(ctx context.Context, req []byte) ([]byte, error) {
+	if err := h.grpcControl(ctx); err != nil {
+		return nil, err
+	}
+	if !h.stopJog() {
+		return nil, rpc.Errorf(rpc.FailedPrecondition, "no jog in progress")
+	}
+	return nil, nil
+}
+
+// grpcGetStatus handles GetStatus(Empty) returns (Status), as GET /status.
+// Times are RFC 3339 strings, empty when unset.
+func (h *Handlers) grpcGetStatus(ctx context.Context, req []byte) ([]byte, error) {
+	if h.Lifecycle == nil {
+		return nil, rpc.Errorf(rpc.Unavailable, "capture status not available")
+	}
+	st := h.Lifecycle.Snapshot()
+	var e rpc.Encoder
+	e.String(fieldStatusState, string(st.State))
+	e.String(fieldStatusSince, st.StateSince.Format(time.RFC3339Nano))
+	if st.StartedAt != nil {
+		e.String(fieldStatusStartedAt, st.StartedAt.Format(time.RFC3339Nano))
+	}
+	if st.EndedAt != nil {
+		e.String(fieldStatusEndedAt, st.EndedAt.Format(time.RFC3339Nano))
+	}
+	e.Int(fieldStatusColumn, int64(st.Cell.Column))
+	e.Int(fieldStatusRow, int64(st.Cell.Row))
+	e.Int(fieldStatusColumns, int64(st.Columns))
+	e.Int(fieldStatusRows, int64(st.Rows))
+	e.Int(fieldStatusShotsDone, int64(st.ShotsDone))
+	e.Int(fieldStatusShotsTotal, int64(st.ShotsTotal))
+	e.String(fieldStatusLastError, st.LastError)
+	return e.Bytes(), nil
+}
+
+// grpcStreamEvents handles StreamEvents(EventFilter) returns (stream
+// Event), as GET /status/stream: the status events, with their data as
+// JSON, until the client goes away or the server shuts down.
+func (h *Handlers) grpcStreamEvents(ctx context.Context, req []byte, send func([]byte) error) error {
+	q := url.Values{}
+	var types []string
+	err := rpc.Decode(req, func(f rpc.Field) error {
+		switch f.Num {
+		case fieldFilterTypes:
+			types = append(types, f.String())
+		case fieldFilterMinLevel:
+			q.Set("level", f.String())
+		}
+		return nil
+	})
+	if err != nil {
+		return rpc.Errorf(rpc.InvalidArgument, "%v", err)
+	}
+	if len(types) > 0 {
+		q.Set("types", strings.Join(types, ","))
+	}
+	filter, err := ParseEventFilter(q)
+	if err != nil {
+		return rpc.Errorf(rpc.InvalidArgument, "%v", err)
+	}
+
+	events, unsub := h.Broadcaster.SubscribeFiltered(filter)
+	defer unsub()
+	for {
+		select {
+		case msg, ok := <-events:
+			if !ok {
+				return nil
+			}
+			var evt struct {
+				StatusEvent
+				Data json.RawMessage `json:"data,omitempty"`
+			}
+			if err := json.Unmarshal([]byte(msg), &evt); err != nil {
+				continue
+			}
+			var e rpc.Encoder
+			e.Uint(fieldEventID, evt.ID)
+			e.String(fieldEventTime, evt.Time)
+			e.String(fieldEventType, evt.Type)
+			e.String(fieldEventLevel, evt.Level)
+			e.String(fieldEventMsg, evt.Msg)
+			e.String(fieldEventDataJSON, string(evt.Data))
+			e.String(fieldEventRequestID, evt.RequestID)
+			if err := send(e.Bytes()); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/rpc"
+)
+
+// grpcTestClient calls the gRPC service of h over unencrypted HTTP/2.
+type grpcTestClient struct {
+	url    string
+	header http.Header
+}
+
+func newGRPCTestClient(t *testing.T, h *Handlers, auth AuthConfig) *grpcTestClient {
+	t.Helper()
+	ts := httptest.NewUnstartedServer(h.GRPCHandler(auth))
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return &grpcTestClient{url: ts.URL, header: http.Header{}}
+}
+
+// call invokes a unary method and returns its response.
+func (c *grpcTestClient) call(method string, req []byte) ([]byte, error) {
+	var resp []byte
+	err := rpc.Call(context.Background(), rpc.NewClient(), c.url, "/"+GRPCService+"/"+method, c.header, req, func(m []byte) error {
+		resp = m
+		return nil
+	})
+	return resp, err
+}
+
+// fields decodes a message into field number → value, for assertions.
+func fields(t *testing.T, m []byte) map[int]rpc.Field {
+	t.Helper()
+	out := map[int]rpc.Field{}
+	if err := rpc.Decode(m, func(f rpc.Field) error {
+		out[f.Num] = f
+		return nil
+	}); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	return out
+}
+
+func grpcCode(err error) rpc.Code {
+	var st *rpc.Status
+	if errors.As(err, &st) {
+		return st.Code
+	}
+	return rpc.OK
+}
+
+func encodeOverrides(o Overrides) []byte {
+	var e rpc.Encoder
+	e.Double(fieldHorizontalAngle, o.HorizontalAngleDeg)
+	e.Double(fieldVerticalAngle, o.VerticalAngleDeg)
+	e.Double(fieldFocalLength, o.FocalLengthMm)
+	e.Int(fieldInterMoveDelay, int64(o.InterMoveDelayMs))
+	e.Int(fieldStabilization, int64(o.StabilizationDelayMs))
+	e.Double(fieldOverlap, o.OverlapPercent)
+	e.Int(fieldShotDelay, int64(o.ShotDelayMs))
+	e.Int(fieldMoveSpeed, int64(o.MoveSpeedMs))
+	return e.Bytes()
+}
+
+// ---------- Plan ----------
+
+func TestGRPC_Plan(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	var got Overrides
+	h.Plan = func(o Overrides) (Plan, error) {
+		got = o
+		return Plan{Mode: "grid", Columns: 8, Rows: 3, TotalShots: 24, Panoramas: []PlanGrid{{Name: "wide", Columns: 8, Rows: 3}}, EstimatedSec: 96.5}, nil
+	}
+	c := newGRPCTestClient(t, h, AuthConfig{})
+
+	want := Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35, InterMoveDelayMs: 800, StabilizationDelayMs: 150, OverlapPercent: 40, ShotDelayMs: 500, MoveSpeedMs: 3}
+	resp, err := c.call("Plan", encodeOverrides(want))
+	if err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if got != want {
+		t.Errorf("planned %+v", got)
+	}
+	f := fields(t, resp)
+	if f[fieldPlanMode].String() != "grid" || f[fieldPlanColumns].Int() != 8 || f[fieldPlanTotalShots].Int() != 24 || f[fieldPlanEstimated].Double() != 96.5 {
+		t.Errorf("plan = %v", f)
+	}
+	if grid := fields(t, f[fieldPlanPanoramas].Data); grid[fieldGridName].String() != "wide" || grid[fieldGridRows].Int() != 3 {
+		t.Errorf("panorama = %v", grid)
+	}
+
+	if _, err := c.call("Plan", encodeOverrides(Overrides{HorizontalAngleDeg: 0, VerticalAngleDeg: 30, FocalLengthMm: 35})); grpcCode(err) != rpc.InvalidArgument {
+		t.Errorf("invalid overrides: %v, want InvalidArgument", err)
+	}
+	h.Plan = nil
+	if _, err := c.call("Plan", encodeOverrides(Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35})); grpcCode(err) != rpc.Unavailable {
+		t.Errorf("no planner: %v, want Unavailable", err)
+	}
+}
+
+// ---------- Run / Cancel ----------
+
+func TestGRPC_RunAndCancel(t *testing.T) {
+	started := make(chan struct{})
+	h := newTestHandlers(func(ctx context.Context, _ Overrides) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	c := newGRPCTestClient(t, h, AuthConfig{})
+
+	if _, err := c.call("Cancel", nil); grpcCode(err) != rpc.FailedPrecondition {
+		t.Errorf("Cancel while idle: %v, want FailedPrecondition", err)
+	}
+	resp, err := c.call("Run", encodeOverrides(Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35}))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if fields(t, resp)[fieldRunJobID].String() == "" {
+		t.Error("Run returned no job id")
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("capture not started")
+	}
+	if _, err := c.call("Run", encodeOverrides(Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35})); grpcCode(err) != rpc.FailedPrecondition {
+		t.Errorf("second Run: %v, want FailedPrecondition", err)
+	}
+	if _, err := c.call("Cancel", nil); err != nil {
+		t.Errorf("Cancel: %v", err)
+	}
+}
+
+func TestGRPC_Control(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	token, err := h.Control.Claim("", "studio", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newGRPCTestClient(t, h, AuthConfig{})
+
+	if _, err := c.call("Stop", nil); grpcCode(err) != rpc.PermissionDenied {
+		t.Errorf("Stop without the control token: %v, want PermissionDenied", err)
+	}
+	c.header.Set(ControlHeader, token)
+	if _, err := c.call("Stop", nil); grpcCode(err) != rpc.FailedPrecondition {
+		t.Errorf("Stop with the token and no jog: %v, want FailedPrecondition", err)
+	}
+}
+
+// ---------- Jog ----------
+
+func TestGRPC_Jog(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	var got JogRequest
+	h.Jog = func(_ context.Context, req JogRequest) error {
+		got = req
+		return nil
+	}
+	c := newGRPCTestClient(t, h, AuthConfig{})
+
+	var e rpc.Encoder
+	e.String(fieldJogAxis, "tilt")
+	e.Int(fieldJogSteps, -200)
+	resp, err := c.call("Jog", e.Bytes())
+	if err != nil {
+		t.Fatalf("Jog: %v", err)
+	}
+	if got != (JogRequest{Axis: "tilt", Steps: -200}) {
+		t.Errorf("jogged %+v", got)
+	}
+	if fields(t, resp)[fieldJogStopped].Bool() {
+		t.Error("stopped = true for a complete jog")
+	}
+
+	var bad rpc.Encoder
+	bad.String(fieldJogAxis, "roll")
+	bad.Double(fieldJogDegrees, 5)
+	if _, err := c.call("Jog", bad.Bytes()); grpcCode(err) != rpc.InvalidArgument {
+		t.Errorf("unknown axis: %v, want InvalidArgument", err)
+	}
+	h.Jog = func(context.Context, JogRequest) error { return ErrHeadBusy }
+	if _, err := c.call("Jog", e.Bytes()); grpcCode(err) != rpc.FailedPrecondition {
+		t.Errorf("busy head: %v, want FailedPrecondition", err)
+	}
+}
+
+// ---------- GetStatus / StreamEvents ----------
+
+func TestGRPC_GetStatus(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	c := newGRPCTestClient(t, h, AuthConfig{})
+	if _, err := c.call("GetStatus", nil); grpcCode(err) != rpc.Unavailable {
+		t.Errorf("without lifecycle: %v, want Unavailable", err)
+	}
+
+	h.Lifecycle = capture.NewLifecycle()
+	resp, err := c.call("GetStatus", nil)
+	if err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	f := fields(t, resp)
+	if f[fieldStatusState].String() != "idle" || f[fieldStatusSince].String() == "" {
+		t.Errorf("status = %v", f)
+	}
+	if _, ok := f[fieldStatusStartedAt]; ok {
+		t.Error("started_at set before any run")
+	}
+}
+
+func TestGRPC_StreamEvents(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	c := newGRPCTestClient(t, h, AuthConfig{})
+
+	var filter rpc.Encoder
+	filter.String(fieldFilterTypes, EventMove)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := make(chan map[int]rpc.Field, 4)
+	go rpc.Call(ctx, rpc.NewClient(), c.url, "/"+GRPCService+"/StreamEvents", nil, filter.Bytes(), func(m []byte) error {
+		events <- fields(t, m)
+		return nil
+	})
+
+	// Events are only delivered once the stream subscribed: retry.
+	deadline := time.After(2 * time.Second)
+	for {
+		h.Broadcaster.Broadcast("info", "not selected")
+		h.Broadcaster.Emit(EventMove, capture.Position{PanSteps: 400})
+		select {
+		case f := <-events:
+			if f[fieldEventType].String() != EventMove || f[fieldEventDataJSON].String() != `{"pan_steps":400,"tilt_steps":0}` || f[fieldEventID].Varint == 0 {
+				t.Errorf("event = %v", f)
+			}
+			return
+		case <-deadline:
+			t.Fatal("no event received")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
+
+func TestGRPC_StreamEventsInvalidFilter(t *testing.T) {
+	c := newGRPCTestClient(t, newTestHandlers(noopCapture), AuthConfig{})
+	var filter rpc.Encoder
+	filter.String(fieldFilterMinLevel, "loud")
+	err := rpc.Call(context.Background(), rpc.NewClient(), c.url, "/"+GRPCService+"/StreamEvents", nil, filter.Bytes(), func([]byte) error { return nil })
+	if grpcCode(err) != rpc.InvalidArgument {
+		t.Errorf("err = %v, want InvalidArgument", err)
+	}
+}
+
+// ---------- Auth ----------
+
+func TestGRPC_Auth(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Lifecycle = capture.NewLifecycle()
+	c := newGRPCTestClient(t, h, AuthConfig{Token: "0123456789abcdef"})
+
+	if _, err := c.call("GetStatus", nil); grpcCode(err) != rpc.Unauthenticated {
+		t.Errorf("without credentials: %v, want Unauthenticated", err)
+	}
+	c.header.Set("Authorization", "Bearer 0123456789abcdef")
+	if _, err := c.call("GetStatus", nil); err != nil {
+		t.Errorf("with the token: %v", err)
+	}
+}
+
+// ---------- grpc-go client ----------
+
+// protoMessages compiles api/pango.proto, for messages encoded and decoded
+// by the protobuf runtime rather than rpc.Encoder and rpc.Decode.
+func protoMessages(t *testing.T) func(name string) *dynamicpb.Message {
+	t.Helper()
+	compiler := protocompile.Compiler{Resolver: &protocompile.SourceResolver{ImportPaths: []string{"../../api"}}}
+	files, err := compiler.Compile(context.Background(), "pango.proto")
+	if err != nil {
+		t.Fatalf("compile pango.proto: %v", err)
+	}
+	return func(name string) *dynamicpb.Message {
+		md := files[0].Messages().ByName(protoreflect.Name(name))
+		if md == nil {
+			t.Fatalf("no message %s in pango.proto", name)
+		}
+		return dynamicpb.NewMessage(md)
+	}
+}
+
+// set assigns the fields of m by name.
+func set(m *dynamicpb.Message, values map[string]any) *dynamicpb.Message {
+	for name, v := range values {
+		m.Set(m.Descriptor().Fields().ByName(protoreflect.Name(name)), protoreflect.ValueOf(v))
+	}
+	return m
+}
+
+// get returns the field of m by name.
+func get(m protoreflect.Message, name string) protoreflect.Value {
+	return m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(name)))
+}
+
+// newGRPCConn connects grpc-go to the gRPC service of h.
+func newGRPCConn(t *testing.T, h *Handlers, auth AuthConfig) *grpc.ClientConn {
+	t.Helper()
+	c := newGRPCTestClient(t, h, auth)
+	conn, err := grpc.NewClient(strings.TrimPrefix(c.url, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGRPC_GoClientUnary(t *testing.T) {
+	msg := protoMessages(t)
+	h := newTestHandlers(noopCapture)
+	var planned Overrides
+	h.Plan = func(o Overrides) (Plan, error) {
+		planned = o
+		return Plan{Mode: "grid", Columns: 8, Rows: 3, TotalShots: 24, PanStepSize: 1200, Panoramas: []PlanGrid{{Name: "wide", Columns: 8, Rows: 3, TiltStepSize: 900}}, EstimatedSec: 96.5}, nil
+	}
+	var jogged JogRequest
+	h.Jog = func(_ context.Context, req JogRequest) error {
+		jogged = req
+		return nil
+	}
+	h.Lifecycle = capture.NewLifecycle()
+	conn := newGRPCConn(t, h, AuthConfig{})
+	ctx := context.Background()
+	method := func(name string) string { return "/" + GRPCService + "/" + name }
+
+	req := set(msg("Overrides"), map[string]any{
+		"horizontal_angle_deg": 180.0, "vertical_angle_deg": 30.0, "focal_length_mm": 35.0,
+		"inter_move_delay_ms": int32(800), "overlap_percent": 40.0, "move_speed_ms": int32(3),
+	})
+	plan := msg("PlanResponse")
+	if err := conn.Invoke(ctx, method("Plan"), req, plan); err != nil {
+		t.Fatalf("Plan: %v", err)
+	}
+	if want := (Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35, InterMoveDelayMs: 800, OverlapPercent: 40, MoveSpeedMs: 3}); planned != want {
+		t.Errorf("planned %+v, want %+v", planned, want)
+	}
+	if get(plan, "mode").String() != "grid" || get(plan, "total_shots").Int() != 24 || get(plan, "pan_step_size").Int() != 1200 || get(plan, "estimated_seconds").Float() != 96.5 {
+		t.Errorf("plan = %v", plan)
+	}
+	grids := get(plan, "panoramas").List()
+	if grids.Len() != 1 || get(grids.Get(0).Message(), "name").String() != "wide" || get(grids.Get(0).Message(), "tilt_step_size").Int() != 900 {
+		t.Errorf("panoramas = %v", grids)
+	}
+
+	jog := msg("JogResponse")
+	if err := conn.Invoke(ctx, method("Jog"), set(msg("JogRequest"), map[string]any{"axis": "tilt", "steps": int32(-200)}), jog); err != nil {
+		t.Fatalf("Jog: %v", err)
+	}
+	if jogged != (JogRequest{Axis: "tilt", Steps: -200}) || get(jog, "stopped").Bool() {
+		t.Errorf("jogged %+v, response %v", jogged, jog)
+	}
+
+	st := msg("Status")
+	if err := conn.Invoke(ctx, method("GetStatus"), msg("Empty"), st); err != nil {
+		t.Fatalf("GetStatus: %v", err)
+	}
+	if get(st, "state").String() != "idle" || get(st, "state_since").String() == "" {
+		t.Errorf("status = %v", st)
+	}
+
+	err := conn.Invoke(ctx, method("Cancel"), msg("Empty"), msg("Empty"))
+	if status.Code(err) != codes.FailedPrecondition || status.Convert(err).Message() != "no capture in progress" {
+		t.Errorf("Cancel while idle: %v, want FailedPrecondition", err)
+	}
+	if err := conn.Invoke(ctx, method("Nope"), msg("Empty"), msg("Empty")); status.Code(err) != codes.Unimplemented {
+		t.Errorf("unknown method: %v, want Unimplemented", err)
+	}
+}
+
+func TestGRPC_GoClientAuth(t *testing.T) {
+	msg := protoMessages(t)
+	h := newTestHandlers(noopCapture)
+	h.Lifecycle = capture.NewLifecycle()
+	conn := newGRPCConn(t, h, AuthConfig{Token: "0123456789abcdef"})
+	method := "/" + GRPCService + "/GetStatus"
+
+	if err := conn.Invoke(context.Background(), method, msg("Empty"), msg("Status")); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without credentials: %v, want Unauthenticated", err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer 0123456789abcdef")
+	if err := conn.Invoke(ctx, method, msg("Empty"), msg("Status")); err != nil {
+		t.Errorf("with the token: %v", err)
+	}
+}
+
+func TestGRPC_GoClientStreamEvents(t *testing.T) {
+	msg := protoMessages(t)
+	h := newTestHandlers(noopCapture)
+	conn := newGRPCConn(t, h, AuthConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/"+GRPCService+"/StreamEvents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filter := msg("EventFilter")
+	filter.Mutable(filter.Descriptor().Fields().ByName("types")).List().Append(protoreflect.ValueOfString(EventMove))
+	if err := stream.SendMsg(filter); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan *dynamicpb.Message, 4)
+	go func() {
+		for {
+			evt := msg("Event")
+			if err := stream.RecvMsg(evt); err != nil {
+				return
+			}
+			events <- evt
+		}
+	}()
+
+	// Events are only delivered once the stream subscribed: retry.
+	deadline := time.After(2 * time.Second)
+	for {
+		h.Broadcaster.Broadcast("info", "not selected")
+		h.Broadcaster.Emit(EventMove, capture.Position{PanSteps: 400})
+		select {
+		case evt := <-events:
+			if get(evt, "type").String() != EventMove || get(evt, "data_json").String() != `{"pan_steps":400,"tilt_steps":0}` || get(evt, "id").Uint() == 0 {
+				t.Errorf("event = %v", evt)
+			}
+			return
+		case <-deadline:
+			t.Fatal("no event received")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
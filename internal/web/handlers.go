@@ -5,37 +5,88 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
-	"log"
 	"math"
 	"net/http"
+	"net/netip"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+	"github.com/cjeanneret/PanGo/internal/metrics"
+	"github.com/cjeanneret/PanGo/internal/session"
 )
 
 // Max size for POST /run request body (1 MB). Prevents memory exhaustion on constrained devices.
 const maxRequestBodyBytes = 1 << 20
 
-// Minimum delay between two capture starts. Protects hardware (motors, camera) from rapid successive triggers.
+// Default minimum delay between two capture starts (see Limits.CaptureSpacing).
+// Protects hardware (motors, camera) from rapid successive triggers.
 const minDelayBetweenCaptures = 5 * time.Second
 
 // Overrides holds capture parameters that can override config defaults.
 type Overrides struct {
 	HorizontalAngleDeg float64 `json:"horizontal_angle_deg"`
 	VerticalAngleDeg   float64 `json:"vertical_angle_deg"`
-	FocalLengthMm     float64 `json:"focal_length_mm"`
+	FocalLengthMm      float64 `json:"focal_length_mm"`
+
+	// Optional overlap and timings; 0 keeps the config value.
+	OverlapPercent       float64 `json:"overlap_percent,omitempty"`
+	InterMoveDelayMs     int     `json:"inter_move_delay_ms,omitempty"`
+	StabilizationDelayMs int     `json:"stabilization_delay_ms,omitempty"`
+	ShotDelayMs          int     `json:"shot_delay_ms,omitempty"` // hold after a shot before moving (camera.post_shot_delay_ms)
+	MoveSpeedMs          int     `json:"move_speed_ms,omitempty"` // delay between motor steps
 }
 
+// maxOverrideDelayMs bounds the timing overrides (matches the config limit).
+const maxOverrideDelayMs = 60000
+
+// maxOverrideMoveSpeedMs bounds move_speed_ms (matches the config cap).
+const maxOverrideMoveSpeedMs = 1000
+
 // RunCaptureFunc runs a capture with the given overrides.
 // It is called from the POST /run handler in a goroutine.
 type RunCaptureFunc func(ctx context.Context, overrides Overrides) error
 
+// PlanFunc computes the capture plan for the given overrides without moving
+// anything. It is called from the POST /plan handler.
+type PlanFunc func(overrides Overrides) (Plan, error)
+
+// Plan is a capture preview returned by POST /plan.
+type Plan struct {
+	Mode         string     `json:"mode"` // "grid" or "timelapse"
+	Columns      int        `json:"columns,omitempty"`
+	Rows         int        `json:"rows,omitempty"`
+	TotalShots   int        `json:"total_shots"`
+	PanStepSize  int        `json:"pan_step_size,omitempty"`  // motor steps between two columns
+	TiltStepSize int        `json:"tilt_step_size,omitempty"` // motor steps between two rows
+	Panoramas    []PlanGrid `json:"panoramas,omitempty"`      // one entry per grid in multi-panorama runs
+	EstimatedSec float64    `json:"estimated_seconds"`        // rough capture duration
+}
+
+// PlanGrid is one grid of a multi-panorama plan.
+type PlanGrid struct {
+	Name         string `json:"name,omitempty"`
+	Columns      int    `json:"columns"`
+	Rows         int    `json:"rows"`
+	PanStepSize  int    `json:"pan_step_size"`
+	TiltStepSize int    `json:"tilt_step_size"`
+}
+
 // FormConfig holds default values for the capture form (from config).
 type FormConfig struct {
 	HorizontalAngleDeg float64 `json:"horizontal_angle_deg"`
 	VerticalAngleDeg   float64 `json:"vertical_angle_deg"`
 	FocalLengthMm      float64 `json:"focal_length_mm"`
+
+	OverlapPercent       float64 `json:"overlap_percent"`
+	InterMoveDelayMs     int     `json:"inter_move_delay_ms"`
+	StabilizationDelayMs int     `json:"stabilization_delay_ms"`
+	ShotDelayMs          int     `json:"shot_delay_ms"`
+	MoveSpeedMs          int     `json:"move_speed_ms"`
 }
 
 // Handlers holds dependencies for HTTP handlers.
@@ -43,14 +94,46 @@ type Handlers struct {
 	Broadcaster       *StatusBroadcaster
 	RunCapture        RunCaptureFunc
 	FormDefaults      FormConfig
-	HeartbeatInterval time.Duration // SSE heartbeat interval; 0 defaults to 30s.
-	runningMu         sync.Mutex
-	running           bool
-	lastCaptureMu     sync.Mutex
-	lastCaptureAt     time.Time
-	staticFS          fs.FS
-	captureCancelMu   sync.Mutex
-	captureCancel     context.CancelFunc
+	HeartbeatInterval time.Duration       // SSE heartbeat interval; 0 defaults to 30s.
+	Jobs              *JobQueue           // capture jobs; nil when RunCapture is nil
+	Lifecycle         *capture.Lifecycle  // capture state machine for GET /status; optional
+	Jog               JogFunc             // manual head movement; optional
+	Control           *ControlLock        // control claims; nil lets every client act on the rig
+	Plan              PlanFunc            // capture preview for POST /plan; optional
+	FullConfig        ConfigFunc          // GET /config/full; optional
+	EffectiveConfig   EffectiveConfigFunc // GET /config/effective; optional
+	ConfigSchema      map[string]any      // JSON Schema of the config file for GET /config/schema; optional
+	UpdateConfig      UpdateConfigFunc    // PUT /config; optional
+	Profiles          ProfilesFunc        // GET /profiles; optional
+	ActivateProfile   ActivateProfileFunc // POST /profiles/{name}/activate; optional
+	UploadProfile     UploadProfileFunc   // POST /config/upload; optional
+	SaveDefaults      SaveDefaultsFunc    // POST /config/defaults; optional
+	LiveView          LiveViewFunc        // camera live view for GET /liveview; optional
+	Sessions          *session.Store      // past runs for GET /history; optional
+	Thumbnails        *session.Thumbnails // downloaded pictures for GET /shots; optional
+	Metrics           *metrics.Registry   // GET /metrics; optional
+	Health            HealthFunc          // checks for GET /healthz and /readyz; optional
+	Head              HeadStateFunc       // head state of GET /debug/state; optional
+	SafeStop          SafeStopFunc        // stops the hardware after a panic (see Recover); optional
+	BasePath          string              // path prefix behind a reverse proxy, e.g. "/pango"; "" = root (see StripBasePath)
+	PublicURL         string              // URL encoded by GET /qr, e.g. "http://pango.local:8080/"; "" = from the request
+	ControlNetworks   []netip.Prefix      // clients allowed to act on the rig (controlledRoutes, gRPC, WebSocket commands); empty = any
+	Page              PageInfoFunc        // rig description rendered into the index page; optional
+	Build             BuildInfo           // GET /version
+	assets            *assets
+
+	formMu sync.RWMutex // guards FormDefaults once the server runs
+
+	indexMu sync.Mutex
+	index   *asset // last rendered index page (see indexPage)
+
+	jogMu   sync.Mutex
+	jogStop context.CancelFunc // interrupts the jog in progress; nil when idle
+
+	ptz ptzMover // moves of the ONVIF PTZ service
+
+	syncMu   sync.Mutex
+	syncGate *SyncGate // gate of the last capture started by POST /sync/run
 }
 
 // ValidateOverrides checks that capture overrides contain valid numeric values.
@@ -74,39 +157,61 @@ func ValidateOverrides(o Overrides) error {
 	if o.FocalLengthMm <= 0 || o.FocalLengthMm > 500 {
 		return fmt.Errorf("focal_length_mm must be between 1 and 500, got %g", o.FocalLengthMm)
 	}
+	if math.IsNaN(o.OverlapPercent) || math.IsInf(o.OverlapPercent, 0) {
+		return errors.New("overlap_percent must be a finite number")
+	}
+	if o.OverlapPercent < 0 || o.OverlapPercent >= 100 {
+		return fmt.Errorf("overlap_percent must be at least 0 and below 100, got %g", o.OverlapPercent)
+	}
+	if o.InterMoveDelayMs < 0 || o.InterMoveDelayMs > maxOverrideDelayMs {
+		return fmt.Errorf("inter_move_delay_ms must be between 0 and %d, got %d", maxOverrideDelayMs, o.InterMoveDelayMs)
+	}
+	if o.StabilizationDelayMs < 0 || o.StabilizationDelayMs > maxOverrideDelayMs {
+		return fmt.Errorf("stabilization_delay_ms must be between 0 and %d, got %d", maxOverrideDelayMs, o.StabilizationDelayMs)
+	}
+	if o.ShotDelayMs < 0 || o.ShotDelayMs > maxOverrideDelayMs {
+		return fmt.Errorf("shot_delay_ms must be between 0 and %d, got %d", maxOverrideDelayMs, o.ShotDelayMs)
+	}
+	if o.MoveSpeedMs < 0 || o.MoveSpeedMs > maxOverrideMoveSpeedMs {
+		return fmt.Errorf("move_speed_ms must be between 0 and %d, got %d", maxOverrideMoveSpeedMs, o.MoveSpeedMs)
+	}
 	return nil
 }
 
 // NewHandlers creates handlers with the given dependencies.
-// If runCapture is nil, POST /run will return 503 Service Unavailable.
+// If runCapture is nil, POST /run and POST /jobs will return 503 Service Unavailable.
 func NewHandlers(broadcaster *StatusBroadcaster, runCapture RunCaptureFunc, formDefaults FormConfig, staticFS fs.FS) *Handlers {
-	return &Handlers{
-		Broadcaster:   broadcaster,
-		RunCapture:    runCapture,
-		FormDefaults:  formDefaults,
-		staticFS:      staticFS,
+	h := &Handlers{
+		Broadcaster:  broadcaster,
+		RunCapture:   runCapture,
+		FormDefaults: formDefaults,
+		Control:      NewControlLock(),
+		assets:       newAssets(staticFS),
 	}
+	if runCapture != nil {
+		h.Jobs = NewJobQueue(runCapture, broadcaster)
+		h.Jobs.protect = h.protect
+	}
+	return h
 }
 
 // HandleConfig returns the form default values (from config) as JSON.
 func (h *Handlers) HandleConfig(w http.ResponseWriter, r *http.Request) {
+	h.formMu.RLock()
+	defaults := h.FormDefaults
+	h.formMu.RUnlock()
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(h.FormDefaults)
-}
-
-// ServeIndex serves the main HTML page (root path only).
-func (h *Handlers) ServeIndex(w http.ResponseWriter, r *http.Request) {
-	data, err := fs.ReadFile(h.staticFS, "index.html")
-	if err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
-		return
-	}
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	w.Write(data)
+	json.NewEncoder(w).Encode(defaults)
 }
 
 // HandleRun handles POST /run to start a capture.
 func (h *Handlers) HandleRun(w http.ResponseWriter, r *http.Request) {
+	h.startCapture(w, r, nil)
+}
+
+// startCapture starts the capture described by the request body, as a
+// follower of another rig when gate is not nil (see HandleSyncRun).
+func (h *Handlers) startCapture(w http.ResponseWriter, r *http.Request, gate *SyncGate) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -126,65 +231,35 @@ func (h *Handlers) HandleRun(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if h.RunCapture == nil {
+	if h.Jobs == nil {
 		http.Error(w, "capture not configured", http.StatusServiceUnavailable)
 		return
 	}
 
-	h.runningMu.Lock()
-	if h.running {
-		h.runningMu.Unlock()
-		http.Error(w, "capture already in progress", http.StatusConflict)
-		return
+	// Start immediately; /run does not queue behind other jobs (use POST /jobs for that).
+	// The queue enforces the minimum delay between captures to protect hardware.
+	ctx := r.Context()
+	if gate != nil {
+		ctx = WithSyncGate(ctx, gate)
 	}
-	h.running = true
-	h.runningMu.Unlock()
-
-	// Enforce minimum delay between captures to protect hardware (motors, camera).
-	h.lastCaptureMu.Lock()
-	if d := minDelayBetweenCaptures - time.Since(h.lastCaptureAt); d > 0 {
-		h.lastCaptureMu.Unlock()
-		h.runningMu.Lock()
-		h.running = false
-		h.runningMu.Unlock()
-		http.Error(w, "please wait before starting another capture", http.StatusTooManyRequests)
+	if _, err := h.Jobs.StartNow(ctx, overrides); err != nil {
+		var tooSoon *TooSoonError
+		switch {
+		case errors.As(err, &tooSoon):
+			setRetryAfter(w, tooSoon.RetryAfter)
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+		case errors.Is(err, ErrShuttingDown):
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		default:
+			http.Error(w, err.Error(), http.StatusConflict)
+		}
 		return
 	}
-	h.lastCaptureAt = time.Now()
-	h.lastCaptureMu.Unlock()
-
-	// Create a cancellable context detached from the HTTP request so the capture
-	// survives browser disconnects but can be stopped via POST /cancel.
-	ctx, cancel := context.WithCancel(context.Background())
-
-	h.captureCancelMu.Lock()
-	h.captureCancel = cancel
-	h.captureCancelMu.Unlock()
-
-	// Run in goroutine; clear running and cancel func when done.
-	go func() {
-		defer func() {
-			h.captureCancelMu.Lock()
-			h.captureCancel = nil
-			h.captureCancelMu.Unlock()
-
-			h.runningMu.Lock()
-			h.running = false
-			h.runningMu.Unlock()
-		}()
-
-		if err := h.RunCapture(ctx, overrides); err != nil {
-			if errors.Is(err, context.Canceled) {
-				h.Broadcaster.Broadcast("warning", "Capture cancelled by user")
-				log.Println("capture cancelled by user")
-			} else {
-				h.Broadcaster.Broadcast("error", "Capture failed: "+err.Error())
-				log.Printf("capture failed: %v", err)
-			}
-		} else {
-			h.Broadcaster.Broadcast("info", "Sequence complete")
-		}
-	}()
+	if gate != nil {
+		h.syncMu.Lock()
+		h.syncGate = gate
+		h.syncMu.Unlock()
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusAccepted)
@@ -198,17 +273,11 @@ func (h *Handlers) HandleCancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.captureCancelMu.Lock()
-	cancel := h.captureCancel
-	h.captureCancelMu.Unlock()
-
-	if cancel == nil {
+	if h.Jobs == nil || !h.Jobs.CancelRunning() {
 		http.Error(w, "no capture in progress", http.StatusConflict)
 		return
 	}
 
-	cancel()
-
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -217,6 +286,200 @@ func (h *Handlers) HandleCancel(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// HandleETA handles GET /eta: the shots remaining and the estimated end of
+// the running capture, from the time its shots took so far.
+func (h *Handlers) HandleETA(w http.ResponseWriter, r *http.Request) {
+	if h.Lifecycle == nil {
+		http.Error(w, "capture status not available", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Lifecycle.ETA())
+}
+
+// HandlePause handles POST /pause: the running capture stops at the next
+// cell boundary (or timelapse frame) and waits for POST /resume. It returns
+// the capture status, in state "paused".
+func (h *Handlers) HandlePause(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, true)
+}
+
+// HandleResume handles POST /resume: a paused capture continues.
+func (h *Handlers) HandleResume(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, false)
+}
+
+func (h *Handlers) setPaused(w http.ResponseWriter, pause bool) {
+	if h.Lifecycle == nil {
+		http.Error(w, "capture status not available", http.StatusServiceUnavailable)
+		return
+	}
+	if err := h.pauseCapture(pause); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Lifecycle.Snapshot())
+}
+
+// pauseCapture pauses or resumes the running capture. It fails when no
+// capture is shooting (pause) or paused (resume).
+func (h *Handlers) pauseCapture(pause bool) error {
+	if h.Lifecycle == nil {
+		return errors.New("capture status not available")
+	}
+	if pause {
+		return h.Lifecycle.Pause()
+	}
+	return h.Lifecycle.Resume()
+}
+
+// HandlePlan handles POST /plan: it takes the same overrides as POST /run and
+// returns the grid size, shot count and estimated duration, without moving.
+func (h *Handlers) HandlePlan(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	var overrides Overrides
+	if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := ValidateOverrides(overrides); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.Plan == nil {
+		http.Error(w, "planning not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	plan, err := h.Plan(overrides)
+	if err != nil {
+		// The overrides are valid numbers but cannot be planned (e.g. lens wider than the angle)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+// HandleStatus handles GET /status: the capture state, current cell and timings.
+func (h *Handlers) HandleStatus(w http.ResponseWriter, r *http.Request) {
+	if h.Lifecycle == nil {
+		http.Error(w, "capture status not available", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Lifecycle.Snapshot())
+}
+
+// HandleEnqueueJob handles POST /jobs to queue a capture behind the current one.
+func (h *Handlers) HandleEnqueueJob(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	var overrides Overrides
+	if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := ValidateOverrides(overrides); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.Jobs == nil {
+		http.Error(w, "capture not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	job, err := h.Jobs.Enqueue(r.Context(), overrides)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, http.StatusCreated, job)
+}
+
+// HandleListJobs handles GET /jobs: finished, running and queued jobs in that order.
+func (h *Handlers) HandleListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs := []Job{}
+	if h.Jobs != nil {
+		jobs = h.Jobs.List()
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// HandleGetJob handles GET /jobs/{id}.
+func (h *Handlers) HandleGetJob(w http.ResponseWriter, r *http.Request) {
+	if h.Jobs == nil {
+		http.Error(w, ErrJobNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	job, ok := h.Jobs.Get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, ErrJobNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// HandleCancelJob handles POST /jobs/{id}/cancel. Queued jobs are dropped
+// from the queue; the running job is asked to stop.
+func (h *Handlers) HandleCancelJob(w http.ResponseWriter, r *http.Request) {
+	if h.Jobs == nil {
+		http.Error(w, ErrJobNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	id := r.PathValue("id")
+	if err := h.Jobs.Cancel(id); err != nil {
+		writeJobError(w, err)
+		return
+	}
+	job, _ := h.Jobs.Get(id)
+	writeJSON(w, http.StatusOK, job)
+}
+
+// HandleMoveJob handles POST /jobs/{id}/move with body {"position": n}
+// to reorder a queued job (0 = next to run).
+func (h *Handlers) HandleMoveJob(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	var body struct {
+		Position *int `json:"position"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Position == nil {
+		http.Error(w, "invalid JSON: position is required", http.StatusBadRequest)
+		return
+	}
+	if *body.Position < 0 {
+		http.Error(w, "position must be >= 0", http.StatusBadRequest)
+		return
+	}
+	if h.Jobs == nil {
+		http.Error(w, ErrJobNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err := h.Jobs.Move(r.PathValue("id"), *body.Position); err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Jobs.List())
+}
+
+// writeJobError maps job queue errors to HTTP status codes.
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrJobFinished):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
 // sanitizeSSE strips newlines and carriage returns from an SSE data payload
 // to prevent breaking the SSE framing. json.Marshal already escapes these,
 // but this provides a defensive second layer.
@@ -226,24 +489,69 @@ func sanitizeSSE(s string) string {
 	return s
 }
 
-// HandleStatusStream handles GET /status/stream for SSE.
+// writeSSE writes one event, with its ID so that a reconnecting client can
+// resume after it.
+func writeSSE(w io.Writer, msg string) {
+	var evt struct {
+		ID uint64 `json:"id"`
+	}
+	if json.Unmarshal([]byte(msg), &evt) == nil && evt.ID > 0 {
+		fmt.Fprintf(w, "id: %d\n", evt.ID)
+	}
+	io.WriteString(w, "data: "+sanitizeSSE(msg)+"\n\n")
+}
+
+// lastEventID returns the ID of the last event a reconnecting client
+// received: the Last-Event-ID header (sent by EventSource when it
+// reconnects) or the last_event_id query parameter (for clients that open a
+// new stream).
+func lastEventID(r *http.Request) (uint64, bool) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("last_event_id")
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	return id, err == nil
+}
+
+// HandleStatusStream handles GET /status/stream for SSE. A client resuming
+// with a last event ID (see lastEventID) first gets the events it missed.
+// The types and level query parameters select events (see ParseEventFilter).
 func (h *Handlers) HandleStatusStream(w http.ResponseWriter, r *http.Request) {
+	keepWriting(w)
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		http.Error(w, "streaming not supported", http.StatusInternalServerError)
 		return
 	}
+	filter, err := ParseEventFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 	w.Header().Set("X-Accel-Buffering", "no") // nginx
 
-	ch, unsub := h.Broadcaster.Subscribe()
+	var missed []string
+	var ch <-chan string
+	var unsub func()
+	if id, ok := lastEventID(r); ok {
+		missed, ch, unsub = h.Broadcaster.SubscribeSince(id, filter)
+	} else {
+		ch, unsub = h.Broadcaster.SubscribeFiltered(filter)
+	}
 	defer unsub()
+	dbg.Verbose("status stream opened", "client", clientIP(r))
+	defer dbg.Verbose("status stream closed", "client", clientIP(r))
 
 	// Send initial comment to establish connection
 	w.Write([]byte(": connected\n\n"))
+	for _, msg := range missed {
+		writeSSE(w, msg)
+	}
 	flusher.Flush()
 
 	// Heartbeat while idle
@@ -260,7 +568,7 @@ func (h *Handlers) HandleStatusStream(w http.ResponseWriter, r *http.Request) {
 			if !ok {
 				return
 			}
-			w.Write([]byte("data: " + sanitizeSSE(msg) + "\n\n"))
+			writeSSE(w, msg)
 			flusher.Flush()
 
 		case <-ticker.C:
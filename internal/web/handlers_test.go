@@ -5,6 +5,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +14,8 @@ import (
 	"testing"
 	"testing/fstest"
 	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
 )
 
 // ---------- ValidateOverrides ----------
@@ -21,10 +25,12 @@ func TestValidateOverrides_Valid(t *testing.T) {
 		name string
 		o    Overrides
 	}{
-		{"mid_range", Overrides{180, 90, 35}},
-		{"min_boundary", Overrides{1, 1, 1}},
-		{"max_boundary", Overrides{360, 180, 500}},
-		{"fractional", Overrides{0.5, 0.5, 0.5}},
+		{"mid_range", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35}},
+		{"min_boundary", Overrides{HorizontalAngleDeg: 1, VerticalAngleDeg: 1, FocalLengthMm: 1}},
+		{"max_boundary", Overrides{HorizontalAngleDeg: 360, VerticalAngleDeg: 180, FocalLengthMm: 500}},
+		{"fractional", Overrides{HorizontalAngleDeg: 0.5, VerticalAngleDeg: 0.5, FocalLengthMm: 0.5}},
+		{"delays", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, InterMoveDelayMs: 1200, StabilizationDelayMs: 60000}},
+		{"overlap_and_speed", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, OverlapPercent: 99.5, ShotDelayMs: 60000, MoveSpeedMs: 1000}},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -40,10 +46,10 @@ func TestValidateOverrides_ZeroRejected(t *testing.T) {
 		name string
 		o    Overrides
 	}{
-		{"horizontal_zero", Overrides{0, 90, 35}},
-		{"vertical_zero", Overrides{180, 0, 35}},
-		{"focal_zero", Overrides{180, 90, 0}},
-		{"all_zero", Overrides{0, 0, 0}},
+		{"horizontal_zero", Overrides{HorizontalAngleDeg: 0, VerticalAngleDeg: 90, FocalLengthMm: 35}},
+		{"vertical_zero", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 0, FocalLengthMm: 35}},
+		{"focal_zero", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 0}},
+		{"all_zero", Overrides{HorizontalAngleDeg: 0, VerticalAngleDeg: 0, FocalLengthMm: 0}},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -60,9 +66,10 @@ func TestValidateOverrides_NaN(t *testing.T) {
 		name string
 		o    Overrides
 	}{
-		{"horizontal_NaN", Overrides{nan, 90, 35}},
-		{"vertical_NaN", Overrides{180, nan, 35}},
-		{"focal_NaN", Overrides{180, 90, nan}},
+		{"horizontal_NaN", Overrides{HorizontalAngleDeg: nan, VerticalAngleDeg: 90, FocalLengthMm: 35}},
+		{"vertical_NaN", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: nan, FocalLengthMm: 35}},
+		{"focal_NaN", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: nan}},
+		{"overlap_NaN", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, OverlapPercent: nan}},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -80,10 +87,11 @@ func TestValidateOverrides_Infinity(t *testing.T) {
 		name string
 		o    Overrides
 	}{
-		{"horizontal_+Inf", Overrides{posInf, 90, 35}},
-		{"horizontal_-Inf", Overrides{negInf, 90, 35}},
-		{"vertical_+Inf", Overrides{180, posInf, 35}},
-		{"focal_-Inf", Overrides{180, 90, negInf}},
+		{"horizontal_+Inf", Overrides{HorizontalAngleDeg: posInf, VerticalAngleDeg: 90, FocalLengthMm: 35}},
+		{"horizontal_-Inf", Overrides{HorizontalAngleDeg: negInf, VerticalAngleDeg: 90, FocalLengthMm: 35}},
+		{"vertical_+Inf", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: posInf, FocalLengthMm: 35}},
+		{"focal_-Inf", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: negInf}},
+		{"overlap_+Inf", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, OverlapPercent: posInf}},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -99,9 +107,14 @@ func TestValidateOverrides_Negative(t *testing.T) {
 		name string
 		o    Overrides
 	}{
-		{"horizontal_negative", Overrides{-1, 90, 35}},
-		{"vertical_negative", Overrides{180, -5, 35}},
-		{"focal_negative", Overrides{180, 90, -10}},
+		{"horizontal_negative", Overrides{HorizontalAngleDeg: -1, VerticalAngleDeg: 90, FocalLengthMm: 35}},
+		{"vertical_negative", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: -5, FocalLengthMm: 35}},
+		{"focal_negative", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: -10}},
+		{"inter_move_delay_negative", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, InterMoveDelayMs: -1}},
+		{"stabilization_delay_negative", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, StabilizationDelayMs: -1}},
+		{"overlap_negative", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, OverlapPercent: -1}},
+		{"shot_delay_negative", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, ShotDelayMs: -1}},
+		{"move_speed_negative", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, MoveSpeedMs: -1}},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -117,9 +130,14 @@ func TestValidateOverrides_OutOfRange(t *testing.T) {
 		name string
 		o    Overrides
 	}{
-		{"horizontal_361", Overrides{361, 90, 35}},
-		{"vertical_181", Overrides{180, 181, 35}},
-		{"focal_501", Overrides{180, 90, 501}},
+		{"horizontal_361", Overrides{HorizontalAngleDeg: 361, VerticalAngleDeg: 90, FocalLengthMm: 35}},
+		{"vertical_181", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 181, FocalLengthMm: 35}},
+		{"focal_501", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 501}},
+		{"inter_move_delay_60001", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, InterMoveDelayMs: 60001}},
+		{"stabilization_delay_60001", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, StabilizationDelayMs: 60001}},
+		{"overlap_100", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, OverlapPercent: 100}},
+		{"shot_delay_60001", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, ShotDelayMs: 60001}},
+		{"move_speed_1001", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35, MoveSpeedMs: 1001}},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -153,7 +171,7 @@ func noopCapture(_ context.Context, _ Overrides) error {
 }
 
 func validOverridesJSON() []byte {
-	data, _ := json.Marshal(Overrides{180, 30, 35})
+	data, _ := json.Marshal(Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35})
 	return data
 }
 
@@ -209,7 +227,7 @@ func TestHandleRun_InvalidJSON(t *testing.T) {
 
 func TestHandleRun_InvalidOverrides(t *testing.T) {
 	h := newTestHandlers(noopCapture)
-	data, _ := json.Marshal(Overrides{0, 90, 35})
+	data, _ := json.Marshal(Overrides{HorizontalAngleDeg: 0, VerticalAngleDeg: 90, FocalLengthMm: 35})
 	req := httptest.NewRequest(http.MethodPost, "/run", bytes.NewReader(data))
 	w := httptest.NewRecorder()
 
@@ -303,6 +321,9 @@ func TestHandleRun_RateLimiting(t *testing.T) {
 	if w2.Code != http.StatusTooManyRequests {
 		t.Errorf("rate-limited request: status = %d, want %d", w2.Code, http.StatusTooManyRequests)
 	}
+	if got := w2.Header().Get("Retry-After"); got != "5" {
+		t.Errorf("Retry-After = %q, want 5", got)
+	}
 }
 
 // ---------- HandleConfig ----------
@@ -333,6 +354,131 @@ func TestHandleConfig(t *testing.T) {
 	}
 }
 
+// ---------- HandleStatus ----------
+
+func TestHandleStatus(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Lifecycle = capture.NewLifecycle()
+	h.Lifecycle.Transition(capture.StatePlanning)
+	h.Lifecycle.SetShotsTotal(12)
+	h.Lifecycle.Transition(capture.StateShooting)
+	h.Lifecycle.SetCell(2, 3)
+
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	w := httptest.NewRecorder()
+	h.HandleStatus(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var st capture.Status
+	if err := json.NewDecoder(w.Body).Decode(&st); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if st.State != capture.StateShooting {
+		t.Errorf("state = %q, want shooting", st.State)
+	}
+	if st.Cell.Column != 2 || st.Cell.Row != 3 {
+		t.Errorf("cell = %+v, want column 2 row 3", st.Cell)
+	}
+	if st.ShotsTotal != 12 || st.StartedAt == nil {
+		t.Errorf("shots_total = %d, started_at = %v", st.ShotsTotal, st.StartedAt)
+	}
+}
+
+func TestHandleETA(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	w := httptest.NewRecorder()
+	h.HandleETA(w, httptest.NewRequest(http.MethodGet, "/eta", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("no lifecycle: status = %d, want 503", w.Code)
+	}
+
+	h.Lifecycle = capture.NewLifecycle()
+	h.Lifecycle.Transition(capture.StatePlanning)
+	h.Lifecycle.SetShotsTotal(12)
+	h.Lifecycle.SetEstimate(time.Minute)
+	h.Lifecycle.Transition(capture.StateShooting)
+	w = httptest.NewRecorder()
+	h.HandleETA(w, httptest.NewRequest(http.MethodGet, "/eta", nil))
+	var eta capture.ETA
+	if err := json.NewDecoder(w.Body).Decode(&eta); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusOK || eta.Source != capture.ETAPlan || eta.ShotsRemaining != 12 || eta.CompletesAt == nil {
+		t.Errorf("status %d, eta %+v", w.Code, eta)
+	}
+}
+
+func TestHandleStatus_NoLifecycle(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	w := httptest.NewRecorder()
+	h.HandleStatus(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+// ---------- HandlePlan ----------
+
+func TestHandlePlan(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	var got Overrides
+	h.Plan = func(o Overrides) (Plan, error) {
+		got = o
+		return Plan{Mode: "grid", Columns: 12, Rows: 7, TotalShots: 84, EstimatedSec: 1320}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/plan", bytes.NewReader(validOverridesJSON()))
+	w := httptest.NewRecorder()
+	h.HandlePlan(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var plan Plan
+	if err := json.NewDecoder(w.Body).Decode(&plan); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if plan.TotalShots != 84 || plan.EstimatedSec != 1320 {
+		t.Errorf("plan = %+v", plan)
+	}
+	if got != (Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35}) {
+		t.Errorf("overrides passed to Plan = %+v", got)
+	}
+	if _, running := h.Jobs.Running(); running {
+		t.Error("POST /plan must not start a capture")
+	}
+}
+
+func TestHandlePlan_Errors(t *testing.T) {
+	planner := func(Overrides) (Plan, error) { return Plan{}, errors.New("angle narrower than the lens") }
+	cases := []struct {
+		name string
+		plan PlanFunc
+		body string
+		want int
+	}{
+		{"invalid_json", planner, "{", http.StatusBadRequest},
+		{"invalid_overrides", planner, `{"horizontal_angle_deg":0,"vertical_angle_deg":30,"focal_length_mm":35}`, http.StatusBadRequest},
+		{"not_configured", nil, string(validOverridesJSON()), http.StatusServiceUnavailable},
+		{"cannot_plan", planner, string(validOverridesJSON()), http.StatusUnprocessableEntity},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(noopCapture)
+			h.Plan = tc.plan
+			req := httptest.NewRequest(http.MethodPost, "/plan", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			h.HandlePlan(w, req)
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d", w.Code, tc.want)
+			}
+		})
+	}
+}
+
 // ---------- ServeIndex ----------
 
 func TestServeIndex(t *testing.T) {
@@ -408,12 +554,9 @@ func TestHandleCancel_CancelsRunningCapture(t *testing.T) {
 	// Collect the capture error asynchronously
 	go func() {
 		// The goroutine in HandleRun will broadcast when it finishes;
-		// we just need to wait for the running flag to clear.
+		// we just need to wait for the running job to clear.
 		for {
-			h.runningMu.Lock()
-			running := h.running
-			h.runningMu.Unlock()
-			if !running {
+			if _, running := h.Jobs.Running(); !running {
 				break
 			}
 			time.Sleep(10 * time.Millisecond)
@@ -463,6 +606,47 @@ func TestHandleCancel_CancelsRunningCapture(t *testing.T) {
 	}
 }
 
+// ---------- HandlePause / HandleResume ----------
+
+func TestHandlePauseResume(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Lifecycle = capture.NewLifecycle()
+	post := func(handler http.HandlerFunc, path string) (int, capture.State) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, path, nil))
+		var st capture.Status
+		json.NewDecoder(w.Body).Decode(&st)
+		return w.Code, st.State
+	}
+
+	if code, _ := post(h.HandlePause, "/pause"); code != http.StatusConflict {
+		t.Errorf("pause while idle: status = %d, want 409", code)
+	}
+	h.Lifecycle.Transition(capture.StatePlanning)
+	h.Lifecycle.Transition(capture.StateShooting)
+	if code, st := post(h.HandlePause, "/pause"); code != http.StatusOK || st != capture.StatePaused {
+		t.Errorf("pause: status %d, state %q, want 200 and paused", code, st)
+	}
+	if code, _ := post(h.HandlePause, "/pause"); code != http.StatusConflict {
+		t.Errorf("pause while paused: status = %d, want 409", code)
+	}
+	if code, st := post(h.HandleResume, "/resume"); code != http.StatusOK || st != capture.StateShooting {
+		t.Errorf("resume: status %d, state %q, want 200 and shooting", code, st)
+	}
+	if code, _ := post(h.HandleResume, "/resume"); code != http.StatusConflict {
+		t.Errorf("resume while shooting: status = %d, want 409", code)
+	}
+}
+
+func TestHandlePause_NoLifecycle(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	w := httptest.NewRecorder()
+	h.HandlePause(w, httptest.NewRequest(http.MethodPost, "/pause", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
+
 func TestHandleRun_CancelledCaptureBroadcastsWarning(t *testing.T) {
 	started := make(chan struct{})
 	cancelCapture := func(ctx context.Context, _ Overrides) error {
@@ -485,14 +669,10 @@ func TestHandleRun_CancelledCaptureBroadcastsWarning(t *testing.T) {
 
 	<-started
 
-	// Cancel directly via the stored cancel func
-	h.captureCancelMu.Lock()
-	cancel := h.captureCancel
-	h.captureCancelMu.Unlock()
-	if cancel == nil {
-		t.Fatal("captureCancel is nil after starting capture")
+	// Cancel directly via the job queue
+	if !h.Jobs.CancelRunning() {
+		t.Fatal("no running job after starting capture")
 	}
-	cancel()
 
 	// Wait for warning broadcast
 	select {
@@ -596,6 +776,67 @@ func TestHandleStatusStream_DeliversMessages(t *testing.T) {
 	}
 }
 
+func TestHandleStatusStream_ReplaysAfterLastEventID(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.HeartbeatInterval = 10 * time.Second
+	for _, msg := range []string{"one", "two", "three"} {
+		h.Broadcaster.Broadcast("info", msg)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(h.HandleStatusStream))
+	defer srv.Close()
+
+	cases := []struct {
+		name   string
+		header string
+		query  string
+		want   []string
+	}{
+		{"header", "1", "", []string{"2:two", "3:three"}},
+		{"query", "", "?last_event_id=2", []string{"3:three"}},
+		{"up_to_date", "3", "", []string{"4:live"}},
+		{"unknown_id", "99", "", []string{"1:one", "2:two", "3:three"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+tc.query, nil)
+			if tc.header != "" {
+				req.Header.Set("Last-Event-ID", tc.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET: %v", err)
+			}
+			defer resp.Body.Close()
+
+			scanner := bufio.NewScanner(resp.Body)
+			var got []string
+			var id string
+			for len(got) < len(tc.want) && scanner.Scan() {
+				line := scanner.Text()
+				switch {
+				case line == ": connected" && tc.name == "up_to_date":
+					h.Broadcaster.Broadcast("info", "live")
+				case strings.HasPrefix(line, "id: "):
+					id = strings.TrimPrefix(line, "id: ")
+				case strings.HasPrefix(line, "data: "):
+					var evt StatusEvent
+					if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &evt); err != nil {
+						t.Fatal(err)
+					}
+					if id != fmt.Sprint(evt.ID) {
+						t.Errorf("id line %q, event ID %d", id, evt.ID)
+					}
+					got = append(got, id+":"+evt.Msg)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("events = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestHandleStatusStream_Heartbeat(t *testing.T) {
 	h := newTestHandlers(noopCapture)
 	h.HeartbeatInterval = 50 * time.Millisecond
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Time allowed for the health checks of one request.
+const healthTimeout = 3 * time.Second
+
+// Results of a health check and of a health report.
+const (
+	HealthOK   = "ok"
+	HealthFail = "fail"
+)
+
+// HealthCheck is the result of one check (gpio, camera, config, ...).
+type HealthCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // HealthOK or HealthFail
+	Error  string `json:"error,omitempty"`
+}
+
+// Health is the response of GET /healthz and GET /readyz.
+type Health struct {
+	Status string        `json:"status"` // HealthFail when any check failed
+	Checks []HealthCheck `json:"checks"`
+}
+
+// HealthFunc runs the health checks. ctx carries the request deadline.
+type HealthFunc func(ctx context.Context) []HealthCheck
+
+// health runs the checks and summarizes them.
+func (h *Handlers) health(r *http.Request) Health {
+	report := Health{Status: HealthOK, Checks: []HealthCheck{}}
+	if h.Health == nil {
+		return report
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+	defer cancel()
+	if checks := h.Health(ctx); checks != nil {
+		report.Checks = checks
+	}
+	for _, c := range report.Checks {
+		if c.Status != HealthOK {
+			report.Status = HealthFail
+		}
+	}
+	return report
+}
+
+// HandleHealthz handles GET /healthz (liveness): it answers 200 as long as
+// the program serves requests, with the check results for information, so
+// that a watchdog does not restart the program over an unplugged camera.
+func (h *Handlers) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.health(r))
+}
+
+// HandleReadyz handles GET /readyz (readiness): 200 when every check
+// passes, 503 otherwise.
+func (h *Handlers) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	report := h.health(r)
+	status := http.StatusOK
+	if report.Status != HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func decodeHealth(t *testing.T, w *httptest.ResponseRecorder) Health {
+	t.Helper()
+	var h Health
+	if err := json.Unmarshal(w.Body.Bytes(), &h); err != nil {
+		t.Fatalf("decode: %v (body %q)", err, w.Body.String())
+	}
+	return h
+}
+
+func TestHealth_AllChecksPass(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Health = func(ctx context.Context) []HealthCheck {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("checks should run with a deadline")
+		}
+		return []HealthCheck{{Name: "gpio", Status: HealthOK}, {Name: "camera", Status: HealthOK}}
+	}
+	for name, handle := range map[string]http.HandlerFunc{"healthz": h.HandleHealthz, "readyz": h.HandleReadyz} {
+		w := httptest.NewRecorder()
+		handle(w, httptest.NewRequest(http.MethodGet, "/"+name, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want 200", name, w.Code)
+		}
+		if got := decodeHealth(t, w); got.Status != HealthOK || len(got.Checks) != 2 {
+			t.Errorf("%s: report = %+v", name, got)
+		}
+	}
+}
+
+func TestHealth_FailedCheck(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Health = func(context.Context) []HealthCheck {
+		return []HealthCheck{{Name: "gpio", Status: HealthOK}, {Name: "camera", Status: HealthFail, Error: "not connected"}}
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz: status = %d, want 503", w.Code)
+	}
+	got := decodeHealth(t, w)
+	if got.Status != HealthFail || got.Checks[1].Error != "not connected" {
+		t.Errorf("readyz: report = %+v", got)
+	}
+
+	// Liveness does not depend on the hardware.
+	w = httptest.NewRecorder()
+	h.HandleHealthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthz: status = %d, want 200", w.Code)
+	}
+	if got := decodeHealth(t, w); got.Status != HealthFail {
+		t.Errorf("healthz: status field = %q, want %q", got.Status, HealthFail)
+	}
+}
+
+func TestHealth_NoChecks(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	w := httptest.NewRecorder()
+	h.HandleReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "{\"status\":\"ok\",\"checks\":[]}\n" {
+		t.Errorf("status = %d, body = %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,187 @@
+package web
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/gigapixel"
+	"github.com/cjeanneret/PanGo/internal/hugin"
+	"github.com/cjeanneret/PanGo/internal/session"
+)
+
+// Maximum number of runs returned by GET /history.
+const maxHistoryLimit = session.MaxRecords
+
+// HandleHistory handles GET /history: past runs, most recent first, without
+// their shots. Optional filters: since (RFC 3339 time, or YYYY-MM-DD for
+// local midnight) and limit (default and maximum: all kept records).
+func (h *Handlers) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	if h.Sessions == nil {
+		writeJSON(w, http.StatusOK, []session.Summary{})
+		return
+	}
+
+	q := r.URL.Query()
+	var since time.Time
+	if v := q.Get("since"); v != "" {
+		t, err := parseSince(v)
+		if err != nil {
+			http.Error(w, "since must be an RFC 3339 time or a YYYY-MM-DD date", http.StatusBadRequest)
+			return
+		}
+		since = t
+	}
+	limit := maxHistoryLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxHistoryLimit {
+			http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxHistoryLimit), http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	records := h.Sessions.List()
+	slices.Reverse(records)
+	history := []session.Summary{}
+	for _, rec := range records {
+		if len(history) == limit || rec.StartedAt.Before(since) {
+			break // records are sorted by start time
+		}
+		history = append(history, rec.Summary())
+	}
+	writeJSON(w, http.StatusOK, history)
+}
+
+// HandleGetHistory handles GET /history/{id}: one run with all its shots.
+func (h *Handlers) HandleGetHistory(w http.ResponseWriter, r *http.Request) {
+	if h.Sessions == nil {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	rec, ok := h.Sessions.Get(r.PathValue("id"))
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, rec)
+}
+
+// HandleSessionReport handles GET /sessions/{id}/report: one run with
+// per-shot angles, times, durations and errors, as JSON, or as a CSV file
+// with ?format=csv. ?format=pto, pts, krpano and gigapan return a file for
+// stitching tools instead, and ?format=gpx the GPS track of the shots (see
+// writeStitchingFile).
+func (h *Handlers) HandleSessionReport(w http.ResponseWriter, r *http.Request) {
+	var rec session.Record
+	ok := false
+	if h.Sessions != nil {
+		rec, ok = h.Sessions.Get(r.PathValue("id"))
+	}
+	if !ok {
+		http.Error(w, "session not found", http.StatusNotFound)
+		return
+	}
+	report := rec.Report()
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Disposition", `attachment; filename="pango-`+rec.ID+`.json"`)
+		writeJSON(w, http.StatusOK, report)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="pango-`+rec.ID+`.csv"`)
+		if err := report.WriteCSV(w); err != nil {
+			log.Printf("web: write report %s: %v", rec.ID, err)
+		}
+	case "pto", "pts", "krpano", "gigapan", "gpx":
+		h.writeStitchingFile(w, r, rec, format)
+	default:
+		http.Error(w, `format must be "json", "csv", "pto", "pts", "krpano", "gigapan" or "gpx"`, http.StatusBadRequest)
+	}
+}
+
+// writeStitchingFile writes a file of rec for stitching tools: a Hugin
+// project for format "pto", a PTGui template for "pts", the krpano
+// settings of the stitched panorama for "krpano", a GigaPan Stitch image
+// list for "gigapan", and the GPX track of the shots to geotag the pictures
+// for "gpx". Its images are named from the images pattern (default
+// hugin.DefaultNames) numbered from first (default 1), the camera's numbers
+// of the files of the run; the krpano panorama is the file panorama
+// (default pango-<id>.jpg).
+func (h *Handlers) writeStitchingFile(w http.ResponseWriter, r *http.Request, rec session.Record, format string) {
+	q := r.URL.Query()
+	pattern := q.Get("images")
+	if pattern == "" {
+		pattern = hugin.DefaultNames
+	}
+	first := 1
+	if v := q.Get("first"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "first must be an integer", http.StatusBadRequest)
+			return
+		}
+		first = n
+	}
+	names, err := hugin.NewNamer(pattern, first)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var write func(io.Writer) error
+	filename, contentType := "pango-"+rec.ID+"."+format, "text/plain; charset=utf-8"
+	switch format {
+	case "pto", "pts":
+		var project hugin.Project
+		if project, err = rec.Hugin(names); err == nil {
+			write = project.Write
+			if format == "pts" {
+				write = project.WritePTGui
+			}
+		}
+	case "krpano":
+		var layout gigapixel.Layout
+		if layout, err = rec.Layout(names); err == nil {
+			panorama := q.Get("panorama")
+			if panorama == "" {
+				panorama = "pango-" + rec.ID + ".jpg"
+			}
+			write = func(w io.Writer) error { return layout.WriteKRPano(w, panorama) }
+			filename, contentType = "pango-"+rec.ID+".xml", "application/xml"
+		}
+	case "gigapan":
+		var layout gigapixel.Layout
+		if layout, err = rec.Layout(names); err == nil {
+			write = layout.WriteGigaPan
+			filename = "pango-" + rec.ID + "-gigapan.txt"
+		}
+	case "gpx":
+		write = func(w io.Writer) error { return rec.WriteGPX(w, names) }
+		contentType = "application/gpx+xml"
+	}
+	if err == nil {
+		// Fail before the headers: GigaPan Stitch needs every cell.
+		var b bytes.Buffer
+		if err = write(&b); err == nil {
+			w.Header().Set("Content-Type", contentType)
+			w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+			w.Write(b.Bytes())
+			return
+		}
+	}
+	http.Error(w, "session "+rec.ID+": "+err.Error(), http.StatusUnprocessableEntity)
+}
+
+// parseSince parses an RFC 3339 time, or a date meaning local midnight.
+func parseSince(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, v, time.Local)
+}
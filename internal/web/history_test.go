@@ -0,0 +1,242 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/session"
+)
+
+// newTestSessions returns a store with three runs, one day apart, ending
+// at "now".
+func newTestSessions(t *testing.T, now time.Time) *session.Store {
+	t.Helper()
+	store, err := session.NewStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 2; i >= 0; i-- {
+		start := now.Add(-time.Duration(i) * 24 * time.Hour)
+		rec := session.Record{
+			ID:        session.NewID(start),
+			Mode:      "grid",
+			Params:    session.Params{FocalLengthMm: float64(18 + i), ShotsPlanned: 2},
+			StartedAt: start,
+			EndedAt:   start.Add(time.Minute),
+			Outcome:   session.OutcomeDone,
+			Shots:     []session.Shot{{Index: 1}, {Index: 2}},
+		}
+		if err := store.Save(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func getHistory(t *testing.T, h *Handlers, target string) (int, []session.Summary) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.HandleHistory(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var list []session.Summary
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	return w.Code, list
+}
+
+// ---------- HandleHistory ----------
+
+func TestHandleHistory(t *testing.T) {
+	now := time.Date(2026, 6, 21, 21, 30, 0, 0, time.UTC)
+	h := newTestHandlers(noopCapture)
+	h.Sessions = newTestSessions(t, now)
+
+	code, list := getHistory(t, h, "/history")
+	if code != http.StatusOK || len(list) != 3 {
+		t.Fatalf("status %d, %d runs, want 200 and 3", code, len(list))
+	}
+	if !list[0].StartedAt.Equal(now) {
+		t.Errorf("first run started %v, want the most recent (%v)", list[0].StartedAt, now)
+	}
+	if list[0].ShotsTaken != 2 || list[0].DurationSec != 60 || list[0].Params.FocalLengthMm != 18 {
+		t.Errorf("summary = %+v", list[0])
+	}
+
+	if _, list := getHistory(t, h, "/history?since="+now.Add(-time.Hour).Format(time.RFC3339)); len(list) != 1 {
+		t.Errorf("since one hour ago: %d runs, want 1", len(list))
+	}
+	if _, list := getHistory(t, h, "/history?limit=2"); len(list) != 2 {
+		t.Errorf("limit=2: %d runs, want 2", len(list))
+	}
+}
+
+func TestHandleHistory_SinceDate(t *testing.T) {
+	now := time.Now()
+	h := newTestHandlers(noopCapture)
+	h.Sessions = newTestSessions(t, now)
+
+	_, list := getHistory(t, h, "/history?since="+now.Format(time.DateOnly))
+	if len(list) != 1 {
+		t.Errorf("since today: %d runs, want 1", len(list))
+	}
+}
+
+func TestHandleHistory_Errors(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Sessions = newTestSessions(t, time.Now())
+	for _, target := range []string{"/history?since=yesterday", "/history?limit=0", "/history?limit=x", "/history?limit=100000"} {
+		if code, _ := getHistory(t, h, target); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", target, code)
+		}
+	}
+}
+
+func TestHandleHistory_NoStore(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	if code, list := getHistory(t, h, "/history"); code != http.StatusOK || list == nil || len(list) != 0 {
+		t.Errorf("status %d, list %v, want 200 and []", code, list)
+	}
+}
+
+// ---------- HandleGetHistory ----------
+
+func TestHandleGetHistory(t *testing.T) {
+	now := time.Date(2026, 6, 21, 21, 30, 0, 0, time.UTC)
+	h := newTestHandlers(noopCapture)
+	h.Sessions = newTestSessions(t, now)
+
+	id := session.NewID(now)
+	req := httptest.NewRequest(http.MethodGet, "/history/"+id, nil)
+	req.SetPathValue("id", id)
+	w := httptest.NewRecorder()
+	h.HandleGetHistory(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var rec session.Record
+	json.NewDecoder(w.Body).Decode(&rec)
+	if rec.ID != id || len(rec.Shots) != 2 {
+		t.Errorf("record = %+v", rec)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/history/nope", nil)
+	req.SetPathValue("id", "nope")
+	w = httptest.NewRecorder()
+	h.HandleGetHistory(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown id: status = %d, want 404", w.Code)
+	}
+}
+
+// ---------- HandleSessionReport ----------
+
+func TestHandleSessionReport(t *testing.T) {
+	now := time.Date(2026, 6, 21, 21, 30, 0, 0, time.UTC)
+	h := newTestHandlers(noopCapture)
+	h.Sessions = newTestSessions(t, now)
+	id := session.NewID(now)
+
+	get := func(target, id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.SetPathValue("id", id)
+		w := httptest.NewRecorder()
+		h.HandleSessionReport(w, req)
+		return w
+	}
+
+	w := get("/sessions/"+id+"/report", id)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var rep session.Report
+	json.NewDecoder(w.Body).Decode(&rep)
+	if rep.ID != id || rep.ShotsTaken != 2 || len(rep.Shots) != 2 {
+		t.Errorf("report = %+v", rep)
+	}
+
+	w = get("/sessions/"+id+"/report?format=csv", id)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("csv: status %d, Content-Type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.Contains(got, "pango-"+id+".csv") {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n"); len(lines) != 3 {
+		t.Errorf("csv has %d lines, want header and 2 shots:\n%s", len(lines), w.Body)
+	}
+
+	if w := get("/sessions/"+id+"/report?format=xml", id); w.Code != http.StatusBadRequest {
+		t.Errorf("format=xml: status = %d, want 400", w.Code)
+	}
+	if w := get("/sessions/nope/report", "nope"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown id: status = %d, want 404", w.Code)
+	}
+}
+
+func TestHandleSessionReport_Stitching(t *testing.T) {
+	now := time.Date(2026, 6, 21, 21, 30, 0, 0, time.UTC)
+	h := newTestHandlers(noopCapture)
+	h.Sessions = newTestSessions(t, now)
+	old := session.NewID(now)
+	start := now.Add(time.Hour)
+	rec := session.Record{
+		ID:   session.NewID(start),
+		Mode: "grid",
+		Params: session.Params{
+			Columns: 2, Rows: 1, ShotsPlanned: 2, PanStepsPerDeg: 10, TiltStepsPerDeg: 10,
+			HorizontalFOVDeg: 36, VerticalFOVDeg: 24,
+		},
+		StartedAt: start,
+		Shots: []session.Shot{
+			{Index: 1, Column: 1, Row: 1, PanSteps: -100, Time: start, Location: &session.Location{LatitudeDeg: 46.5197, LongitudeDeg: 6.6323, Time: start}},
+			{Index: 2, Column: 2, Row: 1, PanSteps: 100},
+		},
+	}
+	if err := h.Sessions.Save(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		id       string
+		query    string // after format=
+		wantCode int
+		want     string
+		wantFile string
+	}{
+		{"default_names", rec.ID, "pto", http.StatusOK, `y-10 TrX0 TrY0 TrZ0 Tpy0 Tpp0 j0 Va1 Vb0 Vc0 Vd0 Vx0 Vy0 Vm5 n"IMG_0001.JPG"`, ".pto"},
+		{"camera_names", rec.ID, "pto&images=DSC_%2504d.NEF&first=412", http.StatusOK, `y10 TrX0 TrY0 TrZ0 Tpy0 Tpp0 j0 Va1 Vb0 Vc0 Vd0 Vx0 Vy0 Vm5 n"DSC_0413.NEF"`, ".pto"},
+		{"bad_names", rec.ID, "pto&images=DSC.NEF", http.StatusBadRequest, "", ""},
+		{"bad_first", rec.ID, "pto&first=one", http.StatusBadRequest, "", ""},
+		{"no_fov", old, "pto", http.StatusUnprocessableEntity, "field of view unknown", ""},
+		{"ptgui", rec.ID, "pts", http.StatusOK, "#-imgfile 6000 3925 \"IMG_0002.JPG\"\no f0 y10 r0 p0 v=0 ", ".pts"},
+		{"krpano", rec.ID, "krpano&panorama=night.jpg", http.StatusOK, `<image type="SPHERE" hfov="56" vfov="24" voffset="0">` + "\n\t\t<sphere url=\"night.jpg\" />", ".xml"},
+		{"gigapan", rec.ID, "gigapan&images=DSC_%2504d.NEF", http.StatusOK, "# rows: 1\n# columns: 2\n", "-gigapan.txt"},
+		{"gigapan_timelapse", old, "gigapan", http.StatusUnprocessableEntity, "", ""},
+		{"gpx", rec.ID, "gpx", http.StatusOK, "<trkpt lat=\"46.5197\" lon=\"6.6323\">\n\t\t\t\t<time>2026-06-21T22:30:00Z</time>\n\t\t\t\t<name>IMG_0001.JPG</name>", ".gpx"},
+		{"gpx_no_gps", old, "gpx", http.StatusUnprocessableEntity, "no shot with a GPS location", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/sessions/"+tc.id+"/report?format="+tc.query, nil)
+			req.SetPathValue("id", tc.id)
+			w := httptest.NewRecorder()
+			h.HandleSessionReport(w, req)
+			if w.Code != tc.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.wantCode, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tc.want) {
+				t.Errorf("body does not contain %q:\n%s", tc.want, w.Body)
+			}
+			if tc.wantCode == http.StatusOK && !strings.Contains(w.Header().Get("Content-Disposition"), "pango-"+tc.id+tc.wantFile) {
+				t.Errorf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+}
@@ -0,0 +1,402 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// JobStatus is the lifecycle state of a capture job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobDone      JobStatus = "done"
+	JobFailed    JobStatus = "failed"
+	JobCancelled JobStatus = "cancelled"
+)
+
+// Max finished jobs kept for GET /jobs. Older ones are dropped.
+const maxJobHistory = 50
+
+// Max jobs waiting in the queue. Protects memory on constrained devices.
+const maxQueuedJobs = 100
+
+var (
+	// ErrJobNotFound is returned for an unknown job ID.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling or moving a finished job.
+	ErrJobFinished = errors.New("job already finished")
+	// ErrQueueBusy is returned by StartNow when a job is running or waiting.
+	ErrQueueBusy = errors.New("capture already in progress")
+	// ErrQueueFull is returned by Enqueue when maxQueuedJobs are waiting.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrShuttingDown is returned by Enqueue and StartNow once the queue is
+	// closed, and is the cancellation cause of the job running at that time.
+	ErrShuttingDown = errors.New("server is shutting down")
+)
+
+// TooSoonError is returned by StartNow when the previous capture started
+// less than the minimum spacing ago.
+type TooSoonError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooSoonError) Error() string {
+	return "please wait before starting another capture"
+}
+
+// Job is a capture request and its outcome.
+type Job struct {
+	ID         string     `json:"id"`
+	Status     JobStatus  `json:"status"`
+	Overrides  Overrides  `json:"overrides"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	RequestID  string     `json:"request_id,omitempty"` // request that created the job
+
+	gate   *SyncGate // set when run as the follower of another rig
+	cancel context.CancelCauseFunc
+	done   chan struct{} // closed once the job has left the running slot
+}
+
+// JobQueue runs capture jobs one at a time, in queue order. Consecutive
+// starts are spaced by at least MinSpacing to protect the hardware.
+type JobQueue struct {
+	// MinSpacing is the minimum delay between two capture starts.
+	MinSpacing time.Duration
+
+	mu          sync.Mutex
+	run         RunCaptureFunc
+	broadcaster *StatusBroadcaster
+	queued      []*Job // waiting, in execution order
+	running     *Job
+	finished    []*Job // most recent last
+	nextID      int
+	lastStartAt time.Time
+	timer       *time.Timer // pending delayed dispatch, if any
+	closed      bool
+
+	// protect runs a capture, turning a panic into a *PanicError after
+	// stopping the hardware; nil lets the panic through.
+	protect func(what string, fn func() error) error
+}
+
+// NewJobQueue creates a queue that runs jobs with run and reports outcomes
+// on broadcaster.
+func NewJobQueue(run RunCaptureFunc, broadcaster *StatusBroadcaster) *JobQueue {
+	return &JobQueue{
+		MinSpacing:  minDelayBetweenCaptures,
+		run:         run,
+		broadcaster: broadcaster,
+	}
+}
+
+// Enqueue adds a job at the end of the queue. It starts as soon as the
+// queue is idle and the minimum spacing has elapsed. The request ID of ctx
+// (see RequestID) is kept with the job and passed on to the capture.
+func (q *JobQueue) Enqueue(ctx context.Context, o Overrides) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return Job{}, ErrShuttingDown
+	}
+	if len(q.queued) >= maxQueuedJobs {
+		return Job{}, ErrQueueFull
+	}
+	job := q.newJob(ctx, o)
+	q.queued = append(q.queued, job)
+	q.dispatchLocked()
+	return *job, nil
+}
+
+// StartNow starts a job immediately. It fails with ErrQueueBusy if a job is
+// running or waiting, and with *TooSoonError inside the spacing window.
+// As with Enqueue, ctx only provides the request ID: the capture outlives it.
+func (q *JobQueue) StartNow(ctx context.Context, o Overrides) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return Job{}, ErrShuttingDown
+	}
+	if q.running != nil || len(q.queued) > 0 {
+		return Job{}, ErrQueueBusy
+	}
+	if d := q.MinSpacing - time.Since(q.lastStartAt); d > 0 {
+		return Job{}, &TooSoonError{RetryAfter: d}
+	}
+	job := q.newJob(ctx, o)
+	q.startLocked(job)
+	return *job, nil
+}
+
+// List returns all jobs: finished (oldest first), running, then queued.
+func (q *JobQueue) List() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	jobs := make([]Job, 0, len(q.finished)+1+len(q.queued))
+	for _, j := range q.finished {
+		jobs = append(jobs, *j)
+	}
+	if q.running != nil {
+		jobs = append(jobs, *q.running)
+	}
+	for _, j := range q.queued {
+		jobs = append(jobs, *j)
+	}
+	return jobs
+}
+
+// Get returns the job with the given ID.
+func (q *JobQueue) Get(id string) (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if j := q.findLocked(id); j != nil {
+		return *j, true
+	}
+	return Job{}, false
+}
+
+// Running returns the running job, if any.
+func (q *JobQueue) Running() (Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.running == nil {
+		return Job{}, false
+	}
+	return *q.running, true
+}
+
+// Cancel cancels a queued job (removed from the queue) or requests
+// cancellation of the running job.
+func (q *JobQueue) Cancel(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.running != nil && q.running.ID == id {
+		q.running.cancel(nil)
+		return nil
+	}
+	for i, j := range q.queued {
+		if j.ID == id {
+			q.queued = append(q.queued[:i], q.queued[i+1:]...)
+			now := time.Now()
+			j.Status = JobCancelled
+			j.FinishedAt = &now
+			q.archiveLocked(j)
+			return nil
+		}
+	}
+	if q.findLocked(id) != nil {
+		return ErrJobFinished
+	}
+	return ErrJobNotFound
+}
+
+// CancelRunning requests cancellation of the running job.
+// Returns false if no job is running.
+func (q *JobQueue) CancelRunning() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.running == nil {
+		return false
+	}
+	q.running.cancel(nil)
+	return true
+}
+
+// Close stops the queue for shutdown: new jobs are refused with
+// ErrShuttingDown, queued jobs are cancelled, and the running job is asked
+// to stop (with cause ErrShuttingDown; captures stop at the next cell
+// boundary, after the current shot). Close waits for it to return, or for
+// ctx to be done.
+func (q *JobQueue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	now := time.Now()
+	for _, j := range q.queued {
+		j.Status = JobCancelled
+		j.FinishedAt = &now
+		j.Error = ErrShuttingDown.Error()
+		q.archiveLocked(j)
+	}
+	q.queued = nil
+	running := q.running
+	if running != nil {
+		running.cancel(ErrShuttingDown)
+	}
+	q.mu.Unlock()
+
+	if running == nil {
+		return nil
+	}
+	select {
+	case <-running.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Move moves a queued job to position (0 = next to run) among queued jobs.
+// Positions past the end move the job last.
+func (q *JobQueue) Move(id string, position int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	idx := -1
+	for i, j := range q.queued {
+		if j.ID == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		if q.findLocked(id) != nil {
+			return ErrJobFinished
+		}
+		return ErrJobNotFound
+	}
+	job := q.queued[idx]
+	rest := append(q.queued[:idx:idx], q.queued[idx+1:]...)
+	position = max(0, min(position, len(rest)))
+	q.queued = append(rest[:position:position], append([]*Job{job}, rest[position:]...)...)
+	return nil
+}
+
+func (q *JobQueue) newJob(ctx context.Context, o Overrides) *Job {
+	q.nextID++
+	return &Job{
+		ID:        strconv.Itoa(q.nextID),
+		Status:    JobQueued,
+		Overrides: o,
+		CreatedAt: time.Now(),
+		RequestID: RequestID(ctx),
+		gate:      syncGate(ctx),
+	}
+}
+
+func (q *JobQueue) findLocked(id string) *Job {
+	if q.running != nil && q.running.ID == id {
+		return q.running
+	}
+	for _, j := range q.queued {
+		if j.ID == id {
+			return j
+		}
+	}
+	for _, j := range q.finished {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+func (q *JobQueue) archiveLocked(j *Job) {
+	q.finished = append(q.finished, j)
+	if len(q.finished) > maxJobHistory {
+		q.finished = q.finished[len(q.finished)-maxJobHistory:]
+	}
+}
+
+// dispatchLocked starts the next queued job if the queue is idle, or
+// schedules a retry once the spacing window has elapsed.
+func (q *JobQueue) dispatchLocked() {
+	if q.closed || q.running != nil || len(q.queued) == 0 {
+		return
+	}
+	if d := q.MinSpacing - time.Since(q.lastStartAt); d > 0 {
+		if q.timer == nil {
+			q.timer = time.AfterFunc(d, func() {
+				q.mu.Lock()
+				defer q.mu.Unlock()
+				q.timer = nil
+				q.dispatchLocked()
+			})
+		}
+		return
+	}
+	job := q.queued[0]
+	q.queued = q.queued[1:]
+	q.startLocked(job)
+}
+
+// startLocked runs job in a goroutine with a context detached from any HTTP
+// request, so the capture survives browser disconnects but can be cancelled.
+func (q *JobQueue) startLocked(job *Job) {
+	ctx := WithRequestID(context.Background(), job.RequestID)
+	if job.gate != nil {
+		ctx = WithSyncGate(ctx, job.gate)
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	now := time.Now()
+	job.Status = JobRunning
+	job.StartedAt = &now
+	job.cancel = cancel
+	job.done = make(chan struct{})
+	q.running = job
+	q.lastStartAt = now
+	log.Printf("capture job %s started (request %s)", job.ID, job.RequestID)
+
+	go func() {
+		run := func() error { return q.run(ctx, job.Overrides) }
+		var err error
+		if q.protect != nil {
+			err = q.protect("capture job "+job.ID, run)
+		} else {
+			err = run()
+		}
+		shutdown := errors.Is(context.Cause(ctx), ErrShuttingDown)
+		cancel(nil)
+
+		status := JobDone
+		if err != nil {
+			switch {
+			case errors.Is(err, context.Canceled) && shutdown:
+				status = JobCancelled
+				q.broadcaster.BroadcastRequest(job.RequestID, "warning", "Capture stopped: server shutting down")
+				log.Println("capture stopped for shutdown")
+			case errors.Is(err, context.Canceled):
+				status = JobCancelled
+				q.broadcaster.BroadcastRequest(job.RequestID, "warning", "Capture cancelled by user")
+				log.Println("capture cancelled by user")
+			default:
+				status = JobFailed
+				q.broadcaster.BroadcastRequest(job.RequestID, "error", "Capture failed: "+err.Error())
+				log.Printf("capture failed: %v", err)
+			}
+		} else {
+			q.broadcaster.BroadcastRequest(job.RequestID, "info", "Sequence complete")
+		}
+
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		finished := time.Now()
+		job.Status = status
+		job.FinishedAt = &finished
+		if err != nil {
+			job.Error = err.Error()
+		}
+		q.running = nil
+		close(job.done)
+		q.archiveLocked(job)
+		q.dispatchLocked()
+	}()
+}
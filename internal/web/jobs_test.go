@@ -0,0 +1,508 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingRunner runs captures that block until released or cancelled,
+// recording the order in which jobs started.
+type blockingRunner struct {
+	mu      sync.Mutex
+	started []float64 // HorizontalAngleDeg of each started job
+	startCh chan struct{}
+	release chan struct{}
+}
+
+func newBlockingRunner() *blockingRunner {
+	return &blockingRunner{
+		startCh: make(chan struct{}, 16),
+		release: make(chan struct{}),
+	}
+}
+
+func (r *blockingRunner) run(ctx context.Context, o Overrides) error {
+	r.mu.Lock()
+	r.started = append(r.started, o.HorizontalAngleDeg)
+	r.mu.Unlock()
+	r.startCh <- struct{}{}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.release:
+		return nil
+	}
+}
+
+func (r *blockingRunner) order() []float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]float64(nil), r.started...)
+}
+
+func (r *blockingRunner) waitStart(t *testing.T) {
+	t.Helper()
+	select {
+	case <-r.startCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for job to start")
+	}
+}
+
+func newTestQueue(run RunCaptureFunc) *JobQueue {
+	q := NewJobQueue(run, NewStatusBroadcaster())
+	q.MinSpacing = 0
+	return q
+}
+
+func waitJobStatus(t *testing.T, q *JobQueue, id string, want JobStatus) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if j, ok := q.Get(id); ok && j.Status == want {
+			return j
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	j, _ := q.Get(id)
+	t.Fatalf("job %s status = %q, want %q", id, j.Status, want)
+	return j
+}
+
+// ---------- JobQueue ----------
+
+func TestJobQueue_RunsInOrder(t *testing.T) {
+	r := newBlockingRunner()
+	q := newTestQueue(r.run)
+
+	j1, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 1, VerticalAngleDeg: 1, FocalLengthMm: 1})
+	j2, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 2, VerticalAngleDeg: 1, FocalLengthMm: 1})
+	j3, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 3, VerticalAngleDeg: 1, FocalLengthMm: 1})
+
+	r.waitStart(t)
+	if j, _ := q.Get(j2.ID); j.Status != JobQueued {
+		t.Errorf("job 2 status = %q, want queued while job 1 runs", j.Status)
+	}
+	for range 2 {
+		r.release <- struct{}{}
+		r.waitStart(t)
+	}
+	r.release <- struct{}{}
+
+	for _, id := range []string{j1.ID, j2.ID, j3.ID} {
+		j := waitJobStatus(t, q, id, JobDone)
+		if j.StartedAt == nil || j.FinishedAt == nil {
+			t.Errorf("job %s missing timestamps", id)
+		}
+	}
+	got := r.order()
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("run order = %v, want [1 2 3]", got)
+	}
+}
+
+func TestJobQueue_FailedJob(t *testing.T) {
+	q := newTestQueue(func(_ context.Context, _ Overrides) error {
+		return errors.New("camera unplugged")
+	})
+	job, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	j := waitJobStatus(t, q, job.ID, JobFailed)
+	if j.Error != "camera unplugged" {
+		t.Errorf("error = %q, want \"camera unplugged\"", j.Error)
+	}
+}
+
+func TestJobQueue_CancelQueued(t *testing.T) {
+	r := newBlockingRunner()
+	q := newTestQueue(r.run)
+
+	q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 1, VerticalAngleDeg: 1, FocalLengthMm: 1})
+	j2, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 2, VerticalAngleDeg: 1, FocalLengthMm: 1})
+	r.waitStart(t)
+
+	if err := q.Cancel(j2.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if j, _ := q.Get(j2.ID); j.Status != JobCancelled {
+		t.Errorf("status = %q, want cancelled", j.Status)
+	}
+	if err := q.Cancel(j2.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("second cancel: err = %v, want ErrJobFinished", err)
+	}
+
+	r.release <- struct{}{}
+	time.Sleep(50 * time.Millisecond)
+	if got := r.order(); len(got) != 1 {
+		t.Errorf("cancelled job should not run, run order = %v", got)
+	}
+}
+
+func TestJobQueue_CancelRunning(t *testing.T) {
+	r := newBlockingRunner()
+	q := newTestQueue(r.run)
+
+	j1, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	r.waitStart(t)
+	if err := q.Cancel(j1.ID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	waitJobStatus(t, q, j1.ID, JobCancelled)
+	if _, running := q.Running(); running {
+		t.Error("no job should be running after cancel")
+	}
+}
+
+func TestJobQueue_Close(t *testing.T) {
+	r := newBlockingRunner()
+	q := newTestQueue(r.run)
+	ch, unsub := q.broadcaster.Subscribe()
+	defer unsub()
+
+	j1, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	r.waitStart(t)
+	j2, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 90, VerticalAngleDeg: 30, FocalLengthMm: 35})
+
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if j, _ := q.Get(j1.ID); j.Status != JobCancelled {
+		t.Errorf("running job status = %q, want cancelled once Close returns", j.Status)
+	}
+	if j, _ := q.Get(j2.ID); j.Status != JobCancelled || j.Error != ErrShuttingDown.Error() {
+		t.Errorf("queued job = %+v, want cancelled for shutdown", j)
+	}
+	if msg := <-ch; !strings.Contains(msg, "shutting down") {
+		t.Errorf("broadcast = %s, want a shutdown message", msg)
+	}
+
+	if _, err := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35}); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Enqueue after Close: err = %v, want ErrShuttingDown", err)
+	}
+	if _, err := q.StartNow(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35}); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("StartNow after Close: err = %v, want ErrShuttingDown", err)
+	}
+	if got := r.order(); len(got) != 1 {
+		t.Errorf("started jobs = %v, want only the first", got)
+	}
+}
+
+func TestJobQueue_CloseTimeout(t *testing.T) {
+	q := newTestQueue(func(ctx context.Context, o Overrides) error {
+		time.Sleep(200 * time.Millisecond) // ignores cancellation, like a long exposure
+		return nil
+	})
+	q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close: err = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestJobQueue_CancelUnknown(t *testing.T) {
+	q := newTestQueue(noopCapture)
+	if err := q.Cancel("42"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("err = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestJobQueue_Move(t *testing.T) {
+	r := newBlockingRunner()
+	q := newTestQueue(r.run)
+
+	q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 1, VerticalAngleDeg: 1, FocalLengthMm: 1})
+	r.waitStart(t)
+	q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 2, VerticalAngleDeg: 1, FocalLengthMm: 1})
+	q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 3, VerticalAngleDeg: 1, FocalLengthMm: 1})
+	j4, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 4, VerticalAngleDeg: 1, FocalLengthMm: 1})
+
+	if err := q.Move(j4.ID, 0); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	for range 3 {
+		r.release <- struct{}{}
+		r.waitStart(t)
+	}
+	r.release <- struct{}{}
+
+	got := r.order()
+	want := []float64{1, 4, 2, 3}
+	if len(got) != len(want) {
+		t.Fatalf("run order = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("run order = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestJobQueue_MoveClampsPosition(t *testing.T) {
+	r := newBlockingRunner()
+	q := newTestQueue(r.run)
+	defer close(r.release)
+
+	q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 1, VerticalAngleDeg: 1, FocalLengthMm: 1})
+	r.waitStart(t)
+	j2, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 2, VerticalAngleDeg: 1, FocalLengthMm: 1})
+	j3, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 3, VerticalAngleDeg: 1, FocalLengthMm: 1})
+
+	if err := q.Move(j2.ID, 99); err != nil {
+		t.Fatalf("Move: %v", err)
+	}
+	jobs := q.List()
+	if last := jobs[len(jobs)-1]; last.ID != j2.ID {
+		t.Errorf("last job = %s, want %s", last.ID, j2.ID)
+	}
+	if jobs[1].ID != j3.ID {
+		t.Errorf("next job = %s, want %s", jobs[1].ID, j3.ID)
+	}
+}
+
+func TestJobQueue_MoveRunningRejected(t *testing.T) {
+	r := newBlockingRunner()
+	q := newTestQueue(r.run)
+	defer close(r.release)
+
+	j1, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	r.waitStart(t)
+	if err := q.Move(j1.ID, 0); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("err = %v, want ErrJobFinished", err)
+	}
+}
+
+func TestJobQueue_StartNowBusy(t *testing.T) {
+	r := newBlockingRunner()
+	q := newTestQueue(r.run)
+	defer close(r.release)
+
+	if _, err := q.StartNow(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35}); err != nil {
+		t.Fatalf("StartNow: %v", err)
+	}
+	r.waitStart(t)
+	if _, err := q.StartNow(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35}); !errors.Is(err, ErrQueueBusy) {
+		t.Errorf("err = %v, want ErrQueueBusy", err)
+	}
+}
+
+func TestJobQueue_StartNowTooSoon(t *testing.T) {
+	q := NewJobQueue(noopCapture, NewStatusBroadcaster())
+	job, err := q.StartNow(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	if err != nil {
+		t.Fatalf("StartNow: %v", err)
+	}
+	waitJobStatus(t, q, job.ID, JobDone)
+
+	_, err = q.StartNow(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	var tooSoon *TooSoonError
+	if !errors.As(err, &tooSoon) {
+		t.Fatalf("err = %v, want *TooSoonError", err)
+	}
+	if tooSoon.RetryAfter <= 0 || tooSoon.RetryAfter > minDelayBetweenCaptures {
+		t.Errorf("RetryAfter = %v, want in (0, %v]", tooSoon.RetryAfter, minDelayBetweenCaptures)
+	}
+}
+
+func TestJobQueue_SpacingDelaysNextJob(t *testing.T) {
+	q := newTestQueue(noopCapture)
+	q.MinSpacing = 100 * time.Millisecond
+
+	j1, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	waitJobStatus(t, q, j1.ID, JobDone)
+	j2, _ := q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	if j, _ := q.Get(j2.ID); j.Status != JobQueued {
+		t.Errorf("status = %q, want queued inside the spacing window", j.Status)
+	}
+	j := waitJobStatus(t, q, j2.ID, JobDone)
+	first, _ := q.Get(j1.ID)
+	if gap := j.StartedAt.Sub(*first.StartedAt); gap < q.MinSpacing {
+		t.Errorf("gap between starts = %v, want >= %v", gap, q.MinSpacing)
+	}
+}
+
+func TestJobQueue_HistoryIsBounded(t *testing.T) {
+	q := newTestQueue(noopCapture)
+	var last Job
+	for range maxJobHistory + 5 {
+		last, _ = q.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+		waitJobStatus(t, q, last.ID, JobDone)
+	}
+	if n := len(q.List()); n != maxJobHistory {
+		t.Errorf("len(List) = %d, want %d", n, maxJobHistory)
+	}
+	if _, ok := q.Get("1"); ok {
+		t.Error("oldest job should have been dropped from history")
+	}
+}
+
+// ---------- Job handlers ----------
+
+func TestHandleEnqueueJob(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Jobs.MinSpacing = 0
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewReader(validOverridesJSON()))
+	w := httptest.NewRecorder()
+	h.HandleEnqueueJob(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	var job Job
+	if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if job.ID == "" {
+		t.Error("job ID should be set")
+	}
+	if job.Overrides.HorizontalAngleDeg != 180 {
+		t.Errorf("horizontal_angle_deg = %g, want 180", job.Overrides.HorizontalAngleDeg)
+	}
+}
+
+func TestHandleEnqueueJob_InvalidOverrides(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	body := []byte(`{"horizontal_angle_deg":0,"vertical_angle_deg":90,"focal_length_mm":35}`)
+	req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	h.HandleEnqueueJob(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestHandleEnqueueJob_NilRunCapture(t *testing.T) {
+	h := newTestHandlers(nil)
+	req := httptest.NewRequest(http.MethodPost, "/jobs", bytes.NewReader(validOverridesJSON()))
+	w := httptest.NewRecorder()
+	h.HandleEnqueueJob(w, req)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestHandleListJobs(t *testing.T) {
+	r := newBlockingRunner()
+	h := newTestHandlers(r.run)
+	h.Jobs.MinSpacing = 0
+	defer close(r.release)
+
+	h.Jobs.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	r.waitStart(t)
+	h.Jobs.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 90, VerticalAngleDeg: 45, FocalLengthMm: 50})
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+	w := httptest.NewRecorder()
+	h.HandleListJobs(w, req)
+
+	var jobs []Job
+	if err := json.NewDecoder(w.Body).Decode(&jobs); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("len(jobs) = %d, want 2", len(jobs))
+	}
+	if jobs[0].Status != JobRunning || jobs[1].Status != JobQueued {
+		t.Errorf("statuses = %q, %q, want running, queued", jobs[0].Status, jobs[1].Status)
+	}
+}
+
+func TestHandleGetJob_NotFound(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	req := httptest.NewRequest(http.MethodGet, "/jobs/99", nil)
+	req.SetPathValue("id", "99")
+	w := httptest.NewRecorder()
+	h.HandleGetJob(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleCancelJob(t *testing.T) {
+	r := newBlockingRunner()
+	h := newTestHandlers(r.run)
+	h.Jobs.MinSpacing = 0
+	defer close(r.release)
+
+	h.Jobs.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	r.waitStart(t)
+	queued, _ := h.Jobs.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 90, VerticalAngleDeg: 45, FocalLengthMm: 50})
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs/"+queued.ID+"/cancel", nil)
+	req.SetPathValue("id", queued.ID)
+	w := httptest.NewRecorder()
+	h.HandleCancelJob(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var job Job
+	json.NewDecoder(w.Body).Decode(&job)
+	if job.Status != JobCancelled {
+		t.Errorf("status = %q, want cancelled", job.Status)
+	}
+
+	// Cancelling again conflicts
+	w = httptest.NewRecorder()
+	h.HandleCancelJob(w, req)
+	if w.Code != http.StatusConflict {
+		t.Errorf("second cancel: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func TestHandleMoveJob(t *testing.T) {
+	r := newBlockingRunner()
+	h := newTestHandlers(r.run)
+	h.Jobs.MinSpacing = 0
+	defer close(r.release)
+
+	h.Jobs.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35})
+	r.waitStart(t)
+	h.Jobs.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 90, VerticalAngleDeg: 45, FocalLengthMm: 50})
+	last, _ := h.Jobs.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 45, VerticalAngleDeg: 30, FocalLengthMm: 24})
+
+	req := httptest.NewRequest(http.MethodPost, "/jobs/"+last.ID+"/move", strings.NewReader(`{"position":0}`))
+	req.SetPathValue("id", last.ID)
+	w := httptest.NewRecorder()
+	h.HandleMoveJob(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+	var jobs []Job
+	json.NewDecoder(w.Body).Decode(&jobs)
+	if len(jobs) != 3 || jobs[1].ID != last.ID {
+		t.Errorf("moved job should be next after the running one, got %+v", jobs)
+	}
+}
+
+func TestHandleMoveJob_BadRequest(t *testing.T) {
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"invalid_json", `{`},
+		{"missing_position", `{}`},
+		{"negative_position", `{"position":-1}`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(noopCapture)
+			req := httptest.NewRequest(http.MethodPost, "/jobs/1/move", strings.NewReader(tc.body))
+			req.SetPathValue("id", "1")
+			w := httptest.NewRecorder()
+			h.HandleMoveJob(w, req)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
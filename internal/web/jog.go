@@ -0,0 +1,138 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+)
+
+// Jog limits: one full turn in degrees, a generous bound in motor steps and
+// the slowest step period accepted (same as defaults.move_speed_ms).
+const (
+	maxJogDegrees = 360
+	maxJogSteps   = 100000
+	maxJogSpeedMs = 1000
+)
+
+// ErrHeadBusy is returned by a JogFunc (and by /jog) while a capture or
+// another jog is using the head.
+var ErrHeadBusy = errors.New("head is busy")
+
+// JogRequest is a manual head movement, as sent to POST /jog or with the
+// "jog" WebSocket command. Exactly one of Degrees and Steps is set.
+type JogRequest struct {
+	Axis    string  `json:"axis"`               // "pan" or "tilt"
+	Degrees float64 `json:"degrees,omitempty"`  // signed angle
+	Steps   int     `json:"steps,omitempty"`    // signed motor steps
+	SpeedMs int     `json:"speed_ms,omitempty"` // delay between steps; 0 = configured speed
+}
+
+// JogFunc moves one axis from the current position. It must refuse to move
+// while a capture is running (ErrHeadBusy) and stop when ctx is cancelled.
+type JogFunc func(ctx context.Context, req JogRequest) error
+
+// ValidateJog checks a jog request: a known axis, a finite and bounded
+// angle or step count (not both), and a speed within range.
+func ValidateJog(req JogRequest) error {
+	if req.Axis != "pan" && req.Axis != "tilt" {
+		return fmt.Errorf("axis must be \"pan\" or \"tilt\", got %q", req.Axis)
+	}
+	if math.IsNaN(req.Degrees) || math.IsInf(req.Degrees, 0) || math.Abs(req.Degrees) > maxJogDegrees {
+		return fmt.Errorf("degrees must be between -%d and %d", maxJogDegrees, maxJogDegrees)
+	}
+	if req.Steps < -maxJogSteps || req.Steps > maxJogSteps {
+		return fmt.Errorf("steps must be between -%d and %d", maxJogSteps, maxJogSteps)
+	}
+	switch {
+	case req.Degrees == 0 && req.Steps == 0:
+		return errors.New("degrees or steps is required")
+	case req.Degrees != 0 && req.Steps != 0:
+		return errors.New("degrees and steps are mutually exclusive")
+	}
+	if req.SpeedMs < 0 || req.SpeedMs > maxJogSpeedMs {
+		return fmt.Errorf("speed_ms must be between 0 and %d", maxJogSpeedMs)
+	}
+	return nil
+}
+
+// runJog runs req through h.Jog, one jog at a time, so that stopJog can
+// interrupt it.
+func (h *Handlers) runJog(ctx context.Context, req JogRequest) error {
+	if h.Jog == nil {
+		return errors.New("jog not available")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	h.jogMu.Lock()
+	if h.jogStop != nil {
+		h.jogMu.Unlock()
+		return ErrHeadBusy
+	}
+	h.jogStop = cancel
+	h.jogMu.Unlock()
+
+	defer func() {
+		h.jogMu.Lock()
+		h.jogStop = nil
+		h.jogMu.Unlock()
+	}()
+	return h.Jog(ctx, req)
+}
+
+// stopJog interrupts the jog in progress, if any.
+func (h *Handlers) stopJog() bool {
+	h.jogMu.Lock()
+	defer h.jogMu.Unlock()
+	if h.jogStop == nil {
+		return false
+	}
+	h.jogStop()
+	return true
+}
+
+// HandleJog handles POST /jog: it moves one axis and responds once the move
+// is over, with status "done", or "stopped" when interrupted by POST /stop.
+func (h *Handlers) HandleJog(w http.ResponseWriter, r *http.Request) {
+	keepWriting(w) // responds once the move is over
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	var req JogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := ValidateJog(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if h.Jog == nil {
+		http.Error(w, "jog not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	err := h.runJog(r.Context(), req)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "done"})
+	case errors.Is(err, context.Canceled) && r.Context().Err() == nil:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
+	case errors.Is(err, ErrHeadBusy):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// HandleStop handles POST /stop: it interrupts the jog in progress. The head
+// stops after the current step. Use POST /cancel to stop a capture.
+func (h *Handlers) HandleStop(w http.ResponseWriter, r *http.Request) {
+	if !h.stopJog() {
+		http.Error(w, "no jog in progress", http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
+}
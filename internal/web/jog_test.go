@@ -0,0 +1,146 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ---------- ValidateJog ----------
+
+func TestValidateJog(t *testing.T) {
+	cases := []struct {
+		name    string
+		req     JogRequest
+		wantErr bool
+	}{
+		{"degrees", JogRequest{Axis: "pan", Degrees: -5}, false},
+		{"steps_with_speed", JogRequest{Axis: "tilt", Steps: 400, SpeedMs: 4}, false},
+		{"unknown_axis", JogRequest{Axis: "roll", Degrees: 1}, true},
+		{"no_amount", JogRequest{Axis: "pan"}, true},
+		{"both_amounts", JogRequest{Axis: "pan", Degrees: 1, Steps: 10}, true},
+		{"too_many_degrees", JogRequest{Axis: "pan", Degrees: 361}, true},
+		{"too_many_steps", JogRequest{Axis: "pan", Steps: -maxJogSteps - 1}, true},
+		{"negative_speed", JogRequest{Axis: "pan", Steps: 1, SpeedMs: -1}, true},
+		{"too_slow", JogRequest{Axis: "pan", Steps: 1, SpeedMs: maxJogSpeedMs + 1}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateJog(tc.req)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidateJog(%+v) = %v, wantErr %v", tc.req, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// ---------- HandleJog ----------
+
+func TestHandleJog(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	var got JogRequest
+	h.Jog = func(_ context.Context, req JogRequest) error {
+		got = req
+		return nil
+	}
+
+	body := `{"axis":"tilt","steps":-200,"speed_ms":3}`
+	w := httptest.NewRecorder()
+	h.HandleJog(w, httptest.NewRequest(http.MethodPost, "/jog", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	want := JogRequest{Axis: "tilt", Steps: -200, SpeedMs: 3}
+	if got != want {
+		t.Errorf("Jog called with %+v, want %+v", got, want)
+	}
+	var resp map[string]string
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp["status"] != "done" {
+		t.Errorf("response = %v, want status done", resp)
+	}
+}
+
+func TestHandleJog_Errors(t *testing.T) {
+	busy := func(context.Context, JogRequest) error { return ErrHeadBusy }
+	broken := func(context.Context, JogRequest) error { return errors.New("gpio write failed") }
+	cases := []struct {
+		name string
+		jog  JogFunc
+		body string
+		want int
+	}{
+		{"invalid_json", busy, "{", http.StatusBadRequest},
+		{"invalid_request", busy, `{"axis":"pan"}`, http.StatusBadRequest},
+		{"not_available", nil, `{"axis":"pan","degrees":5}`, http.StatusServiceUnavailable},
+		{"busy", busy, `{"axis":"pan","degrees":5}`, http.StatusConflict},
+		{"hardware_error", broken, `{"axis":"pan","degrees":5}`, http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(noopCapture)
+			h.Jog = tc.jog
+			w := httptest.NewRecorder()
+			h.HandleJog(w, httptest.NewRequest(http.MethodPost, "/jog", strings.NewReader(tc.body)))
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tc.want, w.Body.String())
+			}
+		})
+	}
+}
+
+// ---------- HandleStop ----------
+
+func TestHandleStop_InterruptsJog(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	moving := make(chan struct{})
+	h.Jog = func(ctx context.Context, _ JogRequest) error {
+		close(moving)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	jogDone := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		w := httptest.NewRecorder()
+		h.HandleJog(w, httptest.NewRequest(http.MethodPost, "/jog", strings.NewReader(`{"axis":"pan","degrees":90}`)))
+		jogDone <- w
+	}()
+	<-moving
+
+	// A second jog is refused while the first one runs
+	w := httptest.NewRecorder()
+	h.HandleJog(w, httptest.NewRequest(http.MethodPost, "/jog", strings.NewReader(`{"axis":"tilt","degrees":1}`)))
+	if w.Code != http.StatusConflict {
+		t.Errorf("concurrent jog status = %d, want 409", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleStop(w, httptest.NewRequest(http.MethodPost, "/stop", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("stop status = %d, want 200", w.Code)
+	}
+
+	select {
+	case jw := <-jogDone:
+		var resp map[string]string
+		json.NewDecoder(jw.Body).Decode(&resp)
+		if jw.Code != http.StatusOK || resp["status"] != "stopped" {
+			t.Errorf("jog response = %d %v, want 200 stopped", jw.Code, resp)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("jog not interrupted by /stop")
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleStop(w, httptest.NewRequest(http.MethodPost, "/stop", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("stop without jog status = %d, want 409", w.Code)
+	}
+}
@@ -0,0 +1,202 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Continuous jog timings. Clients resend their input at least every
+// jogHoldTimeout while a key or stick is held; the head stops otherwise, so
+// a lost connection or a frozen page never leaves it running.
+const (
+	jogHoldTimeout = 500 * time.Millisecond
+	jogSliceSteps  = 32 // steps per move before checking for new input or switching axis
+)
+
+// JogVelocity is a message of the continuous jog channel (GET /jog/ws): the
+// direction each axis moves in until the next message. Both axes at 0 stop
+// the head.
+type JogVelocity struct {
+	Pan     int `json:"pan"`                // -1, 0 or 1
+	Tilt    int `json:"tilt"`               // -1 (down), 0 or 1 (up)
+	SpeedMs int `json:"speed_ms,omitempty"` // delay between steps; 0 = configured speed
+}
+
+// moving reports whether v moves any axis.
+func (v JogVelocity) moving() bool {
+	return v.Pan != 0 || v.Tilt != 0
+}
+
+// ValidateJogVelocity checks the directions and the speed of v.
+func ValidateJogVelocity(v JogVelocity) error {
+	if v.Pan < -1 || v.Pan > 1 || v.Tilt < -1 || v.Tilt > 1 {
+		return errors.New("pan and tilt must be -1, 0 or 1")
+	}
+	if v.SpeedMs < 0 || v.SpeedMs > maxJogSpeedMs {
+		return fmt.Errorf("speed_ms must be between 0 and %d", maxJogSpeedMs)
+	}
+	return nil
+}
+
+// jogInput is a message read from the continuous jog channel: a velocity,
+// or why the message was rejected.
+type jogInput struct {
+	v   JogVelocity
+	err error
+}
+
+// HandleJogStream handles GET /jog/ws: a WebSocket for press-and-hold
+// controls (arrow keys, gamepad). Each JogVelocity message replaces the
+// previous one; the head moves in short slices through Jog, alternating
+// axes, and stops on release, disconnect, or jogHoldTimeout without input.
+// The server sends "stopped" when it stops the head on its own (timeout,
+// POST /stop, capture running) and "error" for rejected messages.
+func (h *Handlers) HandleJogStream(w http.ResponseWriter, r *http.Request) {
+	if h.Jog == nil {
+		http.Error(w, "jog not available", http.StatusServiceUnavailable)
+		return
+	}
+	keepWriting(w)
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade already wrote the HTTP error
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	inputs := make(chan jogInput)
+	go jogReadLoop(ctx, cancel, conn, inputs)
+
+	interval := h.HeartbeatInterval
+	if interval == 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var v JogVelocity
+	var lastInput time.Time
+	tilt := false // axis of the next slice when both move
+
+	// apply takes a message into account; it reports false when the
+	// connection failed.
+	apply := func(in jogInput) bool {
+		if in.err != nil {
+			return wsWrite(conn, WSMessage{Type: "error", Error: in.err.Error()}) == nil
+		}
+		v, lastInput = in.v, time.Now()
+		return true
+	}
+	// halt stops the head on the server's initiative and tells the client.
+	halt := func(reason string) bool {
+		v = JogVelocity{}
+		return wsWrite(conn, WSMessage{Type: "stopped", Error: reason}) == nil
+	}
+
+	for {
+		if !v.moving() {
+			select {
+			case in := <-inputs:
+				if !apply(in) {
+					return
+				}
+			case <-ticker.C:
+				conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			continue
+		}
+
+		stopSlice, done := h.startJogSlice(ctx, "jog stream", v, &tilt)
+
+		ok := true
+		select {
+		case err := <-done:
+			switch {
+			case err == nil:
+			case errors.Is(err, context.Canceled) && ctx.Err() == nil:
+				ok = halt("stopped") // POST /stop
+			case ctx.Err() != nil:
+			default:
+				ok = halt(err.Error())
+			}
+		case in := <-inputs:
+			stopSlice()
+			<-done
+			ok = apply(in)
+		case <-time.After(jogHoldTimeout - time.Since(lastInput)):
+			stopSlice()
+			<-done
+			ok = halt("no input for " + jogHoldTimeout.String())
+		case <-ctx.Done():
+			stopSlice()
+			<-done
+		}
+		stopSlice()
+		if !ok || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// startJogSlice moves the head by jogSliceSteps in the direction of v, one
+// axis at a time: with both axes moving, *tilt alternates them from one
+// slice to the next. The slice runs in the background until stop is called
+// or it ends, then its error arrives on done.
+func (h *Handlers) startJogSlice(ctx context.Context, what string, v JogVelocity, tilt *bool) (stop context.CancelFunc, done <-chan error) {
+	if v.Pan == 0 || v.Tilt == 0 {
+		*tilt = v.Tilt != 0
+	}
+	req := JogRequest{Axis: "pan", Steps: v.Pan * jogSliceSteps, SpeedMs: v.SpeedMs}
+	if *tilt {
+		req = JogRequest{Axis: "tilt", Steps: v.Tilt * jogSliceSteps, SpeedMs: v.SpeedMs}
+	}
+	*tilt = !*tilt
+
+	ctx, stop = context.WithCancel(ctx)
+	ch := make(chan error, 1)
+	go func() {
+		ch <- h.protect(what, func() error { return h.runJog(ctx, req) })
+	}()
+	return stop, ch
+}
+
+// jogReadLoop reads velocities until the connection closes, then cancels ctx.
+func jogReadLoop(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, inputs chan<- jogInput) {
+	defer cancel()
+	conn.SetReadLimit(maxRequestBodyBytes)
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+		var in jogInput
+		if err := conn.ReadJSON(&in.v); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if !errors.As(err, &syntaxErr) && !errors.As(err, &typeErr) {
+				return
+			}
+			in = jogInput{err: errors.New("invalid JSON")}
+		} else if err := ValidateJogVelocity(in.v); err != nil {
+			in = jogInput{err: err}
+		}
+		select {
+		case inputs <- in:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
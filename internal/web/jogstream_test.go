@@ -0,0 +1,163 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialJogStream starts a test server for h.HandleJogStream and connects to it.
+func dialJogStream(t *testing.T, h *Handlers) *websocket.Conn {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(h.HandleJogStream))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// sliceJog is a Jog recording each request, then moving for 10 ms.
+func sliceJog(moves chan<- JogRequest) JogFunc {
+	return func(ctx context.Context, req JogRequest) error {
+		moves <- req
+		select {
+		case <-time.After(10 * time.Millisecond):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// expectIdle fails if a move starts within 100 ms.
+func expectIdle(t *testing.T, moves <-chan JogRequest) {
+	t.Helper()
+	// A move may have started just before the stop.
+	time.Sleep(20 * time.Millisecond)
+	for len(moves) > 0 {
+		<-moves
+	}
+	select {
+	case req := <-moves:
+		t.Errorf("head still moving: %+v", req)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// ---------- ValidateJogVelocity ----------
+
+func TestValidateJogVelocity(t *testing.T) {
+	cases := []struct {
+		name    string
+		v       JogVelocity
+		wantErr bool
+	}{
+		{"stop", JogVelocity{}, false},
+		{"diagonal", JogVelocity{Pan: -1, Tilt: 1, SpeedMs: 4}, false},
+		{"pan_too_large", JogVelocity{Pan: 2}, true},
+		{"tilt_too_small", JogVelocity{Tilt: -2}, true},
+		{"negative_speed", JogVelocity{Pan: 1, SpeedMs: -1}, true},
+		{"too_slow", JogVelocity{Pan: 1, SpeedMs: maxJogSpeedMs + 1}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateJogVelocity(tc.v)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("ValidateJogVelocity(%+v) = %v, wantErr %v", tc.v, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// ---------- HandleJogStream ----------
+
+func TestHandleJogStream_MovesUntilRelease(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	moves := make(chan JogRequest, 100)
+	h.Jog = sliceJog(moves)
+	conn := dialJogStream(t, h)
+
+	conn.WriteJSON(JogVelocity{Pan: 1, Tilt: -1, SpeedMs: 3})
+	seen := map[string]JogRequest{}
+	for len(seen) < 2 {
+		select {
+		case req := <-moves:
+			seen[req.Axis] = req
+		case <-time.After(2 * time.Second):
+			t.Fatalf("moves = %v, want both axes", seen)
+		}
+	}
+	if seen["pan"] != (JogRequest{Axis: "pan", Steps: jogSliceSteps, SpeedMs: 3}) || seen["tilt"].Steps != -jogSliceSteps {
+		t.Errorf("moves = %+v", seen)
+	}
+
+	conn.WriteJSON(JogVelocity{})
+	expectIdle(t, moves)
+}
+
+func TestHandleJogStream_StopsWithoutInput(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	moves := make(chan JogRequest, 1000)
+	h.Jog = sliceJog(moves)
+	conn := dialJogStream(t, h)
+
+	start := time.Now()
+	conn.WriteJSON(JogVelocity{Tilt: 1})
+	msg := readWS(t, conn)
+	if msg.Type != "stopped" || !strings.Contains(msg.Error, "no input") {
+		t.Fatalf("message = %+v, want stopped for lack of input", msg)
+	}
+	if elapsed := time.Since(start); elapsed < jogHoldTimeout {
+		t.Errorf("stopped after %v, want at least %v", elapsed, jogHoldTimeout)
+	}
+	expectIdle(t, moves)
+}
+
+func TestHandleJogStream_StopsOnDisconnect(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	moves := make(chan JogRequest, 100)
+	h.Jog = sliceJog(moves)
+	conn := dialJogStream(t, h)
+
+	conn.WriteJSON(JogVelocity{Pan: -1})
+	<-moves
+	conn.Close()
+	expectIdle(t, moves)
+}
+
+func TestHandleJogStream_Errors(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Jog = func(context.Context, JogRequest) error { return ErrHeadBusy }
+	conn := dialJogStream(t, h)
+
+	conn.WriteJSON(JogVelocity{Pan: 3})
+	if msg := readWS(t, conn); msg.Type != "error" || !strings.Contains(msg.Error, "pan and tilt") {
+		t.Errorf("invalid velocity: message = %+v", msg)
+	}
+	conn.WriteMessage(websocket.TextMessage, []byte("not json"))
+	if msg := readWS(t, conn); msg.Type != "error" || msg.Error != "invalid JSON" {
+		t.Errorf("invalid JSON: message = %+v", msg)
+	}
+	conn.WriteJSON(JogVelocity{Pan: 1})
+	if msg := readWS(t, conn); msg.Type != "stopped" || msg.Error != ErrHeadBusy.Error() {
+		t.Errorf("busy head: message = %+v", msg)
+	}
+}
+
+func TestHandleJogStream_NotAvailable(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	w := httptest.NewRecorder()
+	h.HandleJogStream(w, httptest.NewRequest(http.MethodGet, "/jog/ws", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
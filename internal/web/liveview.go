@@ -0,0 +1,92 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrLiveViewUnsupported is returned by a LiveViewFunc when the current
+// camera cannot stream its live view.
+var ErrLiveViewUnsupported = errors.New("live view not supported by this camera")
+
+// LiveViewFunc returns the current live view frame as a JPEG image. It
+// returns ErrHeadBusy while the camera is shooting; the stream then skips
+// frames until it is free again.
+type LiveViewFunc func(ctx context.Context) ([]byte, error)
+
+// Live view frame period (about 10 fps: enough for framing, light on a Pi).
+const liveViewInterval = 100 * time.Millisecond
+
+// mjpegBoundary separates frames in the GET /liveview response.
+const mjpegBoundary = "pango-liveview"
+
+// HandleLiveView handles GET /liveview: an MJPEG stream (usable as an <img>
+// source) of the camera live view, until the client disconnects.
+func (h *Handlers) HandleLiveView(w http.ResponseWriter, r *http.Request) {
+	if h.LiveView == nil {
+		http.Error(w, ErrLiveViewUnsupported.Error(), http.StatusNotImplemented)
+		return
+	}
+	keepWriting(w)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// The first frame decides the status code.
+	frame, err := h.LiveView(r.Context())
+	switch {
+	case errors.Is(err, ErrLiveViewUnsupported):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	case errors.Is(err, ErrHeadBusy):
+		http.Error(w, "camera is busy", http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mjpegBoundary)
+	w.Header().Set("Cache-Control", "no-store")
+	ticker := time.NewTicker(liveViewInterval)
+	defer ticker.Stop()
+
+	for {
+		if frame != nil {
+			if err := writeMJPEGFrame(w, frame); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		frame, err = h.LiveView(r.Context())
+		switch {
+		case errors.Is(err, ErrHeadBusy):
+			frame = nil // camera shooting: keep the last frame on screen
+		case err != nil:
+			return
+		}
+	}
+}
+
+// writeMJPEGFrame writes one JPEG part of a multipart/x-mixed-replace stream.
+func writeMJPEGFrame(w http.ResponseWriter, jpeg []byte) error {
+	if _, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", mjpegBoundary, len(jpeg)); err != nil {
+		return err
+	}
+	if _, err := w.Write(jpeg); err != nil {
+		return err
+	}
+	_, err := w.Write([]byte("\r\n"))
+	return err
+}
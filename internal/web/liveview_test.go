@@ -0,0 +1,81 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// ---------- HandleLiveView ----------
+
+func TestHandleLiveView_StreamsFrames(t *testing.T) {
+	var calls atomic.Int32
+	h := newTestHandlers(noopCapture)
+	h.LiveView = func(context.Context) ([]byte, error) {
+		switch calls.Add(1) {
+		case 2:
+			return nil, ErrHeadBusy // skipped, the stream goes on
+		case 1:
+			return []byte("jpeg-1"), nil
+		default:
+			return []byte("jpeg-n"), nil
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(h.HandleLiveView))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	defer resp.Body.Close()
+
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/x-mixed-replace" {
+		t.Fatalf("Content-Type = %q (%v)", resp.Header.Get("Content-Type"), err)
+	}
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for _, want := range []string{"jpeg-1", "jpeg-n"} {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("next part: %v", err)
+		}
+		if ct := part.Header.Get("Content-Type"); ct != "image/jpeg" {
+			t.Errorf("part Content-Type = %q", ct)
+		}
+		data, _ := io.ReadAll(part)
+		if string(data) != want {
+			t.Errorf("frame = %q, want %q", data, want)
+		}
+	}
+}
+
+func TestHandleLiveView_Errors(t *testing.T) {
+	cases := []struct {
+		name string
+		fn   LiveViewFunc
+		want int
+	}{
+		{"not_configured", nil, http.StatusNotImplemented},
+		{"unsupported", func(context.Context) ([]byte, error) { return nil, ErrLiveViewUnsupported }, http.StatusNotImplemented},
+		{"busy", func(context.Context) ([]byte, error) { return nil, ErrHeadBusy }, http.StatusConflict},
+		{"camera_error", func(context.Context) ([]byte, error) { return nil, errors.New("usb: no device") }, http.StatusBadGateway},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(noopCapture)
+			h.LiveView = tc.fn
+			w := httptest.NewRecorder()
+			h.HandleLiveView(w, httptest.NewRequest(http.MethodGet, "/liveview", nil))
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d", w.Code, tc.want)
+			}
+		})
+	}
+}
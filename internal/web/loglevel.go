@@ -0,0 +1,47 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cjeanneret/PanGo/internal/debug"
+)
+
+// dbg is the debug output of the package.
+var dbg = debug.For("web")
+
+// LogLevels are the debug output levels, for GET and PUT /log-level.
+type LogLevels struct {
+	DebugLevel int               `json:"debug_level"` // global level 0-4 (defaults.debug_level)
+	Modules    map[string]string `json:"modules"`     // level of each module: trace, verbose, live, info, warn, error or off
+}
+
+// currentLogLevels returns the levels in use.
+func currentLogLevels() LogLevels {
+	return LogLevels{DebugLevel: debug.Level(), Modules: debug.ModuleLevels()}
+}
+
+// HandleLogLevel handles GET /log-level: the debug output level of each
+// module.
+func (h *Handlers) HandleLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, currentLogLevels())
+}
+
+// HandlePutLogLevel handles PUT /log-level: sets the level of the modules
+// of the body, module name to level name, e.g. {"gpio":"trace","web":"warn"},
+// until the server restarts; "default" returns a module to debug_level.
+// Nothing changes when a module or level is unknown (400).
+func (h *Handlers) HandlePutLogLevel(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	var levels map[string]string
+	if err := json.NewDecoder(r.Body).Decode(&levels); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := debug.SetModuleLevels(levels); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	dbg.Info("log levels changed", "levels", levels, "client", clientIP(r))
+	writeJSON(w, http.StatusOK, currentLogLevels())
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/debug"
+)
+
+// ---------- HandleLogLevel / HandlePutLogLevel ----------
+
+func TestHandleLogLevel(t *testing.T) {
+	t.Cleanup(debug.ResetModuleLevels)
+	h := newTestHandlers(noopCapture)
+
+	put := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.HandlePutLogLevel(w, httptest.NewRequest(http.MethodPut, "/log-level", strings.NewReader(body)))
+		return w
+	}
+	w := put(`{"gpio":"trace","web":"warn"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	var got LogLevels
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Modules["gpio"] != "trace" || got.Modules["web"] != "warn" || len(got.Modules) != len(debug.Modules) {
+		t.Errorf("modules = %v, want gpio trace and web warn among all modules", got.Modules)
+	}
+
+	cases := []struct {
+		name string
+		body string
+	}{
+		{"unknown_module", `{"motor":"trace"}`},
+		{"unknown_level", `{"gpio":"info","web":"loud"}`},
+		{"invalid_json", `gpio=trace`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if w := put(tc.body); w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+		})
+	}
+
+	w = httptest.NewRecorder()
+	h.HandleLogLevel(w, httptest.NewRequest(http.MethodGet, "/log-level", nil))
+	got = LogLevels{}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Modules["gpio"] != "trace" {
+		t.Errorf("gpio level = %q after rejected updates, want trace", got.Modules["gpio"])
+	}
+}
@@ -0,0 +1,24 @@
+package web
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/cjeanneret/PanGo/internal/metrics"
+)
+
+// HandleMetrics handles GET /metrics: counters and gauges in the Prometheus
+// text format, for monitoring long-running installations.
+func (h *Handlers) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.Metrics == nil {
+		http.Error(w, "metrics not available", http.StatusServiceUnavailable)
+		return
+	}
+	var buf bytes.Buffer
+	if err := h.Metrics.Write(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", metrics.ContentType)
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,37 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/metrics"
+)
+
+func TestHandleMetrics(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Metrics = metrics.NewRegistry()
+	h.Metrics.Counter("pango_shots_total", "Shots taken.").Add(3)
+
+	w := httptest.NewRecorder()
+	h.HandleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != metrics.ContentType {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), "pango_shots_total 3\n") {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
+
+func TestHandleMetrics_NotConfigured(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	w := httptest.NewRecorder()
+	h.HandleMetrics(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
@@ -0,0 +1,248 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/cjeanneret/PanGo/internal/mqtt"
+)
+
+// MQTT topics, under the configured prefix (e.g. "pango/state"):
+//   - status: "online" or "offline" (retained; the broker publishes
+//     "offline" if the rig disappears)
+//   - state: the capture lifecycle, as GET /status (retained)
+//   - progress: shots done and total, with the ETA, after each shot
+//   - position: the head position in steps, after each move (retained)
+//   - log: console messages of level info and above, {"level","msg"}
+//   - cmd/<command>: commands to the rig (see mqttBridge.execute)
+//   - reply: the outcome of each command, as MQTTReply
+const (
+	mqttStatusTopic   = "/status"
+	mqttStateTopic    = "/state"
+	mqttProgressTopic = "/progress"
+	mqttPositionTopic = "/position"
+	mqttLogTopic      = "/log"
+	mqttCmdTopic      = "/cmd/"
+	mqttReplyTopic    = "/reply"
+)
+
+// MQTTReply is published on <prefix>/reply for each command received.
+type MQTTReply struct {
+	Cmd   string `json:"cmd"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// MQTTRun is the payload of <prefix>/cmd/run: an optional profile to
+// activate first, and overrides of the form defaults (zero = default).
+// A payload that is not a JSON object is a profile name.
+type MQTTRun struct {
+	Profile string `json:"profile,omitempty"`
+	Overrides
+}
+
+// mqttPublisher sends messages to the broker (*mqtt.Client).
+type mqttPublisher interface {
+	Publish(m mqtt.Message) error
+}
+
+// mqttBridge links the rig to an MQTT broker.
+type mqttBridge struct {
+	h      *Handlers
+	prefix string
+	client mqttPublisher
+}
+
+// RunMQTT connects to the broker of o and, until ctx is cancelled, publishes
+// the rig status on topics under prefix and runs the commands received on
+// <prefix>/cmd/<command> with the same rules as the WebSocket commands
+// (including the control lock: commands are refused while a client holds
+// control). It reconnects when the broker goes away.
+func (h *Handlers) RunMQTT(ctx context.Context, o mqtt.Options, prefix string) error {
+	b := &mqttBridge{h: h, prefix: prefix}
+	o.Will = &mqtt.Message{Topic: prefix + mqttStatusTopic, Payload: []byte("offline"), Retain: true}
+	o.Topics = []string{prefix + mqttCmdTopic + "+"}
+	client := mqtt.NewClient(o, func(m mqtt.Message) { b.handle(ctx, m) })
+	client.OnConnect(b.connected)
+	b.client = client
+
+	events, unsub := h.Broadcaster.SubscribeFiltered(EventFilter{
+		Types:    []string{EventState, EventProgress, EventMove, EventLog},
+		MinLevel: "info",
+	})
+	go func() {
+		defer unsub()
+		for {
+			select {
+			case msg, ok := <-events:
+				if !ok {
+					return
+				}
+				b.publishEvent(msg)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return client.Run(ctx)
+}
+
+// connected announces the rig and its current state to new subscribers.
+func (b *mqttBridge) connected() {
+	b.publish(mqttStatusTopic, []byte("online"), true)
+	if b.h.Lifecycle != nil {
+		b.publishJSON(mqttStateTopic, b.h.Lifecycle.Snapshot(), true)
+	}
+}
+
+// publishEvent publishes a broadcast status event on its topic.
+func (b *mqttBridge) publishEvent(msg string) {
+	var evt StatusEvent
+	if err := json.Unmarshal([]byte(msg), &evt); err != nil {
+		return
+	}
+	switch evt.Type {
+	case EventState:
+		b.publishJSON(mqttStateTopic, evt.Data, true)
+	case EventProgress:
+		b.publishJSON(mqttProgressTopic, evt.Data, false)
+	case EventMove:
+		b.publishJSON(mqttPositionTopic, evt.Data, true)
+	case EventLog:
+		b.publishJSON(mqttLogTopic, map[string]string{"level": evt.Level, "msg": evt.Msg}, false)
+	}
+}
+
+func (b *mqttBridge) publishJSON(topic string, v any, retain bool) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	b.publish(topic, data, retain)
+}
+
+// publish sends a message; it is dropped while the broker is unreachable.
+func (b *mqttBridge) publish(topic string, payload []byte, retain bool) {
+	err := b.client.Publish(mqtt.Message{Topic: b.prefix + topic, Payload: payload, Retain: retain})
+	if err != nil && !errors.Is(err, mqtt.ErrNotConnected) {
+		log.Printf("mqtt: publish %s: %v", b.prefix+topic, err)
+	}
+}
+
+// handle runs a command in the background, so that a jog does not hold up
+// the connection (or the stop command that interrupts it), and publishes
+// its outcome.
+func (b *mqttBridge) handle(ctx context.Context, m mqtt.Message) {
+	cmd, ok := strings.CutPrefix(m.Topic, b.prefix+mqttCmdTopic)
+	if !ok || m.Retain {
+		return // a retained command would run again on every reconnection
+	}
+	go func() {
+		reply := MQTTReply{Cmd: cmd, OK: true}
+		if err := b.execute(ctx, cmd, m.Payload); err != nil {
+			reply.OK, reply.Error = false, err.Error()
+		}
+		b.publishJSON(mqttReplyTopic, reply, false)
+	}()
+}
+
+// execute runs one command:
+//   - run: MQTTRun (or a profile name); empty runs with the form defaults
+//   - cancel, pause, resume, stop: no payload
+//   - jog: a JogRequest, e.g. {"axis":"pan","degrees":5}
+func (b *mqttBridge) execute(ctx context.Context, cmd string, payload []byte) error {
+	payload = bytes.TrimSpace(payload)
+	switch cmd {
+	case "run":
+		run, err := parseMQTTRun(payload)
+		if err != nil {
+			return err
+		}
+		overrides, err := b.h.prepareRun(run.Profile, run.Overrides)
+		if err != nil {
+			return err
+		}
+		return b.h.wsExecute(ctx, WSCommand{Cmd: "run", Overrides: &overrides}, "")
+
+	case "jog":
+		var req JogRequest
+		if err := json.Unmarshal(payload, &req); err != nil {
+			return errors.New("jog: payload must be a JSON object")
+		}
+		return b.h.wsExecute(ctx, WSCommand{Cmd: "jog", Axis: req.Axis, Degrees: req.Degrees, Steps: req.Steps, SpeedMs: req.SpeedMs}, "")
+
+	case "cancel", "pause", "resume", "stop":
+		return b.h.wsExecute(ctx, WSCommand{Cmd: cmd}, "")
+
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+// parseMQTTRun reads the payload of a run command.
+func parseMQTTRun(payload []byte) (MQTTRun, error) {
+	var run MQTTRun
+	switch {
+	case len(payload) == 0:
+	case payload[0] == '{':
+		if err := json.Unmarshal(payload, &run); err != nil {
+			return MQTTRun{}, errors.New("run: invalid JSON")
+		}
+	default:
+		run.Profile = string(payload)
+	}
+	return run, nil
+}
+
+// prepareRun activates profile, if any, and returns o completed with the
+// form defaults, for the commands of local controls (MQTT, front panel).
+func (h *Handlers) prepareRun(profile string, o Overrides) (Overrides, error) {
+	if h.Control != nil {
+		if err := h.Control.check(""); err != nil {
+			return Overrides{}, err
+		}
+	}
+	if profile != "" {
+		if err := h.useProfile(profile); err != nil {
+			return Overrides{}, err
+		}
+	}
+
+	h.formMu.RLock()
+	form := h.FormDefaults
+	h.formMu.RUnlock()
+	if o.HorizontalAngleDeg == 0 {
+		o.HorizontalAngleDeg = form.HorizontalAngleDeg
+	}
+	if o.VerticalAngleDeg == 0 {
+		o.VerticalAngleDeg = form.VerticalAngleDeg
+	}
+	if o.FocalLengthMm == 0 {
+		o.FocalLengthMm = form.FocalLengthMm
+	}
+	return o, nil
+}
+
+// useProfile activates profile and makes its form the defaults of the
+// following runs.
+func (h *Handlers) useProfile(profile string) error {
+	if h.Control != nil {
+		if err := h.Control.check(""); err != nil {
+			return err
+		}
+	}
+	if h.ActivateProfile == nil {
+		return errors.New("profiles not available")
+	}
+	update, err := h.ActivateProfile(profile)
+	if err != nil {
+		return err
+	}
+	h.SetFormDefaults(update.Form)
+	return nil
+}
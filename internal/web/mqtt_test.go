@@ -0,0 +1,196 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/mqtt"
+)
+
+// fakePublisher records the messages published by a bridge.
+type fakePublisher struct {
+	mu   sync.Mutex
+	msgs []mqtt.Message
+}
+
+func (p *fakePublisher) Publish(m mqtt.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, m)
+	return nil
+}
+
+func (p *fakePublisher) all() []mqtt.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]mqtt.Message(nil), p.msgs...)
+}
+
+// waitTopic waits for a message on topic.
+func (p *fakePublisher) waitTopic(t *testing.T, topic string) mqtt.Message {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, m := range p.all() {
+			if m.Topic == topic {
+				return m
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("no message on %s; got %v", topic, p.all())
+	return mqtt.Message{}
+}
+
+func newTestBridge(h *Handlers) (*mqttBridge, *fakePublisher) {
+	p := &fakePublisher{}
+	return &mqttBridge{h: h, prefix: "pango", client: p}, p
+}
+
+// ---------- publishEvent ----------
+
+func TestMQTTBridge_PublishEvent(t *testing.T) {
+	cases := []struct {
+		name    string
+		evt     StatusEvent
+		topic   string
+		payload string
+		retain  bool
+	}{
+		{"state", StatusEvent{Type: EventState, Data: map[string]string{"state": "running"}}, "pango/state", `{"state":"running"}`, true},
+		{"progress", StatusEvent{Type: EventProgress, Data: map[string]int{"done": 3, "total": 12}}, "pango/progress", `{"done":3,"total":12}`, false},
+		{"move", StatusEvent{Type: EventMove, Data: map[string]int{"pan": 400}}, "pango/position", `{"pan":400}`, true},
+		{"log", StatusEvent{Type: EventLog, Level: "warning", Msg: "Low battery"}, "pango/log", `{"level":"warning","msg":"Low battery"}`, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, p := newTestBridge(newTestHandlers(noopCapture))
+			data, _ := json.Marshal(tc.evt)
+			b.publishEvent(string(data))
+
+			msgs := p.all()
+			if len(msgs) != 1 {
+				t.Fatalf("published %d messages, want 1", len(msgs))
+			}
+			m := msgs[0]
+			if m.Topic != tc.topic || string(m.Payload) != tc.payload || m.Retain != tc.retain {
+				t.Errorf("published %s %s (retain %v), want %s %s (retain %v)", m.Topic, m.Payload, m.Retain, tc.topic, tc.payload, tc.retain)
+			}
+		})
+	}
+}
+
+func TestMQTTBridge_Connected(t *testing.T) {
+	b, p := newTestBridge(newTestHandlers(noopCapture))
+	b.connected()
+	m := p.waitTopic(t, "pango/status")
+	if string(m.Payload) != "online" || !m.Retain {
+		t.Errorf("status = %s (retain %v), want retained online", m.Payload, m.Retain)
+	}
+}
+
+// ---------- Commands ----------
+
+func TestMQTTBridge_RunAndCancel(t *testing.T) {
+	got := make(chan Overrides, 1)
+	h := newTestHandlers(func(ctx context.Context, o Overrides) error {
+		got <- o
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	b, p := newTestBridge(h)
+
+	b.handle(context.Background(), mqtt.Message{Topic: "pango/cmd/run", Payload: []byte(`{"focal_length_mm":50}`)})
+	select {
+	case o := <-got:
+		if o != (Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 50}) {
+			t.Errorf("overrides = %+v, want form defaults with 50 mm", o)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("capture not started")
+	}
+	var reply MQTTReply
+	json.Unmarshal(p.waitTopic(t, "pango/reply").Payload, &reply)
+	if !reply.OK || reply.Cmd != "run" {
+		t.Errorf("run reply = %+v", reply)
+	}
+
+	if err := b.execute(context.Background(), "cancel", nil); err != nil {
+		t.Errorf("cancel: %v", err)
+	}
+}
+
+func TestMQTTBridge_RunProfile(t *testing.T) {
+	got := make(chan Overrides, 1)
+	h := newTestHandlers(func(_ context.Context, o Overrides) error {
+		got <- o
+		return nil
+	})
+	var activated string
+	h.ActivateProfile = func(name string) (ConfigUpdate, error) {
+		activated = name
+		return ConfigUpdate{Form: FormConfig{HorizontalAngleDeg: 360, VerticalAngleDeg: 90, FocalLengthMm: 18}}, nil
+	}
+	b, _ := newTestBridge(h)
+
+	if err := b.execute(context.Background(), "run", []byte(" wide-18mm\n")); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if activated != "wide-18mm" {
+		t.Errorf("activated %q, want wide-18mm", activated)
+	}
+	if o := <-got; o != (Overrides{HorizontalAngleDeg: 360, VerticalAngleDeg: 90, FocalLengthMm: 18}) {
+		t.Errorf("overrides = %+v, want the profile defaults", o)
+	}
+}
+
+func TestMQTTBridge_Errors(t *testing.T) {
+	cases := []struct {
+		name    string
+		cmd     string
+		payload string
+	}{
+		{"unknown", "shutdown", ""},
+		{"invalid_run", "run", "{not json"},
+		{"no_profiles", "run", "wide"},
+		{"invalid_jog", "jog", "left"},
+		{"no_capture", "cancel", ""},
+		{"no_jog", "stop", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, _ := newTestBridge(newTestHandlers(noopCapture))
+			if err := b.execute(context.Background(), tc.cmd, []byte(tc.payload)); err == nil {
+				t.Errorf("%s %q: want an error", tc.cmd, tc.payload)
+			}
+		})
+	}
+}
+
+func TestMQTTBridge_RespectsControl(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	if _, err := h.Control.Claim("", "studio", false); err != nil {
+		t.Fatal(err)
+	}
+	b, p := newTestBridge(h)
+
+	b.handle(context.Background(), mqtt.Message{Topic: "pango/cmd/run"})
+	var reply MQTTReply
+	json.Unmarshal(p.waitTopic(t, "pango/reply").Payload, &reply)
+	if reply.OK || reply.Error == "" {
+		t.Errorf("run reply = %+v, want refused while another client holds control", reply)
+	}
+}
+
+func TestMQTTBridge_IgnoresRetainedAndForeignTopics(t *testing.T) {
+	b, p := newTestBridge(newTestHandlers(noopCapture))
+	b.handle(context.Background(), mqtt.Message{Topic: "pango/cmd/stop", Retain: true})
+	b.handle(context.Background(), mqtt.Message{Topic: "other/cmd/stop"})
+	time.Sleep(50 * time.Millisecond)
+	if msgs := p.all(); len(msgs) != 0 {
+		t.Errorf("published %v, want nothing", msgs)
+	}
+}
@@ -0,0 +1,476 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/onvif"
+)
+
+// ONVIFPath prefixes the ONVIF services: ONVIFPath+"/device_service" is the
+// address to add the head as a camera in video management software.
+const ONVIFPath = "/onvif"
+
+// The ONVIF services, under ONVIFPath.
+const (
+	onvifDeviceService = "/device_service"
+	onvifMediaService  = "/media_service"
+	onvifPTZService    = "/ptz_service"
+)
+
+// Tokens of the single media profile, PTZ configuration and PTZ node.
+const (
+	onvifProfileToken = "pango"
+	onvifConfigToken  = "ptz"
+	onvifNodeToken    = "head"
+)
+
+// ONVIF PTZ limits: ContinuousMove stops after onvifDefaultTimeout without
+// a Timeout of its own, onvifMaxTimeout at most; velocities within
+// onvifDeadZone of 0 do not move their axis.
+const (
+	onvifDefaultTimeout = 10 * time.Second
+	onvifMaxTimeout     = 60 * time.Second
+	onvifDeadZone       = 0.1
+)
+
+// Coordinate spaces of the PTZ node. In the generic spaces, 1 is half a
+// turn in pan and a quarter of a turn in tilt.
+const (
+	onvifVelocitySpace    = "http://www.onvif.org/ver10/tptz/PanTiltSpaces/VelocityGenericSpace"
+	onvifTranslationSpace = "http://www.onvif.org/ver10/tptz/PanTiltSpaces/TranslationGenericSpace"
+	onvifPositionSpace    = "http://www.onvif.org/ver10/tptz/PanTiltSpaces/PositionGenericSpace"
+	onvifPanScaleDeg      = 180
+	onvifTiltScaleDeg     = 90
+)
+
+// onvifVector is a pan/tilt vector of a PTZ request, in a generic space.
+type onvifVector struct {
+	PanTilt *struct {
+		X float64 `xml:"x,attr"`
+		Y float64 `xml:"y,attr"`
+	} `xml:"PanTilt"`
+}
+
+// ptzMover runs the moves of the ONVIF PTZ service in the background, one
+// at a time: a new move or Stop replaces the one in progress.
+type ptzMover struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{} // closed when the move is over; nil when idle
+}
+
+// start stops the move in progress, then runs move until it returns or
+// timeout elapses.
+func (m *ptzMover) start(timeout time.Duration, move func(ctx context.Context)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopLocked()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	done := make(chan struct{})
+	m.cancel, m.done = cancel, done
+	go func() {
+		defer close(done)
+		defer cancel()
+		move(ctx)
+	}()
+}
+
+// stop interrupts the move in progress and waits for the head to stop.
+func (m *ptzMover) stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopLocked()
+}
+
+func (m *ptzMover) stopLocked() {
+	if m.done == nil {
+		return
+	}
+	m.cancel()
+	<-m.done
+	m.cancel, m.done = nil, nil
+}
+
+// moving reports whether a move is in progress.
+func (m *ptzMover) moving() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.done == nil {
+		return false
+	}
+	select {
+	case <-m.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// ONVIFHandler returns the ONVIF device, media and PTZ services, to be
+// served under ONVIFPath: enough of Profile S for video management software
+// and camera apps to drive the head with their PTZ controls, without video.
+// Continuous moves go through the slices of the continuous jog (GET
+// /jog/ws), relative ones through Jog. Clients authenticate with a
+// WS-Security UsernameToken or HTTP credentials: the user name and password
+// of auth, or any user name with its token as password.
+// GetSystemDateAndTime stays open, for clients to compute their digests.
+func (h *Handlers) ONVIFHandler(auth AuthConfig) http.Handler {
+	mux := http.NewServeMux()
+	for path, serve := range map[string]func(*http.Request, *onvif.Request) (string, error){
+		onvifDeviceService: h.onvifDevice,
+		onvifMediaService:  h.onvifMedia,
+		onvifPTZService:    h.onvifPTZ,
+	} {
+		mux.HandleFunc("POST "+ONVIFPath+path, func(w http.ResponseWriter, r *http.Request) {
+			req, err := onvif.ReadRequest(r.Body)
+			if err != nil {
+				onvif.WriteFault(w, onvif.InvalidArgs(err.Error()))
+				return
+			}
+			if req.Action.Local != "GetSystemDateAndTime" && !onvifAuthorized(r, req, auth) {
+				onvif.WriteFault(w, onvif.ErrNotAuthorized)
+				return
+			}
+			body, err := serve(r, req)
+			var fault *onvif.Fault
+			switch {
+			case err == nil:
+				onvif.WriteResponse(w, body)
+			case errors.As(err, &fault):
+				onvif.WriteFault(w, fault)
+			default:
+				onvif.WriteFault(w, onvif.ActionFailed(err.Error()))
+			}
+		})
+	}
+	return mux
+}
+
+// onvifAuthorized reports whether r carries the credentials of auth, in its
+// HTTP headers or its UsernameToken.
+func onvifAuthorized(r *http.Request, req *onvif.Request, auth AuthConfig) bool {
+	if !auth.enabled() || auth.authorized(r) {
+		return true
+	}
+	if user, pass, ok := r.BasicAuth(); ok && auth.Token != "" && equal(pass, auth.Token) && user != "" {
+		return true
+	}
+	t, now := req.Token, time.Now()
+	if t == nil {
+		return false
+	}
+	if auth.Username != "" && t.Verify(auth.Username, auth.Password, now) {
+		return true
+	}
+	return auth.Token != "" && t.Verify(t.Username, auth.Token, now)
+}
+
+// onvifAddr returns the address of an ONVIF service as reached by r.
+func (h *Handlers) onvifAddr(r *http.Request, service string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + h.BasePath + ONVIFPath + service
+}
+
+// onvifDevice serves the device service: identification, capabilities and
+// clock.
+func (h *Handlers) onvifDevice(r *http.Request, req *onvif.Request) (string, error) {
+	switch req.Action.Local {
+	case "GetSystemDateAndTime":
+		now := time.Now().UTC()
+		return fmt.Sprintf(`<tds:GetSystemDateAndTimeResponse><tds:SystemDateAndTime><tt:DateTimeType>NTP</tt:DateTimeType><tt:DaylightSavings>false</tt:DaylightSavings><tt:TimeZone><tt:TZ>UTC0</tt:TZ></tt:TimeZone><tt:UTCDateTime><tt:Time><tt:Hour>%d</tt:Hour><tt:Minute>%d</tt:Minute><tt:Second>%d</tt:Second></tt:Time><tt:Date><tt:Year>%d</tt:Year><tt:Month>%d</tt:Month><tt:Day>%d</tt:Day></tt:Date></tt:UTCDateTime></tds:SystemDateAndTime></tds:GetSystemDateAndTimeResponse>`,
+			now.Hour(), now.Minute(), now.Second(), now.Year(), now.Month(), now.Day()), nil
+	case "GetDeviceInformation":
+		host, _ := os.Hostname()
+		version := h.Build.Version
+		if version == "" {
+			version = "dev"
+		}
+		return fmt.Sprintf(`<tds:GetDeviceInformationResponse><tds:Manufacturer>PanGo</tds:Manufacturer><tds:Model>PanGo panoramic head</tds:Model><tds:FirmwareVersion>%s</tds:FirmwareVersion><tds:SerialNumber>%s</tds:SerialNumber><tds:HardwareId>pango</tds:HardwareId></tds:GetDeviceInformationResponse>`,
+			onvif.Escape(version), onvif.Escape(host)), nil
+	case "GetCapabilities":
+		return fmt.Sprintf(`<tds:GetCapabilitiesResponse><tds:Capabilities><tt:Device><tt:XAddr>%s</tt:XAddr></tt:Device><tt:Media><tt:XAddr>%s</tt:XAddr><tt:StreamingCapabilities><tt:RTPMulticast>false</tt:RTPMulticast><tt:RTP_TCP>false</tt:RTP_TCP><tt:RTP_RTSP_TCP>false</tt:RTP_RTSP_TCP></tt:StreamingCapabilities></tt:Media><tt:PTZ><tt:XAddr>%s</tt:XAddr></tt:PTZ></tds:Capabilities></tds:GetCapabilitiesResponse>`,
+			onvif.Escape(h.onvifAddr(r, onvifDeviceService)), onvif.Escape(h.onvifAddr(r, onvifMediaService)), onvif.Escape(h.onvifAddr(r, onvifPTZService))), nil
+	case "GetServices":
+		var b strings.Builder
+		b.WriteString("<tds:GetServicesResponse>")
+		for _, s := range []struct{ namespace, service string }{
+			{onvif.NamespaceDevice, onvifDeviceService},
+			{onvif.NamespaceMedia, onvifMediaService},
+			{onvif.NamespacePTZ, onvifPTZService},
+		} {
+			fmt.Fprintf(&b, `<tds:Service><tds:Namespace>%s</tds:Namespace><tds:XAddr>%s</tds:XAddr><tds:Version><tt:Major>2</tt:Major><tt:Minor>0</tt:Minor></tds:Version></tds:Service>`,
+				s.namespace, onvif.Escape(h.onvifAddr(r, s.service)))
+		}
+		b.WriteString("</tds:GetServicesResponse>")
+		return b.String(), nil
+	case "GetScopes":
+		var b strings.Builder
+		b.WriteString("<tds:GetScopesResponse>")
+		for _, scope := range []string{"type/ptz", "name/PanGo", "hardware/PanGo"} {
+			fmt.Fprintf(&b, "<tds:Scopes><tt:ScopeDef>Fixed</tt:ScopeDef><tt:ScopeItem>onvif://www.onvif.org/%s</tt:ScopeItem></tds:Scopes>", scope)
+		}
+		b.WriteString("</tds:GetScopesResponse>")
+		return b.String(), nil
+	}
+	return "", onvif.ErrActionNotSupported
+}
+
+// onvifMedia serves the media service: a single profile, with the PTZ
+// configuration and without video.
+func (h *Handlers) onvifMedia(r *http.Request, req *onvif.Request) (string, error) {
+	switch req.Action.Local {
+	case "GetProfiles":
+		return "<trt:GetProfilesResponse>" + onvifProfile("trt:Profiles") + "</trt:GetProfilesResponse>", nil
+	case "GetProfile":
+		if err := onvifCheckProfile(req); err != nil {
+			return "", err
+		}
+		return "<trt:GetProfileResponse>" + onvifProfile("trt:Profile") + "</trt:GetProfileResponse>", nil
+	}
+	return "", onvif.ErrActionNotSupported
+}
+
+// onvifProfile returns the media profile as the element called name.
+func onvifProfile(name string) string {
+	return fmt.Sprintf(`<%s token="%s" fixed="true"><tt:Name>PanGo</tt:Name>%s</%s>`,
+		name, onvifProfileToken, onvifConfiguration("tt:PTZConfiguration"), name)
+}
+
+// onvifCheckProfile checks the ProfileToken of req.
+func onvifCheckProfile(req *onvif.Request) error {
+	var v struct {
+		ProfileToken string `xml:"ProfileToken"`
+	}
+	if err := req.Decode(&v); err != nil {
+		return onvif.InvalidArgs(err.Error())
+	}
+	if strings.TrimSpace(v.ProfileToken) != onvifProfileToken {
+		return &onvif.Fault{Code: "Sender", Subcode: "ter:NoProfile", Reason: "unknown profile " + strconv.Quote(v.ProfileToken)}
+	}
+	return nil
+}
+
+// onvifConfiguration returns the PTZ configuration as the element called
+// name.
+func onvifConfiguration(name string) string {
+	return fmt.Sprintf(`<%s token="%s"><tt:Name>PanGo head</tt:Name><tt:UseCount>1</tt:UseCount><tt:NodeToken>%s</tt:NodeToken><tt:DefaultContinuousPanTiltVelocitySpace>%s</tt:DefaultContinuousPanTiltVelocitySpace><tt:DefaultRelativePanTiltTranslationSpace>%s</tt:DefaultRelativePanTiltTranslationSpace><tt:DefaultPTZTimeout>%s</tt:DefaultPTZTimeout></%s>`,
+		name, onvifConfigToken, onvifNodeToken, onvifVelocitySpace, onvifTranslationSpace, onvif.FormatDuration(onvifDefaultTimeout), name)
+}
+
+// onvifNode returns the PTZ node, the head, as the element called name.
+func onvifNode(name string) string {
+	const ranges = `<tt:XRange><tt:Min>-1</tt:Min><tt:Max>1</tt:Max></tt:XRange><tt:YRange><tt:Min>-1</tt:Min><tt:Max>1</tt:Max></tt:YRange>`
+	return fmt.Sprintf(`<%s token="%s" FixedHomePosition="false"><tt:Name>PanGo head</tt:Name><tt:SupportedPTZSpaces><tt:RelativePanTiltTranslationSpace><tt:URI>%s</tt:URI>%s</tt:RelativePanTiltTranslationSpace><tt:ContinuousPanTiltVelocitySpace><tt:URI>%s</tt:URI>%s</tt:ContinuousPanTiltVelocitySpace></tt:SupportedPTZSpaces><tt:MaximumNumberOfPresets>0</tt:MaximumNumberOfPresets><tt:HomeSupported>false</tt:HomeSupported></%s>`,
+		name, onvifNodeToken, onvifTranslationSpace, ranges, onvifVelocitySpace, ranges, name)
+}
+
+// onvifPTZ serves the PTZ service: continuous and relative moves, stop and
+// status.
+func (h *Handlers) onvifPTZ(r *http.Request, req *onvif.Request) (string, error) {
+	switch req.Action.Local {
+	case "GetConfigurations":
+		return "<tptz:GetConfigurationsResponse>" + onvifConfiguration("tptz:PTZConfiguration") + "</tptz:GetConfigurationsResponse>", nil
+	case "GetConfiguration":
+		return "<tptz:GetConfigurationResponse>" + onvifConfiguration("tptz:PTZConfiguration") + "</tptz:GetConfigurationResponse>", nil
+	case "GetNodes":
+		return "<tptz:GetNodesResponse>" + onvifNode("tptz:PTZNode") + "</tptz:GetNodesResponse>", nil
+	case "GetNode":
+		return "<tptz:GetNodeResponse>" + onvifNode("tptz:PTZNode") + "</tptz:GetNodeResponse>", nil
+	case "GetStatus":
+		if err := onvifCheckProfile(req); err != nil {
+			return "", err
+		}
+		return h.onvifStatus(), nil
+	case "ContinuousMove":
+		return "<tptz:ContinuousMoveResponse/>", h.onvifContinuousMove(r, req)
+	case "RelativeMove":
+		return "<tptz:RelativeMoveResponse/>", h.onvifRelativeMove(r, req)
+	case "Stop":
+		if err := onvifCheckProfile(req); err != nil {
+			return "", err
+		}
+		if err := h.onvifControl(r); err != nil {
+			return "", err
+		}
+		h.ptz.stop()
+		return "<tptz:StopResponse/>", nil
+	}
+	return "", onvif.ErrActionNotSupported
+}
+
+// onvifControl checks that the client of r may act on the rig, as for
+// controlledRoutes. ONVIF clients cannot send a control token: they may
+// not move the head while another client holds control.
+func (h *Handlers) onvifControl(r *http.Request) error {
+	if h.Jog == nil {
+		return onvif.ActionFailed("jog not available")
+	}
+	if err := h.checkNetwork(r); err != nil {
+		return onvif.Prohibited(err.Error())
+	}
+	if h.Control != nil {
+		if err := h.Control.check(controlToken(r)); err != nil {
+			return onvif.Prohibited(err.Error())
+		}
+	}
+	return nil
+}
+
+// onvifStartMove checks the client of r, then replaces the move in progress
+// by move.
+func (h *Handlers) onvifStartMove(r *http.Request, timeout time.Duration, move func(ctx context.Context)) error {
+	if err := h.onvifControl(r); err != nil {
+		return err
+	}
+	h.ptz.stop()
+	if h.Head != nil && h.Head().Busy {
+		return onvif.ActionFailed(ErrHeadBusy.Error())
+	}
+	h.ptz.start(timeout, move)
+	return nil
+}
+
+// onvifContinuousMove handles ContinuousMove: the head moves in the
+// direction of the velocity, at the configured speed, until Stop, the
+// timeout or POST /stop.
+func (h *Handlers) onvifContinuousMove(r *http.Request, req *onvif.Request) error {
+	if err := onvifCheckProfile(req); err != nil {
+		return err
+	}
+	var v struct {
+		Velocity onvifVector `xml:"Velocity"`
+		Timeout  string      `xml:"Timeout"`
+	}
+	if err := req.Decode(&v); err != nil {
+		return onvif.InvalidArgs(err.Error())
+	}
+	timeout := onvifDefaultTimeout
+	if v.Timeout != "" {
+		d, err := onvif.ParseDuration(v.Timeout)
+		if err != nil || d <= 0 {
+			return onvif.InvalidArgs("invalid Timeout " + strconv.Quote(v.Timeout))
+		}
+		timeout = min(d, onvifMaxTimeout)
+	}
+	var velocity JogVelocity
+	if pt := v.Velocity.PanTilt; pt != nil {
+		velocity = JogVelocity{Pan: onvifDirection(pt.X), Tilt: onvifDirection(pt.Y)}
+	}
+	if !velocity.moving() {
+		if err := h.onvifControl(r); err != nil {
+			return err
+		}
+		h.ptz.stop()
+		return nil
+	}
+	return h.onvifStartMove(r, timeout, func(ctx context.Context) {
+		tilt := false
+		for {
+			stop, done := h.startJogSlice(ctx, "onvif", velocity, &tilt)
+			err := <-done
+			stop()
+			if err != nil {
+				if ctx.Err() == nil && !errors.Is(err, context.Canceled) {
+					log.Printf("onvif: continuous move: %v", err)
+				}
+				return
+			}
+		}
+	})
+}
+
+// onvifDirection returns the direction of a velocity component: -1, 0 or 1.
+func onvifDirection(v float64) int {
+	switch {
+	case v > onvifDeadZone:
+		return 1
+	case v < -onvifDeadZone:
+		return -1
+	}
+	return 0
+}
+
+// onvifRelativeMove handles RelativeMove: the head turns by the
+// translation, pan then tilt.
+func (h *Handlers) onvifRelativeMove(r *http.Request, req *onvif.Request) error {
+	if err := onvifCheckProfile(req); err != nil {
+		return err
+	}
+	var v struct {
+		Translation onvifVector `xml:"Translation"`
+	}
+	if err := req.Decode(&v); err != nil {
+		return onvif.InvalidArgs(err.Error())
+	}
+	pt := v.Translation.PanTilt
+	if pt == nil {
+		return nil
+	}
+	if math.Abs(pt.X) > 1 || math.Abs(pt.Y) > 1 {
+		return onvif.InvalidArgs("translation out of the generic space")
+	}
+	var moves []JogRequest
+	for _, m := range []JogRequest{
+		{Axis: "pan", Degrees: pt.X * onvifPanScaleDeg},
+		{Axis: "tilt", Degrees: pt.Y * onvifTiltScaleDeg},
+	} {
+		if m.Degrees == 0 {
+			continue
+		}
+		if err := ValidateJog(m); err != nil {
+			return onvif.InvalidArgs(err.Error())
+		}
+		moves = append(moves, m)
+	}
+	if len(moves) == 0 {
+		return nil
+	}
+	return h.onvifStartMove(r, onvifMaxTimeout, func(ctx context.Context) {
+		for _, m := range moves {
+			err := h.protect("onvif", func() error { return h.runJog(ctx, m) })
+			if err != nil {
+				if ctx.Err() == nil && !errors.Is(err, context.Canceled) {
+					log.Printf("onvif: relative move: %v", err)
+				}
+				return
+			}
+		}
+	})
+}
+
+// onvifStatus returns the GetStatus response: the position of the head in
+// the generic space, when known, and whether it moves.
+func (h *Handlers) onvifStatus() string {
+	var b strings.Builder
+	b.WriteString("<tptz:GetStatusResponse><tptz:PTZStatus>")
+	moving := h.ptz.moving()
+	if h.Head != nil {
+		head := h.Head()
+		x := math.Remainder(head.PanDeg, 360) / onvifPanScaleDeg
+		y := math.Max(-1, math.Min(1, head.TiltDeg/onvifTiltScaleDeg))
+		fmt.Fprintf(&b, `<tt:Position><tt:PanTilt x="%s" y="%s" space="%s"/></tt:Position>`,
+			strconv.FormatFloat(x, 'f', 6, 64), strconv.FormatFloat(y, 'f', 6, 64), onvifPositionSpace)
+		moving = moving || head.Busy
+	}
+	status := "IDLE"
+	if moving {
+		status = "MOVING"
+	}
+	fmt.Fprintf(&b, "<tt:MoveStatus><tt:PanTilt>%s</tt:PanTilt></tt:MoveStatus><tt:UtcTime>%s</tt:UtcTime>",
+		status, time.Now().UTC().Format(time.RFC3339))
+	b.WriteString("</tptz:PTZStatus></tptz:GetStatusResponse>")
+	return b.String()
+}
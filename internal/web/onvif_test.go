@@ -0,0 +1,296 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/onvif"
+)
+
+// soapEnvelope returns a request envelope calling body, with header.
+func soapEnvelope(header, body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:tds="http://www.onvif.org/ver10/device/wsdl" xmlns:trt="http://www.onvif.org/ver10/media/wsdl" xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl" xmlns:tt="http://www.onvif.org/ver10/schema">
+<s:Header>` + header + `</s:Header><s:Body>` + body + `</s:Body></s:Envelope>`
+}
+
+// usernameToken returns a security header with a password digest created
+// now.
+func usernameToken(user, password string) string {
+	nonce := []byte("0123456789abcdef")
+	created := time.Now().UTC().Format(time.RFC3339)
+	return fmt.Sprintf(`<wsse:Security xmlns:wsse="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd" xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd"><wsse:UsernameToken><wsse:Username>%s</wsse:Username><wsse:Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest">%s</wsse:Password><wsse:Nonce>MDEyMzQ1Njc4OWFiY2RlZg==</wsse:Nonce><wsu:Created>%s</wsu:Created></wsse:UsernameToken></wsse:Security>`,
+		user, onvif.PasswordDigest(nonce, created, password), created)
+}
+
+// onvifCall posts an envelope to the service of handler.
+func onvifCall(t *testing.T, handler http.Handler, service, envelope string, prepare ...func(*http.Request)) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, ONVIFPath+service, strings.NewReader(envelope))
+	r.Header.Set("Content-Type", "application/soap+xml")
+	for _, p := range prepare {
+		p(r)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// ---------- Authentication ----------
+
+func TestONVIF_Auth(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	info := soapEnvelope("", "<tds:GetDeviceInformation/>")
+
+	cases := []struct {
+		name    string
+		auth    AuthConfig
+		header  string
+		prepare func(*http.Request)
+		want    int
+	}{
+		{"no_auth", AuthConfig{}, "", nil, http.StatusOK},
+		{"missing", AuthConfig{Username: "admin", Password: "secret"}, "", nil, http.StatusUnauthorized},
+		{"digest", AuthConfig{Username: "admin", Password: "secret"}, usernameToken("admin", "secret"), nil, http.StatusOK},
+		{"digest_wrong", AuthConfig{Username: "admin", Password: "secret"}, usernameToken("admin", "guess"), nil, http.StatusUnauthorized},
+		{"basic", AuthConfig{Username: "admin", Password: "secret"}, "", func(r *http.Request) { r.SetBasicAuth("admin", "secret") }, http.StatusOK},
+		{"token_digest", AuthConfig{Token: "0123456789abcdef"}, usernameToken("vms", "0123456789abcdef"), nil, http.StatusOK},
+		{"token_basic", AuthConfig{Token: "0123456789abcdef"}, "", func(r *http.Request) { r.SetBasicAuth("vms", "0123456789abcdef") }, http.StatusOK},
+		{"token_bearer", AuthConfig{Token: "0123456789abcdef"}, "", func(r *http.Request) { r.Header.Set("Authorization", "Bearer 0123456789abcdef") }, http.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := info
+			if tc.header != "" {
+				env = soapEnvelope(tc.header, "<tds:GetDeviceInformation/>")
+			}
+			var prepare []func(*http.Request)
+			if tc.prepare != nil {
+				prepare = append(prepare, tc.prepare)
+			}
+			w := onvifCall(t, h.ONVIFHandler(tc.auth), onvifDeviceService, env, prepare...)
+			if w.Code != tc.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tc.want, w.Body)
+			}
+			if tc.want == http.StatusUnauthorized && !strings.Contains(w.Body.String(), "ter:NotAuthorized") {
+				t.Errorf("fault = %s", w.Body)
+			}
+		})
+	}
+}
+
+func TestONVIF_SystemDateAndTimeOpen(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	w := onvifCall(t, h.ONVIFHandler(AuthConfig{Token: "0123456789abcdef"}), onvifDeviceService,
+		soapEnvelope("", "<tds:GetSystemDateAndTime/>"))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "<tt:Year>") {
+		t.Errorf("%d %s", w.Code, w.Body)
+	}
+}
+
+// ---------- Device and media ----------
+
+func TestONVIF_Capabilities(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.BasePath = "/pango"
+	for _, action := range []string{"GetCapabilities", "GetServices"} {
+		w := onvifCall(t, h.ONVIFHandler(AuthConfig{}), onvifDeviceService, soapEnvelope("", "<tds:"+action+"/>"))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", action, w.Code, w.Body)
+		}
+		if want := "http://example.com/pango/onvif/ptz_service"; !strings.Contains(w.Body.String(), want) {
+			t.Errorf("%s: no %s in %s", action, want, w.Body)
+		}
+	}
+}
+
+func TestONVIF_Profiles(t *testing.T) {
+	handler := newTestHandlers(noopCapture).ONVIFHandler(AuthConfig{})
+	w := onvifCall(t, handler, onvifMediaService, soapEnvelope("", "<trt:GetProfiles/>"))
+	if body := w.Body.String(); !strings.Contains(body, `token="pango"`) || !strings.Contains(body, `<tt:PTZConfiguration token="ptz">`) {
+		t.Errorf("GetProfiles = %s", body)
+	}
+	w = onvifCall(t, handler, onvifMediaService, soapEnvelope("", "<trt:GetProfile><trt:ProfileToken>other</trt:ProfileToken></trt:GetProfile>"))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "ter:NoProfile") {
+		t.Errorf("GetProfile(other) = %d %s", w.Code, w.Body)
+	}
+}
+
+func TestONVIF_ActionNotSupported(t *testing.T) {
+	handler := newTestHandlers(noopCapture).ONVIFHandler(AuthConfig{})
+	w := onvifCall(t, handler, onvifMediaService, soapEnvelope("", "<trt:GetStreamUri/>"))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "ter:ActionNotSupported") {
+		t.Errorf("GetStreamUri = %d %s", w.Code, w.Body)
+	}
+	w = onvifCall(t, handler, onvifPTZService, "not a envelope")
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid envelope: status %d", w.Code)
+	}
+}
+
+// ---------- PTZ ----------
+
+func continuousMove(x, y float64, timeout string) string {
+	return soapEnvelope("", fmt.Sprintf(`<tptz:ContinuousMove><tptz:ProfileToken>pango</tptz:ProfileToken><tptz:Velocity><tt:PanTilt x="%g" y="%g"/></tptz:Velocity>%s</tptz:ContinuousMove>`, x, y, timeout))
+}
+
+const ptzStop = `<tptz:Stop><tptz:ProfileToken>pango</tptz:ProfileToken></tptz:Stop>`
+
+func TestONVIF_ContinuousMove(t *testing.T) {
+	moves := make(chan JogRequest, 100)
+	h := newTestHandlers(noopCapture)
+	h.Jog = sliceJog(moves)
+	handler := h.ONVIFHandler(AuthConfig{})
+	t.Cleanup(h.ptz.stop)
+
+	w := onvifCall(t, handler, onvifPTZService, continuousMove(0.05, 0.8, ""))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ContinuousMove: status %d: %s", w.Code, w.Body)
+	}
+	for range 2 {
+		if req := <-moves; req.Axis != "tilt" || req.Steps != jogSliceSteps {
+			t.Errorf("move = %+v, want tilt up (pan in the dead zone)", req)
+		}
+	}
+
+	// A new velocity replaces the previous one.
+	onvifCall(t, handler, onvifPTZService, continuousMove(-1, 0, ""))
+	time.Sleep(20 * time.Millisecond)
+	for len(moves) > 1 {
+		<-moves
+	}
+	<-moves // the slice running when the velocity changed, if any
+	if req := <-moves; req.Axis != "pan" || req.Steps != -jogSliceSteps {
+		t.Errorf("move = %+v, want pan left", req)
+	}
+
+	w = onvifCall(t, handler, onvifPTZService, soapEnvelope("", ptzStop))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Stop: status %d: %s", w.Code, w.Body)
+	}
+	expectIdle(t, moves)
+}
+
+func TestONVIF_ContinuousMoveTimeout(t *testing.T) {
+	moves := make(chan JogRequest, 100)
+	h := newTestHandlers(noopCapture)
+	h.Jog = sliceJog(moves)
+	t.Cleanup(h.ptz.stop)
+
+	w := onvifCall(t, h.ONVIFHandler(AuthConfig{}), onvifPTZService, continuousMove(1, 0, "<tptz:Timeout>PT0.05S</tptz:Timeout>"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	<-moves
+	time.Sleep(50 * time.Millisecond)
+	expectIdle(t, moves)
+	if h.ptz.moving() {
+		t.Error("still moving after the timeout")
+	}
+}
+
+func TestONVIF_PostStopInterrupts(t *testing.T) {
+	moves := make(chan JogRequest, 100)
+	h := newTestHandlers(noopCapture)
+	h.Jog = sliceJog(moves)
+	t.Cleanup(h.ptz.stop)
+
+	onvifCall(t, h.ONVIFHandler(AuthConfig{}), onvifPTZService, continuousMove(1, 1, ""))
+	<-moves
+	h.stopJog()
+	expectIdle(t, moves)
+}
+
+func TestONVIF_RelativeMove(t *testing.T) {
+	moves := make(chan JogRequest, 10)
+	h := newTestHandlers(noopCapture)
+	h.Jog = sliceJog(moves)
+	handler := h.ONVIFHandler(AuthConfig{})
+	t.Cleanup(h.ptz.stop)
+
+	w := onvifCall(t, handler, onvifPTZService, soapEnvelope("", `<tptz:RelativeMove><tptz:ProfileToken>pango</tptz:ProfileToken><tptz:Translation><tt:PanTilt x="0.5" y="-0.25"/></tptz:Translation></tptz:RelativeMove>`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	for _, want := range []JogRequest{{Axis: "pan", Degrees: 90}, {Axis: "tilt", Degrees: -22.5}} {
+		if got := <-moves; got != want {
+			t.Errorf("move = %+v, want %+v", got, want)
+		}
+	}
+
+	w = onvifCall(t, handler, onvifPTZService, soapEnvelope("", `<tptz:RelativeMove><tptz:ProfileToken>pango</tptz:ProfileToken><tptz:Translation><tt:PanTilt x="1.5" y="0"/></tptz:Translation></tptz:RelativeMove>`))
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "ter:InvalidArgVal") {
+		t.Errorf("out of range: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestONVIF_MoveRefused(t *testing.T) {
+	cases := []struct {
+		name    string
+		setup   func(h *Handlers)
+		subcode string
+	}{
+		{"no_jog", func(h *Handlers) { h.Jog = nil }, "ter:Action"},
+		{"busy", func(h *Handlers) { h.Head = func() HeadState { return HeadState{Busy: true} } }, "ter:Action"},
+		{"control_claimed", func(h *Handlers) {
+			h.Control = NewControlLock()
+			h.Control.Claim("", "phone", false)
+		}, "ter:OperationProhibited"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			moves := make(chan JogRequest, 10)
+			h := newTestHandlers(noopCapture)
+			h.Jog = sliceJog(moves)
+			tc.setup(h)
+			t.Cleanup(h.ptz.stop)
+
+			w := onvifCall(t, h.ONVIFHandler(AuthConfig{}), onvifPTZService, continuousMove(1, 0, ""))
+			if !strings.Contains(w.Body.String(), "<s:Value>"+tc.subcode+"</s:Value>") {
+				t.Errorf("%d %s, want %s", w.Code, w.Body, tc.subcode)
+			}
+			if len(moves) > 0 {
+				t.Errorf("head moved: %+v", <-moves)
+			}
+		})
+	}
+}
+
+func TestONVIF_GetStatus(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Jog = func(ctx context.Context, req JogRequest) error { return nil }
+	h.Head = func() HeadState { return HeadState{PanDeg: 270, TiltDeg: 45} }
+	w := onvifCall(t, h.ONVIFHandler(AuthConfig{}), onvifPTZService,
+		soapEnvelope("", "<tptz:GetStatus><tptz:ProfileToken>pango</tptz:ProfileToken></tptz:GetStatus>"))
+	body := w.Body.String()
+	for _, want := range []string{`x="-0.500000"`, `y="0.500000"`, "<tt:PanTilt>IDLE</tt:PanTilt>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("no %s in %s", want, body)
+		}
+	}
+}
+
+// ---------- Server ----------
+
+func TestServer_ONVIF(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+		srv.SetAccessLog(nil)
+		srv.SetAuth(AuthConfig{Username: "admin", Password: "secret"})
+		srv.SetBasePath("/pango")
+		srv.SetONVIF(enabled)
+
+		r := httptest.NewRequest(http.MethodPost, "/pango"+ONVIFPath+onvifDeviceService,
+			strings.NewReader(soapEnvelope(usernameToken("admin", "secret"), "<tds:GetDeviceInformation/>")))
+		w := httptest.NewRecorder()
+		srv.Mux().ServeHTTP(w, r)
+		if got := w.Code == http.StatusOK; got != enabled {
+			t.Errorf("enabled %v: status %d", enabled, w.Code)
+		}
+	}
+}
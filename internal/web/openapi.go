@@ -0,0 +1,375 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// APIVersion is the version of the documented HTTP API (info.version of the
+// OpenAPI document). Bump it when an operation or schema changes.
+const APIVersion = "1.9.0"
+
+// APIPrefix is where the versioned API routes are served: an Operation
+// with Path "/run" is at "/api/v1/run" (servers[0].url of the OpenAPI
+// document). A new major version gets its own prefix, so that existing
+// clients keep working.
+const APIPrefix = "/api/v1"
+
+// QueryParam is a query string parameter of an Operation.
+type QueryParam struct {
+	Name        string
+	Type        string // OpenAPI type: "boolean", "integer" or "string"
+	Description string
+}
+
+// Operation documents a route of the HTTP API. The OpenAPI document and the
+// generated Go client (package client) are built from Operations, with the
+// request and response schemas derived from the Go types the handlers use.
+type Operation struct {
+	ID          string // operationId; also the client method name
+	Method      string
+	Path        string
+	Summary     string
+	Query       []QueryParam
+	Request     any // JSON request body (zero value of its type); nil = no body. json.RawMessage = any YAML or JSON document.
+	Response    any // JSON response body (zero value of its type); nil = none
+	Status      int // success status code
+	Description string
+}
+
+// Operations returns the documented API operations, in document order.
+func Operations() []Operation {
+	return []Operation{
+		{
+			ID: "RunCapture", Method: http.MethodPost, Path: "/run",
+			Summary:     "Start a capture now",
+			Description: "Starts a capture with the given parameters. Fails with 409 when a capture is running and 429, with a Retry-After header, when the previous one started too recently (web.limits.capture_spacing_ms).",
+			Request:     Overrides{}, Response: map[string]string{}, Status: http.StatusAccepted,
+		},
+		{
+			ID: "CancelCapture", Method: http.MethodPost, Path: "/cancel",
+			Summary:  "Cancel the running capture",
+			Response: map[string]string{}, Status: http.StatusOK,
+		},
+		{
+			ID: "PauseCapture", Method: http.MethodPost, Path: "/pause",
+			Summary:  "Pause the running capture",
+			Response: capture.Status{}, Status: http.StatusOK,
+			Description: "The capture stops at the next cell boundary or timelapse frame, in state \"paused\", until ResumeCapture. 409 when no capture is shooting.",
+		},
+		{
+			ID: "ResumeCapture", Method: http.MethodPost, Path: "/resume",
+			Summary:  "Resume a paused capture",
+			Response: capture.Status{}, Status: http.StatusOK,
+			Description: "409 when no capture is paused.",
+		},
+		{
+			ID: "GetControl", Method: http.MethodGet, Path: "/control",
+			Summary:  "Get who holds control of the rig",
+			Response: ControlStatus{}, Status: http.StatusOK,
+		},
+		{
+			ID: "ClaimControl", Method: http.MethodPost, Path: "/control/claim",
+			Summary:     "Claim control of the rig",
+			Description: "Until released or expired (60 s without renewal), only requests carrying the returned token in the X-Control-Token header may start, cancel or pause captures, move the head or change the configuration; others get 423. Claiming again with the token renews the claim. 409 when another client holds control, unless force is set.",
+			Request:     ControlClaim{}, Response: ControlStatus{}, Status: http.StatusOK,
+		},
+		{
+			ID: "ReleaseControl", Method: http.MethodPost, Path: "/control/release",
+			Summary:     "Release control of the rig",
+			Description: "409 when the X-Control-Token header does not hold control.",
+			Response:    ControlStatus{}, Status: http.StatusOK,
+		},
+		{
+			ID: "PlanCapture", Method: http.MethodPost, Path: "/plan",
+			Summary:     "Preview a capture",
+			Description: "Returns the grid size, shot count and estimated duration for the given parameters, without moving the head.",
+			Request:     Overrides{}, Response: Plan{}, Status: http.StatusOK,
+		},
+		{
+			ID: "GetFormConfig", Method: http.MethodGet, Path: "/config",
+			Summary:  "Get the capture form defaults",
+			Response: FormConfig{}, Status: http.StatusOK,
+		},
+		{
+			ID: "GetConfig", Method: http.MethodGet, Path: "/config/full",
+			Summary:     "Get the full configuration",
+			Description: "The configuration document, keyed like the YAML file, without the web section.",
+			Response:    map[string]any{}, Status: http.StatusOK,
+		},
+		{
+			ID: "GetEffectiveConfig", Method: http.MethodGet, Path: "/config/effective",
+			Summary:     "Get the configuration in use",
+			Description: "Every key of the configuration captures run with: included files merged, defaults, PANGO_* variables, profile and command line overrides applied. Also lists the files merged and the variables applied. Credentials are redacted, secret references shown as is.",
+			Response:    EffectiveConfig{}, Status: http.StatusOK,
+		},
+		{
+			ID: "GetConfigSchema", Method: http.MethodGet, Path: "/config/schema",
+			Summary:     "Get the JSON Schema of the config file",
+			Description: "A JSON Schema (draft 2020-12) of the YAML, JSON or TOML config file, for editors to complete and check it. Served as application/schema+json.",
+			Response:    map[string]any{}, Status: http.StatusOK,
+		},
+		{
+			ID: "UpdateConfig", Method: http.MethodPut, Path: "/config",
+			Summary:     "Replace the configuration",
+			Description: "Validates a full configuration document (YAML or JSON) and applies it to the next captures.",
+			Query:       []QueryParam{{Name: "save", Type: "boolean", Description: "Also write the configuration to the config file."}},
+			Request:     json.RawMessage{}, Response: ConfigUpdate{}, Status: http.StatusOK,
+		},
+		{
+			ID: "UploadProfile", Method: http.MethodPost, Path: "/config/upload",
+			Summary:     "Add a configuration profile",
+			Description: "Validates a configuration document (YAML or JSON) and stores it as a profile next to the active one. Browsers may send it as the file field of a multipart form. Fails with 409 when the profile exists and replace is not set.",
+			Query: []QueryParam{
+				{Name: "name", Type: "string", Description: "Profile name (file name without .yaml)."},
+				{Name: "replace", Type: "boolean", Description: "Overwrite an existing profile."},
+				{Name: "activate", Type: "boolean", Description: "Switch to the profile once stored."},
+			},
+			Request: json.RawMessage{}, Response: ProfileUpload{}, Status: http.StatusCreated,
+		},
+		{
+			ID: "SaveDefaults", Method: http.MethodPost, Path: "/config/defaults",
+			Summary:     "Save capture parameters as profile defaults",
+			Description: "Writes the angles, focal length and delays of the body, with the active overlap, into a profile's config file, so they become its defaults. A missing file profile is created from the active configuration. Saving into the active profile reloads it.",
+			Query: []QueryParam{
+				{Name: "profile", Type: "string", Description: "Profile to write: a file name without extension, or file/entry for an entry of its profiles: section. Empty = the active profile."},
+			},
+			Request: Overrides{}, Response: SavedDefaults{}, Status: http.StatusOK,
+		},
+		{
+			ID: "GetStatus", Method: http.MethodGet, Path: "/status",
+			Summary:  "Get the capture status",
+			Response: capture.Status{}, Status: http.StatusOK,
+		},
+		{
+			ID: "GetETA", Method: http.MethodGet, Path: "/eta",
+			Summary:  "Estimate when the running capture completes",
+			Response: capture.ETA{}, Status: http.StatusOK,
+			Description: "The estimate comes from the mean time per shot measured so far (source \"measured\"), pauses excluded, or from the plan before the first shot (\"plan\"). Source is \"none\" when no capture runs.",
+		},
+		{
+			ID: "GetVersion", Method: http.MethodGet, Path: "/version",
+			Summary:     "Get the PanGo build",
+			Description: "Version, commit and build date of the server, as printed by pango version.",
+			Response:    BuildInfo{}, Status: http.StatusOK,
+		},
+		{
+			ID: "GetLogLevels", Method: http.MethodGet, Path: "/log-level",
+			Summary:     "Get the debug output levels",
+			Description: "The global debug_level and the level of each module of the debug output (camera, capture, gpio, pango, stepper, trigger, web): its own, from log.levels or SetLogLevels, or the global one.",
+			Response:    LogLevels{}, Status: http.StatusOK,
+		},
+		{
+			ID: "SetLogLevels", Method: http.MethodPut, Path: "/log-level",
+			Summary:     "Set the debug output level of modules",
+			Description: "Sets the level of the modules of the body, module name to level name (trace, verbose, live, info, warn, error or off), e.g. {\"gpio\":\"trace\",\"web\":\"warn\"}, until the server restarts; the other modules keep theirs. \"default\" returns a module to debug_level. 400, with nothing changed, when a module or level is unknown.",
+			Request:     map[string]string{}, Response: LogLevels{}, Status: http.StatusOK,
+		},
+		{
+			ID: "GetDebugState", Method: http.MethodGet, Path: "/debug/state",
+			Summary:     "Dump the state of the server",
+			Description: "The capture state and grid position, the tracked motor positions and steps made, the goroutine count and the last 50 status events: what to look at when a long run seems hung. Also logged on SIGUSR1.",
+			Response:    DebugState{}, Status: http.StatusOK,
+		},
+		{
+			ID: "SyncRun", Method: http.MethodPost, Path: "/sync/run",
+			Summary:     "Start a capture as a follower rig",
+			Description: "Starts a capture like RunCapture, whose shots wait for the leader of a multi-rig capture: at each cell, the leader waits for SyncReady, then releases the shot with SyncShoot.",
+			Request:     Overrides{}, Response: map[string]string{}, Status: http.StatusAccepted,
+		},
+		{
+			ID: "SyncReady", Method: http.MethodGet, Path: "/sync/ready",
+			Summary:     "Wait until a follower rig is in place for its next shot",
+			Description: "Blocks until the capture started by SyncRun waits at a cell, and returns the shot. 409 when no such capture runs or it has ended.",
+			Response:    SyncCell{}, Status: http.StatusOK,
+		},
+		{
+			ID: "SyncShoot", Method: http.MethodPost, Path: "/sync/shoot",
+			Summary:     "Release the shot of a follower rig",
+			Description: "Lets the capture started by SyncRun take the given shot, as returned by SyncReady. 409 when it waits at another shot.",
+			Request:     SyncCell{}, Response: SyncCell{}, Status: http.StatusOK,
+		},
+	}
+}
+
+// OpenAPI returns the OpenAPI 3.0 document describing Operations.
+func OpenAPI() map[string]any {
+	set := &schemaSet{schemas: map[string]any{}, types: map[string]reflect.Type{}}
+	paths := map[string]map[string]any{}
+	for _, op := range Operations() {
+		item := paths[op.Path]
+		if item == nil {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = op.document(set)
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "PanGo",
+			"version": APIVersion,
+			"license": map[string]any{"name": "MIT"},
+		},
+		"servers": []any{map[string]any{"url": APIPrefix}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": set.schemas,
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+				"basic":  map[string]any{"type": "http", "scheme": "basic"},
+			},
+		},
+		"security": []any{map[string]any{}, map[string]any{"bearer": []string{}}, map[string]any{"basic": []string{}}},
+	}
+}
+
+// document returns the OpenAPI operation object of op, adding the named
+// types it uses to set.
+func (op Operation) document(set *schemaSet) map[string]any {
+	doc := map[string]any{"operationId": op.ID, "summary": op.Summary}
+	if op.Description != "" {
+		doc["description"] = op.Description
+	}
+	if len(op.Query) > 0 {
+		var params []any
+		for _, q := range op.Query {
+			params = append(params, map[string]any{
+				"name": q.Name, "in": "query", "description": q.Description,
+				"schema": map[string]any{"type": q.Type},
+			})
+		}
+		doc["parameters"] = params
+	}
+	if op.Request != nil {
+		content := map[string]any{"application/json": map[string]any{"schema": set.schema(reflect.TypeOf(op.Request))}}
+		if _, raw := op.Request.(json.RawMessage); raw {
+			content["application/yaml"] = map[string]any{"schema": map[string]any{"type": "string"}}
+		}
+		doc["requestBody"] = map[string]any{"required": true, "content": content}
+	}
+	ok := map[string]any{"description": http.StatusText(op.Status)}
+	if op.Response != nil {
+		ok["content"] = map[string]any{"application/json": map[string]any{"schema": set.schema(reflect.TypeOf(op.Response))}}
+	}
+	doc["responses"] = map[string]any{
+		fmt.Sprint(op.Status): ok,
+		"default": map[string]any{
+			"description": "Error, described in plain text",
+			"content":     map[string]any{"text/plain": map[string]any{"schema": map[string]any{"type": "string"}}},
+		},
+	}
+	return doc
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaSet collects the named types of the document under
+// components/schemas.
+type schemaSet struct {
+	schemas map[string]any
+	types   map[string]reflect.Type
+}
+
+// schema returns the JSON schema of t following encoding/json rules. Named
+// struct types are added to the set under their Go name and referenced.
+func (s *schemaSet) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+		if known, ok := s.types[t.Name()]; ok {
+			if known != t {
+				panic(fmt.Sprintf("web: API types %s and %s have the same name", known, t))
+			}
+			return ref
+		}
+		s.types[t.Name()] = t // before recursing, for self-referencing types
+		s.schemas[t.Name()] = s.object(t)
+		return ref
+	}
+	panic(fmt.Sprintf("web: no JSON schema for %s", t))
+}
+
+// object returns the schema of struct type t.
+func (s *schemaSet) object(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			// Embedded struct: its fields are promoted, as with encoding/json.
+			embedded := s.object(f.Type)
+			for k, v := range embedded["properties"].(map[string]any) {
+				props[k] = v
+			}
+			if req, ok := embedded["required"].([]string); ok {
+				required = append(required, req...)
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = s.schema(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+	obj := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		obj["required"] = required
+	}
+	return obj
+}
+
+// HandleOpenAPI handles GET /openapi.json: the OpenAPI document of the API.
+func (h *Handlers) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc := OpenAPI()
+	if h.BasePath != "" {
+		doc["servers"] = []any{map[string]any{"url": h.BasePath + APIPrefix}}
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOperations_Routed(t *testing.T) {
+	mux := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{}).Mux()
+	for _, op := range Operations() {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(op.Method, APIPrefix+op.Path, strings.NewReader("{}")))
+		if w.Code == http.StatusNotFound || w.Code == http.StatusMethodNotAllowed {
+			t.Errorf("%s %s%s (%s) is not routed: status %d", op.Method, APIPrefix, op.Path, op.ID, w.Code)
+		}
+	}
+}
+
+func TestOpenAPI_Document(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	w := httptest.NewRecorder()
+	h.HandleOpenAPI(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths map[string]map[string]struct {
+			OperationID string                            `json:"operationId"`
+			RequestBody *struct{ Content map[string]any } `json:"requestBody"`
+			Responses   map[string]any                    `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]any `json:"properties"`
+				Required   []string                  `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("openapi = %q", doc.OpenAPI)
+	}
+	if len(doc.Servers) != 1 || doc.Servers[0].URL != APIPrefix {
+		t.Errorf("servers = %+v, want %s", doc.Servers, APIPrefix)
+	}
+	for _, op := range Operations() {
+		got := doc.Paths[op.Path][strings.ToLower(op.Method)]
+		if got.OperationID != op.ID {
+			t.Errorf("%s %s: operationId = %q, want %q", op.Method, op.Path, got.OperationID, op.ID)
+		}
+	}
+
+	run := doc.Paths["/run"]["post"]
+	if _, ok := run.Responses["202"]; !ok {
+		t.Errorf("/run responses = %v, want 202", run.Responses)
+	}
+	if _, ok := doc.Paths["/config"]["put"].RequestBody.Content["application/yaml"]; !ok {
+		t.Error("PUT /config should accept YAML")
+	}
+
+	overrides := doc.Components.Schemas["Overrides"]
+	if overrides.Properties["focal_length_mm"]["type"] != "number" || len(overrides.Required) != 3 {
+		t.Errorf("Overrides schema = %+v", overrides)
+	}
+	status := doc.Components.Schemas["Status"]
+	if status.Properties["started_at"]["format"] != "date-time" || status.Properties["cell"]["$ref"] != "#/components/schemas/Cell" {
+		t.Errorf("Status schema = %+v", status)
+	}
+	for _, name := range status.Required {
+		if name == "started_at" || name == "last_error" {
+			t.Errorf("%s is optional (pointer or omitempty), listed as required", name)
+		}
+	}
+	if _, ok := doc.Components.Schemas["ConfigUpdate"].Properties["Form"]; ok {
+		t.Error(`fields tagged json:"-" must not be documented`)
+	}
+	upload := doc.Components.Schemas["ProfileUpload"]
+	if _, ok := upload.Properties["reinitialized"]; !ok || upload.Properties["ConfigUpdate"] != nil {
+		t.Errorf("embedded structs should be flattened, ProfileUpload schema = %+v", upload)
+	}
+}
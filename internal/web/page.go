@@ -0,0 +1,85 @@
+package web
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// PageInfo describes the rig in the index page, so that the interface
+// matches the hardware from the first paint rather than after API calls.
+type PageInfo struct {
+	Version      string       // PanGo build
+	Profile      string       // active configuration profile, e.g. "default"
+	CameraType   string       // camera.type, e.g. "gphoto2"
+	Capabilities Capabilities // what the rig can do
+}
+
+// Capabilities lists the optional features of the rig.
+type Capabilities struct {
+	LiveView bool // the camera streams frames for GET /liveview
+	Download bool // pictures are downloaded after each shot (GET /shots)
+	Trigger  bool // an external trigger input gates the shots
+	MockGPIO bool // motors and GPIO camera are simulated
+}
+
+// PageInfoFunc describes the rig as it currently is: a profile switch can
+// change the camera.
+type PageInfoFunc func() PageInfo
+
+// ServeIndex serves the main HTML page (root path only), index.html
+// rendered as an html/template with the PageInfo of the rig.
+func (h *Handlers) ServeIndex(w http.ResponseWriter, r *http.Request) {
+	src, ok := h.assets.lookup("index.html")
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	data, err := renderIndex(src.body, h.pageInfo())
+	if err != nil {
+		log.Printf("web: index page: %v", err)
+		http.Error(w, "index page unavailable", http.StatusInternalServerError)
+		return
+	}
+	data = h.assets.versionAssets(data)
+	if h.BasePath != "" {
+		data = withBase(data, h.BasePath)
+	}
+	w.Header().Set("Cache-Control", revalidate)
+	h.indexPage(data).serve(w, r)
+}
+
+// indexPage returns the asset of the rendered index page data, reused while
+// the page does not change (a profile switch, an edited override file):
+// hashing and compressing it costs far more than rendering it.
+func (h *Handlers) indexPage(data []byte) *asset {
+	h.indexMu.Lock()
+	defer h.indexMu.Unlock()
+	if h.index == nil || !bytes.Equal(h.index.body, data) {
+		h.index = newAsset("index.html", data)
+		h.index.contentType = "text/html; charset=utf-8"
+	}
+	return h.index
+}
+
+// pageInfo returns the description of the rig, empty when Page is unset.
+func (h *Handlers) pageInfo() PageInfo {
+	if h.Page == nil {
+		return PageInfo{}
+	}
+	return h.Page()
+}
+
+// renderIndex executes the index page template src with info.
+func renderIndex(src []byte, info PageInfo) ([]byte, error) {
+	tmpl, err := template.New("index.html").Parse(string(src))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, info); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// serveIndex renders the embedded index page with info.
+func serveIndex(t *testing.T, info *PageInfo) string {
+	t.Helper()
+	h := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{}).Handlers()
+	if info != nil {
+		h.Page = func() PageInfo { return *info }
+	}
+	w := httptest.NewRecorder()
+	h.ServeIndex(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	return w.Body.String()
+}
+
+func TestServeIndex_PageInfo(t *testing.T) {
+	body := serveIndex(t, &PageInfo{
+		Version:      "v1.4.0",
+		Profile:      "astro",
+		CameraType:   "gphoto2",
+		Capabilities: Capabilities{LiveView: true, Trigger: true},
+	})
+	for _, want := range []string{
+		"Capture control · astro</p>",
+		`<p class="rig-info">gphoto2 camera · external trigger · PanGo v1.4.0</p>`,
+		`aria-pressed="false">Live view</button>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page does not contain %q", want)
+		}
+	}
+	if strings.Contains(body, "rig-notice") || strings.Contains(body, "{{") {
+		t.Error("page shows the mock GPIO notice or template actions")
+	}
+}
+
+func TestServeIndex_Cached(t *testing.T) {
+	h := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{}).Handlers()
+	info := PageInfo{Profile: "wide"}
+	h.Page = func() PageInfo { return info }
+	serve := func() *asset {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeIndex(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+		}
+		return h.index
+	}
+	first := serve()
+	if again := serve(); again != first {
+		t.Error("unchanged page compressed again")
+	}
+	info.Profile = "tele"
+	if changed := serve(); changed == first || !strings.Contains(string(changed.body), "Capture control · tele") {
+		t.Error("page not rebuilt after a profile switch")
+	}
+}
+
+func TestServeIndex_Capabilities(t *testing.T) {
+	body := serveIndex(t, &PageInfo{CameraType: "nikon_d90_gpio", Capabilities: Capabilities{MockGPIO: true}})
+	if !strings.Contains(body, `aria-pressed="false" hidden>Live view</button>`) {
+		t.Error("live view button shown for a camera without live view")
+	}
+	if !strings.Contains(body, `<p class="rig-notice">`) {
+		t.Error("mock GPIO notice missing")
+	}
+}
+
+func TestServeIndex_NoPageInfo(t *testing.T) {
+	body := serveIndex(t, nil)
+	if !strings.Contains(body, "Capture control</p>") || strings.Contains(body, "rig-info") {
+		t.Error("page without rig description should show the plain subtitle")
+	}
+}
+
+func TestServeIndex_Escaped(t *testing.T) {
+	body := serveIndex(t, &PageInfo{Profile: `<script>alert(1)</script>`, CameraType: "gphoto2"})
+	if strings.Contains(body, "<script>alert") || !strings.Contains(body, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+		t.Error("profile name is not HTML-escaped")
+	}
+}
+
+func TestServeIndex_TemplateError(t *testing.T) {
+	h := NewHandlers(NewStatusBroadcaster(), noopCapture, FormConfig{}, fstest.MapFS{
+		"index.html": &fstest.MapFile{Data: []byte("<html>{{.Unknown}}</html>")},
+	})
+	w := httptest.NewRecorder()
+	h.ServeIndex(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+}
@@ -0,0 +1,172 @@
+package web
+
+import (
+	"context"
+	"sync"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// PanelMenu is what the front panel shows: its items, the one selected and
+// the outcome of the last action.
+type PanelMenu struct {
+	Items    []string `json:"items"`
+	Selected int      `json:"selected"`
+	Message  string   `json:"message,omitempty"`
+}
+
+// panelItem is an entry of the front panel menu: its label and what
+// pressing the button on it does, returning a message for the display.
+type panelItem struct {
+	label string
+	act   func(ctx context.Context) (string, error)
+}
+
+// Panel is the menu of a front panel (a rotary encoder with a push button,
+// and a display) driving the rig without the web UI: turning selects an
+// item, pressing acts on it, a long press cancels the running capture.
+// While idle, the items start a capture with the form defaults and select
+// presets (configuration profiles); while a capture runs, they cancel,
+// pause or resume it. Actions are local commands, as MQTT ones: they
+// respect control claims.
+type Panel struct {
+	h *Handlers
+
+	mu       sync.Mutex
+	running  bool // the menu of a running capture was last shown
+	selected int
+	message  string
+
+	changed chan struct{}
+}
+
+// NewPanel returns the front panel menu of h.
+func NewPanel(h *Handlers) *Panel {
+	return &Panel{h: h, changed: make(chan struct{}, 1)}
+}
+
+// Changed is signalled when the menu changes through the panel (a turn, an
+// action), for a display to redraw it at once.
+func (p *Panel) Changed() <-chan struct{} {
+	return p.changed
+}
+
+// notify signals Changed without blocking: a signal pending is enough.
+func (p *Panel) notify() {
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// items returns the entries of the menu in the current state of the rig,
+// and whether a capture runs.
+func (p *Panel) items() ([]panelItem, bool) {
+	h := p.h
+	if h.Jobs != nil {
+		if _, ok := h.Jobs.Running(); ok {
+			pause := panelItem{"Pause", func(ctx context.Context) (string, error) {
+				return "Paused", h.wsExecute(ctx, WSCommand{Cmd: "pause"}, "")
+			}}
+			if h.Lifecycle != nil && h.Lifecycle.Snapshot().State == capture.StatePaused {
+				pause = panelItem{"Resume", func(ctx context.Context) (string, error) {
+					return "Resumed", h.wsExecute(ctx, WSCommand{Cmd: "resume"}, "")
+				}}
+			}
+			return []panelItem{{"Cancel capture", p.cancel}, pause}, true
+		}
+	}
+
+	items := []panelItem{{"Start capture", p.start}}
+	if h.Profiles == nil {
+		return items, false
+	}
+	profiles, err := h.Profiles()
+	if err != nil {
+		return items, false
+	}
+	for _, name := range profiles.Available {
+		label := "Preset: " + name
+		if name == profiles.Active {
+			label += " *"
+		}
+		items = append(items, panelItem{label, func(context.Context) (string, error) {
+			return "Preset " + name + " active", h.useProfile(name)
+		}})
+	}
+	return items, false
+}
+
+// start starts a capture with the form defaults.
+func (p *Panel) start(ctx context.Context) (string, error) {
+	o, err := p.h.prepareRun("", Overrides{})
+	if err != nil {
+		return "", err
+	}
+	return "Capture started", p.h.wsExecute(ctx, WSCommand{Cmd: "run", Overrides: &o}, "")
+}
+
+// cancel cancels the running capture.
+func (p *Panel) cancel(ctx context.Context) (string, error) {
+	return "Capture cancelled", p.h.wsExecute(ctx, WSCommand{Cmd: "cancel"}, "")
+}
+
+// Menu returns what the panel shows now.
+func (p *Panel) Menu() PanelMenu {
+	items, running := p.items()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sync(len(items), running)
+	m := PanelMenu{Selected: p.selected, Message: p.message}
+	for _, it := range items {
+		m.Items = append(m.Items, it.label)
+	}
+	return m
+}
+
+// sync returns to the first item when the menu changed (a capture started
+// or ended) or the selection is past its end. p.mu must be held.
+func (p *Panel) sync(n int, running bool) {
+	if running != p.running || p.selected >= n {
+		p.running, p.selected = running, 0
+	}
+}
+
+// Turn moves the selection by n items, wrapping around.
+func (p *Panel) Turn(n int) {
+	items, running := p.items()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sync(len(items), running)
+	p.selected = ((p.selected+n)%len(items) + len(items)) % len(items)
+	p.notify()
+}
+
+// Press acts on the selected item. ctx bounds the capture it starts.
+func (p *Panel) Press(ctx context.Context) {
+	items, running := p.items()
+	p.mu.Lock()
+	p.sync(len(items), running)
+	it := items[p.selected]
+	p.mu.Unlock()
+
+	msg, err := it.act(ctx)
+	p.setMessage(msg, err)
+}
+
+// LongPress cancels the running capture.
+func (p *Panel) LongPress(ctx context.Context) {
+	msg, err := p.cancel(ctx)
+	p.setMessage(msg, err)
+}
+
+// setMessage records the outcome of an action: msg, or err if it failed.
+func (p *Panel) setMessage(msg string, err error) {
+	if err != nil {
+		msg = err.Error()
+	}
+	p.mu.Lock()
+	p.message = msg
+	p.mu.Unlock()
+	p.notify()
+}
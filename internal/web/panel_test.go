@@ -0,0 +1,138 @@
+package web
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// newTestPanel returns a panel on handlers with two profiles, wide active,
+// and a capture that runs until cancelled, sending its overrides to got.
+func newTestPanel(t *testing.T) (*Panel, *Handlers, chan Overrides) {
+	t.Helper()
+	got := make(chan Overrides, 1)
+	h := newTestHandlers(func(ctx context.Context, o Overrides) error {
+		got <- o
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	t.Cleanup(func() { h.Jobs.CancelRunning() })
+	h.Lifecycle = capture.NewLifecycle()
+	active := "wide"
+	h.Profiles = func() (Profiles, error) {
+		return Profiles{Active: active, Available: []string{"tele", "wide"}}, nil
+	}
+	h.ActivateProfile = func(name string) (ConfigUpdate, error) {
+		active = name
+		return ConfigUpdate{Form: FormConfig{HorizontalAngleDeg: 90, VerticalAngleDeg: 20, FocalLengthMm: 200}}, nil
+	}
+	return NewPanel(h), h, got
+}
+
+// waitRunning waits until a capture runs, or not.
+func waitRunning(t *testing.T, h *Handlers, want bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := h.Jobs.Running(); ok == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("running = %v, want %v", !want, want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// ---------- Menu ----------
+
+func TestPanel_IdleMenu(t *testing.T) {
+	p, _, _ := newTestPanel(t)
+	want := PanelMenu{Items: []string{"Start capture", "Preset: tele", "Preset: wide *"}}
+	if m := p.Menu(); !reflect.DeepEqual(m, want) {
+		t.Errorf("menu = %+v, want %+v", m, want)
+	}
+
+	cases := []struct {
+		turn int
+		want int
+	}{
+		{1, 1}, {1, 2}, {1, 0}, {-1, 2}, {-4, 1},
+	}
+	for _, tc := range cases {
+		p.Turn(tc.turn)
+		if got := p.Menu().Selected; got != tc.want {
+			t.Errorf("turn %d: selected %d, want %d", tc.turn, got, tc.want)
+		}
+		select {
+		case <-p.Changed():
+		default:
+			t.Errorf("turn %d: Changed not signalled", tc.turn)
+		}
+	}
+}
+
+func TestPanel_SelectPreset(t *testing.T) {
+	p, h, _ := newTestPanel(t)
+	p.Turn(1)
+	p.Press(context.Background())
+
+	m := p.Menu()
+	if m.Items[1] != "Preset: tele *" || m.Message != "Preset tele active" {
+		t.Errorf("menu = %+v, want tele active", m)
+	}
+	if h.FormDefaults.FocalLengthMm != 200 {
+		t.Errorf("form defaults = %+v, want the tele ones", h.FormDefaults)
+	}
+}
+
+// ---------- Capture ----------
+
+func TestPanel_StartPauseCancel(t *testing.T) {
+	p, h, got := newTestPanel(t)
+	p.Press(context.Background())
+	if o := <-got; o != (Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35}) {
+		t.Errorf("overrides = %+v, want the form defaults", o)
+	}
+	waitRunning(t, h, true)
+	if m := p.Menu(); !reflect.DeepEqual(m.Items, []string{"Cancel capture", "Pause"}) || m.Selected != 0 || m.Message != "Capture started" {
+		t.Errorf("menu = %+v, want the capture menu", m)
+	}
+
+	h.Lifecycle.Transition(capture.StatePlanning)
+	h.Lifecycle.Transition(capture.StateShooting)
+	p.Turn(1)
+	p.Press(context.Background())
+	if m := p.Menu(); m.Items[1] != "Resume" || m.Message != "Paused" {
+		t.Errorf("menu = %+v, want paused", m)
+	}
+
+	p.LongPress(context.Background())
+	waitRunning(t, h, false)
+	if m := p.Menu(); m.Items[0] != "Start capture" || m.Message != "Capture cancelled" {
+		t.Errorf("menu = %+v, want the idle menu", m)
+	}
+}
+
+func TestPanel_Errors(t *testing.T) {
+	p, h, _ := newTestPanel(t)
+	p.LongPress(context.Background())
+	if m := p.Menu(); m.Message != "no capture in progress" {
+		t.Errorf("message = %q, want no capture", m.Message)
+	}
+
+	if _, err := h.Control.Claim("", "studio", false); err != nil {
+		t.Fatal(err)
+	}
+	p.Press(context.Background())
+	if m := p.Menu(); !strings.Contains(m.Message, "studio") {
+		t.Errorf("message = %q, want the claim of studio", m.Message)
+	}
+	if _, ok := h.Jobs.Running(); ok {
+		t.Error("capture started despite the claim")
+	}
+}
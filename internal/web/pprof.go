@@ -0,0 +1,23 @@
+package web
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// PprofPath is where the runtime profiles of net/http/pprof are served when
+// enabled (SetPprof), behind the same authentication as the API.
+const PprofPath = "/debug/pprof/"
+
+// pprofRoutes lists the net/http/pprof handlers. Index also serves the named
+// profiles (heap, goroutine, block…) under PprofPath.
+func pprofRoutes() []route {
+	return []route{
+		{"GET " + PprofPath, http.HandlerFunc(pprof.Index)},
+		{"GET " + PprofPath + "cmdline", http.HandlerFunc(pprof.Cmdline)},
+		{"GET " + PprofPath + "profile", http.HandlerFunc(pprof.Profile)},
+		{"GET " + PprofPath + "symbol", http.HandlerFunc(pprof.Symbol)},
+		{"POST " + PprofPath + "symbol", http.HandlerFunc(pprof.Symbol)},
+		{"GET " + PprofPath + "trace", http.HandlerFunc(pprof.Trace)},
+	}
+}
@@ -0,0 +1,43 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ---------- pprof ----------
+
+func TestServer_Pprof(t *testing.T) {
+	get := func(h http.Handler, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	srv.SetAuth(AuthConfig{Token: "0123456789abcdef"})
+	if w := get(srv.Mux(), PprofPath, "0123456789abcdef"); w.Code != http.StatusNotFound {
+		t.Errorf("disabled: status %d, want 404", w.Code)
+	}
+
+	srv.SetPprof(true)
+	srv.SetBasePath("/pango")
+	mux := srv.Mux()
+	if w := get(mux, "/pango"+PprofPath, ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without credentials: status %d, want 401", w.Code)
+	}
+	w := get(mux, "/pango"+PprofPath, "0123456789abcdef")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine") {
+		t.Errorf("index: status %d", w.Code)
+	}
+	if w := get(mux, "/pango"+PprofPath+"goroutine?debug=1", "0123456789abcdef"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "goroutine profile") {
+		t.Errorf("goroutine profile: status %d", w.Code)
+	}
+}
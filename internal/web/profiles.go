@@ -0,0 +1,195 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrProfileNotFound is returned by an ActivateProfileFunc for unknown profiles.
+	ErrProfileNotFound = errors.New("profile not found")
+	// ErrProfileExists is returned by an UploadProfileFunc when the profile
+	// exists and replace is not set.
+	ErrProfileExists = errors.New("profile already exists")
+)
+
+// Profiles lists the configuration profiles for GET /profiles.
+type Profiles struct {
+	Active    string   `json:"active"`
+	Available []string `json:"available"`
+}
+
+// ProfilesFunc lists the configuration profiles.
+type ProfilesFunc func() (Profiles, error)
+
+// ActivateProfileFunc loads the named profile and makes it the configuration
+// of the following captures, re-initializing the hardware when needed.
+// Invalid profiles wrap ErrInvalidConfig; it fails with ErrHeadBusy when the
+// hardware must change while a capture or jog is running.
+type ActivateProfileFunc func(name string) (ConfigUpdate, error)
+
+// UploadProfileFunc validates a configuration document (YAML or JSON) and
+// stores it as profile name, then activates it if asked (see
+// ActivateProfileFunc). Invalid names or documents wrap ErrInvalidConfig.
+type UploadProfileFunc func(name string, data []byte, replace, activate bool) (ProfileUpload, error)
+
+// SaveDefaultsFunc writes the values of a capture form (angles, focal
+// length, delays) and the overlap of the active configuration into profile
+// name ("file" or "file/entry"; "" = the active profile), creating a file
+// profile that does not exist yet. Saving into the active profile makes the
+// values its new defaults. Invalid values wrap ErrInvalidConfig, unknown
+// profile entries ErrProfileNotFound.
+type SaveDefaultsFunc func(name string, overrides Overrides) (SavedDefaults, error)
+
+// SavedDefaults is the outcome of POST /config/defaults. When Active, the
+// embedded ConfigUpdate describes the reload of the active profile.
+type SavedDefaults struct {
+	Profile string `json:"profile"`
+	Created bool   `json:"created"` // a new profile file was written
+	Active  bool   `json:"active"`  // the active profile was updated and reloaded
+	ConfigUpdate
+}
+
+// ProfileUpload is the outcome of POST /config/upload. When Activated, the
+// embedded ConfigUpdate describes the activation.
+type ProfileUpload struct {
+	Profile   string `json:"profile"`
+	Activated bool   `json:"activated"`
+	ConfigUpdate
+}
+
+// HandleListProfiles handles GET /profiles.
+func (h *Handlers) HandleListProfiles(w http.ResponseWriter, r *http.Request) {
+	if h.Profiles == nil {
+		http.Error(w, "profiles not available", http.StatusServiceUnavailable)
+		return
+	}
+	profiles, err := h.Profiles()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, profiles)
+}
+
+// HandleActivateProfile handles POST /profiles/{name}/activate.
+func (h *Handlers) HandleActivateProfile(w http.ResponseWriter, r *http.Request) {
+	if h.ActivateProfile == nil {
+		http.Error(w, "profiles not available", http.StatusServiceUnavailable)
+		return
+	}
+	update, err := h.ActivateProfile(r.PathValue("name"))
+	if err != nil {
+		writeConfigError(w, err)
+		return
+	}
+	h.SetFormDefaults(update.Form)
+	writeJSON(w, http.StatusOK, update)
+}
+
+// HandleUploadProfile handles POST /config/upload: stores a configuration
+// file as a new profile, next to the active one. The body is the file, either
+// raw or as the "file" field of a multipart form (browser upload). The
+// profile name comes from the name parameter, or else from the uploaded file
+// name; replace=true overwrites an existing profile and activate=true
+// switches to it.
+func (h *Handlers) HandleUploadProfile(w http.ResponseWriter, r *http.Request) {
+	if h.UploadProfile == nil {
+		http.Error(w, "profiles not available", http.StatusServiceUnavailable)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	var data []byte
+	param := r.URL.Query().Get
+	name := param("name")
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, "multipart upload must have a file field", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		if data, err = io.ReadAll(file); err != nil {
+			http.Error(w, "read uploaded file: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		param = r.FormValue // query string, then form fields
+		if name == "" {
+			name = param("name")
+		}
+		if name == "" {
+			name = header.Filename
+			for _, ext := range []string{".yaml", ".yml", ".json", ".toml"} {
+				name = strings.TrimSuffix(name, ext)
+			}
+		}
+	} else {
+		var err error
+		if data, err = io.ReadAll(r.Body); err != nil {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+	}
+	if name == "" {
+		http.Error(w, "profile name required", http.StatusBadRequest)
+		return
+	}
+	replace, err := boolParam("replace", param("replace"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	activate, err := boolParam("activate", param("activate"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	upload, err := h.UploadProfile(name, data, replace, activate)
+	if err != nil {
+		writeConfigError(w, err)
+		return
+	}
+	if upload.Activated {
+		h.SetFormDefaults(upload.Form)
+	}
+	writeJSON(w, http.StatusCreated, upload)
+}
+
+// HandleSaveDefaults handles POST /config/defaults: saves the capture
+// parameters of the body (as for POST /run) as the defaults of the profile
+// named by the profile parameter, the active one when empty, so that a setup
+// dialed in from the form survives a restart.
+func (h *Handlers) HandleSaveDefaults(w http.ResponseWriter, r *http.Request) {
+	if h.SaveDefaults == nil {
+		http.Error(w, "profiles not available", http.StatusServiceUnavailable)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	var overrides Overrides
+	if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if err := ValidateOverrides(overrides); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.SaveDefaults(r.URL.Query().Get("profile"), overrides)
+	if err != nil {
+		writeConfigError(w, err)
+		return
+	}
+	if saved.Active {
+		h.SetFormDefaults(saved.Form)
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
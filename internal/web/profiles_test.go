@@ -0,0 +1,284 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ---------- HandleListProfiles ----------
+
+func TestHandleListProfiles(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Profiles = func() (Profiles, error) {
+		return Profiles{Active: "wide-18mm", Available: []string{"tele-200mm", "wide-18mm"}}, nil
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleListProfiles(w, httptest.NewRequest(http.MethodGet, "/profiles", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var got Profiles
+	json.NewDecoder(w.Body).Decode(&got)
+	if got.Active != "wide-18mm" || len(got.Available) != 2 {
+		t.Errorf("profiles = %+v", got)
+	}
+
+	h.Profiles = nil
+	w = httptest.NewRecorder()
+	h.HandleListProfiles(w, httptest.NewRequest(http.MethodGet, "/profiles", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status without Profiles = %d, want 503", w.Code)
+	}
+}
+
+// ---------- HandleActivateProfile ----------
+
+func TestHandleActivateProfile(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	var got string
+	h.ActivateProfile = func(name string) (ConfigUpdate, error) {
+		got = name
+		return ConfigUpdate{Reinitialized: true, Form: FormConfig{HorizontalAngleDeg: 360, VerticalAngleDeg: 90, FocalLengthMm: 18}}, nil
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/profiles/wide-18mm/activate", nil)
+	req.SetPathValue("name", "wide-18mm")
+	w := httptest.NewRecorder()
+	h.HandleActivateProfile(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if got != "wide-18mm" {
+		t.Errorf("activated %q, want wide-18mm", got)
+	}
+	var update ConfigUpdate
+	json.NewDecoder(w.Body).Decode(&update)
+	if !update.Reinitialized {
+		t.Errorf("response = %+v", update)
+	}
+	if h.FormDefaults.FocalLengthMm != 18 {
+		t.Errorf("form defaults = %+v, want the profile's", h.FormDefaults)
+	}
+}
+
+func TestHandleActivateProfile_Errors(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not_found", fmt.Errorf("%w: %q", ErrProfileNotFound, "nope"), http.StatusNotFound},
+		{"invalid", fmt.Errorf("%w: lens.focal_length_mm is required", ErrInvalidConfig), http.StatusBadRequest},
+		{"busy", ErrHeadBusy, http.StatusConflict},
+		{"other", fmt.Errorf("init camera: unsupported camera type"), http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(noopCapture)
+			h.ActivateProfile = func(string) (ConfigUpdate, error) { return ConfigUpdate{}, tc.err }
+			w := httptest.NewRecorder()
+			h.HandleActivateProfile(w, httptest.NewRequest(http.MethodPost, "/profiles/x/activate", nil))
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d", w.Code, tc.want)
+			}
+		})
+	}
+}
+
+// ---------- HandleUploadProfile ----------
+
+// uploadCall records the arguments of an UploadProfileFunc call.
+type uploadCall struct {
+	name              string
+	data              string
+	replace, activate bool
+}
+
+func TestHandleUploadProfile(t *testing.T) {
+	multipartBody := func(filename, content string, fields map[string]string) (*bytes.Buffer, string) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		for k, v := range fields {
+			mw.WriteField(k, v)
+		}
+		fw, _ := mw.CreateFormFile("file", filename)
+		io.WriteString(fw, content)
+		mw.Close()
+		return &buf, mw.FormDataContentType()
+	}
+
+	cases := []struct {
+		name string
+		req  func() *http.Request
+		want uploadCall
+	}{
+		{"raw", func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/config/upload?name=tele&activate=true", strings.NewReader("lens: {}"))
+		}, uploadCall{name: "tele", data: "lens: {}", activate: true}},
+		{"multipart_filename", func() *http.Request {
+			body, ct := multipartBody("wide-18mm.yaml", "lens: {}", map[string]string{"replace": "true"})
+			req := httptest.NewRequest(http.MethodPost, "/config/upload", body)
+			req.Header.Set("Content-Type", ct)
+			return req
+		}, uploadCall{name: "wide-18mm", data: "lens: {}", replace: true}},
+		{"multipart_name_field", func() *http.Request {
+			body, ct := multipartBody("rig.yml", "lens: {}", map[string]string{"name": "night", "activate": "1"})
+			req := httptest.NewRequest(http.MethodPost, "/config/upload", body)
+			req.Header.Set("Content-Type", ct)
+			return req
+		}, uploadCall{name: "night", data: "lens: {}", activate: true}},
+		{"multipart_json_filename", func() *http.Request {
+			body, ct := multipartBody("generated.json", `{"lens":{}}`, nil)
+			req := httptest.NewRequest(http.MethodPost, "/config/upload", body)
+			req.Header.Set("Content-Type", ct)
+			return req
+		}, uploadCall{name: "generated", data: `{"lens":{}}`}},
+		{"multipart_toml_filename", func() *http.Request {
+			body, ct := multipartBody("rig.toml", "[lens]\n", nil)
+			req := httptest.NewRequest(http.MethodPost, "/config/upload", body)
+			req.Header.Set("Content-Type", ct)
+			return req
+		}, uploadCall{name: "rig", data: "[lens]\n"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(noopCapture)
+			var got uploadCall
+			h.UploadProfile = func(name string, data []byte, replace, activate bool) (ProfileUpload, error) {
+				got = uploadCall{name, string(data), replace, activate}
+				return ProfileUpload{Profile: name, Activated: activate, ConfigUpdate: ConfigUpdate{Form: FormConfig{FocalLengthMm: 200}}}, nil
+			}
+			w := httptest.NewRecorder()
+			h.HandleUploadProfile(w, tc.req())
+			if w.Code != http.StatusCreated {
+				t.Fatalf("status = %d, want 201: %s", w.Code, w.Body.String())
+			}
+			if got != tc.want {
+				t.Errorf("upload call = %+v, want %+v", got, tc.want)
+			}
+			var result ProfileUpload
+			json.NewDecoder(w.Body).Decode(&result)
+			if result.Profile != tc.want.name || result.Activated != tc.want.activate {
+				t.Errorf("response = %+v", result)
+			}
+			if activated := h.FormDefaults.FocalLengthMm == 200; activated != tc.want.activate {
+				t.Errorf("form defaults = %+v; must change only on activation", h.FormDefaults)
+			}
+		})
+	}
+}
+
+func TestHandleUploadProfile_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		target string
+		err    error
+		want   int
+	}{
+		{"no_name", "/config/upload", nil, http.StatusBadRequest},
+		{"bad_bool", "/config/upload?name=x&activate=maybe", nil, http.StatusBadRequest},
+		{"invalid", "/config/upload?name=x", fmt.Errorf("%w: unmarshal yaml", ErrInvalidConfig), http.StatusBadRequest},
+		{"exists", "/config/upload?name=x", fmt.Errorf("%w: %q", ErrProfileExists, "x"), http.StatusConflict},
+		{"busy", "/config/upload?name=x&activate=true", ErrHeadBusy, http.StatusConflict},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(noopCapture)
+			h.UploadProfile = func(string, []byte, bool, bool) (ProfileUpload, error) {
+				return ProfileUpload{}, tc.err
+			}
+			w := httptest.NewRecorder()
+			h.HandleUploadProfile(w, httptest.NewRequest(http.MethodPost, tc.target, strings.NewReader("lens: {}")))
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d", w.Code, tc.want)
+			}
+		})
+	}
+
+	h := newTestHandlers(noopCapture)
+	w := httptest.NewRecorder()
+	h.HandleUploadProfile(w, httptest.NewRequest(http.MethodPost, "/config/upload?name=x", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status without UploadProfile = %d, want 503", w.Code)
+	}
+}
+
+// ---------- HandleSaveDefaults ----------
+
+func TestHandleSaveDefaults(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	var gotName string
+	var got Overrides
+	h.SaveDefaults = func(name string, o Overrides) (SavedDefaults, error) {
+		gotName, got = name, o
+		return SavedDefaults{Profile: "default", Active: true, ConfigUpdate: ConfigUpdate{Saved: true, Form: FormConfig{HorizontalAngleDeg: 270, VerticalAngleDeg: 45, FocalLengthMm: 50}}}, nil
+	}
+
+	body := `{"horizontal_angle_deg":270,"vertical_angle_deg":45,"focal_length_mm":50,"stabilization_delay_ms":800}`
+	w := httptest.NewRecorder()
+	h.HandleSaveDefaults(w, httptest.NewRequest(http.MethodPost, "/config/defaults", strings.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	want := Overrides{HorizontalAngleDeg: 270, VerticalAngleDeg: 45, FocalLengthMm: 50, StabilizationDelayMs: 800}
+	if gotName != "" || got != want {
+		t.Errorf("saved %+v to %q, want %+v to the active profile", got, gotName, want)
+	}
+	var saved SavedDefaults
+	json.NewDecoder(w.Body).Decode(&saved)
+	if saved.Profile != "default" || !saved.Active || !saved.Saved {
+		t.Errorf("response = %+v", saved)
+	}
+	if h.FormDefaults.HorizontalAngleDeg != 270 {
+		t.Errorf("form defaults = %+v, want the saved ones", h.FormDefaults)
+	}
+
+	h.HandleSaveDefaults(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/config/defaults?profile=default/tele", strings.NewReader(body)))
+	if gotName != "default/tele" {
+		t.Errorf("saved to %q, want default/tele", gotName)
+	}
+}
+
+func TestHandleSaveDefaults_Errors(t *testing.T) {
+	valid := string(validOverridesJSON())
+	cases := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"bad_json", "{", nil, http.StatusBadRequest},
+		{"invalid_overrides", `{"horizontal_angle_deg":0,"vertical_angle_deg":30,"focal_length_mm":35}`, nil, http.StatusBadRequest},
+		{"invalid_config", valid, fmt.Errorf("%w: overlap_percent", ErrInvalidConfig), http.StatusBadRequest},
+		{"not_found", valid, fmt.Errorf("%w: %q", ErrProfileNotFound, "default/nope"), http.StatusNotFound},
+		{"busy", valid, ErrHeadBusy, http.StatusConflict},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(noopCapture)
+			h.SaveDefaults = func(string, Overrides) (SavedDefaults, error) { return SavedDefaults{}, tc.err }
+			w := httptest.NewRecorder()
+			h.HandleSaveDefaults(w, httptest.NewRequest(http.MethodPost, "/config/defaults", strings.NewReader(tc.body)))
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d", w.Code, tc.want)
+			}
+		})
+	}
+
+	h := newTestHandlers(noopCapture)
+	w := httptest.NewRecorder()
+	h.HandleSaveDefaults(w, httptest.NewRequest(http.MethodPost, "/config/defaults", strings.NewReader(valid)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status without SaveDefaults = %d, want 503", w.Code)
+	}
+}
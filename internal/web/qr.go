@@ -0,0 +1,58 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/cjeanneret/PanGo/internal/qr"
+)
+
+// Pixels per module of GET /qr?format=png: default and maximum.
+const (
+	defaultQRScale = 8
+	maxQRScale     = 32
+)
+
+// HandleQR handles GET /qr: a QR code of the interface URL (PublicURL, or
+// the URL of the request), so that a phone opens the interface by scanning
+// a laptop screen. ?format=svg (default) or png; ?scale sets the pixels per
+// module of the PNG (1-32, default 8). Credentials are never encoded.
+func (h *Handlers) HandleQR(w http.ResponseWriter, r *http.Request) {
+	url := h.PublicURL
+	if url == "" {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		url = scheme + "://" + r.Host + h.BasePath + "/"
+	}
+	code, err := qr.Encode(url)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "svg":
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write([]byte(code.SVG()))
+	case "png":
+		scale := defaultQRScale
+		if s := r.URL.Query().Get("scale"); s != "" {
+			if scale, err = strconv.Atoi(s); err != nil || scale < 1 || scale > maxQRScale {
+				http.Error(w, "scale must be 1-"+strconv.Itoa(maxQRScale), http.StatusBadRequest)
+				return
+			}
+		}
+		img, err := code.PNG(scale)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(img)
+	default:
+		http.Error(w, "format must be svg or png", http.StatusBadRequest)
+	}
+}
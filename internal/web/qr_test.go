@@ -0,0 +1,62 @@
+package web
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/qr"
+)
+
+// ---------- GET /qr ----------
+
+func TestHandleQR(t *testing.T) {
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	srv.SetBasePath("/pango")
+	mux := srv.Mux()
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://pi.lan:8080"+path, nil))
+		return w
+	}
+	svgOf := func(url string) string {
+		code, err := qr.Encode(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code.SVG()
+	}
+
+	// URL of the request, with the base path
+	w := get("/pango/qr")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("status %d, Content-Type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w.Body.String() != svgOf("http://pi.lan:8080/pango/") {
+		t.Error("SVG does not encode the request URL")
+	}
+
+	srv.Handlers().PublicURL = "https://pango.local:8443/pango/"
+	if w := get("/pango/qr"); w.Body.String() != svgOf("https://pango.local:8443/pango/") {
+		t.Error("SVG does not encode PublicURL")
+	}
+
+	w = get("/pango/qr?format=png&scale=2")
+	img, err := png.Decode(bytes.NewReader(w.Body.Bytes()))
+	if w.Code != http.StatusOK || err != nil {
+		t.Fatalf("png: status %d, %v", w.Code, err)
+	}
+	code, _ := qr.Encode("https://pango.local:8443/pango/")
+	if side := (code.Size + 8) * 2; img.Bounds().Dx() != side {
+		t.Errorf("png width %d, want %d", img.Bounds().Dx(), side)
+	}
+
+	for _, q := range []string{"?format=gif", "?format=png&scale=0", "?format=png&scale=64", "?format=png&scale=big"} {
+		if w := get("/pango/qr" + q); w.Code != http.StatusBadRequest {
+			t.Errorf("GET /qr%s: status %d, want 400", q, w.Code)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package web
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Number of tracked clients above which idle ones are forgotten.
+const rateLimitPruneAt = 1024
+
+// Limits configures request rate limits and capture spacing.
+type Limits struct {
+	// CaptureSpacing is the minimum delay between two capture starts
+	// (0 = default, 5 s). Protects motors and camera from rapid restarts.
+	CaptureSpacing time.Duration
+	// RequestsPerMinute limits requests per client IP for the given routes,
+	// keyed by pattern as registered ("POST /run", "GET /jobs/{id}").
+	RequestsPerMinute map[string]int
+}
+
+// RateLimit wraps next to accept at most perMinute requests per minute from
+// each client IP, with bursts up to perMinute. Excess requests get 429 with
+// a Retry-After header.
+func RateLimit(next http.Handler, perMinute int) http.Handler {
+	l := &rateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   float64(perMinute),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait := l.take(clientIP(r)); wait > 0 {
+			setRetryAfter(w, wait)
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimiter is a token bucket per client.
+type rateLimiter struct {
+	rate  float64 // tokens per second
+	burst float64
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take consumes a token for client. It returns 0 when allowed, or how long
+// to wait for the next token.
+func (l *rateLimiter) take(client string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if len(l.buckets) >= rateLimitPruneAt {
+		l.pruneLocked(now)
+	}
+	b := l.buckets[client]
+	if b == nil {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// pruneLocked forgets clients whose bucket has refilled: they are back to
+// the initial state.
+func (l *rateLimiter) pruneLocked(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// setRetryAfter sets the Retry-After header to d, rounded up to seconds.
+func setRetryAfter(w http.ResponseWriter, d time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.Seconds()))))
+}
@@ -0,0 +1,100 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	h := RateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), 2)
+	get := func(addr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/status", nil)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := get("192.0.2.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200 within the burst", i+1, w.Code)
+		}
+	}
+	w := get("192.0.2.1:5678")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: status = %d, want 429", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30 (one token every 30 s)", got)
+	}
+	if w := get("192.0.2.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("other client: status = %d, want 200", w.Code)
+	}
+}
+
+func TestRateLimiter_Refill(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := &rateLimiter{rate: 1, burst: 1, buckets: map[string]*bucket{}, now: func() time.Time { return now }}
+
+	if wait := l.take("a"); wait != 0 {
+		t.Fatalf("first take: wait = %v, want 0", wait)
+	}
+	if wait := l.take("a"); wait != time.Second {
+		t.Errorf("empty bucket: wait = %v, want 1s", wait)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if wait := l.take("a"); wait != 500*time.Millisecond {
+		t.Errorf("half refilled: wait = %v, want 500ms", wait)
+	}
+	now = now.Add(time.Second)
+	if wait := l.take("a"); wait != 0 {
+		t.Errorf("refilled: wait = %v, want 0", wait)
+	}
+
+	now = now.Add(time.Hour)
+	l.pruneLocked(now)
+	if len(l.buckets) != 0 {
+		t.Errorf("buckets after prune = %d, want 0", len(l.buckets))
+	}
+}
+
+func TestServer_SetLimits(t *testing.T) {
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+
+	if err := srv.SetLimits(Limits{RequestsPerMinute: map[string]int{"POST /nope": 1}}); err == nil {
+		t.Error("unknown route: want error")
+	}
+	if err := srv.SetLimits(Limits{RequestsPerMinute: map[string]int{"GET " + APIPrefix + "/status": 1}}); err == nil || !strings.Contains(err.Error(), `"GET /status"`) {
+		t.Errorf("versioned route: err = %v, want a hint to name GET /status", err)
+	}
+	if err := srv.SetLimits(Limits{RequestsPerMinute: map[string]int{"GET /status": -1}}); err == nil {
+		t.Error("negative count: want error")
+	}
+
+	err := srv.SetLimits(Limits{CaptureSpacing: 2 * time.Second, RequestsPerMinute: map[string]int{"GET /config": 1}})
+	if err != nil {
+		t.Fatalf("SetLimits: %v", err)
+	}
+	if got := srv.Handlers().Jobs.MinSpacing; got != 2*time.Second {
+		t.Errorf("MinSpacing = %v, want 2s", got)
+	}
+	// Both paths of a route share its budget
+	mux := srv.Mux()
+	for i, path := range []string{"/config", APIPrefix + "/config"} {
+		want := []int{http.StatusOK, http.StatusTooManyRequests}[i]
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Errorf("GET %s #%d: status = %d, want %d", path, i+1, w.Code, want)
+		}
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("unlimited route: status = %d, want 200", w.Code)
+	}
+}
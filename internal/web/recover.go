@@ -0,0 +1,72 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// SafeStopFunc brings the hardware to a safe state after a panic: motors
+// disabled and camera trigger lines released, so that the shutter is not
+// left held. It must not wait for the head to be free.
+type SafeStopFunc func()
+
+// PanicError is the error of a capture or request that panicked.
+type PanicError struct {
+	Value any // value passed to panic
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("internal error (panic): %v", e.Value)
+}
+
+// recovered handles the panic v in what: logs it with the stack of the
+// panicking goroutine and stops the hardware with h.SafeStop. Call it from
+// the deferred function that recovered v.
+func (h *Handlers) recovered(what string, v any) {
+	log.Printf("panic in %s: %v\n%s", what, v, debug.Stack())
+	if h.SafeStop != nil {
+		h.SafeStop()
+	}
+}
+
+// Recover wraps next so that a panic in a handler does not leave the
+// hardware running: it is logged, the hardware is stopped with h.SafeStop,
+// status clients get an error event and the client gets 500 Internal
+// Server Error. http.ErrAbortHandler, the way handlers abort a response,
+// is passed on.
+func (h *Handlers) Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if err, ok := v.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				panic(v)
+			}
+			h.recovered(r.Method+" "+r.URL.Path, v)
+			if h.Broadcaster != nil {
+				h.Broadcaster.BroadcastRequest(RequestID(r.Context()), "error",
+					fmt.Sprintf("Internal error in %s %s: motors disabled", r.Method, r.URL.Path))
+			}
+			// Fails silently when the response has started.
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// protect runs fn and returns a *PanicError when it panics, after
+// h.recovered.
+func (h *Handlers) protect(what string, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			h.recovered(what, v)
+			err = &PanicError{Value: v}
+		}
+	}()
+	return fn()
+}
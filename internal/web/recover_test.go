@@ -0,0 +1,89 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// ---------- Recover ----------
+
+func TestRecover(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	var stops atomic.Int32
+	h.SafeStop = func() { stops.Add(1) }
+	handler := h.Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/abort" {
+			panic(http.ErrAbortHandler)
+		}
+		panic("nil map")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/jog", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if stops.Load() != 1 {
+		t.Errorf("SafeStop called %d times, want 1", stops.Load())
+	}
+	events := h.Broadcaster.Recent(1)
+	if len(events) != 1 || events[0].Level != "error" || !strings.Contains(events[0].Msg, "POST /jog") {
+		t.Errorf("events = %+v, want an error about POST /jog", events)
+	}
+
+	defer func() {
+		if v := recover(); v != http.ErrAbortHandler {
+			t.Errorf("recovered %v, want http.ErrAbortHandler passed on", v)
+		}
+		if stops.Load() != 1 {
+			t.Error("SafeStop called for http.ErrAbortHandler")
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abort", nil))
+}
+
+func TestJobQueue_Panic(t *testing.T) {
+	h := newTestHandlers(func(context.Context, Overrides) error {
+		panic("index out of range")
+	})
+	h.Jobs.MinSpacing = 0
+	var stops atomic.Int32
+	h.SafeStop = func() { stops.Add(1) }
+
+	job, err := h.Jobs.Enqueue(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35})
+	if err != nil {
+		t.Fatal(err)
+	}
+	job = waitJobStatus(t, h.Jobs, job.ID, JobFailed)
+	if !strings.Contains(job.Error, "panic") || !strings.Contains(job.Error, "index out of range") {
+		t.Errorf("job error = %q, want the panic", job.Error)
+	}
+	if stops.Load() != 1 {
+		t.Errorf("SafeStop called %d times, want 1", stops.Load())
+	}
+	events := h.Broadcaster.Recent(1)
+	if len(events) != 1 || events[0].Level != "error" {
+		t.Errorf("events = %+v, want a capture failure", events)
+	}
+}
+
+func TestProtect(t *testing.T) {
+	h := newTestHandlers(nil)
+	if err := h.protect("test", func() error { return nil }); err != nil {
+		t.Errorf("protect = %v, want nil", err)
+	}
+	want := errors.New("failed")
+	if err := h.protect("test", func() error { return want }); err != want {
+		t.Errorf("protect = %v, want %v", err, want)
+	}
+	var perr *PanicError
+	err := h.protect("test", func() error { panic(42) })
+	if !errors.As(err, &perr) || perr.Value != 42 {
+		t.Errorf("protect = %v, want a *PanicError of 42", err)
+	}
+}
@@ -1,19 +1,52 @@
 package web
 
 import (
+	"bufio"
 	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
 // Server wraps the HTTP server and handlers.
 type Server struct {
-	addr     string
-	handlers *Handlers
+	addr       string         // "" = no TCP listener
+	unixSocket string         // "" = no Unix socket listener
+	listeners  []net.Listener // replace addr, e.g. from socket activation
+	handlers   *Handlers
+	auth       AuthConfig
+	tls        TLSConfig
+	cors       CORSConfig
+	limits     Limits
+	accessLog  io.Writer // nil = no access log
+	basePath   string
+	grpcAddr   string // "" = no gRPC service
+	onvif      bool   // serve the ONVIF services under ONVIFPath
+	pprof      bool   // serve pprofRoutes
+	timeouts   Timeouts
+
+	onReady    []func()
+	onShutdown []func()
+
+	mu         sync.Mutex
+	serving    []net.Listener // while Run serves, for Alive
+	servingTLS bool
 }
 
+// How long shutdown waits for the running capture to finish its current
+// shot. Long enough for a bulb exposure, short enough for systemd's default
+// stop timeout (90 s).
+const captureDrainTimeout = 60 * time.Second
+
 // NewServer creates a server configured for the given address and dependencies.
 func NewServer(addr string, broadcaster *StatusBroadcaster, runCapture RunCaptureFunc, formDefaults FormConfig) *Server {
 	subFS, err := fs.Sub(staticFiles, "static")
@@ -24,23 +57,274 @@ func NewServer(addr string, broadcaster *StatusBroadcaster, runCapture RunCaptur
 	handlers := NewHandlers(broadcaster, runCapture, formDefaults, subFS)
 
 	return &Server{
-		addr:     addr,
-		handlers: handlers,
+		addr:      addr,
+		handlers:  handlers,
+		accessLog: os.Stderr,
+	}
+}
+
+// Handlers returns the server's handlers, to set optional dependencies
+// (e.g. Lifecycle) before Run.
+func (s *Server) Handlers() *Handlers {
+	return s.handlers
+}
+
+// SetAuth requires authentication on every route (see AuthConfig).
+func (s *Server) SetAuth(auth AuthConfig) {
+	s.auth = auth
+}
+
+// SetAccessLog sets where access log lines are written (default: standard
+// error). nil disables the access log and request IDs.
+func (s *Server) SetAccessLog(w io.Writer) {
+	s.accessLog = w
+}
+
+// SetCORS allows browsers on other origins to call the API (see CORSConfig).
+func (s *Server) SetCORS(c CORSConfig) {
+	s.cors = c
+}
+
+// SetLimits configures request rate limits and the capture spacing (see
+// Limits). It fails when a rate limit names an unknown route.
+func (s *Server) SetLimits(l Limits) error {
+	known := map[string]bool{}
+	for _, rt := range append(s.routes(), s.pages()...) {
+		known[rt.pattern] = true
+	}
+	for pattern, n := range l.RequestsPerMinute {
+		if !known[pattern] {
+			if p := strings.Replace(pattern, " "+APIPrefix+"/", " /", 1); known[p] {
+				return fmt.Errorf("rate limit for %q: name the route without %s (%q)", pattern, APIPrefix, p)
+			}
+			return fmt.Errorf("rate limit for unknown route %q", pattern)
+		}
+		if n < 0 {
+			return fmt.Errorf("rate limit for %q must not be negative, got %d", pattern, n)
+		}
+	}
+	s.limits = l
+	if l.CaptureSpacing > 0 && s.handlers.Jobs != nil {
+		s.handlers.Jobs.MinSpacing = l.CaptureSpacing
+	}
+	return nil
+}
+
+// RegisterOnShutdown registers f to run during shutdown, once the running
+// capture has stopped and before the final status event (e.g. to park the
+// head).
+func (s *Server) RegisterOnShutdown(f func()) {
+	s.onShutdown = append(s.onShutdown, f)
+}
+
+// RegisterOnReady registers f to run once the server listens, before it
+// serves the first request (e.g. to notify a service manager).
+func (s *Server) RegisterOnReady(f func()) {
+	s.onReady = append(s.onReady, f)
+}
+
+// SetListeners serves the interface on already open listeners (e.g. passed
+// by systemd socket activation) instead of the server address. Listeners on
+// TCP use TLS when configured.
+func (s *Server) SetListeners(listeners []net.Listener) {
+	s.listeners = listeners
+}
+
+// SetBasePath serves the interface under base, e.g. "/pango" behind a
+// reverse proxy (see StripBasePath). base starts with "/" and has no
+// trailing slash; "" serves at the root.
+func (s *Server) SetBasePath(base string) {
+	s.basePath = base
+	s.handlers.BasePath = base
+}
+
+// SetGRPC also serves the gRPC service (GRPCService) on addr, e.g.
+// ":50051": over TLS when the web interface uses it, over unencrypted
+// HTTP/2 otherwise.
+func (s *Server) SetGRPC(addr string) {
+	s.grpcAddr = addr
+}
+
+// SetONVIF also serves the ONVIF device, media and PTZ services under
+// ONVIFPath (see Handlers.ONVIFHandler), for video management software to
+// pan and tilt the head with its PTZ controls.
+func (s *Server) SetONVIF(enabled bool) {
+	s.onvif = enabled
+}
+
+// SetUnixSocket also serves the interface on a Unix domain socket at path,
+// for a reverse proxy or companion process on the same machine. The socket
+// is plain HTTP (TLS applies to TCP only), readable and writable by the
+// owner and group. With an empty server address, only the socket is served.
+func (s *Server) SetUnixSocket(path string) {
+	s.unixSocket = path
+}
+
+// SetPprof serves the runtime profiles of net/http/pprof under PprofPath, to
+// profile CPU use or step timing on the rig itself. Only enable it with
+// authentication: profiles expose the command line and memory contents.
+func (s *Server) SetPprof(enabled bool) {
+	s.pprof = enabled
+}
+
+// SetStaticDir serves the interface files (index.html, static/) from dir
+// when it has them, read at each request, falling back to the embedded
+// ones: the frontend can then be edited on the rig without rebuilding
+// PanGo. It fails when dir is not a directory; "" restores the embedded
+// files only.
+func (s *Server) SetStaticDir(dir string) error {
+	if dir == "" {
+		s.handlers.assets.dir = nil
+		return nil
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("static dir: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("static dir: %s is not a directory", dir)
+	}
+	s.handlers.assets.dir = os.DirFS(dir)
+	return nil
+}
+
+// SetTimeouts sets the HTTP server timeouts (see Timeouts).
+func (s *Server) SetTimeouts(t Timeouts) {
+	s.timeouts = t
+}
+
+// SetTLS serves over HTTPS (see TLSConfig).
+func (s *Server) SetTLS(c TLSConfig) {
+	s.tls = c
+}
+
+// route is an authenticated route.
+type route struct {
+	pattern string // http.ServeMux pattern, e.g. "POST /run"
+	handler http.Handler
+}
+
+// routes lists the authenticated API routes. They are served under
+// APIPrefix, and at their unversioned path for older clients (see
+// deprecated).
+func (s *Server) routes() []route {
+	h := s.handlers
+	return []route{
+		{"POST /run", http.HandlerFunc(h.HandleRun)},
+		{"POST /cancel", http.HandlerFunc(h.HandleCancel)},
+		{"POST /pause", http.HandlerFunc(h.HandlePause)},
+		{"POST /resume", http.HandlerFunc(h.HandleResume)},
+		{"POST /plan", http.HandlerFunc(h.HandlePlan)},
+		{"POST /jog", http.HandlerFunc(h.HandleJog)},
+		{"POST /stop", http.HandlerFunc(h.HandleStop)},
+		{"GET /jog/ws", http.HandlerFunc(h.HandleJogStream)},
+		{"GET /control", http.HandlerFunc(h.HandleControl)},
+		{"POST /control/claim", http.HandlerFunc(h.HandleClaimControl)},
+		{"POST /control/release", http.HandlerFunc(h.HandleReleaseControl)},
+		{"POST /jobs", http.HandlerFunc(h.HandleEnqueueJob)},
+		{"GET /jobs", http.HandlerFunc(h.HandleListJobs)},
+		{"GET /jobs/{id}", http.HandlerFunc(h.HandleGetJob)},
+		{"POST /jobs/{id}/cancel", http.HandlerFunc(h.HandleCancelJob)},
+		{"POST /jobs/{id}/move", http.HandlerFunc(h.HandleMoveJob)},
+		{"GET /config", http.HandlerFunc(h.HandleConfig)},
+		{"GET /config/full", http.HandlerFunc(h.HandleFullConfig)},
+		{"GET /config/effective", http.HandlerFunc(h.HandleEffectiveConfig)},
+		{"GET /config/schema", http.HandlerFunc(h.HandleConfigSchema)},
+		{"PUT /config", http.HandlerFunc(h.HandlePutConfig)},
+		{"POST /config/upload", http.HandlerFunc(h.HandleUploadProfile)},
+		{"POST /config/defaults", http.HandlerFunc(h.HandleSaveDefaults)},
+		{"GET /profiles", http.HandlerFunc(h.HandleListProfiles)},
+		{"POST /profiles/{name}/activate", http.HandlerFunc(h.HandleActivateProfile)},
+		{"GET /status", http.HandlerFunc(h.HandleStatus)},
+		{"GET /eta", http.HandlerFunc(h.HandleETA)},
+		{"POST /sync/run", http.HandlerFunc(h.HandleSyncRun)},
+		{"GET /sync/ready", http.HandlerFunc(h.HandleSyncReady)},
+		{"POST /sync/shoot", http.HandlerFunc(h.HandleSyncShoot)},
+		{"GET /history", http.HandlerFunc(h.HandleHistory)},
+		{"GET /history/{id}", http.HandlerFunc(h.HandleGetHistory)},
+		{"GET /sessions/{id}/report", http.HandlerFunc(h.HandleSessionReport)},
+		{"GET /shots/{session}", http.HandlerFunc(h.HandleListShots)},
+		{"GET /shots/{session}/{n}/thumb", http.HandlerFunc(h.HandleShotThumb)},
+		{"GET /status/stream", http.HandlerFunc(h.HandleStatusStream)},
+		{"GET /ws", http.HandlerFunc(h.HandleWS)},
+		{"GET /liveview", http.HandlerFunc(h.HandleLiveView)},
+		{"GET /metrics", http.HandlerFunc(h.HandleMetrics)},
+		{"GET /openapi.json", http.HandlerFunc(h.HandleOpenAPI)},
+		{"GET /version", http.HandlerFunc(h.HandleVersion)},
+		{"GET /log-level", http.HandlerFunc(h.HandleLogLevel)},
+		{"PUT /log-level", http.HandlerFunc(h.HandlePutLogLevel)},
+		{"GET /debug/state", http.HandlerFunc(h.HandleDebugState)},
+	}
+}
+
+// pages lists the authenticated web page routes, served as is.
+func (s *Server) pages() []route {
+	h := s.handlers
+	return []route{
+		{"/static/", http.StripPrefix("/static/", h.assets)},
+		{"GET /{$}", http.HandlerFunc(h.ServeIndex)}, // exact match for root only
+		{"GET /qr", http.HandlerFunc(h.HandleQR)},
+	}
+}
+
+// versioned returns the pattern of a route under APIPrefix:
+// "POST /run" becomes "POST /api/v1/run".
+func versioned(pattern string) string {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		return APIPrefix + pattern
 	}
+	return method + " " + APIPrefix + path
 }
 
 // Mux returns an http.Handler with all routes registered.
 func (s *Server) Mux() http.Handler {
 	mux := http.NewServeMux()
+	for _, rt := range s.routes() {
+		handler := s.limit(rt)
+		mux.Handle(versioned(rt.pattern), handler)
+		mux.Handle(rt.pattern, deprecated(handler, s.basePath))
+	}
+	for _, rt := range s.pages() {
+		mux.Handle(rt.pattern, s.limit(rt))
+	}
+	if s.pprof {
+		for _, rt := range pprofRoutes() {
+			mux.Handle(rt.pattern, keepWritingHandler(rt.handler))
+		}
+	}
 
-	mux.HandleFunc("POST /run", s.handlers.HandleRun)
-	mux.HandleFunc("POST /cancel", s.handlers.HandleCancel)
-	mux.HandleFunc("GET /config", s.handlers.HandleConfig)
-	mux.HandleFunc("GET /status/stream", s.handlers.HandleStatusStream)
-	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(s.handlers.staticFS))))
-	mux.HandleFunc("GET /{$}", s.handlers.ServeIndex) // exact match for root only
+	// Health endpoints stay reachable without credentials, for uptime
+	// monitors and reverse proxies.
+	root := http.NewServeMux()
+	for _, prefix := range []string{"", APIPrefix} {
+		root.HandleFunc("GET "+prefix+"/healthz", s.handlers.HandleHealthz)
+		root.HandleFunc("GET "+prefix+"/readyz", s.handlers.HandleReadyz)
+	}
+	// ONVIF clients authenticate in the SOAP envelopes.
+	if s.onvif {
+		root.Handle(ONVIFPath+"/", s.handlers.ONVIFHandler(s.auth))
+	}
+	root.Handle("/", RequireAuth(mux, s.auth, s.basePath))
+	handler := CORS(StripBasePath(s.handlers.Recover(root), s.basePath), s.cors)
+	if s.accessLog == nil {
+		return handler
+	}
+	return AccessLog(handler, s.accessLog)
+}
 
-	return mux
+// limit returns the handler of rt, rate-limited when configured and
+// reserved to the control holder on the allowed networks for
+// controlledRoutes. Both paths of an API route share the same budget.
+func (s *Server) limit(rt route) http.Handler {
+	handler := rt.handler
+	if controlledRoutes[rt.pattern] {
+		handler = s.handlers.requireNetwork(RequireControl(handler, s.handlers.Control))
+	}
+	if n := s.limits.RequestsPerMinute[rt.pattern]; n > 0 {
+		return RateLimit(handler, n)
+	}
+	return handler
 }
 
 // ListenAndServe starts the HTTP server.
@@ -49,31 +333,244 @@ func (s *Server) ListenAndServe() error {
 	return http.ListenAndServe(s.addr, s.Mux())
 }
 
-// Run starts the server and blocks until ctx is cancelled, then shuts down gracefully.
+// Run starts the server and blocks until ctx is cancelled, then shuts down
+// gracefully: the running capture finishes its current shot and stops (new
+// ones are refused), the RegisterOnShutdown functions run, status clients get
+// a final EventShutdown event, and in-flight requests complete.
 func (s *Server) Run(ctx context.Context) error {
+	timeouts := s.timeouts.withDefaults()
 	srv := &http.Server{
-		Addr:        s.addr,
-		Handler:     s.Mux(),
-		ReadTimeout: 5 * time.Second,
-		IdleTimeout: 120 * time.Second,
-		// WriteTimeout intentionally left at 0 (unlimited) because the SSE
-		// endpoint streams data for the entire duration of a capture.
-	}
-	errCh := make(chan error, 1)
-	go func() {
-		log.Printf("web server listening on %s", s.addr)
-		errCh <- srv.ListenAndServe()
+		Addr:              s.addr,
+		Handler:           s.Mux(),
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		ReadTimeout:       timeouts.Read,
+		// Streaming responses lift the write timeout (keepWriting): the
+		// status stream lasts for the entire duration of a capture.
+		WriteTimeout: timeouts.Write,
+		IdleTimeout:  timeouts.Idle,
+	}
+	if s.tls.enabled() {
+		cert, err := LoadCertificate(s.tls)
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+	listeners, err := s.listen()
+	if err != nil {
+		return err
+	}
+	grpcSrv := s.grpcServer(srv.TLSConfig)
+	var grpcLn net.Listener
+	if grpcSrv != nil {
+		if grpcLn, err = net.Listen("tcp", s.grpcAddr); err != nil {
+			for _, ln := range listeners {
+				ln.Close()
+			}
+			return err
+		}
+	}
+	s.mu.Lock()
+	s.serving, s.servingTLS = listeners, srv.TLSConfig != nil
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.serving = nil
+		s.mu.Unlock()
 	}()
+	for _, f := range s.onReady {
+		f()
+	}
+
+	errCh := make(chan error, len(listeners)+1)
+	for _, ln := range listeners {
+		go func() {
+			// TLS applies to network listeners; Unix sockets are local.
+			if srv.TLSConfig != nil && ln.Addr().Network() != "unix" {
+				log.Printf("web server listening on %s (HTTPS)", ln.Addr())
+				errCh <- srv.ServeTLS(ln, "", "")
+				return
+			}
+			log.Printf("web server listening on %s %s", ln.Addr().Network(), ln.Addr())
+			errCh <- srv.Serve(ln)
+		}()
+	}
+	if grpcSrv != nil {
+		go func() {
+			log.Printf("gRPC service %s listening on %s", GRPCService, s.grpcAddr)
+			if grpcSrv.TLSConfig != nil {
+				errCh <- grpcSrv.ServeTLS(grpcLn, "", "")
+				return
+			}
+			errCh <- grpcSrv.Serve(grpcLn)
+		}()
+	}
 
 	select {
 	case err := <-errCh:
+		if grpcSrv != nil {
+			grpcSrv.Close()
+		}
+		srv.Close()
 		if err != nil && err != http.ErrServerClosed {
 			return err
 		}
 		return nil
 	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		s.drain()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeouts.Shutdown)
 		defer cancel()
+		if grpcSrv != nil {
+			// Event streams end with the broadcaster; other calls finish.
+			grpcSrv.Shutdown(shutdownCtx)
+		}
 		return srv.Shutdown(shutdownCtx)
 	}
 }
+
+// Alive checks that the server still accepts and answers connections, for
+// the service watchdog: it sends "OPTIONS *" to its first listener, which
+// net/http answers without running a handler (nor writing the access log).
+// It returns nil when the server is not serving.
+func (s *Server) Alive(ctx context.Context) error {
+	s.mu.Lock()
+	var addr net.Addr
+	if len(s.serving) > 0 {
+		addr = s.serving[0].Addr()
+	}
+	useTLS := s.servingTLS
+	s.mu.Unlock()
+	if addr == nil {
+		return nil
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, addr.Network(), addr.String())
+	if err != nil {
+		return fmt.Errorf("web server not accepting connections: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if useTLS && addr.Network() != "unix" {
+		// Our own certificate, whatever names it is issued for.
+		conn = tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+	}
+	if _, err := io.WriteString(conn, "OPTIONS * HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"); err != nil {
+		return fmt.Errorf("web server not answering: %w", err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return fmt.Errorf("web server not answering: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("web server answered %s", resp.Status)
+	}
+	return nil
+}
+
+// grpcServer returns the HTTP/2 server of the gRPC service, nil when it is
+// disabled. tlsConfig is the web server's, nil without TLS.
+func (s *Server) grpcServer(tlsConfig *tls.Config) *http.Server {
+	if s.grpcAddr == "" {
+		return nil
+	}
+	handler := s.handlers.Recover(s.handlers.GRPCHandler(s.auth))
+	if s.accessLog != nil {
+		handler = AccessLog(handler, s.accessLog)
+	}
+	srv := &http.Server{
+		Addr:              s.grpcAddr,
+		Handler:           handler,
+		ReadHeaderTimeout: s.timeouts.withDefaults().ReadHeader,
+		IdleTimeout:       s.timeouts.withDefaults().Idle,
+		Protocols:         new(http.Protocols),
+	}
+	if tlsConfig != nil {
+		srv.TLSConfig = tlsConfig.Clone()
+		srv.Protocols.SetHTTP2(true)
+	} else {
+		srv.Protocols.SetUnencryptedHTTP2(true)
+	}
+	return srv
+}
+
+// listen opens the listeners of the web interface: the ones passed with
+// SetListeners, or else the TCP address, and the Unix socket.
+func (s *Server) listen() ([]net.Listener, error) {
+	listeners := slices.Clone(s.listeners)
+	if len(listeners) == 0 && s.addr != "" {
+		ln, err := net.Listen("tcp", s.addr)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+	}
+	if s.unixSocket != "" {
+		ln, err := listenUnix(s.unixSocket)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, ln)
+	}
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("web server: no address or unix socket to listen on")
+	}
+	return listeners, nil
+}
+
+// listenUnix listens on the Unix socket at path. A socket file left by a
+// previous run that did not exit cleanly is replaced; one still in use is
+// an error. The file is removed when the listener closes.
+func listenUnix(path string) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode().Type() != os.ModeSocket {
+			return nil, fmt.Errorf("unix socket %s: file exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unix socket %s: already in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("unix socket: remove stale %s: %w", path, err)
+		}
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("unix socket: %w", err)
+	}
+	if err := os.Chmod(path, 0o660); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("unix socket: %w", err)
+	}
+	return ln, nil
+}
+
+// drain stops the capture activity before the HTTP server closes, while
+// status clients can still follow it.
+func (s *Server) drain() {
+	h := s.handlers
+	if h.Jobs != nil {
+		if _, running := h.Jobs.Running(); running {
+			log.Printf("shutdown: waiting for the current shot to finish")
+			h.Broadcaster.Broadcast("warning", "Server shutting down: stopping after the current shot")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), captureDrainTimeout)
+		defer cancel()
+		if err := h.Jobs.Close(ctx); err != nil {
+			log.Printf("shutdown: capture still running after %v, exiting anyway", captureDrainTimeout)
+		}
+	}
+	for _, f := range s.onShutdown {
+		f()
+	}
+	h.Broadcaster.Close("server shutting down")
+}
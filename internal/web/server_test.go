@@ -0,0 +1,313 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_RunGracefulShutdown(t *testing.T) {
+	r := newBlockingRunner()
+	srv := NewServer("127.0.0.1:0", NewStatusBroadcaster(), r.run, FormConfig{})
+	srv.SetAccessLog(nil)
+	h := srv.Handlers()
+	h.Jobs.MinSpacing = 0
+	var runningAtHook bool
+	srv.RegisterOnShutdown(func() {
+		_, runningAtHook = h.Jobs.Running()
+	})
+	ch, unsub := h.Broadcaster.Subscribe()
+	defer unsub()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+	if _, err := h.Jobs.StartNow(context.Background(), Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 90, FocalLengthMm: 35}); err != nil {
+		t.Fatalf("StartNow: %v", err)
+	}
+	r.waitStart(t)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after shutdown")
+	}
+	if runningAtHook {
+		t.Error("shutdown hook ran before the capture stopped")
+	}
+
+	var last StatusEvent
+	for msg := range ch {
+		if err := json.Unmarshal([]byte(msg), &last); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if last.Type != EventShutdown {
+		t.Errorf("last event = %+v, want %q", last, EventShutdown)
+	}
+}
+
+func TestServer_VersionedRoutes(t *testing.T) {
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	mux := srv.Mux()
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	for _, path := range []string{APIPrefix + "/config", APIPrefix + "/healthz", "/healthz", "/"} {
+		if w := get(path); w.Code != http.StatusOK || w.Header().Get("Deprecation") != "" {
+			t.Errorf("GET %s: status %d, Deprecation %q, want 200 and none", path, w.Code, w.Header().Get("Deprecation"))
+		}
+	}
+
+	// Unversioned API paths still work, flagged as deprecated
+	w := get("/config")
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "true" {
+		t.Errorf("GET /config: status %d, Deprecation %q, want 200 and true", w.Code, w.Header().Get("Deprecation"))
+	}
+	if got, want := w.Header().Get("Link"), "<"+APIPrefix+`/config>; rel="successor-version"`; got != want {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+
+	if w := get(APIPrefix + "/static/app.js"); w.Code != http.StatusNotFound {
+		t.Errorf("web page files are not versioned: status %d, want 404", w.Code)
+	}
+}
+
+// ---------- Unix socket ----------
+
+func TestServer_UnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pango.sock")
+	srv := NewServer("", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	srv.SetUnixSocket(path)
+
+	// A stale socket from a previous run is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+
+	c := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", path)
+		},
+	}}
+	var resp *http.Response
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if resp, err = c.Get("http://pango" + APIPrefix + "/healthz"); err == nil || time.Now().After(deadline) {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("GET over the socket: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d, want 200", resp.StatusCode)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o660 {
+		t.Errorf("socket mode = %v (%v), want 0660", fi.Mode().Perm(), err)
+	}
+
+	// A socket in use is not taken over
+	other := NewServer("", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	other.SetAccessLog(nil)
+	other.SetUnixSocket(path)
+	if err := other.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Errorf("second server: %v, want already in use", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file left after shutdown: %v", err)
+	}
+}
+
+func TestServer_UnixSocketNotASocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pango.sock")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer("", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	srv.SetUnixSocket(path)
+	if err := srv.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "not a socket") {
+		t.Errorf("Run: %v, want not a socket", err)
+	}
+}
+
+// ---------- Static dir ----------
+
+func TestServer_StaticDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(`<html><p>{{.CameraType}}</p><script src="static/app.js"></script></html>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	if err := srv.SetStaticDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	srv.Handlers().Page = func() PageInfo { return PageInfo{CameraType: "gphoto2"} }
+	mux := srv.Mux()
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	if w := get("/"); !strings.HasPrefix(w.Body.String(), "<html><p>gphoto2</p>") {
+		t.Errorf("index from the directory: %q", w.Body)
+	}
+	if w := get("/static/app.js"); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "PanGo") {
+		t.Errorf("embedded app.js: status %d", w.Code)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte("// edited"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if w := get("/static/app.js"); w.Body.String() != "// edited" {
+		t.Errorf("edited app.js: %q", w.Body)
+	}
+}
+
+func TestServer_StaticDirInvalid(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.js")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(":0", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	for _, dir := range []string{file, filepath.Join(t.TempDir(), "missing")} {
+		if err := srv.SetStaticDir(dir); err == nil {
+			t.Errorf("SetStaticDir(%s): want an error", dir)
+		}
+	}
+}
+
+// ---------- Listeners ----------
+
+func TestServer_SetListeners(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewServer(":1", NewStatusBroadcaster(), noopCapture, FormConfig{}) // address unused
+	srv.SetAccessLog(nil)
+	srv.SetListeners([]net.Listener{ln})
+	ready := make(chan struct{})
+	srv.RegisterOnReady(func() { close(ready) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+	select {
+	case <-ready:
+	case err := <-done:
+		t.Fatalf("Run: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("not ready")
+	}
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/healthz")
+	if err != nil {
+		t.Fatalf("GET on the passed listener: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d, want 200", resp.StatusCode)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+}
+
+// ---------- Alive ----------
+
+func TestServer_Alive(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		tls  TLSConfig
+	}{
+		{"http", TLSConfig{}},
+		{"https", TLSConfig{SelfSigned: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			srv := NewServer(":1", NewStatusBroadcaster(), noopCapture, FormConfig{})
+			srv.SetAccessLog(nil)
+			srv.SetTLS(tc.tls)
+			srv.SetListeners([]net.Listener{ln})
+			if err := srv.Alive(context.Background()); err != nil {
+				t.Errorf("Alive before Run: %v", err)
+			}
+			ready := make(chan struct{})
+			srv.RegisterOnReady(func() { close(ready) })
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- srv.Run(ctx) }()
+			select {
+			case <-ready:
+			case err := <-done:
+				t.Fatalf("Run: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("not ready")
+			}
+
+			probeCtx, probeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer probeCancel()
+			if err := srv.Alive(probeCtx); err != nil {
+				t.Errorf("Alive while serving: %v", err)
+			}
+			cancel()
+			if err := <-done; err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+		})
+	}
+}
+
+func TestServer_AliveNotAnswering(t *testing.T) {
+	// A listener nobody accepts on: the connection is queued, never answered.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	srv := NewServer(":1", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.serving = []net.Listener{ln}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := srv.Alive(ctx); err == nil || !strings.Contains(err.Error(), "not answering") {
+		t.Errorf("Alive = %v, want not answering", err)
+	}
+}
@@ -0,0 +1,153 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// ErrInvalidConfig wraps configuration documents rejected by validation.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// ConfigFunc returns the full current configuration for GET /config/full,
+// as a JSON-encodable document using the YAML file keys.
+type ConfigFunc func() (any, error)
+
+// UpdateConfigFunc validates a full configuration document (YAML or JSON)
+// and applies it to the following captures. With persist, it is also written
+// back to the config file. Validation failures wrap ErrInvalidConfig.
+type UpdateConfigFunc func(data []byte, persist bool) (ConfigUpdate, error)
+
+// ConfigUpdate is the outcome of PUT /config.
+type ConfigUpdate struct {
+	Saved           bool       `json:"saved"`            // written back to the config file
+	Reinitialized   bool       `json:"reinitialized"`    // motors, camera or trigger re-initialized
+	RestartRequired bool       `json:"restart_required"` // GPIO driver or server settings changed
+	Form            FormConfig `json:"-"`                // new capture form defaults
+}
+
+// HandleFullConfig handles GET /config/full: the whole configuration, for
+// editing. Web server settings (authentication, TLS) are not included.
+func (h *Handlers) HandleFullConfig(w http.ResponseWriter, r *http.Request) {
+	if h.FullConfig == nil {
+		http.Error(w, "configuration editing not available", http.StatusServiceUnavailable)
+		return
+	}
+	doc, err := h.FullConfig()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+// EffectiveConfig is the configuration captures run with, for GET
+// /config/effective: the files merged, variables and overrides applied.
+type EffectiveConfig struct {
+	Sources  []string       `json:"sources"`            // config files merged, in order
+	Profile  string         `json:"profile,omitempty"`  // entry of the profiles: section applied
+	Env      []string       `json:"env,omitempty"`      // PANGO_* variables applied
+	Warnings []string       `json:"warnings,omitempty"` // keys migrated from an older config version
+	Config   map[string]any `json:"config"`             // every key, secrets redacted
+}
+
+// EffectiveConfigFunc returns the configuration for GET /config/effective.
+type EffectiveConfigFunc func() (EffectiveConfig, error)
+
+// HandleEffectiveConfig handles GET /config/effective: every key of the
+// configuration in use, with where it comes from, to find out why a value
+// is not the one written in the file.
+func (h *Handlers) HandleEffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	if h.EffectiveConfig == nil {
+		http.Error(w, "effective configuration not available", http.StatusServiceUnavailable)
+		return
+	}
+	effective, err := h.EffectiveConfig()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, effective)
+}
+
+// HandleConfigSchema handles GET /config/schema: the JSON Schema of the
+// config file, for editors completing and checking it.
+func (h *Handlers) HandleConfigSchema(w http.ResponseWriter, r *http.Request) {
+	if h.ConfigSchema == nil {
+		http.Error(w, "configuration schema not available", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.ConfigSchema)
+}
+
+// HandlePutConfig handles PUT /config: the body is a full configuration
+// document (YAML or JSON), validated with the same rules as the config file.
+// With ?save=true it is also written back to the file. Motors and camera are
+// re-initialized when their settings change; GPIO driver and server settings
+// only take effect after a restart.
+func (h *Handlers) HandlePutConfig(w http.ResponseWriter, r *http.Request) {
+	if h.UpdateConfig == nil {
+		http.Error(w, "configuration editing not available", http.StatusServiceUnavailable)
+		return
+	}
+	persist, err := boolParam("save", r.URL.Query().Get("save"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	update, err := h.UpdateConfig(data, persist)
+	if err != nil {
+		writeConfigError(w, err)
+		return
+	}
+	h.SetFormDefaults(update.Form)
+	writeJSON(w, http.StatusOK, update)
+}
+
+// boolParam parses v, the value of the optional boolean parameter name;
+// it defaults to false.
+func boolParam(name, v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false", name)
+	}
+	return b, nil
+}
+
+// SetFormDefaults replaces the capture form defaults after a config change,
+// e.g. a reload of the config file.
+func (h *Handlers) SetFormDefaults(form FormConfig) {
+	h.formMu.Lock()
+	h.FormDefaults = form
+	h.formMu.Unlock()
+}
+
+// writeConfigError maps configuration change errors to HTTP status codes.
+func writeConfigError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidConfig):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrProfileNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrHeadBusy), errors.Is(err, ErrProfileExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,186 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ---------- HandleFullConfig ----------
+
+func TestHandleFullConfig(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.FullConfig = func() (any, error) {
+		return map[string]any{"lens": map[string]any{"focal_length_mm": 35}}, nil
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleFullConfig(w, httptest.NewRequest(http.MethodGet, "/config/full", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var doc map[string]map[string]float64
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc["lens"]["focal_length_mm"] != 35 {
+		t.Errorf("document = %v", doc)
+	}
+
+	h.FullConfig = nil
+	w = httptest.NewRecorder()
+	h.HandleFullConfig(w, httptest.NewRequest(http.MethodGet, "/config/full", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status without FullConfig = %d, want 503", w.Code)
+	}
+}
+
+// ---------- HandleEffectiveConfig ----------
+
+func TestHandleEffectiveConfig(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.EffectiveConfig = func() (EffectiveConfig, error) {
+		return EffectiveConfig{
+			Sources: []string{"configs/base.yaml", "configs/site.yaml"},
+			Env:     []string{"PANGO_DEFAULTS_OVERLAP_PERCENT"},
+			Config:  map[string]any{"defaults": map[string]any{"overlap_percent": 30}},
+		}, nil
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleEffectiveConfig(w, httptest.NewRequest(http.MethodGet, "/config/effective", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var got struct {
+		Sources []string                      `json:"sources"`
+		Env     []string                      `json:"env"`
+		Profile *string                       `json:"profile"`
+		Config  map[string]map[string]float64 `json:"config"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got.Sources) != 2 || got.Env[0] != "PANGO_DEFAULTS_OVERLAP_PERCENT" || got.Profile != nil || got.Config["defaults"]["overlap_percent"] != 30 {
+		t.Errorf("response = %+v", got)
+	}
+
+	h.EffectiveConfig = func() (EffectiveConfig, error) { return EffectiveConfig{}, errors.New("marshal failed") }
+	w = httptest.NewRecorder()
+	h.HandleEffectiveConfig(w, httptest.NewRequest(http.MethodGet, "/config/effective", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status on error = %d, want 500", w.Code)
+	}
+
+	h.EffectiveConfig = nil
+	w = httptest.NewRecorder()
+	h.HandleEffectiveConfig(w, httptest.NewRequest(http.MethodGet, "/config/effective", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status without EffectiveConfig = %d, want 503", w.Code)
+	}
+}
+
+// ---------- HandleConfigSchema ----------
+
+func TestHandleConfigSchema(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	w := httptest.NewRecorder()
+	h.HandleConfigSchema(w, httptest.NewRequest(http.MethodGet, "/config/schema", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status without ConfigSchema = %d, want 503", w.Code)
+	}
+
+	h.ConfigSchema = map[string]any{"type": "object", "properties": map[string]any{"lens": map[string]any{"type": "object"}}}
+	w = httptest.NewRecorder()
+	h.HandleConfigSchema(w, httptest.NewRequest(http.MethodGet, "/config/schema", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/schema+json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var doc struct {
+		Properties map[string]any `json:"properties"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil || doc.Properties["lens"] == nil {
+		t.Errorf("schema = %+v, %v", doc, err)
+	}
+}
+
+// ---------- HandlePutConfig ----------
+
+func TestHandlePutConfig(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	var gotBody string
+	var gotPersist bool
+	h.UpdateConfig = func(data []byte, persist bool) (ConfigUpdate, error) {
+		gotBody, gotPersist = string(data), persist
+		return ConfigUpdate{
+			Saved:           persist,
+			RestartRequired: true,
+			Form:            FormConfig{HorizontalAngleDeg: 90, VerticalAngleDeg: 20, FocalLengthMm: 85},
+		}, nil
+	}
+
+	body := "lens:\n  focal_length_mm: 85\n"
+	w := httptest.NewRecorder()
+	h.HandlePutConfig(w, httptest.NewRequest(http.MethodPut, "/config?save=true", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if gotBody != body || !gotPersist {
+		t.Errorf("UpdateConfig(%q, %v)", gotBody, gotPersist)
+	}
+	var resp ConfigUpdate
+	json.NewDecoder(w.Body).Decode(&resp)
+	if !resp.Saved || !resp.RestartRequired {
+		t.Errorf("response = %+v", resp)
+	}
+
+	// The capture form picks up the new defaults
+	w = httptest.NewRecorder()
+	h.HandleConfig(w, httptest.NewRequest(http.MethodGet, "/config", nil))
+	var form FormConfig
+	json.NewDecoder(w.Body).Decode(&form)
+	if form.FocalLengthMm != 85 {
+		t.Errorf("form defaults = %+v, want focal length 85", form)
+	}
+}
+
+func TestHandlePutConfig_Errors(t *testing.T) {
+	invalid := func([]byte, bool) (ConfigUpdate, error) {
+		return ConfigUpdate{}, fmt.Errorf("%w: overlap_percent must be between 0 and 100", ErrInvalidConfig)
+	}
+	diskFull := func([]byte, bool) (ConfigUpdate, error) {
+		return ConfigUpdate{}, errors.New("write config file: no space left on device")
+	}
+	cases := []struct {
+		name   string
+		update UpdateConfigFunc
+		target string
+		want   int
+	}{
+		{"not_available", nil, "/config", http.StatusServiceUnavailable},
+		{"bad_save_flag", invalid, "/config?save=maybe", http.StatusBadRequest},
+		{"invalid", invalid, "/config", http.StatusBadRequest},
+		{"save_failed", diskFull, "/config?save=1", http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandlers(noopCapture)
+			h.UpdateConfig = tc.update
+			w := httptest.NewRecorder()
+			h.HandlePutConfig(w, httptest.NewRequest(http.MethodPut, tc.target, strings.NewReader("{}")))
+			if w.Code != tc.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tc.want, w.Body.String())
+			}
+			if h.FormDefaults.FocalLengthMm != 35 {
+				t.Error("form defaults must not change on error")
+			}
+		})
+	}
+}
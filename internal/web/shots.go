@@ -0,0 +1,60 @@
+package web
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// latestSession names, in GET /shots/{session}, the run that received the
+// last thumbnail: usually the one in progress.
+const latestSession = "latest"
+
+// ShotGallery lists the thumbnails of a run (GET /shots/{session}).
+type ShotGallery struct {
+	Session string      `json:"session"`
+	Shots   []ShotThumb `json:"shots"`
+}
+
+// ShotThumb is a shot with a thumbnail.
+type ShotThumb struct {
+	Index int    `json:"index"` // shot number, as in the session record
+	Thumb string `json:"thumb"` // URL of the JPEG thumbnail
+}
+
+// HandleListShots handles GET /shots/{session}: the shots of a run that
+// have a thumbnail (camera.download), "latest" for the current or last run.
+func (h *Handlers) HandleListShots(w http.ResponseWriter, r *http.Request) {
+	gallery := ShotGallery{Session: r.PathValue("session"), Shots: []ShotThumb{}}
+	if h.Thumbnails == nil {
+		writeJSON(w, http.StatusOK, gallery)
+		return
+	}
+	if gallery.Session == latestSession {
+		gallery.Session = h.Thumbnails.Latest()
+	}
+	for _, i := range h.Thumbnails.List(gallery.Session) {
+		gallery.Shots = append(gallery.Shots, ShotThumb{
+			Index: i,
+			Thumb: h.BasePath + APIPrefix + "/shots/" + gallery.Session + "/" + strconv.Itoa(i) + "/thumb",
+		})
+	}
+	writeJSON(w, http.StatusOK, gallery)
+}
+
+// HandleShotThumb handles GET /shots/{session}/{n}/thumb: the thumbnail of
+// shot n, as a JPEG image.
+func (h *Handlers) HandleShotThumb(w http.ResponseWriter, r *http.Request) {
+	n, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || h.Thumbnails == nil {
+		http.Error(w, "thumbnail not found", http.StatusNotFound)
+		return
+	}
+	thumb, ok := h.Thumbnails.Get(r.PathValue("session"), n)
+	if !ok {
+		http.Error(w, "thumbnail not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "private, max-age=86400") // a shot never changes
+	w.Write(thumb)
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cjeanneret/PanGo/internal/session"
+)
+
+// newTestThumbnails returns an in-memory store with shots 1 and 2 of run1.
+func newTestThumbnails(t *testing.T) *session.Thumbnails {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, 16, 16)), nil); err != nil {
+		t.Fatal(err)
+	}
+	store := session.NewThumbnails("")
+	for i := 1; i <= 2; i++ {
+		if err := store.Add("run1", i, buf.Bytes()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return store
+}
+
+func listShots(t *testing.T, h *Handlers, id string) ShotGallery {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/shots/"+id, nil)
+	req.SetPathValue("session", id)
+	w := httptest.NewRecorder()
+	h.HandleListShots(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var gallery ShotGallery
+	if err := json.NewDecoder(w.Body).Decode(&gallery); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return gallery
+}
+
+func getThumb(h *Handlers, id, n string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/shots/"+id+"/"+n+"/thumb", nil)
+	req.SetPathValue("session", id)
+	req.SetPathValue("n", n)
+	w := httptest.NewRecorder()
+	h.HandleShotThumb(w, req)
+	return w
+}
+
+// ---------- HandleListShots ----------
+
+func TestHandleListShots(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Thumbnails = newTestThumbnails(t)
+
+	for _, id := range []string{"run1", latestSession} {
+		gallery := listShots(t, h, id)
+		if gallery.Session != "run1" || len(gallery.Shots) != 2 {
+			t.Fatalf("%s: gallery = %+v", id, gallery)
+		}
+		if got, want := gallery.Shots[1].Thumb, APIPrefix+"/shots/run1/2/thumb"; got != want {
+			t.Errorf("%s: thumb = %q, want %q", id, got, want)
+		}
+	}
+	if gallery := listShots(t, h, "other"); len(gallery.Shots) != 0 {
+		t.Errorf("unknown run: shots = %+v, want none", gallery.Shots)
+	}
+}
+
+func TestHandleListShots_NotAvailable(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	gallery := listShots(t, h, latestSession)
+	if gallery.Shots == nil || len(gallery.Shots) != 0 {
+		t.Errorf("shots = %#v, want an empty list", gallery.Shots)
+	}
+}
+
+// ---------- HandleShotThumb ----------
+
+func TestHandleShotThumb(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Thumbnails = newTestThumbnails(t)
+
+	w := getThumb(h, "run1", "2")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("Content-Type = %q, want image/jpeg", ct)
+	}
+	if _, err := jpeg.Decode(w.Body); err != nil {
+		t.Errorf("decode thumbnail: %v", err)
+	}
+
+	cases := []struct{ id, n string }{
+		{"run1", "3"},
+		{"run1", "x"},
+		{"other", "1"},
+	}
+	for _, tc := range cases {
+		if w := getThumb(h, tc.id, tc.n); w.Code != http.StatusNotFound {
+			t.Errorf("%s/%s: status = %d, want 404", tc.id, tc.n, w.Code)
+		}
+	}
+
+	h.Thumbnails = nil
+	if w := getThumb(h, "run1", "1"); w.Code != http.StatusNotFound {
+		t.Errorf("without thumbnails: status = %d, want 404", w.Code)
+	}
+}
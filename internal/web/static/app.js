@@ -1,31 +1,209 @@
 /**
  * PanGo — Lightweight web control
- * Form handling, POST /run and SSE console stream
+ * Form handling, profiles, POST /plan preview, POST /run, POST /pause and /resume, POST /jog and /stop, press-and-hold jog (GET /jog/ws), control claims (POST /control/claim), live view, shot thumbnails, history and reports, typed SSE events, GET /status polling and the GET /eta countdown
  */
 
 (function () {
+  // Versioned API routes (web.APIPrefix), under the page's base path
+  // (web.base_path) when served behind a reverse proxy.
+  const API = new URL('api/v1', document.baseURI).pathname;
   const form = document.getElementById('capture-form');
   const launchBtn = document.getElementById('launch-btn');
   const cancelBtn = document.getElementById('cancel-btn');
+  const pauseBtn = document.getElementById('pause-btn');
   const consoleEl = document.getElementById('console');
   const statusBadge = document.getElementById('status-badge');
+  const planPreview = document.getElementById('plan-preview');
+  const progressEl = document.getElementById('progress');
+  const progressBar = document.getElementById('progress-bar');
+  const etaEl = document.getElementById('eta');
+  const positionEl = document.getElementById('position');
+  const headEl = document.getElementById('head');
+  const headPointerEl = document.getElementById('head-pointer');
+  const headAnglesEl = document.getElementById('head-angles');
+  const shotsEl = document.getElementById('shots');
+  const profileField = document.getElementById('profile-field');
+  const profileSelect = document.getElementById('profile');
+  const jogStep = document.getElementById('jog-step');
+  const jogStopBtn = document.getElementById('jog-stop');
+  const jogBtns = document.querySelectorAll('.btn-jog[data-axis]');
+  const liveviewBtn = document.getElementById('liveview-btn');
+  const liveviewImg = document.getElementById('liveview');
+  const historyList = document.getElementById('history-list');
+  const controlEl = document.querySelector('.control');
+  const controlNote = document.getElementById('control-note');
+  const controlBtn = document.getElementById('control-btn');
 
   let evtSource = null;
+  let lastEventId = ''; // resume point of the status stream
+  let reconnectTimer = null;
   let isRunning = false;
+  let launchedAt = 0;
+  let planTimer = null;
+  let shotsTimer = null;
+  let controlToken = sessionStorage.getItem('pango-control') || ''; // set while this page holds control
+  let readOnly = false; // another client holds control
+  let pauseState = null;
+
+  // Capture states reported by GET /status while a capture is active
+  const ACTIVE_STATES = ['planning', 'homing', 'shooting', 'paused', 'finishing'];
 
   async function loadFormDefaults() {
     try {
-      const res = await fetch('/config');
+      const res = await fetch(API + '/config');
       if (res.ok) {
         const cfg = await res.json();
         form.horizontal_angle_deg.value = cfg.horizontal_angle_deg ?? 180;
         form.vertical_angle_deg.value = cfg.vertical_angle_deg ?? 30;
         form.focal_length_mm.value = cfg.focal_length_mm ?? 35;
+        form.overlap_percent.value = cfg.overlap_percent ?? 30;
+        form.inter_move_delay_ms.value = cfg.inter_move_delay_ms ?? 500;
+        form.stabilization_delay_ms.value = cfg.stabilization_delay_ms ?? 300;
+        form.shot_delay_ms.value = cfg.shot_delay_ms ?? 300;
+        form.move_speed_ms.value = cfg.move_speed_ms ?? 2;
       }
     } catch (_) {
       form.horizontal_angle_deg.value = 180;
       form.vertical_angle_deg.value = 30;
       form.focal_length_mm.value = 35;
+      form.overlap_percent.value = 30;
+      form.inter_move_delay_ms.value = 500;
+      form.stabilization_delay_ms.value = 300;
+      form.shot_delay_ms.value = 300;
+      form.move_speed_ms.value = 2;
+    }
+  }
+
+  // Profile selector: shown when the config directory holds several profiles
+  async function loadProfiles() {
+    try {
+      const res = await fetch(API + '/profiles');
+      if (!res.ok) return;
+      const profiles = await res.json();
+      profileSelect.replaceChildren(...profiles.available.map(function (name) {
+        const opt = document.createElement('option');
+        opt.value = name;
+        opt.textContent = name;
+        return opt;
+      }));
+      profileSelect.value = profiles.active;
+      profileField.hidden = profiles.available.length < 2;
+    } catch (_) {
+      // Profiles unavailable: keep the selector hidden
+    }
+  }
+
+  async function activateProfile(name) {
+    try {
+      const res = await fetch(API + '/profiles/' + encodeURIComponent(name) + '/activate', { method: 'POST', headers: withControl() });
+      if (!res.ok) {
+        const err = await res.text();
+        appendConsole('Profile not activated: ' + (err || res.status), 'error');
+        loadProfiles();
+  loadHistory();
+        return;
+      }
+      const update = await res.json();
+      appendConsole('Profile "' + name + '" activated' +
+        (update.reinitialized ? ', motors and camera re-initialized' : '') +
+        (update.restart_required ? '; restart PanGo to apply all settings' : '') + '.', 'info');
+      await loadFormDefaults();
+      refreshPlan();
+    } catch (err) {
+      appendConsole('Network error: ' + err.message, 'error');
+    }
+  }
+
+  function formPayload() {
+    return {
+      horizontal_angle_deg: parseFloat(form.horizontal_angle_deg.value),
+      vertical_angle_deg: parseFloat(form.vertical_angle_deg.value),
+      focal_length_mm: parseFloat(form.focal_length_mm.value),
+      overlap_percent: parseFloat(form.overlap_percent.value) || 0,
+      inter_move_delay_ms: parseInt(form.inter_move_delay_ms.value, 10) || 0,
+      stabilization_delay_ms: parseInt(form.stabilization_delay_ms.value, 10) || 0,
+      shot_delay_ms: parseInt(form.shot_delay_ms.value, 10) || 0,
+      move_speed_ms: parseInt(form.move_speed_ms.value, 10) || 0
+    };
+  }
+
+  function formatDuration(seconds) {
+    if (seconds < 90) return Math.round(seconds) + ' s';
+    const minutes = Math.round(seconds / 60);
+    if (minutes < 90) return minutes + ' min';
+    return Math.floor(minutes / 60) + ' h ' + (minutes % 60) + ' min';
+  }
+
+  // Preview the plan ("84 photos, ~22 min") as the user types
+  async function refreshPlan() {
+    if (!form.checkValidity()) {
+      planPreview.textContent = '';
+      return;
+    }
+    try {
+      const res = await fetch(API + '/plan', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify(formPayload())
+      });
+      if (!res.ok) {
+        planPreview.dataset.error = '';
+        planPreview.textContent = res.status === 503 ? '' : (await res.text()).trim();
+        return;
+      }
+      const plan = await res.json();
+      delete planPreview.dataset.error;
+      let text = plan.total_shots + (plan.mode === 'timelapse' ? ' frames' : ' photos');
+      if (plan.columns && !plan.panoramas) text += ' (' + plan.columns + ' × ' + plan.rows + ')';
+      if (plan.panoramas) text += ' in ' + plan.panoramas.length + ' panoramas';
+      text += ', ~' + formatDuration(plan.estimated_seconds);
+      planPreview.textContent = text;
+    } catch (_) {
+      planPreview.textContent = '';
+    }
+  }
+
+  function schedulePlan() {
+    clearTimeout(planTimer);
+    planTimer = setTimeout(refreshPlan, 300);
+  }
+
+  // Today's runs from GET /history, most recent first
+  async function loadHistory() {
+    const midnight = new Date();
+    midnight.setHours(0, 0, 0, 0);
+    try {
+      const res = await fetch(API + '/history?since=' + encodeURIComponent(midnight.toISOString()));
+      if (!res.ok) return;
+      const runs = await res.json();
+      historyList.replaceChildren(...runs.map(function (run) {
+        const p = run.params;
+        const item = document.createElement('li');
+        item.dataset.outcome = run.outcome;
+        const time = new Date(run.started_at).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+        let what = run.mode;
+        if (p.horizontal_angle_deg) what += ' ' + p.horizontal_angle_deg + '°×' + p.vertical_angle_deg + '°';
+        if (p.focal_length_mm) what += ' @ ' + p.focal_length_mm + ' mm';
+        item.textContent = time + ' · ' + what + ' · ' + run.shots_taken + '/' + p.shots_planned + ' shots · ' +
+          formatDuration(run.duration_seconds) + ' · ' + run.outcome;
+        if (run.error) item.title = run.error;
+        const formats = [['csv', 'CSV']];
+        if (run.mode === 'grid' && p.horizontal_fov_deg) {
+          formats.push(['pto', 'Hugin'], ['pts', 'PTGui']);
+          if (p.columns) formats.push(['krpano', 'krpano'], ['gigapan', 'GigaPan']);
+        }
+        if (run.location) formats.push(['gpx', 'GPX']);
+        formats.forEach(function (f) {
+          const report = document.createElement('a');
+          report.href = API + '/sessions/' + encodeURIComponent(run.id) + '/report?format=' + f[0];
+          report.textContent = f[1];
+          report.download = '';
+          item.append(' · ', report);
+        });
+        return item;
+      }));
+    } catch (_) {
+      // Server unreachable: keep the current list
     }
   }
 
@@ -33,10 +211,107 @@
     statusBadge.className = 'status-badge status-' + status;
     statusBadge.textContent = label;
     isRunning = status === 'running';
-    launchBtn.disabled = isRunning;
-    cancelBtn.disabled = !isRunning;
+    if (!isRunning) {
+      pauseState = null;
+      etaState = null;
+      setETA(null);
+    }
+    updateButtons();
+  }
+
+  // Pause button: enabled while shooting, turns into Resume while paused
+  // (state is null when no capture runs)
+  function setPaused(state) {
+    pauseState = state;
+    updateButtons();
+  }
+
+  // Enables the buttons that act on the rig for the current status, all
+  // disabled while another client holds control
+  function updateButtons() {
+    launchBtn.disabled = isRunning || readOnly;
+    cancelBtn.disabled = !isRunning || readOnly;
+    pauseBtn.disabled = readOnly || (pauseState !== 'shooting' && pauseState !== 'paused');
+    pauseBtn.textContent = pauseState === 'paused' ? 'Resume' : 'Pause';
+    jogStopBtn.disabled = readOnly;
+    jogBtns.forEach(function (btn) { btn.disabled = isRunning || readOnly; });
+  }
+
+  // Control lock: a page that claims control keeps it (renewing the claim)
+  // until released; the others only follow the status. Requests that act on
+  // the rig carry the claim token.
+  function withControl(headers) {
+    const h = Object.assign({}, headers);
+    if (controlToken) h['X-Control-Token'] = controlToken;
+    return h;
+  }
+
+  function showControl(ctl) {
+    if (!ctl.mine) {
+      controlToken = '';
+      sessionStorage.removeItem('pango-control');
+    }
+    readOnly = ctl.claimed && !ctl.mine;
+    controlEl.toggleAttribute('data-read-only', readOnly);
+    controlNote.textContent = ctl.mine ? 'You have control.' :
+      readOnly ? 'Controlled by ' + (ctl.holder || 'another client') + '.' : '';
+    controlBtn.textContent = ctl.mine ? 'Release control' : readOnly ? 'Take over' : 'Take control';
+    updateButtons();
+  }
+
+  async function loadControl() {
+    try {
+      const res = await fetch(API + '/control', { headers: withControl() });
+      if (res.ok) showControl(await res.json());
+    } catch {
+      // Server unreachable: keep the current state
+    }
+  }
+
+  async function claimControl(force) {
+    let holder = localStorage.getItem('pango-holder');
+    if (holder === null) {
+      holder = (prompt('Your name, shown to other users:', '') || '').trim();
+      localStorage.setItem('pango-holder', holder);
+    }
+    try {
+      const res = await fetch(API + '/control/claim', {
+        method: 'POST',
+        headers: withControl({ 'Content-Type': 'application/json' }),
+        body: JSON.stringify({ holder: holder, force: force })
+      });
+      if (!res.ok) {
+        appendConsole('Control not claimed: ' + (await res.text() || res.status), 'warning');
+        loadControl();
+        return;
+      }
+      const ctl = await res.json();
+      controlToken = ctl.token;
+      sessionStorage.setItem('pango-control', controlToken);
+      showControl(ctl);
+    } catch (err) {
+      appendConsole('Network error: ' + err.message, 'error');
+    }
   }
 
+  controlBtn.addEventListener('click', async function () {
+    if (controlToken) {
+      try {
+        await fetch(API + '/control/release', { method: 'POST', headers: withControl() });
+      } catch (err) {
+        appendConsole('Network error: ' + err.message, 'error');
+      }
+      loadControl();
+    } else if (!readOnly || confirm('Take control from the other user?')) {
+      claimControl(readOnly);
+    }
+  });
+
+  // Renew the claim well before it expires (60 s)
+  setInterval(function () {
+    if (controlToken) claimControl(false);
+  }, 20000);
+
   function appendConsole(msg, level) {
     const line = document.createElement('div');
     line.className = 'console-line';
@@ -46,42 +321,171 @@
     consoleEl.scrollTop = consoleEl.scrollHeight;
   }
 
+  // Open the status stream. After a disconnect (phone screen locked, WiFi
+  // drop), the server replays the events missed since lastEventId.
   function connectSSE() {
     if (evtSource) evtSource.close();
-    evtSource = new EventSource('/status/stream');
+    clearTimeout(reconnectTimer);
+    const query = lastEventId ? '?last_event_id=' + encodeURIComponent(lastEventId) : '';
+    evtSource = new EventSource(API + '/status/stream' + query);
     evtSource.onmessage = function (e) {
+      if (e.lastEventId) lastEventId = e.lastEventId;
+      let evt;
       try {
-        const data = JSON.parse(e.data);
-        appendConsole(data.msg || data, data.level || 'info');
-        if (/\b(complete|cancelled|failed)\b/i.test(data.msg || '')) {
-          setStatus('idle', 'Idle');
-        }
+        evt = JSON.parse(e.data);
       } catch {
         appendConsole(e.data);
+        return;
+      }
+      switch (evt.type) {
+        case 'state':
+          applyStatus(evt.data);
+          break;
+        case 'progress':
+          showProgress(evt.data.shots_done, evt.data.shots_total);
+          setETA(evt.data.eta);
+          break;
+        case 'shot':
+          if (evt.data.column) {
+            positionEl.textContent = 'Shot ' + evt.data.index + ': column ' + evt.data.column + ', row ' + evt.data.row +
+              (evt.data.error ? ' (failed)' : '');
+          }
+          // The picture is downloaded after the shot event (camera.download)
+          clearTimeout(shotsTimer);
+          shotsTimer = setTimeout(loadShots, 1500);
+          break;
+        case 'control':
+          loadControl();
+          break;
+        case 'move':
+          positionEl.textContent = 'Pan ' + evt.data.pan_steps + ' steps · Tilt ' + evt.data.tilt_steps + ' steps';
+          break;
+        case 'position':
+          showHead(evt.data);
+          break;
+        default:
+          appendConsole(evt.msg || e.data, evt.l || 'info');
       }
     };
     evtSource.onerror = function () {
       evtSource.close();
       evtSource = null;
+      reconnectTimer = setTimeout(connectSSE, 3000);
     };
   }
 
+  // Thumbnails of the downloaded shots of the current or last run, most
+  // recent first (GET /shots/latest; empty without camera.download)
+  async function loadShots() {
+    try {
+      const res = await fetch(API + '/shots/latest');
+      if (!res.ok) return;
+      const gallery = await res.json();
+      shotsEl.replaceChildren();
+      gallery.shots.slice().reverse().forEach(function (shot) {
+        const img = document.createElement('img');
+        img.src = shot.thumb;
+        img.alt = 'Shot ' + shot.index;
+        img.title = img.alt;
+        img.loading = 'lazy';
+        shotsEl.appendChild(img);
+      });
+      shotsEl.hidden = gallery.shots.length === 0;
+    } catch {
+      // Server unreachable: keep the current thumbnails
+    }
+  }
+
+  function showProgress(done, total) {
+    progressEl.hidden = !total;
+    if (total) progressBar.style.width = Math.min(100, 100 * done / total) + '%';
+  }
+
+  // Live head position (position events, sent while the head moves): the
+  // dial is a view from above, the pointer shortening as the head tilts
+  function showHead(pos) {
+    headEl.hidden = false;
+    headEl.classList.toggle('moving', pos.moving);
+    const reach = Math.max(0.15, Math.abs(Math.cos(pos.tilt_deg * Math.PI / 180)));
+    headPointerEl.style.transform = 'rotate(' + pos.pan_deg + 'deg) scaleY(' + reach + ')';
+    headAnglesEl.textContent = 'Pan ' + pos.pan_deg.toFixed(1) + '° · Tilt ' + pos.tilt_deg.toFixed(1) + '°';
+  }
+
+  // Countdown to the estimated end (GET /eta, or the eta of progress events),
+  // refreshed every second with the status
+  let etaEnd = null;
+  let etaState = null; // state when the ETA was last loaded: reloaded on changes (pause, resume)
+
+  function setETA(eta) {
+    etaEnd = eta && eta.completes_at ? new Date(eta.completes_at) : null;
+    showETA();
+  }
+
+  function showETA() {
+    etaEl.hidden = !etaEnd || !isRunning || etaState === 'paused';
+    if (etaEl.hidden) return;
+    const left = Math.max(0, (etaEnd - Date.now()) / 1000);
+    etaEl.textContent = 'About ' + formatDuration(Math.round(left)) + ' left · ends at ' +
+      etaEnd.toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+  }
+
+  async function loadETA() {
+    try {
+      const res = await fetch(API + '/eta');
+      if (res.ok) setETA(await res.json());
+    } catch (_) {
+      // Next progress event brings it
+    }
+  }
+
+  // Update the badge and progress from a capture status (GET /status or state event)
+  function applyStatus(st) {
+    if (ACTIVE_STATES.includes(st.state)) {
+      let label = st.state.charAt(0).toUpperCase() + st.state.slice(1);
+      if (st.shots_total) label += ' ' + st.shots_done + '/' + st.shots_total;
+      setStatus('running', label);
+      setPaused(st.state);
+      showProgress(st.shots_done, st.shots_total);
+      if (st.state !== etaState) {
+        etaState = st.state;
+        loadETA();
+      }
+      showETA();
+      return;
+    }
+    // Just launched: the capture may not have left idle yet
+    if (Date.now() - launchedAt < 2000) return;
+    if (isRunning) loadHistory(); // a run just ended
+    if (st.state === 'error') {
+      setStatus('error', 'Error');
+    } else if (isRunning) {
+      setStatus('idle', 'Idle');
+    }
+  }
+
+  async function refreshStatus() {
+    try {
+      const res = await fetch(API + '/status');
+      if (!res.ok) return;
+      applyStatus(await res.json());
+    } catch (_) {
+      // Server unreachable: keep the current badge
+    }
+  }
+
   form.addEventListener('submit', async function (e) {
     e.preventDefault();
     if (isRunning) return;
 
-    const payload = {
-      horizontal_angle_deg: parseFloat(form.horizontal_angle_deg.value),
-      vertical_angle_deg: parseFloat(form.vertical_angle_deg.value),
-      focal_length_mm: parseFloat(form.focal_length_mm.value)
-    };
+    const payload = formPayload();
 
     setStatus('running', 'Running…');
+    launchedAt = Date.now();
 
     try {
-      const res = await fetch('/run', {
+      const res = await fetch(API + '/run', {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: withControl({ 'Content-Type': 'application/json' }),
         body: JSON.stringify(payload)
       });
 
@@ -109,7 +513,7 @@
     if (!isRunning) return;
 
     try {
-      const res = await fetch('/cancel', { method: 'POST' });
+      const res = await fetch(API + '/cancel', { method: 'POST', headers: withControl() });
 
       if (res.ok) {
         appendConsole('Cancellation requested...', 'warning');
@@ -125,6 +529,169 @@
     }
   });
 
-  loadFormDefaults();
+  pauseBtn.addEventListener('click', async function () {
+    const resume = pauseBtn.textContent === 'Resume';
+    pauseBtn.disabled = true;
+    try {
+      const res = await fetch(API + (resume ? '/resume' : '/pause'), { method: 'POST', headers: withControl() });
+      if (res.ok) {
+        applyStatus(await res.json());
+      } else {
+        const err = await res.text();
+        appendConsole((resume ? 'Resume' : 'Pause') + ' failed: ' + (err || res.status), 'warning');
+        refreshStatus();
+      }
+    } catch (err) {
+      appendConsole('Network error: ' + err.message, 'error');
+    }
+  });
+
+  // Arrow buttons: move one axis by the selected angle to frame the start position
+  async function jog(axis, degrees) {
+    try {
+      const res = await fetch(API + '/jog', {
+        method: 'POST',
+        headers: withControl({ 'Content-Type': 'application/json' }),
+        body: JSON.stringify({ axis: axis, degrees: degrees })
+      });
+      if (res.status === 409) {
+        appendConsole('Head is busy.', 'warning');
+      } else if (!res.ok) {
+        const err = await res.text();
+        appendConsole('Jog failed: ' + (err || res.status), 'error');
+      }
+    } catch (err) {
+      appendConsole('Network error: ' + err.message, 'error');
+    }
+  }
+
+  jogBtns.forEach(function (btn) {
+    btn.addEventListener('click', function () {
+      jog(btn.dataset.axis, parseInt(btn.dataset.dir, 10) * parseFloat(jogStep.value));
+    });
+  });
+
+  // Press-and-hold framing with the arrow keys or a gamepad (GET /jog/ws):
+  // the directions held are resent every 200 ms, the server stops the head
+  // on release or when they stop coming.
+  const heldKeys = { ArrowLeft: false, ArrowRight: false, ArrowUp: false, ArrowDown: false };
+  let jogSocket = null;
+  let jogSent = '{"pan":0,"tilt":0}';
+  let jogHalted = false; // stopped by the server: wait for a release before moving again
+  let gamepadPolling = false;
+
+  function jogVelocity() {
+    let pan = (heldKeys.ArrowRight ? 1 : 0) - (heldKeys.ArrowLeft ? 1 : 0);
+    let tilt = (heldKeys.ArrowUp ? 1 : 0) - (heldKeys.ArrowDown ? 1 : 0);
+    for (const pad of navigator.getGamepads ? navigator.getGamepads() : []) {
+      if (!pad) continue;
+      const b = function (i) { return pad.buttons[i] && pad.buttons[i].pressed; };
+      if (!pan) pan = pad.axes[0] > 0.5 || b(15) ? 1 : pad.axes[0] < -0.5 || b(14) ? -1 : 0;
+      if (!tilt) tilt = pad.axes[1] < -0.5 || b(12) ? 1 : pad.axes[1] > 0.5 || b(13) ? -1 : 0;
+    }
+    return { pan: pan, tilt: tilt };
+  }
+
+  function sendJogVelocity(force) {
+    const msg = JSON.stringify(jogVelocity());
+    if (jogHalted) {
+      if (msg !== '{"pan":0,"tilt":0}') return;
+      jogHalted = false;
+    }
+    if (msg === jogSent && !force) return;
+    jogSent = msg;
+    if (!jogSocket) {
+      if (msg === '{"pan":0,"tilt":0}') return;
+      const proto = location.protocol === 'https:' ? 'wss:' : 'ws:';
+      const query = controlToken ? '?control_token=' + encodeURIComponent(controlToken) : '';
+      jogSocket = new WebSocket(proto + '//' + location.host + API + '/jog/ws' + query);
+      jogSocket.onopen = function () { jogSocket.send(jogSent); };
+      jogSocket.onmessage = function (e) {
+        const m = JSON.parse(e.data);
+        if (m.type === 'stopped') {
+          jogHalted = true;
+          jogSent = '{"pan":0,"tilt":0}';
+        }
+        if (m.error && m.error !== 'stopped') appendConsole('Jog: ' + m.error, 'warning');
+      };
+      jogSocket.onclose = function () { jogSocket = null; };
+      return;
+    }
+    if (jogSocket.readyState === WebSocket.OPEN) jogSocket.send(msg);
+  }
+
+  setInterval(function () {
+    if (jogSent !== '{"pan":0,"tilt":0}') sendJogVelocity(true);
+  }, 200);
+
+  document.addEventListener('keydown', function (e) {
+    if (!(e.key in heldKeys) || isRunning || readOnly || e.target.closest('input, select, textarea')) return;
+    e.preventDefault();
+    heldKeys[e.key] = true;
+    sendJogVelocity(false);
+  });
+  document.addEventListener('keyup', function (e) {
+    if (!(e.key in heldKeys)) return;
+    heldKeys[e.key] = false;
+    sendJogVelocity(false);
+  });
+  window.addEventListener('blur', function () {
+    Object.keys(heldKeys).forEach(function (k) { heldKeys[k] = false; });
+    sendJogVelocity(false);
+  });
+  window.addEventListener('gamepadconnected', function () {
+    if (gamepadPolling) return;
+    gamepadPolling = true;
+    (function poll() {
+      if (!isRunning && !readOnly) sendJogVelocity(false);
+      requestAnimationFrame(poll);
+    })();
+  });
+
+  // Live view (MJPEG): only for cameras that support it
+  function setLiveView(on) {
+    liveviewBtn.setAttribute('aria-pressed', on ? 'true' : 'false');
+    liveviewImg.hidden = !on;
+    if (on) {
+      liveviewImg.src = API + '/liveview';
+    } else {
+      liveviewImg.removeAttribute('src'); // closes the stream
+    }
+  }
+
+  liveviewBtn.addEventListener('click', function () {
+    setLiveView(liveviewImg.hidden);
+  });
+
+  liveviewImg.addEventListener('error', function () {
+    if (!liveviewImg.getAttribute('src')) return;
+    setLiveView(false);
+    appendConsole('Live view not available (unsupported camera, or a capture is running).', 'warning');
+  });
+
+  jogStopBtn.addEventListener('click', async function () {
+    try {
+      await fetch(API + '/stop', { method: 'POST', headers: withControl() }); // 409 when nothing moves: nothing to do
+    } catch (err) {
+      appendConsole('Network error: ' + err.message, 'error');
+    }
+  });
+
+  form.addEventListener('input', function (e) {
+    if (e.target !== profileSelect) schedulePlan();
+  });
+  profileSelect.addEventListener('change', function () {
+    activateProfile(profileSelect.value);
+  });
+
+  loadFormDefaults().then(refreshPlan);
+  loadProfiles();
+  loadShots();
+  loadControl();
+  document.addEventListener('visibilitychange', function () {
+    if (document.visibilityState === 'visible' && !evtSource) connectSSE();
+  });
   connectSSE();
+  refreshStatus();
+  setInterval(refreshStatus, 1000);
 })();
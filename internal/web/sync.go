@@ -0,0 +1,187 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// Errors of the synchronized capture of a follower rig.
+var (
+	ErrSyncEnded    = errors.New("the synchronized capture has ended")
+	ErrSyncNotReady = errors.New("the synchronized capture is not waiting at this shot")
+)
+
+// SyncCell is a shot of a synchronized capture: its number in the run, from
+// 1, and its grid cell.
+type SyncCell struct {
+	Shot   int `json:"shot"`
+	Column int `json:"column"`
+	Row    int `json:"row"`
+}
+
+// SyncGate holds the shots of a capture run as the follower of another rig
+// (multi-rig captures, see package multirig): at each cell, the capture
+// waits in Wait until the leader, having seen every follower Ready,
+// releases the shutter with Release.
+type SyncGate struct {
+	mu      sync.Mutex
+	shots   int           // Wait calls so far
+	waiting *SyncCell     // shot the capture waits to take; nil when moving
+	release chan struct{} // closed by Release
+	arrived chan struct{} // closed when the capture starts waiting, then replaced
+	done    chan struct{} // closed by Close
+}
+
+// NewSyncGate returns the gate of a new follower capture.
+func NewSyncGate() *SyncGate {
+	return &SyncGate{arrived: make(chan struct{}), done: make(chan struct{})}
+}
+
+// Wait blocks the capture at cell until the leader releases the shot or ctx
+// is done. Its signature matches capture.GridShotParams.BeforeShot.
+func (g *SyncGate) Wait(ctx context.Context, cell capture.Cell) error {
+	g.mu.Lock()
+	g.shots++
+	release := make(chan struct{})
+	g.waiting = &SyncCell{Shot: g.shots, Column: cell.Column, Row: cell.Row}
+	g.release = release
+	close(g.arrived)
+	g.mu.Unlock()
+
+	select {
+	case <-release:
+		return nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		if g.release == release {
+			g.waiting = nil
+			g.arrived = make(chan struct{})
+		}
+		g.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Ready waits until the capture waits at a shot, and returns it. It fails
+// with ErrSyncEnded once the capture has ended.
+func (g *SyncGate) Ready(ctx context.Context) (SyncCell, error) {
+	for {
+		g.mu.Lock()
+		waiting, arrived := g.waiting, g.arrived
+		g.mu.Unlock()
+		if waiting != nil {
+			return *waiting, nil
+		}
+		select {
+		case <-arrived:
+		case <-g.done:
+			return SyncCell{}, ErrSyncEnded
+		case <-ctx.Done():
+			return SyncCell{}, ctx.Err()
+		}
+	}
+}
+
+// Release lets the capture waiting at shot take it. It fails with
+// ErrSyncNotReady when the capture waits elsewhere or not at all.
+func (g *SyncGate) Release(shot int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.waiting == nil || g.waiting.Shot != shot {
+		return fmt.Errorf("%w (shot %d)", ErrSyncNotReady, shot)
+	}
+	close(g.release)
+	g.waiting = nil
+	g.arrived = make(chan struct{})
+	return nil
+}
+
+// Close ends the gate when the capture ends: Ready then fails at once.
+func (g *SyncGate) Close() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	select {
+	case <-g.done:
+	default:
+		close(g.done)
+	}
+}
+
+type syncGateKey struct{}
+
+// WithSyncGate returns a copy of ctx carrying the gate of a follower
+// capture.
+func WithSyncGate(ctx context.Context, g *SyncGate) context.Context {
+	return context.WithValue(ctx, syncGateKey{}, g)
+}
+
+// SyncGateFrom returns the gate carried by the context of a capture run as
+// a follower, or nil for an ordinary capture.
+func SyncGateFrom(ctx context.Context) *SyncGate {
+	return syncGate(ctx)
+}
+
+func syncGate(ctx context.Context) *SyncGate {
+	g, _ := ctx.Value(syncGateKey{}).(*SyncGate)
+	return g
+}
+
+// HandleSyncRun handles POST /sync/run: starts a capture, like POST /run,
+// whose shots wait for the leader rig (GET /sync/ready, POST /sync/shoot).
+func (h *Handlers) HandleSyncRun(w http.ResponseWriter, r *http.Request) {
+	h.startCapture(w, r, NewSyncGate())
+}
+
+// HandleSyncReady handles GET /sync/ready: waits until the synchronized
+// capture is in place for its next shot and returns it. 409 when no
+// synchronized capture runs.
+func (h *Handlers) HandleSyncReady(w http.ResponseWriter, r *http.Request) {
+	gate := h.currentSyncGate()
+	if gate == nil {
+		http.Error(w, "no synchronized capture", http.StatusConflict)
+		return
+	}
+	keepWriting(w)
+	cell, err := gate.Ready(r.Context())
+	switch {
+	case errors.Is(err, ErrSyncEnded):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		return // client gone
+	}
+	writeJSON(w, http.StatusOK, cell)
+}
+
+// HandleSyncShoot handles POST /sync/shoot: releases the shot the
+// synchronized capture waits to take. 409 when it waits at another shot.
+func (h *Handlers) HandleSyncShoot(w http.ResponseWriter, r *http.Request) {
+	var cell SyncCell
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&cell); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	gate := h.currentSyncGate()
+	if gate == nil {
+		http.Error(w, "no synchronized capture", http.StatusConflict)
+		return
+	}
+	if err := gate.Release(cell.Shot); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeJSON(w, http.StatusOK, cell)
+}
+
+func (h *Handlers) currentSyncGate() *SyncGate {
+	h.syncMu.Lock()
+	defer h.syncMu.Unlock()
+	return h.syncGate
+}
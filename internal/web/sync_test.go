@@ -0,0 +1,161 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// ---------- SyncGate ----------
+
+func TestSyncGate(t *testing.T) {
+	g := NewSyncGate()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	waited := make(chan error, 1)
+	go func() { waited <- g.Wait(ctx, capture.Cell{Column: 1, Row: 2}) }()
+
+	cell, err := g.Ready(ctx)
+	if err != nil || cell != (SyncCell{Shot: 1, Column: 1, Row: 2}) {
+		t.Fatalf("Ready = %+v, %v", cell, err)
+	}
+	if err := g.Release(2); !errors.Is(err, ErrSyncNotReady) {
+		t.Errorf("Release(other shot) = %v, want ErrSyncNotReady", err)
+	}
+	select {
+	case err := <-waited:
+		t.Fatalf("Wait returned before the release: %v", err)
+	default:
+	}
+	if err := g.Release(1); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := <-waited; err != nil {
+		t.Errorf("Wait: %v", err)
+	}
+	if err := g.Release(1); !errors.Is(err, ErrSyncNotReady) {
+		t.Errorf("Release twice = %v, want ErrSyncNotReady", err)
+	}
+
+	go func() { waited <- g.Wait(ctx, capture.Cell{Column: 2, Row: 2}) }()
+	if cell, _ := g.Ready(ctx); cell.Shot != 2 {
+		t.Errorf("second shot = %+v", cell)
+	}
+	g.Release(2)
+	<-waited
+
+	g.Close()
+	if _, err := g.Ready(ctx); !errors.Is(err, ErrSyncEnded) {
+		t.Errorf("Ready after Close = %v, want ErrSyncEnded", err)
+	}
+}
+
+func TestSyncGate_WaitCancelled(t *testing.T) {
+	g := NewSyncGate()
+	ctx, cancel := context.WithCancel(context.Background())
+	waited := make(chan error, 1)
+	go func() { waited <- g.Wait(ctx, capture.Cell{Column: 1, Row: 1}) }()
+	if _, err := g.Ready(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := <-waited; !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait = %v, want context.Canceled", err)
+	}
+	if err := g.Release(1); !errors.Is(err, ErrSyncNotReady) {
+		t.Errorf("Release after cancel = %v, want ErrSyncNotReady", err)
+	}
+	short, cancelShort := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancelShort()
+	if _, err := g.Ready(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Ready while moving = %v, want the deadline", err)
+	}
+}
+
+// ---------- Handlers ----------
+
+func TestSyncRoutes(t *testing.T) {
+	shots := make(chan SyncCell, 2)
+	runCapture := func(ctx context.Context, _ Overrides) error {
+		gate := SyncGateFrom(ctx)
+		if gate == nil {
+			return errors.New("not synchronized")
+		}
+		defer gate.Close()
+		for _, cell := range []capture.Cell{{Column: 1, Row: 1}, {Column: 1, Row: 2}} {
+			if err := gate.Wait(ctx, cell); err != nil {
+				return err
+			}
+			shots <- SyncCell{Column: cell.Column, Row: cell.Row}
+		}
+		return nil
+	}
+	srv := NewServer(":0", NewStatusBroadcaster(), runCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	mux := srv.Mux()
+	do := func(method, path string, body any) *httptest.ResponseRecorder {
+		var data []byte
+		if body != nil {
+			data, _ = json.Marshal(body)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, APIPrefix+path, bytes.NewReader(data)))
+		return w
+	}
+
+	if w := do(http.MethodGet, "/sync/ready", nil); w.Code != http.StatusConflict {
+		t.Errorf("ready before any run: status %d, want 409", w.Code)
+	}
+	if w := do(http.MethodPost, "/sync/run", Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35}); w.Code != http.StatusAccepted {
+		t.Fatalf("run: status %d: %s", w.Code, w.Body)
+	}
+
+	for shot := 1; shot <= 2; shot++ {
+		w := do(http.MethodGet, "/sync/ready", nil)
+		var cell SyncCell
+		if err := json.Unmarshal(w.Body.Bytes(), &cell); w.Code != http.StatusOK || err != nil || cell.Shot != shot {
+			t.Fatalf("ready: status %d, body %s", w.Code, w.Body)
+		}
+		select {
+		case <-shots:
+			t.Fatal("shot taken before the release")
+		default:
+		}
+		if w := do(http.MethodPost, "/sync/shoot", SyncCell{Shot: shot + 1}); w.Code != http.StatusConflict {
+			t.Errorf("shoot the wrong shot: status %d, want 409", w.Code)
+		}
+		if w := do(http.MethodPost, "/sync/shoot", cell); w.Code != http.StatusOK {
+			t.Fatalf("shoot: status %d: %s", w.Code, w.Body)
+		}
+		if got := <-shots; got.Row != cell.Row {
+			t.Errorf("shot at %+v, want %+v", got, cell)
+		}
+	}
+	if w := do(http.MethodGet, "/sync/ready", nil); w.Code != http.StatusConflict {
+		t.Errorf("ready after the run: status %d, want 409", w.Code)
+	}
+}
+
+func TestHandleRun_NotSynchronized(t *testing.T) {
+	got := make(chan *SyncGate, 1)
+	h := newTestHandlers(func(ctx context.Context, _ Overrides) error {
+		got <- SyncGateFrom(ctx)
+		return nil
+	})
+	w := httptest.NewRecorder()
+	h.HandleRun(w, httptest.NewRequest(http.MethodPost, "/run", bytes.NewReader(validOverridesJSON())))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status %d", w.Code)
+	}
+	if g := <-got; g != nil {
+		t.Error("POST /run capture carries a sync gate")
+	}
+}
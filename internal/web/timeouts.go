@@ -0,0 +1,57 @@
+package web
+
+import (
+	"net/http"
+	"time"
+)
+
+// Timeouts bounds how long the HTTP server waits on clients, so that stuck
+// or slow connections do not hold memory on the Pi. Zero fields take the
+// defaults of DefaultTimeouts.
+type Timeouts struct {
+	ReadHeader time.Duration // reading the request headers
+	Read       time.Duration // reading the whole request, body included
+	// Write bounds the response. Event streams, WebSockets, the live view,
+	// jogs and profiles are excepted. 0 = no limit.
+	Write    time.Duration
+	Idle     time.Duration // keep-alive connection between two requests
+	Shutdown time.Duration // in-flight requests on shutdown, once the capture stopped
+}
+
+// DefaultTimeouts are the timeouts used for zero Timeouts fields.
+var DefaultTimeouts = Timeouts{
+	ReadHeader: 5 * time.Second,
+	Read:       5 * time.Second,
+	Idle:       120 * time.Second,
+	Shutdown:   5 * time.Second,
+}
+
+// withDefaults returns t with its zero fields set from DefaultTimeouts.
+func (t Timeouts) withDefaults() Timeouts {
+	for _, f := range []struct{ v, def *time.Duration }{
+		{&t.ReadHeader, &DefaultTimeouts.ReadHeader},
+		{&t.Read, &DefaultTimeouts.Read},
+		{&t.Write, &DefaultTimeouts.Write},
+		{&t.Idle, &DefaultTimeouts.Idle},
+		{&t.Shutdown, &DefaultTimeouts.Shutdown},
+	} {
+		if *f.v == 0 {
+			*f.v = *f.def
+		}
+	}
+	return t
+}
+
+// keepWriting lifts the write timeout of a long-lived response: event
+// streams, WebSockets (before the upgrade), the live view and jogs.
+func keepWriting(w http.ResponseWriter) {
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+}
+
+// keepWritingHandler wraps next with keepWriting.
+func keepWritingHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keepWriting(w)
+		next.ServeHTTP(w, r)
+	})
+}
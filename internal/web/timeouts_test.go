@@ -0,0 +1,106 @@
+package web
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runTestServer runs srv on a local listener until the test ends and
+// returns its address.
+func runTestServer(t *testing.T, srv *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.SetListeners([]net.Listener{ln})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return ln.Addr().String()
+}
+
+// ---------- Timeouts ----------
+
+func TestTimeouts_WithDefaults(t *testing.T) {
+	got := Timeouts{Write: time.Minute, Idle: 10 * time.Second}.withDefaults()
+	want := Timeouts{ReadHeader: 5 * time.Second, Read: 5 * time.Second, Write: time.Minute, Idle: 10 * time.Second, Shutdown: 5 * time.Second}
+	if got != want {
+		t.Errorf("withDefaults = %+v, want %+v", got, want)
+	}
+}
+
+func TestServer_ReadHeaderTimeout(t *testing.T) {
+	srv := NewServer("", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	srv.SetTimeouts(Timeouts{ReadHeader: 100 * time.Millisecond})
+	addr := runTestServer(t, srv)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET /healthz HTTP/1.1\r\nHost: pango\r\n")) // headers never end
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	start := time.Now()
+	buf := make([]byte, 512)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				t.Fatal("connection still open after the read header timeout")
+			}
+			break
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("closed after %v", d)
+	}
+}
+
+func TestServer_WriteTimeoutSparesStreams(t *testing.T) {
+	srv := NewServer("", NewStatusBroadcaster(), noopCapture, FormConfig{})
+	srv.SetAccessLog(nil)
+	srv.SetTimeouts(Timeouts{Write: 100 * time.Millisecond})
+	addr := runTestServer(t, srv)
+
+	resp, err := http.Get("http://" + addr + APIPrefix + "/status/stream?types=log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	time.Sleep(300 * time.Millisecond) // past the write timeout
+	srv.Handlers().Broadcaster.Broadcast("info", "still streaming")
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	deadline := time.After(2 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream closed by the write timeout")
+			}
+			if strings.Contains(line, "still streaming") {
+				return
+			}
+		case <-deadline:
+			t.Fatal("event not received")
+		}
+	}
+}
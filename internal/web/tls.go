@@ -0,0 +1,132 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"net"
+	"os"
+	"time"
+)
+
+// Validity of generated self-signed certificates.
+const selfSignedValidity = 10 * 365 * 24 * time.Hour
+
+// TLSConfig enables HTTPS. CertFile/KeyFile are PEM files; with SelfSigned,
+// a certificate is generated when they are missing (and saved to them when
+// the paths are set, so browsers only have to accept it once).
+type TLSConfig struct {
+	CertFile   string
+	KeyFile    string
+	SelfSigned bool
+}
+
+func (c TLSConfig) enabled() bool {
+	return c.CertFile != "" || c.SelfSigned
+}
+
+// LoadCertificate returns the certificate described by c: loaded from
+// CertFile/KeyFile, or generated (and saved) when SelfSigned is set and
+// neither file exists. A lone certificate or key is an error rather than
+// overwritten: it may be one the user put there.
+func LoadCertificate(c TLSConfig) (tls.Certificate, error) {
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		switch {
+		case err == nil:
+			return cert, nil
+		case !c.SelfSigned || !errors.Is(err, fs.ErrNotExist):
+			return tls.Certificate{}, fmt.Errorf("load TLS certificate: %w", err)
+		}
+		for _, name := range []string{c.CertFile, c.KeyFile} {
+			if _, statErr := os.Stat(name); statErr == nil {
+				return tls.Certificate{}, fmt.Errorf("load TLS certificate: %w (%s exists without its pair: remove it to generate a new one)", err, name)
+			}
+		}
+	}
+
+	certPEM, keyPEM, err := GenerateSelfSigned(localHosts())
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if c.CertFile != "" {
+		if err := os.WriteFile(c.KeyFile, keyPEM, 0o600); err != nil {
+			return tls.Certificate{}, fmt.Errorf("save TLS key: %w", err)
+		}
+		if err := os.WriteFile(c.CertFile, certPEM, 0o644); err != nil {
+			return tls.Certificate{}, fmt.Errorf("save TLS certificate: %w", err)
+		}
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// GenerateSelfSigned creates a self-signed ECDSA P-256 certificate valid for
+// the given host names and IP addresses. It returns PEM-encoded certificate
+// and private key.
+func GenerateSelfSigned(hosts []string) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate TLS key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	tmpl := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"PanGo"}, CommonName: "PanGo"},
+		NotBefore:             now.Add(-time.Hour), // tolerate a Pi clock slightly behind
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create TLS certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode TLS key: %w", err)
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+// localHosts returns the names and addresses the rig can be reached at:
+// localhost, the host name (and its .local mDNS name) and interface IPs.
+func localHosts() []string {
+	hosts := []string{"localhost"}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hosts = append(hosts, name, name+".local")
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return hosts
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			hosts = append(hosts, ipnet.IP.String())
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,140 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateSelfSigned(t *testing.T) {
+	certPEM, keyPEM, err := GenerateSelfSigned([]string{"localhost", "pango.local", "192.168.1.20"})
+	if err != nil {
+		t.Fatalf("GenerateSelfSigned: %v", err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("X509KeyPair: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if err := leaf.VerifyHostname("pango.local"); err != nil {
+		t.Errorf("VerifyHostname(pango.local): %v", err)
+	}
+	if err := leaf.VerifyHostname("192.168.1.20"); err != nil {
+		t.Errorf("VerifyHostname(192.168.1.20): %v", err)
+	}
+}
+
+func TestLoadCertificate_SelfSignedSavedAndReused(t *testing.T) {
+	dir := t.TempDir()
+	c := TLSConfig{
+		CertFile:   filepath.Join(dir, "cert.pem"),
+		KeyFile:    filepath.Join(dir, "key.pem"),
+		SelfSigned: true,
+	}
+	first, err := LoadCertificate(c)
+	if err != nil {
+		t.Fatalf("LoadCertificate: %v", err)
+	}
+	info, err := os.Stat(c.KeyFile)
+	if err != nil {
+		t.Fatalf("key not saved: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("key permissions = %o, want 600", perm)
+	}
+
+	second, err := LoadCertificate(c)
+	if err != nil {
+		t.Fatalf("LoadCertificate (reload): %v", err)
+	}
+	if string(first.Certificate[0]) != string(second.Certificate[0]) {
+		t.Error("a saved certificate should be reused, not regenerated")
+	}
+}
+
+func TestLoadCertificate_HalfPairNotOverwritten(t *testing.T) {
+	certPEM, keyPEM, err := GenerateSelfSigned([]string{"localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name    string
+		present string // the file left in place
+		data    []byte
+	}{
+		{"cert only", "cert.pem", certPEM},
+		{"key only", "key.pem", keyPEM},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			c := TLSConfig{
+				CertFile:   filepath.Join(dir, "cert.pem"),
+				KeyFile:    filepath.Join(dir, "key.pem"),
+				SelfSigned: true,
+			}
+			present := filepath.Join(dir, tc.present)
+			if err := os.WriteFile(present, tc.data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			_, err := LoadCertificate(c)
+			if err == nil || !strings.Contains(err.Error(), present+" exists without its pair") {
+				t.Errorf("LoadCertificate = %v, want an error naming %s", err, present)
+			}
+			if got, _ := os.ReadFile(present); string(got) != string(tc.data) {
+				t.Errorf("%s was overwritten", tc.present)
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("%d files in the directory, want the one left in place", len(entries))
+			}
+		})
+	}
+}
+
+func TestLoadCertificate_InMemory(t *testing.T) {
+	if _, err := LoadCertificate(TLSConfig{SelfSigned: true}); err != nil {
+		t.Fatalf("LoadCertificate: %v", err)
+	}
+}
+
+func TestLoadCertificate_MissingFilesWithoutSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+	_, err := LoadCertificate(TLSConfig{
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+	})
+	if err == nil {
+		t.Fatal("expected error for missing certificate files")
+	}
+}
+
+func TestLoadCertificate_ServesHTTPS(t *testing.T) {
+	cert, err := LoadCertificate(TLSConfig{SelfSigned: true})
+	if err != nil {
+		t.Fatalf("LoadCertificate: %v", err)
+	}
+	srv := httptest.NewUnstartedServer(okHandler)
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // self-signed
+	}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("GET over TLS: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
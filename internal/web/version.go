@@ -0,0 +1,46 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+)
+
+// BuildInfo identifies the PanGo build serving the API, for GET /version.
+type BuildInfo struct {
+	Version   string `json:"version"`            // release, e.g. "v1.4.0", or "dev"
+	Commit    string `json:"commit,omitempty"`   // VCS revision
+	Modified  bool   `json:"modified,omitempty"` // built from a tree with uncommitted changes
+	Date      string `json:"date,omitempty"`     // build date (or commit date), RFC 3339
+	GoVersion string `json:"go_version"`         // Go toolchain, e.g. "go1.25.7"
+}
+
+// String describes b on one line, e.g. "v1.4.0 (commit 0123456789ab,
+// built 2026-10-01T12:00:00Z, go1.25.7)".
+func (b BuildInfo) String() string {
+	var details []string
+	if b.Commit != "" {
+		commit := b.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if b.Modified {
+			commit += "-dirty"
+		}
+		details = append(details, "commit "+commit)
+	}
+	if b.Date != "" {
+		details = append(details, "built "+b.Date)
+	}
+	if b.GoVersion != "" {
+		details = append(details, b.GoVersion)
+	}
+	if len(details) == 0 {
+		return b.Version
+	}
+	return b.Version + " (" + strings.Join(details, ", ") + ")"
+}
+
+// HandleVersion handles GET /version: the build serving the API.
+func (h *Handlers) HandleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.Build)
+}
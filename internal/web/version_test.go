@@ -0,0 +1,47 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// ---------- HandleVersion ----------
+
+func TestHandleVersion(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Build = BuildInfo{Version: "v1.4.0", Commit: "0123456789abcdef", Date: "2026-10-01T12:00:00Z", GoVersion: "go1.25.7"}
+
+	w := httptest.NewRecorder()
+	h.HandleVersion(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	var got BuildInfo
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got != h.Build {
+		t.Errorf("build = %+v, want %+v", got, h.Build)
+	}
+}
+
+func TestBuildInfo_String(t *testing.T) {
+	cases := []struct {
+		name string
+		b    BuildInfo
+		want string
+	}{
+		{"release", BuildInfo{Version: "v1.4.0", Commit: "0123456789abcdef", Date: "2026-10-01T12:00:00Z", GoVersion: "go1.25.7"}, "v1.4.0 (commit 0123456789ab, built 2026-10-01T12:00:00Z, go1.25.7)"},
+		{"modified", BuildInfo{Version: "dev", Commit: "0123456789abcdef", Modified: true, GoVersion: "go1.25.7"}, "dev (commit 0123456789ab-dirty, go1.25.7)"},
+		{"version_only", BuildInfo{Version: "dev"}, "dev"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.b.String(); got != tc.want {
+				t.Errorf("String() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
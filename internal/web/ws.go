@@ -0,0 +1,225 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket timings: the client must answer pings within wsPongWait.
+const (
+	wsWriteWait = 5 * time.Second
+	wsPongWait  = 60 * time.Second
+)
+
+// WSCommand is a message sent by a WebSocket client on /ws.
+// ID is optional and echoed back in the reply.
+type WSCommand struct {
+	ID        string     `json:"id,omitempty"`
+	Cmd       string     `json:"cmd"` // "run", "cancel", "pause", "resume", "jog" or "stop"
+	Overrides *Overrides `json:"overrides,omitempty"`
+	Axis      string     `json:"axis,omitempty"`     // jog: "pan" or "tilt"
+	Degrees   float64    `json:"degrees,omitempty"`  // jog: signed angle
+	Steps     int        `json:"steps,omitempty"`    // jog: signed motor steps
+	SpeedMs   int        `json:"speed_ms,omitempty"` // jog: delay between steps
+}
+
+// WSMessage is a message sent to WebSocket clients on /ws:
+//   - "event": a status event (same payload as SSE), in Event
+//   - "status": the capture lifecycle snapshot, sent on connect, in Status
+//   - "reply": the outcome of a command, with OK and Error
+//
+// On /jog/ws (see HandleJogStream), the server sends "stopped" with the
+// reason in Error, and "error" for rejected messages.
+type WSMessage struct {
+	Type   string       `json:"type"`
+	ID     string       `json:"id,omitempty"`
+	Event  *StatusEvent `json:"event,omitempty"`
+	Status any          `json:"status,omitempty"`
+	OK     bool         `json:"ok,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// wsUpgrader rejects cross-origin upgrades (the default origin check):
+// browsers do not apply CORS to WebSockets.
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// HandleWS handles GET /ws: a bidirectional connection carrying the status
+// events broadcast to SSE clients, and accepting run, cancel, pause, resume,
+// jog and stop commands. As for SSE, the types and level query parameters
+// select events. When control is claimed (see ControlLock), commands need
+// the holder's token in the control_token query parameter.
+func (h *Handlers) HandleWS(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseEventFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	keepWriting(w)
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade already wrote the HTTP error
+	}
+	defer conn.Close()
+
+	ch, unsub := h.Broadcaster.SubscribeFiltered(filter)
+	defer unsub()
+	dbg.Verbose("websocket opened", "client", clientIP(r))
+	defer dbg.Verbose("websocket closed", "client", clientIP(r))
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Replies from the reader go through the writer: gorilla connections
+	// support one concurrent writer only.
+	replies := make(chan WSMessage, 8)
+	go h.wsReadLoop(ctx, cancel, conn, replies, controlToken(r), h.checkNetwork(r))
+
+	interval := h.HeartbeatInterval
+	if interval == 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	if h.Lifecycle != nil {
+		if err := wsWrite(conn, WSMessage{Type: "status", Status: h.Lifecycle.Snapshot()}); err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			var evt StatusEvent
+			if err := json.Unmarshal([]byte(msg), &evt); err != nil {
+				continue
+			}
+			if err := wsWrite(conn, WSMessage{Type: "event", Event: &evt}); err != nil {
+				return
+			}
+
+		case reply := <-replies:
+			if err := wsWrite(conn, reply); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// wsReadLoop reads commands until the connection closes, then cancels ctx.
+// token is the control token of the client; commands fail with netErr when
+// set (see checkNetwork).
+func (h *Handlers) wsReadLoop(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn, replies chan<- WSMessage, token string, netErr error) {
+	defer cancel()
+	conn.SetReadLimit(maxRequestBodyBytes)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var cmd WSCommand
+		if err := conn.ReadJSON(&cmd); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				select {
+				case replies <- WSMessage{Type: "reply", Error: "invalid JSON"}:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			return
+		}
+		reply := WSMessage{Type: "reply", ID: cmd.ID, OK: true}
+		err := netErr
+		if err == nil {
+			err = h.wsExecute(ctx, cmd, token)
+		}
+		if err != nil {
+			reply.OK = false
+			reply.Error = err.Error()
+		}
+		select {
+		case replies <- reply:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// wsExecute runs one client command with the same rules as the HTTP endpoints.
+func (h *Handlers) wsExecute(ctx context.Context, cmd WSCommand, token string) error {
+	if h.Control != nil {
+		if err := h.Control.check(token); err != nil {
+			return err // every command acts on the rig
+		}
+	}
+	switch cmd.Cmd {
+	case "run":
+		if cmd.Overrides == nil {
+			return errors.New("run: overrides are required")
+		}
+		if err := ValidateOverrides(*cmd.Overrides); err != nil {
+			return err
+		}
+		if h.Jobs == nil {
+			return errors.New("capture not configured")
+		}
+		_, err := h.Jobs.StartNow(ctx, *cmd.Overrides)
+		return err
+
+	case "cancel":
+		if h.Jobs == nil || !h.Jobs.CancelRunning() {
+			return errors.New("no capture in progress")
+		}
+		return nil
+
+	case "pause", "resume":
+		return h.pauseCapture(cmd.Cmd == "pause")
+
+	case "jog":
+		req := JogRequest{Axis: cmd.Axis, Degrees: cmd.Degrees, Steps: cmd.Steps, SpeedMs: cmd.SpeedMs}
+		if err := ValidateJog(req); err != nil {
+			return err
+		}
+		return h.runJog(ctx, req)
+
+	case "stop":
+		if !h.stopJog() {
+			return errors.New("no jog in progress")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q", cmd.Cmd)
+	}
+}
+
+// wsWrite sends msg as JSON with a write deadline.
+func wsWrite(conn *websocket.Conn, msg WSMessage) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(msg)
+}
@@ -0,0 +1,283 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/cjeanneret/PanGo/internal/logic/capture"
+)
+
+// dialWS starts a test server for h.HandleWS and connects to it. The
+// lifecycle is set so the handler sends its "status" message once
+// subscribed; dialWS consumes it, so broadcasts made afterwards are received.
+func dialWS(t *testing.T, h *Handlers) *websocket.Conn {
+	t.Helper()
+	if h.Lifecycle == nil {
+		h.Lifecycle = capture.NewLifecycle()
+	}
+	srv := httptest.NewServer(http.HandlerFunc(h.HandleWS))
+	t.Cleanup(srv.Close)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if msg := readWS(t, conn); msg.Type != "status" {
+		t.Fatalf("first message type = %q, want status", msg.Type)
+	}
+	return conn
+}
+
+func readWS(t *testing.T, conn *websocket.Conn) WSMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg WSMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return msg
+}
+
+// sendWS sends cmd and returns the reply, skipping broadcast events.
+func sendWS(t *testing.T, conn *websocket.Conn, cmd WSCommand) WSMessage {
+	t.Helper()
+	if err := conn.WriteJSON(cmd); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	for {
+		if msg := readWS(t, conn); msg.Type == "reply" {
+			return msg
+		}
+	}
+}
+
+// ---------- HandleWS ----------
+
+func TestHandleWS_StatusOnConnect(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Lifecycle = capture.NewLifecycle()
+	srv := httptest.NewServer(http.HandlerFunc(h.HandleWS))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	var msg struct {
+		Type   string         `json:"type"`
+		Status capture.Status `json:"status"`
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if msg.Type != "status" || msg.Status.State != capture.StateIdle {
+		t.Errorf("got %+v, want idle status", msg)
+	}
+}
+
+func TestHandleWS_ReceivesBroadcast(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	conn := dialWS(t, h)
+
+	h.Broadcaster.Broadcast("warning", "cap the lens")
+	msg := readWS(t, conn)
+	if msg.Type != "event" || msg.Event == nil {
+		t.Fatalf("got %+v, want event", msg)
+	}
+	if msg.Event.Level != "warning" || msg.Event.Msg != "cap the lens" {
+		t.Errorf("event = %+v", msg.Event)
+	}
+}
+
+func TestHandleWS_RunAndCancel(t *testing.T) {
+	started := make(chan struct{})
+	h := newTestHandlers(func(ctx context.Context, _ Overrides) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	conn := dialWS(t, h)
+
+	o := Overrides{HorizontalAngleDeg: 180, VerticalAngleDeg: 30, FocalLengthMm: 35}
+	reply := sendWS(t, conn, WSCommand{ID: "1", Cmd: "run", Overrides: &o})
+	if !reply.OK || reply.ID != "1" {
+		t.Fatalf("run reply = %+v, want ok with id 1", reply)
+	}
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("capture not started")
+	}
+
+	reply = sendWS(t, conn, WSCommand{Cmd: "run", Overrides: &o})
+	if reply.OK {
+		t.Error("second run while busy should fail")
+	}
+
+	reply = sendWS(t, conn, WSCommand{ID: "2", Cmd: "cancel"})
+	if !reply.OK {
+		t.Errorf("cancel reply = %+v, want ok", reply)
+	}
+}
+
+func TestHandleWS_RunValidatesOverrides(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	conn := dialWS(t, h)
+
+	if reply := sendWS(t, conn, WSCommand{Cmd: "run"}); reply.OK {
+		t.Error("run without overrides should fail")
+	}
+	bad := Overrides{HorizontalAngleDeg: 0, VerticalAngleDeg: 30, FocalLengthMm: 35}
+	if reply := sendWS(t, conn, WSCommand{Cmd: "run", Overrides: &bad}); reply.OK {
+		t.Error("run with invalid overrides should fail")
+	}
+	if _, running := h.Jobs.Running(); running {
+		t.Error("no capture should have started")
+	}
+}
+
+func TestHandleWS_Jog(t *testing.T) {
+	var mu sync.Mutex
+	var gotAxis string
+	var gotDeg float64
+	h := newTestHandlers(noopCapture)
+	h.Jog = func(_ context.Context, req JogRequest) error {
+		mu.Lock()
+		defer mu.Unlock()
+		gotAxis, gotDeg = req.Axis, req.Degrees
+		return nil
+	}
+	conn := dialWS(t, h)
+
+	if reply := sendWS(t, conn, WSCommand{Cmd: "jog", Axis: "tilt", Degrees: -2.5}); !reply.OK {
+		t.Fatalf("jog reply = %+v, want ok", reply)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if gotAxis != "tilt" || gotDeg != -2.5 {
+		t.Errorf("jog called with %q %v, want tilt -2.5", gotAxis, gotDeg)
+	}
+}
+
+func TestHandleWS_JogErrors(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	conn := dialWS(t, h)
+
+	cases := []struct {
+		name string
+		cmd  WSCommand
+	}{
+		{"unknown_axis", WSCommand{Cmd: "jog", Axis: "roll", Degrees: 1}},
+		{"too_far", WSCommand{Cmd: "jog", Axis: "pan", Degrees: 720}},
+		{"no_amount", WSCommand{Cmd: "jog", Axis: "pan"}},
+		{"not_available", WSCommand{Cmd: "jog", Axis: "pan", Degrees: 1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if reply := sendWS(t, conn, tc.cmd); reply.OK || reply.Error == "" {
+				t.Errorf("reply = %+v, want error", reply)
+			}
+		})
+	}
+
+	h = newTestHandlers(noopCapture)
+	h.Jog = func(context.Context, JogRequest) error { return errors.New("head is busy") }
+	conn = dialWS(t, h)
+	if reply := sendWS(t, conn, WSCommand{Cmd: "jog", Axis: "pan", Degrees: 1}); reply.Error != "head is busy" {
+		t.Errorf("reply = %+v, want jog error forwarded", reply)
+	}
+}
+
+func TestHandleWS_UnknownCommandAndInvalidJSON(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	conn := dialWS(t, h)
+
+	if reply := sendWS(t, conn, WSCommand{ID: "x", Cmd: "dance"}); reply.OK || reply.ID != "x" {
+		t.Errorf("reply = %+v, want error with id x", reply)
+	}
+
+	conn.WriteMessage(websocket.TextMessage, []byte("{not json"))
+	if reply := readWS(t, conn); reply.Type != "reply" || reply.Error != "invalid JSON" {
+		t.Errorf("reply = %+v, want invalid JSON error", reply)
+	}
+	// The connection stays usable after a bad message
+	if reply := sendWS(t, conn, WSCommand{Cmd: "cancel"}); reply.Error != "no capture in progress" {
+		t.Errorf("reply = %+v", reply)
+	}
+}
+
+func TestHandleWS_RejectsCrossOrigin(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	srv := httptest.NewServer(http.HandlerFunc(h.HandleWS))
+	defer srv.Close()
+
+	header := http.Header{"Origin": {"http://evil.example"}}
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), header)
+	if err == nil {
+		t.Fatal("expected cross-origin upgrade to be rejected")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("response = %v, want 403", resp)
+	}
+}
+
+func TestHandleWS_Stop(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	moving := make(chan struct{})
+	h.Jog = func(ctx context.Context, _ JogRequest) error {
+		close(moving)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	conn := dialWS(t, h)
+
+	if reply := sendWS(t, conn, WSCommand{Cmd: "stop"}); reply.OK {
+		t.Error("stop without a jog should fail")
+	}
+
+	// Commands are handled one at a time per connection: jog from HTTP,
+	// stop from the WebSocket.
+	done := make(chan error, 1)
+	go func() { done <- h.runJog(context.Background(), JogRequest{Axis: "pan", Steps: 100}) }()
+	<-moving
+	if reply := sendWS(t, conn, WSCommand{Cmd: "stop"}); !reply.OK {
+		t.Fatalf("stop reply = %+v, want ok", reply)
+	}
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("jog err = %v, want context.Canceled", err)
+	}
+}
+
+func TestHandleWS_PauseResume(t *testing.T) {
+	h := newTestHandlers(noopCapture)
+	h.Lifecycle = capture.NewLifecycle()
+	conn := dialWS(t, h)
+
+	if reply := sendWS(t, conn, WSCommand{Cmd: "pause"}); reply.OK {
+		t.Error("pause while idle should fail")
+	}
+	h.Lifecycle.Transition(capture.StatePlanning)
+	h.Lifecycle.Transition(capture.StateShooting)
+	if reply := sendWS(t, conn, WSCommand{ID: "p", Cmd: "pause"}); !reply.OK || reply.ID != "p" {
+		t.Fatalf("pause reply = %+v", reply)
+	}
+	if st := h.Lifecycle.State(); st != capture.StatePaused {
+		t.Errorf("state = %q, want paused", st)
+	}
+	if reply := sendWS(t, conn, WSCommand{Cmd: "resume"}); !reply.OK {
+		t.Errorf("resume reply = %+v", reply)
+	}
+}
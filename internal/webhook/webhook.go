@@ -0,0 +1,190 @@
+// Package webhook notifies external systems (workflow automation,
+// notification services) of capture events by posting JSON payloads to
+// configured URLs.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/session"
+)
+
+// Capture events, in Payload.Event. The end events are named after the
+// session outcomes.
+const (
+	EventStarted   = "started"
+	EventDone      = session.OutcomeDone
+	EventFailed    = session.OutcomeFailed
+	EventCancelled = session.OutcomeCancelled
+)
+
+// Events lists the capture events, in the order they happen.
+var Events = []string{EventStarted, EventDone, EventFailed, EventCancelled}
+
+// Request headers set on every delivery.
+const (
+	EventHeader     = "X-PanGo-Event"     // Payload.Event
+	SignatureHeader = "X-PanGo-Signature" // "sha256=" + hex HMAC-SHA256 of the body, with Hook.Secret
+)
+
+// Delivery limits: a hook that does not answer in time is retried, up to
+// maxAttempts in all.
+const (
+	maxAttempts    = 3
+	requestTimeout = 10 * time.Second
+)
+
+// Hook is a URL receiving capture events.
+type Hook struct {
+	URL    string
+	Events []string // events to send; empty = all
+	Secret string   // signs the payloads (SignatureHeader) when set
+}
+
+// wants reports whether h receives event.
+func (h Hook) wants(event string) bool {
+	return len(h.Events) == 0 || slices.Contains(h.Events, event)
+}
+
+// Payload is the JSON body posted to hooks.
+type Payload struct {
+	Event   string          `json:"event"`
+	Time    time.Time       `json:"time"`
+	Session session.Summary `json:"session"` // outcome and counts are set on the end events
+}
+
+// Sender delivers payloads in the background, so that a slow or
+// unreachable hook never delays a capture.
+type Sender struct {
+	Client     *http.Client  // nil = a client with requestTimeout
+	RetryDelay time.Duration // before the first retry, doubled for the next ones
+
+	wg sync.WaitGroup
+}
+
+// NewSender returns a Sender with a 2 s retry delay.
+func NewSender() *Sender {
+	return &Sender{RetryDelay: 2 * time.Second}
+}
+
+// Send posts p to the hooks that want its event. It returns at once;
+// failures are logged.
+func (s *Sender) Send(hooks []Hook, p Payload) {
+	var body []byte
+	for _, h := range hooks {
+		if !h.wants(p.Event) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = json.Marshal(p); err != nil {
+				log.Printf("webhook: encode %s event: %v", p.Event, err)
+				return
+			}
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			if err := s.deliver(h, p.Event, body); err != nil {
+				log.Printf("webhook: %s event to %s: %v", p.Event, host(h.URL), err)
+			}
+		}()
+	}
+}
+
+// Wait waits for the deliveries in progress, at most timeout. It reports
+// false when some did not finish in time.
+func (s *Sender) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// deliver posts body to h, retrying on network errors, 429 and 5xx.
+func (s *Sender) deliver(h Hook, event string, body []byte) error {
+	delay := s.RetryDelay
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var retry bool
+		if retry, err = s.post(h, event, body); err == nil || !retry {
+			return err
+		}
+		if attempt < maxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return fmt.Errorf("%w (after %d attempts)", err, maxAttempts)
+}
+
+// post makes one delivery attempt. retry reports whether a failure may be
+// temporary.
+func (s *Sender) post(h Hook, event string, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PanGo")
+	req.Header.Set(EventHeader, event)
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("status %s", resp.Status)
+}
+
+// Sign returns the SignatureHeader value of body for secret, for receivers
+// checking that a payload comes from the rig.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// host returns the host of rawURL, for logs: the path or query may carry
+// credentials.
+func host(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "invalid URL"
+	}
+	return u.Host
+}
@@ -0,0 +1,162 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cjeanneret/PanGo/internal/session"
+)
+
+// receiver records the deliveries of a test hook endpoint.
+type receiver struct {
+	mu       sync.Mutex
+	payloads []Payload
+	headers  []http.Header
+	bodies   [][]byte
+}
+
+func (rc *receiver) handler(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var p Payload
+	json.Unmarshal(body, &p)
+	rc.mu.Lock()
+	rc.payloads = append(rc.payloads, p)
+	rc.headers = append(rc.headers, r.Header.Clone())
+	rc.bodies = append(rc.bodies, body)
+	rc.mu.Unlock()
+}
+
+func testPayload(event string) Payload {
+	return Payload{
+		Event:   event,
+		Time:    time.Date(2026, 6, 21, 21, 30, 0, 0, time.UTC),
+		Session: session.Summary{ID: "20260621-213000", Mode: "grid", ShotsTaken: 12, Outcome: event},
+	}
+}
+
+// ---------- Send ----------
+
+func TestSend_DeliversAndSigns(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(http.HandlerFunc(rc.handler))
+	defer srv.Close()
+
+	s := NewSender()
+	s.Send([]Hook{{URL: srv.URL, Secret: "s3cret"}}, testPayload(EventDone))
+	if !s.Wait(5 * time.Second) {
+		t.Fatal("delivery did not finish")
+	}
+
+	if len(rc.payloads) != 1 {
+		t.Fatalf("deliveries = %d, want 1", len(rc.payloads))
+	}
+	if p := rc.payloads[0]; p.Event != EventDone || p.Session.ID != "20260621-213000" || p.Session.ShotsTaken != 12 {
+		t.Errorf("payload = %+v", p)
+	}
+	h := rc.headers[0]
+	if h.Get("Content-Type") != "application/json" || h.Get(EventHeader) != EventDone {
+		t.Errorf("headers = %v", h)
+	}
+	if got, want := h.Get(SignatureHeader), Sign("s3cret", rc.bodies[0]); got != want || len(got) != len("sha256=")+64 {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+}
+
+func TestSend_FiltersEvents(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(http.HandlerFunc(rc.handler))
+	defer srv.Close()
+
+	s := NewSender()
+	hooks := []Hook{{URL: srv.URL, Events: []string{EventFailed, EventCancelled}}}
+	for _, event := range Events {
+		s.Send(hooks, testPayload(event))
+	}
+	s.Wait(5 * time.Second)
+
+	if len(rc.payloads) != 2 {
+		t.Fatalf("deliveries = %d, want 2 (failed, cancelled)", len(rc.payloads))
+	}
+	for i, p := range rc.payloads {
+		if p.Event != EventFailed && p.Event != EventCancelled {
+			t.Errorf("delivery %d: event %q not selected", i, p.Event)
+		}
+		if rc.headers[i].Get(SignatureHeader) != "" {
+			t.Errorf("delivery %d: signed without a secret", i)
+		}
+	}
+}
+
+func TestSend_Retries(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   int
+		attempts int32
+	}{
+		{"server_error", http.StatusBadGateway, maxAttempts},
+		{"rate_limited", http.StatusTooManyRequests, maxAttempts},
+		{"client_error", http.StatusNotFound, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			s := &Sender{RetryDelay: time.Millisecond}
+			s.Send([]Hook{{URL: srv.URL}}, testPayload(EventStarted))
+			s.Wait(5 * time.Second)
+			if got := calls.Load(); got != tc.attempts {
+				t.Errorf("attempts = %d, want %d", got, tc.attempts)
+			}
+		})
+	}
+}
+
+func TestSend_RecoversAfterRetry(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	s := &Sender{RetryDelay: time.Millisecond}
+	if err := s.deliver(Hook{URL: srv.URL}, EventDone, []byte("{}")); err != nil {
+		t.Errorf("deliver: %v", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("attempts = %d, want 2", got)
+	}
+}
+
+func TestWait_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	s := NewSender()
+	s.Send([]Hook{{URL: srv.URL}}, testPayload(EventDone))
+	if s.Wait(20 * time.Millisecond) {
+		t.Error("Wait returned true with a delivery pending")
+	}
+}
+
+func TestHost(t *testing.T) {
+	if got := host("https://user:pw@hooks.example.com/t/0123?key=abc"); got != "hooks.example.com" {
+		t.Errorf("host = %q", got)
+	}
+}